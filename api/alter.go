@@ -0,0 +1,353 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/wilhasse/innodb-go/btr"
+	"github.com/wilhasse/innodb-go/buf"
+	"github.com/wilhasse/innodb-go/data"
+	"github.com/wilhasse/innodb-go/dict"
+	"github.com/wilhasse/innodb-go/fil"
+	ibos "github.com/wilhasse/innodb-go/os"
+	"github.com/wilhasse/innodb-go/row"
+	"github.com/wilhasse/innodb-go/trx"
+)
+
+// AlterAction selects the change made by an AlterOp.
+type AlterAction int
+
+const (
+	AlterAddColumn AlterAction = iota
+	AlterDropColumn
+)
+
+// AlterOp describes one column change applied by AlterTable.
+type AlterOp struct {
+	Action AlterAction
+	// Column is the column to add. Only Name is used when dropping.
+	Column ColumnSchema
+	// Default is stored in existing rows for an added column, in the format
+	// accepted by ColSetValue. Nil stores SQL NULL.
+	Default []byte
+}
+
+// alterRebuild holds the rebuilt copy of a table before it is installed.
+type alterRebuild struct {
+	id      uint64
+	tmpName string
+	schema  *TableSchema
+	store   *row.Store
+	spaceID uint32
+	index   *dict.Index
+}
+
+// AlterTable adds or drops columns by rebuilding the table into a new
+// tablespace and swapping the dictionary definition. The rebuild is journaled
+// in the DDL log so a crash either discards the copy or finishes the swap.
+// ibTrx takes the schema lock and an exclusive table lock; with a nil ibTrx
+// the change runs in a transaction of its own. The table must have no open
+// cursors.
+func AlterTable(ibTrx *trx.Trx, name string, ops []AlterOp) ErrCode {
+	if len(ops) == 0 {
+		return DB_INVALID_INPUT
+	}
+	if ibTrx == nil {
+		return ddlInTrx(func(ibTrx *trx.Trx) ErrCode {
+			return AlterTable(ibTrx, name, ops)
+		})
+	}
+	table := findTable(name)
+	if table == nil {
+		return DB_TABLE_NOT_FOUND
+	}
+	if err := lockTableForDDL(ibTrx, table); err != DB_SUCCESS {
+		return err
+	}
+	schemaMu.Lock()
+	defer schemaMu.Unlock()
+	if findTableLocked(name) != table || table.Schema == nil || table.Store == nil {
+		return DB_TABLE_NOT_FOUND
+	}
+	if table.cursors.Load() > 0 {
+		return DB_TABLE_IS_BEING_USED
+	}
+	rebuild, err := alterTableRebuild(table, ops)
+	if err != DB_SUCCESS {
+		return err
+	}
+	defer clearDDLLog()
+	return alterTableInstall(table, rebuild)
+}
+
+// alterTableRebuild copies the table into a temporary tablespace and swaps
+// the dictionary metadata. Callers must hold schemaMu.
+func alterTableRebuild(table *Table, ops []AlterOp) (*alterRebuild, ErrCode) {
+	schema, colMap, defaults, err := alterSchema(table.Schema, ops)
+	if err != DB_SUCCESS {
+		return nil, err
+	}
+	oldDict := dict.DictTableGet(table.Schema.Name)
+	if oldDict == nil {
+		return nil, DB_TABLE_NOT_FOUND
+	}
+	dictTableID, dictErr := dict.DictHdrGetNewID(dict.DictHdrTableID)
+	if dictErr != nil {
+		return nil, DB_ERROR
+	}
+	dbName, _ := splitTableName(schema.Name)
+	rebuild := &alterRebuild{
+		id:     dict.DulintToUint64(dictTableID),
+		schema: schema,
+	}
+	rebuild.tmpName = fmt.Sprintf("%s/#sql-alter-%d", dbName, rebuild.id)
+	rebuild.spaceID = uint32(rebuild.id + 1)
+	if writeDDLLogEntry(ddlLogEntry{Op: ddlOpAlter, Table: schema.Name, NewTable: rebuild.tmpName, Space: rebuild.spaceID}) != nil {
+		return nil, DB_ERROR
+	}
+	if err := alterCreateStore(rebuild); err != DB_SUCCESS {
+		clearDDLLog()
+		return nil, err
+	}
+	discard := func(code ErrCode) (*alterRebuild, ErrCode) {
+		btr.FreeRoot(rebuild.index)
+		fil.SpaceDrop(rebuild.spaceID)
+		if rebuild.schema.Encrypted {
+			dropTableKey(rebuild.spaceID)
+		}
+		_ = rebuild.store.DeleteFile()
+		clearDDLLog()
+		return nil, code
+	}
+	for _, oldRow := range table.Store.SelectAll() {
+		newRow, encErr := encodeDecodeTuple(alterRow(oldRow, colMap, defaults))
+		if encErr != DB_SUCCESS {
+			return discard(encErr)
+		}
+		if err := rebuild.store.Insert(newRow); err != nil {
+			if errors.Is(err, row.ErrDuplicateKey) {
+				return discard(DB_DUPLICATE_KEY)
+			}
+			return discard(DB_ERROR)
+		}
+	}
+	for _, idxSchema := range schema.Indexes {
+		if idxSchema == nil || idxSchema.Clustered {
+			continue
+		}
+		fields, posErr := indexColumnPositions(schema, idxSchema)
+		if posErr != nil {
+			return discard(DB_SCHEMA_ERROR)
+		}
+		if err := rebuild.store.AddSecondaryIndex(idxSchema.Name, fields, idxSchema.Prefixes, idxSchema.Unique); err != nil {
+			if errors.Is(err, row.ErrDuplicateKey) {
+				return discard(DB_DUPLICATE_KEY)
+			}
+			return discard(DB_ERROR)
+		}
+	}
+	_ = buf.FlushAll()
+	newDict, buildErr := buildDictTable(schema, rebuild.spaceID, rebuild.id, rebuild.index)
+	if buildErr != nil {
+		return discard(DB_ERROR)
+	}
+	if dict.DictPersistTableReplace(oldDict, newDict) != nil {
+		return discard(DB_ERROR)
+	}
+	return rebuild, DB_SUCCESS
+}
+
+func alterCreateStore(rebuild *alterRebuild) ErrCode {
+	primaryKey, primaryKeyPrefix, primaryKeyFields, primaryKeyPrefixes := primaryKeyConfig(rebuild.schema)
+	store := row.NewStore(primaryKey)
+	store.PrimaryKeyPrefix = primaryKeyPrefix
+	store.PrimaryKeyFields = primaryKeyFields
+	store.PrimaryKeyPrefixes = primaryKeyPrefixes
+	if !tableSpaceCreate(rebuild.tmpName, rebuild.spaceID, rebuild.schema) {
+		return DB_ERROR
+	}
+	store.SpaceID = rebuild.spaceID
+	index := &dict.Index{Name: "PRIMARY", Clustered: true, SpaceID: rebuild.spaceID}
+	for _, idx := range rebuild.schema.Indexes {
+		if idx != nil && idx.Clustered {
+			if idx.Name != "" {
+				index.Name = idx.Name
+			}
+			index.Fields = append([]string(nil), idx.Columns...)
+			index.Unique = idx.Unique
+			break
+		}
+	}
+	indexID, err := dict.DictHdrGetNewID(dict.DictHdrIndexID)
+	if err != nil {
+		fil.SpaceDrop(rebuild.spaceID)
+		return DB_ERROR
+	}
+	index.ID = indexID
+	btr.Create(index)
+	store.PageTree = btr.NewPageTree(rebuild.spaceID, row.CompareKeys)
+	store.PageTree.RootPage = index.RootPage
+	if err := attachTableFile(store, rebuild.tmpName); err != DB_SUCCESS {
+		btr.FreeRoot(index)
+		fil.SpaceDrop(rebuild.spaceID)
+		return err
+	}
+	rebuild.store = store
+	rebuild.index = index
+	return DB_SUCCESS
+}
+
+// alterTableInstall retires the old tablespace and moves the rebuilt one
+// under the table name. Callers must hold schemaMu.
+func alterTableInstall(table *Table, rebuild *alterRebuild) ErrCode {
+	_ = table.Store.DeleteFile()
+	if table.Index != nil {
+		btr.FreeRoot(table.Index)
+	}
+	if table.SpaceID != 0 {
+		fil.SpaceDrop(table.SpaceID)
+		if table.Schema != nil && table.Schema.Encrypted {
+			dropTableKey(table.SpaceID)
+		}
+	}
+	_ = rebuild.store.CloseFile()
+	moveAlterFile(rebuild.tmpName, rebuild.schema.Name)
+	_ = fil.SpaceRename(rebuild.spaceID, rebuild.schema.Name)
+	if err := attachTableFile(rebuild.store, rebuild.schema.Name); err != DB_SUCCESS {
+		return err
+	}
+	table.ID = rebuild.id
+	table.Schema = rebuild.schema
+	table.Store = rebuild.store
+	table.SpaceID = rebuild.spaceID
+	table.Index = rebuild.index
+	return DB_SUCCESS
+}
+
+// moveAlterFile replaces the table file with the rebuilt copy.
+func moveAlterFile(tmpName, name string) {
+	if !filePerTableEnabled() {
+		return
+	}
+	tmpPath, errTmp := tableFilePath(tmpName)
+	path, errPath := tableFilePath(name)
+	if errTmp != DB_SUCCESS || errPath != DB_SUCCESS {
+		return
+	}
+	if exists, _ := ibos.FileExists(tmpPath); !exists {
+		return
+	}
+	_ = ibos.FileDelete(path)
+	_ = ibos.FileRename(tmpPath, path)
+}
+
+// alterSchema applies ops to a copy of schema. colMap maps each new column to
+// its old position, or -1 for added columns whose value comes from defaults.
+func alterSchema(schema *TableSchema, ops []AlterOp) (*TableSchema, []int, [][]byte, ErrCode) {
+	columns := append([]ColumnSchema(nil), schema.Columns...)
+	colMap := make([]int, len(columns))
+	defaults := make([][]byte, len(columns))
+	for i := range colMap {
+		colMap[i] = i
+	}
+	indexes := make([]*IndexSchema, 0, len(schema.Indexes))
+	for _, idx := range schema.Indexes {
+		if idx == nil {
+			continue
+		}
+		copied := *idx
+		copied.Columns = append([]string(nil), idx.Columns...)
+		copied.Prefixes = append([]int(nil), idx.Prefixes...)
+		indexes = append(indexes, &copied)
+	}
+	for _, op := range ops {
+		pos := -1
+		for i, col := range columns {
+			if strings.EqualFold(col.Name, op.Column.Name) {
+				pos = i
+				break
+			}
+		}
+		switch op.Action {
+		case AlterAddColumn:
+			if op.Column.Name == "" || pos >= 0 {
+				return nil, nil, nil, DB_INVALID_INPUT
+			}
+			if op.Column.Attr&IB_COL_NOT_NULL != 0 && op.Default == nil {
+				return nil, nil, nil, DB_DATA_MISMATCH
+			}
+			columns = append(columns, op.Column)
+			colMap = append(colMap, -1)
+			defaults = append(defaults, op.Default)
+		case AlterDropColumn:
+			if pos < 0 {
+				return nil, nil, nil, DB_NOT_FOUND
+			}
+			if len(columns) == 1 {
+				return nil, nil, nil, DB_INVALID_INPUT
+			}
+			kept := indexes[:0]
+			for _, idx := range indexes {
+				at := -1
+				for i, colName := range idx.Columns {
+					if strings.EqualFold(colName, columns[pos].Name) {
+						at = i
+						break
+					}
+				}
+				if at >= 0 && idx.Clustered {
+					return nil, nil, nil, DB_CANNOT_DROP_CONSTRAINT
+				}
+				if at >= 0 {
+					idx.Columns = append(idx.Columns[:at], idx.Columns[at+1:]...)
+					if at < len(idx.Prefixes) {
+						idx.Prefixes = append(idx.Prefixes[:at], idx.Prefixes[at+1:]...)
+					}
+				}
+				if len(idx.Columns) > 0 {
+					kept = append(kept, idx)
+				}
+			}
+			indexes = kept
+			columns = append(columns[:pos], columns[pos+1:]...)
+			colMap = append(colMap[:pos], colMap[pos+1:]...)
+			defaults = append(defaults[:pos], defaults[pos+1:]...)
+		default:
+			return nil, nil, nil, DB_INVALID_INPUT
+		}
+	}
+	altered := &TableSchema{
+		Name:           schema.Name,
+		Format:         schema.Format,
+		PageSize:       schema.PageSize,
+		PageCompressed: schema.PageCompressed,
+		Encrypted:      schema.Encrypted,
+		Columns:        columns,
+		Indexes:        indexes,
+	}
+	for _, idx := range indexes {
+		idx.Table = altered
+	}
+	return altered, colMap, defaults, DB_SUCCESS
+}
+
+func alterRow(oldRow *data.Tuple, colMap []int, defaults [][]byte) *data.Tuple {
+	newRow := &data.Tuple{Fields: make([]data.Field, len(colMap))}
+	for i, oldPos := range colMap {
+		switch {
+		case oldPos >= 0 && oldPos < len(oldRow.Fields):
+			field := oldRow.Fields[oldPos]
+			field.Data = append([]byte(nil), field.Data...)
+			newRow.Fields[i] = field
+		case defaults[i] != nil:
+			newRow.Fields[i] = data.Field{Data: append([]byte(nil), defaults[i]...), Len: uint32(len(defaults[i]))}
+		default:
+			newRow.Fields[i] = data.Field{Len: data.UnivSQLNull}
+		}
+	}
+	newRow.NFields = len(newRow.Fields)
+	newRow.NFieldsCmp = newRow.NFields
+	newRow.Magic = data.DataTupleMagic
+	return newRow
+}
@@ -0,0 +1,306 @@
+package api
+
+import (
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/wilhasse/innodb-go/dict"
+	ibos "github.com/wilhasse/innodb-go/os"
+)
+
+func u32Bytes(v uint32) []byte {
+	var buf [4]byte
+	binary.BigEndian.PutUint32(buf[:], v)
+	return buf[:]
+}
+
+func seedAlterTable(t *testing.T, tableName string) {
+	t.Helper()
+	createIndexDropTable(t, tableName)
+	var crsr *Cursor
+	if err := CursorOpenTable(tableName, nil, &crsr); err != DB_SUCCESS {
+		t.Fatalf("CursorOpenTable: %v", err)
+	}
+	for i := uint32(1); i <= 5; i++ {
+		if err := insertU32Row(crsr, i, 100-i); err != DB_SUCCESS {
+			t.Fatalf("insert %d: %v", i, err)
+		}
+	}
+	_ = CursorClose(crsr)
+}
+
+func readAllU32Columns(t *testing.T, tableName string) [][]uint32 {
+	t.Helper()
+	var crsr *Cursor
+	if err := CursorOpenTable(tableName, nil, &crsr); err != DB_SUCCESS {
+		t.Fatalf("CursorOpenTable: %v", err)
+	}
+	defer CursorClose(crsr)
+	ncols := len(crsr.Table.Schema.Columns)
+	var rows [][]uint32
+	err := CursorFirst(crsr)
+	for err == DB_SUCCESS {
+		tpl := ClustReadTupleCreate(crsr)
+		if rerr := CursorReadRow(crsr, tpl); rerr != DB_SUCCESS {
+			t.Fatalf("CursorReadRow: %v", rerr)
+		}
+		values := make([]uint32, ncols)
+		for i := range values {
+			if rerr := TupleReadU32(tpl, i, &values[i]); rerr != DB_SUCCESS {
+				t.Fatalf("TupleReadU32 col %d: %v", i, rerr)
+			}
+		}
+		rows = append(rows, values)
+		err = CursorNext(crsr)
+	}
+	if err != DB_END_OF_INDEX && err != DB_RECORD_NOT_FOUND {
+		t.Fatalf("scan: %v", err)
+	}
+	return rows
+}
+
+func assertAlteredRows(t *testing.T, tableName string) {
+	t.Helper()
+	rows := readAllU32Columns(t, tableName)
+	if len(rows) != 5 {
+		t.Fatalf("rows=%d want 5", len(rows))
+	}
+	for i, values := range rows {
+		key := uint32(i + 1)
+		if len(values) != 2 || values[0] != key || values[1] != 7 {
+			t.Fatalf("row %d=%v want [%d 7]", i, values, key)
+		}
+	}
+	dictTable := dict.DictTableGet(tableName)
+	if dictTable == nil || len(dictTable.Columns) != 2 {
+		t.Fatalf("dict columns=%v", dictTable)
+	}
+	if dictTable.Columns[1].Name != "c3" {
+		t.Fatalf("dict column 1=%s want c3", dictTable.Columns[1].Name)
+	}
+	if _, ok := dictTable.Indexes["idx_c2"]; ok {
+		t.Fatalf("expected idx_c2 dropped with its column")
+	}
+}
+
+var alterOps = []AlterOp{
+	{Action: AlterAddColumn, Column: ColumnSchema{Name: "c3", Type: IB_INT, Attr: IB_COL_UNSIGNED | IB_COL_NOT_NULL, Size: 4}, Default: u32Bytes(7)},
+	{Action: AlterDropColumn, Column: ColumnSchema{Name: "c2"}},
+}
+
+func TestAlterTableAddDropColumn(t *testing.T) {
+	resetAPIState()
+	dataDir := t.TempDir() + "/"
+	tableName := "alter_db/t1"
+
+	startupInDir(t, dataDir)
+	if err := DatabaseCreate("alter_db"); err != DB_SUCCESS {
+		t.Fatalf("DatabaseCreate: %v", err)
+	}
+	seedAlterTable(t, tableName)
+
+	noDefault := []AlterOp{{Action: AlterAddColumn, Column: ColumnSchema{Name: "c4", Type: IB_INT, Attr: IB_COL_NOT_NULL, Size: 4}}}
+	if err := AlterTable(nil, tableName, noDefault); err != DB_DATA_MISMATCH {
+		t.Fatalf("AlterTable NOT NULL without default=%v, want DB_DATA_MISMATCH", err)
+	}
+	dropKey := []AlterOp{{Action: AlterDropColumn, Column: ColumnSchema{Name: "c1"}}}
+	if err := AlterTable(nil, tableName, dropKey); err != DB_CANNOT_DROP_CONSTRAINT {
+		t.Fatalf("AlterTable drop key=%v, want DB_CANNOT_DROP_CONSTRAINT", err)
+	}
+	if err := AlterTable(nil, tableName, alterOps); err != DB_SUCCESS {
+		t.Fatalf("AlterTable: %v", err)
+	}
+	assertAlteredRows(t, tableName)
+	if exists, _ := ibos.FileExists(ddlLogPath()); exists {
+		t.Fatalf("expected ddl log cleared")
+	}
+
+	var crsr *Cursor
+	if err := CursorOpenTable(tableName, nil, &crsr); err != DB_SUCCESS {
+		t.Fatalf("CursorOpenTable: %v", err)
+	}
+	if err := insertU32Row(crsr, 6, 7); err != DB_SUCCESS {
+		t.Fatalf("insert after alter: %v", err)
+	}
+	_ = CursorClose(crsr)
+	if err := Shutdown(ShutdownNormal); err != DB_SUCCESS {
+		t.Fatalf("Shutdown: %v", err)
+	}
+
+	startupInDir(t, dataDir)
+	if rows := readAllU32Columns(t, tableName); len(rows) != 6 || rows[5][1] != 7 {
+		t.Fatalf("rows after restart=%v", rows)
+	}
+	if err := Shutdown(ShutdownNormal); err != DB_SUCCESS {
+		t.Fatalf("Shutdown restart: %v", err)
+	}
+}
+
+func TestAlterTableSecondaryIndexRebuilt(t *testing.T) {
+	resetAPIState()
+	dataDir := t.TempDir() + "/"
+	tableName := "alter_idx_db/t1"
+
+	startupInDir(t, dataDir)
+	defer func() { _ = Shutdown(ShutdownNormal) }()
+	if err := DatabaseCreate("alter_idx_db"); err != DB_SUCCESS {
+		t.Fatalf("DatabaseCreate: %v", err)
+	}
+	seedAlterTable(t, tableName)
+	addOnly := []AlterOp{{Action: AlterAddColumn, Column: ColumnSchema{Name: "c3", Type: IB_INT, Attr: IB_COL_UNSIGNED, Size: 4}}}
+	if err := AlterTable(nil, tableName, addOnly); err != DB_SUCCESS {
+		t.Fatalf("AlterTable: %v", err)
+	}
+	var crsr *Cursor
+	if err := CursorOpenTable(tableName, nil, &crsr); err != DB_SUCCESS {
+		t.Fatalf("CursorOpenTable: %v", err)
+	}
+	var secCur *Cursor
+	if err := CursorOpenIndexUsingName(crsr, "idx_c2", &secCur); err != DB_SUCCESS {
+		t.Fatalf("CursorOpenIndexUsingName: %v", err)
+	}
+	keys, err := scanU32RowsBackward(secCur)
+	if err != DB_SUCCESS {
+		t.Fatalf("scan index: %v", err)
+	}
+	assertU32Keys(t, keys, []uint32{1, 2, 3, 4, 5})
+	tpl := ClustReadTupleCreate(crsr)
+	if err := CursorFirst(crsr); err != DB_SUCCESS {
+		t.Fatalf("CursorFirst: %v", err)
+	}
+	if err := CursorReadRow(crsr, tpl); err != DB_SUCCESS {
+		t.Fatalf("CursorReadRow: %v", err)
+	}
+	if ColGetLen(tpl, 2) != IBSQLNull {
+		t.Fatalf("expected NULL in added nullable column")
+	}
+}
+
+func TestDDLLogAlterRecoveryBeforeSwap(t *testing.T) {
+	resetAPIState()
+	dataDir := t.TempDir() + "/"
+	tableName := "alter_rec_db/t1"
+	tmpName := "alter_rec_db/#sql-alter-999"
+
+	startupInDir(t, dataDir)
+	if err := DatabaseCreate("alter_rec_db"); err != DB_SUCCESS {
+		t.Fatalf("DatabaseCreate: %v", err)
+	}
+	seedAlterTable(t, tableName)
+	tmpPath, _ := tableFilePath(tmpName)
+	if err := ibos.FileCreateSubdirsIfNeeded(tmpPath); err != nil {
+		t.Fatalf("create dirs: %v", err)
+	}
+	file, err := ibos.FileCreateSimple(tmpPath, ibos.FileCreate, ibos.FileReadWrite)
+	if err != nil {
+		t.Fatalf("create tmp file: %v", err)
+	}
+	_ = ibos.FileClose(file)
+	if err := writeDDLLogEntry(ddlLogEntry{Op: ddlOpAlter, Table: tableName, NewTable: tmpName, Space: 999}); err != nil {
+		t.Fatalf("write ddl log: %v", err)
+	}
+	if err := Shutdown(ShutdownNormal); err != DB_SUCCESS {
+		t.Fatalf("Shutdown: %v", err)
+	}
+
+	startupInDir(t, dataDir)
+	if exists, _ := ibos.FileExists(tmpPath); exists && filePerTableEnabled() {
+		t.Fatalf("expected rebuilt copy discarded")
+	}
+	rows := readAllU32Columns(t, tableName)
+	if len(rows) != 5 || len(rows[0]) != 2 || rows[0][1] != 99 {
+		t.Fatalf("rows after recovery=%v", rows)
+	}
+	if err := Shutdown(ShutdownNormal); err != DB_SUCCESS {
+		t.Fatalf("Shutdown restart: %v", err)
+	}
+}
+
+func TestDDLLogAlterRecoveryAfterSwap(t *testing.T) {
+	resetAPIState()
+	dataDir := t.TempDir() + "/"
+	tableName := "alter_swap_db/t1"
+
+	startupInDir(t, dataDir)
+	if err := DatabaseCreate("alter_swap_db"); err != DB_SUCCESS {
+		t.Fatalf("DatabaseCreate: %v", err)
+	}
+	seedAlterTable(t, tableName)
+
+	schemaMu.Lock()
+	rebuild, err := alterTableRebuild(findTableLocked(tableName), alterOps)
+	schemaMu.Unlock()
+	if err != DB_SUCCESS {
+		t.Fatalf("alterTableRebuild: %v", err)
+	}
+	if err := Shutdown(ShutdownNormal); err != DB_SUCCESS {
+		t.Fatalf("Shutdown: %v", err)
+	}
+	_ = rebuild.store.CloseFile()
+
+	startupInDir(t, dataDir)
+	assertAlteredRows(t, tableName)
+	if exists, _ := ibos.FileExists(ddlLogPath()); exists {
+		t.Fatalf("expected ddl log cleared")
+	}
+	if err := Shutdown(ShutdownNormal); err != DB_SUCCESS {
+		t.Fatalf("Shutdown restart: %v", err)
+	}
+}
+
+func TestAlterTableLocksTable(t *testing.T) {
+	resetAPIState()
+	dataDir := t.TempDir() + "/"
+	tableName := "alter_lock_db/t1"
+
+	startupInDir(t, dataDir)
+	defer func() { _ = Shutdown(ShutdownNormal) }()
+	if err := DatabaseCreate("alter_lock_db"); err != DB_SUCCESS {
+		t.Fatalf("DatabaseCreate: %v", err)
+	}
+	seedAlterTable(t, tableName)
+	addOnly := []AlterOp{{Action: AlterAddColumn, Column: ColumnSchema{Name: "c3", Type: IB_INT, Attr: IB_COL_UNSIGNED, Size: 4}}}
+
+	var crsr *Cursor
+	if err := CursorOpenTable(tableName, nil, &crsr); err != DB_SUCCESS {
+		t.Fatalf("CursorOpenTable: %v", err)
+	}
+	if err := AlterTable(nil, tableName, addOnly); err != DB_TABLE_IS_BEING_USED {
+		t.Fatalf("AlterTable with open cursor=%v, want DB_TABLE_IS_BEING_USED", err)
+	}
+	_ = CursorClose(crsr)
+
+	writer := TrxBegin(IB_TRX_REPEATABLE_READ)
+	writeCur := openLockingCursor(t, tableName, writer, LockIX)
+	if err := insertU32Row(writeCur, 6, 94); err != DB_SUCCESS {
+		t.Fatalf("insert: %v", err)
+	}
+	_ = CursorClose(writeCur)
+
+	ddl := TrxBegin(IB_TRX_REPEATABLE_READ)
+	done := make(chan ErrCode, 1)
+	go func() {
+		done <- AlterTable(ddl, tableName, addOnly)
+	}()
+	select {
+	case err := <-done:
+		t.Fatalf("AlterTable finished while a writer held the table: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	if err := TrxCommit(writer); err != DB_SUCCESS {
+		t.Fatalf("TrxCommit writer: %v", err)
+	}
+	if err := waitErr(t, done, time.Second); err != DB_SUCCESS {
+		t.Fatalf("AlterTable after commit=%v, want DB_SUCCESS", err)
+	}
+	if !isSchemaLocked(ddl) {
+		t.Fatalf("AlterTable did not take the schema lock")
+	}
+	if err := TrxCommit(ddl); err != DB_SUCCESS {
+		t.Fatalf("TrxCommit ddl: %v", err)
+	}
+	if table := findTable(tableName); table == nil || len(table.Schema.Columns) != 3 {
+		t.Fatalf("column not added")
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/wilhasse/innodb-go/fsp"
 	"github.com/wilhasse/innodb-go/lock"
 	"github.com/wilhasse/innodb-go/log"
+	ibos "github.com/wilhasse/innodb-go/os"
 	"github.com/wilhasse/innodb-go/page"
 	"github.com/wilhasse/innodb-go/srv"
 	"github.com/wilhasse/innodb-go/trx"
@@ -49,6 +50,11 @@ func Init() ErrCode {
 	return DB_SUCCESS
 }
 
+// Started reports whether Startup has completed and Shutdown has not run since.
+func Started() bool {
+	return started
+}
+
 // Startup initializes internal state and validates file format (if provided).
 func Startup(format string) ErrCode {
 	if !initialized {
@@ -71,6 +77,9 @@ func Startup(format string) ErrCode {
 	if err := CfgGet("file_preallocate", &prealloc); err == DB_SUCCESS {
 		fsp.SetPreallocateFiles(prealloc == IBTrue)
 	}
+	if err := openKeyring(); err != DB_SUCCESS {
+		return err
+	}
 	configureLog()
 	log.Init()
 	if err := log.InitErr(); err != nil {
@@ -83,6 +92,7 @@ func Startup(format string) ErrCode {
 	}
 	trx.TrxVarInit()
 	trx.TrxSysVarInit()
+	trx.TrxSysInit()
 	trx.PurgeVarInit()
 	trx.PurgeSysCreate()
 	trx.RsegVarInit()
@@ -196,6 +206,7 @@ func Shutdown(_ ShutdownFlag) ErrCode {
 	lock.SysClose()
 	trx.PurgeSysClose()
 	fil.VarInit()
+	ibos.EncryptionClose()
 	started = false
 	activeDBFormat = ""
 	initialized = false
@@ -177,6 +177,12 @@ func registerDefaults() {
 		Flag:  CfgFlagNone,
 		Value: IBTrue,
 	})
+	registerVar(&ConfigVar{
+		Name:  "encrypt_redo",
+		Type:  CfgTypeBool,
+		Flag:  CfgFlagReadOnlyAfterStartup,
+		Value: IBFalse,
+	})
 	registerVar(&ConfigVar{
 		Name:  "file_format",
 		Type:  CfgTypeText,
@@ -219,6 +225,12 @@ func registerDefaults() {
 		Flag:  CfgFlagNone,
 		Value: Ulint(0),
 	})
+	registerVar(&ConfigVar{
+		Name:  "keyring_file",
+		Type:  CfgTypeText,
+		Flag:  CfgFlagReadOnlyAfterStartup,
+		Value: "",
+	})
 	registerVar(&ConfigVar{
 		Name:  "lock_wait_timeout",
 		Type:  CfgTypeUlint,
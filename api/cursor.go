@@ -21,6 +21,10 @@ type LockMode int
 const (
 	LockIX LockMode = iota + 1
 	LockIS
+	// LockS makes cursor reads take shared row locks (IB_LOCK_S).
+	LockS
+	// LockX makes cursor reads take exclusive row locks (IB_LOCK_X).
+	LockX
 )
 
 // CursorMode controls cursor movement.
@@ -29,6 +33,8 @@ type CursorMode int
 const (
 	CursorGE CursorMode = iota
 	CursorG
+	CursorLE
+	CursorL
 )
 
 // MatchMode mirrors ib_match_t.
@@ -53,6 +59,8 @@ type Cursor struct {
 	Trx        *trx.Trx
 	MatchMode  MatchMode
 	LockMode   LockMode
+	rng        *cursorRange
+	open       bool
 }
 
 func (crsr *Cursor) usePageTree() bool {
@@ -76,6 +84,7 @@ func CursorOpenTable(name string, ibTrx *trx.Trx, out **Cursor) ErrCode {
 	if tree != nil {
 		cursor.pcur = btr.NewPcur(tree)
 	}
+	cursorOpened(cursor)
 	if ibTrx != nil {
 		trx.TrxAssignReadView(ibTrx)
 	}
@@ -90,10 +99,22 @@ func CursorClose(crsr *Cursor) ErrCode {
 	}
 	if crsr != nil {
 		crsr.pageCur = nil
+		if crsr.open && crsr.Table != nil {
+			crsr.Table.cursors.Add(-1)
+		}
+		crsr.open = false
 	}
 	return DB_SUCCESS
 }
 
+// cursorOpened counts a new cursor against its table until CursorClose.
+func cursorOpened(crsr *Cursor) {
+	if crsr.Table != nil {
+		crsr.Table.cursors.Add(1)
+		crsr.open = true
+	}
+}
+
 // CursorLock is a no-op for the in-memory cursor.
 func CursorLock(crsr *Cursor, mode LockMode) ErrCode {
 	return CursorSetLockMode(crsr, mode)
@@ -123,6 +144,7 @@ func CursorReset(crsr *Cursor) ErrCode {
 	crsr.pageCur = nil
 	crsr.lastKey = nil
 	crsr.virtualRow = nil
+	crsr.rng = nil
 	return DB_SUCCESS
 }
 
@@ -144,6 +166,9 @@ func CursorInsertRow(crsr *Cursor, tpl *data.Tuple) ErrCode {
 	if err := lockRecordForDML(crsr, encoded, lock.ModeX, lock.FlagInsertIntention); err != DB_SUCCESS {
 		return err
 	}
+	if err := lockInsertGap(crsr, encoded); err != DB_SUCCESS {
+		return err
+	}
 	if err := crsr.Table.Store.Insert(encoded); err != nil {
 		if errors.Is(err, row.ErrDuplicateKey) {
 			return DB_DUPLICATE_KEY
@@ -176,6 +201,10 @@ func validateNotNull(crsr *Cursor, tpl *data.Tuple) ErrCode {
 
 // CursorFirst positions the cursor at the first row.
 func CursorFirst(crsr *Cursor) ErrCode {
+	return cursorLockStep(crsr, cursorFirst(crsr), cursorNext)
+}
+
+func cursorFirst(crsr *Cursor) ErrCode {
 	if crsr == nil || crsr.Table == nil {
 		return DB_ERROR
 	}
@@ -223,6 +252,10 @@ func CursorFirst(crsr *Cursor) ErrCode {
 
 // CursorNext advances the cursor.
 func CursorNext(crsr *Cursor) ErrCode {
+	return cursorRangeStep(crsr, cursorLockStep(crsr, cursorNext(crsr), cursorNext))
+}
+
+func cursorNext(crsr *Cursor) ErrCode {
 	if crsr == nil || crsr.Table == nil {
 		return DB_ERROR
 	}
@@ -295,11 +328,148 @@ func CursorNext(crsr *Cursor) ErrCode {
 	return DB_SUCCESS
 }
 
+// CursorLast positions the cursor at the last row.
+func CursorLast(crsr *Cursor) ErrCode {
+	if err := lockCursorSupremum(crsr); err != DB_SUCCESS {
+		return err
+	}
+	return cursorLockStepBack(crsr, cursorLast(crsr))
+}
+
+func cursorLast(crsr *Cursor) ErrCode {
+	if crsr == nil || crsr.Table == nil {
+		return DB_ERROR
+	}
+	if crsr.Index != nil && crsr.Table.Store != nil {
+		if err := crsr.Table.Store.MergeSecondaryIndexBuffer(crsr.Index); err != nil {
+			return DB_ERROR
+		}
+	}
+	if crsr.usePageTree() {
+		cur, err := crsr.Table.Store.PageTree.Last()
+		if err != nil {
+			return DB_ERROR
+		}
+		crsr.pageCur = cur
+		crsr.treeCur = nil
+		crsr.virtualRow = nil
+		if crsr.pageCur == nil || !crsr.pageCur.Valid() {
+			return DB_RECORD_NOT_FOUND
+		}
+		if !retreatPageCursorVisible(crsr) {
+			return DB_RECORD_NOT_FOUND
+		}
+		crsr.lastKey = crsr.pageCur.Key()
+		return DB_SUCCESS
+	}
+	if crsr.Tree == nil {
+		return DB_ERROR
+	}
+	pcur := ensurePcur(crsr)
+	if pcur == nil {
+		return DB_ERROR
+	}
+	crsr.virtualRow = nil
+	if !pcur.OpenAtIndexSide(false) {
+		return DB_RECORD_NOT_FOUND
+	}
+	crsr.treeCur = pcur.Cur.Cursor
+	if crsr.treeCur == nil || !crsr.treeCur.Valid() {
+		return DB_RECORD_NOT_FOUND
+	}
+	if !retreatTreeCursorVisible(crsr) {
+		return DB_RECORD_NOT_FOUND
+	}
+	return DB_SUCCESS
+}
+
+// CursorPrev moves the cursor to the previous row.
+func CursorPrev(crsr *Cursor) ErrCode {
+	return cursorRangeStep(crsr, cursorLockStepBack(crsr, cursorPrev(crsr)))
+}
+
+func cursorPrev(crsr *Cursor) ErrCode {
+	if crsr == nil || crsr.Table == nil {
+		return DB_ERROR
+	}
+	crsr.virtualRow = nil
+	if crsr.usePageTree() {
+		crsr.treeCur = nil
+		if crsr.pageCur != nil && crsr.pageCur.Valid() {
+			crsr.lastKey = crsr.pageCur.Key()
+			if !crsr.pageCur.Prev() {
+				crsr.pageCur = nil
+				return DB_END_OF_INDEX
+			}
+		} else {
+			if len(crsr.lastKey) == 0 {
+				return DB_END_OF_INDEX
+			}
+			cur, _, err := crsr.Table.Store.PageTree.Seek(crsr.lastKey, btr.SearchLE)
+			if err != nil || cur == nil || !cur.Valid() {
+				return DB_END_OF_INDEX
+			}
+			if bytes.Equal(cur.Key(), crsr.lastKey) {
+				if !cur.Prev() {
+					return DB_END_OF_INDEX
+				}
+			}
+			crsr.pageCur = cur
+		}
+		if !retreatPageCursorVisible(crsr) {
+			return DB_END_OF_INDEX
+		}
+		crsr.lastKey = crsr.pageCur.Key()
+		return DB_SUCCESS
+	}
+	if crsr.Tree == nil {
+		return DB_ERROR
+	}
+	pcur := ensurePcur(crsr)
+	if pcur == nil || pcur.Cur == nil {
+		return DB_ERROR
+	}
+	if pcur.Cur.Valid() {
+		crsr.lastKey = pcur.Cur.Key()
+		if !pcur.Cur.Prev() {
+			crsr.treeCur = nil
+			return DB_END_OF_INDEX
+		}
+		if !retreatTreeCursorVisible(crsr) {
+			return DB_END_OF_INDEX
+		}
+		return DB_SUCCESS
+	}
+	if len(crsr.lastKey) == 0 {
+		return DB_END_OF_INDEX
+	}
+	if !pcur.OpenOnUserRec(crsr.lastKey, btr.SearchLE) {
+		return DB_END_OF_INDEX
+	}
+	if pcur.Cur.Valid() && row.CompareKeys(pcur.Cur.Key(), crsr.lastKey) == 0 {
+		if !pcur.Cur.Prev() {
+			crsr.treeCur = nil
+			return DB_END_OF_INDEX
+		}
+	}
+	if !pcur.Cur.Valid() {
+		return DB_END_OF_INDEX
+	}
+	crsr.treeCur = pcur.Cur.Cursor
+	if !retreatTreeCursorVisible(crsr) {
+		return DB_END_OF_INDEX
+	}
+	return DB_SUCCESS
+}
+
 // CursorReadRow reads the current row into tpl.
 func CursorReadRow(crsr *Cursor, tpl *data.Tuple) ErrCode {
 	if crsr == nil || crsr.Table == nil || tpl == nil {
 		return DB_ERROR
 	}
+	if err := lockCursorRecord(crsr); err != DB_SUCCESS {
+		return err
+	}
 	if crsr.virtualRow != nil {
 		copyTuple(tpl, crsr.virtualRow)
 		return DB_SUCCESS
@@ -485,6 +655,52 @@ func advanceTreeCursorVisible(crsr *Cursor) bool {
 	return false
 }
 
+func retreatPageCursorVisible(crsr *Cursor) bool {
+	if crsr == nil {
+		return false
+	}
+	for crsr.pageCur != nil && crsr.pageCur.Valid() {
+		if _, ok := cursorPageVisibleTuple(crsr); ok {
+			return true
+		}
+		if !crsr.pageCur.Prev() {
+			crsr.pageCur = nil
+			return false
+		}
+	}
+	return false
+}
+
+func retreatTreeCursorVisible(crsr *Cursor) bool {
+	if crsr == nil {
+		return false
+	}
+	pcur := ensurePcur(crsr)
+	if pcur == nil || pcur.Cur == nil {
+		return false
+	}
+	for pcur.Cur.Valid() {
+		crsr.treeCur = pcur.Cur.Cursor
+		rowID, tuple, ok := cursorRow(crsr)
+		if ok && tuple != nil {
+			recordKey := []byte(nil)
+			if crsr.Index == nil && crsr.treeCur != nil {
+				recordKey = crsr.treeCur.Key()
+			}
+			if _, ok := cursorVisibleTuple(crsr, rowID, tuple, recordKey); ok {
+				crsr.lastKey = crsr.treeCur.Key()
+				return true
+			}
+		}
+		if !pcur.Cur.Prev() {
+			crsr.treeCur = nil
+			return false
+		}
+	}
+	crsr.treeCur = nil
+	return false
+}
+
 func cursorPageKey(crsr *Cursor, tuple *data.Tuple, rowID uint64, recordKey []byte) []byte {
 	if crsr == nil || crsr.Table == nil || crsr.Table.Store == nil {
 		return recordKey
@@ -509,15 +725,31 @@ func cursorPageKey(crsr *Cursor, tuple *data.Tuple, rowID uint64, recordKey []by
 	return recordKey
 }
 
+// cursorReadView returns the read view used to filter row versions, or nil
+// when the cursor reads the latest version.
+func cursorReadView(crsr *Cursor) *read.ReadView {
+	if crsr == nil || crsr.Trx == nil || cursorLockingRead(crsr) {
+		return nil
+	}
+	if crsr.Trx.Isolation == trx.IsolationReadUncommitted {
+		return nil
+	}
+	return crsr.Trx.ReadView
+}
+
 func cursorVisibleTuple(crsr *Cursor, rowID uint64, tuple *data.Tuple, recordKey []byte) (*data.Tuple, bool) {
-	if crsr == nil || crsr.Trx == nil || crsr.Trx.ReadView == nil || crsr.Table == nil || crsr.Table.Store == nil {
+	if crsr == nil || crsr.Trx == nil || crsr.Table == nil || crsr.Table.Store == nil {
+		return tuple, tuple != nil
+	}
+	view := cursorReadView(crsr)
+	if view == nil && !cursorLockingRead(crsr) {
 		return tuple, tuple != nil
 	}
 	key := cursorPageKey(crsr, tuple, rowID, recordKey)
 	if len(key) == 0 {
 		return tuple, tuple != nil
 	}
-	visible, ok := crsr.Table.Store.VersionForView(key, crsr.Trx.ReadView)
+	visible, ok := crsr.Table.Store.VersionForView(key, view)
 	if ok {
 		if visible == nil {
 			return nil, false
@@ -570,22 +802,50 @@ func decodeCursorTuple(crsr *Cursor, value []byte) (*data.Tuple, bool) {
 	if err != nil {
 		return nil, false
 	}
+	for i := range decoded.Fields {
+		field := &decoded.Fields[i]
+		if !field.Ext {
+			continue
+		}
+		value := btr.GetExternallyStoredField(field.Data)
+		if value == nil {
+			return nil, false
+		}
+		*field = data.Field{Data: value, Len: uint32(len(value))}
+	}
 	return decoded, true
 }
 
 // CursorMoveTo positions the cursor based on a search tuple.
 func CursorMoveTo(crsr *Cursor, tpl *data.Tuple, mode CursorMode, ret *int) ErrCode {
+	err := cursorMoveTo(crsr, tpl, mode, ret)
+	if crsr == nil || crsr.MatchMode == IB_EXACT_MATCH {
+		return cursorLockStep(crsr, err, nil)
+	}
+	if cursorModeBackward(mode) {
+		return cursorLockStepBack(crsr, err)
+	}
+	return cursorLockStep(crsr, err, cursorNext)
+}
+
+func cursorMoveTo(crsr *Cursor, tpl *data.Tuple, mode CursorMode, ret *int) ErrCode {
+	return cursorMoveToFields(crsr, tpl, searchFieldCount(tpl), mode, ret)
+}
+
+// cursorMoveToFields positions the cursor like cursorMoveTo but searches on the
+// first keyFields key columns of tpl instead of counting the set fields.
+func cursorMoveToFields(crsr *Cursor, tpl *data.Tuple, keyFields int, mode CursorMode, ret *int) ErrCode {
 	if crsr == nil || crsr.Table == nil || tpl == nil {
 		return DB_ERROR
 	}
 	crsr.virtualRow = nil
 	crsr.pageCur = nil
-	keyFields := searchFieldCount(tpl)
 	if keyFields == 0 {
 		return DB_ERROR
 	}
 	exactRequired := crsr.MatchMode == IB_EXACT_MATCH
 	prefixRequired := crsr.MatchMode == IB_EXACT_PREFIX
+	backward := cursorModeBackward(mode)
 	if crsr.Table.Store == nil {
 		return DB_ERROR
 	}
@@ -620,11 +880,23 @@ func CursorMoveTo(crsr *Cursor, tpl *data.Tuple, mode CursorMode, ret *int) ErrC
 			return DB_ERROR
 		}
 	}
+	compare := func(rowTuple *data.Tuple) int {
+		if crsr.Index != nil {
+			return compareIndexTuplePrefix(rowTuple, tpl, cols, keyFields)
+		}
+		return compareTuplePrefix(rowTuple, tpl, keyFields)
+	}
 	if crsr.usePageTree() && crsr.Index == nil && storeHasPrimaryKey(store) {
 		cur, exact, err := store.PageTree.Seek(searchKey, btr.SearchGE)
 		if err != nil {
 			return DB_ERROR
 		}
+		if backward {
+			cur, err = cursorPageSeekBackward(crsr, cur, mode, compare)
+			if err != nil {
+				return DB_ERROR
+			}
+		}
 		if cur == nil || !cur.Valid() {
 			if exactRequired && assignVirtualRow(crsr, searchKey, keyFields, pkFields, ret) {
 				return DB_SUCCESS
@@ -638,46 +910,37 @@ func CursorMoveTo(crsr *Cursor, tpl *data.Tuple, mode CursorMode, ret *int) ErrC
 		}
 		crsr.pageCur = cur
 		crsr.treeCur = nil
+		step := func() bool {
+			if backward {
+				return crsr.pageCur.Prev()
+			}
+			return crsr.pageCur.Next()
+		}
 		for crsr.pageCur != nil && crsr.pageCur.Valid() {
 			_, rowTuple, ok := cursorPageTuple(crsr)
 			if !ok {
-				if !crsr.pageCur.Next() {
+				if !step() {
 					break
 				}
 				continue
 			}
-			cmp := 0
-			if crsr.Index != nil {
-				cmp = compareIndexTuplePrefix(rowTuple, tpl, cols, keyFields)
-			} else {
-				cmp = compareTuplePrefix(rowTuple, tpl, keyFields)
-			}
-			switch mode {
-			case CursorGE:
-				if cmp < 0 {
-					if !crsr.pageCur.Next() {
-						return cursorMoveNotFound(crsr, searchKey)
-					}
-					continue
-				}
-			case CursorG:
-				if cmp <= 0 {
-					if !crsr.pageCur.Next() {
-						return cursorMoveNotFound(crsr, searchKey)
-					}
-					continue
+			cmp := compare(rowTuple)
+			if !cursorMoveAccepts(mode, cmp) {
+				if !step() {
+					return cursorMoveNotFound(crsr, searchKey)
 				}
+				continue
 			}
 			if prefixRequired {
 				if crsr.Index != nil {
 					if !tupleHasIndexPrefix(rowTuple, tpl, cols, prefixes, keyFields) {
-						if !crsr.pageCur.Next() {
+						if !step() {
 							return cursorMoveNotFound(crsr, searchKey)
 						}
 						continue
 					}
 				} else if !tupleHasPrefix(rowTuple, tpl, keyFields) {
-					if !crsr.pageCur.Next() {
+					if !step() {
 						return cursorMoveNotFound(crsr, searchKey)
 					}
 					continue
@@ -685,20 +948,14 @@ func CursorMoveTo(crsr *Cursor, tpl *data.Tuple, mode CursorMode, ret *int) ErrC
 			}
 			if exactRequired {
 				if cmp != 0 || (pkFields > 0 && keyFields != pkFields) {
-					if !crsr.pageCur.Next() {
+					if !step() {
 						return cursorMoveNotFound(crsr, searchKey)
 					}
 					continue
 				}
 			}
 			crsr.lastKey = crsr.pageCur.Key()
-			if ret != nil {
-				if cmp == 0 && (pkFields == 0 || keyFields == pkFields) {
-					*ret = 0
-				} else {
-					*ret = -1
-				}
-			}
+			setCursorMoveResult(ret, mode, cmp, keyFields, pkFields)
 			return DB_SUCCESS
 		}
 		if exactRequired && assignVirtualRow(crsr, searchKey, keyFields, pkFields, ret) {
@@ -713,59 +970,54 @@ func CursorMoveTo(crsr *Cursor, tpl *data.Tuple, mode CursorMode, ret *int) ErrC
 	if pcur == nil || pcur.Cur == nil {
 		return DB_ERROR
 	}
-	if !pcur.Cur.Search(searchKey, btr.SearchGE) {
+	found := pcur.Cur.Search(searchKey, btr.SearchGE)
+	if backward {
+		found = cursorTreeSeekBackward(crsr, pcur, found, mode, compare)
+	}
+	if !found {
 		if exactRequired && crsr.Index == nil && assignVirtualRow(crsr, searchKey, keyFields, pkFields, ret) {
 			return DB_SUCCESS
 		}
 		return cursorMoveNotFound(crsr, searchKey)
 	}
+	step := func() bool {
+		if backward {
+			return pcur.Cur.Prev()
+		}
+		return pcur.Cur.Next()
+	}
 	for pcur.Cur.Valid() {
 		rowID, ok := row.DecodeRowID(pcur.Cur.Value())
 		if !ok {
-			if !pcur.Cur.Next() {
+			if !step() {
 				break
 			}
 			continue
 		}
 		rowTuple := store.RowByID(rowID)
 		if rowTuple == nil {
-			if !pcur.Cur.Next() {
+			if !step() {
 				break
 			}
 			continue
 		}
-		cmp := 0
-		if crsr.Index != nil {
-			cmp = compareIndexTuplePrefix(rowTuple, tpl, cols, keyFields)
-		} else {
-			cmp = compareTuplePrefix(rowTuple, tpl, keyFields)
-		}
-		switch mode {
-		case CursorGE:
-			if cmp < 0 {
-				if !pcur.Cur.Next() {
-					return cursorMoveNotFound(crsr, searchKey)
-				}
-				continue
-			}
-		case CursorG:
-			if cmp <= 0 {
-				if !pcur.Cur.Next() {
-					return cursorMoveNotFound(crsr, searchKey)
-				}
-				continue
+		cmp := compare(rowTuple)
+		if !cursorMoveAccepts(mode, cmp) {
+			if !step() {
+				return cursorMoveNotFound(crsr, searchKey)
 			}
+			continue
 		}
 		if prefixRequired {
 			if crsr.Index != nil {
 				if !tupleHasIndexPrefix(rowTuple, tpl, cols, prefixes, keyFields) {
-					if !pcur.Cur.Next() {
+					if !step() {
 						return cursorMoveNotFound(crsr, searchKey)
 					}
 					continue
 				}
 			} else if !tupleHasPrefix(rowTuple, tpl, keyFields) {
-				if !pcur.Cur.Next() {
+				if !step() {
 					return cursorMoveNotFound(crsr, searchKey)
 				}
 				continue
@@ -773,7 +1025,7 @@ func CursorMoveTo(crsr *Cursor, tpl *data.Tuple, mode CursorMode, ret *int) ErrC
 		}
 		if exactRequired {
 			if cmp != 0 || (pkFields > 0 && keyFields != pkFields) {
-				if !pcur.Cur.Next() {
+				if !step() {
 					return cursorMoveNotFound(crsr, searchKey)
 				}
 				continue
@@ -781,13 +1033,7 @@ func CursorMoveTo(crsr *Cursor, tpl *data.Tuple, mode CursorMode, ret *int) ErrC
 		}
 		crsr.treeCur = pcur.Cur.Cursor
 		crsr.lastKey = pcur.Cur.Key()
-		if ret != nil {
-			if cmp == 0 && (pkFields == 0 || keyFields == pkFields) {
-				*ret = 0
-			} else {
-				*ret = -1
-			}
-		}
+		setCursorMoveResult(ret, mode, cmp, keyFields, pkFields)
 		return DB_SUCCESS
 	}
 	if exactRequired && crsr.Index == nil && assignVirtualRow(crsr, searchKey, keyFields, pkFields, ret) {
@@ -796,6 +1042,81 @@ func CursorMoveTo(crsr *Cursor, tpl *data.Tuple, mode CursorMode, ret *int) ErrC
 	return cursorMoveNotFound(crsr, searchKey)
 }
 
+func cursorModeBackward(mode CursorMode) bool {
+	return mode == CursorLE || mode == CursorL
+}
+
+// cursorMoveAccepts reports whether a row comparing cmp against the search
+// tuple satisfies the search mode.
+func cursorMoveAccepts(mode CursorMode, cmp int) bool {
+	switch mode {
+	case CursorG:
+		return cmp > 0
+	case CursorLE:
+		return cmp <= 0
+	case CursorL:
+		return cmp < 0
+	default:
+		return cmp >= 0
+	}
+}
+
+func setCursorMoveResult(ret *int, mode CursorMode, cmp, keyFields, pkFields int) {
+	if ret == nil {
+		return
+	}
+	switch {
+	case cmp == 0 && (pkFields == 0 || keyFields == pkFields):
+		*ret = 0
+	case cursorModeBackward(mode):
+		*ret = 1
+	default:
+		*ret = -1
+	}
+}
+
+// cursorPageSeekBackward moves a SearchGE position to the first record past
+// every candidate for a LE/L search, so a backward scan starts from there.
+func cursorPageSeekBackward(crsr *Cursor, cur *btr.PageCursor, mode CursorMode, compare func(*data.Tuple) int) (*btr.PageCursor, error) {
+	if cur == nil || !cur.Valid() {
+		return crsr.Table.Store.PageTree.Last()
+	}
+	if mode != CursorLE {
+		return cur, nil
+	}
+	crsr.pageCur = cur
+	for {
+		_, rowTuple, ok := cursorPageTuple(crsr)
+		if ok && compare(rowTuple) > 0 {
+			return cur, nil
+		}
+		if !cur.Next() {
+			return crsr.Table.Store.PageTree.Last()
+		}
+	}
+}
+
+// cursorTreeSeekBackward is the btr.Tree counterpart of cursorPageSeekBackward.
+func cursorTreeSeekBackward(crsr *Cursor, pcur *btr.Pcur, found bool, mode CursorMode, compare func(*data.Tuple) int) bool {
+	if !found || !pcur.Cur.Valid() {
+		return pcur.OpenAtIndexSide(false)
+	}
+	if mode != CursorLE {
+		return true
+	}
+	store := crsr.Table.Store
+	for {
+		if rowID, ok := row.DecodeRowID(pcur.Cur.Value()); ok {
+			if rowTuple := store.RowByID(rowID); rowTuple != nil && compare(rowTuple) > 0 {
+				return true
+			}
+		}
+		if !pcur.Cur.Next() {
+			return pcur.OpenAtIndexSide(false)
+		}
+	}
+}
+
 func cursorMoveNotFound(crsr *Cursor, searchKey []byte) ErrCode {
 	if err := lockGapForKey(crsr, searchKey); err != DB_SUCCESS {
 		return err
@@ -810,11 +1131,7 @@ func assignVirtualRow(crsr *Cursor, searchKey []byte, keyFields, pkFields int, r
 	if pkFields > 0 && keyFields != pkFields {
 		return false
 	}
-	view := (*read.ReadView)(nil)
-	if crsr.Trx != nil {
-		view = crsr.Trx.ReadView
-	}
-	visible, ok := crsr.Table.Store.VersionForView(searchKey, view)
+	visible, ok := crsr.Table.Store.VersionForView(searchKey, cursorReadView(crsr))
 	if !ok || visible == nil {
 		return false
 	}
@@ -0,0 +1,128 @@
+package api
+
+import (
+	"github.com/wilhasse/innodb-go/lock"
+	"github.com/wilhasse/innodb-go/trx"
+)
+
+// cursorLockingRead reports whether cursor reads take row locks and see the
+// latest committed row version instead of the transaction snapshot.
+// SERIALIZABLE transactions turn every read into a shared locking read.
+func cursorLockingRead(crsr *Cursor) bool {
+	if crsr == nil || crsr.Trx == nil {
+		return false
+	}
+	if crsr.LockMode == LockS || crsr.LockMode == LockX {
+		return true
+	}
+	return crsr.Trx.Isolation == trx.IsolationSerializable
+}
+
+func cursorRecordLockMode(crsr *Cursor) lock.Mode {
+	if crsr != nil && crsr.LockMode == LockX {
+		return lock.ModeX
+	}
+	return lock.ModeS
+}
+
+// lockCursorRecord takes a next-key lock on the row under the cursor when the
+// cursor performs locking reads.
+func lockCursorRecord(crsr *Cursor) ErrCode {
+	if !cursorLockingRead(crsr) {
+		return DB_SUCCESS
+	}
+	tuple, ok := cursorCurrentTuple(crsr)
+	if !ok || tuple == nil {
+		return DB_SUCCESS
+	}
+	return lockRecordForDML(crsr, tuple, cursorRecordLockMode(crsr), lock.FlagNextKey)
+}
+
+// lockCursorSupremum locks the gap after the last row so that a locking scan
+// reaching the end of the index also blocks appends.
+func lockCursorSupremum(crsr *Cursor) ErrCode {
+	if !cursorLockingRead(crsr) {
+		return DB_SUCCESS
+	}
+	_, status := lock.LockRecWithFlags(crsr.Trx, lockSupremumKey(crsr.Table), cursorRecordLockMode(crsr), lock.FlagNextKey)
+	return lockStatusToErr(status)
+}
+
+// lockCursorFirstGap locks the gap before the first row so that a locking
+// backward scan running off the start of the index blocks inserts ahead of
+// every row. The gap belongs to the first row, or to the supremum when the
+// table is empty.
+func lockCursorFirstGap(crsr *Cursor) ErrCode {
+	if !cursorLockingRead(crsr) {
+		return DB_SUCCESS
+	}
+	key := lockSupremumKey(crsr.Table)
+	if crsr.Table != nil && crsr.Table.Store != nil {
+		if first, ok := crsr.Table.Store.NextKey(nil); ok {
+			key = lockRecordKeyFromBytes(crsr.Table, first)
+		}
+	}
+	_, status := lock.LockRecWithFlags(crsr.Trx, key, cursorRecordLockMode(crsr), lock.FlagGap)
+	return lockStatusToErr(status)
+}
+
+// cursorLockStep locks the row reached by a forward cursor move. If the row
+// vanished while waiting for the lock, step moves on to the next candidate;
+// a scan reaching the end of the index locks the supremum.
+func cursorLockStep(crsr *Cursor, err ErrCode, step func(*Cursor) ErrCode) ErrCode {
+	return cursorLockScan(crsr, err, step, lockCursorSupremum)
+}
+
+// cursorLockStepBack locks the row reached by a backward cursor move, moving
+// on to the previous row when it vanished; a scan running off the start of
+// the index locks the gap before the first row.
+func cursorLockStepBack(crsr *Cursor, err ErrCode) ErrCode {
+	return cursorLockScan(crsr, err, cursorPrev, lockCursorFirstGap)
+}
+
+func cursorLockScan(crsr *Cursor, err ErrCode, step func(*Cursor) ErrCode, lockEnd func(*Cursor) ErrCode) ErrCode {
+	if step != nil && (err == DB_END_OF_INDEX || err == DB_RECORD_NOT_FOUND) {
+		if lockErr := lockEnd(crsr); lockErr != DB_SUCCESS {
+			return lockErr
+		}
+		return err
+	}
+	for err == DB_SUCCESS && cursorLockingRead(crsr) {
+		if lockErr := lockCursorRecord(crsr); lockErr != DB_SUCCESS {
+			return lockErr
+		}
+		if cursorCurrentVisible(crsr) {
+			return DB_SUCCESS
+		}
+		if step == nil {
+			return DB_RECORD_NOT_FOUND
+		}
+		err = step(crsr)
+		if err == DB_END_OF_INDEX || err == DB_RECORD_NOT_FOUND {
+			if lockErr := lockEnd(crsr); lockErr != DB_SUCCESS {
+				return lockErr
+			}
+		}
+	}
+	return err
+}
+
+func cursorCurrentVisible(crsr *Cursor) bool {
+	if crsr.virtualRow != nil {
+		return true
+	}
+	if crsr.usePageTree() {
+		_, ok := cursorPageVisibleTuple(crsr)
+		return ok
+	}
+	rowID, tuple, ok := cursorRow(crsr)
+	if !ok || tuple == nil {
+		return false
+	}
+	recordKey := []byte(nil)
+	if crsr.Index == nil && crsr.treeCur != nil {
+		recordKey = crsr.treeCur.Key()
+	}
+	_, ok = cursorVisibleTuple(crsr, rowID, tuple, recordKey)
+	return ok
+}
@@ -0,0 +1,150 @@
+package api
+
+import "testing"
+
+func moveToU32(t *testing.T, crsr *Cursor, col int, key uint32, mode CursorMode) (uint32, int, ErrCode) {
+	t.Helper()
+	search := ClustSearchTupleCreate(crsr)
+	defer TupleDelete(search)
+	if col > 0 {
+		if err := TupleWriteU32(search, 0, 0); err != DB_SUCCESS {
+			t.Fatalf("TupleWriteU32 pad: %v", err)
+		}
+	}
+	if err := TupleWriteU32(search, col, key); err != DB_SUCCESS {
+		t.Fatalf("TupleWriteU32 search: %v", err)
+	}
+	var ret int
+	if err := CursorMoveTo(crsr, search, mode, &ret); err != DB_SUCCESS {
+		return 0, ret, err
+	}
+	return readCurrentU32(t, crsr, 0), ret, DB_SUCCESS
+}
+
+func readCurrentU32(t *testing.T, crsr *Cursor, col int) uint32 {
+	t.Helper()
+	readTpl := ClustReadTupleCreate(crsr)
+	defer TupleDelete(readTpl)
+	if err := CursorReadRow(crsr, readTpl); err != DB_SUCCESS {
+		t.Fatalf("CursorReadRow: %v", err)
+	}
+	var val uint32
+	if err := TupleReadU32(readTpl, col, &val); err != DB_SUCCESS {
+		t.Fatalf("TupleReadU32: %v", err)
+	}
+	return val
+}
+
+func TestCursorMoveToLEAndL(t *testing.T) {
+	tableName := setupU32Table(t, "cursor_le_db")
+	var crsr *Cursor
+	if err := CursorOpenTable(tableName, nil, &crsr); err != DB_SUCCESS {
+		t.Fatalf("CursorOpenTable: %v", err)
+	}
+	for _, k := range []uint32{10, 20, 30, 40} {
+		if err := insertU32Row(crsr, k, k+1); err != DB_SUCCESS {
+			t.Fatalf("insert %d: %v", k, err)
+		}
+	}
+
+	cases := []struct {
+		key  uint32
+		mode CursorMode
+		want uint32
+		ret  int
+	}{
+		{key: 30, mode: CursorLE, want: 30, ret: 0},
+		{key: 35, mode: CursorLE, want: 30, ret: 1},
+		{key: 99, mode: CursorLE, want: 40, ret: 1},
+		{key: 30, mode: CursorL, want: 20, ret: 1},
+		{key: 35, mode: CursorL, want: 30, ret: 1},
+	}
+	for _, tc := range cases {
+		got, ret, err := moveToU32(t, crsr, 0, tc.key, tc.mode)
+		if err != DB_SUCCESS {
+			t.Fatalf("move %d mode %d: %v", tc.key, tc.mode, err)
+		}
+		if got != tc.want || ret != tc.ret {
+			t.Fatalf("move %d mode %d got=%d ret=%d want %d ret %d", tc.key, tc.mode, got, ret, tc.want, tc.ret)
+		}
+	}
+	if _, _, err := moveToU32(t, crsr, 0, 5, CursorLE); err != DB_RECORD_NOT_FOUND {
+		t.Fatalf("expected not found below first key, got %v", err)
+	}
+	if _, _, err := moveToU32(t, crsr, 0, 10, CursorL); err != DB_RECORD_NOT_FOUND {
+		t.Fatalf("expected not found before first key, got %v", err)
+	}
+
+	if _, _, err := moveToU32(t, crsr, 0, 25, CursorLE); err != DB_SUCCESS {
+		t.Fatalf("move 25: %v", err)
+	}
+	if err := CursorPrev(crsr); err != DB_SUCCESS {
+		t.Fatalf("CursorPrev: %v", err)
+	}
+	if got := readCurrentU32(t, crsr, 0); got != 10 {
+		t.Fatalf("prev after LE=%d want 10", got)
+	}
+	if err := CursorNext(crsr); err != DB_SUCCESS {
+		t.Fatalf("CursorNext: %v", err)
+	}
+	if err := CursorNext(crsr); err != DB_SUCCESS {
+		t.Fatalf("CursorNext: %v", err)
+	}
+	if got := readCurrentU32(t, crsr, 0); got != 30 {
+		t.Fatalf("next after LE=%d want 30", got)
+	}
+
+	if err := CursorSetMatchMode(crsr, IB_EXACT_MATCH); err != DB_SUCCESS {
+		t.Fatalf("CursorSetMatchMode: %v", err)
+	}
+	if got, ret, err := moveToU32(t, crsr, 0, 20, CursorLE); err != DB_SUCCESS || got != 20 || ret != 0 {
+		t.Fatalf("exact LE got=%d ret=%d err=%v", got, ret, err)
+	}
+	if _, _, err := moveToU32(t, crsr, 0, 25, CursorLE); err != DB_RECORD_NOT_FOUND {
+		t.Fatalf("expected exact LE miss, got %v", err)
+	}
+}
+
+func TestCursorMoveToLESecondary(t *testing.T) {
+	tableName := setupU32Table(t, "cursor_le_sec_db")
+	var sec *IndexSchema
+	if err := IndexSchemaCreate(nil, "idx_c2", tableName, &sec); err != DB_SUCCESS {
+		t.Fatalf("IndexSchemaCreate: %v", err)
+	}
+	if err := IndexSchemaAddCol(sec, "c2", 0); err != DB_SUCCESS {
+		t.Fatalf("IndexSchemaAddCol: %v", err)
+	}
+	if err := IndexCreate(sec, nil); err != DB_SUCCESS {
+		t.Fatalf("IndexCreate: %v", err)
+	}
+	var crsr *Cursor
+	if err := CursorOpenTable(tableName, nil, &crsr); err != DB_SUCCESS {
+		t.Fatalf("CursorOpenTable: %v", err)
+	}
+	for _, row := range [][2]uint32{{1, 300}, {2, 100}, {3, 200}, {4, 200}} {
+		if err := insertU32Row(crsr, row[0], row[1]); err != DB_SUCCESS {
+			t.Fatalf("insert %d: %v", row[0], err)
+		}
+	}
+	var secCur *Cursor
+	if err := CursorOpenIndexUsingName(crsr, "idx_c2", &secCur); err != DB_SUCCESS {
+		t.Fatalf("CursorOpenIndexUsingName: %v", err)
+	}
+	got, ret, err := moveToU32(t, secCur, 1, 250, CursorLE)
+	if err != DB_SUCCESS || ret != 1 {
+		t.Fatalf("LE 250 err=%v ret=%d", err, ret)
+	}
+	if got != 4 {
+		t.Fatalf("LE 250 landed on c1=%d want 4", got)
+	}
+	got, ret, err = moveToU32(t, secCur, 1, 200, CursorL)
+	if err != DB_SUCCESS || got != 2 || ret != 1 {
+		t.Fatalf("L 200 got=%d ret=%d err=%v", got, ret, err)
+	}
+	if err := CursorNext(secCur); err != DB_SUCCESS {
+		t.Fatalf("CursorNext: %v", err)
+	}
+	if got := readCurrentU32(t, secCur, 1); got != 200 {
+		t.Fatalf("next after L c2=%d want 200", got)
+	}
+}
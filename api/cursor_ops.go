@@ -21,9 +21,9 @@ func CursorSetLockMode(crsr *Cursor, mode LockMode) ErrCode {
 	}
 	var lockMode lock.Mode
 	switch mode {
-	case LockIX:
+	case LockIX, LockX:
 		lockMode = lock.ModeIX
-	case LockIS:
+	case LockIS, LockS:
 		lockMode = lock.ModeIS
 	default:
 		return DB_INVALID_INPUT
@@ -188,6 +188,11 @@ func CursorDeleteRow(crsr *Cursor) ErrCode {
 			return DB_RECORD_NOT_FOUND
 		}
 	}
+	return deleteCursorRow(crsr, row)
+}
+
+// deleteCursorRow locks and deletes row, a tuple held by the cursor's store.
+func deleteCursorRow(crsr *Cursor, row *data.Tuple) ErrCode {
 	if err := lockTableForDML(crsr); err != DB_SUCCESS {
 		return err
 	}
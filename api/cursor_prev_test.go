@@ -0,0 +1,235 @@
+package api
+
+import "testing"
+
+func setupU32Table(t *testing.T, dbName string) string {
+	t.Helper()
+	resetAPIState()
+	if err := Init(); err != DB_SUCCESS {
+		t.Fatalf("Init: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = Shutdown(ShutdownNormal)
+	})
+	if err := Startup("barracuda"); err != DB_SUCCESS {
+		t.Fatalf("Startup: %v", err)
+	}
+	if err := DatabaseCreate(dbName); err != DB_SUCCESS {
+		t.Fatalf("DatabaseCreate: %v", err)
+	}
+	tableName := dbName + "/t"
+	var schema *TableSchema
+	if err := TableSchemaCreate(tableName, &schema, IB_TBL_COMPACT, 0); err != DB_SUCCESS {
+		t.Fatalf("TableSchemaCreate: %v", err)
+	}
+	if err := TableSchemaAddCol(schema, "c1", IB_INT, IB_COL_UNSIGNED, 0, 4); err != DB_SUCCESS {
+		t.Fatalf("TableSchemaAddCol c1: %v", err)
+	}
+	if err := TableSchemaAddCol(schema, "c2", IB_INT, IB_COL_UNSIGNED, 0, 4); err != DB_SUCCESS {
+		t.Fatalf("TableSchemaAddCol c2: %v", err)
+	}
+	var idx *IndexSchema
+	if err := TableSchemaAddIndex(schema, "PRIMARY", &idx); err != DB_SUCCESS {
+		t.Fatalf("TableSchemaAddIndex: %v", err)
+	}
+	if err := IndexSchemaAddCol(idx, "c1", 0); err != DB_SUCCESS {
+		t.Fatalf("IndexSchemaAddCol: %v", err)
+	}
+	if err := IndexSchemaSetClustered(idx); err != DB_SUCCESS {
+		t.Fatalf("IndexSchemaSetClustered: %v", err)
+	}
+	if err := TableCreate(nil, schema, nil); err != DB_SUCCESS {
+		t.Fatalf("TableCreate: %v", err)
+	}
+	return tableName
+}
+
+func scanU32RowsBackward(crsr *Cursor) ([]uint32, ErrCode) {
+	if err := CursorLast(crsr); err != DB_SUCCESS {
+		if err == DB_RECORD_NOT_FOUND || err == DB_END_OF_INDEX {
+			return nil, DB_SUCCESS
+		}
+		return nil, err
+	}
+	readTpl := ClustReadTupleCreate(crsr)
+	defer TupleDelete(readTpl)
+	keys := make([]uint32, 0)
+	for {
+		if err := CursorReadRow(crsr, readTpl); err != DB_SUCCESS {
+			return keys, err
+		}
+		var key uint32
+		if err := TupleReadU32(readTpl, 0, &key); err != DB_SUCCESS {
+			return keys, err
+		}
+		keys = append(keys, key)
+		err := CursorPrev(crsr)
+		if err == DB_END_OF_INDEX || err == DB_RECORD_NOT_FOUND {
+			break
+		}
+		if err != DB_SUCCESS {
+			return keys, err
+		}
+		readTpl = TupleClear(readTpl)
+	}
+	return keys, DB_SUCCESS
+}
+
+func assertU32Keys(t *testing.T, got, want []uint32) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("keys=%v want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("keys=%v want %v", got, want)
+		}
+	}
+}
+
+func TestCursorPrevPageTree(t *testing.T) {
+	tableName := setupU32Table(t, "cursor_prev_db")
+	table := findTable(tableName)
+	if table == nil || table.Store == nil || table.Store.PageTree == nil {
+		t.Fatalf("expected page tree backed table")
+	}
+	table.Store.PageTree.MaxRecs = 3
+
+	var crsr *Cursor
+	if err := CursorOpenTable(tableName, nil, &crsr); err != DB_SUCCESS {
+		t.Fatalf("CursorOpenTable: %v", err)
+	}
+	for i := uint32(1); i <= 10; i++ {
+		if err := insertU32Row(crsr, i, i*10); err != DB_SUCCESS {
+			t.Fatalf("insert %d: %v", i, err)
+		}
+	}
+	keys, err := scanU32RowsBackward(crsr)
+	if err != DB_SUCCESS {
+		t.Fatalf("scan backward: %v", err)
+	}
+	assertU32Keys(t, keys, []uint32{10, 9, 8, 7, 6, 5, 4, 3, 2, 1})
+
+	if err := CursorLast(crsr); err != DB_SUCCESS {
+		t.Fatalf("CursorLast: %v", err)
+	}
+	if err := CursorPrev(crsr); err != DB_SUCCESS {
+		t.Fatalf("CursorPrev: %v", err)
+	}
+	if err := CursorNext(crsr); err != DB_SUCCESS {
+		t.Fatalf("CursorNext after prev: %v", err)
+	}
+	readTpl := ClustReadTupleCreate(crsr)
+	if err := CursorReadRow(crsr, readTpl); err != DB_SUCCESS {
+		t.Fatalf("CursorReadRow: %v", err)
+	}
+	var key uint32
+	if err := TupleReadU32(readTpl, 0, &key); err != DB_SUCCESS || key != 10 {
+		t.Fatalf("key after prev/next=%d err=%v want 10", key, err)
+	}
+	if err := CursorClose(crsr); err != DB_SUCCESS {
+		t.Fatalf("CursorClose: %v", err)
+	}
+}
+
+func TestCursorPrevTree(t *testing.T) {
+	tableName := setupU32Table(t, "cursor_prev_tree_db")
+	table := findTable(tableName)
+	if table == nil || table.Store == nil {
+		t.Fatalf("table not found")
+	}
+	table.Store.PageTree = nil
+
+	var crsr *Cursor
+	if err := CursorOpenTable(tableName, nil, &crsr); err != DB_SUCCESS {
+		t.Fatalf("CursorOpenTable: %v", err)
+	}
+	for _, k := range []uint32{5, 1, 4, 2, 3} {
+		if err := insertU32Row(crsr, k, k*10); err != DB_SUCCESS {
+			t.Fatalf("insert %d: %v", k, err)
+		}
+	}
+	keys, err := scanU32RowsBackward(crsr)
+	if err != DB_SUCCESS {
+		t.Fatalf("scan backward: %v", err)
+	}
+	assertU32Keys(t, keys, []uint32{5, 4, 3, 2, 1})
+}
+
+func TestCursorPrevSecondaryIndex(t *testing.T) {
+	tableName := setupU32Table(t, "cursor_prev_sec_db")
+	var sec *IndexSchema
+	if err := IndexSchemaCreate(nil, "idx_c2", tableName, &sec); err != DB_SUCCESS {
+		t.Fatalf("IndexSchemaCreate: %v", err)
+	}
+	if err := IndexSchemaAddCol(sec, "c2", 0); err != DB_SUCCESS {
+		t.Fatalf("IndexSchemaAddCol: %v", err)
+	}
+	if err := IndexCreate(sec, nil); err != DB_SUCCESS {
+		t.Fatalf("IndexCreate: %v", err)
+	}
+
+	var crsr *Cursor
+	if err := CursorOpenTable(tableName, nil, &crsr); err != DB_SUCCESS {
+		t.Fatalf("CursorOpenTable: %v", err)
+	}
+	for _, row := range [][2]uint32{{1, 50}, {2, 10}, {3, 40}, {4, 20}, {5, 30}} {
+		if err := insertU32Row(crsr, row[0], row[1]); err != DB_SUCCESS {
+			t.Fatalf("insert %d: %v", row[0], err)
+		}
+	}
+	var secCur *Cursor
+	if err := CursorOpenIndexUsingName(crsr, "idx_c2", &secCur); err != DB_SUCCESS {
+		t.Fatalf("CursorOpenIndexUsingName: %v", err)
+	}
+	keys, err := scanU32RowsBackward(secCur)
+	if err != DB_SUCCESS {
+		t.Fatalf("scan backward: %v", err)
+	}
+	assertU32Keys(t, keys, []uint32{1, 3, 5, 4, 2})
+}
+
+func TestCursorPrevSkipsInvisibleRows(t *testing.T) {
+	tableName := setupU32Table(t, "cursor_prev_mvcc_db")
+
+	base := TrxBegin(IB_TRX_REPEATABLE_READ)
+	var baseCur *Cursor
+	if err := CursorOpenTable(tableName, base, &baseCur); err != DB_SUCCESS {
+		t.Fatalf("CursorOpenTable base: %v", err)
+	}
+	for i := uint32(1); i <= 3; i++ {
+		if err := insertU32Row(baseCur, i, i*100); err != DB_SUCCESS {
+			t.Fatalf("insert base %d: %v", i, err)
+		}
+	}
+	if err := TrxCommit(base); err != DB_SUCCESS {
+		t.Fatalf("TrxCommit base: %v", err)
+	}
+
+	reader := TrxBegin(IB_TRX_REPEATABLE_READ)
+	var readCur *Cursor
+	if err := CursorOpenTable(tableName, reader, &readCur); err != DB_SUCCESS {
+		t.Fatalf("CursorOpenTable reader: %v", err)
+	}
+
+	writer := TrxBegin(IB_TRX_REPEATABLE_READ)
+	var writeCur *Cursor
+	if err := CursorOpenTable(tableName, writer, &writeCur); err != DB_SUCCESS {
+		t.Fatalf("CursorOpenTable writer: %v", err)
+	}
+	if err := insertU32Row(writeCur, 4, 400); err != DB_SUCCESS {
+		t.Fatalf("insert writer 4: %v", err)
+	}
+	if err := TrxCommit(writer); err != DB_SUCCESS {
+		t.Fatalf("TrxCommit writer: %v", err)
+	}
+
+	keys, err := scanU32RowsBackward(readCur)
+	if err != DB_SUCCESS {
+		t.Fatalf("scan backward: %v", err)
+	}
+	assertU32Keys(t, keys, []uint32{3, 2, 1})
+	if err := TrxRollback(reader); err != DB_SUCCESS {
+		t.Fatalf("TrxRollback reader: %v", err)
+	}
+}
@@ -0,0 +1,167 @@
+package api
+
+import "github.com/wilhasse/innodb-go/data"
+
+// CursorDirection selects the scan direction of a range cursor.
+type CursorDirection int
+
+const (
+	CursorForward CursorDirection = iota
+	CursorBackward
+)
+
+// cursorRange holds the bounds installed by CursorSetRange.
+type cursorRange struct {
+	lower          *data.Tuple
+	upper          *data.Tuple
+	lowerInclusive bool
+	upperInclusive bool
+	direction      CursorDirection
+}
+
+// CursorSetRange restricts the cursor to rows between lower and upper and
+// positions it on the first row in the scan direction. A nil bound leaves that
+// side open. Subsequent CursorNext/CursorPrev calls return DB_END_OF_INDEX once
+// the cursor leaves the range.
+func CursorSetRange(crsr *Cursor, lower *data.Tuple, lowerInclusive bool, upper *data.Tuple, upperInclusive bool, dir CursorDirection) ErrCode {
+	if crsr == nil || crsr.Table == nil {
+		return DB_ERROR
+	}
+	if lower != nil && searchFieldCount(lower) == 0 {
+		return DB_INVALID_INPUT
+	}
+	if upper != nil && searchFieldCount(upper) == 0 {
+		return DB_INVALID_INPUT
+	}
+	if err := CursorReset(crsr); err != DB_SUCCESS {
+		return err
+	}
+	var err ErrCode
+	switch dir {
+	case CursorForward:
+		if lower == nil {
+			err = CursorFirst(crsr)
+		} else {
+			mode := CursorG
+			if lowerInclusive {
+				mode = CursorGE
+			}
+			err = cursorMoveToClosest(crsr, lower, mode)
+		}
+	case CursorBackward:
+		if upper == nil {
+			err = CursorLast(crsr)
+		} else {
+			mode := CursorL
+			if upperInclusive {
+				mode = CursorLE
+			}
+			err = cursorMoveToClosest(crsr, upper, mode)
+		}
+	default:
+		return DB_INVALID_INPUT
+	}
+	crsr.rng = &cursorRange{
+		lower:          cloneTuple(lower),
+		upper:          cloneTuple(upper),
+		lowerInclusive: lowerInclusive,
+		upperInclusive: upperInclusive,
+		direction:      dir,
+	}
+	if err != DB_SUCCESS {
+		if err == DB_END_OF_INDEX {
+			return DB_RECORD_NOT_FOUND
+		}
+		return err
+	}
+	if !cursorInRange(crsr) {
+		return DB_RECORD_NOT_FOUND
+	}
+	return DB_SUCCESS
+}
+
+// CursorRangeNext moves the cursor one row in the direction set by CursorSetRange.
+func CursorRangeNext(crsr *Cursor) ErrCode {
+	if crsr == nil {
+		return DB_ERROR
+	}
+	if crsr.rng != nil && crsr.rng.direction == CursorBackward {
+		return CursorPrev(crsr)
+	}
+	return CursorNext(crsr)
+}
+
+// CursorClearRange removes the bounds installed by CursorSetRange.
+func CursorClearRange(crsr *Cursor) ErrCode {
+	if crsr == nil {
+		return DB_ERROR
+	}
+	crsr.rng = nil
+	return DB_SUCCESS
+}
+
+func cursorMoveToClosest(crsr *Cursor, tpl *data.Tuple, mode CursorMode) ErrCode {
+	matchMode := crsr.MatchMode
+	crsr.MatchMode = IB_CLOSEST_MATCH
+	err := CursorMoveTo(crsr, tpl, mode, nil)
+	crsr.MatchMode = matchMode
+	return err
+}
+
+// cursorRangeStep applies the range bounds to the result of a cursor move.
+func cursorRangeStep(crsr *Cursor, err ErrCode) ErrCode {
+	if err != DB_SUCCESS || crsr == nil || crsr.rng == nil {
+		return err
+	}
+	if !cursorInRange(crsr) {
+		return DB_END_OF_INDEX
+	}
+	return DB_SUCCESS
+}
+
+func cursorInRange(crsr *Cursor) bool {
+	if crsr == nil || crsr.rng == nil {
+		return true
+	}
+	rowTuple, ok := cursorCurrentTuple(crsr)
+	if !ok {
+		return false
+	}
+	rng := crsr.rng
+	if rng.lower != nil {
+		cmp := cursorCompareBound(crsr, rowTuple, rng.lower)
+		if cmp < 0 || (cmp == 0 && !rng.lowerInclusive) {
+			return false
+		}
+	}
+	if rng.upper != nil {
+		cmp := cursorCompareBound(crsr, rowTuple, rng.upper)
+		if cmp > 0 || (cmp == 0 && !rng.upperInclusive) {
+			return false
+		}
+	}
+	return true
+}
+
+func cursorCompareBound(crsr *Cursor, rowTuple, bound *data.Tuple) int {
+	keyFields := searchFieldCount(bound)
+	if crsr.Index != nil {
+		return compareIndexTuplePrefix(rowTuple, bound, crsr.Index.Fields, keyFields)
+	}
+	if pkFields := primaryKeyCols(crsr.Table); pkFields > 0 && keyFields > pkFields {
+		keyFields = pkFields
+	}
+	return compareTuplePrefix(rowTuple, bound, keyFields)
+}
+
+func cursorCurrentTuple(crsr *Cursor) (*data.Tuple, bool) {
+	if crsr.virtualRow != nil {
+		return crsr.virtualRow, true
+	}
+	if crsr.usePageTree() {
+		_, tuple, ok := cursorPageTuple(crsr)
+		return tuple, ok
+	}
+	_, tuple, ok := cursorRow(crsr)
+	return tuple, ok
+}
@@ -0,0 +1,107 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/wilhasse/innodb-go/data"
+)
+
+func scanU32Range(t *testing.T, crsr *Cursor, lower *uint32, lowerIncl bool, upper *uint32, upperIncl bool, dir CursorDirection, col int) []uint32 {
+	t.Helper()
+	boundTuple := func(v *uint32) *data.Tuple {
+		if v == nil {
+			return nil
+		}
+		tpl := ClustSearchTupleCreate(crsr)
+		if col > 0 {
+			_ = TupleWriteU32(tpl, 0, 0)
+		}
+		if err := TupleWriteU32(tpl, col, *v); err != DB_SUCCESS {
+			t.Fatalf("TupleWriteU32 bound: %v", err)
+		}
+		return tpl
+	}
+	err := CursorSetRange(crsr, boundTuple(lower), lowerIncl, boundTuple(upper), upperIncl, dir)
+	if err == DB_RECORD_NOT_FOUND {
+		return nil
+	}
+	if err != DB_SUCCESS {
+		t.Fatalf("CursorSetRange: %v", err)
+	}
+	var keys []uint32
+	for {
+		keys = append(keys, readCurrentU32(t, crsr, 0))
+		err := CursorRangeNext(crsr)
+		if err == DB_END_OF_INDEX {
+			return keys
+		}
+		if err != DB_SUCCESS {
+			t.Fatalf("CursorRangeNext: %v", err)
+		}
+	}
+}
+
+func TestCursorSetRangeClustered(t *testing.T) {
+	tableName := setupU32Table(t, "cursor_range_db")
+	table := findTable(tableName)
+	table.Store.PageTree.MaxRecs = 3
+	var crsr *Cursor
+	if err := CursorOpenTable(tableName, nil, &crsr); err != DB_SUCCESS {
+		t.Fatalf("CursorOpenTable: %v", err)
+	}
+	for i := uint32(1); i <= 10; i++ {
+		if err := insertU32Row(crsr, i, i); err != DB_SUCCESS {
+			t.Fatalf("insert %d: %v", i, err)
+		}
+	}
+	lo, hi := uint32(3), uint32(7)
+	assertU32Keys(t, scanU32Range(t, crsr, &lo, true, &hi, false, CursorForward, 0), []uint32{3, 4, 5, 6})
+	assertU32Keys(t, scanU32Range(t, crsr, &lo, false, &hi, true, CursorForward, 0), []uint32{4, 5, 6, 7})
+	assertU32Keys(t, scanU32Range(t, crsr, &lo, true, &hi, true, CursorBackward, 0), []uint32{7, 6, 5, 4, 3})
+	assertU32Keys(t, scanU32Range(t, crsr, nil, false, &lo, false, CursorForward, 0), []uint32{1, 2})
+	assertU32Keys(t, scanU32Range(t, crsr, &hi, false, nil, false, CursorBackward, 0), []uint32{10, 9, 8})
+	empty := uint32(5)
+	if got := scanU32Range(t, crsr, &empty, false, &empty, false, CursorForward, 0); len(got) != 0 {
+		t.Fatalf("expected empty range, got %v", got)
+	}
+	if err := CursorFirst(crsr); err != DB_SUCCESS {
+		t.Fatalf("CursorFirst: %v", err)
+	}
+	if err := CursorReset(crsr); err != DB_SUCCESS {
+		t.Fatalf("CursorReset: %v", err)
+	}
+	keys, _, err := scanU32Rows(crsr)
+	if err != DB_SUCCESS || len(keys) != 10 {
+		t.Fatalf("expected reset to clear range, keys=%v err=%v", keys, err)
+	}
+}
+
+func TestCursorSetRangeSecondary(t *testing.T) {
+	tableName := setupU32Table(t, "cursor_range_sec_db")
+	var sec *IndexSchema
+	if err := IndexSchemaCreate(nil, "idx_c2", tableName, &sec); err != DB_SUCCESS {
+		t.Fatalf("IndexSchemaCreate: %v", err)
+	}
+	if err := IndexSchemaAddCol(sec, "c2", 0); err != DB_SUCCESS {
+		t.Fatalf("IndexSchemaAddCol: %v", err)
+	}
+	if err := IndexCreate(sec, nil); err != DB_SUCCESS {
+		t.Fatalf("IndexCreate: %v", err)
+	}
+	var crsr *Cursor
+	if err := CursorOpenTable(tableName, nil, &crsr); err != DB_SUCCESS {
+		t.Fatalf("CursorOpenTable: %v", err)
+	}
+	for i := uint32(1); i <= 6; i++ {
+		if err := insertU32Row(crsr, i, 100-i*10); err != DB_SUCCESS {
+			t.Fatalf("insert %d: %v", i, err)
+		}
+	}
+	var secCur *Cursor
+	if err := CursorOpenIndexUsingName(crsr, "idx_c2", &secCur); err != DB_SUCCESS {
+		t.Fatalf("CursorOpenIndexUsingName: %v", err)
+	}
+	lo, hi := uint32(50), uint32(80)
+	assertU32Keys(t, scanU32Range(t, secCur, &lo, true, &hi, false, CursorForward, 1), []uint32{5, 4, 3})
+	assertU32Keys(t, scanU32Range(t, secCur, &lo, false, &hi, true, CursorBackward, 1), []uint32{2, 3, 4})
+}
@@ -6,6 +6,7 @@ import (
 	"path/filepath"
 	"sync"
 
+	"github.com/wilhasse/innodb-go/btr"
 	"github.com/wilhasse/innodb-go/dict"
 	"github.com/wilhasse/innodb-go/fil"
 	ibos "github.com/wilhasse/innodb-go/os"
@@ -17,12 +18,19 @@ const (
 	ddlOpCreate = "create"
 	ddlOpDrop   = "drop"
 	ddlOpRename = "rename"
+	// ddlOpDropIndex journals IndexDrop; Index names the dropped index.
+	ddlOpDropIndex = "drop_index"
+	// ddlOpAlter journals AlterTable; NewTable names the rebuilt copy and
+	// Space its tablespace.
+	ddlOpAlter = "alter"
 )
 
 type ddlLogEntry struct {
 	Op       string `json:"op"`
 	Table    string `json:"table"`
 	NewTable string `json:"new_table,omitempty"`
+	Index    string `json:"index,omitempty"`
+	Space    uint32 `json:"space,omitempty"`
 }
 
 var ddlLogMu sync.Mutex
@@ -107,6 +115,10 @@ func recoverDDLLog() ErrCode {
 		recoverDDLDrop(entry.Table)
 	case ddlOpRename:
 		recoverDDLRename(entry.Table, entry.NewTable)
+	case ddlOpDropIndex:
+		recoverDDLDropIndex(entry.Table, entry.Index)
+	case ddlOpAlter:
+		recoverDDLAlter(entry.Table, entry.NewTable, entry.Space)
 	}
 	clearDDLLog()
 	return DB_SUCCESS
@@ -146,6 +158,45 @@ func recoverDDLRename(oldName, newName string) {
 	renameDDLArtifacts(oldName, newName)
 }
 
+func recoverDDLDropIndex(tableName, indexName string) {
+	if tableName == "" || indexName == "" {
+		return
+	}
+	table := dict.DictTableGet(tableName)
+	if table == nil {
+		return
+	}
+	dropDictIndex(table, indexName)
+}
+
+// dropDictIndex frees the index root page, if any, and removes the index
+// from the SYS_INDEXES/SYS_FIELDS rows.
+func dropDictIndex(table *dict.Table, indexName string) {
+	index := table.Indexes[indexName]
+	if index == nil {
+		return
+	}
+	// Indexes built only in memory carry no root page; page 0 is the
+	// tablespace header and never an index root.
+	if index.RootPage != fil.NullPageOffset && index.RootPage != 0 {
+		btr.FreeRoot(index)
+	}
+	_ = dict.DictPersistIndexDrop(table, indexName)
+}
+
+// recoverDDLAlter finishes an AlterTable whose dictionary swap committed and
+// discards the rebuilt copy otherwise.
+func recoverDDLAlter(name, tmpName string, spaceID uint32) {
+	if name == "" || tmpName == "" {
+		return
+	}
+	if table := dict.DictTableGet(name); table != nil && spaceID != 0 && table.Space == spaceID {
+		moveAlterFile(tmpName, name)
+		return
+	}
+	dropDDLArtifacts(tmpName)
+}
+
 func dropDDLArtifacts(name string) {
 	if name == "" {
 		return
@@ -0,0 +1,51 @@
+package api
+
+import (
+	"path/filepath"
+
+	ibos "github.com/wilhasse/innodb-go/os"
+)
+
+// keyInfoFileName holds the wrapped tablespace and redo keys.
+const keyInfoFileName = "ib_encryption"
+
+// openKeyring loads the master keys from the keyring_file, when set, before
+// the redo log and the tablespaces are opened.
+func openKeyring() ErrCode {
+	var path string
+	if err := CfgGet("keyring_file", &path); err != DB_SUCCESS || path == "" {
+		return DB_SUCCESS
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dataHomeDir(), path)
+	}
+	if err := ibos.EncryptionOpen(path, filepath.Join(dataHomeDir(), keyInfoFileName)); err != nil {
+		Log(nil, "InnoDB: cannot open keyring %s: %v\n", path, err)
+		return DB_ERROR
+	}
+	return DB_SUCCESS
+}
+
+// MasterKeyRotate replaces the master key of the keyring. The tablespace
+// and redo keys are rewrapped with the new master key; no data is
+// rewritten.
+func MasterKeyRotate() ErrCode {
+	if !started {
+		return DB_ERROR
+	}
+	if !ibos.EncryptionIsOpen() {
+		return DB_UNSUPPORTED
+	}
+	if _, err := ibos.EncryptionRotateMasterKey(); err != nil {
+		Log(nil, "InnoDB: master key rotation failed: %v\n", err)
+		return DB_ERROR
+	}
+	return DB_SUCCESS
+}
+
+// dropTableKey forgets the key of a dropped encrypted tablespace.
+func dropTableKey(spaceID uint32) {
+	if ibos.EncryptionIsOpen() {
+		_ = ibos.EncryptionDropKey(spaceID)
+	}
+}
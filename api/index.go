@@ -14,7 +14,10 @@ import (
 
 var nextIndexID uint64
 
-// IndexSchemaCreate creates an index schema for an existing table.
+// IndexSchemaCreate creates an index schema for an existing table. The index
+// is not listed in the table schema yet: IndexCreate or IndexCreateOnline add
+// it once it is built, so the schema never lists an index the table cannot
+// read through.
 func IndexSchemaCreate(_ *trx.Trx, name string, tableName string, out **IndexSchema) ErrCode {
 	if out == nil {
 		return DB_ERROR
@@ -25,9 +28,7 @@ func IndexSchemaCreate(_ *trx.Trx, name string, tableName string, out **IndexSch
 	if table == nil || table.Schema == nil {
 		return DB_TABLE_NOT_FOUND
 	}
-	index := &IndexSchema{Name: name, Table: table.Schema}
-	table.Schema.Indexes = append(table.Schema.Indexes, index)
-	*out = index
+	*out = &IndexSchema{Name: name, Table: table.Schema}
 	return DB_SUCCESS
 }
 
@@ -35,36 +36,84 @@ func IndexSchemaCreate(_ *trx.Trx, name string, tableName string, out **IndexSch
 func IndexSchemaDelete(_ *IndexSchema) {
 }
 
-// IndexCreate registers an index schema.
+// IndexCreate registers an index schema and lists it in the table schema.
+// Clustered indexes are only registered. Secondary indexes are built online
+// in a transaction of their own, see IndexCreateOnline: the build waits for
+// the transactions writing to the table and briefly locks it exclusively,
+// so callers must not hold locks on the table in another transaction.
 func IndexCreate(index *IndexSchema, indexID *uint64) ErrCode {
 	if index == nil {
 		return DB_ERROR
 	}
-	if indexID != nil {
-		*indexID = atomic.AddUint64(&nextIndexID, 1)
-	}
 	if index.Clustered {
+		if indexID != nil {
+			*indexID = atomic.AddUint64(&nextIndexID, 1)
+		}
+		schemaMu.Lock()
+		defer schemaMu.Unlock()
+		if table := findTableBySchemaLocked(index.Table); table != nil {
+			publishIndexSchema(table, index)
+		}
 		return DB_SUCCESS
 	}
+	ibTrx := TrxBegin(IB_TRX_REPEATABLE_READ)
+	err := IndexCreateOnline(ibTrx, index, indexID)
+	if err != DB_SUCCESS {
+		_ = TrxRollback(ibTrx)
+		return err
+	}
+	return TrxCommit(ibTrx)
+}
+
+// IndexDrop drops a secondary index from a table. The drop is journaled in
+// the DDL log so an interrupted drop completes on the next startup. ibTrx
+// takes the schema lock and an exclusive table lock; with a nil ibTrx the
+// drop runs in a transaction of its own.
+func IndexDrop(ibTrx *trx.Trx, tableName, indexName string) ErrCode {
+	if tableName == "" || indexName == "" {
+		return DB_INVALID_INPUT
+	}
+	if ibTrx == nil {
+		return ddlInTrx(func(ibTrx *trx.Trx) ErrCode {
+			return IndexDrop(ibTrx, tableName, indexName)
+		})
+	}
+	table := findTable(tableName)
+	if table == nil {
+		return DB_TABLE_NOT_FOUND
+	}
+	if err := lockTableForDDL(ibTrx, table); err != DB_SUCCESS {
+		return err
+	}
 	schemaMu.Lock()
 	defer schemaMu.Unlock()
-	table := findTableBySchemaLocked(index.Table)
-	if table == nil || table.Store == nil {
+	if findTableLocked(tableName) != table || table.Schema == nil {
 		return DB_TABLE_NOT_FOUND
 	}
-	fields, err := indexColumnPositions(index.Table, index)
-	if err != nil {
-		return DB_SCHEMA_ERROR
-	}
-	if err := table.Store.AddSecondaryIndex(index.Name, fields, index.Prefixes, index.Unique); err != nil {
-		if errors.Is(err, row.ErrDuplicateKey) {
-			return DB_DUPLICATE_KEY
+	pos := -1
+	for i, idx := range table.Schema.Indexes {
+		if idx != nil && strings.EqualFold(idx.Name, indexName) {
+			pos = i
+			break
 		}
+	}
+	if pos < 0 {
+		return DB_NOT_FOUND
+	}
+	index := table.Schema.Indexes[pos]
+	if index.Clustered {
+		return DB_CANNOT_DROP_CONSTRAINT
+	}
+	if err := writeDDLLogEntry(ddlLogEntry{Op: ddlOpDropIndex, Table: table.Schema.Name, Index: index.Name}); err != nil {
 		return DB_ERROR
 	}
-	if err := persistSecondaryIndex(table, index); err != DB_SUCCESS {
+	defer clearDDLLog()
+	if table.Store != nil {
 		table.Store.RemoveSecondaryIndex(index.Name)
-		return err
+	}
+	table.Schema.Indexes = append(table.Schema.Indexes[:pos], table.Schema.Indexes[pos+1:]...)
+	if dictTable := dict.DictTableGet(table.Schema.Name); dictTable != nil {
+		dropDictIndex(dictTable, index.Name)
 	}
 	return DB_SUCCESS
 }
@@ -102,6 +151,7 @@ func CursorOpenIndexUsingName(crsr *Cursor, indexName string, out **Cursor) ErrC
 			if tree != nil {
 				cursor.pcur = btr.NewPcur(tree)
 			}
+			cursorOpened(cursor)
 			*out = cursor
 			return DB_SUCCESS
 		}
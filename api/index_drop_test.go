@@ -0,0 +1,208 @@
+package api
+
+import (
+	"testing"
+	"time"
+
+	"github.com/wilhasse/innodb-go/dict"
+	ibos "github.com/wilhasse/innodb-go/os"
+)
+
+func startupInDir(t *testing.T, dataDir string) {
+	t.Helper()
+	if err := Init(); err != DB_SUCCESS {
+		t.Fatalf("Init: %v", err)
+	}
+	if err := CfgSet("data_home_dir", dataDir); err != DB_SUCCESS {
+		t.Fatalf("CfgSet data_home_dir: %v", err)
+	}
+	if err := CfgSet("data_file_path", "ibdata1:4M:autoextend"); err != DB_SUCCESS {
+		t.Fatalf("CfgSet data_file_path: %v", err)
+	}
+	if err := Startup(""); err != DB_SUCCESS {
+		t.Fatalf("Startup: %v", err)
+	}
+}
+
+func createIndexDropTable(t *testing.T, tableName string) {
+	t.Helper()
+	var schema *TableSchema
+	if err := TableSchemaCreate(tableName, &schema, IB_TBL_COMPACT, 0); err != DB_SUCCESS {
+		t.Fatalf("TableSchemaCreate: %v", err)
+	}
+	if err := TableSchemaAddCol(schema, "c1", IB_INT, IB_COL_UNSIGNED, 0, 4); err != DB_SUCCESS {
+		t.Fatalf("TableSchemaAddCol c1: %v", err)
+	}
+	if err := TableSchemaAddCol(schema, "c2", IB_INT, IB_COL_UNSIGNED, 0, 4); err != DB_SUCCESS {
+		t.Fatalf("TableSchemaAddCol c2: %v", err)
+	}
+	var idx *IndexSchema
+	if err := TableSchemaAddIndex(schema, "PRIMARY", &idx); err != DB_SUCCESS {
+		t.Fatalf("TableSchemaAddIndex: %v", err)
+	}
+	if err := IndexSchemaAddCol(idx, "c1", 0); err != DB_SUCCESS {
+		t.Fatalf("IndexSchemaAddCol: %v", err)
+	}
+	if err := IndexSchemaSetClustered(idx); err != DB_SUCCESS {
+		t.Fatalf("IndexSchemaSetClustered: %v", err)
+	}
+	if err := TableCreate(nil, schema, nil); err != DB_SUCCESS {
+		t.Fatalf("TableCreate: %v", err)
+	}
+	var sec *IndexSchema
+	if err := IndexSchemaCreate(nil, "idx_c2", tableName, &sec); err != DB_SUCCESS {
+		t.Fatalf("IndexSchemaCreate: %v", err)
+	}
+	if err := IndexSchemaAddCol(sec, "c2", 0); err != DB_SUCCESS {
+		t.Fatalf("IndexSchemaAddCol sec: %v", err)
+	}
+	if err := IndexCreate(sec, nil); err != DB_SUCCESS {
+		t.Fatalf("IndexCreate: %v", err)
+	}
+}
+
+func assertIndexDropped(t *testing.T, tableName, indexName string) {
+	t.Helper()
+	dictTable := dict.DictTableGet(tableName)
+	if dictTable == nil {
+		t.Fatalf("table %s missing from dict", tableName)
+	}
+	if _, ok := dictTable.Indexes[indexName]; ok {
+		t.Fatalf("index %s still in dict", indexName)
+	}
+	var crsr *Cursor
+	if err := CursorOpenTable(tableName, nil, &crsr); err != DB_SUCCESS {
+		t.Fatalf("CursorOpenTable: %v", err)
+	}
+	var idxCur *Cursor
+	if err := CursorOpenIndexUsingName(crsr, indexName, &idxCur); err != DB_NOT_FOUND {
+		t.Fatalf("CursorOpenIndexUsingName=%v, want DB_NOT_FOUND", err)
+	}
+	if crsr.Table.Store.SecondaryIndex(indexName) != nil {
+		t.Fatalf("index %s still in row store", indexName)
+	}
+}
+
+func TestIndexDrop(t *testing.T) {
+	resetAPIState()
+	dataDir := t.TempDir() + "/"
+	tableName := "index_drop_db/t1"
+
+	startupInDir(t, dataDir)
+	if err := DatabaseCreate("index_drop_db"); err != DB_SUCCESS {
+		t.Fatalf("DatabaseCreate: %v", err)
+	}
+	createIndexDropTable(t, tableName)
+
+	var crsr *Cursor
+	if err := CursorOpenTable(tableName, nil, &crsr); err != DB_SUCCESS {
+		t.Fatalf("CursorOpenTable: %v", err)
+	}
+	for i := uint32(1); i <= 3; i++ {
+		if err := insertU32Row(crsr, i, 10-i); err != DB_SUCCESS {
+			t.Fatalf("insert %d: %v", i, err)
+		}
+	}
+
+	if err := IndexDrop(nil, tableName, "missing"); err != DB_NOT_FOUND {
+		t.Fatalf("IndexDrop missing=%v, want DB_NOT_FOUND", err)
+	}
+	if err := IndexDrop(nil, tableName, "PRIMARY"); err != DB_CANNOT_DROP_CONSTRAINT {
+		t.Fatalf("IndexDrop PRIMARY=%v, want DB_CANNOT_DROP_CONSTRAINT", err)
+	}
+	if err := IndexDrop(nil, tableName, "idx_c2"); err != DB_SUCCESS {
+		t.Fatalf("IndexDrop: %v", err)
+	}
+	assertIndexDropped(t, tableName, "idx_c2")
+	if exists, _ := ibos.FileExists(ddlLogPath()); exists {
+		t.Fatalf("expected ddl log cleared")
+	}
+	if err := insertU32Row(crsr, 4, 6); err != DB_SUCCESS {
+		t.Fatalf("insert after drop: %v", err)
+	}
+	if err := Shutdown(ShutdownNormal); err != DB_SUCCESS {
+		t.Fatalf("Shutdown: %v", err)
+	}
+
+	startupInDir(t, dataDir)
+	assertIndexDropped(t, tableName, "idx_c2")
+	if err := Shutdown(ShutdownNormal); err != DB_SUCCESS {
+		t.Fatalf("Shutdown restart: %v", err)
+	}
+}
+
+func TestDDLLogDropIndexRecovery(t *testing.T) {
+	resetAPIState()
+	dataDir := t.TempDir() + "/"
+	tableName := "index_drop_rec_db/t1"
+
+	startupInDir(t, dataDir)
+	if err := DatabaseCreate("index_drop_rec_db"); err != DB_SUCCESS {
+		t.Fatalf("DatabaseCreate: %v", err)
+	}
+	createIndexDropTable(t, tableName)
+	if _, ok := dict.DictTableGet(tableName).Indexes["idx_c2"]; !ok {
+		t.Fatalf("expected idx_c2 in dict before recovery")
+	}
+
+	if err := writeDDLLogEntry(ddlLogEntry{Op: ddlOpDropIndex, Table: tableName, Index: "idx_c2"}); err != nil {
+		t.Fatalf("write ddl log: %v", err)
+	}
+	if err := Shutdown(ShutdownNormal); err != DB_SUCCESS {
+		t.Fatalf("Shutdown: %v", err)
+	}
+
+	startupInDir(t, dataDir)
+	assertIndexDropped(t, tableName, "idx_c2")
+	if _, ok := dict.DictTableGet(tableName).Indexes["PRIMARY"]; !ok {
+		t.Fatalf("expected clustered index kept")
+	}
+	if exists, _ := ibos.FileExists(ddlLogPath()); exists {
+		t.Fatalf("expected ddl log cleared")
+	}
+	if err := Shutdown(ShutdownNormal); err != DB_SUCCESS {
+		t.Fatalf("Shutdown restart: %v", err)
+	}
+}
+
+func TestIndexDropWaitsForWriters(t *testing.T) {
+	resetAPIState()
+	dataDir := t.TempDir() + "/"
+	tableName := "index_drop_lock_db/t1"
+
+	startupInDir(t, dataDir)
+	defer func() { _ = Shutdown(ShutdownNormal) }()
+	if err := DatabaseCreate("index_drop_lock_db"); err != DB_SUCCESS {
+		t.Fatalf("DatabaseCreate: %v", err)
+	}
+	createIndexDropTable(t, tableName)
+
+	writer := TrxBegin(IB_TRX_REPEATABLE_READ)
+	writeCur := openLockingCursor(t, tableName, writer, LockIX)
+	if err := insertU32Row(writeCur, 1, 9); err != DB_SUCCESS {
+		t.Fatalf("insert: %v", err)
+	}
+	ddl := TrxBegin(IB_TRX_REPEATABLE_READ)
+	done := make(chan ErrCode, 1)
+	go func() {
+		done <- IndexDrop(ddl, tableName, "idx_c2")
+	}()
+	select {
+	case err := <-done:
+		t.Fatalf("IndexDrop finished while a writer held the table: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	if err := TrxCommit(writer); err != DB_SUCCESS {
+		t.Fatalf("TrxCommit writer: %v", err)
+	}
+	if err := waitErr(t, done, time.Second); err != DB_SUCCESS {
+		t.Fatalf("IndexDrop after commit=%v, want DB_SUCCESS", err)
+	}
+	if !isSchemaLocked(ddl) {
+		t.Fatalf("IndexDrop did not take the schema lock")
+	}
+	if err := TrxCommit(ddl); err != DB_SUCCESS {
+		t.Fatalf("TrxCommit ddl: %v", err)
+	}
+	assertIndexDropped(t, tableName, "idx_c2")
+}
@@ -0,0 +1,170 @@
+package api
+
+import (
+	"errors"
+	"sync/atomic"
+
+	"github.com/wilhasse/innodb-go/lock"
+	"github.com/wilhasse/innodb-go/row"
+	"github.com/wilhasse/innodb-go/trx"
+)
+
+// onlineIndexLogPasses bounds how often the row log is drained while DML runs
+// before the build moves on to the final switch.
+const onlineIndexLogPasses = 8
+
+// onlineIndexLogBatch is the row log size small enough to apply during the
+// final switch.
+const onlineIndexLogBatch = 64
+
+// IndexCreateOnline builds a secondary index while DML on the table continues.
+// A shared table lock waits out the transactions writing to the table while
+// row log capture starts and the clustered index is read through a fresh
+// read view of ibTrx; inserts, updates and deletes made during the scan are
+// replayed into the new index. The index becomes visible under a brief
+// exclusive schema lock and table lock held by ibTrx, which are released
+// again unless ibTrx already held them.
+func IndexCreateOnline(ibTrx *trx.Trx, index *IndexSchema, indexID *uint64) ErrCode {
+	if ibTrx == nil || index == nil {
+		return DB_ERROR
+	}
+	if indexID != nil {
+		*indexID = atomic.AddUint64(&nextIndexID, 1)
+	}
+	schemaMu.Lock()
+	table := findTableBySchemaLocked(index.Table)
+	if table == nil || table.Store == nil {
+		schemaMu.Unlock()
+		return DB_TABLE_NOT_FOUND
+	}
+	store := table.Store
+	if index.Clustered {
+		schemaMu.Unlock()
+		return indexCreateSwitch(ibTrx, table, store, index, nil)
+	}
+	fields, posErr := indexColumnPositions(index.Table, index)
+	schemaMu.Unlock()
+	if posErr != nil {
+		return DB_SCHEMA_ERROR
+	}
+
+	build, errCode := beginOnlineIndex(ibTrx, table, store, index, fields)
+	if errCode != DB_SUCCESS {
+		return errCode
+	}
+	if build == nil {
+		return indexCreateSwitch(ibTrx, table, store, index, nil)
+	}
+	if err := build.Build(); err != nil {
+		build.Abort()
+		return onlineIndexErr(err)
+	}
+	for pass := 0; pass < onlineIndexLogPasses; pass++ {
+		applied, err := build.ApplyLog()
+		if err != nil {
+			build.Abort()
+			return onlineIndexErr(err)
+		}
+		if applied <= onlineIndexLogBatch {
+			break
+		}
+	}
+	return indexCreateSwitch(ibTrx, table, store, index, build)
+}
+
+// beginOnlineIndex starts row log capture while ibTrx holds a shared table
+// lock, so every change the read view misses is in the row log. It returns a
+// nil build when the store already has the index.
+func beginOnlineIndex(ibTrx *trx.Trx, table *Table, store *row.Store, index *IndexSchema, fields []int) (*row.OnlineIndexBuild, ErrCode) {
+	unlock, errCode := lockTableBriefly(ibTrx, table, lock.ModeS)
+	if errCode != DB_SUCCESS {
+		return nil, errCode
+	}
+	defer unlock()
+	view := trx.TrxOpenReadView(ibTrx)
+	defer trx.TrxCloseView(view)
+	build, err := store.BeginOnlineIndex(index.Name, fields, index.Prefixes, index.Unique, view)
+	if errors.Is(err, row.ErrSecondaryIndexExists) {
+		return nil, DB_SUCCESS
+	}
+	if err != nil {
+		return nil, DB_ERROR
+	}
+	return build, DB_SUCCESS
+}
+
+// indexCreateSwitch publishes a built index in the store and the table
+// schema and records it in the data dictionary under an exclusive schema
+// lock and table lock.
+func indexCreateSwitch(ibTrx *trx.Trx, table *Table, store *row.Store, index *IndexSchema, build *row.OnlineIndexBuild) ErrCode {
+	held := isSchemaLocked(ibTrx)
+	if err := SchemaLockExclusive(ibTrx); err != DB_SUCCESS {
+		build.Abort()
+		return err
+	}
+	if !held {
+		defer clearSchemaLock(ibTrx)
+	}
+	unlock, err := lockTableBriefly(ibTrx, table, lock.ModeX)
+	if err != DB_SUCCESS {
+		build.Abort()
+		return err
+	}
+	defer unlock()
+	schemaMu.Lock()
+	defer schemaMu.Unlock()
+	if findTableBySchemaLocked(index.Table) != table || table.Store != store {
+		build.Abort()
+		return DB_TABLE_NOT_FOUND
+	}
+	if build != nil {
+		if err := build.Finish(); err != nil {
+			return onlineIndexErr(err)
+		}
+	}
+	if !index.Clustered {
+		if err := persistSecondaryIndex(table, index); err != DB_SUCCESS {
+			store.RemoveSecondaryIndex(index.Name)
+			return err
+		}
+	}
+	publishIndexSchema(table, index)
+	return DB_SUCCESS
+}
+
+// publishIndexSchema adds index to the table schema unless it is listed
+// already. It assumes schemaMu is held, and for a secondary index an
+// exclusive table lock.
+func publishIndexSchema(table *Table, index *IndexSchema) {
+	for _, idx := range table.Schema.Indexes {
+		if idx == index {
+			return
+		}
+	}
+	table.Schema.Indexes = append(table.Schema.Indexes, index)
+}
+
+// lockTableBriefly locks table in mode for ibTrx and returns the function
+// that releases the lock. A transaction that already locks the table keeps
+// its lock, strengthened to mode, until it ends.
+func lockTableBriefly(ibTrx *trx.Trx, table *Table, mode lock.Mode) (func(), ErrCode) {
+	name := tableLockName(table)
+	held := lock.TableLocked(ibTrx, name)
+	if _, status := lock.LockTable(ibTrx, name, mode); status != lock.LockGranted {
+		return nil, lockStatusToErr(status)
+	}
+	if held {
+		return func() {}, DB_SUCCESS
+	}
+	return func() { lock.UnlockTable(ibTrx, name) }, DB_SUCCESS
+}
+
+func onlineIndexErr(err error) ErrCode {
+	if errors.Is(err, row.ErrDuplicateKey) {
+		return DB_DUPLICATE_KEY
+	}
+	if errors.Is(err, row.ErrOnlineBuildAborted) {
+		return DB_INTERRUPTED
+	}
+	return DB_ERROR
+}
@@ -0,0 +1,252 @@
+package api
+
+import (
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/wilhasse/innodb-go/lock"
+)
+
+func runU32Writer(tableName string, stop <-chan struct{}, started chan<- struct{}) ErrCode {
+	for i := uint32(0); ; i++ {
+		select {
+		case <-stop:
+			return DB_SUCCESS
+		default:
+		}
+		ibTrx := TrxBegin(IB_TRX_REPEATABLE_READ)
+		var crsr *Cursor
+		if err := CursorOpenTable(tableName, ibTrx, &crsr); err != DB_SUCCESS {
+			return err
+		}
+		if err := insertU32Row(crsr, 1000+i, i); err != DB_SUCCESS {
+			return err
+		}
+		if err := updateU32Row(crsr, i%200+1, 50000+i); err != DB_SUCCESS {
+			return err
+		}
+		if i%3 == 0 {
+			if err := moveToU32Key(crsr, 1000+i/3); err != DB_SUCCESS {
+				return err
+			}
+			if err := CursorDeleteRow(crsr); err != DB_SUCCESS {
+				return err
+			}
+		}
+		_ = CursorClose(crsr)
+		if err := TrxCommit(ibTrx); err != DB_SUCCESS {
+			return err
+		}
+		if i == 10 {
+			close(started)
+		}
+	}
+}
+
+func TestIndexCreateOnlineConcurrentDML(t *testing.T) {
+	tableName := setupU32Table(t, "online_index_db")
+	keys := make([]uint32, 0, 200)
+	for i := uint32(1); i <= 200; i++ {
+		keys = append(keys, i)
+	}
+	seedU32Rows(t, tableName, keys...)
+
+	stop := make(chan struct{})
+	started := make(chan struct{})
+	var wg sync.WaitGroup
+	var writerErr ErrCode
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		writerErr = runU32Writer(tableName, stop, started)
+	}()
+	<-started
+
+	var sec *IndexSchema
+	if err := IndexSchemaCreate(nil, "idx_c2", tableName, &sec); err != DB_SUCCESS {
+		t.Fatalf("IndexSchemaCreate: %v", err)
+	}
+	if err := IndexSchemaAddCol(sec, "c2", 0); err != DB_SUCCESS {
+		t.Fatalf("IndexSchemaAddCol: %v", err)
+	}
+	ddlTrx := TrxBegin(IB_TRX_REPEATABLE_READ)
+	if err := IndexCreateOnline(ddlTrx, sec, nil); err != DB_SUCCESS {
+		t.Fatalf("IndexCreateOnline: %v", err)
+	}
+	if isSchemaLocked(ddlTrx) {
+		t.Fatalf("schema lock held after online build")
+	}
+	if err := TrxCommit(ddlTrx); err != DB_SUCCESS {
+		t.Fatalf("TrxCommit ddl: %v", err)
+	}
+	close(stop)
+	wg.Wait()
+	if writerErr != DB_SUCCESS {
+		t.Fatalf("writer: %v", writerErr)
+	}
+
+	var crsr *Cursor
+	if err := CursorOpenTable(tableName, nil, &crsr); err != DB_SUCCESS {
+		t.Fatalf("CursorOpenTable: %v", err)
+	}
+	defer CursorClose(crsr)
+	clustKeys, clustVals, err := scanU32Rows(crsr)
+	if err != DB_SUCCESS {
+		t.Fatalf("scan clustered: %v", err)
+	}
+	var secCur *Cursor
+	if err := CursorOpenIndexUsingName(crsr, "idx_c2", &secCur); err != DB_SUCCESS {
+		t.Fatalf("CursorOpenIndexUsingName: %v", err)
+	}
+	secKeys, secVals, err := scanU32Rows(secCur)
+	if err != DB_SUCCESS {
+		t.Fatalf("scan secondary: %v", err)
+	}
+	if !sort.SliceIsSorted(secVals, func(i, j int) bool { return secVals[i] < secVals[j] }) {
+		t.Fatalf("secondary scan not ordered by c2: %v", secVals)
+	}
+	want := make(map[[2]uint32]int, len(clustKeys))
+	for i := range clustKeys {
+		want[[2]uint32{clustKeys[i], clustVals[i]}]++
+	}
+	got := make(map[[2]uint32]int, len(secKeys))
+	for i := range secKeys {
+		got[[2]uint32{secKeys[i], secVals[i]}]++
+	}
+	if len(got) != len(want) || len(secKeys) != len(clustKeys) {
+		t.Fatalf("secondary rows=%d clustered rows=%d", len(secKeys), len(clustKeys))
+	}
+	for pair, n := range want {
+		if got[pair] != n {
+			t.Fatalf("row %v missing from secondary index", pair)
+		}
+	}
+}
+
+func TestIndexCreateOnlineKeepsCallerSchemaLock(t *testing.T) {
+	tableName := setupU32Table(t, "online_index_lock_db")
+	seedU32Rows(t, tableName, 1, 2, 3)
+	var sec *IndexSchema
+	if err := IndexSchemaCreate(nil, "idx_c2", tableName, &sec); err != DB_SUCCESS {
+		t.Fatalf("IndexSchemaCreate: %v", err)
+	}
+	if err := IndexSchemaAddCol(sec, "c2", 0); err != DB_SUCCESS {
+		t.Fatalf("IndexSchemaAddCol: %v", err)
+	}
+	ddlTrx := TrxBegin(IB_TRX_REPEATABLE_READ)
+	if err := SchemaLockExclusive(ddlTrx); err != DB_SUCCESS {
+		t.Fatalf("SchemaLockExclusive: %v", err)
+	}
+	if err := IndexCreateOnline(ddlTrx, sec, nil); err != DB_SUCCESS {
+		t.Fatalf("IndexCreateOnline: %v", err)
+	}
+	if !isSchemaLocked(ddlTrx) {
+		t.Fatalf("caller schema lock released by online build")
+	}
+	if err := TrxCommit(ddlTrx); err != DB_SUCCESS {
+		t.Fatalf("TrxCommit: %v", err)
+	}
+	if IndexCreateOnline(nil, sec, nil) != DB_ERROR {
+		t.Fatalf("expected DB_ERROR without a transaction")
+	}
+}
+
+func TestIndexCreateOnlinePublishesSchema(t *testing.T) {
+	tableName := setupU32Table(t, "online_index_publish_db")
+	seedU32Rows(t, tableName, 1, 2, 3)
+	var sec *IndexSchema
+	if err := IndexSchemaCreate(nil, "idx_c2", tableName, &sec); err != DB_SUCCESS {
+		t.Fatalf("IndexSchemaCreate: %v", err)
+	}
+	if err := IndexSchemaAddCol(sec, "c2", 0); err != DB_SUCCESS {
+		t.Fatalf("IndexSchemaAddCol: %v", err)
+	}
+	listed := func() int {
+		n := 0
+		for _, idx := range findTable(tableName).Schema.Indexes {
+			if idx == sec {
+				n++
+			}
+		}
+		return n
+	}
+	if listed() != 0 {
+		t.Fatalf("index listed in the table schema before it is built")
+	}
+	if err := IndexCreate(sec, nil); err != DB_SUCCESS {
+		t.Fatalf("IndexCreate: %v", err)
+	}
+	if listed() != 1 {
+		t.Fatalf("index listed %d times after build, want 1", listed())
+	}
+}
+
+func TestIndexCreateExistingCaller(t *testing.T) {
+	tableName := setupU32Table(t, "index_create_caller_db")
+	seedU32Rows(t, tableName, 1, 2, 3)
+	listed := func(index *IndexSchema) bool {
+		for _, idx := range findTable(tableName).Schema.Indexes {
+			if idx == index {
+				return true
+			}
+		}
+		return false
+	}
+
+	var sec *IndexSchema
+	if err := IndexSchemaCreate(nil, "idx_c2", tableName, &sec); err != DB_SUCCESS {
+		t.Fatalf("IndexSchemaCreate: %v", err)
+	}
+	if err := IndexSchemaAddCol(sec, "c2", 0); err != DB_SUCCESS {
+		t.Fatalf("IndexSchemaAddCol: %v", err)
+	}
+	var id uint64
+	if err := IndexCreate(sec, &id); err != DB_SUCCESS || id == 0 {
+		t.Fatalf("IndexCreate: err=%v id=%d", err, id)
+	}
+	if !listed(sec) {
+		t.Fatalf("secondary index not listed after IndexCreate")
+	}
+	ibTrx := TrxBegin(IB_TRX_REPEATABLE_READ)
+	var crsr, secCur *Cursor
+	if err := CursorOpenTable(tableName, ibTrx, &crsr); err != DB_SUCCESS {
+		t.Fatalf("CursorOpenTable: %v", err)
+	}
+	if err := CursorOpenIndexUsingName(crsr, "idx_c2", &secCur); err != DB_SUCCESS {
+		t.Fatalf("CursorOpenIndexUsingName: %v", err)
+	}
+	if keys, _, err := scanU32Rows(secCur); err != DB_SUCCESS || len(keys) != 3 {
+		t.Fatalf("index scan keys=%v err=%v", keys, err)
+	}
+	_ = CursorClose(secCur)
+	_ = CursorClose(crsr)
+	if err := TrxCommit(ibTrx); err != DB_SUCCESS {
+		t.Fatalf("TrxCommit: %v", err)
+	}
+
+	// A clustered index is only registered, so it does not wait for a
+	// transaction that locks the table.
+	holder := TrxBegin(IB_TRX_REPEATABLE_READ)
+	unlock, err := lockTableBriefly(holder, findTable(tableName), lock.ModeX)
+	if err != DB_SUCCESS {
+		t.Fatalf("lock table: %v", err)
+	}
+	var clust *IndexSchema
+	if err := IndexSchemaCreate(nil, "clust", tableName, &clust); err != DB_SUCCESS {
+		t.Fatalf("IndexSchemaCreate clustered: %v", err)
+	}
+	if err := IndexSchemaAddCol(clust, "c1", 0); err != DB_SUCCESS {
+		t.Fatalf("IndexSchemaAddCol: %v", err)
+	}
+	if err := IndexSchemaSetClustered(clust); err != DB_SUCCESS {
+		t.Fatalf("IndexSchemaSetClustered: %v", err)
+	}
+	if err := IndexCreate(clust, &id); err != DB_SUCCESS || !listed(clust) {
+		t.Fatalf("IndexCreate clustered: err=%v listed=%v", err, listed(clust))
+	}
+	unlock()
+	if err := TrxCommit(holder); err != DB_SUCCESS {
+		t.Fatalf("TrxCommit holder: %v", err)
+	}
+}
@@ -0,0 +1,115 @@
+package api
+
+import "testing"
+
+func TestReadUncommittedSeesDirtyRows(t *testing.T) {
+	tableName := setupU32Table(t, "iso_ru_db")
+	seedU32Rows(t, tableName, 1)
+
+	reader := TrxBegin(IB_TRX_READ_UNCOMMITTED)
+	if got := TrxIsolationGet(reader); got != IB_TRX_READ_UNCOMMITTED {
+		t.Fatalf("isolation=%v want IB_TRX_READ_UNCOMMITTED", got)
+	}
+	var readCur *Cursor
+	if err := CursorOpenTable(tableName, reader, &readCur); err != DB_SUCCESS {
+		t.Fatalf("CursorOpenTable reader: %v", err)
+	}
+
+	writer := TrxBegin(IB_TRX_REPEATABLE_READ)
+	writeCur := openLockingCursor(t, tableName, writer, LockIX)
+	if err := updateU32Row(writeCur, 1, 111); err != DB_SUCCESS {
+		t.Fatalf("update: %v", err)
+	}
+	if err := insertU32Row(writeCur, 2, 222); err != DB_SUCCESS {
+		t.Fatalf("insert: %v", err)
+	}
+
+	keys, values, err := scanU32Rows(readCur)
+	if err != DB_SUCCESS {
+		t.Fatalf("scan: %v", err)
+	}
+	assertU32Keys(t, keys, []uint32{1, 2})
+	assertU32Keys(t, values, []uint32{111, 222})
+
+	if err := TrxRollback(writer); err != DB_SUCCESS {
+		t.Fatalf("TrxRollback writer: %v", err)
+	}
+	keys, values, err = scanU32Rows(readCur)
+	if err != DB_SUCCESS {
+		t.Fatalf("scan after rollback: %v", err)
+	}
+	assertU32Keys(t, keys, []uint32{1})
+	assertU32Keys(t, values, []uint32{100})
+	_ = TrxCommit(reader)
+}
+
+func TestReadCommittedRefreshesViewPerCursor(t *testing.T) {
+	tableName := setupU32Table(t, "iso_rc_db")
+	seedU32Rows(t, tableName, 1)
+
+	rc := TrxBegin(IB_TRX_READ_COMMITTED)
+	rr := TrxBegin(IB_TRX_REPEATABLE_READ)
+	var rcCur, rrCur *Cursor
+	if err := CursorOpenTable(tableName, rc, &rcCur); err != DB_SUCCESS {
+		t.Fatalf("CursorOpenTable rc: %v", err)
+	}
+	if err := CursorOpenTable(tableName, rr, &rrCur); err != DB_SUCCESS {
+		t.Fatalf("CursorOpenTable rr: %v", err)
+	}
+
+	writer := TrxBegin(IB_TRX_REPEATABLE_READ)
+	writeCur := openLockingCursor(t, tableName, writer, LockIX)
+	if err := updateU32Row(writeCur, 1, 111); err != DB_SUCCESS {
+		t.Fatalf("update: %v", err)
+	}
+
+	_, values, err := scanU32Rows(rcCur)
+	if err != DB_SUCCESS {
+		t.Fatalf("rc scan: %v", err)
+	}
+	assertU32Keys(t, values, []uint32{100})
+
+	if err := TrxCommit(writer); err != DB_SUCCESS {
+		t.Fatalf("TrxCommit writer: %v", err)
+	}
+
+	_, values, err = scanU32Rows(rcCur)
+	if err != DB_SUCCESS {
+		t.Fatalf("rc rescan: %v", err)
+	}
+	assertU32Keys(t, values, []uint32{100})
+
+	var rcCur2, rrCur2 *Cursor
+	if err := CursorOpenTable(tableName, rc, &rcCur2); err != DB_SUCCESS {
+		t.Fatalf("CursorOpenTable rc2: %v", err)
+	}
+	_, values, err = scanU32Rows(rcCur2)
+	if err != DB_SUCCESS {
+		t.Fatalf("rc scan new cursor: %v", err)
+	}
+	assertU32Keys(t, values, []uint32{111})
+
+	if err := CursorOpenTable(tableName, rr, &rrCur2); err != DB_SUCCESS {
+		t.Fatalf("CursorOpenTable rr2: %v", err)
+	}
+	_, values, err = scanU32Rows(rrCur2)
+	if err != DB_SUCCESS {
+		t.Fatalf("rr scan new cursor: %v", err)
+	}
+	assertU32Keys(t, values, []uint32{100})
+
+	_ = TrxCommit(rc)
+	_ = TrxCommit(rr)
+}
+
+func TestTrxIsolationValuesStable(t *testing.T) {
+	if IB_TRX_REPEATABLE_READ != 0 || IB_TRX_SERIALIZABLE != 1 {
+		t.Fatalf("original isolation levels renumbered")
+	}
+	var level TrxIsolation
+	ibTrx := TrxBegin(level)
+	if got := TrxIsolationGet(ibTrx); got != IB_TRX_REPEATABLE_READ {
+		t.Fatalf("zero isolation=%v want IB_TRX_REPEATABLE_READ", got)
+	}
+	_ = TrxCommit(ibTrx)
+}
@@ -32,6 +32,20 @@ func lockRecordForDML(crsr *Cursor, tpl *data.Tuple, mode lock.Mode, flags lock.
 	return lockStatusToErr(status)
 }
 
+// lockInsertGap takes an insert intention lock on the gap the row lands in,
+// which is owned by the next row or by the supremum past the last row.
+func lockInsertGap(crsr *Cursor, tpl *data.Tuple) ErrCode {
+	if crsr == nil || crsr.Table == nil || crsr.Table.Store == nil || crsr.Trx == nil {
+		return DB_SUCCESS
+	}
+	gapKey := lockSupremumKey(crsr.Table)
+	if next, ok := crsr.Table.Store.NextKey(primaryKeyBytes(crsr.Table.Store, tpl)); ok {
+		gapKey = lockRecordKeyFromBytes(crsr.Table, next)
+	}
+	_, status := lock.LockRecWithFlags(crsr.Trx, gapKey, lock.ModeX, lock.FlagInsertIntention)
+	return lockStatusToErr(status)
+}
+
 func lockStatusToErr(status lock.Status) ErrCode {
 	switch status {
 	case lock.LockGranted:
@@ -67,6 +81,11 @@ func lockRecordKey(table *Table, tpl *data.Tuple) lock.RecordKey {
 	return lockRecordKeyFromBytes(table, key)
 }
 
+// lockSupremumKey names the gap after the last row of a table.
+func lockSupremumKey(table *Table) lock.RecordKey {
+	return lockRecordKeyFromBytes(table, nil)
+}
+
 func lockRecordKeyFromBytes(table *Table, key []byte) lock.RecordKey {
 	name := tableLockName(table)
 	hasher := fnv.New32a()
@@ -83,7 +102,7 @@ func lockGapForKey(crsr *Cursor, key []byte) ErrCode {
 	if crsr == nil || crsr.Table == nil || crsr.Trx == nil {
 		return DB_SUCCESS
 	}
-	if crsr.LockMode != LockIX {
+	if crsr.LockMode != LockIX && !cursorLockingRead(crsr) {
 		return DB_SUCCESS
 	}
 	lockKey := lockRecordKeyFromBytes(crsr.Table, key)
@@ -0,0 +1,190 @@
+package api
+
+import (
+	"testing"
+	"time"
+
+	"github.com/wilhasse/innodb-go/trx"
+)
+
+func updateU32Row(crsr *Cursor, key, val uint32) ErrCode {
+	oldTpl := ClustSearchTupleCreate(crsr)
+	if oldTpl == nil {
+		return DB_ERROR
+	}
+	if err := TupleWriteU32(oldTpl, 0, key); err != DB_SUCCESS {
+		return err
+	}
+	newTpl := ClustReadTupleCreate(crsr)
+	if newTpl == nil {
+		return DB_ERROR
+	}
+	if err := TupleWriteU32(newTpl, 0, key); err != DB_SUCCESS {
+		return err
+	}
+	if err := TupleWriteU32(newTpl, 1, val); err != DB_SUCCESS {
+		return err
+	}
+	return CursorUpdateRow(crsr, oldTpl, newTpl)
+}
+
+func moveToU32Key(crsr *Cursor, key uint32) ErrCode {
+	search := ClustSearchTupleCreate(crsr)
+	if search == nil {
+		return DB_ERROR
+	}
+	defer TupleDelete(search)
+	if err := TupleWriteU32(search, 0, key); err != DB_SUCCESS {
+		return err
+	}
+	var ret int
+	return CursorMoveTo(crsr, search, CursorGE, &ret)
+}
+
+func seedU32Rows(t *testing.T, tableName string, keys ...uint32) {
+	t.Helper()
+	trx := TrxBegin(IB_TRX_REPEATABLE_READ)
+	var crsr *Cursor
+	if err := CursorOpenTable(tableName, trx, &crsr); err != DB_SUCCESS {
+		t.Fatalf("CursorOpenTable seed: %v", err)
+	}
+	for _, key := range keys {
+		if err := insertU32Row(crsr, key, key*100); err != DB_SUCCESS {
+			t.Fatalf("insert %d: %v", key, err)
+		}
+	}
+	if err := TrxCommit(trx); err != DB_SUCCESS {
+		t.Fatalf("TrxCommit seed: %v", err)
+	}
+}
+
+func openLockingCursor(t *testing.T, tableName string, trx *trx.Trx, mode LockMode) *Cursor {
+	t.Helper()
+	var crsr *Cursor
+	if err := CursorOpenTable(tableName, trx, &crsr); err != DB_SUCCESS {
+		t.Fatalf("CursorOpenTable: %v", err)
+	}
+	if err := CursorSetLockMode(crsr, mode); err != DB_SUCCESS {
+		t.Fatalf("CursorSetLockMode: %v", err)
+	}
+	return crsr
+}
+
+func TestLockingReadExclusiveBlocksUpdate(t *testing.T) {
+	tableName := setupU32Table(t, "locking_read_x_db")
+	seedU32Rows(t, tableName, 1, 2, 3)
+
+	reader := TrxBegin(IB_TRX_REPEATABLE_READ)
+	readCur := openLockingCursor(t, tableName, reader, LockX)
+	if err := moveToU32Key(readCur, 2); err != DB_SUCCESS {
+		t.Fatalf("CursorMoveTo: %v", err)
+	}
+	if got := readCurrentU32(t, readCur, 0); got != 2 {
+		t.Fatalf("locked key=%d want 2", got)
+	}
+
+	writer := TrxBegin(IB_TRX_REPEATABLE_READ)
+	writeCur := openLockingCursor(t, tableName, writer, LockIX)
+	done := make(chan ErrCode, 1)
+	go func() {
+		done <- updateU32Row(writeCur, 2, 222)
+	}()
+	select {
+	case err := <-done:
+		t.Fatalf("update finished while row was locked: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	if err := TrxCommit(reader); err != DB_SUCCESS {
+		t.Fatalf("TrxCommit reader: %v", err)
+	}
+	if err := waitErr(t, done, time.Second); err != DB_SUCCESS {
+		t.Fatalf("update after commit=%v, want DB_SUCCESS", err)
+	}
+	_ = TrxRollback(writer)
+}
+
+func TestLockingReadSharedCompatible(t *testing.T) {
+	tableName := setupU32Table(t, "locking_read_s_db")
+	seedU32Rows(t, tableName, 1, 2, 3)
+
+	trx1 := TrxBegin(IB_TRX_REPEATABLE_READ)
+	cur1 := openLockingCursor(t, tableName, trx1, LockS)
+	keys, _, err := scanU32Rows(cur1)
+	if err != DB_SUCCESS {
+		t.Fatalf("scan trx1: %v", err)
+	}
+	assertU32Keys(t, keys, []uint32{1, 2, 3})
+
+	trx2 := TrxBegin(IB_TRX_REPEATABLE_READ)
+	cur2 := openLockingCursor(t, tableName, trx2, LockS)
+	done := make(chan ErrCode, 1)
+	go func() {
+		_, _, err := scanU32Rows(cur2)
+		done <- err
+	}()
+	if err := waitErr(t, done, time.Second); err != DB_SUCCESS {
+		t.Fatalf("shared scan trx2=%v, want DB_SUCCESS", err)
+	}
+
+	writer := TrxBegin(IB_TRX_REPEATABLE_READ)
+	writeCur := openLockingCursor(t, tableName, writer, LockX)
+	xDone := make(chan ErrCode, 1)
+	go func() {
+		xDone <- moveToU32Key(writeCur, 1)
+	}()
+	select {
+	case err := <-xDone:
+		t.Fatalf("exclusive read finished while row was share locked: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	_ = TrxCommit(trx1)
+	_ = TrxCommit(trx2)
+	if err := waitErr(t, xDone, time.Second); err != DB_SUCCESS {
+		t.Fatalf("exclusive read after commit=%v, want DB_SUCCESS", err)
+	}
+	_ = TrxRollback(writer)
+}
+
+func TestLockingReadSeesLatestCommitted(t *testing.T) {
+	tableName := setupU32Table(t, "locking_read_latest_db")
+	seedU32Rows(t, tableName, 1, 2)
+
+	reader := TrxBegin(IB_TRX_REPEATABLE_READ)
+	var snapCur *Cursor
+	if err := CursorOpenTable(tableName, reader, &snapCur); err != DB_SUCCESS {
+		t.Fatalf("CursorOpenTable snapshot: %v", err)
+	}
+	_, values, err := scanU32Rows(snapCur)
+	if err != DB_SUCCESS {
+		t.Fatalf("snapshot scan: %v", err)
+	}
+	assertU32Keys(t, values, []uint32{100, 200})
+
+	writer := TrxBegin(IB_TRX_REPEATABLE_READ)
+	writeCur := openLockingCursor(t, tableName, writer, LockIX)
+	if err := updateU32Row(writeCur, 1, 111); err != DB_SUCCESS {
+		t.Fatalf("update: %v", err)
+	}
+	if err := insertU32Row(writeCur, 3, 300); err != DB_SUCCESS {
+		t.Fatalf("insert: %v", err)
+	}
+	if err := TrxCommit(writer); err != DB_SUCCESS {
+		t.Fatalf("TrxCommit writer: %v", err)
+	}
+
+	_, values, err = scanU32Rows(snapCur)
+	if err != DB_SUCCESS {
+		t.Fatalf("snapshot rescan: %v", err)
+	}
+	assertU32Keys(t, values, []uint32{100, 200})
+
+	lockCur := openLockingCursor(t, tableName, reader, LockS)
+	_, values, err = scanU32Rows(lockCur)
+	if err != DB_SUCCESS {
+		t.Fatalf("locking scan: %v", err)
+	}
+	assertU32Keys(t, values, []uint32{111, 200, 300})
+	if err := TrxCommit(reader); err != DB_SUCCESS {
+		t.Fatalf("TrxCommit reader: %v", err)
+	}
+}
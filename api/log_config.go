@@ -13,6 +13,8 @@ func configureLog() {
 	_ = CfgGet("log_buffer_size", &bufferSize)
 	var prealloc Bool
 	_ = CfgGet("file_preallocate", &prealloc)
+	var encrypt Bool
+	_ = CfgGet("encrypt_redo", &encrypt)
 	dataDir := dataHomeDir()
 	enabled := fileSize > 0 && (dataDir != "." || logDir != "")
 	log.Configure(log.Config{
@@ -23,5 +25,6 @@ func configureLog() {
 		Files:       int(files),
 		BufferSize:  uint64(bufferSize),
 		Preallocate: prealloc == IBTrue,
+		Encrypt:     encrypt == IBTrue,
 	})
 }
@@ -0,0 +1,233 @@
+package api
+
+import (
+	"sync"
+
+	"github.com/wilhasse/innodb-go/pars"
+	"github.com/wilhasse/innodb-go/que"
+	"github.com/wilhasse/innodb-go/trx"
+)
+
+// PreparedStmt is a statement parsed once by Prepare and run any number of
+// times with Execute, in the same or different transactions. Arguments are
+// bound with the same rules as SQLArg and stay bound between executions.
+// The query graph is built once and reads the bound literals each time it
+// runs; it is built again only when a literal changes type or signedness or
+// a table it reads changes, and binding an identifier re-parses the
+// statement on the next execution. Calls on one statement are serialized,
+// so it may be shared between goroutines.
+type PreparedStmt struct {
+	mu   sync.Mutex
+	sql  string
+	info *pars.Info
+	// stmt holds the parsed statement with its bound literals left as
+	// parameters; nil when an identifier changed since parsing.
+	stmt pars.Statement
+	// graph is the last graph built and tables the cursors and context it
+	// was built against; nil when a literal changed type since building.
+	graph  *que.Fork
+	tables *sqlTables
+	closed bool
+}
+
+// Prepare parses a SELECT, INSERT, UPDATE or DELETE statement for repeated
+// execution. Identifiers used as table or column names must be bound by
+// args; literals may be bound now or later with the Bind functions.
+func Prepare(sql string, args ...SQLArg) (*PreparedStmt, ErrCode) {
+	if !started {
+		return nil, DB_ERROR
+	}
+	info, err := execVSQL(sql, args)
+	if err != DB_SUCCESS {
+		return nil, err
+	}
+	s := &PreparedStmt{sql: sql, info: info}
+	if err := s.parse(); err != DB_SUCCESS {
+		return nil, err
+	}
+	return s, DB_SUCCESS
+}
+
+func (s *PreparedStmt) parse() ErrCode {
+	stmt, parseErr := pars.ParsePrepared(s.info, s.sql)
+	if parseErr != nil {
+		return DB_INVALID_INPUT
+	}
+	if _, isProc := stmt.(*pars.ProcedureStmt); isProc || isDDLStatement(stmt) || len(que.StatementTables(stmt)) == 0 {
+		return DB_UNSUPPORTED
+	}
+	s.stmt = stmt
+	return DB_SUCCESS
+}
+
+// Bind binds arguments built with the SQLArg functions.
+func (s *PreparedStmt) Bind(args ...SQLArg) ErrCode {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return DB_ERROR
+	}
+	for _, arg := range args {
+		if err := s.bind(arg); err != DB_SUCCESS {
+			return err
+		}
+	}
+	return DB_SUCCESS
+}
+
+// BindString binds a string literal (":name").
+func (s *PreparedStmt) BindString(name, value string) ErrCode {
+	return s.Bind(SQLArgString(name, value))
+}
+
+// BindChar binds a fixed-length string literal (":name").
+func (s *PreparedStmt) BindChar(name, value string) ErrCode {
+	return s.Bind(SQLArgChar(name, value))
+}
+
+// BindNull binds a SQL NULL literal (":name").
+func (s *PreparedStmt) BindNull(name string) ErrCode {
+	return s.Bind(SQLArgNull(name))
+}
+
+// BindID binds an identifier ("$name").
+func (s *PreparedStmt) BindID(name, value string) ErrCode {
+	return s.Bind(SQLArgID(name, value))
+}
+
+// BindIntSigned binds a signed integer literal.
+func (s *PreparedStmt) BindIntSigned(name string, length int, value int64) ErrCode {
+	return s.Bind(SQLArgIntSigned(name, length, value))
+}
+
+// BindIntUnsigned binds an unsigned integer literal.
+func (s *PreparedStmt) BindIntUnsigned(name string, length int, value uint64) ErrCode {
+	return s.Bind(SQLArgIntUnsigned(name, length, value))
+}
+
+// BindFunc binds a user function.
+func (s *PreparedStmt) BindFunc(name string, fn pars.UserFunc, arg any) ErrCode {
+	return s.Bind(SQLArgFunc(name, fn, arg))
+}
+
+// bind adds arg to the bindings and drops what it invalidates: the graph
+// when a literal is new or changes type or signedness, the parsed statement
+// when an identifier changes. A new value of a literal is read by the graph
+// when it next runs.
+func (s *PreparedStmt) bind(arg SQLArg) ErrCode {
+	name := trimNamePrefix(arg.Name)
+	oldLit, hadLit := s.info.Literals[name]
+	oldID, hadID := s.info.IDs[name]
+	if err := bindSQLArg(s.info, arg); err != DB_SUCCESS {
+		return err
+	}
+	if id, ok := s.info.IDs[name]; ok && (!hadID || id.ID != oldID.ID) {
+		s.stmt = nil
+		s.freeGraph()
+	}
+	if lit, ok := s.info.Literals[name]; ok {
+		if !hadLit || lit.Type != oldLit.Type || lit.Unsigned != oldLit.Unsigned {
+			s.freeGraph()
+		}
+	}
+	return DB_SUCCESS
+}
+
+// Execute runs the statement inside ibTrx. With a nil ibTrx it runs in its
+// own transaction that is committed on success and rolled back on failure.
+// Every literal of the statement must be bound.
+func (s *PreparedStmt) Execute(ibTrx *trx.Trx) (*SQLResult, ErrCode) {
+	if !started {
+		return nil, DB_ERROR
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil, DB_ERROR
+	}
+	autocommit := ibTrx == nil
+	if autocommit {
+		if ibTrx = TrxBegin(IB_TRX_REPEATABLE_READ); ibTrx == nil {
+			return nil, DB_ERROR
+		}
+	}
+	res, err := s.execute(ibTrx)
+	if !autocommit {
+		return res, err
+	}
+	if err != DB_SUCCESS {
+		_ = TrxRollback(ibTrx)
+		return nil, err
+	}
+	if err := TrxCommit(ibTrx); err != DB_SUCCESS {
+		return nil, err
+	}
+	return res, DB_SUCCESS
+}
+
+func (s *PreparedStmt) execute(ibTrx *trx.Trx) (*SQLResult, ErrCode) {
+	if s.stmt == nil {
+		if err := s.parse(); err != DB_SUCCESS {
+			return nil, err
+		}
+	}
+	tables, err := openSQLTables(ibTrx, s.stmt)
+	if err != DB_SUCCESS {
+		return nil, err
+	}
+	defer tables.close()
+	if !s.reuseGraph(tables) {
+		s.freeGraph()
+		tables.ctx.Params = s.info
+		graph, buildErr := que.BuildGraph(s.stmt, tables.ctx)
+		if buildErr != nil {
+			return nil, sqlErrCode(buildErr)
+		}
+		s.graph, s.tables = graph, tables
+	}
+	que.ForkStartCommand(s.graph)
+	if runErr := que.ForkRun(s.graph); runErr != nil {
+		return nil, sqlErrCode(runErr)
+	}
+	return sqlGraphResult(s.graph, s.tables.columns), DB_SUCCESS
+}
+
+// reuseGraph points the kept graph at the cursors of tables. It reports
+// false when there is no graph or a table was dropped, rebuilt or altered
+// since the graph was built.
+func (s *PreparedStmt) reuseGraph(tables *sqlTables) bool {
+	if s.graph == nil {
+		return false
+	}
+	for name, schema := range tables.schemas {
+		built, ok := s.tables.ctx.Tables[name]
+		if !ok || s.tables.schemas[name] != schema || built.Store != tables.ctx.Tables[name].Store {
+			return false
+		}
+	}
+	for name, access := range tables.access {
+		s.tables.access[name].crsr = access.crsr
+	}
+	return true
+}
+
+func (s *PreparedStmt) freeGraph() {
+	if s.graph != nil {
+		que.GraphFree(s.graph)
+	}
+	s.graph = nil
+	s.tables = nil
+}
+
+// Close releases the statement. Later calls on it return DB_ERROR.
+func (s *PreparedStmt) Close() ErrCode {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return DB_ERROR
+	}
+	s.freeGraph()
+	s.stmt = nil
+	s.closed = true
+	return DB_SUCCESS
+}
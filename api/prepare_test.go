@@ -0,0 +1,171 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/wilhasse/innodb-go/que"
+	"github.com/wilhasse/innodb-go/trx"
+)
+
+func TestPreparedStmtReuse(t *testing.T) {
+	tableName := setupU32Table(t, "prepare_db")
+	seedU32Rows(t, tableName, 1, 2, 3)
+
+	sel, err := Prepare(`SELECT * FROM $tbl WHERE c1 >= :lo ORDER BY c1`, SQLArgID("tbl", tableName))
+	if err != DB_SUCCESS {
+		t.Fatalf("Prepare: %v", err)
+	}
+	defer sel.Close()
+	if _, err := sel.Execute(nil); err != DB_INVALID_INPUT {
+		t.Fatalf("unbound literal err=%v", err)
+	}
+	if err := sel.BindIntUnsigned("lo", 3, 1); err != DB_INVALID_INPUT {
+		t.Fatalf("bad width err=%v", err)
+	}
+	keys := func(ibTrx *trx.Trx) []uint32 {
+		t.Helper()
+		res, err := sel.Execute(ibTrx)
+		if err != DB_SUCCESS {
+			t.Fatalf("Execute: %v", err)
+		}
+		out := make([]uint32, len(res.Rows))
+		for i, row := range res.Rows {
+			if err := TupleReadU32(row, 0, &out[i]); err != DB_SUCCESS {
+				t.Fatalf("TupleReadU32: %v", err)
+			}
+		}
+		return out
+	}
+	if err := sel.BindIntUnsigned("lo", 4, 2); err != DB_SUCCESS {
+		t.Fatalf("Bind: %v", err)
+	}
+	if got := keys(nil); len(got) != 2 || got[0] != 2 {
+		t.Fatalf("rows=%v", got)
+	}
+	graph := sel.graph
+	if got := keys(nil); len(got) != 2 || sel.graph != graph {
+		t.Fatalf("rows=%v, graph rebuilt=%v", got, sel.graph != graph)
+	}
+
+	ins, err := Prepare(`INSERT INTO "prepare_db/t" VALUES (?, ?)`)
+	if err != DB_SUCCESS {
+		t.Fatalf("Prepare insert: %v", err)
+	}
+	defer ins.Close()
+	ibTrx := TrxBegin(IB_TRX_REPEATABLE_READ)
+	for _, key := range []uint64{4, 5} {
+		if err := ins.Bind(SQLArgIntUnsigned("1", 4, key), SQLArgIntUnsigned("2", 4, key*100)); err != DB_SUCCESS {
+			t.Fatalf("Bind insert: %v", err)
+		}
+		if res, err := ins.Execute(ibTrx); err != DB_SUCCESS || res.RowsAffected != 1 {
+			t.Fatalf("insert %d: err=%v", key, err)
+		}
+	}
+	if got := keys(ibTrx); len(got) != 4 || got[3] != 5 {
+		t.Fatalf("rows in trx=%v", got)
+	}
+	if got := keys(nil); len(got) != 2 {
+		t.Fatalf("uncommitted rows visible: %v", got)
+	}
+	if err := TrxCommit(ibTrx); err != DB_SUCCESS {
+		t.Fatalf("TrxCommit: %v", err)
+	}
+	if got := keys(nil); len(got) != 4 {
+		t.Fatalf("rows after commit=%v", got)
+	}
+	if _, err := ins.Execute(nil); err != DB_DUPLICATE_KEY {
+		t.Fatalf("duplicate insert err=%v", err)
+	}
+
+	if err := ExecDDLSQL(`CREATE TABLE "prepare_db/u" (c1 INT UNSIGNED PRIMARY KEY, c2 INT UNSIGNED)`); err != DB_SUCCESS {
+		t.Fatalf("create: %v", err)
+	}
+	if err := sel.BindID("tbl", "prepare_db/u"); err != DB_SUCCESS {
+		t.Fatalf("BindID: %v", err)
+	}
+	if got := keys(nil); len(got) != 0 {
+		t.Fatalf("rows of u=%v", got)
+	}
+
+	if err := sel.Close(); err != DB_SUCCESS {
+		t.Fatalf("Close: %v", err)
+	}
+	if _, err := sel.Execute(nil); err != DB_ERROR {
+		t.Fatalf("execute after close err=%v", err)
+	}
+	if _, err := Prepare(`DROP TABLE "prepare_db/u"`); err != DB_UNSUPPORTED {
+		t.Fatalf("prepare DDL err=%v", err)
+	}
+}
+
+func TestPreparedStmtRebindKeepsGraph(t *testing.T) {
+	tableName := setupU32Table(t, "prepare_rebind_db")
+	const n = 20
+
+	ins, err := Prepare(`INSERT INTO $tbl VALUES (:k, :v)`, SQLArgID("tbl", tableName))
+	if err != DB_SUCCESS {
+		t.Fatalf("Prepare insert: %v", err)
+	}
+	defer ins.Close()
+	sel, err := Prepare(`SELECT * FROM $tbl WHERE c1 = :k`, SQLArgID("tbl", tableName))
+	if err != DB_SUCCESS {
+		t.Fatalf("Prepare select: %v", err)
+	}
+	defer sel.Close()
+	upd, err := Prepare(`UPDATE $tbl SET c2 = :v WHERE c1 = :k`, SQLArgID("tbl", tableName))
+	if err != DB_SUCCESS {
+		t.Fatalf("Prepare update: %v", err)
+	}
+	defer upd.Close()
+
+	graphs := map[*PreparedStmt]*que.Fork{}
+	run := func(s *PreparedStmt, args ...SQLArg) *SQLResult {
+		t.Helper()
+		if err := s.Bind(args...); err != DB_SUCCESS {
+			t.Fatalf("Bind: %v", err)
+		}
+		res, err := s.Execute(nil)
+		if err != DB_SUCCESS {
+			t.Fatalf("Execute: %v", err)
+		}
+		if built, ok := graphs[s]; !ok {
+			graphs[s] = s.graph
+		} else if s.graph != built {
+			t.Fatalf("graph built again for new values")
+		}
+		return res
+	}
+	for key := uint64(1); key <= n; key++ {
+		run(ins, SQLArgIntUnsigned("k", 4, key), SQLArgIntUnsigned("v", 4, key*10))
+	}
+	for key := uint64(1); key <= n; key++ {
+		run(upd, SQLArgIntUnsigned("k", 4, key), SQLArgIntUnsigned("v", 4, key*100))
+	}
+	for key := uint64(1); key <= n; key++ {
+		res := run(sel, SQLArgIntUnsigned("k", 4, key))
+		if len(res.Rows) != 1 {
+			t.Fatalf("key %d: rows=%d", key, len(res.Rows))
+		}
+		var c1, c2 uint32
+		if err := TupleReadU32(res.Rows[0], 0, &c1); err != DB_SUCCESS {
+			t.Fatalf("TupleReadU32: %v", err)
+		}
+		if err := TupleReadU32(res.Rows[0], 1, &c2); err != DB_SUCCESS {
+			t.Fatalf("TupleReadU32: %v", err)
+		}
+		if uint64(c1) != key || uint64(c2) != key*100 {
+			t.Fatalf("key %d: row=(%d, %d)", key, c1, c2)
+		}
+	}
+
+	built := sel.graph
+	if err := sel.BindNull("k"); err != DB_SUCCESS {
+		t.Fatalf("BindNull: %v", err)
+	}
+	if sel.graph != nil {
+		t.Fatalf("graph kept after the literal changed type")
+	}
+	if res, err := sel.Execute(nil); err != DB_SUCCESS || len(res.Rows) != 0 || sel.graph == built {
+		t.Fatalf("NULL key: err=%v", err)
+	}
+}
@@ -70,7 +70,7 @@ func TestStartupRunsRecoveryOnCrash(t *testing.T) {
 
 func buildMlogStringRecord(space, pageNo uint32, offset int, data []byte) []byte {
 	buf := make([]byte, 0, 16+len(data))
-	buf = append(buf, 30)
+	buf = append(buf, 30|0x80)
 	tmp := make([]byte, 10)
 	n := mach.WriteCompressed(tmp, space)
 	buf = append(buf, tmp[:n]...)
@@ -24,11 +24,13 @@ const (
 
 // TableSchema stores column and index metadata.
 type TableSchema struct {
-	Name     string
-	Format   TableFormat
-	PageSize int
-	Columns  []ColumnSchema
-	Indexes  []*IndexSchema
+	Name           string
+	Format         TableFormat
+	PageSize       int
+	PageCompressed bool
+	Encrypted      bool
+	Columns        []ColumnSchema
+	Indexes        []*IndexSchema
 }
 
 // ColumnSchema describes a column.
@@ -57,6 +59,9 @@ type Table struct {
 	Store   *row.Store
 	SpaceID uint32
 	Index   *dict.Index
+	// cursors counts the open cursors on the table, which block DDL that
+	// rebuilds it.
+	cursors atomic.Int32
 }
 
 // Database holds tables.
@@ -100,6 +105,35 @@ func TableSchemaCreate(name string, out **TableSchema, format TableFormat, pageS
 	return DB_SUCCESS
 }
 
+// TableSchemaSetPageCompressed turns page compression on or off for the
+// table. Its pages are compressed when written and the unused part of each
+// page is punched out of the table file. Compressed tables do not support
+// it.
+func TableSchemaSetPageCompressed(schema *TableSchema, on bool) ErrCode {
+	if schema == nil {
+		return DB_ERROR
+	}
+	if on && (schema.Format == IB_TBL_COMPRESSED || schema.Encrypted) {
+		return DB_INVALID_INPUT
+	}
+	schema.PageCompressed = on
+	return DB_SUCCESS
+}
+
+// TableSchemaSetEncrypted turns encryption at rest on or off for the table.
+// Its pages are encrypted with a key of its own, wrapped by the master key
+// of the keyring. Compressed and page compressed tables do not support it.
+func TableSchemaSetEncrypted(schema *TableSchema, on bool) ErrCode {
+	if schema == nil {
+		return DB_ERROR
+	}
+	if on && (schema.Format == IB_TBL_COMPRESSED || schema.PageCompressed) {
+		return DB_INVALID_INPUT
+	}
+	schema.Encrypted = on
+	return DB_SUCCESS
+}
+
 // TableSchemaAddCol appends a column to the schema.
 func TableSchemaAddCol(schema *TableSchema, name string, typ ColType, attr ColAttr, flags uint32, size uint32) ErrCode {
 	if schema == nil {
@@ -236,7 +270,7 @@ func TableCreate(_ *trx.Trx, schema *TableSchema, tableID *uint64) ErrCode {
 	store.PrimaryKeyFields = primaryKeyFields
 	store.PrimaryKeyPrefixes = primaryKeyPrefixes
 	spaceID := uint32(id + 1)
-	if !fil.SpaceCreate(schema.Name, spaceID, 0, fil.SpaceTablespace) {
+	if !tableSpaceCreate(schema.Name, spaceID, schema) {
 		return DB_ERROR
 	}
 	store.SpaceID = spaceID
@@ -336,6 +370,9 @@ func TableDrop(_ *trx.Trx, name string) ErrCode {
 	}
 	if table.SpaceID != 0 {
 		fil.SpaceDrop(table.SpaceID)
+		if table.Schema != nil && table.Schema.Encrypted {
+			dropTableKey(table.SpaceID)
+		}
 	}
 	if dictTable := dict.DictTableGet(name); dictTable != nil {
 		_ = dict.DictPersistTableDrop(dictTable)
@@ -449,9 +486,59 @@ func encodeTableFlags(format TableFormat, pageSize int) uint32 {
 	return uint32(format) | (uint32(pageSize) << 8)
 }
 
+// tableFlagPageCompressed and tableFlagEncrypted mark page compressed and
+// encrypted tables in the dictionary table flags, above the format and page
+// size.
+const (
+	tableFlagPageCompressed uint32 = 1 << 16
+	tableFlagEncrypted      uint32 = 1 << 17
+)
+
+func schemaTableFlags(schema *TableSchema) uint32 {
+	flags := encodeTableFlags(schema.Format, schema.PageSize)
+	if schema.PageCompressed {
+		flags |= tableFlagPageCompressed
+	}
+	if schema.Encrypted {
+		flags |= tableFlagEncrypted
+	}
+	return flags
+}
+
+// tableSpaceCreate registers the tablespace of a table with the page
+// compression and encryption settings of its schema.
+func tableSpaceCreate(name string, spaceID uint32, schema *TableSchema) bool {
+	if !fil.SpaceCreate(name, spaceID, tableZipSize(schema), fil.SpaceTablespace) {
+		return false
+	}
+	fil.SpaceSetPageCompressed(spaceID, schema != nil && schema.PageCompressed)
+	if schema != nil && schema.Encrypted {
+		if err := fil.SpaceSetEncrypted(spaceID); err != nil {
+			Log(nil, "InnoDB: cannot encrypt tablespace of %s: %v\n", name, err)
+			fil.SpaceDrop(spaceID)
+			return false
+		}
+	}
+	return true
+}
+
+// tableZipSize returns the compressed page size in bytes of the tablespace
+// of a table, or 0 when its pages are not compressed. A compressed table
+// without a page size uses 8K, the InnoDB default KEY_BLOCK_SIZE.
+func tableZipSize(schema *TableSchema) uint32 {
+	if schema == nil || schema.Format != IB_TBL_COMPRESSED {
+		return 0
+	}
+	pageSize := schema.PageSize
+	if pageSize == 0 {
+		pageSize = 8
+	}
+	return uint32(pageSize) << 10
+}
+
 func decodeTableFlags(flags uint32) (TableFormat, int) {
 	format := TableFormat(flags & 0xFF)
-	pageSize := int(flags >> 8)
+	pageSize := int(flags>>8) & 0xFF
 	return format, pageSize
 }
 
@@ -528,7 +615,9 @@ func loadSchemaFromDict() ErrCode {
 		store.PrimaryKeyPrefixes = primaryKeyPrefixes
 		spaceID := dtable.Space
 		if fil.SpaceGetByID(spaceID) == nil {
-			_ = fil.SpaceCreate(dtable.Name, spaceID, 0, fil.SpaceTablespace)
+			if !tableSpaceCreate(dtable.Name, spaceID, schema) && schema.Encrypted {
+				return DB_ERROR
+			}
 		}
 		var idx *dict.Index
 		for _, candidate := range dtable.Indexes {
@@ -584,9 +673,11 @@ func schemaFromDict(table *dict.Table) *TableSchema {
 	}
 	format, pageSize := decodeTableFlags(table.Flags)
 	schema := &TableSchema{
-		Name:     table.Name,
-		Format:   format,
-		PageSize: pageSize,
+		Name:           table.Name,
+		Format:         format,
+		PageSize:       pageSize,
+		PageCompressed: table.Flags&tableFlagPageCompressed != 0,
+		Encrypted:      table.Flags&tableFlagEncrypted != 0,
 	}
 	for _, col := range table.Columns {
 		schema.Columns = append(schema.Columns, ColumnSchema{
@@ -617,7 +708,7 @@ func buildDictTable(schema *TableSchema, spaceID uint32, tableID uint64, cluster
 	if schema == nil {
 		return nil, errors.New("api: invalid schema")
 	}
-	table := dict.MemTableCreate(schema.Name, spaceID, len(schema.Columns), encodeTableFlags(schema.Format, schema.PageSize))
+	table := dict.MemTableCreate(schema.Name, spaceID, len(schema.Columns), schemaTableFlags(schema))
 	table.ID = dict.DulintFromUint64(tableID)
 	for _, col := range schema.Columns {
 		dict.MemTableAddCol(table, col.Name, uint32(col.Type), uint32(col.Attr), col.Size)
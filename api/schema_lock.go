@@ -3,6 +3,7 @@ package api
 import (
 	"sync"
 
+	"github.com/wilhasse/innodb-go/lock"
 	"github.com/wilhasse/innodb-go/trx"
 )
 
@@ -38,3 +39,28 @@ func clearSchemaLock(trx *trx.Trx) {
 	delete(schemaLocks, trx)
 	schemaLockMu.Unlock()
 }
+
+// lockTableForDDL takes the schema lock and an exclusive lock on table for a
+// DDL statement run in ibTrx. The exclusive lock waits for the transactions
+// that read or changed the table with locks; both are held until ibTrx ends.
+func lockTableForDDL(ibTrx *trx.Trx, table *Table) ErrCode {
+	if err := SchemaLockExclusive(ibTrx); err != DB_SUCCESS {
+		return err
+	}
+	_, status := lock.LockTable(ibTrx, tableLockName(table), lock.ModeX)
+	return lockStatusToErr(status)
+}
+
+// ddlInTrx runs a DDL function in a transaction of its own, for callers that
+// pass no transaction.
+func ddlInTrx(fn func(*trx.Trx) ErrCode) ErrCode {
+	ibTrx := TrxBegin(IB_TRX_REPEATABLE_READ)
+	if ibTrx == nil {
+		return DB_ERROR
+	}
+	if err := fn(ibTrx); err != DB_SUCCESS {
+		_ = TrxRollback(ibTrx)
+		return err
+	}
+	return TrxCommit(ibTrx)
+}
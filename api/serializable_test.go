@@ -0,0 +1,215 @@
+package api
+
+import (
+	"testing"
+	"time"
+)
+
+func countOnCall(crsr *Cursor) (uint32, ErrCode) {
+	_, values, err := scanU32Rows(crsr)
+	if err != DB_SUCCESS {
+		return 0, err
+	}
+	var n uint32
+	for _, v := range values {
+		if v != 0 {
+			n++
+		}
+	}
+	return n, DB_SUCCESS
+}
+
+func TestSerializablePreventsWriteSkew(t *testing.T) {
+	tableName := setupU32Table(t, "serializable_skew_db")
+	seedU32Rows(t, tableName, 1, 2)
+
+	trx1 := TrxBegin(IB_TRX_SERIALIZABLE)
+	trx2 := TrxBegin(IB_TRX_SERIALIZABLE)
+	var cur1, cur2 *Cursor
+	if err := CursorOpenTable(tableName, trx1, &cur1); err != DB_SUCCESS {
+		t.Fatalf("CursorOpenTable trx1: %v", err)
+	}
+	if err := CursorOpenTable(tableName, trx2, &cur2); err != DB_SUCCESS {
+		t.Fatalf("CursorOpenTable trx2: %v", err)
+	}
+	for _, crsr := range []*Cursor{cur1, cur2} {
+		n, err := countOnCall(crsr)
+		if err != DB_SUCCESS || n != 2 {
+			t.Fatalf("on call=%d err=%v want 2", n, err)
+		}
+	}
+
+	done := make(chan ErrCode, 1)
+	go func() {
+		done <- updateU32Row(cur1, 1, 0)
+	}()
+	select {
+	case err := <-done:
+		t.Fatalf("trx1 update finished despite shared lock: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	if err := updateU32Row(cur2, 2, 0); err != DB_DEADLOCK {
+		t.Fatalf("trx2 update=%v, want DB_DEADLOCK", err)
+	}
+	if err := TrxRollback(trx2); err != DB_SUCCESS {
+		t.Fatalf("TrxRollback trx2: %v", err)
+	}
+	if err := waitErr(t, done, time.Second); err != DB_SUCCESS {
+		t.Fatalf("trx1 update=%v, want DB_SUCCESS", err)
+	}
+	if err := TrxCommit(trx1); err != DB_SUCCESS {
+		t.Fatalf("TrxCommit trx1: %v", err)
+	}
+
+	check := TrxBegin(IB_TRX_REPEATABLE_READ)
+	var checkCur *Cursor
+	if err := CursorOpenTable(tableName, check, &checkCur); err != DB_SUCCESS {
+		t.Fatalf("CursorOpenTable check: %v", err)
+	}
+	if n, err := countOnCall(checkCur); err != DB_SUCCESS || n != 1 {
+		t.Fatalf("on call=%d err=%v want 1", n, err)
+	}
+	_ = TrxCommit(check)
+}
+
+func TestSerializableScanBlocksInserts(t *testing.T) {
+	tableName := setupU32Table(t, "serializable_gap_db")
+	seedU32Rows(t, tableName, 10, 20)
+
+	reader := TrxBegin(IB_TRX_SERIALIZABLE)
+	var readCur *Cursor
+	if err := CursorOpenTable(tableName, reader, &readCur); err != DB_SUCCESS {
+		t.Fatalf("CursorOpenTable reader: %v", err)
+	}
+	keys, _, err := scanU32Rows(readCur)
+	if err != DB_SUCCESS {
+		t.Fatalf("scan: %v", err)
+	}
+	assertU32Keys(t, keys, []uint32{10, 20})
+
+	writer := TrxBegin(IB_TRX_REPEATABLE_READ)
+	writeCur := openLockingCursor(t, tableName, writer, LockIX)
+	results := make(chan ErrCode, 2)
+	go func() {
+		results <- insertU32Row(writeCur, 15, 1500)
+		results <- insertU32Row(writeCur, 30, 3000)
+	}()
+	select {
+	case err := <-results:
+		t.Fatalf("insert into scanned gap finished: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	if err := TrxCommit(reader); err != DB_SUCCESS {
+		t.Fatalf("TrxCommit reader: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := waitErr(t, results, time.Second); err != DB_SUCCESS {
+			t.Fatalf("insert %d after commit=%v, want DB_SUCCESS", i, err)
+		}
+	}
+	_ = TrxCommit(writer)
+}
+
+func TestSerializableSupremumBlocksAppend(t *testing.T) {
+	tableName := setupU32Table(t, "serializable_sup_db")
+	seedU32Rows(t, tableName, 10, 20)
+
+	reader := TrxBegin(IB_TRX_SERIALIZABLE)
+	var readCur *Cursor
+	if err := CursorOpenTable(tableName, reader, &readCur); err != DB_SUCCESS {
+		t.Fatalf("CursorOpenTable reader: %v", err)
+	}
+	if _, _, err := scanU32Rows(readCur); err != DB_SUCCESS {
+		t.Fatalf("scan: %v", err)
+	}
+
+	writer := TrxBegin(IB_TRX_REPEATABLE_READ)
+	writeCur := openLockingCursor(t, tableName, writer, LockIX)
+	done := make(chan ErrCode, 1)
+	go func() {
+		done <- insertU32Row(writeCur, 30, 3000)
+	}()
+	select {
+	case err := <-done:
+		t.Fatalf("append past scanned end finished: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	if err := TrxRollback(reader); err != DB_SUCCESS {
+		t.Fatalf("TrxRollback reader: %v", err)
+	}
+	if err := waitErr(t, done, time.Second); err != DB_SUCCESS {
+		t.Fatalf("append after rollback=%v, want DB_SUCCESS", err)
+	}
+	_ = TrxCommit(writer)
+}
+
+func TestRepeatableReadScanAllowsInserts(t *testing.T) {
+	tableName := setupU32Table(t, "serializable_rr_db")
+	seedU32Rows(t, tableName, 10, 20)
+
+	reader := TrxBegin(IB_TRX_REPEATABLE_READ)
+	var readCur *Cursor
+	if err := CursorOpenTable(tableName, reader, &readCur); err != DB_SUCCESS {
+		t.Fatalf("CursorOpenTable reader: %v", err)
+	}
+	if _, _, err := scanU32Rows(readCur); err != DB_SUCCESS {
+		t.Fatalf("scan: %v", err)
+	}
+
+	writer := TrxBegin(IB_TRX_REPEATABLE_READ)
+	writeCur := openLockingCursor(t, tableName, writer, LockIX)
+	done := make(chan ErrCode, 1)
+	go func() {
+		done <- insertU32Row(writeCur, 15, 1500)
+	}()
+	if err := waitErr(t, done, time.Second); err != DB_SUCCESS {
+		t.Fatalf("insert under snapshot read=%v, want DB_SUCCESS", err)
+	}
+	_ = TrxCommit(writer)
+	_ = TrxCommit(reader)
+}
+
+func TestSerializableBackwardScanLocksFirstGap(t *testing.T) {
+	tableName := setupU32Table(t, "serializable_back_db")
+	seedU32Rows(t, tableName, 10, 20)
+
+	reader := TrxBegin(IB_TRX_SERIALIZABLE)
+	var readCur *Cursor
+	if err := CursorOpenTable(tableName, reader, &readCur); err != DB_SUCCESS {
+		t.Fatalf("CursorOpenTable reader: %v", err)
+	}
+	search := ClustSearchTupleCreate(readCur)
+	if err := TupleWriteU32(search, 0, 10); err != DB_SUCCESS {
+		t.Fatalf("TupleWriteU32: %v", err)
+	}
+	var ret int
+	if err := CursorMoveTo(readCur, search, CursorLE, &ret); err != DB_SUCCESS {
+		t.Fatalf("CursorMoveTo: %v", err)
+	}
+	TupleDelete(search)
+	if err := CursorPrev(readCur); err != DB_END_OF_INDEX {
+		t.Fatalf("CursorPrev=%v, want DB_END_OF_INDEX", err)
+	}
+
+	writer := TrxBegin(IB_TRX_REPEATABLE_READ)
+	writeCur := openLockingCursor(t, tableName, writer, LockIX)
+	if err := insertU32Row(writeCur, 30, 3000); err != DB_SUCCESS {
+		t.Fatalf("append past unscanned end=%v, want DB_SUCCESS", err)
+	}
+	done := make(chan ErrCode, 1)
+	go func() {
+		done <- insertU32Row(writeCur, 5, 500)
+	}()
+	select {
+	case err := <-done:
+		t.Fatalf("insert before scanned start finished: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	if err := TrxCommit(reader); err != DB_SUCCESS {
+		t.Fatalf("TrxCommit reader: %v", err)
+	}
+	if err := waitErr(t, done, time.Second); err != DB_SUCCESS {
+		t.Fatalf("insert after commit=%v, want DB_SUCCESS", err)
+	}
+	_ = TrxCommit(writer)
+}
@@ -7,6 +7,7 @@ import (
 	"strings"
 
 	"github.com/wilhasse/innodb-go/pars"
+	"github.com/wilhasse/innodb-go/trx"
 )
 
 // SQLArg represents a parameter for ExecSQL/ExecDDLSQL.
@@ -20,6 +21,7 @@ type SQLArg struct {
 	UintValue   uint64
 	UserFunc    pars.UserFunc
 	UserFuncArg any
+	Null        bool
 }
 
 // SQLArgString binds a string literal (":name").
@@ -32,6 +34,11 @@ func SQLArgChar(name, value string) SQLArg {
 	return SQLArg{Type: IB_CHAR, Name: ":" + name, String: value}
 }
 
+// SQLArgNull binds a SQL NULL literal (":name").
+func SQLArgNull(name string) SQLArg {
+	return SQLArg{Type: IB_VARCHAR, Name: ":" + name, Null: true}
+}
+
 // SQLArgID binds an identifier ("$name").
 func SQLArgID(name, value string) SQLArg {
 	return SQLArg{Type: IB_VARCHAR, Name: "$" + name, String: value}
@@ -52,28 +59,67 @@ func SQLArgFunc(name string, fn pars.UserFunc, arg any) SQLArg {
 	return SQLArg{Type: IB_SYS, Name: name, UserFunc: fn, UserFuncArg: arg}
 }
 
-// ExecSQL parses arguments and returns DB_UNSUPPORTED until SQL execution is ported.
+// ExecSQL runs a single statement in its own transaction, which is committed
+// on success and rolled back on failure. Rows returned by a SELECT are
+// discarded; use ExecSQLFunc to receive them.
 func ExecSQL(sql string, args ...SQLArg) ErrCode {
+	return ExecSQLFunc(nil, sql, nil, args...)
+}
+
+// ExecDDLSQL runs a single statement in its own transaction while holding the
+// exclusive schema lock. CREATE DATABASE, CREATE TABLE, CREATE [UNIQUE] INDEX,
+// DROP TABLE, DROP INDEX, RENAME TABLE and TRUNCATE TABLE are applied through
+// the schema functions; other statements run as in ExecSQL.
+func ExecDDLSQL(sql string, args ...SQLArg) ErrCode {
 	if !started {
 		return DB_ERROR
 	}
-	_, err := execVSQL(sql, args)
+	stmt, err := parseSQLStatement(sql, args)
 	if err != DB_SUCCESS {
 		return err
 	}
-	return DB_UNSUPPORTED
-}
-
-// ExecDDLSQL parses arguments and returns DB_UNSUPPORTED until SQL execution is ported.
-func ExecDDLSQL(sql string, args ...SQLArg) ErrCode {
-	if !started {
+	ibTrx := TrxBegin(IB_TRX_REPEATABLE_READ)
+	if ibTrx == nil {
 		return DB_ERROR
 	}
-	_, err := execVSQL(sql, args)
+	if err := SchemaLockExclusive(ibTrx); err != DB_SUCCESS {
+		_ = TrxRollback(ibTrx)
+		return err
+	}
+	if isDDLStatement(stmt) {
+		err = execDDLStatement(ibTrx, stmt)
+	} else {
+		_, err = execSQLStatement(ibTrx, stmt)
+	}
 	if err != DB_SUCCESS {
+		_ = TrxRollback(ibTrx)
 		return err
 	}
-	return DB_UNSUPPORTED
+	return TrxCommit(ibTrx)
+}
+
+// ExecDDLSQLTrx runs a single statement inside ibTrx, applying the DDL
+// statements ExecDDLSQL accepts through the schema functions. For those
+// ibTrx takes the exclusive schema lock, held until it commits or rolls
+// back, and the result is empty; other statements run as in ExecSQLTrx.
+func ExecDDLSQLTrx(ibTrx *trx.Trx, sql string, args ...SQLArg) (*SQLResult, ErrCode) {
+	if !started || ibTrx == nil {
+		return nil, DB_ERROR
+	}
+	stmt, err := parseSQLStatement(sql, args)
+	if err != DB_SUCCESS {
+		return nil, err
+	}
+	if !isDDLStatement(stmt) {
+		return execSQLStatement(ibTrx, stmt)
+	}
+	if err := SchemaLockExclusive(ibTrx); err != DB_SUCCESS {
+		return nil, err
+	}
+	if err := execDDLStatement(ibTrx, stmt); err != DB_SUCCESS {
+		return nil, err
+	}
+	return &SQLResult{}, DB_SUCCESS
 }
 
 func execVSQL(sql string, args []SQLArg) (*pars.Info, ErrCode) {
@@ -82,37 +128,48 @@ func execVSQL(sql string, args []SQLArg) (*pars.Info, ErrCode) {
 	}
 	info := pars.NewInfo()
 	for _, arg := range args {
-		switch arg.Type {
-		case IB_CHAR, IB_VARCHAR:
-			prefix, name, err := parseNamePrefix(arg.Name)
-			if err != nil {
-				return nil, DB_INVALID_INPUT
-			}
-			if prefix == '$' {
-				info.AddID(name, arg.String)
-			} else {
-				info.AddStrLiteral(name, arg.String)
-			}
-		case IB_INT:
-			name := trimNamePrefix(arg.Name)
-			buf, unsigned, err := encodeInt(arg)
-			if err != DB_SUCCESS {
-				return nil, err
-			}
-			info.AddLiteral(name, buf, pars.LiteralInt, unsigned)
-		case IB_SYS:
-			name := trimNamePrefix(arg.Name)
-			if arg.UserFunc == nil {
-				return nil, DB_INVALID_INPUT
-			}
-			info.AddFunction(name, arg.UserFunc, arg.UserFuncArg)
-		default:
-			return nil, DB_UNSUPPORTED
+		if err := bindSQLArg(info, arg); err != DB_SUCCESS {
+			return nil, err
 		}
 	}
 	return info, DB_SUCCESS
 }
 
+// bindSQLArg adds one argument to info.
+func bindSQLArg(info *pars.Info, arg SQLArg) ErrCode {
+	switch arg.Type {
+	case IB_CHAR, IB_VARCHAR:
+		prefix, name, err := parseNamePrefix(arg.Name)
+		if err != nil {
+			return DB_INVALID_INPUT
+		}
+		switch {
+		case prefix == '$':
+			info.AddID(name, arg.String)
+		case arg.Null:
+			info.AddNullLiteral(name)
+		default:
+			info.AddStrLiteral(name, arg.String)
+		}
+	case IB_INT:
+		name := trimNamePrefix(arg.Name)
+		buf, unsigned, err := encodeInt(arg)
+		if err != DB_SUCCESS {
+			return err
+		}
+		info.AddLiteral(name, buf, pars.LiteralInt, unsigned)
+	case IB_SYS:
+		name := trimNamePrefix(arg.Name)
+		if arg.UserFunc == nil {
+			return DB_INVALID_INPUT
+		}
+		info.AddFunction(name, arg.UserFunc, arg.UserFuncArg)
+	default:
+		return DB_UNSUPPORTED
+	}
+	return DB_SUCCESS
+}
+
 func parseNamePrefix(name string) (rune, string, error) {
 	if name == "" {
 		return 0, "", errors.New("empty name")
@@ -174,6 +231,24 @@ func unsignedMax(bits uint) uint64 {
 	return (1 << bits) - 1
 }
 
+// DecodeInt reads a stored IB_INT value of 1, 2, 4 or 8 bytes. A signed
+// value is sign-extended into i and u holds its bits; an unsigned value is
+// returned in u and i holds its bits.
+func DecodeInt(buf []byte, unsigned bool) (i int64, u uint64, err ErrCode) {
+	if len(buf) != 1 && len(buf) != 2 && len(buf) != 4 && len(buf) != 8 {
+		return 0, 0, DB_DATA_MISMATCH
+	}
+	for _, b := range buf {
+		u = u<<8 | uint64(b)
+	}
+	if unsigned {
+		return int64(u), u, DB_SUCCESS
+	}
+	shift := uint(64 - 8*len(buf))
+	i = int64(u<<shift) >> shift
+	return i, uint64(i), DB_SUCCESS
+}
+
 func encodeUint(value uint64, length int) []byte {
 	buf := make([]byte, length)
 	for i := length - 1; i >= 0; i-- {
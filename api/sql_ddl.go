@@ -0,0 +1,173 @@
+package api
+
+import (
+	"strings"
+
+	"github.com/wilhasse/innodb-go/pars"
+	"github.com/wilhasse/innodb-go/trx"
+)
+
+func isDDLStatement(stmt pars.Statement) bool {
+	switch stmt.(type) {
+	case *pars.CreateDatabaseStmt, *pars.CreateTableStmt, *pars.CreateIndexStmt,
+		*pars.DropTableStmt, *pars.DropIndexStmt, *pars.RenameTableStmt, *pars.TruncateTableStmt:
+		return true
+	default:
+		return false
+	}
+}
+
+// execDDLStatement applies a parsed DDL statement through the schema API.
+// ibTrx is expected to hold the exclusive schema lock.
+func execDDLStatement(ibTrx *trx.Trx, stmt pars.Statement) ErrCode {
+	switch st := stmt.(type) {
+	case *pars.CreateDatabaseStmt:
+		return DatabaseCreate(st.Name)
+	case *pars.CreateTableStmt:
+		schema, err := tableSchemaFromDDL(st)
+		if err != DB_SUCCESS {
+			return err
+		}
+		return TableCreate(ibTrx, schema, nil)
+	case *pars.CreateIndexStmt:
+		var index *IndexSchema
+		if err := IndexSchemaCreate(ibTrx, st.Index.Name, st.Table, &index); err != DB_SUCCESS {
+			return err
+		}
+		if err := addDDLIndexColumns(index, st.Index); err != DB_SUCCESS {
+			return err
+		}
+		return IndexCreateOnline(ibTrx, index, nil)
+	case *pars.DropTableStmt:
+		return TableDrop(ibTrx, st.Table)
+	case *pars.DropIndexStmt:
+		return IndexDrop(ibTrx, st.Table, st.Index)
+	case *pars.RenameTableStmt:
+		return TableRename(ibTrx, st.From, st.To)
+	case *pars.TruncateTableStmt:
+		return TableTruncate(st.Table, nil)
+	default:
+		return DB_UNSUPPORTED
+	}
+}
+
+func tableSchemaFromDDL(st *pars.CreateTableStmt) (*TableSchema, ErrCode) {
+	format := IB_TBL_COMPACT
+	switch st.RowFormat {
+	case "", "COMPACT":
+		if st.KeyBlockSize > 0 {
+			format = IB_TBL_COMPRESSED
+		}
+	case "COMPRESSED":
+		format = IB_TBL_COMPRESSED
+	default:
+		return nil, DB_UNSUPPORTED
+	}
+	var schema *TableSchema
+	if err := TableSchemaCreate(st.Table, &schema, format, st.KeyBlockSize); err != DB_SUCCESS {
+		return nil, err
+	}
+	for _, def := range st.Columns {
+		typ, size, err := ddlColumnType(def)
+		if err != DB_SUCCESS {
+			return nil, err
+		}
+		attr := IB_COL_NONE
+		if def.Unsigned {
+			attr |= IB_COL_UNSIGNED
+		}
+		if def.NotNull || ddlPrimaryColumn(st, def.Name) {
+			attr |= IB_COL_NOT_NULL
+		}
+		if err := TableSchemaAddCol(schema, def.Name, typ, attr, 0, size); err != DB_SUCCESS {
+			return nil, err
+		}
+	}
+	for _, def := range st.Indexes {
+		var index *IndexSchema
+		if err := TableSchemaAddIndex(schema, def.Name, &index); err != DB_SUCCESS {
+			return nil, err
+		}
+		if err := addDDLIndexColumns(index, def); err != DB_SUCCESS {
+			return nil, err
+		}
+		if def.Primary {
+			_ = IndexSchemaSetClustered(index)
+		}
+	}
+	return schema, DB_SUCCESS
+}
+
+func addDDLIndexColumns(index *IndexSchema, def pars.IndexDef) ErrCode {
+	for i, col := range def.Columns {
+		prefix := 0
+		if i < len(def.Prefixes) {
+			prefix = def.Prefixes[i]
+		}
+		if err := IndexSchemaAddCol(index, col, prefix); err != DB_SUCCESS {
+			return err
+		}
+	}
+	if def.Unique && !def.Primary {
+		return IndexSchemaSetUnique(index)
+	}
+	return DB_SUCCESS
+}
+
+func ddlPrimaryColumn(st *pars.CreateTableStmt, name string) bool {
+	for _, def := range st.Indexes {
+		if !def.Primary {
+			continue
+		}
+		for _, col := range def.Columns {
+			if strings.EqualFold(col, name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ddlColumnType maps a SQL type name onto the column types of the API.
+func ddlColumnType(def pars.ColumnDef) (ColType, uint32, ErrCode) {
+	size := uint32(def.Size)
+	var typ ColType
+	switch def.Type {
+	case "TINYINT":
+		typ, size = IB_INT, 1
+	case "SMALLINT":
+		typ, size = IB_INT, 2
+	case "INT", "INTEGER":
+		typ, size = IB_INT, 4
+	case "BIGINT":
+		typ, size = IB_INT, 8
+	case "FLOAT":
+		typ, size = IB_FLOAT, 4
+	case "DOUBLE", "REAL":
+		typ, size = IB_DOUBLE, 8
+	case "CHAR":
+		typ = IB_CHAR
+		if size == 0 {
+			size = 1
+		}
+	case "VARCHAR":
+		typ = IB_VARCHAR
+	case "BINARY":
+		typ = IB_BINARY
+		if size == 0 {
+			size = 1
+		}
+	case "VARBINARY":
+		typ = IB_VARBINARY
+	case "BLOB", "TEXT":
+		typ, size = IB_BLOB, 0
+	case "DECIMAL":
+		typ = IB_DECIMAL
+	default:
+		return 0, 0, DB_SCHEMA_ERROR
+	}
+	if def.Unsigned && typ != IB_INT {
+		return 0, 0, DB_SCHEMA_ERROR
+	}
+	return typ, size, DB_SUCCESS
+}
@@ -0,0 +1,97 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/wilhasse/innodb-go/data"
+)
+
+func TestExecDDLSQL(t *testing.T) {
+	resetAPIState()
+	if err := Init(); err != DB_SUCCESS {
+		t.Fatalf("Init: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = Shutdown(ShutdownNormal)
+	})
+	if err := Startup("barracuda"); err != DB_SUCCESS {
+		t.Fatalf("Startup: %v", err)
+	}
+
+	steps := []string{
+		`CREATE DATABASE ddl_sql_db`,
+		`CREATE TABLE "ddl_sql_db/t" (
+			id INT UNSIGNED PRIMARY KEY,
+			name VARCHAR(16) NOT NULL,
+			score BIGINT
+		)`,
+		`INSERT INTO "ddl_sql_db/t" VALUES (1, 'ann', 5)`,
+		`INSERT INTO "ddl_sql_db/t" VALUES (2, 'bob', 7)`,
+		`CREATE UNIQUE INDEX uq_name ON "ddl_sql_db/t" (name)`,
+	}
+	for _, sql := range steps {
+		if err := ExecDDLSQL(sql); err != DB_SUCCESS {
+			t.Fatalf("ExecDDLSQL %q: %v", sql, err)
+		}
+	}
+	table := findTable("ddl_sql_db/t")
+	if table == nil {
+		t.Fatalf("table not created")
+	}
+	schema := table.Schema
+	if col := schema.Columns[0]; col.Type != IB_INT || col.Size != 4 || col.Attr != IB_COL_UNSIGNED|IB_COL_NOT_NULL {
+		t.Fatalf("id column=%+v", col)
+	}
+	if col := schema.Columns[2]; col.Type != IB_INT || col.Size != 8 || col.Attr != IB_COL_NONE {
+		t.Fatalf("score column=%+v", col)
+	}
+	if err := ExecSQL(`INSERT INTO "ddl_sql_db/t" VALUES (3, 'ann', 0)`); err != DB_DUPLICATE_KEY {
+		t.Fatalf("unique index not enforced: %v", err)
+	}
+
+	if err := ExecDDLSQL(`RENAME TABLE "ddl_sql_db/t" TO "ddl_sql_db/u"`); err != DB_SUCCESS {
+		t.Fatalf("rename: %v", err)
+	}
+	count := func() int {
+		n := 0
+		err := ExecSQLFunc(nil, `SELECT * FROM "ddl_sql_db/u"`, func([]SQLColumn, *data.Tuple) ErrCode {
+			n++
+			return DB_SUCCESS
+		})
+		if err != DB_SUCCESS {
+			t.Fatalf("select: %v", err)
+		}
+		return n
+	}
+	if n := count(); n != 2 {
+		t.Fatalf("rows after rename=%d", n)
+	}
+	if err := ExecDDLSQL(`DROP INDEX uq_name ON "ddl_sql_db/u"`); err != DB_SUCCESS {
+		t.Fatalf("drop index: %v", err)
+	}
+	if err := ExecSQL(`INSERT INTO "ddl_sql_db/u" VALUES (3, 'ann', 0)`); err != DB_SUCCESS {
+		t.Fatalf("insert after drop index: %v", err)
+	}
+	if err := ExecDDLSQL(`TRUNCATE TABLE "ddl_sql_db/u"`); err != DB_SUCCESS {
+		t.Fatalf("truncate: %v", err)
+	}
+	if n := count(); n != 0 {
+		t.Fatalf("rows after truncate=%d", n)
+	}
+	if err := ExecDDLSQL(`DROP TABLE "ddl_sql_db/u"`); err != DB_SUCCESS {
+		t.Fatalf("drop table: %v", err)
+	}
+	if err := ExecSQL(`SELECT * FROM "ddl_sql_db/u"`); err != DB_TABLE_NOT_FOUND {
+		t.Fatalf("select dropped table err=%v want %v", err, DB_TABLE_NOT_FOUND)
+	}
+
+	if err := ExecDDLSQL(`CREATE TABLE "ddl_sql_db/bad" (a INT PRIMARY KEY, b GEOMETRY)`); err != DB_SCHEMA_ERROR {
+		t.Fatalf("unknown type err=%v want %v", err, DB_SCHEMA_ERROR)
+	}
+	if err := ExecDDLSQL(`CREATE TABLE "ddl_sql_db/bad" (a INT PRIMARY KEY) ROW_FORMAT=REDUNDANT`); err != DB_UNSUPPORTED {
+		t.Fatalf("row format err=%v want %v", err, DB_UNSUPPORTED)
+	}
+	if err := ExecSQL(`DROP TABLE "ddl_sql_db/t"`); err != DB_UNSUPPORTED {
+		t.Fatalf("DDL through ExecSQL err=%v want %v", err, DB_UNSUPPORTED)
+	}
+}
@@ -0,0 +1,535 @@
+package api
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"strconv"
+
+	"github.com/wilhasse/innodb-go/data"
+	"github.com/wilhasse/innodb-go/eval"
+	"github.com/wilhasse/innodb-go/pars"
+	"github.com/wilhasse/innodb-go/que"
+	"github.com/wilhasse/innodb-go/row"
+	"github.com/wilhasse/innodb-go/trx"
+)
+
+// SQLColumn describes a column of a SQL result.
+type SQLColumn struct {
+	Name string
+	Type ColType
+	Attr ColAttr
+	Size uint32
+}
+
+// SQLResult holds the rows returned by a SELECT and the number of rows
+// changed by INSERT, UPDATE or DELETE. EXPLAIN returns one row per plan step
+// and a procedure its OUT parameters as a single row along with the rows its
+// statements changed. Row fields use the same encoding as tuples read through
+// a cursor, so the Tuple* read functions apply.
+type SQLResult struct {
+	Columns      []SQLColumn
+	Rows         []*data.Tuple
+	RowsAffected int
+}
+
+// SQLRowFunc receives the rows of a SELECT in result order. The statement
+// runs to completion and collects its rows first, so fn is not called while
+// the table is scanned. Returning anything other than DB_SUCCESS stops the
+// iteration and becomes the statement result.
+type SQLRowFunc func(cols []SQLColumn, row *data.Tuple) ErrCode
+
+// ExecSQLFunc runs a single statement and passes each result row to fn, which
+// may be nil. With a nil ibTrx the statement runs in its own transaction that
+// is committed on success and rolled back on failure, including when fn stops
+// the iteration.
+func ExecSQLFunc(ibTrx *trx.Trx, sql string, fn SQLRowFunc, args ...SQLArg) ErrCode {
+	if !started {
+		return DB_ERROR
+	}
+	autocommit := ibTrx == nil
+	if autocommit {
+		if ibTrx = TrxBegin(IB_TRX_REPEATABLE_READ); ibTrx == nil {
+			return DB_ERROR
+		}
+	}
+	err := execSQLFunc(ibTrx, sql, fn, args)
+	if !autocommit {
+		return err
+	}
+	if err != DB_SUCCESS {
+		_ = TrxRollback(ibTrx)
+		return err
+	}
+	return TrxCommit(ibTrx)
+}
+
+func execSQLFunc(ibTrx *trx.Trx, sql string, fn SQLRowFunc, args []SQLArg) ErrCode {
+	res, err := ExecSQLTrx(ibTrx, sql, args...)
+	if err != DB_SUCCESS || fn == nil {
+		return err
+	}
+	for _, row := range res.Rows {
+		if err := fn(res.Columns, row); err != DB_SUCCESS {
+			return err
+		}
+	}
+	return DB_SUCCESS
+}
+
+// ExecSQLTrx runs a single statement or procedure inside ibTrx through the
+// pars/que SQL path. Reads see the transaction's read view and changes take
+// row locks and write undo exactly like the cursor API.
+func ExecSQLTrx(ibTrx *trx.Trx, sql string, args ...SQLArg) (*SQLResult, ErrCode) {
+	if !started || ibTrx == nil {
+		return nil, DB_ERROR
+	}
+	stmt, err := parseSQLStatement(sql, args)
+	if err != DB_SUCCESS {
+		return nil, err
+	}
+	return execSQLStatement(ibTrx, stmt)
+}
+
+func parseSQLStatement(sql string, args []SQLArg) (pars.Statement, ErrCode) {
+	info, err := execVSQL(sql, args)
+	if err != DB_SUCCESS {
+		return nil, err
+	}
+	stmt, parseErr := pars.ParseSQLWithInfo(info, sql)
+	if parseErr != nil {
+		return nil, DB_INVALID_INPUT
+	}
+	return stmt, DB_SUCCESS
+}
+
+func execSQLStatement(ibTrx *trx.Trx, stmt pars.Statement) (*SQLResult, ErrCode) {
+	tables, err := openSQLTables(ibTrx, stmt)
+	if err != DB_SUCCESS {
+		return nil, err
+	}
+	defer tables.close()
+	graph, buildErr := que.BuildGraph(stmt, tables.ctx)
+	if buildErr != nil {
+		return nil, sqlErrCode(buildErr)
+	}
+	defer que.GraphFree(graph)
+	if runErr := que.ForkRun(graph); runErr != nil {
+		return nil, sqlErrCode(runErr)
+	}
+	return sqlGraphResult(graph, tables.columns), DB_SUCCESS
+}
+
+// sqlTables holds the cursors a statement runs through and the build context
+// describing them.
+type sqlTables struct {
+	ctx     *que.BuildContext
+	schemas map[string]*TableSchema
+	access  map[string]*sqlTableAccess
+	// columns lists the result columns of the tables in FROM order.
+	columns []ColumnSchema
+	cursors []*Cursor
+}
+
+// openSQLTables opens a cursor in ibTrx on every table of stmt. Each table is
+// opened once, even when a join names it twice. A procedure may use no
+// tables at all.
+func openSQLTables(ibTrx *trx.Trx, stmt pars.Statement) (*sqlTables, ErrCode) {
+	tableNames := que.StatementTables(stmt)
+	if _, isProc := stmt.(*pars.ProcedureStmt); len(tableNames) == 0 && !isProc {
+		return nil, DB_UNSUPPORTED
+	}
+	tables := &sqlTables{
+		ctx:     &que.BuildContext{Tables: make(map[string]*que.TableContext)},
+		schemas: make(map[string]*TableSchema),
+		access:  make(map[string]*sqlTableAccess),
+	}
+	for _, tableName := range tableNames {
+		if schema := tables.schemas[tableName]; schema != nil {
+			tables.columns = append(tables.columns, schema.Columns...)
+			continue
+		}
+		var crsr *Cursor
+		if err := CursorOpenTable(tableName, ibTrx, &crsr); err != DB_SUCCESS {
+			tables.close()
+			return nil, err
+		}
+		tables.cursors = append(tables.cursors, crsr)
+		schema := crsr.Table.Schema
+		columns := make([]string, len(schema.Columns))
+		for i, col := range schema.Columns {
+			columns[i] = col.Name
+		}
+		access := &sqlTableAccess{crsr: crsr}
+		tables.ctx.Tables[tableName] = &que.TableContext{
+			Store:   crsr.Table.Store,
+			Columns: columns,
+			Access:  access,
+			Encode:  sqlFieldEncoder(schema),
+			Decode:  sqlFieldDecoder(schema),
+			Kinds:   sqlColumnKinds(schema),
+			Ordered: sqlOrderedColumns(schema),
+			Result:  sqlValueEncoder,
+		}
+		tables.schemas[tableName] = schema
+		tables.access[tableName] = access
+		tables.columns = append(tables.columns, schema.Columns...)
+	}
+	return tables, DB_SUCCESS
+}
+
+func (tables *sqlTables) close() {
+	for _, crsr := range tables.cursors {
+		CursorClose(crsr)
+	}
+	tables.cursors = nil
+}
+
+// sqlGraphResult collects the result of a graph that has run.
+func sqlGraphResult(graph *que.Fork, columns []ColumnSchema) *SQLResult {
+	result := &SQLResult{}
+	switch n := que.ForkGetChild(graph).(type) {
+	case *que.SelectNode:
+		result.Rows = n.Rows
+		result.Columns = sqlResultColumns(columns, n.Output)
+	case *que.InsertNode:
+		result.RowsAffected = n.Affected
+	case *que.UpdateNode:
+		result.RowsAffected = n.Affected
+	case *que.DeleteNode:
+		result.RowsAffected = n.Affected
+	case *que.ExplainNode:
+		result.Rows = n.Rows
+		result.Columns = sqlResultColumns(nil, n.Output)
+	case *que.ProcedureNode:
+		result.RowsAffected = n.Affected
+		result.Columns = sqlResultColumns(nil, n.Output)
+		if len(n.Values) > 0 {
+			out := data.NewTuple(len(n.Values))
+			for i, val := range n.Values {
+				out.Fields[i], _ = sqlValueEncoder(val)
+			}
+			result.Rows = []*data.Tuple{out}
+		}
+	}
+	return result
+}
+
+// sqlResultColumns describes the select output. Table columns keep their
+// schema type; computed integers are reported as signed 8-byte IB_INT,
+// floats as IB_DOUBLE and other computed values as IB_VARCHAR.
+func sqlResultColumns(columns []ColumnSchema, output []que.Projection) []SQLColumn {
+	cols := make([]SQLColumn, len(output))
+	for i, proj := range output {
+		switch {
+		case proj.Source >= 0 && proj.Source < len(columns):
+			col := columns[proj.Source]
+			cols[i] = SQLColumn{Name: proj.Name, Type: col.Type, Attr: col.Attr, Size: col.Size}
+		case proj.Kind == eval.KindInt:
+			cols[i] = SQLColumn{Name: proj.Name, Type: IB_INT, Size: 8}
+		case proj.Kind == eval.KindFloat:
+			cols[i] = SQLColumn{Name: proj.Name, Type: IB_DOUBLE, Size: 8}
+		default:
+			cols[i] = SQLColumn{Name: proj.Name, Type: IB_VARCHAR}
+		}
+	}
+	return cols
+}
+
+func sqlErrCode(err error) ErrCode {
+	var code ErrCode
+	switch {
+	case errors.As(err, &code):
+		return code
+	case errors.Is(err, que.ErrMissingTable):
+		return DB_TABLE_NOT_FOUND
+	case errors.Is(err, que.ErrInvalidStatement):
+		return DB_UNSUPPORTED
+	default:
+		return DB_INVALID_INPUT
+	}
+}
+
+// sqlTableAccess performs graph row operations through a cursor so they obey
+// the cursor's transaction.
+type sqlTableAccess struct {
+	crsr *Cursor
+}
+
+func (access *sqlTableAccess) Scan(fn func(row *data.Tuple) error) error {
+	crsr := access.crsr
+	err := CursorFirst(crsr)
+	if err == DB_RECORD_NOT_FOUND || err == DB_END_OF_INDEX {
+		return nil
+	}
+	readTpl := ClustReadTupleCreate(crsr)
+	defer TupleDelete(readTpl)
+	for err == DB_SUCCESS {
+		if err = CursorReadRow(crsr, readTpl); err != DB_SUCCESS {
+			return err
+		}
+		if fnErr := fn(cloneTuple(readTpl)); fnErr != nil {
+			return fnErr
+		}
+		err = CursorNext(crsr)
+	}
+	if err == DB_RECORD_NOT_FOUND || err == DB_END_OF_INDEX {
+		return nil
+	}
+	return err
+}
+
+// ScanIndex reads the rows on an index path, seeking to the lower bound and
+// stopping once the index entry passes the upper bound.
+func (access *sqlTableAccess) ScanIndex(path *que.AccessPath, fn func(row *data.Tuple) error) error {
+	crsr := access.crsr
+	if !path.Index.Clustered {
+		var secCur *Cursor
+		if err := CursorOpenIndexUsingName(crsr, path.Index.Name, &secCur); err != DB_SUCCESS {
+			return err
+		}
+		defer CursorClose(secCur)
+		crsr = secCur
+	}
+	var err ErrCode
+	if path.Lower == nil {
+		err = CursorFirst(crsr)
+	} else {
+		mode := CursorG
+		if path.Lower.Inclusive {
+			mode = CursorGE
+		}
+		search := newTupleForCursor(crsr)
+		defer TupleDelete(search)
+		for i, field := range path.Lower.Fields {
+			search.Fields[path.Index.Columns[i]] = field
+		}
+		err = cursorLockStep(crsr, cursorMoveToFields(crsr, search, len(path.Lower.Fields), mode, nil), cursorNext)
+	}
+	if err == DB_RECORD_NOT_FOUND || err == DB_END_OF_INDEX {
+		return nil
+	}
+	readTpl := ClustReadTupleCreate(crsr)
+	defer TupleDelete(readTpl)
+	for err == DB_SUCCESS {
+		// The bound is checked on the index entry itself; the row read
+		// may be an older version whose key no longer matches.
+		if current, ok := cursorCurrentTuple(crsr); ok && path.PastUpper(current) {
+			return nil
+		}
+		if err = CursorReadRow(crsr, readTpl); err != DB_SUCCESS {
+			return err
+		}
+		if fnErr := fn(cloneTuple(readTpl)); fnErr != nil {
+			return fnErr
+		}
+		err = CursorNext(crsr)
+	}
+	if err == DB_RECORD_NOT_FOUND || err == DB_END_OF_INDEX {
+		return nil
+	}
+	return err
+}
+
+// sqlIndexes lists the indexes the SQL planner may seek on. Clustered cursor
+// searches compare the search tuple by position, so the clustered index is
+// only offered when its key is made of the leading columns.
+func sqlIndexes(store *row.Store) []que.IndexInfo {
+	indexes := que.StoreIndexes(store)
+	out := indexes[:0]
+	for _, index := range indexes {
+		if index.Clustered && !leadingColumns(index.Columns) {
+			continue
+		}
+		out = append(out, index)
+	}
+	return out
+}
+
+func leadingColumns(cols []int) bool {
+	for i, col := range cols {
+		if col != i {
+			return false
+		}
+	}
+	return true
+}
+
+func (access *sqlTableAccess) Insert(row *data.Tuple) error {
+	return sqlAccessErr(CursorInsertRow(access.crsr, row))
+}
+
+func (access *sqlTableAccess) Update(oldRow, newRow *data.Tuple) error {
+	return sqlAccessErr(CursorUpdateRow(access.crsr, oldRow, newRow))
+}
+
+func (access *sqlTableAccess) Delete(row *data.Tuple) error {
+	target := findRowForUpdate(access.crsr, row)
+	if target == nil {
+		return DB_RECORD_NOT_FOUND
+	}
+	return sqlAccessErr(deleteCursorRow(access.crsr, target))
+}
+
+func sqlAccessErr(code ErrCode) error {
+	if code == DB_SUCCESS {
+		return nil
+	}
+	return code
+}
+
+// sqlFieldEncoder converts SQL literals into the stored form of the schema's
+// columns, matching the Tuple* write functions.
+func sqlFieldEncoder(schema *TableSchema) que.FieldEncoder {
+	return func(col int, lit pars.LiteralExpr) (data.Field, error) {
+		if schema == nil || col < 0 || col >= len(schema.Columns) {
+			return data.Field{}, DB_INVALID_INPUT
+		}
+		if lit.Kind == pars.TokenNull {
+			return data.Field{Len: data.UnivSQLNull}, nil
+		}
+		buf, err := encodeSQLValue(schema.Columns[col], lit.Value)
+		if err != DB_SUCCESS {
+			return data.Field{}, err
+		}
+		return data.Field{Data: buf, Len: uint32(len(buf))}, nil
+	}
+}
+
+// sqlFieldDecoder reads stored columns as values for computed expressions:
+// integers and floats as numbers and everything else as its bytes.
+func sqlFieldDecoder(schema *TableSchema) que.FieldDecoder {
+	return func(col int, field data.Field) (eval.Value, error) {
+		if data.FieldIsNull(&field) {
+			return eval.Value{Kind: eval.KindNull}, nil
+		}
+		if schema == nil || col < 0 || col >= len(schema.Columns) {
+			return eval.Value{}, DB_INVALID_INPUT
+		}
+		column := schema.Columns[col]
+		buf := field.Data
+		switch column.Type {
+		case IB_INT:
+			unsigned := column.Attr&IB_COL_UNSIGNED != 0
+			i, u, err := DecodeInt(buf, unsigned)
+			if err != DB_SUCCESS {
+				return eval.Value{}, err
+			}
+			if unsigned && u > math.MaxInt64 {
+				return eval.Value{}, DB_DATA_MISMATCH
+			}
+			return eval.Value{Kind: eval.KindInt, Int: i}, nil
+		case IB_FLOAT:
+			if len(buf) != 4 {
+				return eval.Value{}, DB_DATA_MISMATCH
+			}
+			val := math.Float32frombits(binary.BigEndian.Uint32(buf))
+			return eval.Value{Kind: eval.KindFloat, Float: float64(val)}, nil
+		case IB_DOUBLE:
+			if len(buf) != 8 {
+				return eval.Value{}, DB_DATA_MISMATCH
+			}
+			return eval.Value{Kind: eval.KindFloat, Float: math.Float64frombits(binary.BigEndian.Uint64(buf))}, nil
+		default:
+			return eval.Value{Kind: eval.KindBytes, Bytes: buf}, nil
+		}
+	}
+}
+
+// sqlColumnKinds lists the value kinds sqlFieldDecoder produces.
+func sqlColumnKinds(schema *TableSchema) []eval.ValueKind {
+	kinds := make([]eval.ValueKind, len(schema.Columns))
+	for i, col := range schema.Columns {
+		switch col.Type {
+		case IB_INT:
+			kinds[i] = eval.KindInt
+		case IB_FLOAT, IB_DOUBLE:
+			kinds[i] = eval.KindFloat
+		default:
+			kinds[i] = eval.KindBytes
+		}
+	}
+	return kinds
+}
+
+// sqlOrderedColumns marks the columns whose stored bytes sort in value
+// order. Signed integers are stored in two's complement and floats as their
+// IEEE bits, so neither does.
+func sqlOrderedColumns(schema *TableSchema) []bool {
+	ordered := make([]bool, len(schema.Columns))
+	for i, col := range schema.Columns {
+		switch col.Type {
+		case IB_INT:
+			ordered[i] = col.Attr&IB_COL_UNSIGNED != 0
+		case IB_FLOAT, IB_DOUBLE:
+			ordered[i] = false
+		default:
+			ordered[i] = true
+		}
+	}
+	return ordered
+}
+
+// sqlValueEncoder stores computed values the way sqlResultColumns reports
+// them: integers as signed 8-byte big-endian, floats as doubles and the rest
+// as text.
+func sqlValueEncoder(val eval.Value) (data.Field, error) {
+	buf := make([]byte, 8)
+	switch val.Kind {
+	case eval.KindInt:
+		binary.BigEndian.PutUint64(buf, uint64(val.Int))
+	case eval.KindFloat:
+		binary.BigEndian.PutUint64(buf, math.Float64bits(val.Float))
+	default:
+		return eval.ValueToField(val), nil
+	}
+	return data.Field{Data: buf, Len: 8}, nil
+}
+
+func encodeSQLValue(col ColumnSchema, text string) ([]byte, ErrCode) {
+	switch col.Type {
+	case IB_INT:
+		size := int(col.Size)
+		if size != 1 && size != 2 && size != 4 && size != 8 {
+			return nil, DB_SCHEMA_ERROR
+		}
+		arg := SQLArg{IntLen: size, Signed: col.Attr&IB_COL_UNSIGNED == 0}
+		var parseErr error
+		if arg.Signed {
+			arg.IntValue, parseErr = strconv.ParseInt(text, 10, 64)
+		} else {
+			arg.UintValue, parseErr = strconv.ParseUint(text, 10, 64)
+		}
+		if parseErr != nil {
+			return nil, DB_DATA_MISMATCH
+		}
+		buf, _, err := encodeInt(arg)
+		if err != DB_SUCCESS {
+			return nil, DB_DATA_MISMATCH
+		}
+		return buf, DB_SUCCESS
+	case IB_FLOAT:
+		val, parseErr := strconv.ParseFloat(text, 32)
+		if parseErr != nil {
+			return nil, DB_DATA_MISMATCH
+		}
+		buf := make([]byte, 4)
+		binary.BigEndian.PutUint32(buf, math.Float32bits(float32(val)))
+		return buf, DB_SUCCESS
+	case IB_DOUBLE:
+		val, parseErr := strconv.ParseFloat(text, 64)
+		if parseErr != nil {
+			return nil, DB_DATA_MISMATCH
+		}
+		buf := make([]byte, 8)
+		binary.BigEndian.PutUint64(buf, math.Float64bits(val))
+		return buf, DB_SUCCESS
+	case IB_SYS:
+		return nil, DB_DATA_MISMATCH
+	default:
+		if col.Size > 0 && col.Type != IB_BLOB && len(text) > int(col.Size) {
+			return nil, DB_DATA_MISMATCH
+		}
+		return []byte(text), DB_SUCCESS
+	}
+}
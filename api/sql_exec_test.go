@@ -0,0 +1,568 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/wilhasse/innodb-go/data"
+	"github.com/wilhasse/innodb-go/trx"
+)
+
+func sqlU32Rows(t *testing.T, ibTrx *trx.Trx, sql string, args ...SQLArg) [][2]uint32 {
+	t.Helper()
+	res, err := ExecSQLTrx(ibTrx, sql, args...)
+	if err != DB_SUCCESS {
+		t.Fatalf("ExecSQLTrx %q: %v", sql, err)
+	}
+	rows := make([][2]uint32, 0, len(res.Rows))
+	for _, tpl := range res.Rows {
+		var key, val uint32
+		if err := TupleReadU32(tpl, 0, &key); err != DB_SUCCESS {
+			t.Fatalf("TupleReadU32 c1: %v", err)
+		}
+		if err := TupleReadU32(tpl, 1, &val); err != DB_SUCCESS {
+			t.Fatalf("TupleReadU32 c2: %v", err)
+		}
+		rows = append(rows, [2]uint32{key, val})
+	}
+	return rows
+}
+
+func TestExecSQLTrxDML(t *testing.T) {
+	tableName := setupU32Table(t, "sql_exec_db")
+	seedU32Rows(t, tableName, 1, 2, 3)
+
+	ibTrx := TrxBegin(IB_TRX_REPEATABLE_READ)
+	res, err := ExecSQLTrx(ibTrx, `INSERT INTO "sql_exec_db/t" VALUES (:k, :v)`,
+		SQLArgIntUnsigned("k", 4, 4), SQLArgIntUnsigned("v", 4, 400))
+	if err != DB_SUCCESS || res.RowsAffected != 1 {
+		t.Fatalf("insert err=%v res=%+v", err, res)
+	}
+	res, err = ExecSQLTrx(ibTrx, `UPDATE "sql_exec_db/t" SET c2 = :v WHERE c1 = :k`,
+		SQLArgIntUnsigned("k", 4, 2), SQLArgIntUnsigned("v", 4, 7))
+	if err != DB_SUCCESS || res.RowsAffected != 1 {
+		t.Fatalf("update err=%v res=%+v", err, res)
+	}
+	res, err = ExecSQLTrx(ibTrx, `DELETE FROM "sql_exec_db/t" WHERE c1 = :k`, SQLArgIntUnsigned("k", 4, 1))
+	if err != DB_SUCCESS || res.RowsAffected != 1 {
+		t.Fatalf("delete err=%v res=%+v", err, res)
+	}
+	got := sqlU32Rows(t, ibTrx, `SELECT * FROM "sql_exec_db/t"`)
+	want := [][2]uint32{{2, 7}, {3, 300}, {4, 400}}
+	if len(got) != len(want) {
+		t.Fatalf("rows=%v want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("rows=%v want %v", got, want)
+		}
+	}
+
+	other := TrxBegin(IB_TRX_REPEATABLE_READ)
+	for _, row := range sqlU32Rows(t, other, `SELECT * FROM "sql_exec_db/t"`) {
+		if row[0] == 4 || row == [2]uint32{2, 7} {
+			t.Fatalf("uncommitted change visible: %v", row)
+		}
+	}
+	if err := TrxCommit(other); err != DB_SUCCESS {
+		t.Fatalf("TrxCommit other: %v", err)
+	}
+	if err := TrxRollback(ibTrx); err != DB_SUCCESS {
+		t.Fatalf("TrxRollback: %v", err)
+	}
+
+	after := TrxBegin(IB_TRX_REPEATABLE_READ)
+	defer TrxCommit(after)
+	rows := sqlU32Rows(t, after, `SELECT c1, c2 FROM "sql_exec_db/t" WHERE c1 = :k`, SQLArgIntUnsigned("k", 4, 2))
+	if len(rows) != 1 || rows[0] != [2]uint32{2, 200} {
+		t.Fatalf("rolled back rows=%v", rows)
+	}
+}
+
+func TestExecSQLTrxErrors(t *testing.T) {
+	setupU32Table(t, "sql_exec_err_db")
+	ibTrx := TrxBegin(IB_TRX_REPEATABLE_READ)
+	defer TrxRollback(ibTrx)
+	if _, err := ExecSQLTrx(nil, `SELECT * FROM "sql_exec_err_db/t"`); err != DB_ERROR {
+		t.Fatalf("nil trx err=%v want %v", err, DB_ERROR)
+	}
+	if _, err := ExecSQLTrx(ibTrx, `SELECT * FROM "sql_exec_err_db/missing"`); err != DB_TABLE_NOT_FOUND {
+		t.Fatalf("missing table err=%v want %v", err, DB_TABLE_NOT_FOUND)
+	}
+	if _, err := ExecSQLTrx(ibTrx, `INSERT INTO "sql_exec_err_db/t" VALUES ('x', 1)`); err != DB_DATA_MISMATCH {
+		t.Fatalf("bad value err=%v want %v", err, DB_DATA_MISMATCH)
+	}
+	if _, err := ExecSQLTrx(ibTrx, `SELEC * FROM`); err != DB_INVALID_INPUT {
+		t.Fatalf("parse err=%v want %v", err, DB_INVALID_INPUT)
+	}
+}
+
+func TestExecSQLAutocommit(t *testing.T) {
+	setupU32Table(t, "sql_auto_db")
+	if err := ExecSQL(`INSERT INTO "sql_auto_db/t" VALUES (:k, :v)`,
+		SQLArgIntUnsigned("k", 4, 1), SQLArgIntUnsigned("v", 4, 10)); err != DB_SUCCESS {
+		t.Fatalf("ExecSQL insert: %v", err)
+	}
+	if err := ExecDDLSQL(`INSERT INTO "sql_auto_db/t" VALUES (2, 20)`); err != DB_SUCCESS {
+		t.Fatalf("ExecDDLSQL insert: %v", err)
+	}
+	if err := ExecSQL(`INSERT INTO "sql_auto_db/t" VALUES (1, 99)`); err != DB_DUPLICATE_KEY {
+		t.Fatalf("duplicate insert err=%v want %v", err, DB_DUPLICATE_KEY)
+	}
+
+	var seen [][2]uint32
+	collect := func(cols []SQLColumn, row *data.Tuple) ErrCode {
+		if len(cols) != 2 || cols[1].Name != "c2" {
+			t.Fatalf("columns=%+v", cols)
+		}
+		var key, val uint32
+		_ = TupleReadU32(row, 0, &key)
+		_ = TupleReadU32(row, 1, &val)
+		seen = append(seen, [2]uint32{key, val})
+		return DB_SUCCESS
+	}
+	if err := ExecSQLFunc(nil, `SELECT * FROM "sql_auto_db/t"`, collect); err != DB_SUCCESS {
+		t.Fatalf("ExecSQLFunc select: %v", err)
+	}
+	if len(seen) != 2 || seen[0] != [2]uint32{1, 10} || seen[1] != [2]uint32{2, 20} {
+		t.Fatalf("rows=%v", seen)
+	}
+
+	stop := func([]SQLColumn, *data.Tuple) ErrCode { return DB_INTERRUPTED }
+	if err := ExecSQLFunc(nil, `SELECT * FROM "sql_auto_db/t"`, stop); err != DB_INTERRUPTED {
+		t.Fatalf("stopped select err=%v want %v", err, DB_INTERRUPTED)
+	}
+
+	ibTrx := TrxBegin(IB_TRX_REPEATABLE_READ)
+	if err := ExecSQLFunc(ibTrx, `DELETE FROM "sql_auto_db/t" WHERE c1 = 1`, nil); err != DB_SUCCESS {
+		t.Fatalf("ExecSQLFunc delete: %v", err)
+	}
+	if err := TrxRollback(ibTrx); err != DB_SUCCESS {
+		t.Fatalf("TrxRollback: %v", err)
+	}
+	seen = nil
+	if err := ExecSQLFunc(nil, `SELECT * FROM "sql_auto_db/t"`, collect); err != DB_SUCCESS || len(seen) != 2 {
+		t.Fatalf("rows after rollback=%v err=%v", seen, err)
+	}
+}
+
+func TestExecSQLTrxIndexPaths(t *testing.T) {
+	tableName := setupU32Table(t, "sql_path_db")
+	seedU32Rows(t, tableName, 1, 2, 3, 4, 5, 6)
+	var sec *IndexSchema
+	if err := IndexSchemaCreate(nil, "idx_c2", tableName, &sec); err != DB_SUCCESS {
+		t.Fatalf("IndexSchemaCreate: %v", err)
+	}
+	if err := IndexSchemaAddCol(sec, "c2", 0); err != DB_SUCCESS {
+		t.Fatalf("IndexSchemaAddCol: %v", err)
+	}
+	if err := IndexCreate(sec, nil); err != DB_SUCCESS {
+		t.Fatalf("IndexCreate: %v", err)
+	}
+
+	ibTrx := TrxBegin(IB_TRX_REPEATABLE_READ)
+	defer func() { _ = TrxRollback(ibTrx) }()
+	keys := func(sql string, args ...SQLArg) []uint32 {
+		t.Helper()
+		var out []uint32
+		for _, r := range sqlU32Rows(t, ibTrx, sql, args...) {
+			out = append(out, r[0])
+		}
+		return out
+	}
+	tests := []struct {
+		sql  string
+		args []SQLArg
+		want []uint32
+	}{
+		{`SELECT * FROM "sql_path_db/t" WHERE c1 = ?`, []SQLArg{SQLArgIntUnsigned("1", 4, 3)}, []uint32{3}},
+		{`SELECT * FROM "sql_path_db/t" WHERE c1 > ? AND c1 <= ?`,
+			[]SQLArg{SQLArgIntUnsigned("1", 4, 2), SQLArgIntUnsigned("2", 4, 4)}, []uint32{3, 4}},
+		{`SELECT * FROM "sql_path_db/t" WHERE c2 >= ?`, []SQLArg{SQLArgIntUnsigned("1", 4, 500)}, []uint32{5, 6}},
+		{`SELECT * FROM "sql_path_db/t" WHERE c2 < ? AND c1 <> ?`,
+			[]SQLArg{SQLArgIntUnsigned("1", 4, 300), SQLArgIntUnsigned("2", 4, 1)}, []uint32{2}},
+		{`SELECT * FROM "sql_path_db/t" WHERE c2 = ? OR c1 = ?`,
+			[]SQLArg{SQLArgIntUnsigned("1", 4, 100), SQLArgIntUnsigned("2", 4, 6)}, []uint32{1, 6}},
+	}
+	for _, tt := range tests {
+		got := keys(tt.sql, tt.args...)
+		if len(got) != len(tt.want) {
+			t.Fatalf("%s: rows=%v want %v", tt.sql, got, tt.want)
+		}
+		for i := range tt.want {
+			if got[i] != tt.want[i] {
+				t.Fatalf("%s: rows=%v want %v", tt.sql, got, tt.want)
+			}
+		}
+	}
+
+	res, err := ExecSQLTrx(ibTrx, `UPDATE "sql_path_db/t" SET c2 = 50 WHERE c2 > ? AND c2 < ?`,
+		SQLArgIntUnsigned("1", 4, 100), SQLArgIntUnsigned("2", 4, 400))
+	if err != DB_SUCCESS || res.RowsAffected != 2 {
+		t.Fatalf("update err=%v res=%+v", err, res)
+	}
+	if got := keys(`SELECT * FROM "sql_path_db/t" WHERE c2 = 50`); len(got) != 2 || got[0] != 2 || got[1] != 3 {
+		t.Fatalf("updated rows=%v", got)
+	}
+	res, err = ExecSQLTrx(ibTrx, `DELETE FROM "sql_path_db/t" WHERE c1 >= ?`, SQLArgIntUnsigned("1", 4, 5))
+	if err != DB_SUCCESS || res.RowsAffected != 2 {
+		t.Fatalf("delete err=%v res=%+v", err, res)
+	}
+	if got := keys(`SELECT * FROM "sql_path_db/t"`); len(got) != 4 {
+		t.Fatalf("rows after delete=%v", got)
+	}
+}
+
+func TestExecSQLTrxOrderLimitProjection(t *testing.T) {
+	tableName := setupU32Table(t, "sql_order_db")
+	seedU32Rows(t, tableName, 4, 1, 5, 3, 2)
+
+	ibTrx := TrxBegin(IB_TRX_REPEATABLE_READ)
+	defer func() { _ = TrxRollback(ibTrx) }()
+	rows := sqlU32Rows(t, ibTrx, `SELECT c1, c2 FROM "sql_order_db/t" ORDER BY c2 DESC LIMIT 2 OFFSET 1`)
+	if len(rows) != 2 || rows[0][0] != 4 || rows[1][0] != 3 {
+		t.Fatalf("rows=%v", rows)
+	}
+	rows = sqlU32Rows(t, ibTrx, `SELECT * FROM "sql_order_db/t" ORDER BY c1 LIMIT ?`, SQLArgIntUnsigned("1", 4, 3))
+	if len(rows) != 3 || rows[0][0] != 1 || rows[2][0] != 3 {
+		t.Fatalf("rows=%v", rows)
+	}
+
+	res, err := ExecSQLTrx(ibTrx, `SELECT c1, c2 - c1 * 200 AS diff, CONCAT('k', c1) tag FROM "sql_order_db/t" WHERE c1 >= 4 ORDER BY diff`)
+	if err != DB_SUCCESS {
+		t.Fatalf("ExecSQLTrx: %v", err)
+	}
+	if len(res.Columns) != 3 || res.Columns[1].Name != "diff" || res.Columns[1].Type != IB_INT ||
+		res.Columns[1].Attr&IB_COL_UNSIGNED != 0 || res.Columns[2].Name != "tag" || res.Columns[2].Type != IB_VARCHAR {
+		t.Fatalf("columns=%+v", res.Columns)
+	}
+	if len(res.Rows) != 2 {
+		t.Fatalf("rows=%d", len(res.Rows))
+	}
+	var diff int64
+	if err := TupleReadI64(res.Rows[0], 1, &diff); err != DB_SUCCESS || diff != -500 {
+		t.Fatalf("diff=%d err=%v", diff, err)
+	}
+	if got := string(res.Rows[0].Fields[2].Data); got != "k5" {
+		t.Fatalf("tag=%q", got)
+	}
+}
+
+func TestExecSQLTrxGroupBy(t *testing.T) {
+	tableName := setupU32Table(t, "sql_group_db")
+	seedU32Rows(t, tableName, 1, 2, 3, 4, 5)
+
+	ibTrx := TrxBegin(IB_TRX_REPEATABLE_READ)
+	defer func() { _ = TrxRollback(ibTrx) }()
+	res, err := ExecSQLTrx(ibTrx, `SELECT c1 % 2 AS odd, COUNT(*), SUM(c2), MAX(c1), AVG(c1) FROM "sql_group_db/t" GROUP BY c1 % 2 HAVING SUM(c2) > ? ORDER BY odd`,
+		SQLArgIntUnsigned("1", 4, 500))
+	if err != DB_SUCCESS {
+		t.Fatalf("ExecSQLTrx: %v", err)
+	}
+	wantTypes := []ColType{IB_INT, IB_INT, IB_INT, IB_INT, IB_DOUBLE}
+	for i, col := range res.Columns {
+		if col.Type != wantTypes[i] {
+			t.Fatalf("columns=%+v", res.Columns)
+		}
+	}
+	if len(res.Rows) != 2 {
+		t.Fatalf("rows=%d", len(res.Rows))
+	}
+	var count, sum, maxKey int64
+	var avg float64
+	row := res.Rows[1]
+	if TupleReadI64(row, 1, &count) != DB_SUCCESS || TupleReadI64(row, 2, &sum) != DB_SUCCESS ||
+		TupleReadI64(row, 3, &maxKey) != DB_SUCCESS || TupleReadDouble(row, 4, &avg) != DB_SUCCESS {
+		t.Fatalf("read row")
+	}
+	if count != 3 || sum != 900 || maxKey != 5 || avg != 3 {
+		t.Fatalf("odd group count=%d sum=%d max=%d avg=%v", count, sum, maxKey, avg)
+	}
+	res, err = ExecSQLTrx(ibTrx, `SELECT MAX(c2) FROM "sql_group_db/t" WHERE c1 < 3`)
+	if err != DB_SUCCESS || len(res.Rows) != 1 {
+		t.Fatalf("max err=%v", err)
+	}
+	if TupleReadI64(res.Rows[0], 0, &maxKey) != DB_SUCCESS || maxKey != 200 {
+		t.Fatalf("max=%d", maxKey)
+	}
+}
+
+func TestExecSQLTrxJoin(t *testing.T) {
+	tableName := setupU32Table(t, "sql_join_db")
+	seedU32Rows(t, tableName, 1, 2, 3)
+	for _, sql := range []string{
+		`CREATE TABLE "sql_join_db/o" (oid INT UNSIGNED PRIMARY KEY, tid INT UNSIGNED, note VARCHAR(8))`,
+		`CREATE INDEX idx_tid ON "sql_join_db/o" (tid)`,
+		`INSERT INTO "sql_join_db/o" VALUES (10, 1, 'x')`,
+		`INSERT INTO "sql_join_db/o" VALUES (11, 3, 'y')`,
+		`INSERT INTO "sql_join_db/o" VALUES (12, 3, 'z')`,
+	} {
+		if err := ExecDDLSQL(sql); err != DB_SUCCESS {
+			t.Fatalf("ExecDDLSQL %q: %v", sql, err)
+		}
+	}
+
+	ibTrx := TrxBegin(IB_TRX_REPEATABLE_READ)
+	defer func() { _ = TrxRollback(ibTrx) }()
+	res, err := ExecSQLTrx(ibTrx, `SELECT t.c1, o.oid, note FROM "sql_join_db/t" t LEFT JOIN "sql_join_db/o" o ON o.tid = t.c1 ORDER BY t.c1, oid`)
+	if err != DB_SUCCESS {
+		t.Fatalf("ExecSQLTrx: %v", err)
+	}
+	if len(res.Columns) != 3 || res.Columns[1].Name != "oid" || res.Columns[1].Type != IB_INT || res.Columns[2].Type != IB_VARCHAR {
+		t.Fatalf("columns=%+v", res.Columns)
+	}
+	want := [][2]uint32{{1, 10}, {2, 0}, {3, 11}, {3, 12}}
+	if len(res.Rows) != len(want) {
+		t.Fatalf("rows=%d", len(res.Rows))
+	}
+	for i, row := range res.Rows {
+		var key, oid uint32
+		if TupleReadU32(row, 0, &key) != DB_SUCCESS || key != want[i][0] {
+			t.Fatalf("row %d key=%d", i, key)
+		}
+		if want[i][1] == 0 {
+			if !data.FieldIsNull(&row.Fields[1]) {
+				t.Fatalf("row %d: unmatched oid not NULL", i)
+			}
+			continue
+		}
+		if TupleReadU32(row, 1, &oid) != DB_SUCCESS || oid != want[i][1] {
+			t.Fatalf("row %d oid=%d", i, oid)
+		}
+	}
+
+	rows := sqlU32Rows(t, ibTrx, `SELECT a.c1, b.c2 FROM "sql_join_db/t" a, "sql_join_db/t" b WHERE b.c1 = a.c1 AND a.c1 > 1`)
+	if len(rows) != 2 || rows[0] != [2]uint32{2, 200} || rows[1] != [2]uint32{3, 300} {
+		t.Fatalf("self join rows=%v", rows)
+	}
+}
+
+func TestExecSQLProcedure(t *testing.T) {
+	tableName := setupU32Table(t, "sql_proc_db")
+	seedU32Rows(t, tableName, 1, 2, 3)
+
+	proc := `PROCEDURE bump(lim IN INT, moved OUT INT) IS
+		k INT;
+		v INT;
+		CURSOR c IS SELECT c1, c2 FROM "sql_proc_db/t" WHERE c1 <= lim;
+	BEGIN
+		moved := 0;
+		OPEN c;
+		WHILE 1 = 1 LOOP
+			FETCH c INTO k, v;
+			IF c%NOTFOUND THEN
+				EXIT;
+			END IF;
+			v := v + 1;
+			UPDATE "sql_proc_db/t" SET c2 = v WHERE c1 = k;
+			moved := moved + 1;
+		END LOOP;
+		CLOSE c;
+	END;`
+	ibTrx := TrxBegin(IB_TRX_REPEATABLE_READ)
+	res, err := ExecSQLTrx(ibTrx, proc, SQLArgIntSigned("lim", 4, 2))
+	if err != DB_SUCCESS {
+		t.Fatalf("ExecSQLTrx: %v", err)
+	}
+	var moved int64
+	if len(res.Rows) != 1 || len(res.Columns) != 1 || res.Columns[0].Name != "moved" || res.Columns[0].Type != IB_INT {
+		t.Fatalf("result=%+v", res)
+	}
+	if TupleReadI64(res.Rows[0], 0, &moved) != DB_SUCCESS || moved != 2 || res.RowsAffected != 2 {
+		t.Fatalf("moved=%d affected=%d", moved, res.RowsAffected)
+	}
+	if err := TrxCommit(ibTrx); err != DB_SUCCESS {
+		t.Fatalf("TrxCommit: %v", err)
+	}
+
+	// A failing statement rolls back everything the procedure changed.
+	err = ExecSQL(`PROCEDURE p() IS BEGIN
+		INSERT INTO "sql_proc_db/t" VALUES (9, 900);
+		INSERT INTO "sql_proc_db/t" VALUES (1, 100);
+	END;`)
+	if err != DB_DUPLICATE_KEY {
+		t.Fatalf("ExecSQL: %v, want DB_DUPLICATE_KEY", err)
+	}
+	if _, err := Prepare(proc); err != DB_UNSUPPORTED {
+		t.Fatalf("Prepare procedure: %v", err)
+	}
+
+	ibTrx = TrxBegin(IB_TRX_REPEATABLE_READ)
+	defer func() { _ = TrxRollback(ibTrx) }()
+	rows := sqlU32Rows(t, ibTrx, `SELECT c1, c2 FROM "sql_proc_db/t" ORDER BY c1`)
+	want := [][2]uint32{{1, 101}, {2, 201}, {3, 300}}
+	if len(rows) != len(want) {
+		t.Fatalf("rows=%v", rows)
+	}
+	for i := range want {
+		if rows[i] != want[i] {
+			t.Fatalf("rows=%v want %v", rows, want)
+		}
+	}
+}
+
+func TestExecSQLTrxExplain(t *testing.T) {
+	tableName := setupU32Table(t, "sql_explain_db")
+	seedU32Rows(t, tableName, 1, 2, 3, 4)
+
+	ibTrx := TrxBegin(IB_TRX_REPEATABLE_READ)
+	defer func() { _ = TrxRollback(ibTrx) }()
+	res, err := ExecSQLTrx(ibTrx, `EXPLAIN DELETE FROM "sql_explain_db/t" WHERE c1 >= :k`, SQLArgIntUnsigned(":k", 4, 3))
+	if err != DB_SUCCESS {
+		t.Fatalf("ExecSQLTrx: %v", err)
+	}
+	if len(res.Columns) != 8 || res.Columns[6].Name != "rows" || res.Columns[6].Type != IB_INT || len(res.Rows) != 1 {
+		t.Fatalf("result=%+v", res)
+	}
+	row := res.Rows[0]
+	var rows int64
+	if string(row.Fields[1].Data) != "delete" || string(row.Fields[3].Data) != "index range" || string(row.Fields[4].Data) != "PRIMARY" {
+		t.Fatalf("step=%q %q %q", row.Fields[1].Data, row.Fields[3].Data, row.Fields[4].Data)
+	}
+	if TupleReadI64(row, 6, &rows) != DB_SUCCESS || rows != 2 {
+		t.Fatalf("rows=%d", rows)
+	}
+	if got := sqlU32Rows(t, ibTrx, `SELECT * FROM "sql_explain_db/t"`); len(got) != 4 {
+		t.Fatalf("explain changed rows: %v", got)
+	}
+}
+
+func sqlIDs(t *testing.T, ibTrx *trx.Trx, sql string, args ...SQLArg) []uint32 {
+	t.Helper()
+	res, err := ExecSQLTrx(ibTrx, sql, args...)
+	if err != DB_SUCCESS {
+		t.Fatalf("ExecSQLTrx %q: %v", sql, err)
+	}
+	ids := make([]uint32, 0, len(res.Rows))
+	for _, tpl := range res.Rows {
+		var id uint32
+		if err := TupleReadU32(tpl, 0, &id); err != DB_SUCCESS {
+			t.Fatalf("TupleReadU32 id: %v", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+func TestExecSQLTrxSignedCompare(t *testing.T) {
+	setupU32Table(t, "sql_signed_db")
+	for _, sql := range []string{
+		`CREATE TABLE "sql_signed_db/s" (id INT UNSIGNED PRIMARY KEY, v INT, d DOUBLE)`,
+		`INSERT INTO "sql_signed_db/s" VALUES (2, 3, '3.5')`,
+		`INSERT INTO "sql_signed_db/s" VALUES (3, 10, '10.5')`,
+	} {
+		if err := ExecDDLSQL(sql); err != DB_SUCCESS {
+			t.Fatalf("ExecDDLSQL %q: %v", sql, err)
+		}
+	}
+	if err := ExecSQL(`INSERT INTO "sql_signed_db/s" VALUES (1, :v, :d)`,
+		SQLArgIntSigned("v", 4, -5), SQLArgString("d", "-5.5")); err != DB_SUCCESS {
+		t.Fatalf("ExecSQL insert: %v", err)
+	}
+
+	ibTrx := TrxBegin(IB_TRX_REPEATABLE_READ)
+	defer func() { _ = TrxRollback(ibTrx) }()
+	for _, tc := range []struct {
+		sql  string
+		args []SQLArg
+		want []uint32
+	}{
+		{sql: `SELECT id FROM "sql_signed_db/s" WHERE v > 0`, want: []uint32{2, 3}},
+		{sql: `SELECT id FROM "sql_signed_db/s" WHERE v < 0`, want: []uint32{1}},
+		{sql: `SELECT id FROM "sql_signed_db/s" WHERE v > :x`, args: []SQLArg{SQLArgIntSigned("x", 4, -10)}, want: []uint32{1, 2, 3}},
+		{sql: `SELECT id FROM "sql_signed_db/s" WHERE v = :x`, args: []SQLArg{SQLArgIntSigned("x", 4, -5)}, want: []uint32{1}},
+		{sql: `SELECT id FROM "sql_signed_db/s" WHERE d < 0`, want: []uint32{1}},
+		{sql: `SELECT id FROM "sql_signed_db/s" WHERE d > '3.5' OR v < :x`, args: []SQLArg{SQLArgIntSigned("x", 4, -1)}, want: []uint32{1, 3}},
+	} {
+		got := sqlIDs(t, ibTrx, tc.sql, tc.args...)
+		if len(got) != len(tc.want) {
+			t.Fatalf("%s: ids=%v want %v", tc.sql, got, tc.want)
+		}
+		for i := range tc.want {
+			if got[i] != tc.want[i] {
+				t.Fatalf("%s: ids=%v want %v", tc.sql, got, tc.want)
+			}
+		}
+	}
+}
+
+func TestExecSQLTrxSignedRange(t *testing.T) {
+	setupU32Table(t, "sql_signed_range_db")
+	for _, sql := range []string{
+		`CREATE TABLE "sql_signed_range_db/s" (id INT UNSIGNED PRIMARY KEY, v INT)`,
+		`CREATE INDEX idx_v ON "sql_signed_range_db/s" (v)`,
+		`INSERT INTO "sql_signed_range_db/s" VALUES (2, 3)`,
+		`INSERT INTO "sql_signed_range_db/s" VALUES (3, 10)`,
+	} {
+		if err := ExecDDLSQL(sql); err != DB_SUCCESS {
+			t.Fatalf("ExecDDLSQL %q: %v", sql, err)
+		}
+	}
+	if err := ExecSQL(`INSERT INTO "sql_signed_range_db/s" VALUES (1, :v)`, SQLArgIntSigned("v", 4, -5)); err != DB_SUCCESS {
+		t.Fatalf("ExecSQL insert: %v", err)
+	}
+
+	ibTrx := TrxBegin(IB_TRX_REPEATABLE_READ)
+	defer func() { _ = TrxRollback(ibTrx) }()
+	for _, tc := range []struct {
+		sql  string
+		args []SQLArg
+		want []uint32
+	}{
+		{sql: `SELECT id FROM "sql_signed_range_db/s" WHERE v > :lo AND v < :hi`,
+			args: []SQLArg{SQLArgIntSigned("lo", 4, -10), SQLArgIntSigned("hi", 4, 5)}, want: []uint32{1, 2}},
+		{sql: `SELECT id FROM "sql_signed_range_db/s" WHERE v > :lo AND v >= :hi`,
+			args: []SQLArg{SQLArgIntSigned("lo", 4, -10), SQLArgIntSigned("hi", 4, 0)}, want: []uint32{2, 3}},
+		{sql: `SELECT id FROM "sql_signed_range_db/s" WHERE v = :x`,
+			args: []SQLArg{SQLArgIntSigned("x", 4, -5)}, want: []uint32{1}},
+	} {
+		got := sqlIDs(t, ibTrx, tc.sql, tc.args...)
+		if len(got) != len(tc.want) {
+			t.Fatalf("%s: ids=%v want %v", tc.sql, got, tc.want)
+		}
+		for i := range tc.want {
+			if got[i] != tc.want[i] {
+				t.Fatalf("%s: ids=%v want %v", tc.sql, got, tc.want)
+			}
+		}
+	}
+}
+
+func TestExecSQLTrxSignedOrder(t *testing.T) {
+	setupU32Table(t, "sql_signed_order_db")
+	for _, sql := range []string{
+		`CREATE TABLE "sql_signed_order_db/s" (id INT UNSIGNED PRIMARY KEY, v INT, d DOUBLE)`,
+		`CREATE INDEX idx_v ON "sql_signed_order_db/s" (v)`,
+		`INSERT INTO "sql_signed_order_db/s" VALUES (2, 3, '3.5')`,
+		`INSERT INTO "sql_signed_order_db/s" VALUES (3, 10, '10.5')`,
+	} {
+		if err := ExecDDLSQL(sql); err != DB_SUCCESS {
+			t.Fatalf("ExecDDLSQL %q: %v", sql, err)
+		}
+	}
+	if err := ExecSQL(`INSERT INTO "sql_signed_order_db/s" VALUES (1, :v, :d)`,
+		SQLArgIntSigned("v", 4, -5), SQLArgString("d", "-5.5")); err != DB_SUCCESS {
+		t.Fatalf("ExecSQL insert: %v", err)
+	}
+
+	ibTrx := TrxBegin(IB_TRX_REPEATABLE_READ)
+	defer func() { _ = TrxRollback(ibTrx) }()
+	for _, tc := range []struct {
+		sql  string
+		want []uint32
+	}{
+		{`SELECT id FROM "sql_signed_order_db/s" ORDER BY v`, []uint32{1, 2, 3}},
+		{`SELECT id FROM "sql_signed_order_db/s" ORDER BY v DESC`, []uint32{3, 2, 1}},
+		{`SELECT id FROM "sql_signed_order_db/s" ORDER BY d`, []uint32{1, 2, 3}},
+		{`SELECT id, v FROM "sql_signed_order_db/s" ORDER BY 2 LIMIT 1`, []uint32{1}},
+	} {
+		got := sqlIDs(t, ibTrx, tc.sql)
+		if len(got) != len(tc.want) {
+			t.Fatalf("%s: ids=%v want %v", tc.sql, got, tc.want)
+		}
+		for i := range tc.want {
+			if got[i] != tc.want[i] {
+				t.Fatalf("%s: ids=%v want %v", tc.sql, got, tc.want)
+			}
+		}
+	}
+}
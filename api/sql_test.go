@@ -59,8 +59,8 @@ func TestExecSQLRequiresStartup(t *testing.T) {
 	if err := Startup(""); err != DB_SUCCESS {
 		t.Fatalf("Startup got %v, want %v", err, DB_SUCCESS)
 	}
-	if got := ExecSQL("select 1"); got != DB_UNSUPPORTED {
-		t.Fatalf("ExecSQL got %v, want %v", got, DB_UNSUPPORTED)
+	if got := ExecSQL("select 1"); got != DB_INVALID_INPUT {
+		t.Fatalf("ExecSQL got %v, want %v", got, DB_INVALID_INPUT)
 	}
 	_ = Shutdown(ShutdownNormal)
 }
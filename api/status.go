@@ -47,6 +47,14 @@ var statusVars = []statusVar{
 	{"buffer_pool_pages_written", statusUlint, &srv.ExportVars.InnodbPagesWritten, nil, nil},
 	{"double_write_pages_written", statusUlint, &srv.ExportVars.InnodbDblwrPagesWritten, nil, nil},
 	{"double_write_invoked", statusUlint, &srv.ExportVars.InnodbDblwrWrites, nil, nil},
+	{"zip_compress_ops", statusUlint, &srv.ExportVars.InnodbZipCompressOps, nil, nil},
+	{"zip_compress_failures", statusUlint, &srv.ExportVars.InnodbZipCompressFailures, nil, nil},
+	{"zip_decompress_ops", statusUlint, &srv.ExportVars.InnodbZipDecompressOps, nil, nil},
+	{"page_compression_saved", statusUlint, &srv.ExportVars.InnodbPageCompressionSaved, nil, nil},
+	{"pages_page_compressed", statusUlint, &srv.ExportVars.InnodbPagesPageCompressed, nil, nil},
+	{"pages_page_decompressed", statusUlint, &srv.ExportVars.InnodbPagesPageDecompressed, nil, nil},
+	{"pages_encrypted", statusUlint, &srv.ExportVars.InnodbPagesEncrypted, nil, nil},
+	{"pages_decrypted", statusUlint, &srv.ExportVars.InnodbPagesDecrypted, nil, nil},
 	{"log_buffer_slot_waits", statusUlint, &srv.ExportVars.InnodbLogWaits, nil, nil},
 	{"log_write_reqs", statusUlint, &srv.ExportVars.InnodbLogWriteRequests, nil, nil},
 	{"log_write_flush_count", statusUlint, &srv.ExportVars.InnodbLogWrites, nil, nil},
@@ -0,0 +1,519 @@
+package api
+
+import (
+	"encoding/binary"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/wilhasse/innodb-go/data"
+)
+
+// StructTag is the struct tag key read by StructMapper.
+//
+// The tag value is the column name followed by comma separated options:
+//
+//	pk              the column is part of the clustered PRIMARY index
+//	index=NAME      the column is part of secondary index NAME
+//	unique=NAME     the column is part of unique secondary index NAME
+//	size=N          column size for string and []byte fields
+//	type=T          column type for string and []byte fields: varchar, char,
+//	                binary, varbinary, blob or decimal
+//
+// Exported fields without a tag map to a column named after the field; a tag
+// of "-" skips the field. Pointer fields map to nullable columns and a nil
+// pointer is stored as NULL; all other fields map to NOT NULL columns.
+const StructTag = "innodb"
+
+// StructMapper converts between a struct type and tuples of a table.
+type StructMapper struct {
+	typ     reflect.Type
+	fields  []structField
+	indexes []structIndex
+}
+
+type structField struct {
+	index []int
+	kind  reflect.Kind
+	ptr   bool
+	col   ColumnSchema
+	pos   int
+}
+
+type structIndex struct {
+	name    string
+	unique  bool
+	columns []string
+}
+
+var structMappers sync.Map
+
+// NewStructMapper builds a mapper for the struct type of v, which may be a
+// struct value or a pointer to one. Columns are numbered in field order until
+// the mapper is bound to an existing table with Bind.
+func NewStructMapper(v any) (*StructMapper, ErrCode) {
+	typ := reflect.TypeOf(v)
+	for typ != nil && typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	if typ == nil || typ.Kind() != reflect.Struct {
+		return nil, DB_ERROR
+	}
+	if cached, ok := structMappers.Load(typ); ok {
+		return cached.(*StructMapper), DB_SUCCESS
+	}
+	mapper := &StructMapper{typ: typ}
+	if err := mapper.addFields(typ, nil); err != DB_SUCCESS {
+		return nil, err
+	}
+	if len(mapper.fields) == 0 {
+		return nil, DB_SCHEMA_ERROR
+	}
+	cached, _ := structMappers.LoadOrStore(typ, mapper)
+	return cached.(*StructMapper), DB_SUCCESS
+}
+
+func (mapper *StructMapper) addFields(typ reflect.Type, parent []int) ErrCode {
+	for i := 0; i < typ.NumField(); i++ {
+		sf := typ.Field(i)
+		tag, tagged := sf.Tag.Lookup(StructTag)
+		if tag == "-" {
+			continue
+		}
+		path := append(append([]int(nil), parent...), i)
+		if sf.Anonymous && !tagged && sf.Type.Kind() == reflect.Struct {
+			if err := mapper.addFields(sf.Type, path); err != DB_SUCCESS {
+				return err
+			}
+			continue
+		}
+		if !sf.IsExported() {
+			continue
+		}
+		field, err := mapper.parseField(sf, tag, path)
+		if err != DB_SUCCESS {
+			return err
+		}
+		field.pos = len(mapper.fields)
+		mapper.fields = append(mapper.fields, field)
+	}
+	return DB_SUCCESS
+}
+
+func (mapper *StructMapper) parseField(sf reflect.StructField, tag string, path []int) (structField, ErrCode) {
+	parts := strings.Split(tag, ",")
+	name := strings.TrimSpace(parts[0])
+	if name == "" {
+		name = sf.Name
+	}
+	field := structField{index: path, col: ColumnSchema{Name: name}}
+	typ := sf.Type
+	if typ.Kind() == reflect.Pointer {
+		field.ptr = true
+		typ = typ.Elem()
+	}
+	field.kind = typ.Kind()
+	var typeName string
+	for _, opt := range parts[1:] {
+		key, value, _ := strings.Cut(strings.TrimSpace(opt), "=")
+		switch key {
+		case "":
+		case "pk":
+			mapper.addIndexColumn("PRIMARY", false, name)
+		case "index", "unique":
+			if value == "" {
+				return field, DB_SCHEMA_ERROR
+			}
+			mapper.addIndexColumn(value, key == "unique", name)
+		case "size":
+			size, err := strconv.ParseUint(value, 10, 32)
+			if err != nil {
+				return field, DB_SCHEMA_ERROR
+			}
+			field.col.Size = uint32(size)
+		case "type":
+			typeName = value
+		default:
+			return field, DB_SCHEMA_ERROR
+		}
+	}
+	if err := setStructColumnType(&field, typ, typeName); err != DB_SUCCESS {
+		return field, err
+	}
+	if !field.ptr {
+		field.col.Attr |= IB_COL_NOT_NULL
+	}
+	return field, DB_SUCCESS
+}
+
+func setStructColumnType(field *structField, typ reflect.Type, typeName string) ErrCode {
+	col := &field.col
+	switch field.kind {
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Int:
+		col.Type = IB_INT
+		col.Size = uint32(typ.Size())
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uint:
+		col.Type = IB_INT
+		col.Attr = IB_COL_UNSIGNED
+		col.Size = uint32(typ.Size())
+	case reflect.Bool:
+		col.Type = IB_INT
+		col.Attr = IB_COL_UNSIGNED
+		col.Size = 1
+	case reflect.Float32:
+		col.Type = IB_FLOAT
+		col.Size = 4
+	case reflect.Float64:
+		col.Type = IB_DOUBLE
+		col.Size = 8
+	case reflect.String:
+		col.Type = IB_VARCHAR
+	case reflect.Slice:
+		if typ.Elem().Kind() != reflect.Uint8 {
+			return DB_SCHEMA_ERROR
+		}
+		col.Type = IB_BLOB
+	default:
+		return DB_SCHEMA_ERROR
+	}
+	if typeName == "" {
+		return DB_SUCCESS
+	}
+	if field.kind != reflect.String && field.kind != reflect.Slice {
+		return DB_SCHEMA_ERROR
+	}
+	switch strings.ToLower(typeName) {
+	case "varchar":
+		col.Type = IB_VARCHAR
+	case "char":
+		col.Type = IB_CHAR
+	case "binary":
+		col.Type = IB_BINARY
+	case "varbinary":
+		col.Type = IB_VARBINARY
+	case "blob":
+		col.Type = IB_BLOB
+	case "decimal":
+		col.Type = IB_DECIMAL
+	default:
+		return DB_SCHEMA_ERROR
+	}
+	return DB_SUCCESS
+}
+
+func (mapper *StructMapper) addIndexColumn(name string, unique bool, column string) {
+	for i := range mapper.indexes {
+		if strings.EqualFold(mapper.indexes[i].name, name) {
+			mapper.indexes[i].unique = mapper.indexes[i].unique || unique
+			mapper.indexes[i].columns = append(mapper.indexes[i].columns, column)
+			return
+		}
+	}
+	mapper.indexes = append(mapper.indexes, structIndex{name: name, unique: unique, columns: []string{column}})
+}
+
+// TableSchema derives a table schema with one column per mapped field and the
+// indexes named in the tags.
+func (mapper *StructMapper) TableSchema(name string, format TableFormat, pageSize int, out **TableSchema) ErrCode {
+	if mapper == nil || out == nil {
+		return DB_ERROR
+	}
+	var schema *TableSchema
+	if err := TableSchemaCreate(name, &schema, format, pageSize); err != DB_SUCCESS {
+		return err
+	}
+	for _, field := range mapper.fields {
+		col := field.col
+		if err := TableSchemaAddCol(schema, col.Name, col.Type, col.Attr, col.Flags, col.Size); err != DB_SUCCESS {
+			return err
+		}
+	}
+	for _, idx := range mapper.indexes {
+		var index *IndexSchema
+		if err := TableSchemaAddIndex(schema, idx.name, &index); err != DB_SUCCESS {
+			return err
+		}
+		for _, col := range idx.columns {
+			if err := IndexSchemaAddCol(index, col, 0); err != DB_SUCCESS {
+				return err
+			}
+		}
+		if strings.EqualFold(idx.name, "PRIMARY") {
+			_ = IndexSchemaSetClustered(index)
+		} else if idx.unique {
+			_ = IndexSchemaSetUnique(index)
+		}
+	}
+	*out = schema
+	return DB_SUCCESS
+}
+
+// Bind returns a mapper whose fields are matched to the columns of schema by
+// name, so it can read and write tuples of a table whose column order differs
+// from the struct. Every mapped field must name a compatible column.
+func (mapper *StructMapper) Bind(schema *TableSchema) (*StructMapper, ErrCode) {
+	if mapper == nil || schema == nil {
+		return nil, DB_ERROR
+	}
+	bound := &StructMapper{typ: mapper.typ, indexes: mapper.indexes}
+	bound.fields = make([]structField, len(mapper.fields))
+	for i, field := range mapper.fields {
+		pos := -1
+		for j := range schema.Columns {
+			if strings.EqualFold(schema.Columns[j].Name, field.col.Name) {
+				pos = j
+				break
+			}
+		}
+		if pos < 0 || !structKindFits(field.kind, schema.Columns[pos].Type) {
+			return nil, DB_SCHEMA_ERROR
+		}
+		field.col = schema.Columns[pos]
+		field.pos = pos
+		bound.fields[i] = field
+	}
+	return bound, DB_SUCCESS
+}
+
+func structKindFits(kind reflect.Kind, typ ColType) bool {
+	switch kind {
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Int,
+		reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uint, reflect.Bool:
+		return typ == IB_INT
+	case reflect.Float32, reflect.Float64:
+		return typ == IB_FLOAT || typ == IB_DOUBLE
+	case reflect.String, reflect.Slice:
+		switch typ {
+		case IB_INT, IB_FLOAT, IB_DOUBLE, IB_SYS:
+			return false
+		}
+		return true
+	default:
+		return false
+	}
+}
+
+// Write encodes the struct v, a struct value or pointer, into tpl.
+func (mapper *StructMapper) Write(tpl *data.Tuple, v any) ErrCode {
+	val, err := mapper.structValue(v, false)
+	if err != DB_SUCCESS {
+		return err
+	}
+	if tpl == nil {
+		return DB_ERROR
+	}
+	for _, field := range mapper.fields {
+		if field.pos >= len(tpl.Fields) {
+			return DB_ERROR
+		}
+		fv := val.FieldByIndex(field.index)
+		if field.ptr {
+			if fv.IsNil() {
+				if field.col.Attr&IB_COL_NOT_NULL != 0 {
+					return DB_DATA_MISMATCH
+				}
+				tpl.Fields[field.pos] = data.Field{Len: data.UnivSQLNull}
+				continue
+			}
+			fv = fv.Elem()
+		}
+		buf, err := encodeStructField(field.col, fv)
+		if err != DB_SUCCESS {
+			return err
+		}
+		tpl.Fields[field.pos] = data.Field{Data: buf, Len: uint32(len(buf))}
+	}
+	return DB_SUCCESS
+}
+
+// Read decodes tpl into the struct pointed to by v. NULL columns set pointer
+// fields to nil and fail with DB_DATA_MISMATCH for other fields.
+func (mapper *StructMapper) Read(tpl *data.Tuple, v any) ErrCode {
+	val, err := mapper.structValue(v, true)
+	if err != DB_SUCCESS {
+		return err
+	}
+	if tpl == nil {
+		return DB_ERROR
+	}
+	for _, field := range mapper.fields {
+		if field.pos >= len(tpl.Fields) {
+			return DB_ERROR
+		}
+		fv := val.FieldByIndex(field.index)
+		if ColGetLen(tpl, field.pos) == Ulint(IBSQLNull) {
+			if !field.ptr {
+				return DB_DATA_MISMATCH
+			}
+			fv.Set(reflect.Zero(fv.Type()))
+			continue
+		}
+		target := fv
+		if field.ptr {
+			target = reflect.New(fv.Type().Elem()).Elem()
+		}
+		if err := decodeStructField(field.col, ColGetValue(tpl, field.pos), target); err != DB_SUCCESS {
+			return err
+		}
+		if field.ptr {
+			fv.Set(target.Addr())
+		}
+	}
+	return DB_SUCCESS
+}
+
+func (mapper *StructMapper) structValue(v any, settable bool) (reflect.Value, ErrCode) {
+	if mapper == nil || v == nil {
+		return reflect.Value{}, DB_ERROR
+	}
+	val := reflect.ValueOf(v)
+	if val.Kind() == reflect.Pointer {
+		if val.IsNil() {
+			return reflect.Value{}, DB_ERROR
+		}
+		val = val.Elem()
+	} else if settable {
+		return reflect.Value{}, DB_ERROR
+	}
+	if val.Type() != mapper.typ {
+		return reflect.Value{}, DB_ERROR
+	}
+	return val, DB_SUCCESS
+}
+
+func encodeStructField(col ColumnSchema, fv reflect.Value) ([]byte, ErrCode) {
+	switch col.Type {
+	case IB_INT:
+		arg := SQLArg{IntLen: int(col.Size), Signed: col.Attr&IB_COL_UNSIGNED == 0}
+		switch fv.Kind() {
+		case reflect.Bool:
+			if fv.Bool() {
+				arg.IntValue, arg.UintValue = 1, 1
+			}
+		case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Int:
+			arg.IntValue = fv.Int()
+			if !arg.Signed {
+				if arg.IntValue < 0 {
+					return nil, DB_DATA_MISMATCH
+				}
+				arg.UintValue = uint64(arg.IntValue)
+			}
+		default:
+			arg.UintValue = fv.Uint()
+			if arg.Signed {
+				if arg.UintValue > math.MaxInt64 {
+					return nil, DB_DATA_MISMATCH
+				}
+				arg.IntValue = int64(arg.UintValue)
+			}
+		}
+		buf, _, err := encodeInt(arg)
+		if err != DB_SUCCESS {
+			return nil, DB_DATA_MISMATCH
+		}
+		return buf, DB_SUCCESS
+	case IB_FLOAT:
+		buf := make([]byte, 4)
+		binary.BigEndian.PutUint32(buf, math.Float32bits(float32(fv.Float())))
+		return buf, DB_SUCCESS
+	case IB_DOUBLE:
+		buf := make([]byte, 8)
+		binary.BigEndian.PutUint64(buf, math.Float64bits(fv.Float()))
+		return buf, DB_SUCCESS
+	default:
+		var buf []byte
+		if fv.Kind() == reflect.String {
+			buf = []byte(fv.String())
+		} else {
+			buf = append([]byte(nil), fv.Bytes()...)
+		}
+		if col.Size > 0 && col.Type != IB_BLOB && len(buf) > int(col.Size) {
+			return nil, DB_DATA_MISMATCH
+		}
+		return buf, DB_SUCCESS
+	}
+}
+
+func decodeStructField(col ColumnSchema, buf []byte, fv reflect.Value) ErrCode {
+	switch col.Type {
+	case IB_INT:
+		unsigned := col.Attr&IB_COL_UNSIGNED != 0
+		i, u, err := DecodeInt(buf, unsigned)
+		if err != DB_SUCCESS {
+			return err
+		}
+		return setStructInt(fv, i, u, !unsigned)
+	case IB_FLOAT:
+		if len(buf) != 4 {
+			return DB_DATA_MISMATCH
+		}
+		fv.SetFloat(float64(math.Float32frombits(binary.BigEndian.Uint32(buf))))
+		return DB_SUCCESS
+	case IB_DOUBLE:
+		if len(buf) != 8 {
+			return DB_DATA_MISMATCH
+		}
+		f := math.Float64frombits(binary.BigEndian.Uint64(buf))
+		if fv.OverflowFloat(f) {
+			return DB_DATA_MISMATCH
+		}
+		fv.SetFloat(f)
+		return DB_SUCCESS
+	default:
+		if fv.Kind() == reflect.String {
+			fv.SetString(string(buf))
+		} else {
+			fv.SetBytes(append([]byte(nil), buf...))
+		}
+		return DB_SUCCESS
+	}
+}
+
+func setStructInt(fv reflect.Value, i int64, u uint64, signed bool) ErrCode {
+	switch fv.Kind() {
+	case reflect.Bool:
+		fv.SetBool(u != 0)
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Int:
+		if (!signed && u > math.MaxInt64) || fv.OverflowInt(i) {
+			return DB_DATA_MISMATCH
+		}
+		fv.SetInt(i)
+	default:
+		if (signed && i < 0) || fv.OverflowUint(u) {
+			return DB_DATA_MISMATCH
+		}
+		fv.SetUint(u)
+	}
+	return DB_SUCCESS
+}
+
+// TableSchemaFromStruct derives a table schema from the tagged fields of v.
+func TableSchemaFromStruct(name string, v any, format TableFormat, pageSize int, out **TableSchema) ErrCode {
+	mapper, err := NewStructMapper(v)
+	if err != DB_SUCCESS {
+		return err
+	}
+	return mapper.TableSchema(name, format, pageSize, out)
+}
+
+// TupleWriteStruct encodes v into tpl, assuming the columns follow the field
+// order of v as produced by TableSchemaFromStruct.
+func TupleWriteStruct(tpl *data.Tuple, v any) ErrCode {
+	mapper, err := NewStructMapper(v)
+	if err != DB_SUCCESS {
+		return err
+	}
+	return mapper.Write(tpl, v)
+}
+
+// TupleReadStruct decodes tpl into the struct pointed to by v, assuming the
+// columns follow the field order of v as produced by TableSchemaFromStruct.
+func TupleReadStruct(tpl *data.Tuple, v any) ErrCode {
+	mapper, err := NewStructMapper(v)
+	if err != DB_SUCCESS {
+		return err
+	}
+	return mapper.Read(tpl, v)
+}
@@ -0,0 +1,196 @@
+package api
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/wilhasse/innodb-go/data"
+)
+
+type mappedRow struct {
+	ID      uint32   `innodb:"id,pk"`
+	Delta   int16    `innodb:"delta"`
+	Name    string   `innodb:"name,size=16,index=idx_name"`
+	Code    string   `innodb:"code,type=char,size=4,unique=uniq_code"`
+	Score   float64  `innodb:"score"`
+	Ratio   *float32 `innodb:"ratio"`
+	Note    *string  `innodb:"note,size=32"`
+	Payload []byte   `innodb:"payload"`
+	Active  bool     `innodb:"active"`
+	Skipped int      `innodb:"-"`
+}
+
+func TestTableSchemaFromStruct(t *testing.T) {
+	var schema *TableSchema
+	if err := TableSchemaFromStruct("map_db/t", &mappedRow{}, IB_TBL_COMPACT, 0, &schema); err != DB_SUCCESS {
+		t.Fatalf("TableSchemaFromStruct: %v", err)
+	}
+	want := []ColumnSchema{
+		{Name: "id", Type: IB_INT, Attr: IB_COL_UNSIGNED | IB_COL_NOT_NULL, Size: 4},
+		{Name: "delta", Type: IB_INT, Attr: IB_COL_NOT_NULL, Size: 2},
+		{Name: "name", Type: IB_VARCHAR, Attr: IB_COL_NOT_NULL, Size: 16},
+		{Name: "code", Type: IB_CHAR, Attr: IB_COL_NOT_NULL, Size: 4},
+		{Name: "score", Type: IB_DOUBLE, Attr: IB_COL_NOT_NULL, Size: 8},
+		{Name: "ratio", Type: IB_FLOAT, Size: 4},
+		{Name: "note", Type: IB_VARCHAR, Size: 32},
+		{Name: "payload", Type: IB_BLOB, Attr: IB_COL_NOT_NULL},
+		{Name: "active", Type: IB_INT, Attr: IB_COL_UNSIGNED | IB_COL_NOT_NULL, Size: 1},
+	}
+	if len(schema.Columns) != len(want) {
+		t.Fatalf("columns=%+v", schema.Columns)
+	}
+	for i := range want {
+		if schema.Columns[i] != want[i] {
+			t.Fatalf("column %d=%+v want %+v", i, schema.Columns[i], want[i])
+		}
+	}
+	if len(schema.Indexes) != 3 {
+		t.Fatalf("indexes=%d want 3", len(schema.Indexes))
+	}
+	if idx := schema.Indexes[0]; idx.Name != "PRIMARY" || !idx.Clustered || idx.Columns[0] != "id" {
+		t.Fatalf("primary=%+v", idx)
+	}
+	if idx := schema.Indexes[2]; idx.Name != "uniq_code" || !idx.Unique || idx.Clustered {
+		t.Fatalf("unique=%+v", idx)
+	}
+
+	var bad struct {
+		Tags []string `innodb:"tags"`
+	}
+	if err := TableSchemaFromStruct("map_db/t", &bad, IB_TBL_COMPACT, 0, &schema); err != DB_SCHEMA_ERROR {
+		t.Fatalf("unsupported field err=%v want %v", err, DB_SCHEMA_ERROR)
+	}
+	if err := TableSchemaFromStruct("map_db/t", 42, IB_TBL_COMPACT, 0, &schema); err != DB_ERROR {
+		t.Fatalf("non-struct err=%v want %v", err, DB_ERROR)
+	}
+}
+
+func TestStructMapperRoundTrip(t *testing.T) {
+	resetAPIState()
+	if err := Init(); err != DB_SUCCESS {
+		t.Fatalf("Init: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = Shutdown(ShutdownNormal)
+	})
+	if err := Startup("barracuda"); err != DB_SUCCESS {
+		t.Fatalf("Startup: %v", err)
+	}
+	if err := DatabaseCreate("map_db"); err != DB_SUCCESS {
+		t.Fatalf("DatabaseCreate: %v", err)
+	}
+	var schema *TableSchema
+	if err := TableSchemaFromStruct("map_db/t", mappedRow{}, IB_TBL_COMPACT, 0, &schema); err != DB_SUCCESS {
+		t.Fatalf("TableSchemaFromStruct: %v", err)
+	}
+	if err := TableCreate(nil, schema, nil); err != DB_SUCCESS {
+		t.Fatalf("TableCreate: %v", err)
+	}
+
+	ratio := float32(0.5)
+	rows := []mappedRow{
+		{ID: 1, Delta: -3, Name: "ann", Code: "A1", Score: 1.25, Ratio: &ratio, Payload: []byte{0, 1}, Active: true},
+		{ID: 2, Delta: 7, Name: "bob", Code: "B2", Score: -2, Payload: []byte{2}, Skipped: 9},
+	}
+	ibTrx := TrxBegin(IB_TRX_REPEATABLE_READ)
+	var crsr *Cursor
+	if err := CursorOpenTable("map_db/t", ibTrx, &crsr); err != DB_SUCCESS {
+		t.Fatalf("CursorOpenTable: %v", err)
+	}
+	tpl := ClustReadTupleCreate(crsr)
+	for i := range rows {
+		tpl = TupleClear(tpl)
+		if err := TupleWriteStruct(tpl, &rows[i]); err != DB_SUCCESS {
+			t.Fatalf("TupleWriteStruct: %v", err)
+		}
+		if err := CursorInsertRow(crsr, tpl); err != DB_SUCCESS {
+			t.Fatalf("CursorInsertRow: %v", err)
+		}
+	}
+	if err := CursorFirst(crsr); err != DB_SUCCESS {
+		t.Fatalf("CursorFirst: %v", err)
+	}
+	for i := range rows {
+		tpl = TupleClear(tpl)
+		if err := CursorReadRow(crsr, tpl); err != DB_SUCCESS {
+			t.Fatalf("CursorReadRow: %v", err)
+		}
+		got := mappedRow{Skipped: 5}
+		if err := TupleReadStruct(tpl, &got); err != DB_SUCCESS {
+			t.Fatalf("TupleReadStruct: %v", err)
+		}
+		want := rows[i]
+		if got.ID != want.ID || got.Delta != want.Delta || got.Name != want.Name || got.Code != want.Code ||
+			got.Score != want.Score || got.Active != want.Active || !bytes.Equal(got.Payload, want.Payload) {
+			t.Fatalf("row %d=%+v want %+v", i, got, want)
+		}
+		if (got.Ratio == nil) != (want.Ratio == nil) || (got.Ratio != nil && *got.Ratio != *want.Ratio) {
+			t.Fatalf("row %d ratio=%v want %v", i, got.Ratio, want.Ratio)
+		}
+		if got.Note != nil || got.Skipped != 5 {
+			t.Fatalf("row %d note=%v skipped=%d", i, got.Note, got.Skipped)
+		}
+		_ = CursorNext(crsr)
+	}
+	TupleDelete(tpl)
+	_ = CursorClose(crsr)
+	if err := TrxCommit(ibTrx); err != DB_SUCCESS {
+		t.Fatalf("TrxCommit: %v", err)
+	}
+}
+
+func TestStructMapperBind(t *testing.T) {
+	schema := &TableSchema{Name: "map_db/t", Columns: []ColumnSchema{
+		{Name: "extra", Type: IB_VARCHAR},
+		{Name: "val", Type: IB_INT, Size: 8},
+		{Name: "key", Type: IB_INT, Attr: IB_COL_UNSIGNED | IB_COL_NOT_NULL, Size: 4},
+	}}
+	type partial struct {
+		Key uint64 `innodb:"key"`
+		Val *int8  `innodb:"val"`
+	}
+	mapper, err := NewStructMapper(partial{})
+	if err != DB_SUCCESS {
+		t.Fatalf("NewStructMapper: %v", err)
+	}
+	bound, err := mapper.Bind(schema)
+	if err != DB_SUCCESS {
+		t.Fatalf("Bind: %v", err)
+	}
+	tpl := &data.Tuple{Fields: make([]data.Field, 3)}
+	val := int8(-2)
+	if err := bound.Write(tpl, partial{Key: 9, Val: &val}); err != DB_SUCCESS {
+		t.Fatalf("Write: %v", err)
+	}
+	var key uint32
+	var wide int64
+	if TupleReadU32(tpl, 2, &key) != DB_SUCCESS || key != 9 || TupleReadI64(tpl, 1, &wide) != DB_SUCCESS || wide != -2 {
+		t.Fatalf("key=%d val=%d", key, wide)
+	}
+	if err := bound.Write(tpl, partial{Key: 1 << 40}); err != DB_DATA_MISMATCH {
+		t.Fatalf("overflow err=%v want %v", err, DB_DATA_MISMATCH)
+	}
+
+	if err := TupleWriteI64(tpl, 1, 300); err != DB_SUCCESS {
+		t.Fatalf("TupleWriteI64: %v", err)
+	}
+	var out partial
+	if err := bound.Read(tpl, &out); err != DB_DATA_MISMATCH {
+		t.Fatalf("narrow read err=%v want %v", err, DB_DATA_MISMATCH)
+	}
+	tpl.Fields[2] = data.Field{Len: data.UnivSQLNull}
+	if err := bound.Read(tpl, &out); err != DB_DATA_MISMATCH {
+		t.Fatalf("NULL into non-pointer err=%v want %v", err, DB_DATA_MISMATCH)
+	}
+
+	var wrong struct {
+		Key string `innodb:"key"`
+	}
+	mapper, err = NewStructMapper(&wrong)
+	if err != DB_SUCCESS {
+		t.Fatalf("NewStructMapper: %v", err)
+	}
+	if _, err := mapper.Bind(schema); err != DB_SCHEMA_ERROR {
+		t.Fatalf("incompatible bind err=%v want %v", err, DB_SCHEMA_ERROR)
+	}
+}
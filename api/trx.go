@@ -9,9 +9,13 @@ import (
 // TrxIsolation mirrors ib_trx_level_t.
 type TrxIsolation int
 
+// The levels added later follow the original ones so that existing values,
+// including the zero value IB_TRX_REPEATABLE_READ, keep their meaning.
 const (
 	IB_TRX_REPEATABLE_READ TrxIsolation = iota
 	IB_TRX_SERIALIZABLE
+	IB_TRX_READ_UNCOMMITTED
+	IB_TRX_READ_COMMITTED
 )
 
 // TrxState mirrors ib_trx_state_t.
@@ -25,12 +29,43 @@ const (
 )
 
 // TrxBegin starts a new transaction.
-func TrxBegin(_ TrxIsolation) *trx.Trx {
+func TrxBegin(level TrxIsolation) *trx.Trx {
 	ibTrx := trx.TrxCreate()
+	ibTrx.Isolation = trxIsolationLevel(level)
 	trx.TrxBegin(ibTrx)
 	return ibTrx
 }
 
+// TrxIsolationGet returns the isolation level of a transaction.
+func TrxIsolationGet(ibTrx *trx.Trx) TrxIsolation {
+	if ibTrx == nil {
+		return IB_TRX_REPEATABLE_READ
+	}
+	switch ibTrx.Isolation {
+	case trx.IsolationReadUncommitted:
+		return IB_TRX_READ_UNCOMMITTED
+	case trx.IsolationReadCommitted:
+		return IB_TRX_READ_COMMITTED
+	case trx.IsolationSerializable:
+		return IB_TRX_SERIALIZABLE
+	default:
+		return IB_TRX_REPEATABLE_READ
+	}
+}
+
+func trxIsolationLevel(level TrxIsolation) trx.IsolationLevel {
+	switch level {
+	case IB_TRX_READ_UNCOMMITTED:
+		return trx.IsolationReadUncommitted
+	case IB_TRX_READ_COMMITTED:
+		return trx.IsolationReadCommitted
+	case IB_TRX_SERIALIZABLE:
+		return trx.IsolationSerializable
+	default:
+		return trx.IsolationRepeatableRead
+	}
+}
+
 // TrxCommit commits a transaction.
 func TrxCommit(ibTrx *trx.Trx) ErrCode {
 	if ibTrx == nil {
@@ -2,29 +2,62 @@ package btr
 
 import (
 	"encoding/binary"
+	"errors"
 
 	"github.com/wilhasse/innodb-go/fil"
+	"github.com/wilhasse/innodb-go/fsp"
+	"github.com/wilhasse/innodb-go/page"
+	"github.com/wilhasse/innodb-go/ut"
 )
 
+// An externally stored field is kept in a chain of BLOB pages in the
+// tablespace of its record. The record holds a reference: a marker byte,
+// the 20-byte field reference of InnoDB (space id, first page number, offset
+// of the BLOB header on that page and total length) and a copy of the field
+// prefix with its length.
 const (
 	externMarker         = 0xEE
-	externHeaderSize     = 1 + 8 + 2
+	externFieldRefSize   = 4 + 4 + 4 + 8
+	externHeaderSize     = 1 + externFieldRefSize + 2
 	externDefaultPrefix  = 16
 	ExternFieldThreshold = 32
 )
 
+// BLOB page layout after the file header, as BTR_BLOB_HDR_* in InnoDB: the
+// length of the column data on the page and the next page of the chain.
+const (
+	blobHdrPartLen    = 0
+	blobHdrNextPageNo = 4
+	blobHdrSize       = 8
+	blobPartMax       = ut.UNIV_PAGE_SIZE - int(fil.PageData) - blobHdrSize - int(fil.PageDataEnd)
+	// blobZipReserve is left free of column data on the BLOB pages of a
+	// compressed tablespace, so that a page of incompressible data still
+	// fits the zip size.
+	blobZipReserve = 512
+)
+
+type externRef struct {
+	space  uint32
+	page   uint32
+	offset uint32
+	length uint64
+	prefix []byte
+}
+
 // RecGetExternallyStoredLen returns the external length when stored out of page.
 func RecGetExternallyStoredLen(value []byte) int {
-	if id, _, ok := decodeExternRef(value); ok {
-		return len(fil.ExternGet(id))
+	if ref, ok := decodeExternRef(value); ok {
+		return int(ref.length)
 	}
 	return len(value)
 }
 
-// StoreBigRecExternFields stores a large field externally and returns a reference.
-func StoreBigRecExternFields(value []byte, prefixLen int) []byte {
+// StoreBigRecExternFields writes a large field to a chain of BLOB pages
+// allocated in spaceID and returns its reference, which keeps prefixLen
+// bytes of the field. Fields up to ExternFieldThreshold are returned as is.
+func StoreBigRecExternFields(spaceID uint32, value []byte, prefixLen int) ([]byte, error) {
 	if len(value) <= ExternFieldThreshold {
-		return cloneBytes(value)
+		return cloneBytes(value), nil
 	}
 	if prefixLen <= 0 || prefixLen > len(value) {
 		prefixLen = externDefaultPrefix
@@ -32,27 +65,81 @@ func StoreBigRecExternFields(value []byte, prefixLen int) []byte {
 			prefixLen = len(value)
 		}
 	}
-	id := fil.ExternStore(value)
-	return encodeExternRef(id, value[:prefixLen])
+	partMax := blobPartSize(spaceID)
+	pages := make([]uint32, 0, (len(value)+partMax-1)/partMax)
+	for i := 0; i < cap(pages); i++ {
+		pageNo := fsp.AllocPage(spaceID)
+		if pageNo == fil.NullPageOffset {
+			freeBlobPages(spaceID, pages)
+			return nil, errors.New("btr: no free page")
+		}
+		pages = append(pages, pageNo)
+	}
+	rest := value
+	for i, pageNo := range pages {
+		next := fil.NullPageOffset
+		if i+1 < len(pages) {
+			next = pages[i+1]
+		}
+		part := rest
+		if len(part) > partMax {
+			part = part[:partMax]
+		}
+		if err := writeBlobPage(spaceID, pageNo, part, next); err != nil {
+			freeBlobPages(spaceID, pages)
+			return nil, err
+		}
+		rest = rest[len(part):]
+	}
+	return encodeExternRef(externRef{
+		space:  spaceID,
+		page:   pages[0],
+		offset: fil.PageData,
+		length: uint64(len(value)),
+		prefix: value[:prefixLen],
+	}), nil
 }
 
-// FreeExternallyStoredField frees external storage for a field reference.
+// FreeExternallyStoredField frees the BLOB pages of a field reference.
 func FreeExternallyStoredField(value []byte) {
-	if id, _, ok := decodeExternRef(value); ok {
-		fil.ExternFree(id)
+	ref, ok := decodeExternRef(value)
+	if !ok {
+		return
+	}
+	var pages []uint32
+	_ = forEachBlobPage(ref, func(pageNo uint32, pageBytes, _ []byte) bool {
+		page.PageSetType(pageBytes, fil.PageTypeAllocated)
+		pages = append(pages, pageNo)
+		return true
+	})
+	for _, pageNo := range pages {
+		fsp.FreePage(ref.space, pageNo)
 	}
 }
 
+// ReserveExternallyStoredField marks the BLOB pages of a field reference
+// allocated in fsp, as PageTree.ReservePages does for index pages.
+func ReserveExternallyStoredField(value []byte) error {
+	ref, ok := decodeExternRef(value)
+	if !ok {
+		return nil
+	}
+	return forEachBlobPage(ref, func(pageNo uint32, _, _ []byte) bool {
+		fsp.MarkPageUsed(ref.space, pageNo)
+		return false
+	})
+}
+
 // CopyExternallyStoredFieldPrefix returns the stored prefix or a slice of the value.
 func CopyExternallyStoredFieldPrefix(value []byte, length int) []byte {
 	if length < 0 {
 		return nil
 	}
-	if _, prefix, ok := decodeExternRef(value); ok {
-		if length == 0 || length > len(prefix) {
-			length = len(prefix)
+	if ref, ok := decodeExternRef(value); ok {
+		if length == 0 || length > len(ref.prefix) {
+			length = len(ref.prefix)
 		}
-		return cloneBytes(prefix[:length])
+		return cloneBytes(ref.prefix[:length])
 	}
 	if length == 0 || length > len(value) {
 		length = len(value)
@@ -67,35 +154,123 @@ func RecFreeExternallyStoredFields(values ...[]byte) {
 	}
 }
 
-// GetExternallyStoredField resolves an external field reference.
+// GetExternallyStoredField resolves an external field reference. It returns
+// nil when the BLOB chain was freed or cannot be read.
 func GetExternallyStoredField(value []byte) []byte {
-	if id, _, ok := decodeExternRef(value); ok {
-		return fil.ExternGet(id)
+	ref, ok := decodeExternRef(value)
+	if !ok {
+		return cloneBytes(value)
+	}
+	out := make([]byte, 0, ref.length)
+	err := forEachBlobPage(ref, func(_ uint32, _, part []byte) bool {
+		out = append(out, part...)
+		return false
+	})
+	if err != nil {
+		return nil
+	}
+	return out
+}
+
+// blobPartSize returns the column data stored on each BLOB page of spaceID.
+func blobPartSize(spaceID uint32) int {
+	if zipSize := fil.SpaceZipSize(spaceID); zipSize > 0 && zipSize-blobZipReserve < blobPartMax {
+		return zipSize - blobZipReserve
+	}
+	return blobPartMax
+}
+
+// writeBlobPage formats pageNo as a BLOB page holding part and redo-logs it.
+func writeBlobPage(spaceID, pageNo uint32, part []byte, next uint32) error {
+	h, err := fetchSpacePage(spaceID, pageNo)
+	if err != nil {
+		return err
+	}
+	clear(h.data)
+	page.PageSetSpaceID(h.data, spaceID)
+	page.PageSetPageNo(h.data, pageNo)
+	page.PageSetType(h.data, fil.PageTypeBlob)
+	hdr := h.data[fil.PageData:]
+	binary.BigEndian.PutUint32(hdr[blobHdrPartLen:], uint32(len(part)))
+	binary.BigEndian.PutUint32(hdr[blobHdrNextPageNo:], next)
+	copy(hdr[blobHdrSize:], part)
+	logPageWrite(h.data)
+	return h.commit(true)
+}
+
+// forEachBlobPage calls fn with every page of the chain of ref and the
+// column data stored on it. fn reports whether it changed the page, which
+// is then redo-logged and written back. A page that is not a BLOB page or
+// a chain that does not add up to the referenced length is an error.
+func forEachBlobPage(ref externRef, fn func(pageNo uint32, pageBytes, part []byte) bool) error {
+	pageNo, offset := ref.page, int(ref.offset)
+	for remaining := ref.length; remaining > 0; {
+		if isNullPageNo(pageNo) {
+			return errors.New("btr: BLOB chain too short")
+		}
+		h, err := fetchSpacePage(ref.space, pageNo)
+		if err != nil {
+			return err
+		}
+		if page.PageGetType(h.data) != fil.PageTypeBlob || offset+blobHdrSize > len(h.data) {
+			_ = h.commit(false)
+			return errors.New("btr: not a BLOB page")
+		}
+		partLen := int(binary.BigEndian.Uint32(h.data[offset+blobHdrPartLen:]))
+		next := binary.BigEndian.Uint32(h.data[offset+blobHdrNextPageNo:])
+		start := offset + blobHdrSize
+		if partLen == 0 || uint64(partLen) > remaining || start+partLen > len(h.data) {
+			_ = h.commit(false)
+			return errors.New("btr: corrupt BLOB page")
+		}
+		dirty := fn(pageNo, h.data, h.data[start:start+partLen])
+		if dirty {
+			logPageWrite(h.data)
+		}
+		if err := h.commit(dirty); err != nil {
+			return err
+		}
+		remaining -= uint64(partLen)
+		pageNo, offset = next, int(fil.PageData)
+	}
+	return nil
+}
+
+func freeBlobPages(spaceID uint32, pages []uint32) {
+	for _, pageNo := range pages {
+		fsp.FreePage(spaceID, pageNo)
 	}
-	return cloneBytes(value)
 }
 
-func encodeExternRef(id uint64, prefix []byte) []byte {
+func encodeExternRef(ref externRef) []byte {
+	prefix := ref.prefix
 	if len(prefix) > 0xFFFF {
 		prefix = prefix[:0xFFFF]
 	}
 	buf := make([]byte, externHeaderSize+len(prefix))
 	buf[0] = externMarker
-	binary.BigEndian.PutUint64(buf[1:], id)
-	binary.BigEndian.PutUint16(buf[9:], uint16(len(prefix)))
+	binary.BigEndian.PutUint32(buf[1:], ref.space)
+	binary.BigEndian.PutUint32(buf[5:], ref.page)
+	binary.BigEndian.PutUint32(buf[9:], ref.offset)
+	binary.BigEndian.PutUint64(buf[13:], ref.length)
+	binary.BigEndian.PutUint16(buf[1+externFieldRefSize:], uint16(len(prefix)))
 	copy(buf[externHeaderSize:], prefix)
 	return buf
 }
 
-func decodeExternRef(value []byte) (uint64, []byte, bool) {
+func decodeExternRef(value []byte) (externRef, bool) {
 	if len(value) < externHeaderSize || value[0] != externMarker {
-		return 0, nil, false
+		return externRef{}, false
 	}
-	id := binary.BigEndian.Uint64(value[1:])
-	prefixLen := int(binary.BigEndian.Uint16(value[9:]))
+	prefixLen := int(binary.BigEndian.Uint16(value[1+externFieldRefSize:]))
 	if externHeaderSize+prefixLen > len(value) {
-		return 0, nil, false
+		return externRef{}, false
 	}
-	prefix := value[externHeaderSize : externHeaderSize+prefixLen]
-	return id, prefix, true
+	return externRef{
+		space:  binary.BigEndian.Uint32(value[1:]),
+		page:   binary.BigEndian.Uint32(value[5:]),
+		offset: binary.BigEndian.Uint32(value[9:]),
+		length: binary.BigEndian.Uint64(value[13:]),
+		prefix: value[externHeaderSize : externHeaderSize+prefixLen],
+	}, true
 }
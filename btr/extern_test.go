@@ -2,18 +2,26 @@ package btr
 
 import (
 	"bytes"
+	"encoding/binary"
 	"testing"
 
 	"github.com/wilhasse/innodb-go/fil"
+	"github.com/wilhasse/innodb-go/fsp"
+	"github.com/wilhasse/innodb-go/page"
 )
 
 func TestExternFieldStorage(t *testing.T) {
-	fil.VarInit()
+	tree, cleanup := setupPageTree(t)
+	defer cleanup()
 
-	tree := NewTree(4, nil)
 	longVal := bytes.Repeat([]byte("x"), ExternFieldThreshold+5)
-	stored := StoreBigRecExternFields(longVal, 8)
-	tree.Insert([]byte("a"), stored)
+	stored, err := StoreBigRecExternFields(tree.SpaceID, longVal, 8)
+	if err != nil {
+		t.Fatalf("StoreBigRecExternFields: %v", err)
+	}
+	if _, err := tree.Insert([]byte("a"), stored); err != nil {
+		t.Fatalf("Insert: %v", err)
+	}
 
 	if got := RecGetExternallyStoredLen(stored); got != len(longVal) {
 		t.Fatalf("extern len mismatch: got %d want %d", got, len(longVal))
@@ -32,3 +40,61 @@ func TestExternFieldStorage(t *testing.T) {
 		t.Fatalf("expected freed extern data to be missing")
 	}
 }
+
+func TestExternFieldPageChain(t *testing.T) {
+	tree, cleanup := setupPageTree(t)
+	defer cleanup()
+
+	longVal := make([]byte, 2*blobPartMax+100)
+	for i := range longVal {
+		longVal[i] = byte(i % 251)
+	}
+	stored, err := StoreBigRecExternFields(tree.SpaceID, longVal, 0)
+	if err != nil {
+		t.Fatalf("StoreBigRecExternFields: %v", err)
+	}
+	ref, ok := decodeExternRef(stored)
+	if !ok || ref.space != tree.SpaceID || ref.offset != fil.PageData {
+		t.Fatalf("unexpected reference %+v", ref)
+	}
+	if len(ref.prefix) != externDefaultPrefix {
+		t.Fatalf("prefix len=%d, want %d", len(ref.prefix), externDefaultPrefix)
+	}
+
+	// The chain is read back from the tablespace file.
+	var pages []uint32
+	for pageNo := ref.page; pageNo != fil.NullPageOffset; {
+		pageBytes, err := fil.SpaceReadPage(tree.SpaceID, pageNo)
+		if err != nil {
+			t.Fatalf("SpaceReadPage(%d): %v", pageNo, err)
+		}
+		if typ := page.PageGetType(pageBytes); typ != fil.PageTypeBlob {
+			t.Fatalf("page %d type=%d, want BLOB", pageNo, typ)
+		}
+		pages = append(pages, pageNo)
+		pageNo = binary.BigEndian.Uint32(pageBytes[int(fil.PageData)+blobHdrNextPageNo:])
+	}
+	if len(pages) != 3 {
+		t.Fatalf("chain pages=%v, want 3", pages)
+	}
+	if got := GetExternallyStoredField(stored); !bytes.Equal(got, longVal) {
+		t.Fatalf("extern fetch mismatch: got %d bytes", len(got))
+	}
+
+	// After the allocations are forgotten, reserving the chain keeps new
+	// pages off it.
+	fsp.Init()
+	if err := ReserveExternallyStoredField(stored); err != nil {
+		t.Fatalf("ReserveExternallyStoredField: %v", err)
+	}
+	for _, pageNo := range pages {
+		if got := fsp.AllocPage(tree.SpaceID); got == pageNo {
+			t.Fatalf("allocated reserved page %d", got)
+		}
+	}
+
+	FreeExternallyStoredField(stored)
+	if got := GetExternallyStoredField(stored); got != nil {
+		t.Fatalf("expected freed chain to be missing")
+	}
+}
@@ -7,6 +7,9 @@ import (
 	"github.com/wilhasse/innodb-go/page"
 )
 
+// pageLastRecord asks cursorFromPageIndex for the last record on a page.
+const pageLastRecord = int(^uint(0) >> 1)
+
 // PageCursor iterates over records in a page-based B-tree.
 type PageCursor struct {
 	Tree    *PageTree
@@ -68,6 +71,33 @@ func (c *PageCursor) Next() bool {
 	return c.Valid()
 }
 
+// Prev moves to the previous record in order.
+func (c *PageCursor) Prev() bool {
+	if !c.Valid() {
+		return false
+	}
+	if c.index > 0 {
+		c.index--
+		return true
+	}
+	if c.Tree == nil {
+		c.invalidate()
+		return false
+	}
+	prev, err := c.Tree.leafPrevPage(c.pageNo)
+	if err != nil {
+		c.invalidate()
+		return false
+	}
+	cur, err := c.Tree.cursorFromPageIndex(prev, pageLastRecord, false)
+	if err != nil || cur == nil {
+		c.invalidate()
+		return false
+	}
+	*c = *cur
+	return c.Valid()
+}
+
 func (c *PageCursor) invalidate() {
 	if c == nil {
 		return
@@ -94,6 +124,24 @@ func (t *PageTree) First() (*PageCursor, error) {
 	return t.cursorFromPageIndex(start, 0, true)
 }
 
+// Last positions a page cursor on the last record in key order.
+func (t *PageTree) Last() (*PageCursor, error) {
+	if t == nil {
+		return nil, errors.New("btr: nil tree")
+	}
+	if t.RootPage == fil.NullPageOffset {
+		return nil, nil
+	}
+	if err := t.ensureRootInitialized(); err != nil {
+		return nil, err
+	}
+	end, err := t.rightmostLeaf()
+	if err != nil {
+		return nil, err
+	}
+	return t.cursorFromPageIndex(end, pageLastRecord, false)
+}
+
 // Seek positions a page cursor based on the search key and mode.
 func (t *PageTree) Seek(key []byte, mode SearchMode) (*PageCursor, bool, error) {
 	if t == nil {
@@ -127,7 +175,7 @@ func (t *PageTree) Seek(key []byte, mode SearchMode) (*PageCursor, bool, error)
 				idx--
 				cur, err := t.cursorFromPageIndex(pageNo, idx, false)
 				if cur == nil && !isNullPageNo(prev) {
-					cur, err = t.cursorFromPageIndex(prev, -1, false)
+					cur, err = t.cursorFromPageIndex(prev, pageLastRecord, false)
 				}
 				return cur, false, err
 			default:
@@ -156,10 +204,11 @@ func (t *PageTree) cursorFromPageIndex(pageNo uint32, idx int, forward bool) (*P
 		if len(records) == 0 {
 			if forward {
 				pageNo = next
+				idx = 0
 			} else {
 				pageNo = prev
+				idx = pageLastRecord
 			}
-			idx = 0
 			continue
 		}
 		if forward {
@@ -177,7 +226,7 @@ func (t *PageTree) cursorFromPageIndex(pageNo uint32, idx int, forward bool) (*P
 			}
 			if idx < 0 {
 				pageNo = prev
-				idx = -1
+				idx = pageLastRecord
 				continue
 			}
 		}
@@ -213,3 +262,8 @@ func (t *PageTree) leafNextPage(pageNo uint32) (uint32, error) {
 	_, _, next, err := t.leafRecords(pageNo)
 	return next, err
 }
+
+func (t *PageTree) leafPrevPage(pageNo uint32) (uint32, error) {
+	_, prev, _, err := t.leafRecords(pageNo)
+	return prev, err
+}
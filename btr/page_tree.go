@@ -68,44 +68,192 @@ func (t *PageTree) Insert(key, value []byte) (bool, error) {
 		return replaced, nil
 	}
 
+	if err := t.raiseRoot(sepKey, rightPage); err != nil {
+		return false, err
+	}
+	return replaced, nil
+}
+
+// raiseRoot grows the tree by a level after the root split into itself and
+// rightPage. As btr_root_raise_and_insert, the records left in the root move
+// to a new page and the root is rebuilt in place one level up, so the root
+// page number kept in the dictionary stays valid.
+func (t *PageTree) raiseRoot(sepKey []byte, rightPage uint32) error {
 	rootLevel, err := t.pageLevel(t.RootPage)
 	if err != nil {
-		return false, err
+		return err
 	}
-	newRoot, err := t.allocPage(rootLevel + 1)
+	leftPage, err := t.allocPage(rootLevel)
 	if err != nil {
-		return false, err
+		return err
 	}
-	leftKey, err := t.pageMinKey(t.RootPage)
+	h, err := t.fetchPage(t.RootPage)
 	if err != nil {
-		return false, err
+		return err
+	}
+	lh, err := t.fetchPage(leftPage)
+	if err != nil {
+		_ = h.commit(false)
+		return err
+	}
+	leftRecords := t.sortRecords(collectUserRecords(h.data))
+	prev, next := page.PageGetPrev(h.data), page.PageGetNext(h.data)
+	if !rebuildIndexPage(lh.data, t.SpaceID, leftPage, rootLevel, prev, next, leftRecords) {
+		_ = lh.commit(false)
+		_ = h.commit(false)
+		return errors.New("btr: root raise rebuild failed")
+	}
+	logPageWrite(lh.data)
+	if err := lh.commit(true); err != nil {
+		_ = h.commit(false)
+		return err
+	}
+	if rootLevel == 0 {
+		rh, err := t.fetchPage(rightPage)
+		if err != nil {
+			_ = h.commit(false)
+			return err
+		}
+		page.PageSetPrev(rh.data, leftPage)
+		logPageWrite(rh.data)
+		if err := rh.commit(true); err != nil {
+			_ = h.commit(false)
+			return err
+		}
+	}
+
+	leftKey := recordKeyOrEmpty(leftRecords)
+	if rootLevel > 0 {
+		leftKey, _ = t.pageMinKey(nil, leftPage)
 	}
 	if len(leftKey) == 0 {
 		leftKey = sepKey
 	}
 	rightKey := sepKey
 	if len(rightKey) == 0 {
-		rightKey, _ = t.pageMinKey(rightPage)
+		rightKey, _ = t.pageMinKey(nil, rightPage)
+	}
+	records := [][]byte{
+		encodeNodePtrRecord(leftKey, leftPage),
+		encodeNodePtrRecord(rightKey, rightPage),
+	}
+	if !rebuildIndexPage(h.data, t.SpaceID, t.RootPage, rootLevel+1, fil.NullPageOffset, fil.NullPageOffset, records) {
+		_ = h.commit(false)
+		return errors.New("btr: root rebuild failed")
 	}
+	logPageWrite(h.data)
+	return h.commit(true)
+}
 
-	h, err := t.fetchPage(newRoot)
+// Truncate removes every record, leaving the root page as an empty leaf.
+// Every other page of the tree goes back to fsp, and so do the BLOB chains
+// that externFields, when set, finds referenced by the record values.
+func (t *PageTree) Truncate(externFields func(value []byte) [][]byte) error {
+	if t == nil {
+		return errors.New("btr: nil tree")
+	}
+	t.ensureDefaults()
+	t.size = 0
+	if t.RootPage == fil.NullPageOffset {
+		return nil
+	}
+	h, err := t.fetchPage(t.RootPage)
 	if err != nil {
-		return false, err
+		return err
 	}
-	records := [][]byte{
-		encodeNodePtrRecord(leftKey, t.RootPage),
-		encodeNodePtrRecord(rightKey, rightPage),
+	var pages []uint32
+	var rootValues [][]byte
+	if page.PageGetType(h.data) == fil.PageTypeIndex {
+		if page.PageGetLevel(h.data) == 0 {
+			rootValues = leafValues(h.data)
+		} else if pages, err = t.childPages(h.data); err != nil {
+			_ = h.commit(false)
+			return err
+		}
 	}
-	if !rebuildIndexPage(h.data, t.SpaceID, newRoot, rootLevel+1, fil.NullPageOffset, fil.NullPageOffset, records) {
+	if !initIndexPageBytes(h.data, t.SpaceID, t.RootPage, 0) {
 		_ = h.commit(false)
-		return false, errors.New("btr: root rebuild failed")
+		return errors.New("btr: root init failed")
 	}
-	t.logPageWrite(h.data)
+	logPageWrite(h.data)
 	if err := h.commit(true); err != nil {
-		return false, err
+		return err
 	}
-	t.RootPage = newRoot
-	return replaced, nil
+
+	// The pages below the old root are unreachable now; release them one
+	// at a time, leaves after freeing the BLOB chains of their records.
+	freeExtern := func(values [][]byte) {
+		if externFields == nil {
+			return
+		}
+		for _, value := range values {
+			RecFreeExternallyStoredFields(externFields(value)...)
+		}
+	}
+	freeExtern(rootValues)
+	for _, pageNo := range pages {
+		m := t.startMtr()
+		ph, err := t.fetch(m, pageNo)
+		if err == nil {
+			if page.PageGetLevel(ph.data) == 0 {
+				freeExtern(leafValues(ph.data))
+			}
+			_ = ph.commit(false)
+			err = t.freePage(m, pageNo)
+		}
+		if cerr := m.commit(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// childPages returns the pages below the internal page pageBytes, parents
+// before their children.
+func (t *PageTree) childPages(pageBytes []byte) ([]uint32, error) {
+	var pages []uint32
+	pending := nodePtrChildren(pageBytes)
+	for len(pending) > 0 {
+		pageNo := pending[0]
+		pending = pending[1:]
+		pages = append(pages, pageNo)
+		h, err := t.fetchPage(pageNo)
+		if err != nil {
+			return nil, err
+		}
+		if page.PageGetType(h.data) != fil.PageTypeIndex {
+			_ = h.commit(false)
+			return nil, errors.New("btr: child not an index page")
+		}
+		if page.PageGetLevel(h.data) > 0 {
+			pending = append(pending, nodePtrChildren(h.data)...)
+		}
+		_ = h.commit(false)
+	}
+	return pages, nil
+}
+
+func nodePtrChildren(pageBytes []byte) []uint32 {
+	var children []uint32
+	for _, recBytes := range collectUserRecords(pageBytes) {
+		if _, child, ok := decodeNodePtrRecord(recBytes); ok {
+			children = append(children, child)
+		}
+	}
+	return children
+}
+
+func leafValues(pageBytes []byte) [][]byte {
+	var values [][]byte
+	for _, recBytes := range collectUserRecords(pageBytes) {
+		if _, value, ok := decodeLeafRecord(recBytes); ok {
+			values = append(values, value)
+		}
+	}
+	return values
 }
 
 // Search looks up a key in the page-based tree.
@@ -155,7 +303,10 @@ func (t *PageTree) Search(key []byte) ([]byte, bool, error) {
 	}
 }
 
-// Delete removes a key/value pair by key.
+// Delete removes a key/value pair by key. Pages left below the fill
+// threshold are merged with or refilled from a sibling, freed pages go back
+// to fsp and the tree loses a level when the root keeps a single child. All
+// of it is redo-logged in one mini-transaction.
 func (t *PageTree) Delete(key []byte) (bool, error) {
 	if t == nil {
 		return false, errors.New("btr: nil tree")
@@ -168,44 +319,18 @@ func (t *PageTree) Delete(key []byte) (bool, error) {
 		return false, err
 	}
 
-	pageNo := t.RootPage
-	for {
-		h, err := t.fetchPage(pageNo)
-		if err != nil {
-			return false, err
-		}
-		level := page.PageGetLevel(h.data)
-		if level == 0 {
-			records := collectUserRecords(h.data)
-			idx, exact := findRecordIndex(records, key, t.Compare)
-			if !exact {
-				_ = h.commit(false)
-				return false, nil
-			}
-			records = append(records[:idx], records[idx+1:]...)
-			prev := page.PageGetPrev(h.data)
-			next := page.PageGetNext(h.data)
-			if !rebuildIndexPage(h.data, t.SpaceID, pageNo, level, prev, next, records) {
-				_ = h.commit(false)
-				return false, errors.New("btr: leaf delete rebuild failed")
-			}
-			t.logPageWrite(h.data)
-			if err := h.commit(true); err != nil {
-				return false, err
-			}
-			if t.size > 0 {
-				t.size--
-			}
-			return true, nil
-		}
-		records := t.sortRecords(collectUserRecords(h.data))
-		child, ok := findChildPage(records, key, t.Compare)
-		_ = h.commit(false)
-		if !ok {
-			return false, errors.New("btr: missing child page")
-		}
-		pageNo = child
+	m := t.startMtr()
+	deleted, err := t.deletePage(m, t.RootPage, key)
+	if err == nil && deleted {
+		err = t.lowerRoot(m)
+	}
+	if cerr := m.commit(); err == nil {
+		err = cerr
 	}
+	if deleted && t.size > 0 {
+		t.size--
+	}
+	return deleted, err
 }
 
 // ForEach iterates all leaf records in key order.
@@ -268,6 +393,79 @@ func (t *PageTree) ForEach(fn func(key, value []byte) bool) error {
 	return nil
 }
 
+// ReservePages marks every page of the tree allocated in fsp and recounts
+// its records. Call it after opening a tree on an existing tablespace, whose
+// page allocations are not kept between restarts.
+func (t *PageTree) ReservePages() error {
+	if t == nil || t.RootPage == fil.NullPageOffset {
+		return nil
+	}
+	t.ensureDefaults()
+	t.size = 0
+	pending := []uint32{t.RootPage}
+	for len(pending) > 0 {
+		pageNo := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		h, err := t.fetchPage(pageNo)
+		if err != nil {
+			return err
+		}
+		fsp.MarkPageUsed(t.SpaceID, pageNo)
+		if page.PageGetType(h.data) != fil.PageTypeIndex {
+			_ = h.commit(false)
+			if pageNo == t.RootPage {
+				return nil
+			}
+			return errors.New("btr: child not an index page")
+		}
+		records := collectUserRecords(h.data)
+		if page.PageGetLevel(h.data) == 0 {
+			t.size += len(records)
+		} else {
+			for _, recBytes := range records {
+				if _, child, ok := decodeNodePtrRecord(recBytes); ok {
+					pending = append(pending, child)
+				}
+			}
+		}
+		_ = h.commit(false)
+	}
+	return nil
+}
+
+// ForEachRange iterates leaf records with lower <= key < upper in key order.
+// A nil bound leaves that side open; the scan descends directly to the leaf
+// holding lower instead of walking from the leftmost leaf.
+func (t *PageTree) ForEachRange(lower, upper []byte, fn func(key, value []byte) bool) error {
+	if t == nil || fn == nil {
+		return nil
+	}
+	if lower == nil {
+		return t.ForEach(func(key, value []byte) bool {
+			if upper != nil && t.Compare(key, upper) >= 0 {
+				return false
+			}
+			return fn(key, value)
+		})
+	}
+	t.ensureDefaults()
+	cur, _, err := t.Seek(lower, SearchGE)
+	if err != nil {
+		return err
+	}
+	for cur.Valid() {
+		key := cur.Key()
+		if upper != nil && t.Compare(key, upper) >= 0 {
+			return nil
+		}
+		if !fn(key, cur.Value()) {
+			return nil
+		}
+		cur.Next()
+	}
+	return nil
+}
+
 func (t *PageTree) leftmostLeaf() (uint32, error) {
 	pageNo := t.RootPage
 	for {
@@ -298,6 +496,36 @@ func (t *PageTree) leftmostLeaf() (uint32, error) {
 	}
 }
 
+func (t *PageTree) rightmostLeaf() (uint32, error) {
+	pageNo := t.RootPage
+	for {
+		h, err := t.fetchPage(pageNo)
+		if err != nil {
+			return 0, err
+		}
+		if page.PageGetType(h.data) != fil.PageTypeIndex {
+			_ = h.commit(false)
+			return 0, errors.New("btr: non-index page")
+		}
+		level := page.PageGetLevel(h.data)
+		if level == 0 {
+			_ = h.commit(false)
+			return pageNo, nil
+		}
+		records := t.sortRecords(collectUserRecords(h.data))
+		if len(records) == 0 {
+			_ = h.commit(false)
+			return 0, errors.New("btr: empty internal page")
+		}
+		_, child, ok := decodeNodePtrRecord(records[len(records)-1])
+		_ = h.commit(false)
+		if !ok {
+			return 0, errors.New("btr: invalid node pointer")
+		}
+		pageNo = child
+	}
+}
+
 func (t *PageTree) splitLeafRecords(records [][]byte) ([][]byte, [][]byte, bool) {
 	if t == nil || len(records) < 2 {
 		return nil, nil, false
@@ -320,11 +548,23 @@ func (t *PageTree) canFitRecords(records [][]byte) bool {
 		return true
 	}
 	buf := make([]byte, ut.UNIV_PAGE_SIZE)
-	return rebuildIndexPage(buf, t.SpaceID, 0, 0, fil.NullPageOffset, fil.NullPageOffset, records)
+	return t.rebuildFits(buf, 0, 0, fil.NullPageOffset, fil.NullPageOffset, records)
 }
 
-func (t *PageTree) logPageWrite(pageBytes []byte) {
-	if t == nil || pageBytes == nil {
+// rebuildFits rebuilds pageBytes with records and, in a compressed
+// tablespace, reports whether the page still compresses into the zip size.
+// A page that does not is split as if its records overflowed it.
+func (t *PageTree) rebuildFits(pageBytes []byte, pageNo uint32, level uint16, prev, next uint32, records [][]byte) bool {
+	if !rebuildIndexPage(pageBytes, t.SpaceID, pageNo, level, prev, next, records) {
+		return false
+	}
+	zipSize := fil.SpaceZipSize(t.SpaceID)
+	return zipSize == 0 || fil.PageZipFits(pageBytes, zipSize)
+}
+
+// logPageWrite redo-logs the whole page image.
+func logPageWrite(pageBytes []byte) {
+	if pageBytes == nil {
 		return
 	}
 	var mini mtr.Mtr
@@ -355,13 +595,13 @@ func (t *PageTree) ensureRootInitialized() error {
 			_ = h.commit(false)
 			return errors.New("btr: root init failed")
 		}
-		t.logPageWrite(h.data)
+		logPageWrite(h.data)
 		return h.commit(true)
 	}
 	if page.PageGetSpaceID(h.data) != t.SpaceID {
 		page.PageSetSpaceID(h.data, t.SpaceID)
 		page.PageSetPageNo(h.data, t.RootPage)
-		t.logPageWrite(h.data)
+		logPageWrite(h.data)
 		return h.commit(true)
 	}
 	return h.commit(false)
@@ -406,7 +646,7 @@ func (t *PageTree) sortRecords(records [][]byte) [][]byte {
 	return records
 }
 
-func (t *PageTree) refreshNodePtrRecords(records [][]byte) [][]byte {
+func (t *PageTree) refreshNodePtrRecords(m *pageMtr, records [][]byte) [][]byte {
 	if t == nil || len(records) == 0 {
 		return records
 	}
@@ -417,7 +657,7 @@ func (t *PageTree) refreshNodePtrRecords(records [][]byte) [][]byte {
 			updated = append(updated, recBytes)
 			continue
 		}
-		minKey, err := t.pageMinKey(child)
+		minKey, err := t.pageMinKey(m, child)
 		if err != nil || len(minKey) == 0 {
 			minKey = key
 		}
@@ -432,37 +672,50 @@ type pageHandle struct {
 	data    []byte
 	pool    *buf.Pool
 	bufPage *buf.Page
+	mtr     *pageMtr
 }
 
 func (t *PageTree) fetchPage(pageNo uint32) (*pageHandle, error) {
-	pool := buf.GetPool(t.SpaceID, pageNo)
+	return fetchSpacePage(t.SpaceID, pageNo)
+}
+
+// fetchSpacePage reads a page through the buffer pool when one covers it
+// and directly from the tablespace otherwise.
+func fetchSpacePage(spaceID, pageNo uint32) (*pageHandle, error) {
+	pool := buf.GetPool(spaceID, pageNo)
 	if pool != nil {
-		bufPage, _, err := pool.Fetch(t.SpaceID, pageNo)
+		bufPage, _, err := pool.Fetch(spaceID, pageNo)
 		if err != nil {
 			return nil, err
 		}
 		return &pageHandle{
-			spaceID: t.SpaceID,
+			spaceID: spaceID,
 			pageNo:  pageNo,
 			data:    bufPage.Data,
 			pool:    pool,
 			bufPage: bufPage,
 		}, nil
 	}
-	pageBytes, err := fil.SpaceReadPage(t.SpaceID, pageNo)
+	pageBytes, err := fil.SpaceReadPage(spaceID, pageNo)
 	if err != nil {
 		return nil, err
 	}
 	if pageBytes == nil {
 		pageBytes = make([]byte, ut.UNIV_PAGE_SIZE)
 	}
-	return &pageHandle{spaceID: t.SpaceID, pageNo: pageNo, data: pageBytes}, nil
+	return &pageHandle{spaceID: spaceID, pageNo: pageNo, data: pageBytes}, nil
 }
 
 func (h *pageHandle) commit(dirty bool) error {
 	if h == nil {
 		return nil
 	}
+	if h.mtr != nil {
+		if dirty {
+			h.mtr.dirty[h.pageNo] = true
+		}
+		return nil
+	}
 	if h.pool != nil && h.bufPage != nil {
 		if dirty {
 			h.pool.MarkDirty(h.bufPage)
@@ -489,7 +742,7 @@ func (t *PageTree) allocPage(level uint16) (uint32, error) {
 		_ = h.commit(false)
 		return fil.NullPageOffset, errors.New("btr: init page failed")
 	}
-	t.logPageWrite(h.data)
+	logPageWrite(h.data)
 	if err := h.commit(true); err != nil {
 		return fil.NullPageOffset, err
 	}
@@ -506,8 +759,8 @@ func (t *PageTree) pageLevel(pageNo uint32) (uint16, error) {
 	return level, nil
 }
 
-func (t *PageTree) pageMinKey(pageNo uint32) ([]byte, error) {
-	h, err := t.fetchPage(pageNo)
+func (t *PageTree) pageMinKey(m *pageMtr, pageNo uint32) ([]byte, error) {
+	h, err := t.fetch(m, pageNo)
 	if err != nil {
 		return nil, err
 	}
@@ -530,7 +783,7 @@ func (t *PageTree) pageMinKey(pageNo uint32) ([]byte, error) {
 	if !ok {
 		return nil, nil
 	}
-	return t.pageMinKey(child)
+	return t.pageMinKey(m, child)
 }
 
 func (t *PageTree) insertPage(pageNo uint32, key, value []byte) (bool, []byte, uint32, bool, error) {
@@ -552,8 +805,8 @@ func (t *PageTree) insertPage(pageNo uint32, key, value []byte) (bool, []byte, u
 		if len(records) <= t.maxRecords() {
 			prev := page.PageGetPrev(pageBytes)
 			next := page.PageGetNext(pageBytes)
-			if rebuildIndexPage(pageBytes, t.SpaceID, pageNo, level, prev, next, records) {
-				t.logPageWrite(pageBytes)
+			if t.rebuildFits(pageBytes, pageNo, level, prev, next, records) {
+				logPageWrite(pageBytes)
 				if err := h.commit(true); err != nil {
 					return false, nil, fil.NullPageOffset, exact, err
 				}
@@ -582,7 +835,7 @@ func (t *PageTree) insertPage(pageNo uint32, key, value []byte) (bool, []byte, u
 			_ = h.commit(false)
 			return false, nil, fil.NullPageOffset, exact, errors.New("btr: leaf split rebuild failed")
 		}
-		t.logPageWrite(pageBytes)
+		logPageWrite(pageBytes)
 		if err := h.commit(true); err != nil {
 			return false, nil, fil.NullPageOffset, exact, err
 		}
@@ -595,7 +848,7 @@ func (t *PageTree) insertPage(pageNo uint32, key, value []byte) (bool, []byte, u
 			_ = rh.commit(false)
 			return false, nil, fil.NullPageOffset, exact, errors.New("btr: leaf split right rebuild failed")
 		}
-		t.logPageWrite(rh.data)
+		logPageWrite(rh.data)
 		if err := rh.commit(true); err != nil {
 			return false, nil, fil.NullPageOffset, exact, err
 		}
@@ -605,7 +858,7 @@ func (t *PageTree) insertPage(pageNo uint32, key, value []byte) (bool, []byte, u
 				return false, nil, fil.NullPageOffset, exact, err
 			}
 			page.PageSetPrev(nh.data, rightPage)
-			t.logPageWrite(nh.data)
+			logPageWrite(nh.data)
 			if err := nh.commit(true); err != nil {
 				return false, nil, fil.NullPageOffset, exact, err
 			}
@@ -630,7 +883,7 @@ func (t *PageTree) insertPage(pageNo uint32, key, value []byte) (bool, []byte, u
 	}
 
 	if split {
-		rightKey, err := t.pageMinKey(rightPage)
+		rightKey, err := t.pageMinKey(nil, rightPage)
 		if err != nil || len(rightKey) == 0 {
 			rightKey = sepKey
 		}
@@ -638,19 +891,21 @@ func (t *PageTree) insertPage(pageNo uint32, key, value []byte) (bool, []byte, u
 		idx, _ := findRecordIndex(records, rightKey, t.Compare)
 		records = insertRecord(records, idx, insertRec)
 	}
-	records = t.refreshNodePtrRecords(records)
+	records = t.refreshNodePtrRecords(nil, records)
 	if len(records) <= t.maxRecords() {
 		prev := page.PageGetPrev(pageBytes)
 		next := page.PageGetNext(pageBytes)
-		if !rebuildIndexPage(pageBytes, t.SpaceID, pageNo, level, prev, next, records) {
+		if t.rebuildFits(pageBytes, pageNo, level, prev, next, records) {
+			logPageWrite(pageBytes)
+			if err := h.commit(true); err != nil {
+				return false, nil, fil.NullPageOffset, replaced, err
+			}
+			return false, nil, fil.NullPageOffset, replaced, nil
+		}
+		if len(records) < 2 {
 			_ = h.commit(false)
 			return false, nil, fil.NullPageOffset, replaced, errors.New("btr: internal rebuild failed")
 		}
-		t.logPageWrite(pageBytes)
-		if err := h.commit(true); err != nil {
-			return false, nil, fil.NullPageOffset, replaced, err
-		}
-		return false, nil, fil.NullPageOffset, replaced, nil
 	}
 
 	mid := len(records) / 2
@@ -668,7 +923,7 @@ func (t *PageTree) insertPage(pageNo uint32, key, value []byte) (bool, []byte, u
 		_ = h.commit(false)
 		return false, nil, fil.NullPageOffset, replaced, errors.New("btr: internal split left rebuild failed")
 	}
-	t.logPageWrite(pageBytes)
+	logPageWrite(pageBytes)
 	if err := h.commit(true); err != nil {
 		return false, nil, fil.NullPageOffset, replaced, err
 	}
@@ -681,7 +936,7 @@ func (t *PageTree) insertPage(pageNo uint32, key, value []byte) (bool, []byte, u
 		_ = rh.commit(false)
 		return false, nil, fil.NullPageOffset, replaced, errors.New("btr: internal split right rebuild failed")
 	}
-	t.logPageWrite(rh.data)
+	logPageWrite(rh.data)
 	if err := rh.commit(true); err != nil {
 		return false, nil, fil.NullPageOffset, replaced, err
 	}
@@ -0,0 +1,247 @@
+package btr
+
+import (
+	"errors"
+
+	"github.com/wilhasse/innodb-go/fil"
+	"github.com/wilhasse/innodb-go/page"
+)
+
+// deletePage removes key from the subtree rooted at pageNo. A child left
+// below the fill threshold is merged with or refilled from a sibling, and
+// pages emptied by a merge are released to fsp when m commits.
+func (t *PageTree) deletePage(m *pageMtr, pageNo uint32, key []byte) (bool, error) {
+	h, err := t.fetch(m, pageNo)
+	if err != nil {
+		return false, err
+	}
+	level := page.PageGetLevel(h.data)
+	if level == 0 {
+		records := collectUserRecords(h.data)
+		idx, exact := findRecordIndex(records, key, t.Compare)
+		if !exact {
+			_ = h.commit(false)
+			return false, nil
+		}
+		records = append(records[:idx], records[idx+1:]...)
+		prev := page.PageGetPrev(h.data)
+		next := page.PageGetNext(h.data)
+		if !rebuildIndexPage(h.data, t.SpaceID, pageNo, level, prev, next, records) {
+			_ = h.commit(false)
+			return false, errors.New("btr: leaf delete rebuild failed")
+		}
+		return true, h.commit(true)
+	}
+
+	records := t.sortRecords(collectUserRecords(h.data))
+	idx, ok := findChildIndex(records, key, t.Compare)
+	if !ok {
+		_ = h.commit(false)
+		return false, errors.New("btr: missing child page")
+	}
+	_, child, _ := decodeNodePtrRecord(records[idx])
+	deleted, err := t.deletePage(m, child, key)
+	if err != nil || !deleted {
+		_ = h.commit(false)
+		return deleted, err
+	}
+	records, changed, err := t.rebalanceChild(m, level-1, records, idx)
+	if err != nil || !changed {
+		_ = h.commit(false)
+		return true, err
+	}
+	prev := page.PageGetPrev(h.data)
+	next := page.PageGetNext(h.data)
+	if !rebuildIndexPage(h.data, t.SpaceID, pageNo, level, prev, next, records) {
+		_ = h.commit(false)
+		return true, errors.New("btr: internal delete rebuild failed")
+	}
+	return true, h.commit(true)
+}
+
+// minRecords is the fill threshold of a non-root page: a page holding fewer
+// records is merged with or refilled from a sibling.
+func (t *PageTree) minRecords() int {
+	return (t.maxRecords() + 1) / 2
+}
+
+// rebalanceChild merges the child at records[idx] with a sibling under the
+// same parent when it has fallen below the fill threshold, or moves records
+// over from the sibling when both do not fit one page. The right page of a
+// merged pair is freed, so the leftmost page of a level always stays. It
+// returns the updated node pointers of the parent and whether they changed.
+func (t *PageTree) rebalanceChild(m *pageMtr, level uint16, records [][]byte, idx int) ([][]byte, bool, error) {
+	if len(records) < 2 {
+		return records, false, nil
+	}
+	_, child, ok := decodeNodePtrRecord(records[idx])
+	if !ok {
+		return records, false, errors.New("btr: invalid node pointer")
+	}
+	h, err := t.fetch(m, child)
+	if err != nil {
+		return records, false, err
+	}
+	n := len(collectUserRecords(h.data))
+	_ = h.commit(false)
+	if n >= t.minRecords() {
+		return records, false, nil
+	}
+
+	leftIdx := idx - 1
+	if leftIdx < 0 {
+		leftIdx = 0
+	}
+	_, leftNo, okLeft := decodeNodePtrRecord(records[leftIdx])
+	_, rightNo, okRight := decodeNodePtrRecord(records[leftIdx+1])
+	if !okLeft || !okRight {
+		return records, false, errors.New("btr: invalid node pointer")
+	}
+	lh, err := t.fetch(m, leftNo)
+	if err != nil {
+		return records, false, err
+	}
+	rh, err := t.fetch(m, rightNo)
+	if err != nil {
+		_ = lh.commit(false)
+		return records, false, err
+	}
+	leftRecords := t.sortRecords(collectUserRecords(lh.data))
+	rightRecords := t.sortRecords(collectUserRecords(rh.data))
+	combined := make([][]byte, 0, len(leftRecords)+len(rightRecords))
+	combined = append(combined, leftRecords...)
+	combined = append(combined, rightRecords...)
+	leftPrev, leftNext := page.PageGetPrev(lh.data), page.PageGetNext(lh.data)
+	rightPrev, rightNext := page.PageGetPrev(rh.data), page.PageGetNext(rh.data)
+
+	if len(combined) <= t.maxRecords() && t.canFitRecords(combined) {
+		if !t.rebuildFits(lh.data, leftNo, level, leftPrev, rightNext, combined) {
+			_ = lh.commit(false)
+			_ = rh.commit(false)
+			return records, false, errors.New("btr: merge rebuild failed")
+		}
+		_ = rh.commit(false)
+		if err := lh.commit(true); err != nil {
+			return records, false, err
+		}
+		if level == 0 && !isNullPageNo(rightNext) {
+			nh, err := t.fetch(m, rightNext)
+			if err != nil {
+				return records, false, err
+			}
+			page.PageSetPrev(nh.data, leftNo)
+			if err := nh.commit(true); err != nil {
+				return records, false, err
+			}
+		}
+		if err := t.freePage(m, rightNo); err != nil {
+			return records, false, err
+		}
+		records = append(records[:leftIdx+1], records[leftIdx+2:]...)
+		return t.refreshNodePtrRecords(m, records), true, nil
+	}
+
+	left, right, ok := t.balanceRecords(combined)
+	if !ok || len(left) == len(leftRecords) {
+		_ = lh.commit(false)
+		_ = rh.commit(false)
+		return records, false, nil
+	}
+	if !t.rebuildFits(lh.data, leftNo, level, leftPrev, leftNext, left) ||
+		!t.rebuildFits(rh.data, rightNo, level, rightPrev, rightNext, right) {
+		_ = lh.commit(false)
+		_ = rh.commit(false)
+		return records, false, errors.New("btr: redistribute rebuild failed")
+	}
+	if err := lh.commit(true); err != nil {
+		_ = rh.commit(false)
+		return records, false, err
+	}
+	if err := rh.commit(true); err != nil {
+		return records, false, err
+	}
+	return t.refreshNodePtrRecords(m, records), true, nil
+}
+
+// balanceRecords splits records into two pages as evenly as they fit.
+func (t *PageTree) balanceRecords(records [][]byte) ([][]byte, [][]byte, bool) {
+	if len(records) < 2 {
+		return nil, nil, false
+	}
+	mid := len(records) / 2
+	for offset := 0; offset < len(records); offset++ {
+		for _, split := range []int{mid - offset, mid + offset} {
+			if split < 1 || split >= len(records) {
+				continue
+			}
+			left, right := records[:split], records[split:]
+			if len(left) <= t.maxRecords() && len(right) <= t.maxRecords() &&
+				t.canFitRecords(left) && t.canFitRecords(right) {
+				return left, right, true
+			}
+		}
+	}
+	return nil, nil, false
+}
+
+// lowerRoot shortens the tree while the root is an internal page with a
+// single child. As btr_lift_page_up, the records of the child move into the
+// root page, which keeps its page number, and the child is freed.
+func (t *PageTree) lowerRoot(m *pageMtr) error {
+	for {
+		h, err := t.fetch(m, t.RootPage)
+		if err != nil {
+			return err
+		}
+		if page.PageGetLevel(h.data) == 0 {
+			return h.commit(false)
+		}
+		records := collectUserRecords(h.data)
+		if len(records) != 1 {
+			return h.commit(false)
+		}
+		_, child, ok := decodeNodePtrRecord(records[0])
+		if !ok {
+			_ = h.commit(false)
+			return errors.New("btr: invalid node pointer")
+		}
+		ch, err := t.fetch(m, child)
+		if err != nil {
+			_ = h.commit(false)
+			return err
+		}
+		level := page.PageGetLevel(ch.data)
+		childRecords := t.sortRecords(collectUserRecords(ch.data))
+		_ = ch.commit(false)
+		if !rebuildIndexPage(h.data, t.SpaceID, t.RootPage, level, fil.NullPageOffset, fil.NullPageOffset, childRecords) {
+			_ = h.commit(false)
+			return errors.New("btr: root lower rebuild failed")
+		}
+		if err := h.commit(true); err != nil {
+			return err
+		}
+		if err := t.freePage(m, child); err != nil {
+			return err
+		}
+	}
+}
+
+// freePage wipes a page no longer in the tree and returns it to fsp when m
+// commits.
+func (t *PageTree) freePage(m *pageMtr, pageNo uint32) error {
+	h, err := t.fetch(m, pageNo)
+	if err != nil {
+		return err
+	}
+	clear(h.data)
+	page.PageSetSpaceID(h.data, t.SpaceID)
+	page.PageSetPageNo(h.data, pageNo)
+	page.PageSetType(h.data, fil.PageTypeAllocated)
+	page.PageSetPrev(h.data, fil.NullPageOffset)
+	page.PageSetNext(h.data, fil.NullPageOffset)
+	if err := h.commit(true); err != nil {
+		return err
+	}
+	m.freePage(pageNo)
+	return nil
+}
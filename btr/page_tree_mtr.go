@@ -0,0 +1,75 @@
+package btr
+
+import (
+	"github.com/wilhasse/innodb-go/fsp"
+	"github.com/wilhasse/innodb-go/mtr"
+	"github.com/wilhasse/innodb-go/ut"
+)
+
+// pageMtr is the mini-transaction of a PageTree operation that changes
+// several pages. Pages fetched through it stay fixed until commit, which
+// redo-logs every changed page as one log group, so recovery replays the
+// operation completely or not at all. Pages freed in it go back to fsp only
+// after that.
+type pageMtr struct {
+	spaceID uint32
+	handles map[uint32]*pageHandle
+	order   []uint32
+	dirty   map[uint32]bool
+	freed   []uint32
+}
+
+func (t *PageTree) startMtr() *pageMtr {
+	return &pageMtr{
+		spaceID: t.SpaceID,
+		handles: map[uint32]*pageHandle{},
+		dirty:   map[uint32]bool{},
+	}
+}
+
+// fetch reads a page for an operation running in m, or on its own when m
+// is nil.
+func (t *PageTree) fetch(m *pageMtr, pageNo uint32) (*pageHandle, error) {
+	if m == nil {
+		return t.fetchPage(pageNo)
+	}
+	h := m.handles[pageNo]
+	if h == nil {
+		var err error
+		h, err = fetchSpacePage(m.spaceID, pageNo)
+		if err != nil {
+			return nil, err
+		}
+		m.handles[pageNo] = h
+		m.order = append(m.order, pageNo)
+	}
+	return &pageHandle{spaceID: h.spaceID, pageNo: pageNo, data: h.data, mtr: m}, nil
+}
+
+// freePage marks pageNo to be returned to fsp when m commits.
+func (m *pageMtr) freePage(pageNo uint32) {
+	m.freed = append(m.freed, pageNo)
+}
+
+// commit logs the changed pages, writes them back and frees the pages
+// released by the operation.
+func (m *pageMtr) commit() error {
+	var mini mtr.Mtr
+	mtr.Start(&mini)
+	for _, pageNo := range m.order {
+		if m.dirty[pageNo] {
+			mtr.MlogLogString(m.handles[pageNo].data, 0, ut.UNIV_PAGE_SIZE, &mini)
+		}
+	}
+	mtr.Commit(&mini)
+	var firstErr error
+	for _, pageNo := range m.order {
+		if err := m.handles[pageNo].commit(m.dirty[pageNo]); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	for _, pageNo := range m.freed {
+		fsp.FreePage(m.spaceID, pageNo)
+	}
+	return firstErr
+}
@@ -228,6 +228,25 @@ func findChildPage(records [][]byte, key []byte, compare CompareFunc) (uint32, b
 	return child, found
 }
 
+// findChildIndex is findChildPage returning the index of the node pointer.
+func findChildIndex(records [][]byte, key []byte, compare CompareFunc) (int, bool) {
+	idx := -1
+	for i, recBytes := range records {
+		recKey, _, ok := decodeNodePtrRecord(recBytes)
+		if !ok {
+			continue
+		}
+		if idx < 0 {
+			idx = i
+		}
+		if compare(recKey, key) > 0 {
+			break
+		}
+		idx = i
+	}
+	return idx, idx >= 0
+}
+
 func rebuildRecordPage(pageBytes []byte, records [][]byte) bool {
 	if pageBytes == nil {
 		return false
@@ -3,6 +3,7 @@ package btr
 import (
 	"bytes"
 	"errors"
+	"fmt"
 	"path/filepath"
 	"testing"
 
@@ -11,6 +12,7 @@ import (
 	"github.com/wilhasse/innodb-go/fsp"
 	ibos "github.com/wilhasse/innodb-go/os"
 	"github.com/wilhasse/innodb-go/page"
+	"github.com/wilhasse/innodb-go/ut"
 )
 
 func setupPageTree(t *testing.T) (*PageTree, func()) {
@@ -180,3 +182,274 @@ func collectLeafKeys(tree *PageTree) ([]string, error) {
 	}
 	return keys, nil
 }
+
+func TestPageTreeCursorPrev(t *testing.T) {
+	tree, cleanup := setupPageTree(t)
+	defer cleanup()
+
+	tree.MaxRecs = 3
+	keys := []string{"a", "b", "c", "d", "e", "f", "g"}
+	for _, key := range keys {
+		if _, err := tree.Insert([]byte(key), []byte("v"+key)); err != nil {
+			t.Fatalf("insert %s: %v", key, err)
+		}
+	}
+	cur, err := tree.Last()
+	if err != nil {
+		t.Fatalf("last: %v", err)
+	}
+	got := make([]string, 0, len(keys))
+	for cur.Valid() {
+		got = append(got, string(cur.Key()))
+		if !cur.Prev() {
+			break
+		}
+	}
+	if len(got) != len(keys) {
+		t.Fatalf("expected %d keys, got %v", len(keys), got)
+	}
+	for i := range got {
+		if want := keys[len(keys)-1-i]; got[i] != want {
+			t.Fatalf("got[%d]=%s want %s", i, got[i], want)
+		}
+	}
+
+	cur, _, err = tree.Seek([]byte("cc"), SearchLE)
+	if err != nil {
+		t.Fatalf("seek: %v", err)
+	}
+	if !cur.Valid() || string(cur.Key()) != "c" {
+		t.Fatalf("expected seek LE to land on c, got %q", cur.Key())
+	}
+	cur, _, err = tree.Seek([]byte("0"), SearchLE)
+	if err != nil {
+		t.Fatalf("seek before first: %v", err)
+	}
+	if cur.Valid() {
+		t.Fatalf("expected no record before first, got %q", cur.Key())
+	}
+}
+
+func TestPageTreeForEachRange(t *testing.T) {
+	tree, cleanup := setupPageTree(t)
+	defer cleanup()
+
+	tree.MaxRecs = 3
+	for _, key := range []string{"a", "b", "c", "d", "e", "f", "g"} {
+		if _, err := tree.Insert([]byte(key), []byte("v"+key)); err != nil {
+			t.Fatalf("insert %s: %v", key, err)
+		}
+	}
+	var got []string
+	err := tree.ForEachRange([]byte("bb"), []byte("f"), func(key, value []byte) bool {
+		got = append(got, string(key))
+		return true
+	})
+	if err != nil {
+		t.Fatalf("range: %v", err)
+	}
+	if len(got) != 3 || got[0] != "c" || got[2] != "e" {
+		t.Fatalf("unexpected range keys %v", got)
+	}
+	got = got[:0]
+	if err := tree.ForEachRange(nil, []byte("c"), func(key, value []byte) bool {
+		got = append(got, string(key))
+		return true
+	}); err != nil {
+		t.Fatalf("open range: %v", err)
+	}
+	if len(got) != 2 || got[0] != "a" || got[1] != "b" {
+		t.Fatalf("unexpected open range keys %v", got)
+	}
+}
+
+func TestPageTreeTruncate(t *testing.T) {
+	tree, cleanup := setupPageTree(t)
+	defer cleanup()
+
+	tree.MaxRecs = 3
+	blob, err := StoreBigRecExternFields(tree.SpaceID, bytes.Repeat([]byte("b"), 3*ut.UNIV_PAGE_SIZE), 0)
+	if err != nil {
+		t.Fatalf("StoreBigRecExternFields: %v", err)
+	}
+	keys := []string{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j"}
+	for _, key := range keys {
+		value := []byte("v" + key)
+		if key == "g" {
+			value = blob
+		}
+		if _, err := tree.Insert([]byte(key), value); err != nil {
+			t.Fatalf("insert %s: %v", key, err)
+		}
+	}
+	grown, err := treePages(tree)
+	if err != nil || len(grown) < 4 {
+		t.Fatalf("tree pages=%d err=%v", len(grown), err)
+	}
+	root := tree.RootPage
+	limit := fsp.AllocPage(tree.SpaceID)
+	fsp.FreePage(tree.SpaceID, limit)
+	externFields := func(value []byte) [][]byte {
+		if bytes.Equal(value, blob) {
+			return [][]byte{value}
+		}
+		return nil
+	}
+	if err := tree.Truncate(externFields); err != nil {
+		t.Fatalf("truncate: %v", err)
+	}
+	if tree.Size() != 0 || tree.RootPage != root {
+		t.Fatalf("size=%d root=%d, want 0 and %d", tree.Size(), tree.RootPage, root)
+	}
+	if _, ok, _ := tree.Search([]byte("c")); ok {
+		t.Fatalf("record survived truncate")
+	}
+	if got := GetExternallyStoredField(blob); got != nil {
+		t.Fatalf("BLOB chain survived truncate")
+	}
+	// The released pages are handed out again before the space grows.
+	for i := 0; i < len(grown)-1; i++ {
+		if pageNo := fsp.AllocPage(tree.SpaceID); pageNo >= limit {
+			t.Fatalf("allocated page %d, want a page below %d", pageNo, limit)
+		}
+	}
+	if _, err := tree.Insert([]byte("z"), []byte("vz")); err != nil {
+		t.Fatalf("insert after truncate: %v", err)
+	}
+	count := 0
+	if err := tree.ForEach(func(_, _ []byte) bool {
+		count++
+		return true
+	}); err != nil || count != 1 {
+		t.Fatalf("records after truncate=%d err=%v", count, err)
+	}
+}
+
+func TestPageTreeDeleteMergesPages(t *testing.T) {
+	tree, cleanup := setupPageTree(t)
+	defer cleanup()
+
+	tree.MaxRecs = 4
+	const n = 200
+	key := func(i int) []byte { return []byte(fmt.Sprintf("k%04d", i)) }
+	var root uint32
+	for i := 0; i < n; i++ {
+		if _, err := tree.Insert(key(i), []byte("v")); err != nil {
+			t.Fatalf("insert %d: %v", i, err)
+		}
+		if i == 0 {
+			root = tree.RootPage
+		}
+	}
+	grown, err := treePages(tree)
+	if err != nil {
+		t.Fatalf("tree pages: %v", err)
+	}
+	// Delete in an interleaved order so that merges and moves happen on
+	// both sides of a page and at every level.
+	for step := 0; step < 3; step++ {
+		for i := step; i < n; i += 3 {
+			if i%50 == 7 {
+				continue
+			}
+			deleted, err := tree.Delete(key(i))
+			if err != nil || !deleted {
+				t.Fatalf("delete %d: deleted=%v err=%v", i, deleted, err)
+			}
+		}
+	}
+	keys, err := collectLeafKeys(tree)
+	if err != nil {
+		t.Fatalf("collect keys: %v", err)
+	}
+	want := []string{"k0007", "k0057", "k0107", "k0157"}
+	if fmt.Sprint(keys) != fmt.Sprint(want) || tree.Size() != len(want) {
+		t.Fatalf("keys=%v size=%d, want %v", keys, tree.Size(), want)
+	}
+	for _, k := range want {
+		if _, ok, err := tree.Search([]byte(k)); err != nil || !ok {
+			t.Fatalf("search %s: ok=%v err=%v", k, ok, err)
+		}
+	}
+	shrunk, err := treePages(tree)
+	if err != nil {
+		t.Fatalf("tree pages: %v", err)
+	}
+	if len(shrunk) > len(grown)/10 {
+		t.Fatalf("tree kept %d of %d pages for %d records", len(shrunk), len(grown), len(want))
+	}
+	if level, _ := tree.pageLevel(tree.RootPage); level > 1 {
+		t.Fatalf("root level=%d after deletes", level)
+	}
+	for _, k := range want {
+		if _, err := tree.Delete([]byte(k)); err != nil {
+			t.Fatalf("delete %s: %v", k, err)
+		}
+	}
+	if empty, err := treePages(tree); err != nil || len(empty) != 1 {
+		t.Fatalf("empty tree pages=%d err=%v, want a single root leaf", len(empty), err)
+	}
+	// The root keeps its page number as the tree grows and shrinks.
+	if tree.RootPage != root {
+		t.Fatalf("root moved from %d to %d", root, tree.RootPage)
+	}
+
+	// Freed pages are reused before the tablespace grows.
+	maxPage := uint32(0)
+	for pageNo := range grown {
+		if pageNo > maxPage {
+			maxPage = pageNo
+		}
+	}
+	for i := 0; i < n; i++ {
+		if _, err := tree.Insert(key(i), []byte("v")); err != nil {
+			t.Fatalf("reinsert %d: %v", i, err)
+		}
+	}
+	regrown, err := treePages(tree)
+	if err != nil {
+		t.Fatalf("tree pages: %v", err)
+	}
+	for pageNo := range regrown {
+		if pageNo > maxPage+1 {
+			t.Fatalf("page %d allocated beyond %d", pageNo, maxPage)
+		}
+	}
+}
+
+// treePages returns the pages reachable from the root of tree, checking
+// that the leaf chain links back.
+func treePages(tree *PageTree) (map[uint32]bool, error) {
+	pages := map[uint32]bool{}
+	pending := []uint32{tree.RootPage}
+	for len(pending) > 0 {
+		pageNo := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		pageBytes, err := fil.SpaceReadPage(tree.SpaceID, pageNo)
+		if err != nil {
+			return nil, err
+		}
+		if page.PageGetType(pageBytes) != fil.PageTypeIndex {
+			return nil, fmt.Errorf("page %d is not an index page", pageNo)
+		}
+		pages[pageNo] = true
+		if page.PageGetLevel(pageBytes) == 0 {
+			if next := page.PageGetNext(pageBytes); !isNullPageNo(next) {
+				nextBytes, err := fil.SpaceReadPage(tree.SpaceID, next)
+				if err != nil {
+					return nil, err
+				}
+				if page.PageGetPrev(nextBytes) != pageNo {
+					return nil, fmt.Errorf("page %d prev link broken", next)
+				}
+			}
+			continue
+		}
+		for _, recBytes := range collectUserRecords(pageBytes) {
+			if _, child, ok := decodeNodePtrRecord(recBytes); ok {
+				pending = append(pending, child)
+			}
+		}
+	}
+	return pages, nil
+}
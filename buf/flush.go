@@ -1,7 +1,5 @@
 package buf
 
-import "github.com/wilhasse/innodb-go/fil"
-
 // FlushType mirrors buf_flush.
 type FlushType int
 
@@ -91,7 +89,7 @@ func (p *Pool) flushDirty(page *Page) bool {
 	if p == nil || page == nil || !page.Dirty {
 		return false
 	}
-	if err := fil.SpaceWritePage(page.ID.Space, page.ID.PageNo, page.Data); err != nil {
+	if err := p.writePage(page); err != nil {
 		return false
 	}
 	page.Dirty = false
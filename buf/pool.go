@@ -5,7 +5,6 @@ import (
 	"errors"
 	"sync"
 
-	"github.com/wilhasse/innodb-go/fil"
 	"github.com/wilhasse/innodb-go/ut"
 )
 
@@ -28,6 +27,7 @@ type Page struct {
 	Dirty     bool
 	IsOld     bool
 	PinCount  int
+	Zip       *BuddyBlock
 	lruElem   *list.Element
 	flushElem *list.Element
 }
@@ -51,6 +51,7 @@ type Pool struct {
 	lru       *LRU
 	flush     *list.List
 	readAhead *ReadAhead
+	zipArenas []*BuddyAllocator
 	hits      uint64
 	misses    uint64
 	evicts    uint64
@@ -130,6 +131,7 @@ func (p *Pool) Drop(space, pageNo uint32) {
 		p.lru.Remove(page)
 	}
 	p.removeFromFlushList(page)
+	p.freeZip(page)
 }
 
 // Flush clears dirty flags and returns the number of pages flushed.
@@ -177,6 +179,7 @@ func (p *Pool) evictOne() bool {
 		delete(p.pages, page.ID)
 		p.lru.Remove(page)
 		p.removeFromFlushList(page)
+		p.freeZip(page)
 		p.evicts++
 		return true
 	}
@@ -242,7 +245,7 @@ func (p *Pool) fetch(space, pageNo uint32, prefetch bool) (*Page, bool, error) {
 		Data:     make([]byte, p.pageSize),
 		PinCount: 1,
 	}
-	if err := fil.SpaceReadPageInto(space, pageNo, page.Data); err != nil {
+	if err := p.readPage(page); err != nil {
 		p.mu.Unlock()
 		return nil, false, err
 	}
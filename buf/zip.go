@@ -0,0 +1,63 @@
+package buf
+
+import "github.com/wilhasse/innodb-go/fil"
+
+// allocZip returns a frame of size bytes for the compressed copy of a page,
+// adding a buddy arena when the existing ones are full. Caller holds p.mu.
+func (p *Pool) allocZip(size int) *BuddyBlock {
+	for _, arena := range p.zipArenas {
+		if block, ok := arena.Alloc(size); ok {
+			return block
+		}
+	}
+	arena, err := NewBuddyAllocator(BufBuddyHigh)
+	if err != nil {
+		return nil
+	}
+	block, ok := arena.Alloc(size)
+	if !ok {
+		return nil
+	}
+	p.zipArenas = append(p.zipArenas, arena)
+	return block
+}
+
+// freeZip returns the compressed frame of page to its arena. Caller holds
+// p.mu.
+func (p *Pool) freeZip(page *Page) {
+	if page == nil || page.Zip == nil {
+		return
+	}
+	_ = page.Zip.allocator.Free(page.Zip)
+	page.Zip = nil
+}
+
+// readPage loads a page into page.Data. Pages of compressed tablespaces are
+// read into a compressed frame kept with the page and decompressed from it.
+func (p *Pool) readPage(page *Page) error {
+	zipSize := fil.SpaceZipSize(page.ID.Space)
+	if zipSize == 0 {
+		return fil.SpaceReadPageInto(page.ID.Space, page.ID.PageNo, page.Data)
+	}
+	page.Zip = p.allocZip(zipSize)
+	var frame []byte
+	if page.Zip != nil {
+		frame = page.Zip.Bytes()
+	} else {
+		frame = make([]byte, zipSize)
+	}
+	if err := fil.SpaceReadPageZip(page.ID.Space, page.ID.PageNo, page.Data, frame); err != nil {
+		p.freeZip(page)
+		return err
+	}
+	return nil
+}
+
+// writePage writes page.Data to its tablespace, compressing it into the
+// compressed frame of the page first when it has one.
+func (p *Pool) writePage(page *Page) error {
+	if page.Zip == nil {
+		return fil.SpaceWritePage(page.ID.Space, page.ID.PageNo, page.Data)
+	}
+	return fil.SpaceWritePageZip(page.ID.Space, page.ID.PageNo, page.Data, page.Zip.Bytes())
+}
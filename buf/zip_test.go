@@ -0,0 +1,59 @@
+package buf
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+
+	"github.com/wilhasse/innodb-go/fil"
+	ibos "github.com/wilhasse/innodb-go/os"
+	_ "github.com/wilhasse/innodb-go/page"
+)
+
+func TestPoolCompressedPages(t *testing.T) {
+	fil.VarInit()
+	file, err := ibos.FileCreateSimple(filepath.Join(t.TempDir(), "zip.ibd"), ibos.FileOverwrite, ibos.FileReadWrite)
+	if err != nil {
+		t.Fatalf("FileCreateSimple: %v", err)
+	}
+	defer ibos.FileClose(file)
+	if !fil.SpaceCreate("zip", 5, 2048, fil.SpaceTablespace) {
+		t.Fatalf("SpaceCreate failed")
+	}
+	defer fil.SpaceDrop(5)
+	if err := fil.SpaceSetFile(5, file); err != nil {
+		t.Fatalf("SpaceSetFile: %v", err)
+	}
+
+	pool := NewPool(4, BufPoolDefaultPageSize)
+	page, _, err := pool.Fetch(5, 1)
+	if err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+	if page.Zip == nil || page.Zip.Size() != 2048 {
+		t.Fatalf("compressed frame missing")
+	}
+	want := bytes.Repeat([]byte("compressed page "), 100)
+	copy(page.Data[100:], want)
+	pool.MarkDirty(page)
+	pool.Release(page)
+	if flushed := pool.Flush(); flushed != 1 {
+		t.Fatalf("flushed=%d, want 1", flushed)
+	}
+	if size, err := ibos.FileSize(file); err != nil || size != 2*2048 {
+		t.Fatalf("file size=%d err=%v", size, err)
+	}
+
+	pool.Drop(5, 1)
+	if page.Zip != nil {
+		t.Fatalf("Drop kept the compressed frame")
+	}
+	page, hit, err := pool.Fetch(5, 1)
+	if err != nil || hit {
+		t.Fatalf("Fetch after drop: hit=%v err=%v", hit, err)
+	}
+	defer pool.Release(page)
+	if !bytes.Equal(page.Data[100:100+len(want)], want) {
+		t.Fatalf("page mismatch after reload")
+	}
+}
@@ -12,6 +12,7 @@ var (
 	ErrTableExists   = errors.New("dict: table already exists")
 	ErrTableNotFound = errors.New("dict: table not found")
 	ErrIndexExists   = errors.New("dict: index already exists")
+	ErrIndexNotFound = errors.New("dict: index not found")
 )
 
 // Index type flags.
@@ -136,6 +136,50 @@ func DictPersistIndexCreate(table *Table, index *Index) error {
 	return DictPersist()
 }
 
+// DictPersistIndexDrop removes an index from SYS_* rows and persists it.
+func DictPersistIndexDrop(table *Table, name string) error {
+	if table == nil || table.Name == "" || name == "" {
+		return ErrInvalidName
+	}
+	if DictSys == nil {
+		return ErrTableNotFound
+	}
+	DictSys.mu.Lock()
+	if _, exists := table.Indexes[name]; !exists {
+		DictSys.mu.Unlock()
+		return ErrIndexNotFound
+	}
+	delete(table.Indexes, name)
+	removeTableSysRows(table)
+	addTableSysRows(table)
+	dedupeSysRows()
+	DictSys.mu.Unlock()
+	return DictPersist()
+}
+
+// DictPersistTableReplace swaps the metadata of oldTable for newTable in a
+// single persist so readers see either the old or the new definition.
+func DictPersistTableReplace(oldTable, newTable *Table) error {
+	if oldTable == nil || newTable == nil || newTable.Name == "" {
+		return ErrInvalidName
+	}
+	if DictSys == nil {
+		return ErrTableNotFound
+	}
+	DictSys.mu.Lock()
+	if DictSys.Tables == nil {
+		DictSys.Tables = make(map[string]*Table)
+	}
+	removeTableSysRows(oldTable)
+	removeTableSysRows(newTable)
+	addTableSysRows(newTable)
+	dedupeSysRows()
+	delete(DictSys.Tables, oldTable.Name)
+	DictSys.Tables[newTable.Name] = newTable
+	DictSys.mu.Unlock()
+	return DictPersist()
+}
+
 // DictPersistTableRename updates a table name and persists SYS_* rows.
 func DictPersistTableRename(table *Table, newName string) error {
 	if table == nil || table.Name == "" || newName == "" {
@@ -0,0 +1,101 @@
+package eval
+
+import "fmt"
+
+// Aggregate accumulates one aggregate function over the rows of a group.
+// NULL inputs are ignored, except by COUNT(*) which counts every row. Over no
+// non-NULL input COUNT returns 0 and the other functions return NULL.
+type Aggregate struct {
+	Name    string
+	Star    bool
+	count   int64
+	sum     int64
+	fsum    float64
+	isFloat bool
+	best    Value
+}
+
+// NewAggregate returns the accumulator for the aggregate function name. Star
+// selects COUNT(*).
+func NewAggregate(name string, star bool) (*Aggregate, error) {
+	switch name {
+	case "COUNT":
+	case "SUM", "MIN", "MAX", "AVG":
+		if star {
+			return nil, fmt.Errorf("eval: %s(*) is not supported", name)
+		}
+	default:
+		return nil, fmt.Errorf("eval: unknown aggregate %s", name)
+	}
+	return &Aggregate{Name: name, Star: star}, nil
+}
+
+// Add feeds the value of the aggregate argument for one row. COUNT(*)
+// ignores val.
+func (a *Aggregate) Add(val Value) error {
+	if a.Star {
+		a.count++
+		return nil
+	}
+	if val.Kind == KindNull {
+		return nil
+	}
+	switch a.Name {
+	case "SUM", "AVG":
+		n, f, isFloat, err := valueNumber(val)
+		if err != nil {
+			return err
+		}
+		switch {
+		case isFloat && !a.isFloat:
+			a.isFloat = true
+			a.fsum = float64(a.sum) + f
+		case a.isFloat && isFloat:
+			a.fsum += f
+		case a.isFloat:
+			a.fsum += float64(n)
+		default:
+			a.sum += n
+		}
+	case "MIN", "MAX":
+		if a.count > 0 {
+			cmp, err := compareValues(val, a.best)
+			if err != nil {
+				return err
+			}
+			if (a.Name == "MIN" && cmp >= 0) || (a.Name == "MAX" && cmp <= 0) {
+				a.count++
+				return nil
+			}
+		}
+		a.best = val
+	}
+	a.count++
+	return nil
+}
+
+// Result returns the aggregate over the values added so far. SUM of integers
+// is an integer, AVG is always a float.
+func (a *Aggregate) Result() Value {
+	if a.Name == "COUNT" {
+		return Value{Kind: KindInt, Int: a.count}
+	}
+	if a.count == 0 {
+		return Value{Kind: KindNull}
+	}
+	switch a.Name {
+	case "SUM":
+		if a.isFloat {
+			return Value{Kind: KindFloat, Float: a.fsum}
+		}
+		return Value{Kind: KindInt, Int: a.sum}
+	case "AVG":
+		total := a.fsum
+		if !a.isFloat {
+			total = float64(a.sum)
+		}
+		return Value{Kind: KindFloat, Float: total / float64(a.count)}
+	default:
+		return a.best
+	}
+}
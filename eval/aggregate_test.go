@@ -0,0 +1,63 @@
+package eval
+
+import "testing"
+
+func TestAggregateNullSemantics(t *testing.T) {
+	inputs := []Value{
+		{Kind: KindInt, Int: 4},
+		{Kind: KindNull},
+		{Kind: KindInt, Int: -2},
+		{Kind: KindBytes, Bytes: []byte("7")},
+	}
+	tests := []struct {
+		name string
+		star bool
+		want Value
+	}{
+		{"COUNT", true, Value{Kind: KindInt, Int: 4}},
+		{"COUNT", false, Value{Kind: KindInt, Int: 3}},
+		{"SUM", false, Value{Kind: KindInt, Int: 9}},
+		{"MIN", false, Value{Kind: KindInt, Int: -2}},
+		{"MAX", false, Value{Kind: KindBytes, Bytes: []byte("7")}},
+		{"AVG", false, Value{Kind: KindFloat, Float: 3}},
+	}
+	for _, tt := range tests {
+		agg, err := NewAggregate(tt.name, tt.star)
+		if err != nil {
+			t.Fatalf("NewAggregate %s: %v", tt.name, err)
+		}
+		for _, val := range inputs {
+			if err := agg.Add(val); err != nil {
+				t.Fatalf("%s Add: %v", tt.name, err)
+			}
+		}
+		got := agg.Result()
+		if cmp, err := compareValues(got, tt.want); err != nil || cmp != 0 || got.Kind != tt.want.Kind {
+			t.Fatalf("%s(star=%v)=%+v want %+v", tt.name, tt.star, got, tt.want)
+		}
+	}
+
+	for _, name := range []string{"SUM", "MIN", "MAX", "AVG"} {
+		agg, _ := NewAggregate(name, false)
+		_ = agg.Add(Value{Kind: KindNull})
+		if got := agg.Result(); got.Kind != KindNull {
+			t.Fatalf("%s over NULLs=%+v", name, got)
+		}
+	}
+	agg, _ := NewAggregate("COUNT", false)
+	if got := agg.Result(); got.Kind != KindInt || got.Int != 0 {
+		t.Fatalf("COUNT over no rows=%+v", got)
+	}
+	agg, _ = NewAggregate("SUM", false)
+	_ = agg.Add(Value{Kind: KindInt, Int: 1})
+	_ = agg.Add(Value{Kind: KindFloat, Float: 0.5})
+	if got := agg.Result(); got.Kind != KindFloat || got.Float != 1.5 {
+		t.Fatalf("mixed SUM=%+v", got)
+	}
+	if _, err := NewAggregate("SUM", true); err == nil {
+		t.Fatalf("expected error for SUM(*)")
+	}
+	if err := agg.Add(Value{Kind: KindBytes, Bytes: []byte("x")}); err == nil {
+		t.Fatalf("expected error summing text")
+	}
+}
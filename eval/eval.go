@@ -13,6 +13,7 @@ const (
 	KindInt
 	KindBool
 	KindBytes
+	KindFloat
 )
 
 // Value holds an evaluated value.
@@ -21,6 +22,7 @@ type Value struct {
 	Int   int64
 	Bool  bool
 	Bytes []byte
+	Float float64
 }
 
 // NodeType identifies a query graph node.
@@ -37,6 +39,8 @@ const (
 	NodeAssignment
 	NodeProc
 	NodeSymbol
+	NodeExpr
+	NodeStatement
 )
 
 // NodeRef provides access to the embedded node data.
@@ -213,6 +217,22 @@ func nodeBase(node NodeRef) *Node {
 }
 
 func compareValues(left, right Value) (int, error) {
+	if (left.Kind == KindFloat) != (right.Kind == KindFloat) {
+		return compareNumbers(left, right)
+	}
+	if left.Kind == KindInt && right.Kind == KindBytes {
+		n, err := valueInt(right)
+		if err != nil {
+			return 0, errors.New("eval: mismatched value kinds")
+		}
+		right = Value{Kind: KindInt, Int: n}
+	} else if left.Kind == KindBytes && right.Kind == KindInt {
+		n, err := valueInt(left)
+		if err != nil {
+			return 0, errors.New("eval: mismatched value kinds")
+		}
+		left = Value{Kind: KindInt, Int: n}
+	}
 	if left.Kind != right.Kind {
 		return 0, errors.New("eval: mismatched value kinds")
 	}
@@ -237,9 +257,42 @@ func compareValues(left, right Value) (int, error) {
 		}
 	case KindBytes:
 		return bytes.Compare(left.Bytes, right.Bytes), nil
+	case KindFloat:
+		return compareNumbers(left, right)
 	case KindNull:
 		return 0, nil
 	default:
 		return 0, errors.New("eval: unsupported value kind")
 	}
 }
+
+// compareNumbers compares two numeric values as floats.
+func compareNumbers(left, right Value) (int, error) {
+	l, err := valueFloat(left)
+	if err != nil {
+		return 0, err
+	}
+	r, err := valueFloat(right)
+	if err != nil {
+		return 0, err
+	}
+	switch {
+	case l < r:
+		return -1, nil
+	case l > r:
+		return 1, nil
+	default:
+		return 0, nil
+	}
+}
+
+func valueFloat(val Value) (float64, error) {
+	n, f, isFloat, err := valueNumber(val)
+	if err != nil {
+		return 0, errors.New("eval: mismatched value kinds")
+	}
+	if isFloat {
+		return f, nil
+	}
+	return float64(n), nil
+}
@@ -2,6 +2,8 @@ package eval
 
 import (
 	"fmt"
+	"math"
+	"strconv"
 	"strings"
 
 	"github.com/wilhasse/innodb-go/data"
@@ -10,19 +12,48 @@ import (
 
 // EvalExpr evaluates a parsed expression against a tuple row.
 func EvalExpr(expr pars.Expr, row *data.Tuple, columns []string) (Value, error) {
+	return EvalExprWith(expr, func(name string) (Value, error) {
+		return valueFromIdent(pars.IdentExpr{Name: name}, row, columns)
+	})
+}
+
+// EvalExprWith evaluates a parsed expression, resolving column references
+// through ident.
+func EvalExprWith(expr pars.Expr, ident func(name string) (Value, error)) (Value, error) {
 	switch e := expr.(type) {
 	case pars.LiteralExpr:
 		return valueFromLiteral(e), nil
 	case pars.IdentExpr:
-		return valueFromIdent(e, row, columns)
+		return ident(e.Name)
+	case pars.UnaryExpr:
+		val, err := EvalExprWith(e.Expr, ident)
+		if err != nil {
+			return Value{}, err
+		}
+		if e.Op != pars.TokenMinus {
+			return Value{}, fmt.Errorf("eval: unsupported unary op %v", e.Op)
+		}
+		if val.Kind == KindNull {
+			return val, nil
+		}
+		n, f, isFloat, err := valueNumber(val)
+		if err != nil {
+			return Value{}, err
+		}
+		if isFloat {
+			return Value{Kind: KindFloat, Float: -f}, nil
+		}
+		return Value{Kind: KindInt, Int: -n}, nil
+	case pars.FuncExpr:
+		return evalFunc(e, ident)
 	case pars.BinaryExpr:
 		switch e.Op {
 		case pars.TokenAnd, pars.TokenOr:
-			left, err := EvalBool(e.Left, row, columns)
+			left, err := evalBoolWith(e.Left, ident)
 			if err != nil {
 				return Value{}, err
 			}
-			right, err := EvalBool(e.Right, row, columns)
+			right, err := evalBoolWith(e.Right, ident)
 			if err != nil {
 				return Value{}, err
 			}
@@ -31,20 +62,33 @@ func EvalExpr(expr pars.Expr, row *data.Tuple, columns []string) (Value, error)
 				return Value{}, err
 			}
 			return Value{Kind: KindBool, Bool: val}, nil
-		case pars.TokenEq:
-			left, err := EvalExpr(e.Left, row, columns)
+		case pars.TokenEq, pars.TokenNe, pars.TokenLt, pars.TokenLe, pars.TokenGt, pars.TokenGe:
+			left, err := EvalExprWith(e.Left, ident)
 			if err != nil {
 				return Value{}, err
 			}
-			right, err := EvalExpr(e.Right, row, columns)
+			right, err := EvalExprWith(e.Right, ident)
 			if err != nil {
 				return Value{}, err
 			}
-			ok, err := EvalCmp("=", left, right)
+			ok, err := EvalCmp(opString(e.Op), left, right)
 			if err != nil {
 				return Value{}, err
 			}
 			return Value{Kind: KindBool, Bool: ok}, nil
+		case pars.TokenPlus, pars.TokenMinus, pars.TokenStar, pars.TokenSlash, pars.TokenPercent:
+			left, err := EvalExprWith(e.Left, ident)
+			if err != nil {
+				return Value{}, err
+			}
+			right, err := EvalExprWith(e.Right, ident)
+			if err != nil {
+				return Value{}, err
+			}
+			if left.Kind == KindNull || right.Kind == KindNull {
+				return Value{Kind: KindNull}, nil
+			}
+			return evalArithValues(opString(e.Op), left, right)
 		default:
 			return Value{}, fmt.Errorf("eval: unsupported op %v", e.Op)
 		}
@@ -53,17 +97,198 @@ func EvalExpr(expr pars.Expr, row *data.Tuple, columns []string) (Value, error)
 	}
 }
 
+// evalArithValues applies an arithmetic operator, in floating point when
+// either operand is a float and in integers otherwise.
+func evalArithValues(op string, left, right Value) (Value, error) {
+	l, lf, lFloat, err := valueNumber(left)
+	if err != nil {
+		return Value{}, err
+	}
+	r, rf, rFloat, err := valueNumber(right)
+	if err != nil {
+		return Value{}, err
+	}
+	if !lFloat && !rFloat {
+		n, err := EvalArith(op, Value{Kind: KindInt, Int: l}, Value{Kind: KindInt, Int: r})
+		if err != nil {
+			return Value{}, err
+		}
+		return Value{Kind: KindInt, Int: n}, nil
+	}
+	if !lFloat {
+		lf = float64(l)
+	}
+	if !rFloat {
+		rf = float64(r)
+	}
+	switch op {
+	case "+":
+		return Value{Kind: KindFloat, Float: lf + rf}, nil
+	case "-":
+		return Value{Kind: KindFloat, Float: lf - rf}, nil
+	case "*":
+		return Value{Kind: KindFloat, Float: lf * rf}, nil
+	case "/":
+		if rf == 0 {
+			return Value{}, fmt.Errorf("eval: divide by zero")
+		}
+		return Value{Kind: KindFloat, Float: lf / rf}, nil
+	case "%":
+		if rf == 0 {
+			return Value{}, fmt.Errorf("eval: modulo by zero")
+		}
+		return Value{Kind: KindFloat, Float: math.Mod(lf, rf)}, nil
+	default:
+		return Value{}, fmt.Errorf("eval: unsupported arithmetic op %s", op)
+	}
+}
+
+// evalFunc evaluates the scalar functions SUBSTR, CONCAT and INSTR. A NULL
+// argument makes the result NULL.
+func evalFunc(fn pars.FuncExpr, ident func(name string) (Value, error)) (Value, error) {
+	args := make([]Value, len(fn.Args))
+	for i, arg := range fn.Args {
+		val, err := EvalExprWith(arg, ident)
+		if err != nil {
+			return Value{}, err
+		}
+		if val.Kind == KindNull {
+			return Value{Kind: KindNull}, nil
+		}
+		args[i] = val
+	}
+	switch fn.Name {
+	case "SUBSTR", "SUBSTRING":
+		if len(args) != 2 && len(args) != 3 {
+			return Value{}, fmt.Errorf("eval: %s takes 2 or 3 arguments", fn.Name)
+		}
+		input := valueBytes(args[0])
+		pos, err := valueInt(args[1])
+		if err != nil {
+			return Value{}, err
+		}
+		length := int64(len(input))
+		if len(args) == 3 {
+			if length, err = valueInt(args[2]); err != nil {
+				return Value{}, err
+			}
+		}
+		switch {
+		case pos > 0:
+			pos--
+		case pos < 0:
+			pos += int64(len(input))
+			if pos < 0 {
+				return Value{Kind: KindBytes, Bytes: []byte{}}, nil
+			}
+		default:
+			return Value{Kind: KindBytes, Bytes: []byte{}}, nil
+		}
+		if pos > int64(len(input)) {
+			pos = int64(len(input))
+		}
+		if length > int64(len(input)) {
+			length = int64(len(input))
+		}
+		out := EvalSubstr(input, int(pos), int(length))
+		if out == nil {
+			out = []byte{}
+		}
+		return Value{Kind: KindBytes, Bytes: out}, nil
+	case "CONCAT":
+		parts := make([][]byte, len(args))
+		for i, arg := range args {
+			parts[i] = valueBytes(arg)
+		}
+		return Value{Kind: KindBytes, Bytes: EvalConcat(parts...)}, nil
+	case "INSTR":
+		if len(args) != 2 {
+			return Value{}, fmt.Errorf("eval: INSTR takes 2 arguments")
+		}
+		return Value{Kind: KindInt, Int: int64(EvalInstr(valueBytes(args[0]), valueBytes(args[1])))}, nil
+	default:
+		return Value{}, fmt.Errorf("eval: unknown function %s", fn.Name)
+	}
+}
+
+// valueNumber reads val as an integer or, for floats and bytes holding a
+// decimal fraction, as a float.
+func valueNumber(val Value) (int64, float64, bool, error) {
+	switch val.Kind {
+	case KindFloat:
+		return 0, val.Float, true, nil
+	case KindBytes:
+		text := strings.TrimSpace(string(val.Bytes))
+		if n, err := strconv.ParseInt(text, 10, 64); err == nil {
+			return n, 0, false, nil
+		}
+		f, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return 0, 0, false, fmt.Errorf("eval: %q is not a number", val.Bytes)
+		}
+		return 0, f, true, nil
+	default:
+		n, err := valueInt(val)
+		return n, 0, false, err
+	}
+}
+
+// valueInt reads val as an integer; bytes must hold decimal text.
+func valueInt(val Value) (int64, error) {
+	switch val.Kind {
+	case KindInt:
+		return val.Int, nil
+	case KindFloat:
+		return int64(val.Float), nil
+	case KindBool:
+		if val.Bool {
+			return 1, nil
+		}
+		return 0, nil
+	case KindBytes:
+		n, err := strconv.ParseInt(strings.TrimSpace(string(val.Bytes)), 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("eval: %q is not an integer", val.Bytes)
+		}
+		return n, nil
+	default:
+		return 0, fmt.Errorf("eval: integer operand required")
+	}
+}
+
+// valueBytes returns the text form of val.
+func valueBytes(val Value) []byte {
+	field := ValueToField(val)
+	if data.FieldIsNull(&field) {
+		return nil
+	}
+	return field.Data
+}
+
 // EvalBool evaluates an expression and returns a boolean result.
 func EvalBool(expr pars.Expr, row *data.Tuple, columns []string) (bool, error) {
-	val, err := EvalExpr(expr, row, columns)
+	return evalBoolWith(expr, func(name string) (Value, error) {
+		return valueFromIdent(pars.IdentExpr{Name: name}, row, columns)
+	})
+}
+
+func evalBoolWith(expr pars.Expr, ident func(name string) (Value, error)) (bool, error) {
+	val, err := EvalExprWith(expr, ident)
 	if err != nil {
 		return false, err
 	}
+	return ValueTrue(val)
+}
+
+// ValueTrue reports whether val counts as true in a condition. NULL is false.
+func ValueTrue(val Value) (bool, error) {
 	switch val.Kind {
 	case KindBool:
 		return val.Bool, nil
 	case KindInt:
 		return val.Int != 0, nil
+	case KindFloat:
+		return val.Float != 0, nil
 	case KindBytes:
 		return len(val.Bytes) > 0, nil
 	case KindNull:
@@ -86,6 +311,9 @@ func ValueToField(val Value) data.Field {
 	case KindInt:
 		text := []byte(fmt.Sprintf("%d", val.Int))
 		return data.Field{Data: text, Len: uint32(len(text))}
+	case KindFloat:
+		text := []byte(strconv.FormatFloat(val.Float, 'g', -1, 64))
+		return data.Field{Data: text, Len: uint32(len(text))}
 	case KindBytes:
 		buf := append([]byte(nil), val.Bytes...)
 		return data.Field{Data: buf, Len: uint32(len(buf))}
@@ -136,6 +364,28 @@ func opString(op pars.TokenType) string {
 		return "AND"
 	case pars.TokenOr:
 		return "OR"
+	case pars.TokenEq:
+		return "="
+	case pars.TokenNe:
+		return "!="
+	case pars.TokenLt:
+		return "<"
+	case pars.TokenLe:
+		return "<="
+	case pars.TokenGt:
+		return ">"
+	case pars.TokenGe:
+		return ">="
+	case pars.TokenPlus:
+		return "+"
+	case pars.TokenMinus:
+		return "-"
+	case pars.TokenStar:
+		return "*"
+	case pars.TokenSlash:
+		return "/"
+	case pars.TokenPercent:
+		return "%"
 	default:
 		return ""
 	}
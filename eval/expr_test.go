@@ -27,6 +27,30 @@ func TestEvalPredicate(t *testing.T) {
 	}
 }
 
+func TestEvalComparisons(t *testing.T) {
+	row := makeTuple("5", "m")
+	cols := []string{"id", "name"}
+	tests := []struct {
+		sql  string
+		want bool
+	}{
+		{"SELECT * FROM t WHERE id > 4", true},
+		{"SELECT * FROM t WHERE id >= 5", true},
+		{"SELECT * FROM t WHERE id < 5", false},
+		{"SELECT * FROM t WHERE id <= 5 AND name <> 'n'", true},
+		{"SELECT * FROM t WHERE name != 'm'", false},
+	}
+	for _, tt := range tests {
+		ok, err := EvalBool(whereExpr(t, tt.sql), row, cols)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.sql, err)
+		}
+		if ok != tt.want {
+			t.Fatalf("%s => %v want %v", tt.sql, ok, tt.want)
+		}
+	}
+}
+
 func TestEvalExprIdent(t *testing.T) {
 	row := makeTuple("1", "b")
 	val, err := EvalExpr(pars.IdentExpr{Name: "name"}, row, []string{"id", "name"})
@@ -42,6 +66,44 @@ func TestEvalExprIdent(t *testing.T) {
 	}
 }
 
+func TestEvalComputedExprs(t *testing.T) {
+	row := makeTuple("7", "hello")
+	cols := []string{"id", "name"}
+	tests := []struct {
+		sql  string
+		want string
+	}{
+		{"SELECT id * 2 + 1 FROM t", "15"},
+		{"SELECT (id - 10) / 2 FROM t", "-1"},
+		{"SELECT id % 4 FROM t", "3"},
+		{"SELECT -id FROM t", "-7"},
+		{"SELECT SUBSTR(name, 2, 3) FROM t", "ell"},
+		{"SELECT SUBSTR(name, -2) FROM t", "lo"},
+		{"SELECT CONCAT(name, '-', id) FROM t", "hello-7"},
+		{"SELECT INSTR(name, 'll') FROM t", "3"},
+	}
+	for _, tt := range tests {
+		stmt, err := pars.ParseSQL(tt.sql)
+		if err != nil {
+			t.Fatalf("parse %q: %v", tt.sql, err)
+		}
+		val, err := EvalExpr(stmt.(*pars.SelectStmt).Items[0].Expr, row, cols)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.sql, err)
+		}
+		if got := string(ValueToField(val).Data); got != tt.want {
+			t.Fatalf("%s => %q want %q", tt.sql, got, tt.want)
+		}
+	}
+	val, err := EvalExpr(pars.FuncExpr{Name: "CONCAT", Args: []pars.Expr{pars.IdentExpr{Name: "name"}, pars.LiteralExpr{Kind: pars.TokenNull}}}, row, cols)
+	if err != nil || val.Kind != KindNull {
+		t.Fatalf("CONCAT with NULL => %v, %v", val, err)
+	}
+	if _, err := EvalExpr(pars.BinaryExpr{Op: pars.TokenPlus, Left: pars.IdentExpr{Name: "name"}, Right: pars.IdentExpr{Name: "id"}}, row, cols); err == nil {
+		t.Fatalf("expected error adding text")
+	}
+}
+
 func whereExpr(t *testing.T, sql string) pars.Expr {
 	t.Helper()
 	stmt, err := pars.ParseSQL(sql)
@@ -69,6 +69,14 @@ func EvalExp(node NodeRef) {
 	switch n := node.(type) {
 	case *Symbol:
 		EvalSym(n)
+	case *ExprNode:
+		if n.Eval == nil {
+			return
+		}
+		n.Val, n.Err = n.Eval()
+		if n.Err != nil {
+			n.Val = Value{Kind: KindNull}
+		}
 	}
 }
 
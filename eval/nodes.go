@@ -129,6 +129,7 @@ func (n *AssignNode) Base() *Node {
 // ProcNode represents a stored procedure node.
 type ProcNode struct {
 	Node
+	StatList NodeRef
 }
 
 // Base returns the embedded node data.
@@ -138,3 +139,34 @@ func (n *ProcNode) Base() *Node {
 	}
 	return &n.Node
 }
+
+// ExprNode is an expression whose value EvalExp computes with Eval. When Eval
+// fails the value is NULL and the error is kept in Err.
+type ExprNode struct {
+	Node
+	Eval func() (Value, error)
+	Err  error
+}
+
+// Base returns the embedded node data.
+func (n *ExprNode) Base() *Node {
+	if n == nil {
+		return nil
+	}
+	return &n.Node
+}
+
+// StmtNode is a procedure statement carried out by Run, such as a SQL
+// statement or a cursor operation.
+type StmtNode struct {
+	Node
+	Run func() error
+}
+
+// Base returns the embedded node data.
+func (n *StmtNode) Base() *Node {
+	if n == nil {
+		return nil
+	}
+	return &n.Node
+}
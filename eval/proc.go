@@ -169,3 +169,125 @@ func returnStep(thr *Thr) *Thr {
 
 	return thr
 }
+
+// procStep executes a single step of a procedure node.
+func procStep(thr *Thr) *Thr {
+	if thr == nil {
+		return thr
+	}
+	node, ok := thr.RunNode.(*ProcNode)
+	if !ok || node == nil {
+		return thr
+	}
+
+	if thr.PrevNode == NodeGetParent(node) && node.StatList != nil {
+		thr.RunNode = node.StatList
+	} else {
+		thr.RunNode = NodeGetParent(node)
+	}
+
+	return thr
+}
+
+// stmtStep executes a single step of a statement node.
+func stmtStep(thr *Thr) (*Thr, error) {
+	if thr == nil {
+		return thr, nil
+	}
+	node, ok := thr.RunNode.(*StmtNode)
+	if !ok || node == nil {
+		return thr, nil
+	}
+
+	var err error
+	if node.Run != nil {
+		err = node.Run()
+	}
+	thr.RunNode = NodeGetParent(node)
+
+	return thr, err
+}
+
+// ProcStep executes one step of a procedure graph as que_thr_step does: a
+// control statement whose child statement has finished passes control to
+// the next child if there is one, and any other node runs its step
+// function. It returns the error of a failed statement or of an expression
+// the step evaluated.
+func ProcStep(thr *Thr) error {
+	if thr == nil || thr.RunNode == nil {
+		return nil
+	}
+	node := thr.RunNode
+	var err error
+	switch n := node.(type) {
+	case *IfNode, *WhileNode, *ForNode, *ProcNode:
+		if thr.PrevNode != NodeGetParent(node) && NodeGetNext(thr.PrevNode) != nil {
+			thr.RunNode = NodeGetNext(thr.PrevNode)
+			break
+		}
+		switch n := n.(type) {
+		case *IfNode:
+			evaluated := thr.PrevNode == NodeGetParent(n)
+			ifStep(thr)
+			if evaluated {
+				err = exprErr(n.Cond)
+				for elsif := n.ElsifList; err == nil && elsif != nil; {
+					err = exprErr(elsif.Cond)
+					elsif, _ = NodeGetNext(elsif).(*ElsifNode)
+				}
+			}
+		case *WhileNode:
+			whileStep(thr)
+			err = exprErr(n.Cond)
+		case *ForNode:
+			evaluated := thr.PrevNode == NodeGetParent(n)
+			forStep(thr)
+			if evaluated {
+				err = exprErr(n.LoopStartLimit, n.LoopEndLimit)
+			}
+		case *ProcNode:
+			procStep(thr)
+		}
+	case *AssignNode:
+		assignStep(thr)
+		err = exprErr(n.Val)
+	case *ExitNode:
+		exitStep(thr)
+	case *ReturnNode:
+		returnStep(thr)
+	case *StmtNode:
+		_, err = stmtStep(thr)
+	default:
+		thr.RunNode = NodeGetParent(node)
+	}
+	if NodeTypeOf(node) == NodeExit {
+		thr.PrevNode = NodeGetContainingLoop(node)
+	} else {
+		thr.PrevNode = node
+	}
+	return err
+}
+
+// ProcRun executes a procedure graph until it returns or a step fails.
+func ProcRun(proc *ProcNode) error {
+	if proc == nil {
+		return nil
+	}
+	thr := &Thr{RunNode: proc, PrevNode: NodeGetParent(proc)}
+	for thr.RunNode != nil && thr.RunNode != NodeGetParent(proc) {
+		if err := ProcStep(thr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// exprErr returns the first error kept by the expression nodes.
+func exprErr(nodes ...NodeRef) error {
+	for _, node := range nodes {
+		if expr, ok := node.(*ExprNode); ok && expr.Err != nil {
+			return expr.Err
+		}
+	}
+	return nil
+}
@@ -1,6 +1,9 @@
 package eval
 
-import "testing"
+import (
+	"errors"
+	"testing"
+)
 
 func TestIfStepTrueBranch(t *testing.T) {
 	parent := &ProcNode{Node: Node{Type: NodeProc}}
@@ -137,3 +140,49 @@ func TestReturnStep(t *testing.T) {
 		t.Fatalf("expected return to proc parent, got %v", thr.RunNode)
 	}
 }
+
+func TestProcRun(t *testing.T) {
+	proc := &ProcNode{Node: Node{Type: NodeProc}}
+	sum := &Symbol{Node: Node{Type: NodeSymbol}}
+	i := &Symbol{Node: Node{Type: NodeSymbol}}
+	expr := func(parent NodeRef, fn func() Value) *ExprNode {
+		return &ExprNode{Node: Node{Type: NodeExpr, Parent: parent}, Eval: func() (Value, error) { return fn(), nil }}
+	}
+
+	init := &AssignNode{Node: Node{Type: NodeAssignment, Parent: proc}, Var: sum}
+	init.Val = expr(init, func() Value { return Value{Kind: KindInt} })
+	loop := &ForNode{Node: Node{Type: NodeFor, Parent: proc}, LoopVar: i}
+	loop.LoopStartLimit = expr(loop, func() Value { return Value{Kind: KindInt, Int: 1} })
+	loop.LoopEndLimit = expr(loop, func() Value { return Value{Kind: KindInt, Int: 10} })
+	check := &IfNode{Node: Node{Type: NodeIf, Parent: loop}}
+	check.Cond = expr(check, func() Value { return Value{Kind: KindBool, Bool: i.Val.Int > 4} })
+	check.StatList = &ExitNode{Node: Node{Type: NodeExit, Parent: check}}
+	add := &AssignNode{Node: Node{Type: NodeAssignment, Parent: loop}, Var: sum}
+	add.Val = expr(add, func() Value { return Value{Kind: KindInt, Int: sum.Val.Int + i.Val.Int} })
+	NodeSetNext(check, add)
+	loop.StatList = check
+	var ran int64
+	done := &StmtNode{Node: Node{Type: NodeStatement, Parent: proc}, Run: func() error {
+		ran = sum.Val.Int
+		return nil
+	}}
+	NodeSetNext(init, loop)
+	NodeSetNext(loop, done)
+	proc.StatList = init
+
+	if err := ProcRun(proc); err != nil {
+		t.Fatalf("ProcRun: %v", err)
+	}
+	if sum.Val.Int != 10 || ran != 10 {
+		t.Fatalf("sum=%d ran=%d, want 10", sum.Val.Int, ran)
+	}
+
+	done.Run = func() error { return errors.New("boom") }
+	if err := ProcRun(proc); err == nil || err.Error() != "boom" {
+		t.Fatalf("expected statement error, got %v", err)
+	}
+	init.Val.(*ExprNode).Eval = func() (Value, error) { return Value{}, errors.New("bad") }
+	if err := ProcRun(proc); err == nil || err.Error() != "bad" {
+		t.Fatalf("expected expression error, got %v", err)
+	}
+}
@@ -162,6 +162,12 @@ func DoublewriteRecover() error {
 		}
 		spaceID := binary.BigEndian.Uint32(entry[0:])
 		pageNo := binary.BigEndian.Uint32(entry[4:])
+		if space := SpaceGetByID(spaceID); space != nil && space.Encrypted {
+			// Pages of encrypted tablespaces are buffered encrypted.
+			if err := decryptPage(space, pageNo, entry[8:]); err != nil {
+				continue
+			}
+		}
 		_ = SpaceWritePage(spaceID, pageNo, entry[8:])
 	}
 
@@ -0,0 +1,151 @@
+package fil
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"sync/atomic"
+
+	"github.com/wilhasse/innodb-go/mach"
+	ibos "github.com/wilhasse/innodb-go/os"
+	"github.com/wilhasse/innodb-go/ut"
+)
+
+// Pages of an encrypted tablespace are sealed by AES-GCM under the key of
+// the tablespace. The page number, previous and next pages, LSN and page
+// type stay readable; the rest of the page is encrypted. The page offset and
+// space id fields carry the random part of the nonce and the flush LSN field
+// and page trailer carry the tag; they are restored from the page position
+// when the page is read. The checksum is computed over the encrypted image,
+// so corruption is reported before decryption is attempted.
+const (
+	encNonceSize = 12
+	encTagSize   = 16
+	encBodyStart = PageData
+	encBodyEnd   = ut.UNIV_PAGE_SIZE - int(PageEndLsnOldChecksum)
+)
+
+// ErrPageDecrypt reports an encrypted page that does not authenticate.
+var ErrPageDecrypt = errors.New("fil: cannot decrypt page")
+
+var (
+	encPagesEncrypted uint64
+	encPagesDecrypted uint64
+)
+
+// SpaceSetEncrypted enables encryption of the pages of a tablespace with
+// the tablespace key, creating the key when the tablespace has none. It is
+// set when the tablespace is created or opened, before any page I/O.
+func SpaceSetEncrypted(id uint32) error {
+	space := SpaceGetByID(id)
+	if space == nil {
+		return errors.New("fil: tablespace not found")
+	}
+	if space.ZipSize != 0 || space.PageCompressed {
+		return errors.New("fil: cannot encrypt a compressed tablespace")
+	}
+	key, err := ibos.EncryptionKey(id, true)
+	if err != nil {
+		return err
+	}
+	aead, err := ibos.EncryptionAEAD(key)
+	if err != nil {
+		return err
+	}
+	sys := ensureSystem()
+	sys.mu.Lock()
+	defer sys.mu.Unlock()
+	space.Encrypted = true
+	space.aead = aead
+	return nil
+}
+
+// EncryptionStats returns the pages encrypted and decrypted so far.
+func EncryptionStats() (uint64, uint64) {
+	return atomic.LoadUint64(&encPagesEncrypted), atomic.LoadUint64(&encPagesDecrypted)
+}
+
+func resetEncryptionStats() {
+	atomic.StoreUint64(&encPagesEncrypted, 0)
+	atomic.StoreUint64(&encPagesDecrypted, 0)
+}
+
+func encPageAAD(space *Space, pageNo uint32, page []byte) []byte {
+	aad := make([]byte, 8, 8+PageFileFlushLSN-PagePrev)
+	binary.BigEndian.PutUint32(aad, space.ID)
+	binary.BigEndian.PutUint32(aad[4:], pageNo)
+	return append(aad, page[PagePrev:PageFileFlushLSN]...)
+}
+
+// encryptPage returns the encrypted image of page pageNo of space. The page
+// is stamped with its LSN first, as for an unencrypted write.
+func encryptPage(space *Space, pageNo uint32, data []byte) ([]byte, error) {
+	applyPageChecksum(data)
+	plain := make([]byte, ut.UNIV_PAGE_SIZE)
+	copy(plain, data[:ut.UNIV_PAGE_SIZE])
+	mach.WriteTo4(plain[PageOffset:], pageNo)
+	mach.WriteTo4(plain[PageArchLogNoOrSpaceID:], space.ID)
+
+	nonce := make([]byte, encNonceSize)
+	binary.BigEndian.PutUint32(nonce, pageNo)
+	if _, err := rand.Read(nonce[4:]); err != nil {
+		return nil, err
+	}
+	sealed := space.aead.Seal(nil, nonce, plain[encBodyStart:encBodyEnd], encPageAAD(space, pageNo, plain))
+	image := make([]byte, ut.UNIV_PAGE_SIZE)
+	copy(image[PagePrev:PageFileFlushLSN], plain[PagePrev:PageFileFlushLSN])
+	copy(image[PageOffset:PageOffset+4], nonce[4:8])
+	copy(image[PageArchLogNoOrSpaceID:PageData], nonce[8:])
+	body := encBodyEnd - int(encBodyStart)
+	copy(image[encBodyStart:encBodyEnd], sealed[:body])
+	tag := sealed[body:]
+	copy(image[PageFileFlushLSN:PageArchLogNoOrSpaceID], tag[:8])
+	copy(image[encBodyEnd:], tag[8:])
+	if checksumsOn() {
+		mach.WriteTo4(image[PageSpaceOrChecksum:], crc32.ChecksumIEEE(image))
+	}
+	atomic.AddUint64(&encPagesEncrypted, 1)
+	return image, nil
+}
+
+// decryptPage verifies the checksum of an encrypted image in buf and
+// decrypts it in place. A page never written reads as zeros.
+func decryptPage(space *Space, pageNo uint32, buf []byte) error {
+	if isZeroPage(buf[:ut.UNIV_PAGE_SIZE]) {
+		return nil
+	}
+	if err := verifyPageChecksum(buf); err != nil {
+		return err
+	}
+	nonce := make([]byte, encNonceSize)
+	binary.BigEndian.PutUint32(nonce, pageNo)
+	copy(nonce[4:8], buf[PageOffset:PageOffset+4])
+	copy(nonce[8:], buf[PageArchLogNoOrSpaceID:PageData])
+	body := encBodyEnd - int(encBodyStart)
+	sealed := make([]byte, 0, body+encTagSize)
+	sealed = append(sealed, buf[encBodyStart:encBodyEnd]...)
+	sealed = append(sealed, buf[PageFileFlushLSN:PageArchLogNoOrSpaceID]...)
+	sealed = append(sealed, buf[encBodyEnd:ut.UNIV_PAGE_SIZE]...)
+	plain, err := space.aead.Open(sealed[:0], nonce, sealed, encPageAAD(space, pageNo, buf))
+	if err != nil {
+		return ErrPageDecrypt
+	}
+	copy(buf[encBodyStart:encBodyEnd], plain)
+	mach.WriteTo4(buf[PageSpaceOrChecksum:], 0)
+	mach.WriteTo4(buf[PageOffset:], pageNo)
+	clear(buf[PageFileFlushLSN:PageArchLogNoOrSpaceID])
+	mach.WriteTo4(buf[PageArchLogNoOrSpaceID:], space.ID)
+	clear(buf[encBodyEnd:ut.UNIV_PAGE_SIZE])
+	atomic.AddUint64(&encPagesDecrypted, 1)
+	return nil
+}
+
+func isZeroPage(page []byte) bool {
+	for _, b := range page {
+		if b != 0 {
+			return false
+		}
+	}
+	return true
+}
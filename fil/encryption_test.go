@@ -0,0 +1,91 @@
+package fil
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	ibos "github.com/wilhasse/innodb-go/os"
+	"github.com/wilhasse/innodb-go/ut"
+)
+
+func TestSpaceEncryption(t *testing.T) {
+	VarInit()
+	SetChecksumsEnabled(true)
+	dir := t.TempDir()
+	t.Cleanup(ibos.EncryptionClose)
+	if err := SpaceSetEncrypted(5); err == nil {
+		t.Fatalf("SpaceSetEncrypted on missing space succeeded")
+	}
+	if !SpaceCreate("enc", 5, 0, SpaceTablespace) {
+		t.Fatalf("SpaceCreate failed")
+	}
+	if err := SpaceSetEncrypted(5); err == nil {
+		t.Fatalf("SpaceSetEncrypted without keyring succeeded")
+	}
+	if err := ibos.EncryptionOpen(filepath.Join(dir, "keyring"), filepath.Join(dir, "info")); err != nil {
+		t.Fatalf("EncryptionOpen: %v", err)
+	}
+	file, err := ibos.FileCreateSimple(filepath.Join(dir, "enc.ibd"), ibos.FileOverwrite, ibos.FileReadWrite)
+	if err != nil {
+		t.Fatalf("FileCreateSimple: %v", err)
+	}
+	defer ibos.FileClose(file)
+	if err := SpaceSetFile(5, file); err != nil {
+		t.Fatalf("SpaceSetFile: %v", err)
+	}
+	if err := SpaceSetEncrypted(5); err != nil {
+		t.Fatalf("SpaceSetEncrypted: %v", err)
+	}
+
+	page := make([]byte, ut.UNIV_PAGE_SIZE)
+	binary.BigEndian.PutUint16(page[PageType:], PageTypeIndex)
+	secret := []byte("top secret row payload")
+	copy(page[200:], secret)
+	if err := SpaceWritePage(5, 3, page); err != nil {
+		t.Fatalf("SpaceWritePage: %v", err)
+	}
+	raw := make([]byte, ut.UNIV_PAGE_SIZE)
+	if _, err := ibos.FileReadPage(file, 3, raw); err != nil {
+		t.Fatalf("FileReadPage: %v", err)
+	}
+	if bytes.Contains(raw, secret) {
+		t.Fatalf("page stored in clear")
+	}
+	if typ := binary.BigEndian.Uint16(raw[PageType:]); typ != PageTypeIndex {
+		t.Fatalf("page type=%d, want index", typ)
+	}
+
+	read, err := SpaceReadPage(5, 3)
+	if err != nil {
+		t.Fatalf("SpaceReadPage: %v", err)
+	}
+	if !bytes.Equal(read[PageData:], page[PageData:]) || !bytes.Equal(read[PageType:PageType+2], page[PageType:PageType+2]) {
+		t.Fatalf("decrypted page differs")
+	}
+	if binary.BigEndian.Uint32(read[PageOffset:]) != 3 || binary.BigEndian.Uint32(read[PageArchLogNoOrSpaceID:]) != 5 {
+		t.Fatalf("page number or space id not restored")
+	}
+	if unwritten, err := SpaceReadPage(5, 1); err != nil || !isZeroPage(unwritten) {
+		t.Fatalf("unwritten page: %v", err)
+	}
+
+	// Corruption of the ciphertext is caught by the checksum; with
+	// checksums off, by the authentication of the page.
+	if _, err := ibos.FileWriteAt(file, []byte{raw[300] ^ 0xFF}, 3*int64(ut.UNIV_PAGE_SIZE)+300); err != nil {
+		t.Fatalf("FileWriteAt: %v", err)
+	}
+	if _, err := SpaceReadPage(5, 3); !errors.Is(err, ErrChecksumMismatch) {
+		t.Fatalf("tampered page read: %v, want checksum mismatch", err)
+	}
+	SetChecksumsEnabled(false)
+	defer SetChecksumsEnabled(true)
+	if _, err := SpaceReadPage(5, 3); !errors.Is(err, ErrPageDecrypt) {
+		t.Fatalf("tampered page read: %v, want decrypt failure", err)
+	}
+	if encrypted, decrypted := EncryptionStats(); encrypted != 1 || decrypted != 1 {
+		t.Fatalf("EncryptionStats=%d,%d, want 1,1", encrypted, decrypted)
+	}
+}
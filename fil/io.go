@@ -57,6 +57,9 @@ func SpaceReadPageInto(spaceID, pageNo uint32, buf []byte) error {
 	if space == nil {
 		return nil
 	}
+	if space.ZipSize != 0 {
+		return SpaceReadPageZip(spaceID, pageNo, buf, make([]byte, space.ZipSize))
+	}
 	node, localPage := nodeForPage(space, pageNo)
 	if node == nil || node.File == nil {
 		if space.File == nil {
@@ -67,7 +70,7 @@ func SpaceReadPageInto(spaceID, pageNo uint32, buf []byte) error {
 			return err
 		}
 		if err == nil {
-			if err := verifyPageChecksum(buf); err != nil {
+			if err := restorePage(space, pageNo, buf); err != nil {
 				return err
 			}
 		}
@@ -79,7 +82,7 @@ func SpaceReadPageInto(spaceID, pageNo uint32, buf []byte) error {
 		return err
 	}
 	if err == nil {
-		if err := verifyPageChecksum(buf); err != nil {
+		if err := restorePage(space, pageNo, buf); err != nil {
 			return err
 		}
 	}
@@ -93,6 +96,9 @@ func SpaceWritePage(spaceID, pageNo uint32, data []byte) error {
 	if space == nil {
 		return nil
 	}
+	if space.ZipSize != 0 {
+		return SpaceWritePageZip(spaceID, pageNo, data, make([]byte, space.ZipSize))
+	}
 	node, localPage := nodeForPage(space, pageNo)
 	if node == nil || node.File == nil {
 		if space.File == nil {
@@ -101,12 +107,16 @@ func SpaceWritePage(spaceID, pageNo uint32, data []byte) error {
 		}
 		atomic.AddUint64(&NPendingTablespaceFlushes, 1)
 		defer atomic.AddUint64(&NPendingTablespaceFlushes, ^uint64(0))
+		image, err := spacePageImage(space, pageNo, data)
+		if err != nil {
+			return err
+		}
 		if space.Purpose != SpaceLog {
-			if err := DoublewriteWrite(spaceID, pageNo, data); err != nil {
+			if err := DoublewriteWrite(spaceID, pageNo, image); err != nil {
 				return err
 			}
 		}
-		if err := WritePage(space.File, pageNo, data); err != nil {
+		if err := writeSpacePage(space, space.File, pageNo, image); err != nil {
 			return err
 		}
 		SpaceEnsureSize(spaceID, uint64(pageNo)+1)
@@ -114,18 +124,43 @@ func SpaceWritePage(spaceID, pageNo uint32, data []byte) error {
 	}
 	atomic.AddUint64(&NPendingTablespaceFlushes, 1)
 	defer atomic.AddUint64(&NPendingTablespaceFlushes, ^uint64(0))
+	image, err := spacePageImage(space, pageNo, data)
+	if err != nil {
+		return err
+	}
 	if space.Purpose != SpaceLog {
-		if err := DoublewriteWrite(spaceID, pageNo, data); err != nil {
+		if err := DoublewriteWrite(spaceID, pageNo, image); err != nil {
 			return err
 		}
 	}
-	if err := WritePage(node.File, localPage, data); err != nil {
+	if err := writeSpacePage(space, node.File, localPage, image); err != nil {
 		return err
 	}
 	SpaceEnsureSize(spaceID, uint64(pageNo)+1)
 	return nil
 }
 
+// spacePageImage returns the bytes written for a page of space: the page
+// itself, or its encrypted image for an encrypted tablespace.
+func spacePageImage(space *Space, pageNo uint32, data []byte) ([]byte, error) {
+	if !space.Encrypted || len(data) < ut.UNIV_PAGE_SIZE {
+		return data, nil
+	}
+	return encryptPage(space, pageNo, data)
+}
+
+// restorePage turns a page read from a file of space back into the page
+// written, verifying its checksum.
+func restorePage(space *Space, pageNo uint32, buf []byte) error {
+	if space.Encrypted {
+		return decryptPage(space, pageNo, buf)
+	}
+	if err := pageDecompress(buf); err != nil {
+		return err
+	}
+	return verifyPageChecksum(buf)
+}
+
 func nodeForPage(space *Space, pageNo uint32) (*Node, uint32) {
 	if space == nil || len(space.Nodes) == 0 {
 		return nil, 0
@@ -0,0 +1,140 @@
+package fil
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"errors"
+	"io"
+	"sync/atomic"
+
+	ibos "github.com/wilhasse/innodb-go/os"
+	"github.com/wilhasse/innodb-go/ut"
+)
+
+// PageTypePageCompressed marks a page written by page compression, as
+// FIL_PAGE_PAGE_COMPRESSED in MariaDB. The file header is kept; the original
+// page type and the length of the deflated rest of the page follow it.
+const PageTypePageCompressed uint16 = 34354
+
+const (
+	pageCompOrigType   = PageData
+	pageCompLen        = PageData + 2
+	pageCompHeaderSize = PageData + 4
+	// pageCompBlockSize is the granularity of the written part of a
+	// compressed page; the tail of the page after it is punched.
+	pageCompBlockSize = 512
+)
+
+var (
+	pageCompSaved        uint64
+	pageCompPages        uint64
+	pageCompDecompressed uint64
+)
+
+// SpaceSetPageCompressed enables page compression for the pages written to
+// a tablespace from now on. Pages already written stay as they are.
+func SpaceSetPageCompressed(id uint32, on bool) bool {
+	sys := ensureSystem()
+	sys.mu.Lock()
+	defer sys.mu.Unlock()
+	space := sys.spacesByID[id]
+	if space == nil {
+		return false
+	}
+	space.PageCompressed = on
+	return true
+}
+
+// PageCompressionStats returns the bytes punched out of tablespace files,
+// the pages written compressed and the compressed pages read so far.
+func PageCompressionStats() (uint64, uint64, uint64) {
+	return atomic.LoadUint64(&pageCompSaved), atomic.LoadUint64(&pageCompPages),
+		atomic.LoadUint64(&pageCompDecompressed)
+}
+
+func resetPageCompressionStats() {
+	atomic.StoreUint64(&pageCompSaved, 0)
+	atomic.StoreUint64(&pageCompPages, 0)
+	atomic.StoreUint64(&pageCompDecompressed, 0)
+}
+
+// pageCompress returns the page compressed image of data, padded with zeros
+// to a full page, and the length of its used part. It returns nil when the
+// page would not save a block.
+func pageCompress(data []byte) ([]byte, int) {
+	var body bytes.Buffer
+	w, err := flate.NewWriter(&body, flate.DefaultCompression)
+	if err != nil {
+		return nil, 0
+	}
+	if _, err := w.Write(data[PageData:ut.UNIV_PAGE_SIZE]); err != nil {
+		return nil, 0
+	}
+	if err := w.Close(); err != nil {
+		return nil, 0
+	}
+	used := int(pageCompHeaderSize) + body.Len()
+	used = (used + pageCompBlockSize - 1) / pageCompBlockSize * pageCompBlockSize
+	if used >= ut.UNIV_PAGE_SIZE {
+		return nil, 0
+	}
+	out := make([]byte, ut.UNIV_PAGE_SIZE)
+	copy(out, data[:PageData])
+	binary.BigEndian.PutUint16(out[PageType:], PageTypePageCompressed)
+	copy(out[pageCompOrigType:], data[PageType:PageType+2])
+	binary.BigEndian.PutUint16(out[pageCompLen:], uint16(body.Len()))
+	copy(out[pageCompHeaderSize:], body.Bytes())
+	return out, used
+}
+
+// pageDecompress restores in place a page read from disk when it carries
+// the page compression marker. Other pages are left untouched.
+func pageDecompress(buf []byte) error {
+	if binary.BigEndian.Uint16(buf[PageType:]) != PageTypePageCompressed {
+		return nil
+	}
+	bodyLen := int(binary.BigEndian.Uint16(buf[pageCompLen:]))
+	if int(pageCompHeaderSize)+bodyLen > ut.UNIV_PAGE_SIZE {
+		return errors.New("fil: corrupt compressed page")
+	}
+	r := flate.NewReader(bytes.NewReader(buf[pageCompHeaderSize : int(pageCompHeaderSize)+bodyLen]))
+	defer r.Close()
+	body := make([]byte, ut.UNIV_PAGE_SIZE-int(PageData))
+	if _, err := io.ReadFull(r, body); err != nil {
+		return errors.New("fil: corrupt compressed page")
+	}
+	copy(buf[PageType:], buf[pageCompOrigType:pageCompOrigType+2])
+	copy(buf[PageData:], body)
+	atomic.AddUint64(&pageCompDecompressed, 1)
+	return nil
+}
+
+// writeSpacePage writes a page of space to file. With page compression on,
+// the page is written compressed when that saves a block and the unused
+// tail is punched, leaving a hole in the file. The encrypted image of a
+// page of an encrypted tablespace is written as is.
+func writeSpacePage(space *Space, file ibos.File, pageNo uint32, data []byte) error {
+	if space.Encrypted && len(data) >= ut.UNIV_PAGE_SIZE {
+		_, err := ibos.FileWritePage(file, pageNo, data)
+		return err
+	}
+	if !space.PageCompressed || space.Purpose == SpaceLog || len(data) < ut.UNIV_PAGE_SIZE {
+		return WritePage(file, pageNo, data)
+	}
+	applyPageChecksum(data)
+	image, used := pageCompress(data)
+	if image == nil {
+		return WritePage(file, pageNo, data)
+	}
+	offset := int64(pageNo) * int64(ut.UNIV_PAGE_SIZE)
+	if _, err := ibos.FileWriteAt(file, image, offset); err != nil {
+		return err
+	}
+	atomic.AddUint64(&pageCompPages, 1)
+	hole := int64(ut.UNIV_PAGE_SIZE - used)
+	if err := ibos.FilePunchHole(file, offset+int64(used), hole); err == nil {
+		atomic.AddUint64(&pageCompSaved, uint64(hole))
+	}
+	return nil
+}
@@ -26,7 +26,11 @@ func SpaceSetFile(id uint32, file ibos.File) error {
 	if len(space.Nodes) == 0 {
 		sizePages := uint64(0)
 		if size, err := ibos.FileSize(file); err == nil && size > 0 {
-			sizePages = uint64(size / int64(ut.UNIV_PAGE_SIZE))
+			pageSize := int64(ut.UNIV_PAGE_SIZE)
+			if space.ZipSize != 0 {
+				pageSize = int64(space.ZipSize)
+			}
+			sizePages = uint64(size / pageSize)
 		}
 		node := &Node{
 			Space: space,
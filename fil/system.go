@@ -1,6 +1,7 @@
 package fil
 
 import (
+	"crypto/cipher"
 	"errors"
 	"sync"
 
@@ -42,10 +43,13 @@ type Space struct {
 	Size           uint64
 	Flags          uint32
 	ZipSize        uint32
+	PageCompressed bool
+	Encrypted      bool
 	Autoextend     bool
 	AutoextendInc  uint64
 	Nodes          []*Node
 	File           ibos.File
+	aead           cipher.AEAD
 }
 
 // System holds the tablespace cache.
@@ -77,8 +81,10 @@ func VarInit() {
 	NLogFlushes = 0
 	NPendingLogFlushes = 0
 	NPendingTablespaceFlushes = 0
-	externReset()
 	resetDoublewriteState()
+	resetZipStats()
+	resetPageCompressionStats()
+	resetEncryptionStats()
 }
 
 // SpaceCreate registers a tablespace or log space.
@@ -0,0 +1,214 @@
+package fil
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"sync/atomic"
+
+	iblog "github.com/wilhasse/innodb-go/log"
+	ibos "github.com/wilhasse/innodb-go/os"
+	"github.com/wilhasse/innodb-go/ut"
+)
+
+// A compressed tablespace (ZipSize != 0) stores page pageNo as a frame of
+// ZipSize bytes at offset pageNo*ZipSize. A frame holds the file header of
+// the page as is, the length of the compressed body and the rest of the page
+// compressed by the zip codec, zero padded. An all-zero frame reads as a
+// zero page.
+const (
+	zipFrameLen    = PageData
+	zipFrameHeader = PageData + 2
+)
+
+// zipFitSlack is the room PageZipFits keeps free in a frame, so that the
+// checksum and LSN written at flush time cannot make a page overflow.
+const zipFitSlack = 64
+
+// ErrZipOverflow reports a page that does not compress into its zip size.
+var ErrZipOverflow = errors.New("fil: page does not fit the compressed page size")
+
+// ZipCodec compresses the page bodies of compressed tablespaces. Package
+// page installs page.ZipCompress and page.ZipDecompress.
+type ZipCodec struct {
+	Compress   func(data []byte) ([]byte, error)
+	Decompress func(data []byte, size int) ([]byte, error)
+}
+
+var (
+	zipCodec            ZipCodec
+	zipCompressOps      uint64
+	zipCompressFailures uint64
+	zipDecompressOps    uint64
+)
+
+// SetZipCodec installs the codec used for compressed tablespaces.
+func SetZipCodec(codec ZipCodec) {
+	zipCodec = codec
+}
+
+// ZipStats returns the page compressions, the compressions that did not fit
+// the zip size and the page decompressions done so far.
+func ZipStats() (uint64, uint64, uint64) {
+	return atomic.LoadUint64(&zipCompressOps), atomic.LoadUint64(&zipCompressFailures),
+		atomic.LoadUint64(&zipDecompressOps)
+}
+
+func resetZipStats() {
+	atomic.StoreUint64(&zipCompressOps, 0)
+	atomic.StoreUint64(&zipCompressFailures, 0)
+	atomic.StoreUint64(&zipDecompressOps, 0)
+}
+
+// SpaceZipSize returns the compressed page size of a tablespace, or 0 when
+// its pages are stored uncompressed.
+func SpaceZipSize(id uint32) int {
+	space := SpaceGetByID(id)
+	if space == nil {
+		return 0
+	}
+	return int(space.ZipSize)
+}
+
+// SpacePhysicalPageSize returns the bytes a page takes in the tablespace
+// files.
+func SpacePhysicalPageSize(id uint32) int {
+	if zipSize := SpaceZipSize(id); zipSize > 0 {
+		return zipSize
+	}
+	return ut.UNIV_PAGE_SIZE
+}
+
+// PageZipCompress compresses page into frame, whose length is the zip size.
+// It returns ErrZipOverflow when the page does not fit.
+func PageZipCompress(page, frame []byte) error {
+	if len(page) < ut.UNIV_PAGE_SIZE || len(frame) <= int(zipFrameHeader) {
+		return errors.New("fil: invalid zip frame")
+	}
+	if zipCodec.Compress == nil {
+		return errors.New("fil: no zip codec")
+	}
+	atomic.AddUint64(&zipCompressOps, 1)
+	body, err := zipCodec.Compress(page[PageData:ut.UNIV_PAGE_SIZE])
+	if err != nil {
+		atomic.AddUint64(&zipCompressFailures, 1)
+		return err
+	}
+	if int(zipFrameHeader)+len(body) > len(frame) {
+		atomic.AddUint64(&zipCompressFailures, 1)
+		return ErrZipOverflow
+	}
+	copy(frame, page[:PageData])
+	binary.BigEndian.PutUint16(frame[zipFrameLen:], uint16(len(body)))
+	n := copy(frame[zipFrameHeader:], body)
+	clear(frame[int(zipFrameHeader)+n:])
+	return nil
+}
+
+// PageZipDecompress restores into page the page compressed in frame.
+func PageZipDecompress(frame, page []byte) error {
+	if len(page) < ut.UNIV_PAGE_SIZE || len(frame) <= int(zipFrameHeader) {
+		return errors.New("fil: invalid zip frame")
+	}
+	clear(page[:ut.UNIV_PAGE_SIZE])
+	bodyLen := int(binary.BigEndian.Uint16(frame[zipFrameLen:]))
+	if bodyLen == 0 {
+		return nil
+	}
+	if int(zipFrameHeader)+bodyLen > len(frame) {
+		return errors.New("fil: corrupt zip frame")
+	}
+	if zipCodec.Decompress == nil {
+		return errors.New("fil: no zip codec")
+	}
+	atomic.AddUint64(&zipDecompressOps, 1)
+	body, err := zipCodec.Decompress(frame[zipFrameHeader:int(zipFrameHeader)+bodyLen], ut.UNIV_PAGE_SIZE-int(PageData))
+	if err != nil {
+		return err
+	}
+	copy(page, frame[:PageData])
+	copy(page[PageData:], body)
+	return nil
+}
+
+// PageZipFits reports whether page compresses into zipSize bytes with
+// room to spare for the changes made when it is flushed.
+func PageZipFits(page []byte, zipSize int) bool {
+	if zipSize <= 0 {
+		return true
+	}
+	frame := make([]byte, zipSize-zipFitSlack)
+	return PageZipCompress(page, frame) == nil
+}
+
+// SpaceReadPageZip reads the frame of a page of a compressed tablespace into
+// frame and decompresses it into buf. The page checksum is verified and
+// pending redo applied as for SpaceReadPageInto.
+func SpaceReadPageZip(spaceID, pageNo uint32, buf, frame []byte) error {
+	space := SpaceGetByID(spaceID)
+	if space == nil || space.ZipSize == 0 {
+		return errors.New("fil: not a compressed tablespace")
+	}
+	if len(frame) < int(space.ZipSize) {
+		return errors.New("fil: zip frame too small")
+	}
+	frame = frame[:space.ZipSize]
+	clear(frame)
+	if file, localPage := zipFile(space, pageNo); file != nil {
+		_, err := ibos.FileReadAt(file, frame, int64(localPage)*int64(space.ZipSize))
+		if err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+	}
+	if err := PageZipDecompress(frame, buf); err != nil {
+		return err
+	}
+	if err := verifyPageChecksum(buf); err != nil {
+		return err
+	}
+	iblog.RecvRecoverPage(spaceID, pageNo, buf)
+	return nil
+}
+
+// SpaceWritePageZip compresses data into frame and writes the frame to the
+// compressed tablespace. It returns ErrZipOverflow, writing nothing, when
+// the page does not fit the zip size.
+func SpaceWritePageZip(spaceID, pageNo uint32, data, frame []byte) error {
+	space := SpaceGetByID(spaceID)
+	if space == nil || space.ZipSize == 0 {
+		return errors.New("fil: not a compressed tablespace")
+	}
+	if len(data) < ut.UNIV_PAGE_SIZE {
+		return errors.New("fil: page buffer too small")
+	}
+	if len(frame) < int(space.ZipSize) {
+		return errors.New("fil: zip frame too small")
+	}
+	frame = frame[:space.ZipSize]
+	applyPageChecksum(data)
+	if err := PageZipCompress(data, frame); err != nil {
+		return err
+	}
+	file, localPage := zipFile(space, pageNo)
+	if file == nil {
+		SpaceEnsureSize(spaceID, uint64(pageNo)+1)
+		return nil
+	}
+	atomic.AddUint64(&NPendingTablespaceFlushes, 1)
+	defer atomic.AddUint64(&NPendingTablespaceFlushes, ^uint64(0))
+	if err := DoublewriteWrite(spaceID, pageNo, data); err != nil {
+		return err
+	}
+	if _, err := ibos.FileWriteAt(file, frame, int64(localPage)*int64(space.ZipSize)); err != nil {
+		return err
+	}
+	SpaceEnsureSize(spaceID, uint64(pageNo)+1)
+	return nil
+}
+
+func zipFile(space *Space, pageNo uint32) (ibos.File, uint32) {
+	if node, localPage := nodeForPage(space, pageNo); node != nil && node.File != nil {
+		return node.File, localPage
+	}
+	return space.File, pageNo
+}
@@ -0,0 +1,97 @@
+package fil
+
+import (
+	"bytes"
+	"compress/zlib"
+	"crypto/rand"
+	"errors"
+	"io"
+	"path/filepath"
+	"testing"
+
+	ibos "github.com/wilhasse/innodb-go/os"
+	"github.com/wilhasse/innodb-go/ut"
+)
+
+func setTestZipCodec(t *testing.T) {
+	t.Helper()
+	old := zipCodec
+	t.Cleanup(func() { zipCodec = old })
+	SetZipCodec(ZipCodec{
+		Compress: func(data []byte) ([]byte, error) {
+			var out bytes.Buffer
+			w := zlib.NewWriter(&out)
+			if _, err := w.Write(data); err != nil {
+				return nil, err
+			}
+			if err := w.Close(); err != nil {
+				return nil, err
+			}
+			return out.Bytes(), nil
+		},
+		Decompress: func(data []byte, size int) ([]byte, error) {
+			r, err := zlib.NewReader(bytes.NewReader(data))
+			if err != nil {
+				return nil, err
+			}
+			defer r.Close()
+			return io.ReadAll(r)
+		},
+	})
+}
+
+func TestSpaceZipReadWrite(t *testing.T) {
+	VarInit()
+	setTestZipCodec(t)
+
+	file, err := ibos.FileCreateSimple(filepath.Join(t.TempDir(), "zip.ibd"), ibos.FileOverwrite, ibos.FileReadWrite)
+	if err != nil {
+		t.Fatalf("FileCreateSimple: %v", err)
+	}
+	defer ibos.FileClose(file)
+	if !SpaceCreate("zip", 3, 4096, SpaceTablespace) {
+		t.Fatalf("SpaceCreate failed")
+	}
+	if err := SpaceSetFile(3, file); err != nil {
+		t.Fatalf("SpaceSetFile: %v", err)
+	}
+	if got := SpacePhysicalPageSize(3); got != 4096 {
+		t.Fatalf("physical page size=%d, want 4096", got)
+	}
+
+	page := make([]byte, ut.UNIV_PAGE_SIZE)
+	for i := int(PageData); i < len(page); i++ {
+		page[i] = byte(i / 64)
+	}
+	if err := SpaceWritePage(3, 2, page); err != nil {
+		t.Fatalf("SpaceWritePage: %v", err)
+	}
+	if size, err := ibos.FileSize(file); err != nil || size != 3*4096 {
+		t.Fatalf("file size=%d err=%v, want %d", size, err, 3*4096)
+	}
+	got, err := SpaceReadPage(3, 2)
+	if err != nil {
+		t.Fatalf("SpaceReadPage: %v", err)
+	}
+	if !bytes.Equal(got[PageData:], page[PageData:]) {
+		t.Fatalf("page mismatch after decompression")
+	}
+	if got, err := SpaceReadPage(3, 1); err != nil || !bytes.Equal(got, make([]byte, ut.UNIV_PAGE_SIZE)) {
+		t.Fatalf("unwritten page: err=%v", err)
+	}
+
+	random := make([]byte, ut.UNIV_PAGE_SIZE)
+	if _, err := rand.Read(random); err != nil {
+		t.Fatalf("rand: %v", err)
+	}
+	if PageZipFits(random, 4096) {
+		t.Fatalf("random page fits 4K")
+	}
+	if err := SpaceWritePage(3, 0, random); !errors.Is(err, ErrZipOverflow) {
+		t.Fatalf("SpaceWritePage random: %v, want ErrZipOverflow", err)
+	}
+	ops, failures, decompress := ZipStats()
+	if ops != 3 || failures != 2 || decompress != 1 {
+		t.Fatalf("stats ops=%d failures=%d decompress=%d", ops, failures, decompress)
+	}
+}
@@ -89,6 +89,27 @@ func FreePage(spaceID, pageNo uint32) {
 	}
 }
 
+// MarkPageUsed records a page as allocated without handing it out, so that
+// AllocPage skips pages found in use when a tablespace is opened again.
+func MarkPageUsed(spaceID, pageNo uint32) {
+	allocMu.Lock()
+	defer allocMu.Unlock()
+
+	alloc := ensureAlloc(spaceID)
+	extentIdx := pageNo / uint32(ExtentSize)
+	if extentIdx >= alloc.extentCount {
+		alloc.extentCount = extentIdx + 1
+	}
+	ext := alloc.extents[extentIdx]
+	if ext == nil {
+		ext = newExtent()
+		alloc.extents[extentIdx] = ext
+	}
+	if extentMark(ext, pageNo%uint32(ExtentSize), true) && spaceID == 0 {
+		_ = persistExtentMap(spaceID, alloc)
+	}
+}
+
 func ensureAlloc(spaceID uint32) *spaceAlloc {
 	alloc := allocs[spaceID]
 	if alloc == nil {
@@ -119,11 +140,11 @@ func ensureSpaceSize(spaceID uint32, minPages uint32) bool {
 		if last != nil {
 			last.Size += uint64(inc)
 			if last.File != nil {
-				_ = ensureFileSize(last.File, uint64(last.Size)*ut.UNIV_PAGE_SIZE)
+				_ = ensureFileSize(last.File, uint64(last.Size)*uint64(fil.SpacePhysicalPageSize(spaceID)))
 			}
 		}
 	} else if space.File != nil {
-		_ = ensureFileSize(space.File, uint64(space.Size)*ut.UNIV_PAGE_SIZE)
+		_ = ensureFileSize(space.File, uint64(space.Size)*uint64(fil.SpacePhysicalPageSize(spaceID)))
 	}
 	return true
 }
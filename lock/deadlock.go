@@ -21,6 +21,22 @@ func (sys *LockSys) clearWaitEdges(waiter *trx.Trx) {
 	delete(sys.waitFor, waiter)
 }
 
+// clearQueueEdges drops the wait edges from the waiters in queue to blocker
+// once blocker has released its locks in the queue.
+func (sys *LockSys) clearQueueEdges(queue *Queue, blocker *trx.Trx) {
+	if sys == nil || queue == nil || blocker == nil {
+		return
+	}
+	for lock := queue.First; lock != nil; lock = lock.Next {
+		if lock.Flags&FlagWait == 0 {
+			continue
+		}
+		if edges := sys.waitFor[lock.Trx]; edges != nil {
+			delete(edges, blocker)
+		}
+	}
+}
+
 func (sys *LockSys) deadlock(waiter *trx.Trx) bool {
 	if sys == nil || waiter == nil {
 		return false
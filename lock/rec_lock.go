@@ -71,14 +71,7 @@ func (sys *LockSys) LockRecWithFlags(tr *trx.Trx, record RecordKey, mode Mode, f
 				return ownWaiting, LockGranted
 			}
 			if ownGranted != nil {
-				ownGranted.Flags = flags
 				ownGranted.SetBit(heapNo)
-				if ModeStrongerOrEq(ownGranted.Mode, mode) {
-					sys.clearWaitEdges(tr)
-					sys.mu.Unlock()
-					return ownGranted, LockGranted
-				}
-				ownGranted.Mode = mode
 				sys.clearWaitEdges(tr)
 				sys.mu.Unlock()
 				return ownGranted, LockGranted
@@ -186,7 +179,9 @@ func (sys *LockSys) recordBlockers(queue *Queue, tr *trx.Trx, heapNo int, mode M
 				if ownWaiting == nil {
 					ownWaiting = lock
 				}
-			} else if ownGranted == nil {
+			} else if ownGranted == nil && lock.Mode == mode && lock.Flags == flags {
+				// Locks of another mode or gap type stay separate so that
+				// earlier next-key and gap locks keep their coverage.
 				ownGranted = lock
 			}
 			continue
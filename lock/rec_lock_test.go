@@ -131,3 +131,29 @@ func waitStatus(t *testing.T, ch <-chan Status, timeout time.Duration) Status {
 		return LockWaitTimeout
 	}
 }
+
+func TestLockRecKeepsGapLocksSeparate(t *testing.T) {
+	sys := NewLockSys()
+	prev := waitTimeout()
+	SetWaitTimeout(50 * time.Millisecond)
+	defer SetWaitTimeout(prev)
+	trx1 := &trx.Trx{}
+	trx2 := &trx.Trx{}
+	rec1 := RecordKey{Table: "t1", PageNo: 1, HeapNo: 10}
+	rec2 := RecordKey{Table: "t1", PageNo: 1, HeapNo: 11}
+
+	nextKey, status := sys.LockRecWithFlags(trx1, rec1, ModeS, FlagNextKey)
+	if status != LockGranted {
+		t.Fatalf("expected next-key lock granted")
+	}
+	insert, status := sys.LockRecWithFlags(trx1, rec2, ModeX, FlagInsertIntention)
+	if status != LockGranted {
+		t.Fatalf("expected insert intention granted")
+	}
+	if insert == nextKey || nextKey.Flags != FlagNextKey {
+		t.Fatalf("insert intention replaced next-key lock")
+	}
+	if _, status := sys.LockRecWithFlags(trx2, rec1, ModeX, FlagInsertIntention); status != LockWaitTimeout {
+		t.Fatalf("insert into locked gap=%v, want LockWaitTimeout", status)
+	}
+}
@@ -24,6 +24,16 @@ func UnlockTable(tr *trx.Trx, table string) {
 	sys.UnlockTable(tr, table)
 }
 
+// TableLocked reports whether the transaction holds a granted table lock in
+// the global lock system.
+func TableLocked(tr *trx.Trx, table string) bool {
+	sys := Sys()
+	if sys == nil {
+		return false
+	}
+	return sys.TableLocked(tr, table)
+}
+
 // LockTable acquires a table lock in a lock system.
 func (sys *LockSys) LockTable(tr *trx.Trx, table string, mode Mode) (*Lock, Status) {
 	if sys == nil {
@@ -142,16 +152,38 @@ func (sys *LockSys) UnlockTable(tr *trx.Trx, table string) {
 		}
 		lock = next
 	}
+	sys.clearQueueEdges(queue, tr)
 	sys.signalWaiters(queue)
 	if queue.First == nil {
 		delete(sys.tableHash, table)
 	}
 }
 
+// TableLocked reports whether the transaction holds a granted lock on the
+// table.
+func (sys *LockSys) TableLocked(tr *trx.Trx, table string) bool {
+	if sys == nil {
+		return false
+	}
+	sys.mu.Lock()
+	defer sys.mu.Unlock()
+	queue := sys.tableHash[table]
+	if queue == nil {
+		return false
+	}
+	for lock := queue.First; lock != nil; lock = lock.Next {
+		if lock.Trx == tr && lock.Flags&FlagWait == 0 {
+			return true
+		}
+	}
+	return false
+}
+
 func (sys *LockSys) tableBlockers(queue *Queue, tr *trx.Trx, mode Mode) (*Lock, *Lock, []*trx.Trx) {
 	var ownGranted *Lock
 	var ownWaiting *Lock
 	var blockers []*trx.Trx
+	var queued []*trx.Trx
 	for lock := queue.First; lock != nil; lock = lock.Next {
 		if lock.Trx == tr {
 			if lock.Flags&FlagWait != 0 {
@@ -163,12 +195,20 @@ func (sys *LockSys) tableBlockers(queue *Queue, tr *trx.Trx, mode Mode) (*Lock,
 			}
 			continue
 		}
-		if lock.Flags&FlagWait != 0 {
+		if ModeCompatible(mode, lock.Mode) || lock.Trx == nil {
 			continue
 		}
-		if !ModeCompatible(mode, lock.Mode) && lock.Trx != nil {
+		if lock.Flags&FlagWait == 0 {
 			blockers = append(blockers, lock.Trx)
+		} else if ownWaiting == nil {
+			queued = append(queued, lock.Trx)
 		}
 	}
+	// A new request also queues behind incompatible requests already waiting,
+	// so a stream of compatible locks cannot starve them. A transaction that
+	// holds a lock on the table is not made to wait behind the queue.
+	if ownGranted == nil {
+		blockers = append(blockers, queued...)
+	}
 	return ownGranted, ownWaiting, blockers
 }
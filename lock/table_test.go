@@ -63,6 +63,28 @@ func TestLockTableUpgrade(t *testing.T) {
 	}
 }
 
+func TestLockTableLocked(t *testing.T) {
+	sys := NewLockSys()
+	trx1 := &trx.Trx{}
+	trx2 := &trx.Trx{}
+	if sys.TableLocked(trx1, "t1") {
+		t.Fatalf("expected no table lock before locking")
+	}
+	if _, status := sys.LockTable(trx1, "t1", ModeIX); status != LockGranted {
+		t.Fatalf("expected lock granted")
+	}
+	if !sys.TableLocked(trx1, "t1") {
+		t.Fatalf("expected table lock held")
+	}
+	if sys.TableLocked(trx2, "t1") || sys.TableLocked(trx1, "t2") {
+		t.Fatalf("expected lock scoped to trx and table")
+	}
+	sys.UnlockTable(trx1, "t1")
+	if sys.TableLocked(trx1, "t1") {
+		t.Fatalf("expected table lock released")
+	}
+}
+
 func TestLockTableTimeout(t *testing.T) {
 	sys := NewLockSys()
 	prev := waitTimeout()
@@ -78,3 +100,73 @@ func TestLockTableTimeout(t *testing.T) {
 		t.Fatalf("expected lock wait timeout, got %v", status)
 	}
 }
+
+func TestLockTableQueuesBehindWaiter(t *testing.T) {
+	sys := NewLockSys()
+	prev := waitTimeout()
+	SetWaitTimeout(time.Second)
+	defer SetWaitTimeout(prev)
+	trx1 := &trx.Trx{}
+	trx2 := &trx.Trx{}
+	trx3 := &trx.Trx{}
+	if _, status := sys.LockTable(trx1, "t1", ModeIX); status != LockGranted {
+		t.Fatalf("expected first lock granted")
+	}
+	waitS := make(chan Status, 1)
+	go func() {
+		_, status := sys.LockTable(trx2, "t1", ModeS)
+		waitS <- status
+	}()
+	time.Sleep(20 * time.Millisecond)
+	if _, status := sys.LockTable(trx1, "t1", ModeIX); status != LockGranted {
+		t.Fatalf("expected holder to keep its lock, got %v", status)
+	}
+	waitIX := make(chan Status, 1)
+	go func() {
+		_, status := sys.LockTable(trx3, "t1", ModeIX)
+		waitIX <- status
+	}()
+	time.Sleep(20 * time.Millisecond)
+	select {
+	case status := <-waitIX:
+		t.Fatalf("expected IX to queue behind waiting S, got %v", status)
+	default:
+	}
+	sys.UnlockTable(trx1, "t1")
+	if status := waitStatus(t, waitS, time.Second); status != LockGranted {
+		t.Fatalf("expected S granted, got %v", status)
+	}
+	sys.UnlockTable(trx2, "t1")
+	if status := waitStatus(t, waitIX, time.Second); status != LockGranted {
+		t.Fatalf("expected IX granted, got %v", status)
+	}
+}
+
+func TestLockTableUnlockClearsWaitEdges(t *testing.T) {
+	sys := NewLockSys()
+	prev := waitTimeout()
+	SetWaitTimeout(time.Second)
+	defer SetWaitTimeout(prev)
+	trx1 := &trx.Trx{}
+	trx2 := &trx.Trx{}
+	if _, status := sys.LockTable(trx1, "t1", ModeS); status != LockGranted {
+		t.Fatalf("expected first lock granted")
+	}
+	done := make(chan Status, 1)
+	go func() {
+		_, status := sys.LockTable(trx2, "t1", ModeIX)
+		if status == LockGranted {
+			time.Sleep(20 * time.Millisecond)
+			sys.UnlockTable(trx2, "t1")
+		}
+		done <- status
+	}()
+	time.Sleep(20 * time.Millisecond)
+	sys.UnlockTable(trx1, "t1")
+	if _, status := sys.LockTable(trx1, "t1", ModeX); status != LockGranted {
+		t.Fatalf("expected X granted after waiter, got %v", status)
+	}
+	if status := waitStatus(t, done, time.Second); status != LockGranted {
+		t.Fatalf("expected IX granted, got %v", status)
+	}
+}
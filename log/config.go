@@ -11,6 +11,9 @@ type Config struct {
 	Files       int
 	BufferSize  uint64
 	Preallocate bool
+	// Encrypt stores new log files in encrypted blocks, keyed by the
+	// redo key of the open keyring.
+	Encrypt bool
 }
 
 var (
@@ -0,0 +1,122 @@
+package log
+
+import (
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"io"
+
+	ibos "github.com/wilhasse/innodb-go/os"
+)
+
+// An encrypted log file stores the log bytes in blocks of logEncBlockSize
+// bytes after the header: a random nonce, logEncPayload bytes of log sealed
+// by AES-GCM and the tag. The block number is authenticated with the block,
+// so blocks cannot be reordered. An all-zero block has not been written.
+const (
+	logEncBlockSize = 512
+	logEncNonceSize = 12
+	logEncTagSize   = 16
+	logEncPayload   = logEncBlockSize - logEncNonceSize - logEncTagSize
+)
+
+// ErrLogDecrypt reports an encrypted log block that does not authenticate.
+var ErrLogDecrypt = errors.New("log: cannot decrypt log block")
+
+func (l *Log) initEncryption() error {
+	if l.header.Flags&logFlagEncrypted == 0 {
+		return nil
+	}
+	key, err := ibos.EncryptionKey(ibos.EncryptionRedoKeyID, true)
+	if err != nil {
+		return err
+	}
+	aead, err := ibos.EncryptionAEAD(key)
+	if err != nil {
+		return err
+	}
+	l.aead = aead
+	return nil
+}
+
+func logEncBlockOffset(block uint64) int64 {
+	return int64(logHeaderSize) + int64(block)*logEncBlockSize
+}
+
+func logEncAAD(block uint64) []byte {
+	return binary.BigEndian.AppendUint64(nil, block)
+}
+
+// readEncBlock returns the decrypted payload of a block, zeros when the
+// block has not been written yet.
+func readEncBlock(aead cipher.AEAD, file ibos.File, block uint64) ([]byte, error) {
+	raw := make([]byte, logEncBlockSize)
+	n, err := ibos.FileReadAt(file, raw, logEncBlockOffset(block))
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	if n < logEncBlockSize || isZero(raw) {
+		return make([]byte, logEncPayload), nil
+	}
+	payload, err := aead.Open(nil, raw[:logEncNonceSize], raw[logEncNonceSize:], logEncAAD(block))
+	if err != nil {
+		return nil, ErrLogDecrypt
+	}
+	return payload, nil
+}
+
+func writeEncBlock(aead cipher.AEAD, file ibos.File, block uint64, payload []byte) error {
+	raw := make([]byte, logEncNonceSize, logEncBlockSize)
+	if _, err := rand.Read(raw); err != nil {
+		return err
+	}
+	raw = aead.Seal(raw, raw[:logEncNonceSize], payload, logEncAAD(block))
+	_, err := ibos.FileWriteAt(file, raw, logEncBlockOffset(block))
+	return err
+}
+
+// writeEncrypted stores data at log position pos, rewriting every block it
+// touches.
+func (l *Log) writeEncrypted(pos uint64, data []byte) (int64, error) {
+	for len(data) > 0 {
+		block := pos / logEncPayload
+		off := int(pos % logEncPayload)
+		payload, err := readEncBlock(l.aead, l.file, block)
+		if err != nil {
+			return 0, err
+		}
+		n := copy(payload[off:], data)
+		if err := writeEncBlock(l.aead, l.file, block, payload); err != nil {
+			return 0, err
+		}
+		data = data[n:]
+		pos += uint64(n)
+	}
+	return logEncBlockOffset((pos + logEncPayload - 1) / logEncPayload), nil
+}
+
+// readEncrypted reads len(buf) log bytes at log position pos.
+func readEncrypted(aead cipher.AEAD, file ibos.File, pos uint64, buf []byte) error {
+	for len(buf) > 0 {
+		block := pos / logEncPayload
+		off := int(pos % logEncPayload)
+		payload, err := readEncBlock(aead, file, block)
+		if err != nil {
+			return err
+		}
+		n := copy(buf, payload[off:])
+		buf = buf[n:]
+		pos += uint64(n)
+	}
+	return nil
+}
+
+func isZero(buf []byte) bool {
+	for _, b := range buf {
+		if b != 0 {
+			return false
+		}
+	}
+	return true
+}
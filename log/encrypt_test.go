@@ -0,0 +1,96 @@
+package log
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+
+	ibos "github.com/wilhasse/innodb-go/os"
+)
+
+func TestEncryptedRedoScan(t *testing.T) {
+	dir := t.TempDir()
+	t.Cleanup(func() {
+		if System != nil && System.file != nil {
+			_ = ibos.FileClose(System.file)
+		}
+		config = Config{}
+		configSet = false
+		System = nil
+		ibos.EncryptionClose()
+	})
+	cfg := Config{
+		Enabled:  true,
+		DataDir:  dir,
+		FileSize: 1 << 20,
+		Encrypt:  true,
+	}
+	Configure(cfg)
+	Init()
+	if InitErr() == nil {
+		t.Fatalf("encrypted log opened without a keyring")
+	}
+	if err := ibos.EncryptionOpen(filepath.Join(dir, "keyring"), filepath.Join(dir, "info")); err != nil {
+		t.Fatalf("EncryptionOpen: %v", err)
+	}
+	Init()
+	if err := InitErr(); err != nil {
+		t.Fatalf("InitErr: %v", err)
+	}
+
+	// Enough records to span several encrypted blocks, some written
+	// across a block boundary.
+	const records = 40
+	secret := []byte("redo-secret")
+	for i := 0; i < records; i++ {
+		ReserveAndWriteFast(buildMlogStringRecord(10, uint32(i), 4, secret))
+	}
+	end := System.lsn
+	FlushUpTo(end)
+	if end <= logEncPayload {
+		t.Fatalf("records span %d bytes, want more than a block", end)
+	}
+	if System.file != nil {
+		_ = ibos.FileClose(System.file)
+	}
+	System = nil
+
+	file, err := ibos.FileCreateSimple(logFilePath(dir, 0), ibos.FileOpen, ibos.FileReadWrite)
+	if err != nil {
+		t.Fatalf("open log: %v", err)
+	}
+	raw := make([]byte, logEncBlockOffset(end/logEncPayload+1))
+	_, _ = ibos.FileReadAt(file, raw, 0)
+	_ = ibos.FileClose(file)
+	if bytes.Contains(raw, secret) {
+		t.Fatalf("redo stored in clear")
+	}
+
+	Init()
+	if err := InitErr(); err != nil {
+		t.Fatalf("InitErr after restart: %v", err)
+	}
+	RecvSysVarInit()
+	RecvSysCreate()
+	RecvSysInit(0)
+	contiguous, _, err := RecvScanLogFile(System.file, 0, end)
+	if err != nil {
+		t.Fatalf("RecvScanLogFile: %v", err)
+	}
+	if contiguous != end || RecvSysState.NAddrs != records {
+		t.Fatalf("contiguous=%d addrs=%d, want %d and %d", contiguous, RecvSysState.NAddrs, end, records)
+	}
+
+	// After a clean shutdown the log is recreated in the clear at the
+	// same LSN.
+	Shutdown()
+	cfg.Encrypt = false
+	Configure(cfg)
+	Init()
+	if err := InitErr(); err != nil {
+		t.Fatalf("InitErr unencrypted: %v", err)
+	}
+	if System.aead != nil || System.lsn != end {
+		t.Fatalf("encrypted=%v lsn=%d, want clear log at %d", System.aead != nil, System.lsn, end)
+	}
+}
@@ -16,6 +16,10 @@ const (
 	logFilePrefix         = "ib_logfile"
 )
 
+// logFlagEncrypted marks a log file whose records are stored in encrypted
+// blocks.
+const logFlagEncrypted uint32 = 1
+
 type logHeader struct {
 	Magic         uint32
 	Version       uint32
@@ -24,6 +28,7 @@ type logHeader struct {
 	FlushedLSN    uint64
 	CurrentLSN    uint64
 	FileSize      uint64
+	Flags         uint32
 }
 
 func logFilePath(dir string, index int) string {
@@ -35,6 +40,10 @@ func newLogHeader(cfg Config) logHeader {
 	if fileSize == 0 {
 		fileSize = 4 << 20
 	}
+	var flags uint32
+	if cfg.Encrypt {
+		flags |= logFlagEncrypted
+	}
 	return logHeader{
 		Magic:         logFileMagic,
 		Version:       logFileVersion,
@@ -43,6 +52,7 @@ func newLogHeader(cfg Config) logHeader {
 		FlushedLSN:    0,
 		CurrentLSN:    0,
 		FileSize:      fileSize,
+		Flags:         flags,
 	}
 }
 
@@ -55,6 +65,7 @@ func encodeLogHeader(h logHeader) []byte {
 	binary.BigEndian.PutUint64(buf[24:], h.FlushedLSN)
 	binary.BigEndian.PutUint64(buf[32:], h.CurrentLSN)
 	binary.BigEndian.PutUint64(buf[40:], h.FileSize)
+	binary.BigEndian.PutUint32(buf[48:], h.Flags)
 	return buf
 }
 
@@ -70,6 +81,7 @@ func decodeLogHeader(buf []byte) (logHeader, error) {
 		FlushedLSN:    binary.BigEndian.Uint64(buf[24:]),
 		CurrentLSN:    binary.BigEndian.Uint64(buf[32:]),
 		FileSize:      binary.BigEndian.Uint64(buf[40:]),
+		Flags:         binary.BigEndian.Uint32(buf[48:]),
 	}
 	if h.Magic != logFileMagic || h.Version != logFileVersion {
 		return logHeader{}, errors.New("log: invalid header")
@@ -126,6 +138,38 @@ func openLogFile(cfg Config) (ibos.File, logHeader, error) {
 		_ = ibos.FileClose(file)
 		return nil, logHeader{}, err
 	}
+	if hdr.Flags&logFlagEncrypted != newLogHeader(cfg).Flags&logFlagEncrypted && logFileClean(hdr) {
+		// The encryption setting changed and no redo is pending: start
+		// a new file in the new format at the current LSN.
+		_ = ibos.FileClose(file)
+		return recreateLogFile(path, hdr, cfg)
+	}
+	if err := preallocateLogFile(file, hdr, cfg); err != nil {
+		_ = ibos.FileClose(file)
+		return nil, logHeader{}, err
+	}
+	return file, hdr, nil
+}
+
+// logFileClean reports whether a log file holds no redo to recover.
+func logFileClean(hdr logHeader) bool {
+	return hdr.CheckpointLSN == hdr.CurrentLSN && hdr.FlushedLSN >= hdr.CurrentLSN
+}
+
+func recreateLogFile(path string, old logHeader, cfg Config) (ibos.File, logHeader, error) {
+	file, err := ibos.FileCreateSimple(path, ibos.FileOverwrite, ibos.FileReadWrite)
+	if err != nil {
+		return nil, logHeader{}, err
+	}
+	hdr := newLogHeader(cfg)
+	hdr.StartLSN = old.CurrentLSN
+	hdr.CheckpointLSN = old.CurrentLSN
+	hdr.FlushedLSN = old.CurrentLSN
+	hdr.CurrentLSN = old.CurrentLSN
+	if err := writeLogHeader(file, hdr); err != nil {
+		_ = ibos.FileClose(file)
+		return nil, logHeader{}, err
+	}
 	if err := preallocateLogFile(file, hdr, cfg); err != nil {
 		_ = ibos.FileClose(file)
 		return nil, logHeader{}, err
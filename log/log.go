@@ -1,6 +1,7 @@
 package log
 
 import (
+	"crypto/cipher"
 	"sync"
 	"sync/atomic"
 
@@ -35,6 +36,7 @@ type Log struct {
 	writerDone     chan struct{}
 	file           ibos.File
 	header         logHeader
+	aead           cipher.AEAD
 	initErr        error
 }
 
@@ -64,6 +66,12 @@ func Init() {
 	}
 	System.file = file
 	System.header = hdr
+	if err := System.initEncryption(); err != nil {
+		System.file = nil
+		_ = ibos.FileClose(file)
+		System.initErr = err
+		return
+	}
 	System.startLSN = hdr.StartLSN
 	System.checkpoint = hdr.CheckpointLSN
 	System.lsn = hdr.CurrentLSN
@@ -256,17 +256,41 @@ func RecvRecoveryRollbackActive() {
 	RecvRecoveryOn = false
 }
 
-// RecvScanLogRecs updates scan progress and optionally stores data.
+// RecvScanLogRecs updates scan progress and optionally stores data. As in
+// recv_parse_log_recs, a record with the single record flag is a complete
+// mini-transaction; other records are stored only once the
+// MLOG_MULTI_REC_END of their group is seen, so a group cut short by a crash
+// is not applied in part.
 func RecvScanLogRecs(storeToHash bool, buf []byte, startLSN uint64, contiguousLSN *uint64, groupScannedLSN *uint64) bool {
 	if RecvSysState == nil {
 		RecvSysCreate()
 	}
+	type parsedRec struct {
+		space, pageNo    uint32
+		typ              byte
+		payload          []byte
+		startLSN, endLSN uint64
+	}
+	var group []parsedRec
+	store := func(recs []parsedRec) {
+		if !storeToHash {
+			return
+		}
+		for _, r := range recs {
+			RecvAddRecord(r.space, r.pageNo, r.typ, append([]byte(nil), r.payload...), r.startLSN, r.endLSN)
+		}
+	}
 	offset := 0
+	complete := 0
 	for offset < len(buf) {
 		if buf[offset] == mlogMultiRecEnd {
 			offset++
+			store(group)
+			group = group[:0]
+			complete = offset
 			continue
 		}
+		single := buf[offset]&mlogSingleRecFlag != 0
 		recBuf := buf[offset:]
 		rest, typ, space, pageNo, ok := mlogParseInitial(recBuf)
 		if !ok {
@@ -279,21 +303,30 @@ func RecvScanLogRecs(storeToHash bool, buf []byte, startLSN uint64, contiguousLS
 			break
 		}
 		size := len(recBuf) - len(restAfter)
-		recStart := startLSN + uint64(offset)
-		recEnd := recStart + uint64(size)
-		if storeToHash {
-			RecvAddRecord(space, pageNo, typ, append([]byte(nil), payload...), recStart, recEnd)
+		r := parsedRec{
+			space:    space,
+			pageNo:   pageNo,
+			typ:      typ,
+			payload:  payload,
+			startLSN: startLSN + uint64(offset),
+			endLSN:   startLSN + uint64(offset+size),
 		}
 		offset += size
+		if single && len(group) == 0 {
+			store([]parsedRec{r})
+			complete = offset
+			continue
+		}
+		group = append(group, r)
 	}
-	end := startLSN + uint64(offset)
+	end := startLSN + uint64(complete)
 	if groupScannedLSN != nil {
 		*groupScannedLSN = end
 	}
 	if contiguousLSN != nil && end > *contiguousLSN {
 		*contiguousLSN = end
 	}
-	return offset == len(buf)
+	return complete == len(buf)
 }
 
 // RecvResetLogs resets the log system to a new start lsn.
@@ -23,7 +23,11 @@ func RecvScanLogFile(file ibos.File, startLSN, endLSN uint64) (uint64, uint64, e
 	if System != nil {
 		offset = System.lsnToOffset(startLSN)
 	}
-	if _, err := ibos.FileReadAt(file, buf, offset); err != nil {
+	if System != nil && System.aead != nil {
+		if err := readEncrypted(System.aead, file, uint64(offset-logHeaderSize), buf); err != nil {
+			return 0, 0, err
+		}
+	} else if _, err := ibos.FileReadAt(file, buf, offset); err != nil {
 		return 0, 0, err
 	}
 	var contiguous uint64
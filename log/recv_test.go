@@ -65,6 +65,32 @@ func TestRecvScanLogRecs(t *testing.T) {
 	}
 }
 
+func TestRecvScanLogRecsGroup(t *testing.T) {
+	RecvSysVarInit()
+	RecvSysCreate()
+	RecvSysInit(0)
+	var group []byte
+	for pageNo := uint32(3); pageNo <= 4; pageNo++ {
+		rec := buildMlogStringRecord(2, pageNo, 4, []byte("x"))
+		rec[0] &^= mlogSingleRecFlag
+		group = append(group, rec...)
+	}
+	var contiguous, scanned uint64
+	if RecvScanLogRecs(true, group, 100, &contiguous, &scanned) {
+		t.Fatalf("scan of a group without its end finished")
+	}
+	if RecvSysState.NAddrs != 0 || scanned != 100 {
+		t.Fatalf("torn group stored: addrs=%d scanned=%d", RecvSysState.NAddrs, scanned)
+	}
+	group = append(group, mlogMultiRecEnd)
+	if !RecvScanLogRecs(true, group, 100, &contiguous, &scanned) {
+		t.Fatalf("expected scan to finish")
+	}
+	if RecvSysState.NAddrs != 2 || scanned != 100+uint64(len(group)) {
+		t.Fatalf("group not stored: addrs=%d scanned=%d", RecvSysState.NAddrs, scanned)
+	}
+}
+
 func buildMlogStringPayload(offset int, data []byte) []byte {
 	buf := make([]byte, 4+len(data))
 	mach.WriteTo2(buf[0:], uint32(offset))
@@ -75,7 +101,7 @@ func buildMlogStringPayload(offset int, data []byte) []byte {
 
 func buildMlogStringRecord(space, pageNo uint32, offset int, data []byte) []byte {
 	buf := make([]byte, 0, 16+len(data))
-	buf = append(buf, mlogWriteStringType)
+	buf = append(buf, mlogWriteStringType|mlogSingleRecFlag)
 	tmp := make([]byte, 10)
 	n := mach.WriteCompressed(tmp, space)
 	buf = append(buf, tmp[:n]...)
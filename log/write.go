@@ -19,10 +19,19 @@ func (l *Log) writeRecord(startLSN uint64, data []byte) {
 		return
 	}
 	offset := l.lsnToOffset(startLSN)
-	if _, err := ibos.FileWriteAt(l.file, data, offset); err != nil {
-		return
+	var end int64
+	if l.aead != nil {
+		var err error
+		end, err = l.writeEncrypted(uint64(offset-logHeaderSize), data)
+		if err != nil {
+			return
+		}
+	} else {
+		if _, err := ibos.FileWriteAt(l.file, data, offset); err != nil {
+			return
+		}
+		end = offset + int64(len(data))
 	}
-	end := offset + int64(len(data))
 	if end > 0 && uint64(end) > l.fileSize {
 		l.fileSize = uint64(end)
 	}
@@ -0,0 +1,416 @@
+package os
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	stdos "os"
+	"sort"
+	"sync"
+)
+
+// Encryption at rest, as os0enc in InnoDB. Every encrypted tablespace and
+// the redo log have their own AES-256 key. Those keys are stored wrapped
+// (AES-GCM encrypted) by a master key in the key info file of the data
+// directory; the master keys live in a separate keyring file. Rotating the
+// master key rewraps the keys only, the data stays as it is.
+const (
+	// EncryptionKeyLen is the length of master and tablespace keys.
+	EncryptionKeyLen = 32
+	// EncryptionRedoKeyID is the key id of the redo log key.
+	EncryptionRedoKeyID uint32 = 0xFFFFFFFF
+)
+
+const (
+	keyringMagic    uint32 = 0x49424B52 // "IBKR"
+	keyInfoMagic    uint32 = 0x49424B49 // "IBKI"
+	encFileVersion  uint32 = 1
+	keyringFileMode        = 0o600
+)
+
+var (
+	// ErrEncryptionClosed reports use of encryption without an open keyring.
+	ErrEncryptionClosed = errors.New("os: encryption keyring not open")
+	// ErrEncryptionKeyMissing reports a key id without a stored key.
+	ErrEncryptionKeyMissing = errors.New("os: encryption key not found")
+	// ErrEncryptionCorrupt reports a keyring or key info file that does
+	// not verify.
+	ErrEncryptionCorrupt = errors.New("os: corrupt encryption file")
+)
+
+type wrappedKey struct {
+	masterID uint32
+	data     []byte
+}
+
+type encryptionState struct {
+	keyringPath string
+	infoPath    string
+	masterID    uint32
+	masters     map[uint32][]byte
+	wrapped     map[uint32]wrappedKey
+	keys        map[uint32][]byte
+}
+
+var (
+	encMu sync.Mutex
+	enc   *encryptionState
+)
+
+// EncryptionOpen loads the master keys from keyringPath and the wrapped keys
+// from infoPath. A missing keyring is created with a new master key.
+func EncryptionOpen(keyringPath, infoPath string) error {
+	if keyringPath == "" || infoPath == "" {
+		return errors.New("os: encryption paths not set")
+	}
+	state := &encryptionState{
+		keyringPath: keyringPath,
+		infoPath:    infoPath,
+		masters:     map[uint32][]byte{},
+		wrapped:     map[uint32]wrappedKey{},
+		keys:        map[uint32][]byte{},
+	}
+	if err := state.loadKeyring(); err != nil {
+		return err
+	}
+	if err := state.loadKeyInfo(); err != nil {
+		return err
+	}
+	encMu.Lock()
+	enc = state
+	encMu.Unlock()
+	return nil
+}
+
+// EncryptionClose forgets the loaded keys.
+func EncryptionClose() {
+	encMu.Lock()
+	enc = nil
+	encMu.Unlock()
+}
+
+// EncryptionIsOpen reports whether a keyring is loaded.
+func EncryptionIsOpen() bool {
+	encMu.Lock()
+	defer encMu.Unlock()
+	return enc != nil
+}
+
+// EncryptionMasterKeyID returns the id of the current master key, or 0
+// without an open keyring.
+func EncryptionMasterKeyID() uint32 {
+	encMu.Lock()
+	defer encMu.Unlock()
+	if enc == nil {
+		return 0
+	}
+	return enc.masterID
+}
+
+// EncryptionKey returns the key with id. With create set, a missing key is
+// generated, wrapped by the current master key and persisted.
+func EncryptionKey(id uint32, create bool) ([]byte, error) {
+	encMu.Lock()
+	defer encMu.Unlock()
+	if enc == nil {
+		return nil, ErrEncryptionClosed
+	}
+	if key := enc.keys[id]; key != nil {
+		return key, nil
+	}
+	if wk, ok := enc.wrapped[id]; ok {
+		key, err := enc.unwrap(id, wk)
+		if err != nil {
+			return nil, err
+		}
+		enc.keys[id] = key
+		return key, nil
+	}
+	if !create {
+		return nil, ErrEncryptionKeyMissing
+	}
+	key := make([]byte, EncryptionKeyLen)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	wk, err := enc.wrap(id, key)
+	if err != nil {
+		return nil, err
+	}
+	enc.wrapped[id] = wk
+	if err := enc.saveKeyInfo(); err != nil {
+		delete(enc.wrapped, id)
+		return nil, err
+	}
+	enc.keys[id] = key
+	return key, nil
+}
+
+// EncryptionDropKey removes the key with id, once the data it encrypted is
+// gone.
+func EncryptionDropKey(id uint32) error {
+	encMu.Lock()
+	defer encMu.Unlock()
+	if enc == nil {
+		return ErrEncryptionClosed
+	}
+	if _, ok := enc.wrapped[id]; !ok {
+		return nil
+	}
+	delete(enc.wrapped, id)
+	delete(enc.keys, id)
+	return enc.saveKeyInfo()
+}
+
+// EncryptionRotateMasterKey generates a new master key and rewraps all keys
+// with it. The keyring keeps the old master key until the rewrapped keys are
+// persisted, so a crash in between leaves every key readable.
+func EncryptionRotateMasterKey() (uint32, error) {
+	encMu.Lock()
+	defer encMu.Unlock()
+	if enc == nil {
+		return 0, ErrEncryptionClosed
+	}
+	master := make([]byte, EncryptionKeyLen)
+	if _, err := rand.Read(master); err != nil {
+		return 0, err
+	}
+	newID := enc.masterID + 1
+	rewrapped := make(map[uint32]wrappedKey, len(enc.wrapped))
+	for id, wk := range enc.wrapped {
+		key, err := enc.unwrap(id, wk)
+		if err != nil {
+			return 0, err
+		}
+		rewrapped[id], err = wrapKey(newID, master, id, key)
+		if err != nil {
+			return 0, err
+		}
+	}
+	oldID, oldMasters, oldWrapped := enc.masterID, enc.masters, enc.wrapped
+	enc.masters = map[uint32][]byte{newID: master}
+	for id, key := range oldMasters {
+		enc.masters[id] = key
+	}
+	enc.masterID = newID
+	if err := enc.saveKeyring(); err != nil {
+		enc.masterID, enc.masters = oldID, oldMasters
+		return 0, err
+	}
+	enc.wrapped = rewrapped
+	if err := enc.saveKeyInfo(); err != nil {
+		enc.wrapped = oldWrapped
+		return 0, err
+	}
+	enc.masters = map[uint32][]byte{newID: master}
+	if err := enc.saveKeyring(); err != nil {
+		return 0, err
+	}
+	return newID, nil
+}
+
+// EncryptionAEAD returns the AES-GCM cipher of a key.
+func EncryptionAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func (s *encryptionState) wrap(id uint32, key []byte) (wrappedKey, error) {
+	return wrapKey(s.masterID, s.masters[s.masterID], id, key)
+}
+
+func (s *encryptionState) unwrap(id uint32, wk wrappedKey) ([]byte, error) {
+	master := s.masters[wk.masterID]
+	if master == nil {
+		return nil, ErrEncryptionKeyMissing
+	}
+	aead, err := EncryptionAEAD(master)
+	if err != nil {
+		return nil, err
+	}
+	if len(wk.data) < aead.NonceSize() {
+		return nil, ErrEncryptionCorrupt
+	}
+	nonce, sealed := wk.data[:aead.NonceSize()], wk.data[aead.NonceSize():]
+	key, err := aead.Open(nil, nonce, sealed, keyIDBytes(id))
+	if err != nil {
+		return nil, ErrEncryptionCorrupt
+	}
+	return key, nil
+}
+
+// wrapKey seals key with a master key. The key id is authenticated with
+// it, so a wrapped key cannot be moved to another id.
+func wrapKey(masterID uint32, master []byte, id uint32, key []byte) (wrappedKey, error) {
+	if master == nil {
+		return wrappedKey{}, ErrEncryptionKeyMissing
+	}
+	aead, err := EncryptionAEAD(master)
+	if err != nil {
+		return wrappedKey{}, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return wrappedKey{}, err
+	}
+	return wrappedKey{masterID: masterID, data: aead.Seal(nonce, nonce, key, keyIDBytes(id))}, nil
+}
+
+func keyIDBytes(id uint32) []byte {
+	return binary.BigEndian.AppendUint32(nil, id)
+}
+
+// The keyring file holds its magic, version, the current master key id and
+// the (id, key) pairs of the master keys, followed by a CRC-32 of it all.
+func (s *encryptionState) loadKeyring() error {
+	data, err := readEncFile(s.keyringPath, keyringMagic)
+	if errors.Is(err, stdos.ErrNotExist) {
+		master := make([]byte, EncryptionKeyLen)
+		if _, err := rand.Read(master); err != nil {
+			return err
+		}
+		s.masterID = 1
+		s.masters[1] = master
+		return s.saveKeyring()
+	}
+	if err != nil {
+		return err
+	}
+	if len(data) < 8 {
+		return ErrEncryptionCorrupt
+	}
+	s.masterID = binary.BigEndian.Uint32(data)
+	count := int(binary.BigEndian.Uint32(data[4:]))
+	data = data[8:]
+	if len(data) != count*(4+EncryptionKeyLen) {
+		return ErrEncryptionCorrupt
+	}
+	for i := 0; i < count; i++ {
+		id := binary.BigEndian.Uint32(data)
+		s.masters[id] = append([]byte(nil), data[4:4+EncryptionKeyLen]...)
+		data = data[4+EncryptionKeyLen:]
+	}
+	if s.masters[s.masterID] == nil {
+		return ErrEncryptionCorrupt
+	}
+	return nil
+}
+
+func (s *encryptionState) saveKeyring() error {
+	ids := make([]uint32, 0, len(s.masters))
+	for id := range s.masters {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	data := binary.BigEndian.AppendUint32(nil, s.masterID)
+	data = binary.BigEndian.AppendUint32(data, uint32(len(ids)))
+	for _, id := range ids {
+		data = binary.BigEndian.AppendUint32(data, id)
+		data = append(data, s.masters[id]...)
+	}
+	return writeEncFile(s.keyringPath, keyringMagic, data)
+}
+
+// The key info file holds its magic and version and, for every key, its
+// id, the id of the master key wrapping it and the wrapped key, followed by
+// a CRC-32 of it all.
+func (s *encryptionState) loadKeyInfo() error {
+	data, err := readEncFile(s.infoPath, keyInfoMagic)
+	if errors.Is(err, stdos.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for len(data) > 0 {
+		if len(data) < 10 {
+			return ErrEncryptionCorrupt
+		}
+		id := binary.BigEndian.Uint32(data)
+		masterID := binary.BigEndian.Uint32(data[4:])
+		n := int(binary.BigEndian.Uint16(data[8:]))
+		if len(data) < 10+n {
+			return ErrEncryptionCorrupt
+		}
+		s.wrapped[id] = wrappedKey{masterID: masterID, data: append([]byte(nil), data[10:10+n]...)}
+		data = data[10+n:]
+	}
+	return nil
+}
+
+func (s *encryptionState) saveKeyInfo() error {
+	ids := make([]uint32, 0, len(s.wrapped))
+	for id := range s.wrapped {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	var data []byte
+	for _, id := range ids {
+		wk := s.wrapped[id]
+		data = binary.BigEndian.AppendUint32(data, id)
+		data = binary.BigEndian.AppendUint32(data, wk.masterID)
+		data = binary.BigEndian.AppendUint16(data, uint16(len(wk.data)))
+		data = append(data, wk.data...)
+	}
+	return writeEncFile(s.infoPath, keyInfoMagic, data)
+}
+
+func readEncFile(path string, magic uint32) ([]byte, error) {
+	info, err := Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	file, err := OpenFile(path, stdos.O_RDONLY, keyringFileMode)
+	if err != nil {
+		return nil, err
+	}
+	defer FileClose(file)
+	data := make([]byte, info.Size())
+	if _, err := FileReadAt(file, data, 0); err != nil {
+		return nil, err
+	}
+	if len(data) < 12 {
+		return nil, ErrEncryptionCorrupt
+	}
+	body, sum := data[:len(data)-4], binary.BigEndian.Uint32(data[len(data)-4:])
+	if crc32.ChecksumIEEE(body) != sum || binary.BigEndian.Uint32(body) != magic ||
+		binary.BigEndian.Uint32(body[4:]) != encFileVersion {
+		return nil, ErrEncryptionCorrupt
+	}
+	return body[8:], nil
+}
+
+// writeEncFile replaces path through a temporary file and a rename, so a
+// crash leaves either the old or the new contents.
+func writeEncFile(path string, magic uint32, payload []byte) error {
+	data := binary.BigEndian.AppendUint32(nil, magic)
+	data = binary.BigEndian.AppendUint32(data, encFileVersion)
+	data = append(data, payload...)
+	data = binary.BigEndian.AppendUint32(data, crc32.ChecksumIEEE(data))
+	if err := FileCreateSubdirsIfNeeded(path); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	file, err := OpenFile(tmp, stdos.O_CREATE|stdos.O_TRUNC|stdos.O_RDWR, keyringFileMode)
+	if err != nil {
+		return err
+	}
+	if _, err := FileWriteAt(file, data, 0); err != nil {
+		_ = FileClose(file)
+		return err
+	}
+	if err := FileFlush(file); err != nil {
+		_ = FileClose(file)
+		return err
+	}
+	if err := FileClose(file); err != nil {
+		return err
+	}
+	return Rename(tmp, path)
+}
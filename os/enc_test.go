@@ -0,0 +1,85 @@
+package os
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+)
+
+func TestEncryptionKeysSurviveReopenAndRotation(t *testing.T) {
+	dir := t.TempDir()
+	keyring := filepath.Join(dir, "keyring")
+	info := filepath.Join(dir, "ib_encryption")
+	t.Cleanup(EncryptionClose)
+
+	if _, err := EncryptionKey(7, true); err != ErrEncryptionClosed {
+		t.Fatalf("EncryptionKey without keyring: %v", err)
+	}
+	if err := EncryptionOpen(keyring, info); err != nil {
+		t.Fatalf("EncryptionOpen: %v", err)
+	}
+	if id := EncryptionMasterKeyID(); id != 1 {
+		t.Fatalf("master key id=%d, want 1", id)
+	}
+	key, err := EncryptionKey(7, true)
+	if err != nil {
+		t.Fatalf("EncryptionKey: %v", err)
+	}
+	if _, err := EncryptionKey(8, false); err != ErrEncryptionKeyMissing {
+		t.Fatalf("EncryptionKey(8)=%v, want missing", err)
+	}
+
+	EncryptionClose()
+	if err := EncryptionOpen(keyring, info); err != nil {
+		t.Fatalf("EncryptionOpen reopen: %v", err)
+	}
+	again, err := EncryptionKey(7, false)
+	if err != nil || !bytes.Equal(again, key) {
+		t.Fatalf("key after reopen differs: %v", err)
+	}
+
+	newID, err := EncryptionRotateMasterKey()
+	if err != nil || newID != 2 {
+		t.Fatalf("EncryptionRotateMasterKey=%d, %v", newID, err)
+	}
+	EncryptionClose()
+	if err := EncryptionOpen(keyring, info); err != nil {
+		t.Fatalf("EncryptionOpen after rotation: %v", err)
+	}
+	if id := EncryptionMasterKeyID(); id != 2 {
+		t.Fatalf("master key id=%d, want 2", id)
+	}
+	again, err = EncryptionKey(7, false)
+	if err != nil || !bytes.Equal(again, key) {
+		t.Fatalf("key after rotation differs: %v", err)
+	}
+
+	if err := EncryptionDropKey(7); err != nil {
+		t.Fatalf("EncryptionDropKey: %v", err)
+	}
+	if _, err := EncryptionKey(7, false); err != ErrEncryptionKeyMissing {
+		t.Fatalf("dropped key: %v", err)
+	}
+}
+
+func TestEncryptionDetectsCorruptKeyring(t *testing.T) {
+	dir := t.TempDir()
+	keyring := filepath.Join(dir, "keyring")
+	t.Cleanup(EncryptionClose)
+	if err := EncryptionOpen(keyring, filepath.Join(dir, "info")); err != nil {
+		t.Fatalf("EncryptionOpen: %v", err)
+	}
+	EncryptionClose()
+
+	file, err := FileCreateSimple(keyring, FileOpen, FileReadWrite)
+	if err != nil {
+		t.Fatalf("open keyring: %v", err)
+	}
+	if _, err := FileWriteAt(file, []byte{0xFF}, 20); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	FileClose(file)
+	if err := EncryptionOpen(keyring, filepath.Join(dir, "info")); err != ErrEncryptionCorrupt {
+		t.Fatalf("EncryptionOpen corrupt=%v", err)
+	}
+}
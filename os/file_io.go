@@ -158,6 +158,10 @@ func FileSize(file File) (int64, error) {
 	return info.Size(), nil
 }
 
+// ErrPunchHoleUnsupported reports a file or file system that cannot
+// deallocate a range in place.
+var ErrPunchHoleUnsupported = errors.New("os: punch hole not supported")
+
 // FilePreallocate grows a file to sizeBytes by writing zero blocks.
 func FilePreallocate(file File, sizeBytes int64) error {
 	if file == nil {
@@ -0,0 +1,37 @@
+//go:build linux
+
+package os
+
+import "syscall"
+
+// Linux fallocate flags, from linux/falloc.h.
+const (
+	fallocFlKeepSize  = 0x01
+	fallocFlPunchHole = 0x02
+)
+
+// FilePunchHole deallocates length bytes at offset, keeping the file size;
+// the range reads back as zeros.
+func FilePunchHole(file File, offset, length int64) error {
+	if length <= 0 {
+		return nil
+	}
+	conn, ok := file.(syscall.Conn)
+	if !ok {
+		return ErrPunchHoleUnsupported
+	}
+	raw, err := conn.SyscallConn()
+	if err != nil {
+		return err
+	}
+	var punchErr error
+	if err := raw.Control(func(fd uintptr) {
+		punchErr = syscall.Fallocate(int(fd), fallocFlPunchHole|fallocFlKeepSize, offset, length)
+	}); err != nil {
+		return err
+	}
+	if punchErr == syscall.EOPNOTSUPP || punchErr == syscall.ENOSYS {
+		return ErrPunchHoleUnsupported
+	}
+	return punchErr
+}
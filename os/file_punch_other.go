@@ -0,0 +1,8 @@
+//go:build !linux
+
+package os
+
+// FilePunchHole is not supported on this platform.
+func FilePunchHole(_ File, _, _ int64) error {
+	return ErrPunchHoleUnsupported
+}
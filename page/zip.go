@@ -5,8 +5,25 @@ import (
 	"compress/zlib"
 	"errors"
 	"io"
+
+	"github.com/wilhasse/innodb-go/fil"
 )
 
+func init() {
+	fil.SetZipCodec(fil.ZipCodec{
+		Compress: func(data []byte) ([]byte, error) {
+			zip, err := ZipCompress(data, 0)
+			if err != nil {
+				return nil, err
+			}
+			return zip.Data, nil
+		},
+		Decompress: func(data []byte, size int) ([]byte, error) {
+			return ZipDecompress(&ZipPage{Data: data, OriginalSize: size})
+		},
+	})
+}
+
 // ZipPage stores a compressed page payload.
 type ZipPage struct {
 	Data         []byte
@@ -1,19 +1,80 @@
 package pars
 
+import "strings"
+
 // Statement is a parsed SQL statement.
 type Statement interface {
 	stmtNode()
 }
 
-// SelectStmt represents a simple SELECT statement.
+// SelectStmt represents a SELECT statement. Columns holds the output name of
+// each item, or "*" alone when all columns are selected; Items holds the
+// select list itself and is empty for "*". Table and Alias name the first
+// table of the FROM clause and Joins the tables joined to it. Into names the
+// procedure variables of SELECT ... INTO. Limit and Offset are nil when not
+// given.
 type SelectStmt struct {
 	Columns []string
+	Items   []SelectItem
+	Into    []string
 	Table   string
+	Alias   string
+	Joins   []JoinClause
 	Where   Expr
+	GroupBy []Expr
+	Having  Expr
+	OrderBy []OrderItem
+	Limit   Expr
+	Offset  Expr
 }
 
 func (SelectStmt) stmtNode() {}
 
+// JoinClause is a table joined to the tables before it in a FROM clause. On
+// is nil for a comma join, whose condition is left to WHERE; Left marks a
+// LEFT OUTER JOIN.
+type JoinClause struct {
+	Table string
+	Alias string
+	Left  bool
+	On    Expr
+}
+
+// SelectItem is one expression of a select list. Text is the expression as
+// written and Alias the name given with AS, if any.
+type SelectItem struct {
+	Expr  Expr
+	Alias string
+	Text  string
+}
+
+// Name returns the output column name of the item.
+func (item SelectItem) Name() string {
+	if item.Alias != "" {
+		return item.Alias
+	}
+	if ident, ok := item.Expr.(IdentExpr); ok {
+		_, col := SplitQualified(ident.Name)
+		return col
+	}
+	return item.Text
+}
+
+// SplitQualified splits a column reference written as table.column. The
+// table part is empty for an unqualified name.
+func SplitQualified(name string) (string, string) {
+	if dot := strings.LastIndexByte(name, '.'); dot >= 0 {
+		return name[:dot], name[dot+1:]
+	}
+	return "", name
+}
+
+// OrderItem is one key of an ORDER BY clause.
+type OrderItem struct {
+	Expr Expr
+	Desc bool
+}
+
 // InsertStmt represents a basic INSERT statement.
 type InsertStmt struct {
 	Table   string
@@ -46,6 +107,202 @@ type DeleteStmt struct {
 
 func (DeleteStmt) stmtNode() {}
 
+// ExplainStmt represents EXPLAIN followed by a SELECT, INSERT, UPDATE or
+// DELETE statement, whose plan is returned instead of running it.
+type ExplainStmt struct {
+	Stmt Statement
+}
+
+func (ExplainStmt) stmtNode() {}
+
+// ColumnDef describes a column in CREATE TABLE. Type is the upper-cased type
+// name as written; Size is the declared length or zero.
+type ColumnDef struct {
+	Name     string
+	Type     string
+	Size     int
+	Unsigned bool
+	NotNull  bool
+}
+
+// IndexDef describes an index in CREATE TABLE or CREATE INDEX. Prefixes holds
+// the declared prefix length of each column, zero for the full column.
+type IndexDef struct {
+	Name     string
+	Columns  []string
+	Prefixes []int
+	Primary  bool
+	Unique   bool
+}
+
+// CreateDatabaseStmt represents CREATE DATABASE.
+type CreateDatabaseStmt struct {
+	Name string
+}
+
+func (CreateDatabaseStmt) stmtNode() {}
+
+// CreateTableStmt represents CREATE TABLE. RowFormat is the upper-cased
+// ROW_FORMAT option and KeyBlockSize the KEY_BLOCK_SIZE option, if given.
+type CreateTableStmt struct {
+	Table        string
+	Columns      []ColumnDef
+	Indexes      []IndexDef
+	RowFormat    string
+	KeyBlockSize int
+}
+
+func (CreateTableStmt) stmtNode() {}
+
+// CreateIndexStmt represents CREATE [UNIQUE] INDEX.
+type CreateIndexStmt struct {
+	Table string
+	Index IndexDef
+}
+
+func (CreateIndexStmt) stmtNode() {}
+
+// DropTableStmt represents DROP TABLE.
+type DropTableStmt struct {
+	Table string
+}
+
+func (DropTableStmt) stmtNode() {}
+
+// DropIndexStmt represents DROP INDEX ... ON.
+type DropIndexStmt struct {
+	Table string
+	Index string
+}
+
+func (DropIndexStmt) stmtNode() {}
+
+// RenameTableStmt represents RENAME TABLE ... TO.
+type RenameTableStmt struct {
+	From string
+	To   string
+}
+
+func (RenameTableStmt) stmtNode() {}
+
+// TruncateTableStmt represents TRUNCATE TABLE.
+type TruncateTableStmt struct {
+	Table string
+}
+
+func (TruncateTableStmt) stmtNode() {}
+
+// ProcedureStmt represents PROCEDURE name (params) IS declarations BEGIN
+// body END. Body holds the statements below along with SELECT ... INTO,
+// INSERT, UPDATE and DELETE.
+type ProcedureStmt struct {
+	Name    string
+	Params  []ProcParam
+	Vars    []VarDecl
+	Cursors []CursorDecl
+	Body    []Statement
+}
+
+func (ProcedureStmt) stmtNode() {}
+
+// ProcParam is a procedure parameter. An IN parameter starts with Value, the
+// literal bound under its name, or NULL; OUT parameters are returned when the
+// procedure ends.
+type ProcParam struct {
+	Name  string
+	Type  string
+	Out   bool
+	Value Expr
+}
+
+// VarDecl declares a procedure variable. Type is upper-cased as written.
+type VarDecl struct {
+	Name string
+	Type string
+}
+
+// CursorDecl declares a cursor over a SELECT. In expressions, the cursor
+// attributes name%NOTFOUND and name%FOUND read as IdentExpr names written
+// that way.
+type CursorDecl struct {
+	Name  string
+	Query *SelectStmt
+}
+
+// AssignStmt represents var := expr.
+type AssignStmt struct {
+	Var   string
+	Value Expr
+}
+
+func (AssignStmt) stmtNode() {}
+
+// IfStmt represents IF ... THEN ... [ELSIF ... THEN ...] [ELSE ...] END IF.
+type IfStmt struct {
+	Cond   Expr
+	Then   []Statement
+	Elsifs []ElsifClause
+	Else   []Statement
+}
+
+func (IfStmt) stmtNode() {}
+
+// ElsifClause is one ELSIF branch of an IF statement.
+type ElsifClause struct {
+	Cond Expr
+	Then []Statement
+}
+
+// WhileStmt represents WHILE cond LOOP ... END LOOP.
+type WhileStmt struct {
+	Cond Expr
+	Body []Statement
+}
+
+func (WhileStmt) stmtNode() {}
+
+// ForStmt represents FOR var IN from .. to LOOP ... END LOOP.
+type ForStmt struct {
+	Var  string
+	From Expr
+	To   Expr
+	Body []Statement
+}
+
+func (ForStmt) stmtNode() {}
+
+// ExitStmt leaves the innermost loop.
+type ExitStmt struct{}
+
+func (ExitStmt) stmtNode() {}
+
+// ReturnStmt ends the procedure.
+type ReturnStmt struct{}
+
+func (ReturnStmt) stmtNode() {}
+
+// OpenStmt runs the query of a cursor.
+type OpenStmt struct {
+	Cursor string
+}
+
+func (OpenStmt) stmtNode() {}
+
+// FetchStmt reads the next row of a cursor into variables.
+type FetchStmt struct {
+	Cursor string
+	Into   []string
+}
+
+func (FetchStmt) stmtNode() {}
+
+// CloseStmt closes a cursor.
+type CloseStmt struct {
+	Cursor string
+}
+
+func (CloseStmt) stmtNode() {}
+
 // Expr is a parsed expression node.
 type Expr interface {
 	exprNode()
@@ -74,3 +331,59 @@ type LiteralExpr struct {
 }
 
 func (LiteralExpr) exprNode() {}
+
+// ParamExpr is a bound literal left unresolved by ParsePrepared. Name is the
+// bind name, or the 1-based position of a "?" parameter.
+type ParamExpr struct {
+	Name string
+}
+
+func (ParamExpr) exprNode() {}
+
+// UnaryExpr represents a prefix operation such as negation.
+type UnaryExpr struct {
+	Op   TokenType
+	Expr Expr
+}
+
+func (UnaryExpr) exprNode() {}
+
+// FuncExpr represents a function call. Name is upper-cased and Star marks
+// COUNT(*).
+type FuncExpr struct {
+	Name string
+	Args []Expr
+	Star bool
+}
+
+func (FuncExpr) exprNode() {}
+
+// IsAggregate reports whether name is an aggregate function.
+func IsAggregate(name string) bool {
+	switch strings.ToUpper(name) {
+	case "COUNT", "SUM", "MIN", "MAX", "AVG":
+		return true
+	default:
+		return false
+	}
+}
+
+// HasAggregate reports whether expr contains an aggregate function call.
+func HasAggregate(expr Expr) bool {
+	switch e := expr.(type) {
+	case FuncExpr:
+		if IsAggregate(e.Name) {
+			return true
+		}
+		for _, arg := range e.Args {
+			if HasAggregate(arg) {
+				return true
+			}
+		}
+	case BinaryExpr:
+		return HasAggregate(e.Left) || HasAggregate(e.Right)
+	case UnaryExpr:
+		return HasAggregate(e.Expr)
+	}
+	return false
+}
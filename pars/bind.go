@@ -0,0 +1,142 @@
+package pars
+
+import "fmt"
+
+// BindParams returns a copy of a statement parsed by ParsePrepared with every
+// ParamExpr replaced by its literal from info. The statement itself is left
+// untouched so it can be bound again with other values.
+func BindParams(stmt Statement, info *Info) (Statement, error) {
+	return RewriteExprs(stmt, func(expr Expr) (Expr, error) {
+		param, ok := expr.(ParamExpr)
+		if !ok {
+			return expr, nil
+		}
+		var lit BoundLiteral
+		found := false
+		if info != nil {
+			lit, found = info.Literals[param.Name]
+		}
+		if !found {
+			return nil, fmt.Errorf("pars: unbound literal %s", param.Name)
+		}
+		return boundLiteralExpr(lit)
+	})
+}
+
+// RewriteExprs returns a copy of a SELECT, INSERT, UPDATE or DELETE statement
+// with every literal, identifier and parameter in its expressions replaced by
+// the result of fn, or of the statement an EXPLAIN wraps. A unary minus over
+// a replaced literal is folded into it. Other statements are returned as they
+// are.
+func RewriteExprs(stmt Statement, fn func(Expr) (Expr, error)) (Statement, error) {
+	r := rewriter{fn: fn}
+	var out Statement
+	switch s := stmt.(type) {
+	case *SelectStmt:
+		sel := *s
+		sel.Items = make([]SelectItem, len(s.Items))
+		for i, item := range s.Items {
+			item.Expr = r.expr(item.Expr)
+			sel.Items[i] = item
+		}
+		sel.Joins = make([]JoinClause, len(s.Joins))
+		for i, join := range s.Joins {
+			join.On = r.expr(join.On)
+			sel.Joins[i] = join
+		}
+		sel.Where = r.expr(s.Where)
+		sel.GroupBy = make([]Expr, len(s.GroupBy))
+		for i, key := range s.GroupBy {
+			sel.GroupBy[i] = r.expr(key)
+		}
+		sel.Having = r.expr(s.Having)
+		sel.OrderBy = make([]OrderItem, len(s.OrderBy))
+		for i, item := range s.OrderBy {
+			item.Expr = r.expr(item.Expr)
+			sel.OrderBy[i] = item
+		}
+		sel.Limit = r.expr(s.Limit)
+		sel.Offset = r.expr(s.Offset)
+		out = &sel
+	case *InsertStmt:
+		ins := *s
+		ins.Values = make([]Expr, len(s.Values))
+		for i, value := range s.Values {
+			ins.Values[i] = r.expr(value)
+		}
+		out = &ins
+	case *UpdateStmt:
+		upd := *s
+		upd.Assignments = make([]Assignment, len(s.Assignments))
+		for i, assign := range s.Assignments {
+			assign.Value = r.expr(assign.Value)
+			upd.Assignments[i] = assign
+		}
+		upd.Where = r.expr(s.Where)
+		out = &upd
+	case *DeleteStmt:
+		del := *s
+		del.Where = r.expr(s.Where)
+		out = &del
+	case *ExplainStmt:
+		inner, err := RewriteExprs(s.Stmt, fn)
+		if err != nil {
+			return nil, err
+		}
+		out = &ExplainStmt{Stmt: inner}
+	default:
+		out = stmt
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	return out, nil
+}
+
+// rewriter applies fn to the leaves of expressions and keeps the first error.
+type rewriter struct {
+	fn  func(Expr) (Expr, error)
+	err error
+}
+
+func (r *rewriter) expr(expr Expr) Expr {
+	if r.err != nil || expr == nil {
+		return expr
+	}
+	switch e := expr.(type) {
+	case UnaryExpr:
+		inner := r.expr(e.Expr)
+		if e.Op == TokenMinus {
+			return negate(inner)
+		}
+		return UnaryExpr{Op: e.Op, Expr: inner}
+	case BinaryExpr:
+		return BinaryExpr{Op: e.Op, Left: r.expr(e.Left), Right: r.expr(e.Right)}
+	case FuncExpr:
+		out := FuncExpr{Name: e.Name, Star: e.Star, Args: make([]Expr, len(e.Args))}
+		for i, arg := range e.Args {
+			out.Args[i] = r.expr(arg)
+		}
+		return out
+	default:
+		out, err := r.fn(expr)
+		if err != nil {
+			r.err = err
+			return expr
+		}
+		return out
+	}
+}
+
+// HasParams reports whether a statement parsed by ParsePrepared still holds
+// a ParamExpr.
+func HasParams(stmt Statement) bool {
+	found := false
+	_, _ = RewriteExprs(stmt, func(expr Expr) (Expr, error) {
+		if _, ok := expr.(ParamExpr); ok {
+			found = true
+		}
+		return expr, nil
+	})
+	return found
+}
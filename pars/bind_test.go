@@ -0,0 +1,60 @@
+package pars
+
+import "testing"
+
+func TestBindParams(t *testing.T) {
+	info := NewInfo()
+	info.AddID("tbl", "users")
+	stmt, err := ParsePrepared(info, "SELECT id FROM $tbl WHERE id = -:val AND name <> ? LIMIT ?")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	sel := stmt.(*SelectStmt)
+	if sel.Table != "users" {
+		t.Fatalf("table=%s", sel.Table)
+	}
+	if _, ok := sel.Limit.(ParamExpr); !ok {
+		t.Fatalf("limit=%#v", sel.Limit)
+	}
+	if _, err := BindParams(stmt, info); err == nil {
+		t.Fatalf("expected unbound literal error")
+	}
+
+	info.AddLiteral("val", []byte{0x07}, LiteralInt, true)
+	info.AddStrLiteral("1", "bob")
+	info.AddLiteral("2", []byte{0x03}, LiteralInt, true)
+	bound, err := BindParams(stmt, info)
+	if err != nil {
+		t.Fatalf("bind: %v", err)
+	}
+	where := mustBinary(t, bound.(*SelectStmt).Where)
+	if lit := mustLiteral(t, mustBinary(t, where.Left).Right); lit.Value != "-7" {
+		t.Fatalf("val=%+v", lit)
+	}
+	if lit := mustLiteral(t, mustBinary(t, where.Right).Right); lit.Kind != TokenString || lit.Value != "bob" {
+		t.Fatalf("name=%+v", lit)
+	}
+	if lit := mustLiteral(t, bound.(*SelectStmt).Limit); lit.Value != "3" {
+		t.Fatalf("limit=%+v", lit)
+	}
+	if _, ok := sel.Limit.(ParamExpr); !ok {
+		t.Fatalf("template changed: limit=%#v", sel.Limit)
+	}
+
+	info.AddNullLiteral("1")
+	ins, err := ParsePrepared(nil, "INSERT INTO t VALUES (?, :val)")
+	if err != nil {
+		t.Fatalf("parse insert: %v", err)
+	}
+	bound, err = BindParams(ins, info)
+	if err != nil {
+		t.Fatalf("bind insert: %v", err)
+	}
+	values := bound.(*InsertStmt).Values
+	if lit := mustLiteral(t, values[0]); lit.Kind != TokenNull {
+		t.Fatalf("values[0]=%+v", lit)
+	}
+	if lit := mustLiteral(t, values[1]); lit.Value != "7" {
+		t.Fatalf("values[1]=%+v", lit)
+	}
+}
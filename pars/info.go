@@ -9,6 +9,7 @@ type LiteralType string
 const (
 	LiteralInt    LiteralType = "int"
 	LiteralString LiteralType = "string"
+	LiteralNull   LiteralType = "null"
 )
 
 // BoundLiteral stores a bound literal value.
@@ -63,6 +64,11 @@ func (info *Info) AddStrLiteral(name, value string) {
 	info.AddLiteral(name, []byte(value), LiteralString, false)
 }
 
+// AddNullLiteral stores a SQL NULL literal.
+func (info *Info) AddNullLiteral(name string) {
+	info.AddLiteral(name, nil, LiteralNull, false)
+}
+
 // AddID stores an identifier binding.
 func (info *Info) AddID(name, id string) {
 	info.IDs[name] = BoundID{
@@ -1,6 +1,7 @@
 package pars
 
 import (
+	"strconv"
 	"strings"
 	"unicode"
 )
@@ -32,13 +33,48 @@ const (
 	TokenUpdate
 	TokenSet
 	TokenDelete
+	TokenCreate
+	TokenDrop
+	TokenRename
+	TokenTruncate
+	TokenTable
+	TokenIndex
+	TokenDatabase
+	TokenUnique
+	TokenPrimary
+	TokenKey
+	TokenOn
+	TokenTo
+	TokenOrder
+	TokenBy
+	TokenAsc
+	TokenDesc
+	TokenLimit
+	TokenAs
+	TokenGroup
+	TokenHaving
+	TokenJoin
+	TokenInner
+	TokenLeft
+	TokenOuter
 
 	TokenLParen
 	TokenRParen
 	TokenComma
+	TokenDot
 	TokenSemicolon
 	TokenEq
+	TokenNe
+	TokenLt
+	TokenLe
+	TokenGt
+	TokenGe
 	TokenStar
+	TokenPlus
+	TokenMinus
+	TokenSlash
+	TokenPercent
+	TokenAssign
 )
 
 // Token holds a lexed token.
@@ -50,8 +86,9 @@ type Token struct {
 
 // Lexer tokenizes SQL-like input.
 type Lexer struct {
-	input string
-	pos   int
+	input  string
+	pos    int
+	params int
 }
 
 // NewLexer creates a lexer for the input string.
@@ -80,23 +117,69 @@ func (l *Lexer) NextToken() Token {
 	case ',':
 		l.pos++
 		return Token{Type: TokenComma, Literal: ",", Pos: l.pos - 1}
+	case '.':
+		l.pos++
+		return Token{Type: TokenDot, Literal: ".", Pos: l.pos - 1}
 	case ';':
 		l.pos++
 		return Token{Type: TokenSemicolon, Literal: ";", Pos: l.pos - 1}
 	case '=':
 		l.pos++
 		return Token{Type: TokenEq, Literal: "=", Pos: l.pos - 1}
+	case '<':
+		switch l.peekNext() {
+		case '=':
+			l.pos += 2
+			return Token{Type: TokenLe, Literal: "<=", Pos: l.pos - 2}
+		case '>':
+			l.pos += 2
+			return Token{Type: TokenNe, Literal: "<>", Pos: l.pos - 2}
+		}
+		l.pos++
+		return Token{Type: TokenLt, Literal: "<", Pos: l.pos - 1}
+	case '>':
+		if l.peekNext() == '=' {
+			l.pos += 2
+			return Token{Type: TokenGe, Literal: ">=", Pos: l.pos - 2}
+		}
+		l.pos++
+		return Token{Type: TokenGt, Literal: ">", Pos: l.pos - 1}
+	case '!':
+		if l.peekNext() == '=' {
+			l.pos += 2
+			return Token{Type: TokenNe, Literal: "!=", Pos: l.pos - 2}
+		}
 	case '*':
 		l.pos++
 		return Token{Type: TokenStar, Literal: "*", Pos: l.pos - 1}
+	case '+':
+		l.pos++
+		return Token{Type: TokenPlus, Literal: "+", Pos: l.pos - 1}
+	case '-':
+		l.pos++
+		return Token{Type: TokenMinus, Literal: "-", Pos: l.pos - 1}
+	case '/':
+		l.pos++
+		return Token{Type: TokenSlash, Literal: "/", Pos: l.pos - 1}
+	case '%':
+		l.pos++
+		return Token{Type: TokenPercent, Literal: "%", Pos: l.pos - 1}
 	case '\'':
 		return l.readString()
 	case '"':
 		return l.readQuotedIdent()
 	case ':':
+		if l.peekNext() == '=' {
+			l.pos += 2
+			return Token{Type: TokenAssign, Literal: ":=", Pos: l.pos - 2}
+		}
 		return l.readBoundLiteral()
 	case '$':
 		return l.readBoundID()
+	case '?':
+		l.pos++
+		l.params++
+		return Token{Type: TokenBoundLiteral, Literal: strconv.Itoa(l.params), Pos: l.pos - 1}
 	}
 
 	if isDigit(ch) {
@@ -266,4 +349,28 @@ var keywordTokens = map[string]TokenType{
 	"UPDATE":    TokenUpdate,
 	"SET":       TokenSet,
 	"DELETE":    TokenDelete,
+	"CREATE":    TokenCreate,
+	"DROP":      TokenDrop,
+	"RENAME":    TokenRename,
+	"TRUNCATE":  TokenTruncate,
+	"TABLE":     TokenTable,
+	"INDEX":     TokenIndex,
+	"DATABASE":  TokenDatabase,
+	"UNIQUE":    TokenUnique,
+	"PRIMARY":   TokenPrimary,
+	"KEY":       TokenKey,
+	"ON":        TokenOn,
+	"TO":        TokenTo,
+	"ORDER":     TokenOrder,
+	"BY":        TokenBy,
+	"ASC":       TokenAsc,
+	"DESC":      TokenDesc,
+	"LIMIT":     TokenLimit,
+	"AS":        TokenAs,
+	"GROUP":     TokenGroup,
+	"HAVING":    TokenHaving,
+	"JOIN":      TokenJoin,
+	"INNER":     TokenInner,
+	"LEFT":      TokenLeft,
+	"OUTER":     TokenOuter,
 }
@@ -58,3 +58,28 @@ func TestLexerComments(t *testing.T) {
 		t.Fatalf("expected int, got %v %q", tok.Type, tok.Literal)
 	}
 }
+
+func TestLexerPositionalParams(t *testing.T) {
+	lex := NewLexer("? , ?")
+	for _, want := range []string{"1", "", "2"} {
+		tok := lex.NextToken()
+		if want == "" {
+			if tok.Type != TokenComma {
+				t.Fatalf("expected comma, got %v", tok.Type)
+			}
+			continue
+		}
+		if tok.Type != TokenBoundLiteral || tok.Literal != want {
+			t.Fatalf("positional param: %v %q want %q", tok.Type, tok.Literal, want)
+		}
+	}
+}
+
+func TestLexerComparisonOperators(t *testing.T) {
+	lex := NewLexer("< <= > >= <> != =")
+	for _, want := range []TokenType{TokenLt, TokenLe, TokenGt, TokenGe, TokenNe, TokenNe, TokenEq, TokenEOF} {
+		if tok := lex.NextToken(); tok.Type != want {
+			t.Fatalf("expected %v got %v (%q)", want, tok.Type, tok.Literal)
+		}
+	}
+}
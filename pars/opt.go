@@ -11,20 +11,29 @@ func Optimize(stmt Statement) Statement {
 		if s == nil {
 			return s
 		}
-		out := *s
-		out.Columns = append([]string(nil), s.Columns...)
-		out.Where = OptimizeExpr(s.Where)
-		return &out
+		return optimizeSelect(*s)
 	case SelectStmt:
-		out := s
-		out.Columns = append([]string(nil), s.Columns...)
-		out.Where = OptimizeExpr(s.Where)
-		return &out
+		return optimizeSelect(s)
 	default:
 		return stmt
 	}
 }
 
+func optimizeSelect(s SelectStmt) *SelectStmt {
+	out := s
+	out.Columns = append([]string(nil), s.Columns...)
+	out.Items = append([]SelectItem(nil), s.Items...)
+	out.Joins = make([]JoinClause, len(s.Joins))
+	for i, join := range s.Joins {
+		join.On = OptimizeExpr(join.On)
+		out.Joins[i] = join
+	}
+	out.GroupBy = append([]Expr(nil), s.GroupBy...)
+	out.OrderBy = append([]OrderItem(nil), s.OrderBy...)
+	out.Where = OptimizeExpr(s.Where)
+	return &out
+}
+
 // OptimizeExpr rewrites expressions with basic constant folding.
 func OptimizeExpr(expr Expr) Expr {
 	switch e := expr.(type) {
@@ -57,6 +66,11 @@ func optimizeBinary(expr BinaryExpr) Expr {
 			return folded
 		}
 		return BinaryExpr{Op: expr.Op, Left: left, Right: right}
+	case TokenNe, TokenLt, TokenLe, TokenGt, TokenGe:
+		if isLiteral(left) && isIdent(right) {
+			return BinaryExpr{Op: MirrorComparison(expr.Op), Left: right, Right: left}
+		}
+		return BinaryExpr{Op: expr.Op, Left: left, Right: right}
 	default:
 		return BinaryExpr{Op: expr.Op, Left: left, Right: right}
 	}
@@ -107,6 +121,23 @@ func normalizeEquality(left, right Expr) (Expr, Expr) {
 	return left, right
 }
 
+// MirrorComparison returns the operator that keeps a comparison true when its
+// operands are swapped, so 5 < id becomes id > 5.
+func MirrorComparison(op TokenType) TokenType {
+	switch op {
+	case TokenLt:
+		return TokenGt
+	case TokenLe:
+		return TokenGe
+	case TokenGt:
+		return TokenLt
+	case TokenGe:
+		return TokenLe
+	default:
+		return op
+	}
+}
+
 func foldEquality(left, right Expr) (Expr, bool) {
 	litLeft, okLeft := asLiteral(left)
 	litRight, okRight := asLiteral(right)
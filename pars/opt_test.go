@@ -22,6 +22,24 @@ func TestOptimizeNormalizeEq(t *testing.T) {
 	}
 }
 
+func TestOptimizeMirrorComparison(t *testing.T) {
+	stmt, err := NewParser("SELECT * FROM t WHERE 5 < id").Parse()
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	sel := Optimize(stmt).(*SelectStmt)
+	cmp := mustBinary(t, sel.Where)
+	if cmp.Op != TokenGt {
+		t.Fatalf("expected > got %v", cmp.Op)
+	}
+	if left := mustIdent(t, cmp.Left); left.Name != "id" {
+		t.Fatalf("left ident=%s", left.Name)
+	}
+	if right := mustLiteral(t, cmp.Right); right.Value != "5" {
+		t.Fatalf("right literal=%s", right.Value)
+	}
+}
+
 func TestOptimizeAndWithTrue(t *testing.T) {
 	stmt, err := NewParser("SELECT * FROM t WHERE id = 1 AND 1 = 1").Parse()
 	if err != nil {
@@ -20,6 +20,18 @@ func ParseSQLWithInfo(info *Info, sql string) (Statement, error) {
 	return parser.Parse()
 }
 
+// ParsePrepared parses a statement for repeated execution. Bound identifiers
+// are resolved from info, which may be nil, while bound literals are left as
+// ParamExpr for BindParams.
+func ParsePrepared(info *Info, sql string) (Statement, error) {
+	if strings.TrimSpace(sql) == "" {
+		return nil, fmt.Errorf("pars: empty input")
+	}
+	parser := NewParserWithInfo(sql, info)
+	parser.params = true
+	return parser.Parse()
+}
+
 func boundLiteralExpr(lit BoundLiteral) (LiteralExpr, error) {
 	switch lit.Type {
 	case LiteralInt:
@@ -30,6 +42,8 @@ func boundLiteralExpr(lit BoundLiteral) (LiteralExpr, error) {
 		return LiteralExpr{Value: value, Kind: TokenInt}, nil
 	case LiteralString:
 		return LiteralExpr{Value: string(lit.Value), Kind: TokenString}, nil
+	case LiteralNull:
+		return LiteralExpr{Kind: TokenNull}, nil
 	default:
 		return LiteralExpr{}, fmt.Errorf("pars: unsupported literal type %q", lit.Type)
 	}
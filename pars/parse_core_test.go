@@ -51,3 +51,22 @@ func TestParseSQLUnboundID(t *testing.T) {
 		t.Fatalf("expected error")
 	}
 }
+
+func TestParseSQLWithInfoPositionalNull(t *testing.T) {
+	info := NewInfo()
+	info.AddStrLiteral("1", "bob")
+	info.AddNullLiteral("2")
+
+	stmt, err := ParseSQLWithInfo(info, "INSERT INTO people (name, age) VALUES (?, ?)")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	ins := stmt.(*InsertStmt)
+	name := mustLiteral(t, ins.Values[0])
+	if name.Kind != TokenString || name.Value != "bob" {
+		t.Fatalf("name literal=%+v", name)
+	}
+	if age := mustLiteral(t, ins.Values[1]); age.Kind != TokenNull {
+		t.Fatalf("age literal=%+v", age)
+	}
+}
@@ -0,0 +1,380 @@
+package pars
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// primaryIndexName is the name given to PRIMARY KEY definitions.
+const primaryIndexName = "PRIMARY"
+
+func (p *Parser) parseCreate() (Statement, error) {
+	if p.cur.Type != TokenCreate {
+		return nil, fmt.Errorf("pars: expected CREATE")
+	}
+	p.nextToken()
+	switch p.cur.Type {
+	case TokenDatabase:
+		p.nextToken()
+		name, err := p.parseIdent()
+		if err != nil {
+			return nil, err
+		}
+		return &CreateDatabaseStmt{Name: name}, nil
+	case TokenTable:
+		p.nextToken()
+		return p.parseCreateTable()
+	case TokenUnique:
+		p.nextToken()
+		if p.cur.Type != TokenIndex {
+			return nil, fmt.Errorf("pars: expected INDEX after UNIQUE")
+		}
+		p.nextToken()
+		return p.parseCreateIndex(true)
+	case TokenIndex:
+		p.nextToken()
+		return p.parseCreateIndex(false)
+	default:
+		return nil, fmt.Errorf("pars: unexpected token %v after CREATE", p.cur.Type)
+	}
+}
+
+func (p *Parser) parseCreateTable() (Statement, error) {
+	table, err := p.parseIdent()
+	if err != nil {
+		return nil, err
+	}
+	if p.cur.Type != TokenLParen {
+		return nil, fmt.Errorf("pars: expected ( after table name")
+	}
+	p.nextToken()
+	stmt := &CreateTableStmt{Table: table}
+	for {
+		if err := p.parseTableElement(stmt); err != nil {
+			return nil, err
+		}
+		if p.cur.Type != TokenComma {
+			break
+		}
+		p.nextToken()
+	}
+	if p.cur.Type != TokenRParen {
+		return nil, fmt.Errorf("pars: expected ) after table definition")
+	}
+	p.nextToken()
+	if err := p.parseTableOptions(stmt); err != nil {
+		return nil, err
+	}
+	if len(stmt.Columns) == 0 {
+		return nil, fmt.Errorf("pars: table %s has no columns", table)
+	}
+	return stmt, nil
+}
+
+func (p *Parser) parseTableElement(stmt *CreateTableStmt) error {
+	switch p.cur.Type {
+	case TokenPrimary:
+		p.nextToken()
+		if p.cur.Type != TokenKey {
+			return fmt.Errorf("pars: expected KEY after PRIMARY")
+		}
+		p.nextToken()
+		idx, err := p.parseIndexColumns(IndexDef{Name: primaryIndexName, Primary: true, Unique: true})
+		if err != nil {
+			return err
+		}
+		return addIndexDef(stmt, idx)
+	case TokenUnique:
+		p.nextToken()
+		if p.cur.Type == TokenKey || p.cur.Type == TokenIndex {
+			p.nextToken()
+		}
+		idx, err := p.parseNamedIndex(IndexDef{Unique: true})
+		if err != nil {
+			return err
+		}
+		return addIndexDef(stmt, idx)
+	case TokenKey, TokenIndex:
+		p.nextToken()
+		idx, err := p.parseNamedIndex(IndexDef{})
+		if err != nil {
+			return err
+		}
+		return addIndexDef(stmt, idx)
+	default:
+		return p.parseColumnDef(stmt)
+	}
+}
+
+func (p *Parser) parseColumnDef(stmt *CreateTableStmt) error {
+	name, err := p.parseIdent()
+	if err != nil {
+		return err
+	}
+	if p.cur.Type != TokenIdent {
+		return fmt.Errorf("pars: expected type for column %s", name)
+	}
+	col := ColumnDef{Name: name, Type: strings.ToUpper(p.cur.Literal)}
+	p.nextToken()
+	if p.cur.Type == TokenLParen {
+		p.nextToken()
+		if col.Size, err = p.parseIntToken(); err != nil {
+			return err
+		}
+		if p.cur.Type != TokenRParen {
+			return fmt.Errorf("pars: expected ) after size of column %s", name)
+		}
+		p.nextToken()
+	}
+	for {
+		switch {
+		case p.isWord("UNSIGNED"):
+			col.Unsigned = true
+			p.nextToken()
+		case p.cur.Type == TokenNot:
+			p.nextToken()
+			if p.cur.Type != TokenNull {
+				return fmt.Errorf("pars: expected NULL after NOT")
+			}
+			col.NotNull = true
+			p.nextToken()
+		case p.cur.Type == TokenNull:
+			p.nextToken()
+		case p.cur.Type == TokenPrimary:
+			p.nextToken()
+			if p.cur.Type != TokenKey {
+				return fmt.Errorf("pars: expected KEY after PRIMARY")
+			}
+			p.nextToken()
+			idx := IndexDef{Name: primaryIndexName, Columns: []string{name}, Prefixes: []int{0}, Primary: true, Unique: true}
+			if err := addIndexDef(stmt, idx); err != nil {
+				return err
+			}
+		case p.cur.Type == TokenUnique:
+			p.nextToken()
+			if p.cur.Type == TokenKey {
+				p.nextToken()
+			}
+			idx := IndexDef{Name: name, Columns: []string{name}, Prefixes: []int{0}, Unique: true}
+			if err := addIndexDef(stmt, idx); err != nil {
+				return err
+			}
+		default:
+			stmt.Columns = append(stmt.Columns, col)
+			return nil
+		}
+	}
+}
+
+func (p *Parser) parseTableOptions(stmt *CreateTableStmt) error {
+	for {
+		switch {
+		case p.isWord("ROW_FORMAT"):
+			p.nextToken()
+			p.skipEq()
+			if p.cur.Type != TokenIdent {
+				return fmt.Errorf("pars: expected row format")
+			}
+			stmt.RowFormat = strings.ToUpper(p.cur.Literal)
+			p.nextToken()
+		case p.isWord("KEY_BLOCK_SIZE"):
+			p.nextToken()
+			p.skipEq()
+			size, err := p.parseIntToken()
+			if err != nil {
+				return err
+			}
+			stmt.KeyBlockSize = size
+		case p.cur.Type == TokenComma:
+			p.nextToken()
+		default:
+			return nil
+		}
+	}
+}
+
+func (p *Parser) parseCreateIndex(unique bool) (Statement, error) {
+	name, err := p.parseIdent()
+	if err != nil {
+		return nil, err
+	}
+	if p.cur.Type != TokenOn {
+		return nil, fmt.Errorf("pars: expected ON after index name")
+	}
+	p.nextToken()
+	table, err := p.parseIdent()
+	if err != nil {
+		return nil, err
+	}
+	idx, err := p.parseIndexColumns(IndexDef{Name: name, Unique: unique})
+	if err != nil {
+		return nil, err
+	}
+	return &CreateIndexStmt{Table: table, Index: idx}, nil
+}
+
+// parseNamedIndex parses an optional index name followed by its columns. An
+// unnamed index is named after its first column.
+func (p *Parser) parseNamedIndex(idx IndexDef) (IndexDef, error) {
+	if p.cur.Type != TokenLParen {
+		name, err := p.parseIdent()
+		if err != nil {
+			return idx, err
+		}
+		idx.Name = name
+	}
+	idx, err := p.parseIndexColumns(idx)
+	if err != nil {
+		return idx, err
+	}
+	if idx.Name == "" {
+		idx.Name = idx.Columns[0]
+	}
+	return idx, nil
+}
+
+func (p *Parser) parseIndexColumns(idx IndexDef) (IndexDef, error) {
+	if p.cur.Type != TokenLParen {
+		return idx, fmt.Errorf("pars: expected ( before index columns")
+	}
+	p.nextToken()
+	for {
+		col, err := p.parseIdent()
+		if err != nil {
+			return idx, err
+		}
+		prefix := 0
+		if p.cur.Type == TokenLParen {
+			p.nextToken()
+			if prefix, err = p.parseIntToken(); err != nil {
+				return idx, err
+			}
+			if p.cur.Type != TokenRParen {
+				return idx, fmt.Errorf("pars: expected ) after prefix of %s", col)
+			}
+			p.nextToken()
+		}
+		idx.Columns = append(idx.Columns, col)
+		idx.Prefixes = append(idx.Prefixes, prefix)
+		if p.cur.Type != TokenComma {
+			break
+		}
+		p.nextToken()
+	}
+	if p.cur.Type != TokenRParen {
+		return idx, fmt.Errorf("pars: expected ) after index columns")
+	}
+	p.nextToken()
+	return idx, nil
+}
+
+func addIndexDef(stmt *CreateTableStmt, idx IndexDef) error {
+	for _, existing := range stmt.Indexes {
+		if strings.EqualFold(existing.Name, idx.Name) {
+			return fmt.Errorf("pars: duplicate index %s", idx.Name)
+		}
+	}
+	if idx.Primary {
+		// The clustered index is listed first, as TableCreate expects.
+		stmt.Indexes = append([]IndexDef{idx}, stmt.Indexes...)
+		return nil
+	}
+	stmt.Indexes = append(stmt.Indexes, idx)
+	return nil
+}
+
+func (p *Parser) parseDrop() (Statement, error) {
+	if p.cur.Type != TokenDrop {
+		return nil, fmt.Errorf("pars: expected DROP")
+	}
+	p.nextToken()
+	switch p.cur.Type {
+	case TokenTable:
+		p.nextToken()
+		table, err := p.parseIdent()
+		if err != nil {
+			return nil, err
+		}
+		return &DropTableStmt{Table: table}, nil
+	case TokenIndex:
+		p.nextToken()
+		name, err := p.parseIdent()
+		if err != nil {
+			return nil, err
+		}
+		if p.cur.Type != TokenOn {
+			return nil, fmt.Errorf("pars: expected ON after index name")
+		}
+		p.nextToken()
+		table, err := p.parseIdent()
+		if err != nil {
+			return nil, err
+		}
+		return &DropIndexStmt{Table: table, Index: name}, nil
+	default:
+		return nil, fmt.Errorf("pars: unexpected token %v after DROP", p.cur.Type)
+	}
+}
+
+func (p *Parser) parseRename() (Statement, error) {
+	if p.cur.Type != TokenRename {
+		return nil, fmt.Errorf("pars: expected RENAME")
+	}
+	p.nextToken()
+	if p.cur.Type != TokenTable {
+		return nil, fmt.Errorf("pars: expected TABLE after RENAME")
+	}
+	p.nextToken()
+	from, err := p.parseIdent()
+	if err != nil {
+		return nil, err
+	}
+	if p.cur.Type != TokenTo {
+		return nil, fmt.Errorf("pars: expected TO")
+	}
+	p.nextToken()
+	to, err := p.parseIdent()
+	if err != nil {
+		return nil, err
+	}
+	return &RenameTableStmt{From: from, To: to}, nil
+}
+
+func (p *Parser) parseTruncate() (Statement, error) {
+	if p.cur.Type != TokenTruncate {
+		return nil, fmt.Errorf("pars: expected TRUNCATE")
+	}
+	p.nextToken()
+	if p.cur.Type == TokenTable {
+		p.nextToken()
+	}
+	table, err := p.parseIdent()
+	if err != nil {
+		return nil, err
+	}
+	return &TruncateTableStmt{Table: table}, nil
+}
+
+func (p *Parser) parseIntToken() (int, error) {
+	if p.cur.Type != TokenInt {
+		return 0, fmt.Errorf("pars: expected integer")
+	}
+	n, err := strconv.Atoi(p.cur.Literal)
+	if err != nil {
+		return 0, fmt.Errorf("pars: bad integer %q", p.cur.Literal)
+	}
+	p.nextToken()
+	return n, nil
+}
+
+// isWord reports whether the current token is the unreserved word w.
+func (p *Parser) isWord(w string) bool {
+	return p.cur.Type == TokenIdent && strings.EqualFold(p.cur.Literal, w)
+}
+
+func (p *Parser) skipEq() {
+	if p.cur.Type == TokenEq {
+		p.nextToken()
+	}
+}
@@ -0,0 +1,120 @@
+package pars
+
+import "testing"
+
+func TestParseCreateTable(t *testing.T) {
+	stmt, err := ParseSQL(`CREATE TABLE "db/t" (
+		id INT UNSIGNED NOT NULL,
+		name VARCHAR(32),
+		code CHAR(4) UNIQUE,
+		body BLOB,
+		PRIMARY KEY (id),
+		INDEX idx_name (name(8), id)
+	) ROW_FORMAT=COMPRESSED KEY_BLOCK_SIZE=8`)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	ct, ok := stmt.(*CreateTableStmt)
+	if !ok {
+		t.Fatalf("expected CreateTableStmt, got %T", stmt)
+	}
+	if ct.Table != "db/t" || ct.RowFormat != "COMPRESSED" || ct.KeyBlockSize != 8 {
+		t.Fatalf("table=%q format=%q block=%d", ct.Table, ct.RowFormat, ct.KeyBlockSize)
+	}
+	wantCols := []ColumnDef{
+		{Name: "id", Type: "INT", Unsigned: true, NotNull: true},
+		{Name: "name", Type: "VARCHAR", Size: 32},
+		{Name: "code", Type: "CHAR", Size: 4},
+		{Name: "body", Type: "BLOB"},
+	}
+	if len(ct.Columns) != len(wantCols) {
+		t.Fatalf("columns=%+v", ct.Columns)
+	}
+	for i := range wantCols {
+		if ct.Columns[i] != wantCols[i] {
+			t.Fatalf("column %d=%+v want %+v", i, ct.Columns[i], wantCols[i])
+		}
+	}
+	if len(ct.Indexes) != 3 {
+		t.Fatalf("indexes=%+v", ct.Indexes)
+	}
+	if pk := ct.Indexes[0]; !pk.Primary || pk.Name != "PRIMARY" || pk.Columns[0] != "id" {
+		t.Fatalf("primary=%+v", pk)
+	}
+	if uq := ct.Indexes[1]; !uq.Unique || uq.Name != "code" {
+		t.Fatalf("unique=%+v", uq)
+	}
+	if idx := ct.Indexes[2]; idx.Name != "idx_name" || len(idx.Columns) != 2 || idx.Prefixes[0] != 8 || idx.Prefixes[1] != 0 {
+		t.Fatalf("index=%+v", idx)
+	}
+}
+
+func TestParseCreateTableInlinePrimaryKey(t *testing.T) {
+	stmt, err := ParseSQL("CREATE TABLE t (a BIGINT PRIMARY KEY, b DOUBLE, KEY (b))")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	ct := stmt.(*CreateTableStmt)
+	if len(ct.Indexes) != 2 || !ct.Indexes[0].Primary || ct.Indexes[1].Name != "b" {
+		t.Fatalf("indexes=%+v", ct.Indexes)
+	}
+	if _, err := ParseSQL("CREATE TABLE t (a INT PRIMARY KEY, b INT PRIMARY KEY)"); err == nil {
+		t.Fatalf("expected error for two primary keys")
+	}
+	if _, err := ParseSQL("CREATE TABLE t ()"); err == nil {
+		t.Fatalf("expected error for empty table")
+	}
+}
+
+func TestParseDDLStatements(t *testing.T) {
+	tests := []struct {
+		sql  string
+		want Statement
+	}{
+		{"CREATE DATABASE shop", &CreateDatabaseStmt{Name: "shop"}},
+		{"DROP TABLE \"shop/t\"", &DropTableStmt{Table: "shop/t"}},
+		{"DROP INDEX idx ON \"shop/t\"", &DropIndexStmt{Table: "shop/t", Index: "idx"}},
+		{"RENAME TABLE \"shop/a\" TO \"shop/b\"", &RenameTableStmt{From: "shop/a", To: "shop/b"}},
+		{"TRUNCATE TABLE \"shop/t\";", &TruncateTableStmt{Table: "shop/t"}},
+	}
+	for _, tt := range tests {
+		stmt, err := ParseSQL(tt.sql)
+		if err != nil {
+			t.Fatalf("parse %q: %v", tt.sql, err)
+		}
+		switch want := tt.want.(type) {
+		case *CreateDatabaseStmt:
+			if got, ok := stmt.(*CreateDatabaseStmt); !ok || *got != *want {
+				t.Fatalf("%q => %#v", tt.sql, stmt)
+			}
+		case *DropTableStmt:
+			if got, ok := stmt.(*DropTableStmt); !ok || *got != *want {
+				t.Fatalf("%q => %#v", tt.sql, stmt)
+			}
+		case *DropIndexStmt:
+			if got, ok := stmt.(*DropIndexStmt); !ok || *got != *want {
+				t.Fatalf("%q => %#v", tt.sql, stmt)
+			}
+		case *RenameTableStmt:
+			if got, ok := stmt.(*RenameTableStmt); !ok || *got != *want {
+				t.Fatalf("%q => %#v", tt.sql, stmt)
+			}
+		case *TruncateTableStmt:
+			if got, ok := stmt.(*TruncateTableStmt); !ok || *got != *want {
+				t.Fatalf("%q => %#v", tt.sql, stmt)
+			}
+		}
+	}
+
+	stmt, err := ParseSQL("CREATE UNIQUE INDEX uq ON t (a, b(4))")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	ci := stmt.(*CreateIndexStmt)
+	if ci.Table != "t" || ci.Index.Name != "uq" || !ci.Index.Unique || len(ci.Index.Columns) != 2 || ci.Index.Prefixes[1] != 4 {
+		t.Fatalf("create index=%+v", ci)
+	}
+	if _, err := ParseSQL("DROP INDEX idx"); err == nil {
+		t.Fatalf("expected error for DROP INDEX without ON")
+	}
+}
@@ -0,0 +1,366 @@
+package pars
+
+import (
+	"fmt"
+	"strings"
+)
+
+// parseProcedure parses a procedure in the InnoDB internal procedure
+// language. DECLARE before a declaration is optional, and the body may use
+// ELSE after ELSIF branches.
+func (p *Parser) parseProcedure() (Statement, error) {
+	if p.cur.Type != TokenProcedure {
+		return nil, fmt.Errorf("pars: expected PROCEDURE")
+	}
+	p.nextToken()
+	name, err := p.parseIdent()
+	if err != nil {
+		return nil, err
+	}
+	stmt := &ProcedureStmt{Name: name}
+	p.cursors = make(map[string]bool)
+	if p.cur.Type == TokenLParen {
+		p.nextToken()
+		for p.cur.Type != TokenRParen {
+			param, err := p.parseProcParam()
+			if err != nil {
+				return nil, err
+			}
+			stmt.Params = append(stmt.Params, param)
+			if p.cur.Type == TokenComma {
+				p.nextToken()
+			} else if p.cur.Type != TokenRParen {
+				return nil, fmt.Errorf("pars: expected , or ) after parameter %s", param.Name)
+			}
+		}
+		p.nextToken()
+	}
+	if err := p.expectWord("IS"); err != nil {
+		return nil, err
+	}
+	for !p.isWord("BEGIN") {
+		if p.cur.Type == TokenEOF {
+			return nil, fmt.Errorf("pars: expected BEGIN")
+		}
+		if p.isWord("DECLARE") {
+			p.nextToken()
+		}
+		if p.isWord("CURSOR") {
+			cursor, err := p.parseCursorDecl()
+			if err != nil {
+				return nil, err
+			}
+			stmt.Cursors = append(stmt.Cursors, cursor)
+			continue
+		}
+		varName, err := p.parseIdent()
+		if err != nil {
+			return nil, err
+		}
+		typ, err := p.parseProcType(varName)
+		if err != nil {
+			return nil, err
+		}
+		stmt.Vars = append(stmt.Vars, VarDecl{Name: varName, Type: typ})
+		if err := p.expectSemicolon(); err != nil {
+			return nil, err
+		}
+	}
+	p.nextToken()
+	if stmt.Body, err = p.parseProcStatements("END"); err != nil {
+		return nil, err
+	}
+	p.nextToken()
+	if p.cur.Type == TokenSemicolon {
+		p.nextToken()
+	}
+	if p.cur.Type != TokenEOF {
+		return nil, fmt.Errorf("pars: unexpected token %v after END", p.cur.Type)
+	}
+	return stmt, nil
+}
+
+// parseProcParam parses "name [IN | OUT] type".
+func (p *Parser) parseProcParam() (ProcParam, error) {
+	name, err := p.parseIdent()
+	if err != nil {
+		return ProcParam{}, err
+	}
+	param := ProcParam{Name: name}
+	switch {
+	case p.isWord("IN"):
+		p.nextToken()
+	case p.isWord("OUT"):
+		param.Out = true
+		p.nextToken()
+	}
+	if param.Type, err = p.parseProcType(name); err != nil {
+		return ProcParam{}, err
+	}
+	if !param.Out && p.info != nil {
+		if lit, ok := p.info.Literals[name]; ok {
+			if param.Value, err = boundLiteralExpr(lit); err != nil {
+				return ProcParam{}, err
+			}
+		}
+	}
+	return param, nil
+}
+
+// parseProcType parses a type name with an optional size, which is ignored.
+func (p *Parser) parseProcType(name string) (string, error) {
+	if p.cur.Type != TokenIdent {
+		return "", fmt.Errorf("pars: expected type for %s", name)
+	}
+	typ := strings.ToUpper(p.cur.Literal)
+	p.nextToken()
+	if p.cur.Type == TokenLParen {
+		p.nextToken()
+		if _, err := p.parseIntToken(); err != nil {
+			return "", err
+		}
+		if p.cur.Type != TokenRParen {
+			return "", fmt.Errorf("pars: expected ) after size of %s", name)
+		}
+		p.nextToken()
+	}
+	return typ, nil
+}
+
+// parseCursorDecl parses "CURSOR name IS select;".
+func (p *Parser) parseCursorDecl() (CursorDecl, error) {
+	p.nextToken()
+	name, err := p.parseIdent()
+	if err != nil {
+		return CursorDecl{}, err
+	}
+	if err := p.expectWord("IS"); err != nil {
+		return CursorDecl{}, err
+	}
+	query, err := p.parseSelect()
+	if err != nil {
+		return CursorDecl{}, err
+	}
+	sel := query.(*SelectStmt)
+	if len(sel.Into) > 0 {
+		return CursorDecl{}, fmt.Errorf("pars: cursor %s query has INTO", name)
+	}
+	if err := p.expectSemicolon(); err != nil {
+		return CursorDecl{}, err
+	}
+	p.cursors[strings.ToLower(name)] = true
+	return CursorDecl{Name: name, Query: sel}, nil
+}
+
+// parseProcStatements parses statements up to one of the words in end,
+// which is left as the current token.
+func (p *Parser) parseProcStatements(end ...string) ([]Statement, error) {
+	var stmts []Statement
+	for {
+		for _, word := range end {
+			if p.isWord(word) {
+				return stmts, nil
+			}
+		}
+		stmt, err := p.parseProcStatement()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expectSemicolon(); err != nil {
+			return nil, err
+		}
+		stmts = append(stmts, stmt)
+	}
+}
+
+func (p *Parser) parseProcStatement() (Statement, error) {
+	switch p.cur.Type {
+	case TokenSelect:
+		return p.parseSelect()
+	case TokenInsert:
+		return p.parseInsert()
+	case TokenUpdate:
+		return p.parseUpdate()
+	case TokenDelete:
+		return p.parseDelete()
+	case TokenIdent:
+	default:
+		return nil, fmt.Errorf("pars: unexpected token %v in procedure body", p.cur.Type)
+	}
+	switch {
+	case p.peek.Type == TokenAssign:
+		name := p.cur.Literal
+		p.nextToken()
+		p.nextToken()
+		value, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		return AssignStmt{Var: name, Value: value}, nil
+	case p.isWord("IF"):
+		return p.parseIfStmt()
+	case p.isWord("WHILE"):
+		p.nextToken()
+		cond, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		body, err := p.parseLoopBody()
+		if err != nil {
+			return nil, err
+		}
+		return WhileStmt{Cond: cond, Body: body}, nil
+	case p.isWord("FOR"):
+		return p.parseForStmt()
+	case p.isWord("EXIT"):
+		p.nextToken()
+		return ExitStmt{}, nil
+	case p.isWord("RETURN"):
+		p.nextToken()
+		return ReturnStmt{}, nil
+	case p.isWord("OPEN"), p.isWord("CLOSE"):
+		open := p.isWord("OPEN")
+		p.nextToken()
+		cursor, err := p.parseIdent()
+		if err != nil {
+			return nil, err
+		}
+		if open {
+			return OpenStmt{Cursor: cursor}, nil
+		}
+		return CloseStmt{Cursor: cursor}, nil
+	case p.isWord("FETCH"):
+		p.nextToken()
+		cursor, err := p.parseIdent()
+		if err != nil {
+			return nil, err
+		}
+		if p.cur.Type != TokenInto {
+			return nil, fmt.Errorf("pars: expected INTO after FETCH %s", cursor)
+		}
+		p.nextToken()
+		into, err := p.parseIdentList()
+		if err != nil {
+			return nil, err
+		}
+		return FetchStmt{Cursor: cursor, Into: into}, nil
+	default:
+		return nil, fmt.Errorf("pars: unexpected %s in procedure body", p.cur.Literal)
+	}
+}
+
+func (p *Parser) parseIfStmt() (Statement, error) {
+	p.nextToken()
+	var stmt IfStmt
+	var err error
+	if stmt.Cond, err = p.parseExpr(); err != nil {
+		return nil, err
+	}
+	if err := p.expectWord("THEN"); err != nil {
+		return nil, err
+	}
+	if stmt.Then, err = p.parseProcStatements("ELSIF", "ELSE", "END"); err != nil {
+		return nil, err
+	}
+	for p.isWord("ELSIF") {
+		p.nextToken()
+		var elsif ElsifClause
+		if elsif.Cond, err = p.parseExpr(); err != nil {
+			return nil, err
+		}
+		if err := p.expectWord("THEN"); err != nil {
+			return nil, err
+		}
+		if elsif.Then, err = p.parseProcStatements("ELSIF", "ELSE", "END"); err != nil {
+			return nil, err
+		}
+		stmt.Elsifs = append(stmt.Elsifs, elsif)
+	}
+	if p.isWord("ELSE") {
+		p.nextToken()
+		if stmt.Else, err = p.parseProcStatements("END"); err != nil {
+			return nil, err
+		}
+	}
+	if err := p.expectWord("END"); err != nil {
+		return nil, err
+	}
+	if err := p.expectWord("IF"); err != nil {
+		return nil, err
+	}
+	return stmt, nil
+}
+
+func (p *Parser) parseForStmt() (Statement, error) {
+	p.nextToken()
+	name, err := p.parseIdent()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expectWord("IN"); err != nil {
+		return nil, err
+	}
+	stmt := ForStmt{Var: name}
+	if stmt.From, err = p.parseExpr(); err != nil {
+		return nil, err
+	}
+	if p.cur.Type != TokenDot || p.peek.Type != TokenDot {
+		return nil, fmt.Errorf("pars: expected .. in FOR %s", name)
+	}
+	p.nextToken()
+	p.nextToken()
+	if stmt.To, err = p.parseExpr(); err != nil {
+		return nil, err
+	}
+	if stmt.Body, err = p.parseLoopBody(); err != nil {
+		return nil, err
+	}
+	return stmt, nil
+}
+
+// parseLoopBody parses "LOOP statements END LOOP".
+func (p *Parser) parseLoopBody() ([]Statement, error) {
+	if err := p.expectWord("LOOP"); err != nil {
+		return nil, err
+	}
+	body, err := p.parseProcStatements("END")
+	if err != nil {
+		return nil, err
+	}
+	p.nextToken()
+	if err := p.expectWord("LOOP"); err != nil {
+		return nil, err
+	}
+	return body, nil
+}
+
+// parseCursorAttr reads the %NOTFOUND or %FOUND attribute after the name of
+// a declared cursor.
+func (p *Parser) parseCursorAttr(name string) (string, bool) {
+	if p.cur.Type != TokenPercent || p.peek.Type != TokenIdent || !p.cursors[strings.ToLower(name)] {
+		return "", false
+	}
+	attr := strings.ToUpper(p.peek.Literal)
+	if attr != "NOTFOUND" && attr != "FOUND" {
+		return "", false
+	}
+	p.nextToken()
+	p.nextToken()
+	return name + "%" + attr, true
+}
+
+func (p *Parser) expectWord(w string) error {
+	if !p.isWord(w) {
+		return fmt.Errorf("pars: expected %s", w)
+	}
+	p.nextToken()
+	return nil
+}
+
+func (p *Parser) expectSemicolon() error {
+	if p.cur.Type != TokenSemicolon {
+		return fmt.Errorf("pars: expected ;")
+	}
+	p.nextToken()
+	return nil
+}
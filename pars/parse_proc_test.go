@@ -0,0 +1,83 @@
+package pars
+
+import "testing"
+
+func TestParseProcedure(t *testing.T) {
+	info := NewInfo()
+	info.AddLiteral("lo", []byte{0x02}, LiteralInt, true)
+	stmt, err := ParseSQLWithInfo(info, `PROCEDURE p (lo IN INT, total OUT INT) IS
+		i INT;
+		name VARCHAR(16);
+		DECLARE CURSOR c IS SELECT id, name FROM t WHERE id >= lo;
+	BEGIN
+		total := 0;
+		OPEN c;
+		WHILE 1 = 1 LOOP
+			FETCH c INTO i, name;
+			IF c%NOTFOUND THEN
+				EXIT;
+			ELSIF i % 2 = 0 THEN
+				total := total + i;
+			ELSE
+				UPDATE t SET name = 'odd' WHERE id = i;
+			END IF;
+		END LOOP;
+		CLOSE c;
+		FOR i IN 1 .. lo LOOP
+			INSERT INTO log VALUES (i);
+		END LOOP;
+		SELECT COUNT(*) INTO i FROM t;
+		RETURN;
+	END;`)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	proc := stmt.(*ProcedureStmt)
+	if proc.Name != "p" || len(proc.Params) != 2 || len(proc.Vars) != 2 || len(proc.Cursors) != 1 || len(proc.Body) != 7 {
+		t.Fatalf("proc=%+v", proc)
+	}
+	if p := proc.Params[0]; p.Out || p.Type != "INT" || mustLiteral(t, p.Value).Value != "2" {
+		t.Fatalf("param lo=%+v", p)
+	}
+	if p := proc.Params[1]; !p.Out || p.Value != nil {
+		t.Fatalf("param total=%+v", p)
+	}
+	if v := proc.Vars[1]; v.Name != "name" || v.Type != "VARCHAR" {
+		t.Fatalf("var=%+v", v)
+	}
+	loop := proc.Body[2].(WhileStmt)
+	if fetch := loop.Body[0].(FetchStmt); fetch.Cursor != "c" || len(fetch.Into) != 2 {
+		t.Fatalf("fetch=%+v", fetch)
+	}
+	ifStmt := loop.Body[1].(IfStmt)
+	if mustIdent(t, ifStmt.Cond).Name != "c%NOTFOUND" || len(ifStmt.Elsifs) != 1 || len(ifStmt.Else) != 1 {
+		t.Fatalf("if=%+v", ifStmt)
+	}
+	if _, ok := ifStmt.Then[0].(ExitStmt); !ok {
+		t.Fatalf("then=%+v", ifStmt.Then)
+	}
+	if cond := mustBinary(t, mustBinary(t, ifStmt.Elsifs[0].Cond).Left); cond.Op != TokenPercent {
+		t.Fatalf("elsif cond=%+v", cond)
+	}
+	forStmt := proc.Body[4].(ForStmt)
+	if forStmt.Var != "i" || mustLiteral(t, forStmt.From).Value != "1" || mustIdent(t, forStmt.To).Name != "lo" {
+		t.Fatalf("for=%+v", forStmt)
+	}
+	if sel := proc.Body[5].(*SelectStmt); len(sel.Into) != 1 || sel.Into[0] != "i" {
+		t.Fatalf("select into=%+v", sel)
+	}
+
+	for _, sql := range []string{
+		"PROCEDURE p IS BEGIN x := 1 END",
+		"PROCEDURE p IS BEGIN IF x THEN RETURN; END; END",
+		"PROCEDURE p IS BEGIN WHILE x LOOP EXIT; END WHILE; END",
+		"PROCEDURE p IS BEGIN FOR i IN 1 TO 3 LOOP EXIT; END LOOP; END",
+		"PROCEDURE p IS x INT BEGIN RETURN; END",
+		"PROCEDURE p IS BEGIN RETURN; END; SELECT",
+		"PROCEDURE p IS BEGIN FETCH c; END",
+	} {
+		if _, err := ParseSQL(sql); err == nil {
+			t.Fatalf("%s: expected error", sql)
+		}
+	}
+}
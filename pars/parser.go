@@ -1,6 +1,9 @@
 package pars
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
 
 // Parser consumes tokens from a Lexer and builds an AST.
 type Parser struct {
@@ -8,6 +11,12 @@ type Parser struct {
 	cur   Token
 	peek  Token
 	info  *Info
+	// params keeps bound literals as ParamExpr instead of resolving them
+	// from info.
+	params bool
+	// cursors holds the lower-cased names of the cursors declared by the
+	// procedure being parsed.
+	cursors map[string]bool
 }
 
 // NewParser creates a parser for the input string.
@@ -29,6 +38,9 @@ func (p *Parser) Parse() (Statement, error) {
 	if p.cur.Type == TokenEOF {
 		return nil, fmt.Errorf("pars: empty input")
 	}
+	if p.isWord("EXPLAIN") {
+		return p.parseExplain()
+	}
 	switch p.cur.Type {
 	case TokenSelect:
 		return p.parseSelect()
@@ -38,40 +50,256 @@ func (p *Parser) Parse() (Statement, error) {
 		return p.parseUpdate()
 	case TokenDelete:
 		return p.parseDelete()
+	case TokenCreate:
+		return p.parseCreate()
+	case TokenDrop:
+		return p.parseDrop()
+	case TokenRename:
+		return p.parseRename()
+	case TokenTruncate:
+		return p.parseTruncate()
+	case TokenProcedure:
+		return p.parseProcedure()
 	default:
 		return nil, fmt.Errorf("pars: unexpected token %v", p.cur.Type)
 	}
 }
 
+func (p *Parser) parseExplain() (Statement, error) {
+	p.nextToken()
+	var stmt Statement
+	var err error
+	switch p.cur.Type {
+	case TokenSelect:
+		stmt, err = p.parseSelect()
+	case TokenInsert:
+		stmt, err = p.parseInsert()
+	case TokenUpdate:
+		stmt, err = p.parseUpdate()
+	case TokenDelete:
+		stmt, err = p.parseDelete()
+	default:
+		return nil, fmt.Errorf("pars: cannot explain %v", p.cur.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &ExplainStmt{Stmt: stmt}, nil
+}
+
 func (p *Parser) parseSelect() (Statement, error) {
 	if p.cur.Type != TokenSelect {
 		return nil, fmt.Errorf("pars: expected SELECT")
 	}
 	p.nextToken()
 
-	cols, err := p.parseColumns()
-	if err != nil {
+	stmt := &SelectStmt{}
+	if err := p.parseSelectList(stmt); err != nil {
 		return nil, err
 	}
+	if p.cur.Type == TokenInto {
+		p.nextToken()
+		into, err := p.parseIdentList()
+		if err != nil {
+			return nil, err
+		}
+		stmt.Into = into
+	}
 	if p.cur.Type != TokenFrom {
 		return nil, fmt.Errorf("pars: expected FROM")
 	}
 	p.nextToken()
-	table, err := p.parseIdent()
-	if err != nil {
+	var err error
+	if stmt.Table, stmt.Alias, err = p.parseTableRef(); err != nil {
+		return nil, err
+	}
+	if stmt.Joins, err = p.parseJoins(); err != nil {
 		return nil, err
 	}
 
-	var where Expr
 	if p.cur.Type == TokenWhere {
 		p.nextToken()
-		where, err = p.parseExpr()
+		stmt.Where, err = p.parseExpr()
 		if err != nil {
 			return nil, err
 		}
 	}
+	if p.cur.Type == TokenGroup {
+		p.nextToken()
+		if p.cur.Type != TokenBy {
+			return nil, fmt.Errorf("pars: expected BY after GROUP")
+		}
+		p.nextToken()
+		if stmt.GroupBy, err = p.parseExprList(); err != nil {
+			return nil, err
+		}
+	}
+	if p.cur.Type == TokenHaving {
+		p.nextToken()
+		if stmt.Having, err = p.parseExpr(); err != nil {
+			return nil, err
+		}
+	}
+	if p.cur.Type == TokenOrder {
+		p.nextToken()
+		if p.cur.Type != TokenBy {
+			return nil, fmt.Errorf("pars: expected BY after ORDER")
+		}
+		p.nextToken()
+		if stmt.OrderBy, err = p.parseOrderBy(); err != nil {
+			return nil, err
+		}
+	}
+	if p.cur.Type == TokenLimit {
+		p.nextToken()
+		if err := p.parseLimit(stmt); err != nil {
+			return nil, err
+		}
+	}
+	return stmt, nil
+}
+
+// parseTableRef parses a table name with an optional alias.
+func (p *Parser) parseTableRef() (string, string, error) {
+	table, err := p.parseIdent()
+	if err != nil {
+		return "", "", err
+	}
+	switch p.cur.Type {
+	case TokenAs:
+		p.nextToken()
+		alias, err := p.parseIdent()
+		return table, alias, err
+	case TokenIdent:
+		alias := p.cur.Literal
+		p.nextToken()
+		return table, alias, nil
+	}
+	return table, "", nil
+}
+
+// parseJoins parses the comma joins and [INNER] JOIN ... ON and
+// LEFT [OUTER] JOIN ... ON clauses after the first table.
+func (p *Parser) parseJoins() ([]JoinClause, error) {
+	var joins []JoinClause
+	for {
+		var join JoinClause
+		switch p.cur.Type {
+		case TokenComma:
+			p.nextToken()
+			table, alias, err := p.parseTableRef()
+			if err != nil {
+				return nil, err
+			}
+			joins = append(joins, JoinClause{Table: table, Alias: alias})
+			continue
+		case TokenInner:
+			p.nextToken()
+		case TokenLeft:
+			join.Left = true
+			p.nextToken()
+			if p.cur.Type == TokenOuter {
+				p.nextToken()
+			}
+		case TokenJoin:
+		default:
+			return joins, nil
+		}
+		if p.cur.Type != TokenJoin {
+			return nil, fmt.Errorf("pars: expected JOIN")
+		}
+		p.nextToken()
+		var err error
+		if join.Table, join.Alias, err = p.parseTableRef(); err != nil {
+			return nil, err
+		}
+		if p.cur.Type != TokenOn {
+			return nil, fmt.Errorf("pars: expected ON after JOIN %s", join.Table)
+		}
+		p.nextToken()
+		if join.On, err = p.parseExpr(); err != nil {
+			return nil, err
+		}
+		joins = append(joins, join)
+	}
+}
+
+// parseSelectList parses "*" or a list of expressions with optional aliases.
+func (p *Parser) parseSelectList(stmt *SelectStmt) error {
+	if p.cur.Type == TokenStar {
+		p.nextToken()
+		stmt.Columns = []string{"*"}
+		return nil
+	}
+	for {
+		start := p.cur.Pos
+		expr, err := p.parseExpr()
+		if err != nil {
+			return err
+		}
+		item := SelectItem{Expr: expr, Text: strings.TrimSpace(p.lexer.input[start:p.cur.Pos])}
+		switch p.cur.Type {
+		case TokenAs:
+			p.nextToken()
+			if item.Alias, err = p.parseIdent(); err != nil {
+				return err
+			}
+		case TokenIdent:
+			item.Alias = p.cur.Literal
+			p.nextToken()
+		}
+		stmt.Items = append(stmt.Items, item)
+		stmt.Columns = append(stmt.Columns, item.Name())
+		if p.cur.Type != TokenComma {
+			return nil
+		}
+		p.nextToken()
+	}
+}
 
-	return &SelectStmt{Columns: cols, Table: table, Where: where}, nil
+func (p *Parser) parseOrderBy() ([]OrderItem, error) {
+	var items []OrderItem
+	for {
+		expr, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		item := OrderItem{Expr: expr}
+		switch p.cur.Type {
+		case TokenAsc:
+			p.nextToken()
+		case TokenDesc:
+			item.Desc = true
+			p.nextToken()
+		}
+		items = append(items, item)
+		if p.cur.Type != TokenComma {
+			return items, nil
+		}
+		p.nextToken()
+	}
+}
+
+// parseLimit parses "LIMIT count", "LIMIT offset, count" and
+// "LIMIT count OFFSET offset".
+func (p *Parser) parseLimit(stmt *SelectStmt) error {
+	first, err := p.parseUnary()
+	if err != nil {
+		return err
+	}
+	switch {
+	case p.cur.Type == TokenComma:
+		p.nextToken()
+		stmt.Offset = first
+		stmt.Limit, err = p.parseUnary()
+	case p.isWord("OFFSET"):
+		p.nextToken()
+		stmt.Limit = first
+		stmt.Offset, err = p.parseUnary()
+	default:
+		stmt.Limit = first
+	}
+	return err
 }
 
 func (p *Parser) parseInsert() (Statement, error) {
@@ -169,26 +397,6 @@ func (p *Parser) parseDelete() (Statement, error) {
 	return &DeleteStmt{Table: table, Where: where}, nil
 }
 
-func (p *Parser) parseColumns() ([]string, error) {
-	if p.cur.Type == TokenStar {
-		p.nextToken()
-		return []string{"*"}, nil
-	}
-	var cols []string
-	for {
-		name, err := p.parseIdent()
-		if err != nil {
-			return nil, err
-		}
-		cols = append(cols, name)
-		if p.cur.Type != TokenComma {
-			break
-		}
-		p.nextToken()
-	}
-	return cols, nil
-}
-
 func (p *Parser) parseIdentList() ([]string, error) {
 	var cols []string
 	for {
@@ -267,14 +475,31 @@ func (p *Parser) parseOr() (Expr, error) {
 }
 
 func (p *Parser) parseAnd() (Expr, error) {
-	left, err := p.parseEquality()
+	left, err := p.parseComparison()
 	if err != nil {
 		return nil, err
 	}
 	for p.cur.Type == TokenAnd {
 		op := p.cur.Type
 		p.nextToken()
-		right, err := p.parseEquality()
+		right, err := p.parseComparison()
+		if err != nil {
+			return nil, err
+		}
+		left = BinaryExpr{Op: op, Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *Parser) parseComparison() (Expr, error) {
+	left, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+	if IsComparison(p.cur.Type) {
+		op := p.cur.Type
+		p.nextToken()
+		right, err := p.parseAdditive()
 		if err != nil {
 			return nil, err
 		}
@@ -283,15 +508,15 @@ func (p *Parser) parseAnd() (Expr, error) {
 	return left, nil
 }
 
-func (p *Parser) parseEquality() (Expr, error) {
-	left, err := p.parsePrimary()
+func (p *Parser) parseAdditive() (Expr, error) {
+	left, err := p.parseMultiplicative()
 	if err != nil {
 		return nil, err
 	}
-	if p.cur.Type == TokenEq {
+	for p.cur.Type == TokenPlus || p.cur.Type == TokenMinus {
 		op := p.cur.Type
 		p.nextToken()
-		right, err := p.parsePrimary()
+		right, err := p.parseMultiplicative()
 		if err != nil {
 			return nil, err
 		}
@@ -300,15 +525,107 @@ func (p *Parser) parseEquality() (Expr, error) {
 	return left, nil
 }
 
+func (p *Parser) parseMultiplicative() (Expr, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.cur.Type == TokenStar || p.cur.Type == TokenSlash || p.cur.Type == TokenPercent {
+		op := p.cur.Type
+		p.nextToken()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = BinaryExpr{Op: op, Left: left, Right: right}
+	}
+	return left, nil
+}
+
+// parseUnary parses a prefix sign. Negating an integer literal folds into the
+// literal so that -5 stays a constant.
+func (p *Parser) parseUnary() (Expr, error) {
+	switch p.cur.Type {
+	case TokenPlus:
+		p.nextToken()
+		return p.parseUnary()
+	case TokenMinus:
+		p.nextToken()
+		expr, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return negate(expr), nil
+	default:
+		return p.parsePrimary()
+	}
+}
+
+// negate returns -expr, folding the sign into an integer literal.
+func negate(expr Expr) Expr {
+	if lit, ok := expr.(LiteralExpr); ok && lit.Kind == TokenInt {
+		if strings.HasPrefix(lit.Value, "-") {
+			lit.Value = lit.Value[1:]
+		} else {
+			lit.Value = "-" + lit.Value
+		}
+		return lit
+	}
+	return UnaryExpr{Op: TokenMinus, Expr: expr}
+}
+
+// IsArithmetic reports whether op is one of the arithmetic operators.
+func IsArithmetic(op TokenType) bool {
+	switch op {
+	case TokenPlus, TokenMinus, TokenStar, TokenSlash, TokenPercent:
+		return true
+	default:
+		return false
+	}
+}
+
+// IsComparison reports whether op is one of the comparison operators.
+func IsComparison(op TokenType) bool {
+	switch op {
+	case TokenEq, TokenNe, TokenLt, TokenLe, TokenGt, TokenGe:
+		return true
+	default:
+		return false
+	}
+}
+
 func (p *Parser) parsePrimary() (Expr, error) {
 	switch p.cur.Type {
+	case TokenLParen:
+		p.nextToken()
+		expr, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		if p.cur.Type != TokenRParen {
+			return nil, fmt.Errorf("pars: expected )")
+		}
+		p.nextToken()
+		return expr, nil
 	case TokenIdent, TokenBoundID:
+		if p.cur.Type == TokenIdent && p.peek.Type == TokenLParen {
+			return p.parseFuncCall()
+		}
 		name, err := p.parseIdent()
 		if err != nil {
 			return nil, err
 		}
-		expr := IdentExpr{Name: name}
-		return expr, nil
+		if p.cur.Type == TokenDot && p.peek.Type != TokenDot {
+			p.nextToken()
+			col, err := p.parseIdent()
+			if err != nil {
+				return nil, err
+			}
+			name += "." + col
+		} else if attr, ok := p.parseCursorAttr(name); ok {
+			name = attr
+		}
+		return IdentExpr{Name: name}, nil
 	case TokenInt, TokenString, TokenNull:
 		expr := LiteralExpr{Value: p.cur.Literal, Kind: p.cur.Type}
 		p.nextToken()
@@ -322,6 +639,27 @@ func (p *Parser) parsePrimary() (Expr, error) {
 	}
 }
 
+func (p *Parser) parseFuncCall() (Expr, error) {
+	fn := FuncExpr{Name: strings.ToUpper(p.cur.Literal)}
+	p.nextToken()
+	p.nextToken()
+	if fn.Name == "COUNT" && p.cur.Type == TokenStar {
+		fn.Star = true
+		p.nextToken()
+	} else if p.cur.Type != TokenRParen {
+		args, err := p.parseExprList()
+		if err != nil {
+			return nil, err
+		}
+		fn.Args = args
+	}
+	if p.cur.Type != TokenRParen {
+		return nil, fmt.Errorf("pars: expected ) after arguments of %s", fn.Name)
+	}
+	p.nextToken()
+	return fn, nil
+}
+
 func (p *Parser) parseIdent() (string, error) {
 	switch p.cur.Type {
 	case TokenIdent:
@@ -338,6 +676,9 @@ func (p *Parser) parseIdent() (string, error) {
 }
 
 func (p *Parser) parseBoundLiteral(name string) (Expr, error) {
+	if p.params {
+		return ParamExpr{Name: name}, nil
+	}
 	if p.info == nil {
 		return nil, fmt.Errorf("pars: unbound literal %s", name)
 	}
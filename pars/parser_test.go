@@ -48,6 +48,119 @@ func TestParseSelectStar(t *testing.T) {
 	}
 }
 
+func TestParseSelectOrderLimit(t *testing.T) {
+	p := NewParser("SELECT id, price * (qty + 1) AS total, SUBSTR(name, 1, 2) short, -3 FROM t WHERE id > 1 ORDER BY total DESC, 1 LIMIT 5 OFFSET 2")
+	stmt, err := p.Parse()
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	sel := stmt.(*SelectStmt)
+	want := []string{"id", "total", "short", "-3"}
+	if len(sel.Columns) != len(want) || len(sel.Items) != len(want) {
+		t.Fatalf("columns=%v items=%d", sel.Columns, len(sel.Items))
+	}
+	for i, name := range want {
+		if sel.Columns[i] != name {
+			t.Fatalf("columns=%v", sel.Columns)
+		}
+	}
+	mul, ok := sel.Items[1].Expr.(BinaryExpr)
+	if !ok || mul.Op != TokenStar {
+		t.Fatalf("total expr=%#v", sel.Items[1].Expr)
+	}
+	if add, ok := mul.Right.(BinaryExpr); !ok || add.Op != TokenPlus {
+		t.Fatalf("expected parenthesised addition, got %#v", mul.Right)
+	}
+	if fn, ok := sel.Items[2].Expr.(FuncExpr); !ok || fn.Name != "SUBSTR" || len(fn.Args) != 3 {
+		t.Fatalf("short expr=%#v", sel.Items[2].Expr)
+	}
+	if lit, ok := sel.Items[3].Expr.(LiteralExpr); !ok || lit.Value != "-3" {
+		t.Fatalf("literal=%#v", sel.Items[3].Expr)
+	}
+	if len(sel.OrderBy) != 2 || !sel.OrderBy[0].Desc || sel.OrderBy[1].Desc {
+		t.Fatalf("order by=%+v", sel.OrderBy)
+	}
+	if lit, ok := sel.Limit.(LiteralExpr); !ok || lit.Value != "5" {
+		t.Fatalf("limit=%#v", sel.Limit)
+	}
+	if lit, ok := sel.Offset.(LiteralExpr); !ok || lit.Value != "2" {
+		t.Fatalf("offset=%#v", sel.Offset)
+	}
+
+	stmt, err = NewParser("SELECT * FROM t LIMIT 3, 4").Parse()
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	sel = stmt.(*SelectStmt)
+	if sel.Offset.(LiteralExpr).Value != "3" || sel.Limit.(LiteralExpr).Value != "4" {
+		t.Fatalf("limit=%#v offset=%#v", sel.Limit, sel.Offset)
+	}
+	if _, err := NewParser("SELECT * FROM t ORDER id").Parse(); err == nil {
+		t.Fatalf("expected error for ORDER without BY")
+	}
+}
+
+func TestParseSelectGroupBy(t *testing.T) {
+	stmt, err := NewParser("SELECT name, COUNT(*), SUM(qty) AS total FROM t WHERE qty > 0 GROUP BY name HAVING COUNT(*) > 1 ORDER BY total").Parse()
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	sel := stmt.(*SelectStmt)
+	if len(sel.GroupBy) != 1 || sel.Having == nil || len(sel.OrderBy) != 1 {
+		t.Fatalf("group=%v having=%v order=%v", sel.GroupBy, sel.Having, sel.OrderBy)
+	}
+	count, ok := sel.Items[1].Expr.(FuncExpr)
+	if !ok || count.Name != "COUNT" || !count.Star || len(count.Args) != 0 {
+		t.Fatalf("count=%#v", sel.Items[1].Expr)
+	}
+	if sel.Columns[1] != "COUNT(*)" || sel.Columns[2] != "total" {
+		t.Fatalf("columns=%v", sel.Columns)
+	}
+	if !HasAggregate(sel.Having) || HasAggregate(sel.Where) {
+		t.Fatalf("HasAggregate mismatch")
+	}
+	if _, err := NewParser("SELECT SUM(*) FROM t").Parse(); err == nil {
+		t.Fatalf("expected error for SUM(*)")
+	}
+}
+
+func TestParseSelectJoins(t *testing.T) {
+	stmt, err := NewParser(`SELECT o.id, c.name AS who FROM orders AS o JOIN "db/customers" c ON o.cust = c.id LEFT OUTER JOIN items i ON i.order_id = o.id, notes WHERE notes.order_id = o.id`).Parse()
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	sel := stmt.(*SelectStmt)
+	if sel.Table != "orders" || sel.Alias != "o" || len(sel.Joins) != 3 {
+		t.Fatalf("from=%s %s joins=%+v", sel.Table, sel.Alias, sel.Joins)
+	}
+	want := []JoinClause{
+		{Table: "db/customers", Alias: "c"},
+		{Table: "items", Alias: "i", Left: true},
+		{Table: "notes"},
+	}
+	for i, join := range sel.Joins {
+		if join.Table != want[i].Table || join.Alias != want[i].Alias || join.Left != want[i].Left || (join.On == nil) != (i == 2) {
+			t.Fatalf("join %d=%+v", i, join)
+		}
+	}
+	on := sel.Joins[0].On.(BinaryExpr)
+	if on.Left.(IdentExpr).Name != "o.cust" || on.Right.(IdentExpr).Name != "c.id" {
+		t.Fatalf("on=%#v", on)
+	}
+	if sel.Columns[0] != "id" || sel.Columns[1] != "who" {
+		t.Fatalf("columns=%v", sel.Columns)
+	}
+	for _, sql := range []string{
+		"SELECT * FROM a JOIN b",
+		"SELECT * FROM a LEFT b ON a.x = b.x",
+		"SELECT a. FROM a",
+	} {
+		if _, err := NewParser(sql).Parse(); err == nil {
+			t.Fatalf("%s: expected error", sql)
+		}
+	}
+}
+
 func TestParseError(t *testing.T) {
 	p := NewParser("UPDATE t")
 	if _, err := p.Parse(); err == nil {
@@ -114,3 +227,22 @@ func TestParseDelete(t *testing.T) {
 		t.Fatalf("expected where")
 	}
 }
+
+func TestParseExplain(t *testing.T) {
+	stmt, err := ParseSQL("EXPLAIN SELECT id FROM t WHERE id = 1")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	explain, ok := stmt.(*ExplainStmt)
+	if !ok {
+		t.Fatalf("expected ExplainStmt, got %T", stmt)
+	}
+	if sel, ok := explain.Stmt.(*SelectStmt); !ok || sel.Table != "t" {
+		t.Fatalf("explained=%+v", explain.Stmt)
+	}
+	for _, sql := range []string{"EXPLAIN", "EXPLAIN EXPLAIN SELECT id FROM t", "EXPLAIN DROP TABLE t"} {
+		if _, err := ParseSQL(sql); err == nil {
+			t.Fatalf("%s: expected error", sql)
+		}
+	}
+}
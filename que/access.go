@@ -0,0 +1,37 @@
+package que
+
+import (
+	"github.com/wilhasse/innodb-go/data"
+	"github.com/wilhasse/innodb-go/eval"
+	"github.com/wilhasse/innodb-go/pars"
+)
+
+// RowAccess performs row reads and changes for graph nodes. Nodes built for a
+// table context with an accessor route every row operation through it so the
+// caller can apply visibility, locking and undo; without one they work on the
+// store directly.
+type RowAccess interface {
+	// Scan calls fn for each row visible to the accessor.
+	Scan(fn func(row *data.Tuple) error) error
+	Insert(row *data.Tuple) error
+	Update(oldRow, newRow *data.Tuple) error
+	Delete(row *data.Tuple) error
+}
+
+// FieldEncoder converts a literal assigned to or compared with column col into
+// the column's stored form.
+type FieldEncoder func(col int, lit pars.LiteralExpr) (data.Field, error)
+
+// FieldDecoder converts the stored field of column col into the value that
+// computed expressions operate on.
+type FieldDecoder func(col int, field data.Field) (eval.Value, error)
+
+// ValueEncoder converts a computed value into a result field.
+type ValueEncoder func(val eval.Value) (data.Field, error)
+
+// IndexAccess is implemented by accessors that can read the rows of an index
+// path directly. Accessors without it fall back to Scan, and a path may be
+// answered with extra rows since nodes still apply the full predicate.
+type IndexAccess interface {
+	ScanIndex(path *AccessPath, fn func(row *data.Tuple) error) error
+}
@@ -16,8 +16,10 @@ var ErrDeleteNotFound = errors.New("que: delete target not found")
 // InsertNode executes a row insertion.
 type InsertNode struct {
 	BaseNode
-	Store *row.Store
-	Tuple *data.Tuple
+	Store    *row.Store
+	Tuple    *data.Tuple
+	Access   RowAccess
+	Affected int
 }
 
 // NewInsertNode constructs an insert node.
@@ -31,18 +33,35 @@ func NewInsertNode(parent Node, store *row.Store, tuple *data.Tuple) *InsertNode
 
 // Execute runs the insert node.
 func (n *InsertNode) Execute(_ *Thr) error {
-	if n == nil || n.Store == nil || n.Tuple == nil {
+	if n == nil || n.Tuple == nil || (n.Store == nil && n.Access == nil) {
 		return ErrInvalidDMLNode
 	}
-	return n.Store.Insert(n.Tuple)
+	n.Affected = 0
+	var err error
+	if n.Access != nil {
+		err = n.Access.Insert(n.Tuple)
+	} else {
+		err = n.Store.Insert(n.Tuple)
+	}
+	if err != nil {
+		return err
+	}
+	n.Affected = 1
+	return nil
 }
 
-// UpdateNode executes a row update.
+// UpdateNode executes a row update. With an accessor it updates every row
+// matching Predicate using Assign; otherwise it replaces OldTuple.
 type UpdateNode struct {
 	BaseNode
-	Store    *row.Store
-	OldTuple *data.Tuple
-	NewTuple *data.Tuple
+	Store     *row.Store
+	OldTuple  *data.Tuple
+	NewTuple  *data.Tuple
+	Access    RowAccess
+	Predicate func(*data.Tuple) bool
+	Path      *AccessPath
+	Assign    func(*data.Tuple) (*data.Tuple, error)
+	Affected  int
 }
 
 // NewUpdateNode constructs an update node.
@@ -57,17 +76,51 @@ func NewUpdateNode(parent Node, store *row.Store, oldTuple, newTuple *data.Tuple
 
 // Execute runs the update node.
 func (n *UpdateNode) Execute(_ *Thr) error {
+	if n != nil && n.Access != nil {
+		return n.executeAccess()
+	}
 	if n == nil || n.Store == nil || n.OldTuple == nil || n.NewTuple == nil {
 		return ErrInvalidDMLNode
 	}
-	return n.Store.ReplaceTuple(n.OldTuple, n.NewTuple)
+	if err := n.Store.ReplaceTuple(n.OldTuple, n.NewTuple); err != nil {
+		return err
+	}
+	n.Affected = 1
+	return nil
+}
+
+func (n *UpdateNode) executeAccess() error {
+	if n.Assign == nil {
+		return ErrInvalidDMLNode
+	}
+	n.Affected = 0
+	targets, err := matchingRows(n.Access, n.Path, n.Predicate)
+	if err != nil {
+		return err
+	}
+	for _, target := range targets {
+		next, err := n.Assign(target)
+		if err != nil {
+			return err
+		}
+		if err := n.Access.Update(target, next); err != nil {
+			return err
+		}
+		n.Affected++
+	}
+	return nil
 }
 
-// DeleteNode executes a row deletion.
+// DeleteNode executes a row deletion. With an accessor it deletes every row
+// matching Predicate; otherwise it removes Tuple.
 type DeleteNode struct {
 	BaseNode
-	Store *row.Store
-	Tuple *data.Tuple
+	Store     *row.Store
+	Tuple     *data.Tuple
+	Access    RowAccess
+	Predicate func(*data.Tuple) bool
+	Path      *AccessPath
+	Affected  int
 }
 
 // NewDeleteNode constructs a delete node.
@@ -81,11 +134,41 @@ func NewDeleteNode(parent Node, store *row.Store, tuple *data.Tuple) *DeleteNode
 
 // Execute runs the delete node.
 func (n *DeleteNode) Execute(_ *Thr) error {
+	if n != nil && n.Access != nil {
+		return n.executeAccess()
+	}
 	if n == nil || n.Store == nil || n.Tuple == nil {
 		return ErrInvalidDMLNode
 	}
 	if !n.Store.RemoveTuple(n.Tuple) {
 		return ErrDeleteNotFound
 	}
+	n.Affected = 1
+	return nil
+}
+
+func (n *DeleteNode) executeAccess() error {
+	n.Affected = 0
+	targets, err := matchingRows(n.Access, n.Path, n.Predicate)
+	if err != nil {
+		return err
+	}
+	for _, target := range targets {
+		if err := n.Access.Delete(target); err != nil {
+			return err
+		}
+		n.Affected++
+	}
 	return nil
 }
+
+// matchingRows collects the rows to change before changing any of them, so
+// an update never revisits a row it has already moved.
+func matchingRows(access RowAccess, path *AccessPath, pred func(*data.Tuple) bool) ([]*data.Tuple, error) {
+	var rows []*data.Tuple
+	err := scanRows(nil, access, path, pred, func(row *data.Tuple) error {
+		rows = append(rows, row)
+		return nil
+	})
+	return rows, err
+}
@@ -21,6 +21,11 @@ func ThrStep(thr *Thr) error {
 	}
 	if thr.RunNode == nil {
 		thr.RunNode = thr.Child
+		if err := thr.bindParams(); err != nil {
+			thr.State = ThrError
+			thr.IsActive = false
+			return err
+		}
 	}
 	if thr.RunNode == nil {
 		thr.State = ThrCompleted
@@ -50,6 +55,15 @@ func ThrStep(thr *Thr) error {
 	return nil
 }
 
+// bindParams reads the literals of a graph built with BuildContext.Params
+// into its statement node.
+func (thr *Thr) bindParams() error {
+	if thr.bind == nil || thr.Graph == nil || thr.Graph.Info == nil {
+		return nil
+	}
+	return thr.bind(thr.Graph.Info)
+}
+
 // ThrRun executes a thread until completion or error.
 func ThrRun(thr *Thr) error {
 	for thr != nil && thr.State != ThrCompleted && thr.State != ThrError {
@@ -60,6 +74,24 @@ func ThrRun(thr *Thr) error {
 	return nil
 }
 
+// ForkStartCommand rewinds a fork that has already run so that ForkRun
+// executes its threads again from their first node.
+func ForkStartCommand(fork *Fork) {
+	if fork == nil {
+		return
+	}
+	for _, thr := range fork.Threads {
+		if thr == nil {
+			continue
+		}
+		thr.State = ThrCommandWait
+		thr.IsActive = false
+		thr.RunNode = nil
+		thr.PrevNode = nil
+	}
+	fork.State = ForkCommandWait
+}
+
 // ForkRun executes all threads in the fork sequentially.
 func ForkRun(fork *Fork) error {
 	if fork == nil {
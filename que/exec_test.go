@@ -48,3 +48,29 @@ func makeTuple(values ...string) *data.Tuple {
 	}
 	return tuple
 }
+
+func TestForkStartCommandReruns(t *testing.T) {
+	store := row.NewStore(0)
+	graph := ForkCreate(nil, nil, ForkExecute)
+	thr := ThrCreate(graph)
+	insert := NewInsertNode(thr, store, makeTuple("1", "a"))
+	thr.Child = insert
+
+	if err := ForkRun(graph); err != nil {
+		t.Fatalf("ForkRun: %v", err)
+	}
+	if err := ForkRun(graph); err != nil || len(store.Rows) != 1 {
+		t.Fatalf("completed graph ran again: err=%v rows=%d", err, len(store.Rows))
+	}
+	ForkStartCommand(graph)
+	if thr.State != ThrCommandWait || thr.RunNode != nil {
+		t.Fatalf("thr state=%v run=%v", thr.State, thr.RunNode)
+	}
+	insert.Tuple = makeTuple("2", "b")
+	if err := ForkRun(graph); err != nil {
+		t.Fatalf("ForkRun again: %v", err)
+	}
+	if len(store.Rows) != 2 || thr.State != ThrCompleted {
+		t.Fatalf("rows=%d state=%v", len(store.Rows), thr.State)
+	}
+}
@@ -0,0 +1,284 @@
+package que
+
+import (
+	"fmt"
+
+	"github.com/wilhasse/innodb-go/btr"
+	"github.com/wilhasse/innodb-go/data"
+	"github.com/wilhasse/innodb-go/eval"
+	"github.com/wilhasse/innodb-go/pars"
+	"github.com/wilhasse/innodb-go/row"
+)
+
+// ExplainNode returns the plan of a built statement as rows instead of
+// running it. Each execution lists the tables in join order with the access
+// method, index and number of key columns used and the rows the access is
+// estimated to read, followed by the group, sort and limit steps.
+type ExplainNode struct {
+	BaseNode
+	// Plan is the statement node explained; it is never executed.
+	Plan Node
+	// Tables names the tables of Plan in join order.
+	Tables []string
+	Output []Projection
+	Steps  []PlanStep
+	Rows   []*data.Tuple
+	// Result stores the step values; nil stores their text.
+	Result ValueEncoder
+}
+
+// PlanStep is one row of a plan. Steps that read no table leave Table,
+// Access and Index empty and report no key columns or rows.
+type PlanStep struct {
+	ID        int
+	Operation string
+	Table     string
+	Access    string
+	Index     string
+	KeyParts  int
+	Rows      int
+	Extra     string
+}
+
+// explainColumns are the output columns of an explain and their kinds.
+var explainColumns = []Projection{
+	{Name: "id", Source: -1, Kind: eval.KindInt},
+	{Name: "operation", Source: -1, Kind: eval.KindBytes},
+	{Name: "table", Source: -1, Kind: eval.KindBytes},
+	{Name: "access", Source: -1, Kind: eval.KindBytes},
+	{Name: "index", Source: -1, Kind: eval.KindBytes},
+	{Name: "key_parts", Source: -1, Kind: eval.KindInt},
+	{Name: "rows", Source: -1, Kind: eval.KindInt},
+	{Name: "extra", Source: -1, Kind: eval.KindBytes},
+}
+
+func buildExplainNode(stmt *pars.ExplainStmt, parent Node, ctx *BuildContext) (Node, error) {
+	if _, ok := stmt.Stmt.(*pars.ProcedureStmt); ok {
+		return nil, ErrInvalidStatement
+	}
+	node := &ExplainNode{
+		BaseNode: NewBaseNode(NodeStatement, parent),
+		Output:   explainColumns,
+	}
+	plan, err := buildNode(stmt.Stmt, node, ctx)
+	if err != nil {
+		return nil, err
+	}
+	node.Plan = plan
+	node.Tables = explainTables(stmt.Stmt)
+	if table, err := tableContext(ctx, StatementTables(stmt.Stmt)[0]); err == nil {
+		node.Result = table.Result
+	}
+	return node, nil
+}
+
+// explainTables names the tables of stmt as the plan shows them: by alias
+// when one is given.
+func explainTables(stmt pars.Statement) []string {
+	sel, ok := stmt.(*pars.SelectStmt)
+	if !ok {
+		return StatementTables(stmt)
+	}
+	name := func(table, alias string) string {
+		if alias != "" {
+			return alias
+		}
+		return table
+	}
+	names := []string{name(sel.Table, sel.Alias)}
+	for _, join := range sel.Joins {
+		names = append(names, name(join.Table, join.Alias))
+	}
+	return names
+}
+
+// Execute computes the plan steps and their rows.
+func (n *ExplainNode) Execute(_ *Thr) error {
+	if n == nil || n.Plan == nil {
+		return ErrInvalidStatement
+	}
+	n.Steps = nil
+	switch plan := n.Plan.(type) {
+	case *SelectNode:
+		n.explainSelect(plan)
+	case *InsertNode:
+		n.addStep(PlanStep{Operation: "insert", Table: n.table(0), Rows: 1})
+	case *UpdateNode:
+		n.addStep(tableStep("update", n.table(0), plan.Store, plan.Path))
+	case *DeleteNode:
+		n.addStep(tableStep("delete", n.table(0), plan.Store, plan.Path))
+	default:
+		return ErrInvalidStatement
+	}
+	n.Rows = nil
+	for _, step := range n.Steps {
+		out, err := n.stepRow(step)
+		if err != nil {
+			return err
+		}
+		n.Rows = append(n.Rows, out)
+	}
+	return nil
+}
+
+func (n *ExplainNode) explainSelect(sel *SelectNode) {
+	scan := tableStep("scan", n.table(0), sel.Store, sel.Path)
+	if sel.Sorted {
+		scan.Extra = "ordered by index"
+	}
+	n.addStep(scan)
+	for i, join := range sel.Joins {
+		step := PlanStep{Operation: "join", Table: n.table(i + 1), Access: "full scan"}
+		if join.Left {
+			step.Operation = "left join"
+		}
+		step.Rows = estimateRows(join.Store, nil)
+		if join.Index != nil {
+			step.Access = "index lookup"
+			step.Index = join.Index.Name
+			step.KeyParts = len(join.Keys)
+			if join.Index.Unique && len(join.Keys) == len(join.Index.Columns) {
+				step.Rows = 1
+			}
+		}
+		n.addStep(step)
+	}
+	if sel.Group != nil {
+		step := PlanStep{Operation: "group"}
+		if sel.Having != nil {
+			step.Extra = "having"
+		}
+		n.addStep(step)
+	}
+	if len(sel.Order) > 0 && !sel.Sorted {
+		n.addStep(PlanStep{Operation: "sort", Extra: fmt.Sprintf("%d keys", len(sel.Order))})
+	}
+	if sel.Limit >= 0 || sel.Offset > 0 {
+		step := PlanStep{Operation: "limit"}
+		switch {
+		case sel.Limit < 0:
+			step.Extra = fmt.Sprintf("offset %d", sel.Offset)
+		case sel.Offset > 0:
+			step.Extra = fmt.Sprintf("limit %d offset %d", sel.Limit, sel.Offset)
+		default:
+			step.Extra = fmt.Sprintf("limit %d", sel.Limit)
+		}
+		n.addStep(step)
+	}
+}
+
+func (n *ExplainNode) table(i int) string {
+	if i < len(n.Tables) {
+		return n.Tables[i]
+	}
+	return ""
+}
+
+func (n *ExplainNode) addStep(step PlanStep) {
+	step.ID = len(n.Steps) + 1
+	n.Steps = append(n.Steps, step)
+}
+
+func (n *ExplainNode) stepRow(step PlanStep) (*data.Tuple, error) {
+	text := func(s string) eval.Value {
+		if s == "" {
+			return eval.Value{Kind: eval.KindNull}
+		}
+		return eval.Value{Kind: eval.KindBytes, Bytes: []byte(s)}
+	}
+	vals := []eval.Value{
+		{Kind: eval.KindInt, Int: int64(step.ID)},
+		text(step.Operation),
+		text(step.Table),
+		text(step.Access),
+		text(step.Index),
+		{Kind: eval.KindNull},
+		{Kind: eval.KindNull},
+		text(step.Extra),
+	}
+	if step.Table != "" {
+		vals[5] = eval.Value{Kind: eval.KindInt, Int: int64(step.KeyParts)}
+		vals[6] = eval.Value{Kind: eval.KindInt, Int: int64(step.Rows)}
+	}
+	out := data.NewTuple(len(vals))
+	for i, val := range vals {
+		if n.Result == nil {
+			out.Fields[i] = eval.ValueToField(val)
+			continue
+		}
+		var err error
+		if out.Fields[i], err = n.Result(val); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// tableStep describes reading the table of store along path.
+func tableStep(operation, table string, store *row.Store, path *AccessPath) PlanStep {
+	step := PlanStep{Operation: operation, Table: table, Access: "full scan"}
+	if path != nil && path.Index != nil {
+		switch path.Kind {
+		case AccessIndexSeek:
+			step.Access = "index seek"
+			step.KeyParts = path.Equal
+		case AccessIndexRange:
+			step.Access = "index range"
+			step.KeyParts = path.Equal + 1
+		case AccessIndexScan:
+			step.Access = "index scan"
+		}
+		if path.Kind != AccessFullScan {
+			step.Index = path.Index.Name
+		}
+	}
+	step.Rows = estimateRows(store, path)
+	return step
+}
+
+// keyAfterPrefix sorts after every stored key that starts with the key
+// fields before it: CompareKeys compares a trailing partial length by its
+// bytes, which no stored length other than NULL reaches.
+var keyAfterPrefix = []byte{0xff, 0xff, 0xff}
+
+// estimateRows estimates with btr.EstimateNRowsInRange the rows of store
+// that path reads; a nil path or a path without bounds reads every row.
+func estimateRows(store *row.Store, path *AccessPath) int {
+	if store == nil {
+		return 0
+	}
+	tree := store.Tree
+	var sec *row.SecondaryIndex
+	if path != nil && path.Index != nil && path.Kind != AccessFullScan && !path.Index.Clustered {
+		if sec = store.SecondaryIndex(path.Index.Name); sec == nil || sec.Tree == nil {
+			return estimateRows(store, nil)
+		}
+		if err := store.MergeSecondaryIndexBuffer(sec); err != nil {
+			return estimateRows(store, nil)
+		}
+		tree = sec.Tree
+	}
+	if path == nil || path.Index == nil || path.Kind == AccessFullScan || path.Kind == AccessIndexScan {
+		return btr.EstimateNRowsInRange(tree, nil, keyAfterPrefix)
+	}
+	key := func(bound *KeyBound) []byte {
+		tpl := boundTuple(path.Index, bound.Fields, 0)
+		if sec != nil {
+			return store.KeyForSecondarySearch(sec, tpl, len(bound.Fields))
+		}
+		return store.KeyForSearch(tpl, len(bound.Fields))
+	}
+	var low []byte
+	high := keyAfterPrefix
+	if path.Lower != nil {
+		if low = key(path.Lower); !path.Lower.Inclusive {
+			low = append(low, keyAfterPrefix...)
+		}
+	}
+	if path.Upper != nil {
+		if high = key(path.Upper); path.Upper.Inclusive {
+			high = append(high, keyAfterPrefix...)
+		}
+	}
+	return btr.EstimateNRowsInRange(tree, low, high)
+}
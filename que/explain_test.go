@@ -0,0 +1,67 @@
+package que
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/wilhasse/innodb-go/pars"
+)
+
+func explainRows(t *testing.T, ctx *BuildContext, sql string) []string {
+	t.Helper()
+	stmt, err := pars.ParseSQL(sql)
+	if err != nil {
+		t.Fatalf("parse %q: %v", sql, err)
+	}
+	graph, err := BuildGraph(stmt, ctx)
+	if err != nil {
+		t.Fatalf("BuildGraph %q: %v", sql, err)
+	}
+	node := ForkGetFirstThr(graph).Child.(*ExplainNode)
+	if err := ForkRun(graph); err != nil {
+		t.Fatalf("ForkRun %q: %v", sql, err)
+	}
+	return rowStrings(node.Rows)
+}
+
+func TestExplain(t *testing.T) {
+	ctx := newJoinTestContext(t)
+	tests := []struct {
+		sql  string
+		want []string
+	}{
+		{"EXPLAIN SELECT * FROM t WHERE id = 3", []string{
+			"1,scan,t,index seek,PRIMARY,1,1,NULL",
+		}},
+		{"EXPLAIN SELECT * FROM t WHERE id > 2 AND id <= 4", []string{
+			"1,scan,t,index range,PRIMARY,1,2,NULL",
+		}},
+		{"EXPLAIN SELECT id FROM t WHERE name = 'b' LIMIT 1 OFFSET 1", []string{
+			"1,scan,t,index seek,idx_name,1,2,NULL",
+			"2,limit,NULL,NULL,NULL,NULL,NULL,limit 1 offset 1",
+		}},
+		{"EXPLAIN SELECT name FROM t ORDER BY name", []string{
+			"1,scan,t,index scan,idx_name,0,5,ordered by index",
+		}},
+		{"EXPLAIN SELECT a.id, oid FROM t a LEFT JOIN o ON tid = a.id ORDER BY oid", []string{
+			"1,scan,a,full scan,NULL,0,5,NULL",
+			"2,left join,o,index lookup,idx_tid,1,4,NULL",
+			"3,sort,NULL,NULL,NULL,NULL,NULL,1 keys",
+		}},
+		{"EXPLAIN SELECT name, COUNT(*) FROM t GROUP BY name HAVING COUNT(*) > 1", []string{
+			"1,scan,t,full scan,NULL,0,5,NULL",
+			"2,group,NULL,NULL,NULL,NULL,NULL,having",
+		}},
+		{"EXPLAIN INSERT INTO t VALUES (9, 'z')", []string{
+			"1,insert,t,NULL,NULL,0,1,NULL",
+		}},
+	}
+	for _, tt := range tests {
+		if got := explainRows(t, ctx, tt.sql); strings.Join(got, "|") != strings.Join(tt.want, "|") {
+			t.Fatalf("%s:\n got %q\nwant %q", tt.sql, got, tt.want)
+		}
+	}
+	if got := strings.Join(groupRows(t, ctx, "SELECT id FROM t WHERE id = 9"), "|"); got != "" {
+		t.Fatalf("explain ran the insert: %q", got)
+	}
+}
@@ -0,0 +1,187 @@
+package que
+
+import (
+	"encoding/binary"
+	"math"
+	"strconv"
+
+	"github.com/wilhasse/innodb-go/data"
+	"github.com/wilhasse/innodb-go/eval"
+)
+
+// AggregateCall is one aggregate function of a grouped select. Arg evaluates
+// the argument on a table row and is nil for COUNT(*). Kind is the kind of
+// the result stored in the group row.
+type AggregateCall struct {
+	Name string
+	Star bool
+	Arg  func(row *data.Tuple) (eval.Value, error)
+	Kind eval.ValueKind
+}
+
+// GroupNode groups the rows of a select scan by hashing the values of Keys
+// and accumulates Aggregates per group. Rows are fed one at a time with Add.
+// Each group row holds the first table row of the group in its first Width
+// fields, followed by one field per aggregate.
+type GroupNode struct {
+	BaseNode
+	Keys       []func(row *data.Tuple) (eval.Value, error)
+	Aggregates []AggregateCall
+	Width      int
+	groups     map[string]*rowGroup
+	order      []*rowGroup
+}
+
+type rowGroup struct {
+	first *data.Tuple
+	aggs  []*eval.Aggregate
+}
+
+// NewGroupNode constructs a grouping node for a table of width columns.
+func NewGroupNode(parent Node, width int) *GroupNode {
+	return &GroupNode{
+		BaseNode: NewBaseNode(NodeStatement, parent),
+		Width:    width,
+	}
+}
+
+// Reset discards the groups collected so far.
+func (n *GroupNode) Reset() {
+	n.groups = nil
+	n.order = nil
+}
+
+// Add assigns row to its group and feeds it to the group's aggregates. Rows
+// whose keys are all equal, NULLs included, share a group.
+func (n *GroupNode) Add(row *data.Tuple) error {
+	var key []byte
+	for _, fn := range n.Keys {
+		val, err := fn(row)
+		if err != nil {
+			return err
+		}
+		key = appendGroupKey(key, val)
+	}
+	if n.groups == nil {
+		n.groups = make(map[string]*rowGroup)
+	}
+	group := n.groups[string(key)]
+	if group == nil {
+		var err error
+		if group, err = n.newGroup(row); err != nil {
+			return err
+		}
+		n.groups[string(key)] = group
+		n.order = append(n.order, group)
+	}
+	for i, call := range n.Aggregates {
+		var val eval.Value
+		if call.Arg != nil {
+			var err error
+			if val, err = call.Arg(row); err != nil {
+				return err
+			}
+		}
+		if err := group.aggs[i].Add(val); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (n *GroupNode) newGroup(first *data.Tuple) (*rowGroup, error) {
+	group := &rowGroup{first: first, aggs: make([]*eval.Aggregate, len(n.Aggregates))}
+	for i, call := range n.Aggregates {
+		agg, err := eval.NewAggregate(call.Name, call.Star)
+		if err != nil {
+			return nil, err
+		}
+		group.aggs[i] = agg
+	}
+	return group, nil
+}
+
+// Rows returns one row per group in the order the groups were first seen.
+// Without group keys there is always exactly one group, so aggregating no
+// rows yields a single row with NULL table columns.
+func (n *GroupNode) Rows() ([]*data.Tuple, error) {
+	if len(n.order) == 0 && len(n.Keys) == 0 {
+		empty := data.NewTuple(n.Width)
+		for i := range empty.Fields {
+			empty.Fields[i].Len = data.UnivSQLNull
+		}
+		group, err := n.newGroup(empty)
+		if err != nil {
+			return nil, err
+		}
+		n.order = append(n.order, group)
+	}
+	rows := make([]*data.Tuple, 0, len(n.order))
+	for _, group := range n.order {
+		out := data.NewTuple(n.Width + len(n.Aggregates))
+		for i := 0; i < n.Width; i++ {
+			if group.first != nil && i < len(group.first.Fields) {
+				out.Fields[i] = group.first.Fields[i]
+			} else {
+				out.Fields[i].Len = data.UnivSQLNull
+			}
+		}
+		for i, agg := range group.aggs {
+			val, err := coerceValue(agg.Result(), n.Aggregates[i].Kind)
+			if err != nil {
+				return nil, err
+			}
+			out.Fields[n.Width+i] = slotField(val)
+		}
+		rows = append(rows, out)
+	}
+	return rows, nil
+}
+
+// appendGroupKey appends a self-delimiting encoding of val to key.
+func appendGroupKey(key []byte, val eval.Value) []byte {
+	key = append(key, byte(val.Kind))
+	var payload []byte
+	switch val.Kind {
+	case eval.KindNull:
+		return key
+	case eval.KindBytes:
+		payload = val.Bytes
+	default:
+		payload = eval.ValueToField(val).Data
+	}
+	key = strconv.AppendInt(key, int64(len(payload)), 10)
+	key = append(key, ':')
+	return append(key, payload...)
+}
+
+// slotField stores an aggregate result in a group row field; slotValue reads
+// it back given the slot kind.
+func slotField(val eval.Value) data.Field {
+	var buf []byte
+	switch val.Kind {
+	case eval.KindNull:
+		return data.Field{Len: data.UnivSQLNull}
+	case eval.KindInt:
+		buf = binary.BigEndian.AppendUint64(nil, uint64(val.Int))
+	case eval.KindFloat:
+		buf = binary.BigEndian.AppendUint64(nil, math.Float64bits(val.Float))
+	default:
+		return eval.ValueToField(val)
+	}
+	return data.Field{Data: buf, Len: uint32(len(buf))}
+}
+
+func slotValue(field data.Field, kind eval.ValueKind) eval.Value {
+	if data.FieldIsNull(&field) {
+		return eval.Value{Kind: eval.KindNull}
+	}
+	switch kind {
+	case eval.KindInt:
+		return eval.Value{Kind: eval.KindInt, Int: int64(binary.BigEndian.Uint64(field.Data))}
+	case eval.KindFloat:
+		return eval.Value{Kind: eval.KindFloat, Float: math.Float64frombits(binary.BigEndian.Uint64(field.Data))}
+	default:
+		return eval.Value{Kind: eval.KindBytes, Bytes: field.Data}
+	}
+}
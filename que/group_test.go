@@ -0,0 +1,89 @@
+package que
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/wilhasse/innodb-go/data"
+	"github.com/wilhasse/innodb-go/pars"
+)
+
+func groupRows(t *testing.T, ctx *BuildContext, sql string) []string {
+	t.Helper()
+	stmt, err := pars.ParseSQL(sql)
+	if err != nil {
+		t.Fatalf("parse %q: %v", sql, err)
+	}
+	graph, err := BuildGraph(stmt, ctx)
+	if err != nil {
+		t.Fatalf("BuildGraph %q: %v", sql, err)
+	}
+	node := ForkGetFirstThr(graph).Child.(*SelectNode)
+	if err := ForkRun(graph); err != nil {
+		t.Fatalf("ForkRun %q: %v", sql, err)
+	}
+	return rowStrings(node.Rows)
+}
+
+// rowStrings renders rows as comma separated field text, NULL for nulls.
+func rowStrings(rows []*data.Tuple) []string {
+	out := make([]string, len(rows))
+	for i, r := range rows {
+		vals := make([]string, len(r.Fields))
+		for j := range r.Fields {
+			if data.FieldIsNull(&r.Fields[j]) {
+				vals[j] = "NULL"
+			} else {
+				vals[j] = string(r.Fields[j].Data)
+			}
+		}
+		out[i] = strings.Join(vals, ",")
+	}
+	return out
+}
+
+func TestSelectGroupBy(t *testing.T) {
+	ctx := newIndexedTestContext(t)
+	null := makeSQLTuple("6", "")
+	null.Fields[1].Len = data.UnivSQLNull
+	if err := ctx.Tables["t"].Store.Insert(null); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+	tests := []struct {
+		sql  string
+		want string
+	}{
+		{"SELECT name, COUNT(*), SUM(id), MIN(id), MAX(id), AVG(id) FROM t WHERE id < 6 GROUP BY name ORDER BY name",
+			"a,1,3,3,3,3|b,2,6,2,4,3|c,1,5,5,5,5|d,1,1,1,1,1"},
+		{"SELECT name, COUNT(*) AS n FROM t GROUP BY name HAVING COUNT(*) > 1", "b,2"},
+		{"SELECT COUNT(*), COUNT(name), MIN(name), MAX(name) FROM t", "6,5,a,d"},
+		{"SELECT name, COUNT(*) FROM t GROUP BY name ORDER BY name LIMIT 1", "NULL,1"},
+		{"SELECT COUNT(*), SUM(id), AVG(id) FROM t WHERE name = 'z'", "0,NULL,NULL"},
+		{"SELECT name, COUNT(*) FROM t WHERE name = 'z' GROUP BY name", ""},
+		{"SELECT id % 2 AS odd, COUNT(*) FROM t GROUP BY id % 2 ORDER BY odd DESC", "1,3|0,3"},
+		{"SELECT name FROM t WHERE id < 6 GROUP BY name ORDER BY COUNT(*) DESC, name LIMIT 2", "b|a"},
+		{"SELECT SUM(id) * 2 + COUNT(*) FROM t", "48"},
+	}
+	for _, tt := range tests {
+		if got := strings.Join(groupRows(t, ctx, tt.sql), "|"); got != tt.want {
+			t.Fatalf("%s: rows=%q want %q", tt.sql, got, tt.want)
+		}
+	}
+
+	for _, sql := range []string{
+		"SELECT id, COUNT(*) FROM t GROUP BY name",
+		"SELECT name FROM t GROUP BY name HAVING id > 1",
+		"SELECT SUM(COUNT(*)) FROM t",
+		"SELECT * FROM t WHERE COUNT(*) > 1",
+		"SELECT name FROM t GROUP BY COUNT(*)",
+		"SELECT SUM(id, name) FROM t",
+	} {
+		stmt, err := pars.ParseSQL(sql)
+		if err != nil {
+			t.Fatalf("parse %q: %v", sql, err)
+		}
+		if _, err := BuildGraph(stmt, ctx); err == nil {
+			t.Fatalf("%s: expected build error", sql)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package que
+
+import (
+	"errors"
+
+	"github.com/wilhasse/innodb-go/btr"
+	"github.com/wilhasse/innodb-go/data"
+	"github.com/wilhasse/innodb-go/row"
+)
+
+// errStopScan ends a row scan early without reporting an error.
+var errStopScan = errors.New("que: stop scan")
+
+// scanRows reads the rows on path, through access when set and from store
+// otherwise, and passes the rows matching pred to fn.
+func scanRows(store *row.Store, access RowAccess, path *AccessPath, pred func(*data.Tuple) bool, fn func(*data.Tuple) error) error {
+	visit := func(r *data.Tuple) error {
+		if pred == nil || pred(r) {
+			return fn(r)
+		}
+		return nil
+	}
+	indexed := path != nil && path.Kind != AccessFullScan && path.Index != nil
+	if access != nil {
+		if ia, ok := access.(IndexAccess); ok && indexed {
+			return ia.ScanIndex(path, visit)
+		}
+		return access.Scan(visit)
+	}
+	if indexed {
+		return scanStoreIndex(store, path, visit)
+	}
+	for _, r := range store.Rows {
+		if r == nil {
+			continue
+		}
+		if err := visit(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// scanStoreIndex walks the index tree of path from its lower bound, looking
+// each entry up by row ID, until a row passes the upper bound.
+func scanStoreIndex(store *row.Store, path *AccessPath, fn func(*data.Tuple) error) error {
+	index := path.Index
+	var sec *row.SecondaryIndex
+	if !index.Clustered {
+		if sec = store.SecondaryIndex(index.Name); sec == nil || sec.Tree == nil {
+			return scanRows(store, nil, nil, nil, fn)
+		}
+		if err := store.MergeSecondaryIndexBuffer(sec); err != nil {
+			return err
+		}
+	}
+	var lowerKey []byte
+	if path.Lower != nil {
+		tpl := boundTuple(index, path.Lower.Fields, 0)
+		if sec != nil {
+			lowerKey = store.KeyForSecondarySearch(sec, tpl, len(path.Lower.Fields))
+		} else {
+			lowerKey = store.KeyForSearch(tpl, len(path.Lower.Fields))
+		}
+	}
+	var visitErr error
+	visit := func(value []byte) bool {
+		rowID, ok := row.DecodeRowID(value)
+		if !ok {
+			return true
+		}
+		r := store.RowByID(rowID)
+		if r == nil {
+			return true
+		}
+		if path.PastUpper(r) {
+			return false
+		}
+		if !path.inRange(r) {
+			return true
+		}
+		visitErr = fn(r)
+		return visitErr == nil
+	}
+	if sec == nil && store.PageTree != nil {
+		if err := store.PageTree.ForEachRange(lowerKey, nil, func(_, value []byte) bool {
+			return visit(value)
+		}); err != nil {
+			return err
+		}
+		return visitErr
+	}
+	tree := store.Tree
+	if sec != nil {
+		tree = sec.Tree
+	}
+	var cur *btr.Cursor
+	if lowerKey == nil {
+		cur = tree.First()
+	} else {
+		cur = tree.Seek(lowerKey)
+	}
+	for cur != nil && cur.Valid() {
+		if !visit(cur.Value()) {
+			break
+		}
+		cur.Next()
+	}
+	return visitErr
+}
@@ -0,0 +1,117 @@
+package que
+
+import (
+	"github.com/wilhasse/innodb-go/data"
+	"github.com/wilhasse/innodb-go/row"
+)
+
+// JoinNode joins one table to the rows produced by the tables before it with
+// a nested loop. Rows are combined into a single tuple holding the fields of
+// every table of the select; this table fills Width fields from Offset. When
+// Index is set the inner rows are read by seeking Index to the key built by
+// Keys from the outer row, otherwise the table is read once and kept.
+type JoinNode struct {
+	BaseNode
+	Store  *row.Store
+	Access RowAccess
+	Left   bool
+	On     func(*data.Tuple) bool
+	Index  *IndexInfo
+	// Keys build the stored key fields of Index from the combined outer
+	// row. A key reporting false, as for NULL, matches no inner row.
+	Keys   []func(row *data.Tuple) (data.Field, bool)
+	Offset int
+	Width  int
+	rows   []*data.Tuple
+	loaded bool
+}
+
+// NewJoinNode constructs a join of store whose fields start at offset in the
+// combined row.
+func NewJoinNode(parent Node, store *row.Store, offset, width int) *JoinNode {
+	return &JoinNode{
+		BaseNode: NewBaseNode(NodeStatement, parent),
+		Store:    store,
+		Offset:   offset,
+		Width:    width,
+	}
+}
+
+// Reset drops the inner rows kept from an earlier execution.
+func (n *JoinNode) Reset() {
+	n.rows = nil
+	n.loaded = false
+}
+
+// Match returns the combined rows formed from outer and each inner row that
+// satisfies On. A left join with no match returns outer itself, whose fields
+// for this table are NULL. The inner rows are read completely before Match
+// returns, so the caller may read other tables while walking the result.
+func (n *JoinNode) Match(outer *data.Tuple) ([]*data.Tuple, error) {
+	inner, err := n.candidates(outer)
+	if err != nil {
+		return nil, err
+	}
+	var out []*data.Tuple
+	for _, r := range inner {
+		next := data.NewTuple(len(outer.Fields))
+		copy(next.Fields, outer.Fields)
+		for i := 0; i < n.Width && i < len(r.Fields); i++ {
+			next.Fields[n.Offset+i] = r.Fields[i]
+		}
+		if n.On == nil || n.On(next) {
+			out = append(out, next)
+		}
+	}
+	if len(out) == 0 && n.Left {
+		out = append(out, outer)
+	}
+	return out, nil
+}
+
+func (n *JoinNode) candidates(outer *data.Tuple) ([]*data.Tuple, error) {
+	collect := func(rows *[]*data.Tuple) func(*data.Tuple) error {
+		return func(r *data.Tuple) error {
+			*rows = append(*rows, r)
+			return nil
+		}
+	}
+	if n.Index == nil {
+		if !n.loaded {
+			if err := scanRows(n.Store, n.Access, nil, nil, collect(&n.rows)); err != nil {
+				return nil, err
+			}
+			n.loaded = true
+		}
+		return n.rows, nil
+	}
+	fields := make([]data.Field, len(n.Keys))
+	for i, key := range n.Keys {
+		field, ok := key(outer)
+		if !ok {
+			return nil, nil
+		}
+		fields[i] = field
+	}
+	bound := &KeyBound{Fields: fields, Inclusive: true}
+	path := &AccessPath{Kind: AccessIndexSeek, Index: n.Index, Lower: bound, Upper: bound, Equal: len(fields)}
+	var rows []*data.Tuple
+	if err := scanRows(n.Store, n.Access, path, nil, collect(&rows)); err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// widenRow places the fields of a row of the first table at the start of a
+// combined row of width fields, leaving the other tables' fields NULL.
+func widenRow(r *data.Tuple, width int) *data.Tuple {
+	out := data.NewTuple(width)
+	for i := range out.Fields {
+		if i < len(r.Fields) {
+			out.Fields[i] = r.Fields[i]
+		} else {
+			out.Fields[i].Len = data.UnivSQLNull
+		}
+	}
+	return out
+}
@@ -0,0 +1,431 @@
+package que
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/wilhasse/innodb-go/data"
+	"github.com/wilhasse/innodb-go/eval"
+	"github.com/wilhasse/innodb-go/pars"
+)
+
+// joinSource is one table of a join and the position of its fields in the
+// combined row.
+type joinSource struct {
+	ref    string
+	table  *TableContext
+	offset int
+}
+
+// buildJoins builds the select node of a statement with joins. It returns
+// the node, the context describing the combined rows and the statement with
+// every column reference written as ref.column, ref being the table alias or
+// name.
+func buildJoins(stmt *pars.SelectStmt, parent Node, ctx *BuildContext, first *TableContext) (*SelectNode, *TableContext, *pars.SelectStmt, error) {
+	sources := []joinSource{{ref: tableRef(stmt.Table, stmt.Alias), table: first}}
+	width := len(first.Columns)
+	for _, join := range stmt.Joins {
+		table, err := tableContext(ctx, join.Table)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		ref := tableRef(join.Table, join.Alias)
+		for _, src := range sources {
+			if strings.EqualFold(src.ref, ref) {
+				return nil, nil, nil, fmt.Errorf("que: duplicate table name %s", ref)
+			}
+		}
+		sources = append(sources, joinSource{ref: ref, table: table, offset: width})
+		width += len(table.Columns)
+	}
+	view := joinView(sources)
+	stmt, err := resolveSelectNames(stmt, joinResolver(view))
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	// Conditions on the first table alone narrow its scan; the whole WHERE
+	// clause is still checked on the combined rows.
+	var pushed pars.Expr
+	for _, cond := range splitAnd(stmt.Where) {
+		if local, ok := localCondition(cond, view, sources[0]); ok {
+			pushed = andExpr(pushed, local)
+		}
+	}
+	pred, path, err := buildFilter(pushed, first)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	node := NewSelectNode(parent, first.Store, nil, pred)
+	node.Access = first.Access
+	node.Path = path
+	node.Width = width
+	if stmt.Where != nil {
+		if node.Filter, _, err = compileFilter(stmt.Where, view); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+	for i, join := range stmt.Joins {
+		src := sources[i+1]
+		jn := NewJoinNode(node, src.table.Store, src.offset, len(src.table.Columns))
+		jn.Access = src.table.Access
+		jn.Left = join.Left
+		conds := splitAnd(join.On)
+		if join.On != nil {
+			if err := checkJoinColumns(join.On, view, src.offset+jn.Width); err != nil {
+				return nil, nil, nil, err
+			}
+			if jn.On, _, err = compileFilter(join.On, view); err != nil {
+				return nil, nil, nil, err
+			}
+		}
+		// WHERE conditions may pick inner rows of an inner join, but not
+		// of a left join, whose unmatched rows must survive as NULLs.
+		if !join.Left {
+			conds = append(conds, splitAnd(stmt.Where)...)
+		}
+		jn.Index, jn.Keys = planJoinLookup(conds, view, src)
+		node.Joins = append(node.Joins, jn)
+	}
+	return node, view, stmt, nil
+}
+
+func tableRef(table, alias string) string {
+	if alias != "" {
+		return alias
+	}
+	return table
+}
+
+// joinView describes the combined rows: the columns of every table, named
+// ref.column, with encoding and decoding passed to the owning table.
+func joinView(sources []joinSource) *TableContext {
+	view := &TableContext{Result: sources[0].table.Result}
+	owner := make([]int, 0)
+	for i, src := range sources {
+		for col, name := range src.table.Columns {
+			view.Columns = append(view.Columns, src.ref+"."+name)
+			view.Kinds = append(view.Kinds, src.table.columnKind(col))
+			owner = append(owner, i)
+		}
+	}
+	locate := func(col int) (*TableContext, int) {
+		src := sources[owner[col]]
+		return src.table, col - src.offset
+	}
+	view.Encode = func(col int, lit pars.LiteralExpr) (data.Field, error) {
+		if col < 0 || col >= len(owner) {
+			return data.Field{}, fmt.Errorf("que: unknown column")
+		}
+		table, local := locate(col)
+		return table.fieldFromExpr(local, lit)
+	}
+	view.Decode = func(col int, field data.Field) (eval.Value, error) {
+		if col < 0 || col >= len(owner) {
+			return eval.Value{}, fmt.Errorf("que: unknown column")
+		}
+		table, local := locate(col)
+		return table.decodeColumn(local, field)
+	}
+	return view
+}
+
+// joinResolver resolves a column reference against the combined columns. An
+// unqualified name must match exactly one table.
+func joinResolver(view *TableContext) func(string) (string, error) {
+	return func(name string) (string, error) {
+		qual, col := pars.SplitQualified(name)
+		if qual != "" {
+			if idx, ok := columnIndex(view.Columns, name); ok {
+				return view.Columns[idx], nil
+			}
+			return "", fmt.Errorf("que: unknown column %s", name)
+		}
+		found := ""
+		for _, full := range view.Columns {
+			if _, c := pars.SplitQualified(full); strings.EqualFold(c, col) {
+				if found != "" {
+					return "", fmt.Errorf("que: column %s is ambiguous", name)
+				}
+				found = full
+			}
+		}
+		if found == "" {
+			return "", fmt.Errorf("que: unknown column %s", name)
+		}
+		return found, nil
+	}
+}
+
+// singleTableResolver strips the qualifier from column references of a
+// select on one table; it must name the table or its alias.
+func singleTableResolver(stmt *pars.SelectStmt, table *TableContext) func(string) (string, error) {
+	ref := tableRef(stmt.Table, stmt.Alias)
+	return func(name string) (string, error) {
+		qual, col := pars.SplitQualified(name)
+		if qual == "" {
+			return name, nil
+		}
+		if !strings.EqualFold(qual, ref) {
+			return "", fmt.Errorf("que: unknown column %s", name)
+		}
+		return col, nil
+	}
+}
+
+// resolveSelectNames returns a copy of stmt with every column reference
+// passed through resolve. An ORDER BY key naming a select alias is kept.
+func resolveSelectNames(stmt *pars.SelectStmt, resolve func(string) (string, error)) (*pars.SelectStmt, error) {
+	out := *stmt
+	var err error
+	mapExpr := func(expr pars.Expr) pars.Expr {
+		if err != nil || expr == nil {
+			return expr
+		}
+		var mapped pars.Expr
+		mapped, err = mapIdents(expr, resolve)
+		return mapped
+	}
+	out.Items = make([]pars.SelectItem, len(stmt.Items))
+	for i, item := range stmt.Items {
+		item.Expr = mapExpr(item.Expr)
+		out.Items[i] = item
+	}
+	out.Where = mapExpr(stmt.Where)
+	out.Joins = make([]pars.JoinClause, len(stmt.Joins))
+	for i, join := range stmt.Joins {
+		join.On = mapExpr(join.On)
+		out.Joins[i] = join
+	}
+	out.GroupBy = make([]pars.Expr, len(stmt.GroupBy))
+	for i, key := range stmt.GroupBy {
+		out.GroupBy[i] = mapExpr(key)
+	}
+	out.Having = mapExpr(stmt.Having)
+	out.OrderBy = make([]pars.OrderItem, len(stmt.OrderBy))
+	for i, item := range stmt.OrderBy {
+		if ident, ok := item.Expr.(pars.IdentExpr); !ok || !isSelectAlias(stmt.Items, ident.Name) {
+			item.Expr = mapExpr(item.Expr)
+		}
+		out.OrderBy[i] = item
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+func isSelectAlias(items []pars.SelectItem, name string) bool {
+	for _, item := range items {
+		if item.Alias != "" && strings.EqualFold(item.Alias, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// mapIdents rewrites the column references of expr.
+func mapIdents(expr pars.Expr, fn func(string) (string, error)) (pars.Expr, error) {
+	switch e := expr.(type) {
+	case pars.IdentExpr:
+		name, err := fn(e.Name)
+		if err != nil {
+			return nil, err
+		}
+		return pars.IdentExpr{Name: name}, nil
+	case pars.UnaryExpr:
+		inner, err := mapIdents(e.Expr, fn)
+		if err != nil {
+			return nil, err
+		}
+		return pars.UnaryExpr{Op: e.Op, Expr: inner}, nil
+	case pars.BinaryExpr:
+		left, err := mapIdents(e.Left, fn)
+		if err != nil {
+			return nil, err
+		}
+		right, err := mapIdents(e.Right, fn)
+		if err != nil {
+			return nil, err
+		}
+		return pars.BinaryExpr{Op: e.Op, Left: left, Right: right}, nil
+	case pars.FuncExpr:
+		out := pars.FuncExpr{Name: e.Name, Star: e.Star, Args: make([]pars.Expr, len(e.Args))}
+		for i, arg := range e.Args {
+			var err error
+			if out.Args[i], err = mapIdents(arg, fn); err != nil {
+				return nil, err
+			}
+		}
+		return out, nil
+	default:
+		return expr, nil
+	}
+}
+
+// splitAnd returns the AND-ed conditions of expr.
+func splitAnd(expr pars.Expr) []pars.Expr {
+	if expr == nil {
+		return nil
+	}
+	if bin, ok := expr.(pars.BinaryExpr); ok && bin.Op == pars.TokenAnd {
+		return append(splitAnd(bin.Left), splitAnd(bin.Right)...)
+	}
+	return []pars.Expr{expr}
+}
+
+func andExpr(left, right pars.Expr) pars.Expr {
+	if left == nil {
+		return right
+	}
+	return pars.BinaryExpr{Op: pars.TokenAnd, Left: left, Right: right}
+}
+
+// localCondition rewrites cond for the table of src when it only refers to
+// columns of that table.
+func localCondition(cond pars.Expr, view *TableContext, src joinSource) (pars.Expr, bool) {
+	local := true
+	out, err := mapIdents(cond, func(name string) (string, error) {
+		idx, ok := columnIndex(view.Columns, name)
+		if !ok || idx < src.offset || idx >= src.offset+len(src.table.Columns) {
+			local = false
+			return name, nil
+		}
+		return src.table.Columns[idx-src.offset], nil
+	})
+	if err != nil || !local {
+		return nil, false
+	}
+	return out, true
+}
+
+// checkJoinColumns rejects an ON condition that refers to a table joined
+// after its own.
+func checkJoinColumns(on pars.Expr, view *TableContext, limit int) error {
+	_, err := mapIdents(on, func(name string) (string, error) {
+		if idx, ok := columnIndex(view.Columns, name); ok && idx >= limit {
+			return "", fmt.Errorf("que: ON condition refers to later table column %s", name)
+		}
+		return name, nil
+	})
+	return err
+}
+
+// planJoinLookup looks for equalities between columns of the inner table
+// and literals or columns of the tables before it, and picks the index with
+// the longest key prefix they cover. It returns a nil index when none
+// applies.
+func planJoinLookup(conds []pars.Expr, view *TableContext, src joinSource) (*IndexInfo, []func(*data.Tuple) (data.Field, bool)) {
+	inner := src.table
+	keys := make(map[int]func(*data.Tuple) (data.Field, bool))
+	innerColumn := func(expr pars.Expr) (int, bool) {
+		ident, ok := expr.(pars.IdentExpr)
+		if !ok {
+			return -1, false
+		}
+		idx, ok := columnIndex(view.Columns, ident.Name)
+		if !ok || idx < src.offset || idx >= src.offset+len(inner.Columns) {
+			return -1, false
+		}
+		return idx - src.offset, true
+	}
+	for _, cond := range conds {
+		bin, ok := cond.(pars.BinaryExpr)
+		if !ok || bin.Op != pars.TokenEq {
+			continue
+		}
+		col, ok := innerColumn(bin.Left)
+		other := bin.Right
+		if !ok {
+			col, ok = innerColumn(bin.Right)
+			other = bin.Left
+		}
+		if !ok || keys[col] != nil {
+			continue
+		}
+		if key := joinKey(other, col, view, src); key != nil {
+			keys[col] = key
+		}
+	}
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	indexes := inner.Indexes
+	if indexes == nil {
+		indexes = StoreIndexes(inner.Store)
+	}
+	var best *IndexInfo
+	var bestKeys []func(*data.Tuple) (data.Field, bool)
+	for i := range indexes {
+		var cover []func(*data.Tuple) (data.Field, bool)
+		for _, col := range indexes[i].Columns {
+			key := keys[col]
+			if key == nil {
+				break
+			}
+			cover = append(cover, key)
+		}
+		if len(cover) > len(bestKeys) {
+			best, bestKeys = &indexes[i], cover
+		}
+	}
+	return best, bestKeys
+}
+
+// joinKey returns the builder of the stored key field for inner column col
+// from expr, a literal or a column of an earlier table. Values are converted
+// through the inner column's encoding, so columns of different types can be
+// joined.
+func joinKey(expr pars.Expr, col int, view *TableContext, src joinSource) func(*data.Tuple) (data.Field, bool) {
+	inner := src.table
+	switch e := expr.(type) {
+	case pars.LiteralExpr:
+		if e.Kind == pars.TokenNull {
+			return nil
+		}
+		field, err := inner.fieldFromExpr(col, e)
+		if err != nil {
+			return nil
+		}
+		return func(*data.Tuple) (data.Field, bool) { return field, true }
+	case pars.IdentExpr:
+		idx, ok := columnIndex(view.Columns, e.Name)
+		if !ok || idx >= src.offset {
+			return nil
+		}
+		return func(row *data.Tuple) (data.Field, bool) {
+			if idx >= len(row.Fields) || data.FieldIsNull(&row.Fields[idx]) {
+				return data.Field{}, false
+			}
+			val, err := view.decodeColumn(idx, row.Fields[idx])
+			if err != nil {
+				return data.Field{}, false
+			}
+			field, err := inner.fieldFromExpr(col, valueLiteral(val))
+			if err != nil || data.FieldIsNull(&field) {
+				return data.Field{}, false
+			}
+			return field, true
+		}
+	default:
+		return nil
+	}
+}
+
+// valueLiteral writes a value as the literal that encodes it.
+func valueLiteral(val eval.Value) pars.LiteralExpr {
+	switch val.Kind {
+	case eval.KindNull:
+		return pars.LiteralExpr{Kind: pars.TokenNull}
+	case eval.KindInt:
+		return pars.LiteralExpr{Kind: pars.TokenInt, Value: strconv.FormatInt(val.Int, 10)}
+	case eval.KindBool:
+		if val.Bool {
+			return pars.LiteralExpr{Kind: pars.TokenInt, Value: "1"}
+		}
+		return pars.LiteralExpr{Kind: pars.TokenInt, Value: "0"}
+	default:
+		return pars.LiteralExpr{Kind: pars.TokenString, Value: string(eval.ValueToField(val).Data)}
+	}
+}
@@ -0,0 +1,97 @@
+package que
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/wilhasse/innodb-go/pars"
+	"github.com/wilhasse/innodb-go/row"
+)
+
+func newJoinTestContext(t *testing.T) *BuildContext {
+	t.Helper()
+	ctx := newIndexedTestContext(t)
+	store := row.NewStore(0)
+	for _, r := range [][3]string{{"p", "1", "x"}, {"q", "1", "y"}, {"r", "3", "z"}, {"s", "9", "w"}} {
+		if err := store.Insert(makeSQLTuple(r[0], r[1], r[2])); err != nil {
+			t.Fatalf("insert: %v", err)
+		}
+	}
+	if err := store.AddSecondaryIndex("idx_tid", []int{1}, nil, false); err != nil {
+		t.Fatalf("AddSecondaryIndex: %v", err)
+	}
+	ctx.Tables["o"] = &TableContext{Store: store, Columns: []string{"oid", "tid", "note"}}
+	return ctx
+}
+
+func TestSelectJoin(t *testing.T) {
+	ctx := newJoinTestContext(t)
+	tests := []struct {
+		sql  string
+		want string
+	}{
+		{"SELECT t.id, name, o.note FROM t JOIN o ON o.tid = t.id ORDER BY oid", "1,d,x|1,d,y|3,a,z"},
+		{"SELECT id, note FROM t LEFT JOIN o ON tid = id WHERE id < 4 ORDER BY id, note", "1,x|1,y|2,NULL|3,z"},
+		{"SELECT a.id, b.oid FROM t a, o b WHERE b.tid = a.id AND a.name = 'a'", "3,r"},
+		{"SELECT x.oid, y.oid FROM o x INNER JOIN o y ON x.tid = y.tid AND x.oid < y.oid", "p,q"},
+		{"SELECT name, COUNT(oid) FROM t LEFT OUTER JOIN o ON tid = id GROUP BY name ORDER BY name", "a,1|b,0|c,0|d,2"},
+		{"SELECT * FROM o JOIN t ON id = tid WHERE note = 'z'", "r,3,z,3,a"},
+		{"SELECT id, oid FROM t LEFT JOIN o ON tid = id AND note <> 'x' WHERE id < 3", "1,q|2,NULL"},
+	}
+	for _, tt := range tests {
+		if got := strings.Join(groupRows(t, ctx, tt.sql), "|"); got != tt.want {
+			t.Fatalf("%s: rows=%q want %q", tt.sql, got, tt.want)
+		}
+	}
+
+	for _, sql := range []string{
+		"SELECT id FROM t JOIN o ON tid = id JOIN o ON tid = id",
+		"SELECT oid FROM t a JOIN o b ON b.tid = a.id, o c",
+		"SELECT zz FROM t JOIN o ON tid = id",
+		"SELECT t.oid FROM t JOIN o ON tid = id",
+		"SELECT id FROM t JOIN o ON o.tid = c.id JOIN o c ON c.oid = o.oid",
+		"SELECT id FROM t JOIN missing ON x = id",
+		"SELECT o.id FROM t",
+	} {
+		stmt, err := pars.ParseSQL(sql)
+		if err != nil {
+			t.Fatalf("parse %q: %v", sql, err)
+		}
+		if _, err := BuildGraph(stmt, ctx); err == nil {
+			t.Fatalf("%s: expected build error", sql)
+		}
+	}
+}
+
+func TestSelectJoinIndexLookup(t *testing.T) {
+	ctx := newJoinTestContext(t)
+	tests := []struct {
+		sql   string
+		index string
+	}{
+		{"SELECT * FROM t JOIN o ON o.tid = t.id", "idx_tid"},
+		{"SELECT * FROM o JOIN t ON t.id = o.tid", primaryIndexName},
+		{"SELECT * FROM t, o WHERE note = name", ""},
+		{"SELECT * FROM o LEFT JOIN t ON t.name = 'a'", "idx_name"},
+		{"SELECT * FROM o JOIN t ON t.id > o.tid", ""},
+	}
+	for _, tt := range tests {
+		stmt, err := pars.ParseSQL(tt.sql)
+		if err != nil {
+			t.Fatalf("parse %q: %v", tt.sql, err)
+		}
+		graph, err := BuildGraph(stmt, ctx)
+		if err != nil {
+			t.Fatalf("BuildGraph %q: %v", tt.sql, err)
+		}
+		node := ForkGetFirstThr(graph).Child.(*SelectNode)
+		join := node.Joins[0]
+		got := ""
+		if join.Index != nil {
+			got = join.Index.Name
+		}
+		if got != tt.index {
+			t.Fatalf("%s: index=%q want %q", tt.sql, got, tt.index)
+		}
+	}
+}
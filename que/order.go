@@ -0,0 +1,139 @@
+package que
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/wilhasse/innodb-go/data"
+	"github.com/wilhasse/innodb-go/eval"
+)
+
+// sortRows orders rows by keys with a stable sort, so rows with equal keys
+// keep their scan order. Key values are evaluated once per row; NULL sorts
+// before any other value.
+func sortRows(rows []*data.Tuple, keys []OrderKey) ([]*data.Tuple, error) {
+	type sortRow struct {
+		row  *data.Tuple
+		vals []eval.Value
+	}
+	entries := make([]sortRow, len(rows))
+	for i, r := range rows {
+		entries[i].row = r
+		entries[i].vals = make([]eval.Value, len(keys))
+		for k, key := range keys {
+			if key.Eval == nil {
+				continue
+			}
+			val, err := key.Eval(r)
+			if err != nil {
+				return nil, err
+			}
+			entries[i].vals[k] = val
+		}
+	}
+	var sortErr error
+	sort.SliceStable(entries, func(i, j int) bool {
+		for k, key := range keys {
+			var cmp int
+			if key.Eval == nil {
+				cmp = compareColumn(entries[i].row, entries[j].row, key.Source)
+			} else {
+				var err error
+				if cmp, err = compareValues(entries[i].vals[k], entries[j].vals[k]); err != nil && sortErr == nil {
+					sortErr = err
+				}
+			}
+			if cmp != 0 {
+				if key.Desc {
+					return cmp > 0
+				}
+				return cmp < 0
+			}
+		}
+		return false
+	})
+	if sortErr != nil {
+		return nil, sortErr
+	}
+	out := make([]*data.Tuple, len(entries))
+	for i := range entries {
+		out[i] = entries[i].row
+	}
+	return out, nil
+}
+
+func compareColumn(a, b *data.Tuple, col int) int {
+	fa := data.Field{Len: data.UnivSQLNull}
+	fb := fa
+	if col < len(a.Fields) {
+		fa = a.Fields[col]
+	}
+	if col < len(b.Fields) {
+		fb = b.Fields[col]
+	}
+	return data.CompareFields(&fa, &fb)
+}
+
+func compareValues(a, b eval.Value) (int, error) {
+	switch {
+	case a.Kind == eval.KindNull && b.Kind == eval.KindNull:
+		return 0, nil
+	case a.Kind == eval.KindNull:
+		return -1, nil
+	case b.Kind == eval.KindNull:
+		return 1, nil
+	}
+	less, err := eval.EvalCmp("<", a, b)
+	if err != nil {
+		return 0, err
+	}
+	if less {
+		return -1, nil
+	}
+	greater, err := eval.EvalCmp(">", a, b)
+	if err != nil || !greater {
+		return 0, err
+	}
+	return 1, nil
+}
+
+// limitRows applies OFFSET and LIMIT; a negative limit keeps every row.
+func limitRows(rows []*data.Tuple, offset, limit int) []*data.Tuple {
+	if offset >= len(rows) {
+		return nil
+	}
+	rows = rows[offset:]
+	if limit >= 0 && limit < len(rows) {
+		rows = rows[:limit]
+	}
+	return rows
+}
+
+// coerceValue converts a computed value to the kind reported for its output
+// column. NULL stays NULL.
+func coerceValue(val eval.Value, kind eval.ValueKind) (eval.Value, error) {
+	if val.Kind == eval.KindNull || val.Kind == kind {
+		return val, nil
+	}
+	switch kind {
+	case eval.KindInt:
+		switch val.Kind {
+		case eval.KindBool:
+			if val.Bool {
+				return eval.Value{Kind: eval.KindInt, Int: 1}, nil
+			}
+			return eval.Value{Kind: eval.KindInt}, nil
+		case eval.KindBytes:
+			n, err := strconv.ParseInt(strings.TrimSpace(string(val.Bytes)), 10, 64)
+			if err != nil {
+				return eval.Value{}, err
+			}
+			return eval.Value{Kind: eval.KindInt, Int: n}, nil
+		}
+	case eval.KindBytes:
+		field := eval.ValueToField(val)
+		return eval.Value{Kind: eval.KindBytes, Bytes: field.Data}, nil
+	}
+	return val, nil
+}
@@ -0,0 +1,158 @@
+package que
+
+import "github.com/wilhasse/innodb-go/pars"
+
+// buildParamNode builds the node of a statement holding bound literals under
+// thr. The node is built once from the literals in ctx.Params; before each
+// run the thread refreshes from the current literals only what the node
+// derived from them, such as the inserted row, the row filter and its
+// access path, or LIMIT and OFFSET.
+func buildParamNode(stmt pars.Statement, thr *Thr, ctx *BuildContext) error {
+	bound, err := pars.BindParams(stmt, ctx.Params)
+	if err != nil {
+		return err
+	}
+	node, err := buildNode(bound, thr, ctx)
+	if err != nil {
+		return err
+	}
+	thr.Child = node
+	thr.bind = paramBinder(stmt, node, ctx)
+	return nil
+}
+
+// paramBinder returns the function that refreshes node, built from stmt,
+// from the literals in info.
+func paramBinder(stmt pars.Statement, node Node, ctx *BuildContext) func(info *pars.Info) error {
+	switch n := node.(type) {
+	case *InsertNode:
+		st := stmt.(*pars.InsertStmt)
+		table := ctx.Tables[st.Table]
+		return func(info *pars.Info) error {
+			bound, err := pars.BindParams(st, info)
+			if err != nil {
+				return err
+			}
+			tuple, err := buildInsertTuple(bound.(*pars.InsertStmt), table)
+			if err != nil {
+				return err
+			}
+			n.Tuple = tuple
+			return nil
+		}
+	case *UpdateNode:
+		st := stmt.(*pars.UpdateStmt)
+		table := ctx.Tables[st.Table]
+		if n.Access == nil {
+			break
+		}
+		return func(info *pars.Info) error {
+			bound, err := pars.BindParams(st, info)
+			if err != nil {
+				return err
+			}
+			upd := bound.(*pars.UpdateStmt)
+			pred, path, err := buildFilter(upd.Where, table)
+			if err != nil {
+				return err
+			}
+			n.Predicate, n.Path = pred, path
+			n.Assign = assignFunc(upd.Assignments, table)
+			return nil
+		}
+	case *DeleteNode:
+		st := stmt.(*pars.DeleteStmt)
+		table := ctx.Tables[st.Table]
+		if n.Access == nil {
+			break
+		}
+		return func(info *pars.Info) error {
+			bound, err := pars.BindParams(st, info)
+			if err != nil {
+				return err
+			}
+			pred, path, err := buildFilter(bound.(*pars.DeleteStmt).Where, table)
+			if err != nil {
+				return err
+			}
+			n.Predicate, n.Path = pred, path
+			return nil
+		}
+	case *SelectNode:
+		st := stmt.(*pars.SelectStmt)
+		if !filterParamsOnly(st) || n.Group != nil {
+			break
+		}
+		table := ctx.Tables[st.Table]
+		return func(info *pars.Info) error {
+			bound, err := pars.BindParams(st, info)
+			if err != nil {
+				return err
+			}
+			sel := bound.(*pars.SelectStmt)
+			if sel, err = resolveSelectNames(sel, singleTableResolver(sel, table)); err != nil {
+				return err
+			}
+			pred, path, err := buildFilter(sel.Where, table)
+			if err != nil {
+				return err
+			}
+			if n.Limit, err = limitValue(sel.Limit, -1); err != nil {
+				return err
+			}
+			if n.Offset, err = limitValue(sel.Offset, 0); err != nil {
+				return err
+			}
+			n.Predicate, n.Path, n.Sorted = pred, path, false
+			planSortedScan(n, table)
+			return nil
+		}
+	case *ExplainNode:
+		return paramBinder(stmt.(*pars.ExplainStmt).Stmt, n.Plan, ctx)
+	}
+	return func(info *pars.Info) error {
+		return rebuildParamNode(stmt, node, ctx, info)
+	}
+}
+
+// filterParamsOnly reports whether the bound literals of a single-table
+// select all sit in its WHERE, LIMIT or OFFSET clause.
+func filterParamsOnly(stmt *pars.SelectStmt) bool {
+	if len(stmt.Joins) > 0 {
+		return false
+	}
+	rest := *stmt
+	rest.Where, rest.Limit, rest.Offset = nil, nil, nil
+	return !pars.HasParams(&rest)
+}
+
+// rebuildParamNode builds node again from the literals in info, for the
+// nodes whose literals reach too far into them to refresh in place. The
+// node keeps its place in the graph.
+func rebuildParamNode(stmt pars.Statement, node Node, ctx *BuildContext, info *pars.Info) error {
+	bound, err := pars.BindParams(stmt, info)
+	if err != nil {
+		return err
+	}
+	fresh, err := buildNode(bound, node.Parent(), ctx)
+	if err != nil {
+		return err
+	}
+	switch n := node.(type) {
+	case *UpdateNode:
+		next := fresh.(*UpdateNode)
+		next.BaseNode = n.BaseNode
+		*n = *next
+	case *DeleteNode:
+		next := fresh.(*DeleteNode)
+		next.BaseNode = n.BaseNode
+		*n = *next
+	case *SelectNode:
+		next := fresh.(*SelectNode)
+		next.BaseNode = n.BaseNode
+		*n = *next
+	default:
+		return ErrInvalidStatement
+	}
+	return nil
+}
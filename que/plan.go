@@ -0,0 +1,354 @@
+package que
+
+import (
+	"github.com/wilhasse/innodb-go/data"
+	"github.com/wilhasse/innodb-go/pars"
+	"github.com/wilhasse/innodb-go/row"
+)
+
+// AccessKind identifies how a statement reads its table.
+type AccessKind int
+
+const (
+	// AccessFullScan reads every row of the table.
+	AccessFullScan AccessKind = iota
+	// AccessIndexSeek reads the rows equal to a key prefix of an index.
+	AccessIndexSeek
+	// AccessIndexRange reads the rows between two bounds of an index.
+	AccessIndexRange
+	// AccessIndexScan reads every row of the table in the order of an index.
+	AccessIndexScan
+)
+
+// primaryIndexName names the clustered index in plans built from a store.
+const primaryIndexName = "PRIMARY"
+
+// uniqueSeekScore ranks an equality match on every column of a unique index
+// above any other plan, since it reads at most one row.
+const uniqueSeekScore = 1 << 16
+
+// IndexInfo describes an index the planner may choose. Columns holds the
+// table positions of the searchable key columns in key order; a column
+// indexed by prefix ends the searchable part of the key.
+type IndexInfo struct {
+	Name      string
+	Columns   []int
+	Clustered bool
+	Unique    bool
+}
+
+// KeyBound is one end of an index range. Fields holds the stored values of
+// the leading key columns.
+type KeyBound struct {
+	Fields    []data.Field
+	Inclusive bool
+}
+
+// AccessPath is the plan for reading a table. A nil bound leaves that side
+// of the range open. Equal counts the leading key columns fixed by equality,
+// after which rows arrive in the order of the remaining key columns. Rows
+// read through an index are still checked against the full predicate.
+type AccessPath struct {
+	Kind  AccessKind
+	Index *IndexInfo
+	Lower *KeyBound
+	Upper *KeyBound
+	Equal int
+}
+
+// StoreIndexes lists the clustered and secondary indexes of store that the
+// planner can use, clustered index first.
+func StoreIndexes(store *row.Store) []IndexInfo {
+	if store == nil {
+		return nil
+	}
+	var out []IndexInfo
+	switch {
+	case len(store.PrimaryKeyFields) > 0:
+		out = appendIndexInfo(out, primaryIndexName, store.PrimaryKeyFields, store.PrimaryKeyPrefixes, true, true)
+	case store.PrimaryKey >= 0:
+		out = appendIndexInfo(out, primaryIndexName, []int{store.PrimaryKey}, []int{store.PrimaryKeyPrefix}, true, true)
+	}
+	for _, idx := range store.SecondaryIndexList() {
+		out = appendIndexInfo(out, idx.Name, idx.Fields, idx.Prefixes, false, idx.Unique)
+	}
+	return out
+}
+
+func appendIndexInfo(out []IndexInfo, name string, fields, prefixes []int, clustered, unique bool) []IndexInfo {
+	cols := make([]int, 0, len(fields))
+	for i, col := range fields {
+		if i < len(prefixes) && prefixes[i] > 0 {
+			break
+		}
+		cols = append(cols, col)
+	}
+	if len(cols) == 0 {
+		return out
+	}
+	return append(out, IndexInfo{
+		Name:      name,
+		Columns:   cols,
+		Clustered: clustered,
+		Unique:    unique && len(cols) == len(fields),
+	})
+}
+
+// columnRange collects the conditions on one column.
+type columnRange struct {
+	eq             *data.Field
+	lower, upper   *data.Field
+	lowerInclusive bool
+	upperInclusive bool
+}
+
+// planAccess picks the access path for an encoded predicate. Only AND-ed
+// comparisons between a column and a non-NULL literal narrow the path; the
+// rest of the predicate is left to the row filter.
+func (table *TableContext) planAccess(expr pars.Expr) *AccessPath {
+	path := &AccessPath{Kind: AccessFullScan}
+	if table == nil || expr == nil {
+		return path
+	}
+	indexes := table.Indexes
+	if indexes == nil {
+		indexes = StoreIndexes(table.Store)
+	}
+	ranges := make(map[int]*columnRange)
+	table.collectRanges(expr, ranges)
+	if len(ranges) == 0 {
+		return path
+	}
+	best := 0
+	for i := range indexes {
+		candidate, score := planIndex(&indexes[i], ranges)
+		if score > best {
+			path, best = candidate, score
+		}
+	}
+	return path
+}
+
+// collectRanges gathers the column conditions of expr into ranges. Bounds are
+// ordered by column value, and only columns whose stored values sort in
+// value order get a range, since index bounds compare stored values.
+func (table *TableContext) collectRanges(expr pars.Expr, ranges map[int]*columnRange) {
+	bin, ok := expr.(pars.BinaryExpr)
+	if !ok {
+		return
+	}
+	if bin.Op == pars.TokenAnd {
+		table.collectRanges(bin.Left, ranges)
+		table.collectRanges(bin.Right, ranges)
+		return
+	}
+	if !pars.IsComparison(bin.Op) || bin.Op == pars.TokenNe {
+		return
+	}
+	op := bin.Op
+	ident, okIdent := bin.Left.(pars.IdentExpr)
+	lit, okLit := bin.Right.(pars.LiteralExpr)
+	if !okIdent || !okLit {
+		ident, okIdent = bin.Right.(pars.IdentExpr)
+		lit, okLit = bin.Left.(pars.LiteralExpr)
+		op = pars.MirrorComparison(op)
+	}
+	if !okIdent || !okLit || lit.Kind == pars.TokenNull {
+		return
+	}
+	col, ok := columnIndex(table.Columns, ident.Name)
+	if !ok || (op != pars.TokenEq && !table.ordered(col)) {
+		return
+	}
+	rng := ranges[col]
+	if rng == nil {
+		rng = &columnRange{}
+		ranges[col] = rng
+	}
+	buf := []byte(lit.Value)
+	field := &data.Field{Data: buf, Len: uint32(len(buf))}
+	switch op {
+	case pars.TokenEq:
+		if rng.eq == nil {
+			rng.eq = field
+		}
+	case pars.TokenGt, pars.TokenGe:
+		inclusive := op == pars.TokenGe
+		cmp := 1
+		if rng.lower != nil {
+			cmp = table.compareFields(col, field, rng.lower)
+		}
+		if cmp > 0 || (cmp == 0 && !inclusive) {
+			rng.lower, rng.lowerInclusive = field, inclusive
+		}
+	case pars.TokenLt, pars.TokenLe:
+		inclusive := op == pars.TokenLe
+		cmp := -1
+		if rng.upper != nil {
+			cmp = table.compareFields(col, field, rng.upper)
+		}
+		if cmp < 0 || (cmp == 0 && !inclusive) {
+			rng.upper, rng.upperInclusive = field, inclusive
+		}
+	}
+}
+
+// planIndex matches the column conditions against the key of index: equality
+// on a leading run of columns, optionally followed by a range on the next
+// one. It returns the path and a score; zero means the index does not apply.
+func planIndex(index *IndexInfo, ranges map[int]*columnRange) (*AccessPath, int) {
+	var eq []data.Field
+	var rangeCol *columnRange
+	for _, col := range index.Columns {
+		rng := ranges[col]
+		if rng == nil {
+			break
+		}
+		if rng.eq != nil {
+			eq = append(eq, *rng.eq)
+			continue
+		}
+		if rng.lower != nil || rng.upper != nil {
+			rangeCol = rng
+		}
+		break
+	}
+	if len(eq) == 0 && rangeCol == nil {
+		return nil, 0
+	}
+	path := &AccessPath{Kind: AccessIndexSeek, Index: index, Equal: len(eq)}
+	score := 2 * len(eq)
+	lower := &KeyBound{Fields: append([]data.Field(nil), eq...), Inclusive: true}
+	upper := &KeyBound{Fields: append([]data.Field(nil), eq...), Inclusive: true}
+	if rangeCol != nil {
+		path.Kind = AccessIndexRange
+		score++
+		if rangeCol.lower != nil {
+			lower.Fields = append(lower.Fields, *rangeCol.lower)
+			lower.Inclusive = rangeCol.lowerInclusive
+		}
+		if rangeCol.upper != nil {
+			upper.Fields = append(upper.Fields, *rangeCol.upper)
+			upper.Inclusive = rangeCol.upperInclusive
+		}
+	} else if index.Unique && len(eq) == len(index.Columns) {
+		score = uniqueSeekScore
+	}
+	if len(lower.Fields) > 0 {
+		path.Lower = lower
+	}
+	if len(upper.Fields) > 0 {
+		path.Upper = upper
+	}
+	return path, score
+}
+
+// orderedBy reports whether path returns rows sorted by the table columns
+// cols in ascending order. Columns fixed by equality are ignored.
+func (path *AccessPath) orderedBy(cols []int) bool {
+	if path == nil || path.Index == nil || path.Kind == AccessFullScan {
+		return false
+	}
+	rest := path.Index.Columns[path.Equal:]
+	i := 0
+	for _, col := range cols {
+		if containsColumn(path.Index.Columns[:path.Equal], col) {
+			continue
+		}
+		if i >= len(rest) || rest[i] != col {
+			return false
+		}
+		i++
+	}
+	return true
+}
+
+// planOrder picks an index whose key starts with the columns cols, for a
+// statement that would otherwise read the whole table and sort it.
+func (table *TableContext) planOrder(cols []int) *AccessPath {
+	if table == nil || len(cols) == 0 {
+		return nil
+	}
+	indexes := table.Indexes
+	if indexes == nil {
+		indexes = StoreIndexes(table.Store)
+	}
+	for i := range indexes {
+		path := &AccessPath{Kind: AccessIndexScan, Index: &indexes[i]}
+		if path.orderedBy(cols) {
+			return path
+		}
+	}
+	return nil
+}
+
+func containsColumn(cols []int, col int) bool {
+	for _, c := range cols {
+		if c == col {
+			return true
+		}
+	}
+	return false
+}
+
+// boundTuple places the fields of a bound at the table positions of the index
+// key columns, the layout the store's search key builders expect.
+func boundTuple(index *IndexInfo, fields []data.Field, width int) *data.Tuple {
+	for _, col := range index.Columns {
+		if col >= width {
+			width = col + 1
+		}
+	}
+	tpl := data.NewTuple(width)
+	for i := range tpl.Fields {
+		tpl.Fields[i].Len = data.UnivSQLNull
+	}
+	for i, field := range fields {
+		if i < len(index.Columns) {
+			tpl.Fields[index.Columns[i]] = field
+		}
+	}
+	return tpl
+}
+
+// compareBound compares the key columns of r with the fields of a bound.
+func compareBound(r *data.Tuple, index *IndexInfo, fields []data.Field) int {
+	for i := range fields {
+		if i >= len(index.Columns) {
+			break
+		}
+		col := index.Columns[i]
+		field := data.Field{Len: data.UnivSQLNull}
+		if col < len(r.Fields) {
+			field = r.Fields[col]
+		}
+		if cmp := data.CompareFields(&field, &fields[i]); cmp != 0 {
+			return cmp
+		}
+	}
+	return 0
+}
+
+// inRange reports whether the key columns of r lie within the bounds of path.
+func (path *AccessPath) inRange(r *data.Tuple) bool {
+	if path == nil || path.Index == nil || r == nil {
+		return true
+	}
+	if path.Lower != nil {
+		cmp := compareBound(r, path.Index, path.Lower.Fields)
+		if cmp < 0 || (cmp == 0 && !path.Lower.Inclusive) {
+			return false
+		}
+	}
+	return !path.PastUpper(r)
+}
+
+// PastUpper reports whether the key columns of r sort after the upper bound
+// of path, which ends a forward scan of the index.
+func (path *AccessPath) PastUpper(r *data.Tuple) bool {
+	if path == nil || path.Upper == nil || path.Index == nil || r == nil {
+		return false
+	}
+	cmp := compareBound(r, path.Index, path.Upper.Fields)
+	return cmp > 0 || (cmp == 0 && !path.Upper.Inclusive)
+}
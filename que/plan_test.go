@@ -0,0 +1,170 @@
+package que
+
+import (
+	"testing"
+
+	"github.com/wilhasse/innodb-go/data"
+	"github.com/wilhasse/innodb-go/eval"
+	"github.com/wilhasse/innodb-go/pars"
+)
+
+func newIndexedTestContext(t *testing.T) *BuildContext {
+	t.Helper()
+	ctx, store := newTestContext()
+	for _, r := range [][2]string{{"1", "d"}, {"2", "b"}, {"3", "a"}, {"4", "b"}, {"5", "c"}} {
+		if err := store.Insert(makeSQLTuple(r[0], r[1])); err != nil {
+			t.Fatalf("insert: %v", err)
+		}
+	}
+	if err := store.AddSecondaryIndex("idx_name", []int{1}, nil, false); err != nil {
+		t.Fatalf("AddSecondaryIndex: %v", err)
+	}
+	return ctx
+}
+
+func runPlannedSelect(t *testing.T, ctx *BuildContext, sql string) (*SelectNode, []string) {
+	t.Helper()
+	stmt, err := pars.ParseSQL(sql)
+	if err != nil {
+		t.Fatalf("parse %q: %v", sql, err)
+	}
+	graph, err := BuildGraph(stmt, ctx)
+	if err != nil {
+		t.Fatalf("BuildGraph %q: %v", sql, err)
+	}
+	node := ForkGetFirstThr(graph).Child.(*SelectNode)
+	if err := ForkRun(graph); err != nil {
+		t.Fatalf("ForkRun %q: %v", sql, err)
+	}
+	ids := make([]string, len(node.Rows))
+	for i, r := range node.Rows {
+		ids[i] = string(r.Fields[0].Data)
+	}
+	return node, ids
+}
+
+func TestPlanAccessPaths(t *testing.T) {
+	ctx := newIndexedTestContext(t)
+	tests := []struct {
+		sql   string
+		kind  AccessKind
+		index string
+		ids   string
+	}{
+		{"SELECT * FROM t WHERE id = 3", AccessIndexSeek, "PRIMARY", "3"},
+		{"SELECT * FROM t WHERE 2 < id AND id <= 4", AccessIndexRange, "PRIMARY", "34"},
+		{"SELECT * FROM t WHERE id >= 4", AccessIndexRange, "PRIMARY", "45"},
+		{"SELECT * FROM t WHERE name = 'b'", AccessIndexSeek, "idx_name", "24"},
+		{"SELECT * FROM t WHERE name > 'a' AND name < 'd' AND id <> 4", AccessIndexRange, "idx_name", "25"},
+		{"SELECT * FROM t WHERE name = 'b' AND id = 4", AccessIndexSeek, "PRIMARY", "4"},
+		{"SELECT * FROM t WHERE id = 1 OR name = 'a'", AccessFullScan, "", "13"},
+		{"SELECT * FROM t WHERE id = NULL", AccessFullScan, "", ""},
+	}
+	for _, tt := range tests {
+		node, ids := runPlannedSelect(t, ctx, tt.sql)
+		if node.Path == nil || node.Path.Kind != tt.kind {
+			t.Fatalf("%s: path=%+v want kind %v", tt.sql, node.Path, tt.kind)
+		}
+		if tt.index != "" && node.Path.Index.Name != tt.index {
+			t.Fatalf("%s: index=%s want %s", tt.sql, node.Path.Index.Name, tt.index)
+		}
+		got := ""
+		for _, id := range ids {
+			got += id
+		}
+		if got != tt.ids {
+			t.Fatalf("%s: rows=%q want %q", tt.sql, got, tt.ids)
+		}
+	}
+}
+
+func TestPlanUniqueSeekPreferred(t *testing.T) {
+	ctx := newIndexedTestContext(t)
+	ctx.Tables["t"].Indexes = []IndexInfo{
+		{Name: "idx_name", Columns: []int{1}},
+		{Name: "PRIMARY", Columns: []int{0}, Clustered: true, Unique: true},
+	}
+	node, ids := runPlannedSelect(t, ctx, "SELECT * FROM t WHERE name = 'b' AND id = 2")
+	if node.Path.Index.Name != "PRIMARY" || len(ids) != 1 || ids[0] != "2" {
+		t.Fatalf("path=%+v rows=%v", node.Path, ids)
+	}
+}
+
+func TestPlanUpdateDeleteUseIndex(t *testing.T) {
+	ctx := newIndexedTestContext(t)
+	store := ctx.Tables["t"].Store
+	stmt, err := pars.ParseSQL("UPDATE t SET name='z' WHERE name = 'c'")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	graph, err := BuildGraph(stmt, ctx)
+	if err != nil {
+		t.Fatalf("BuildGraph: %v", err)
+	}
+	upd := ForkGetFirstThr(graph).Child.(*UpdateNode)
+	if got := string(upd.OldTuple.Fields[0].Data); got != "5" {
+		t.Fatalf("update target id=%s", got)
+	}
+	if err := ForkRun(graph); err != nil {
+		t.Fatalf("ForkRun: %v", err)
+	}
+	_, ids := runPlannedSelect(t, ctx, "SELECT * FROM t WHERE name = 'z'")
+	if len(ids) != 1 || ids[0] != "5" {
+		t.Fatalf("after update rows=%v", ids)
+	}
+
+	stmt, err = pars.ParseSQL("DELETE FROM t WHERE id > 4")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	graph, err = BuildGraph(stmt, ctx)
+	if err != nil {
+		t.Fatalf("BuildGraph: %v", err)
+	}
+	del := ForkGetFirstThr(graph).Child.(*DeleteNode)
+	if got := string(del.Tuple.Fields[0].Data); got != "5" {
+		t.Fatalf("delete target id=%s", got)
+	}
+	if err := ForkRun(graph); err != nil {
+		t.Fatalf("ForkRun: %v", err)
+	}
+	if len(store.Rows) != 4 {
+		t.Fatalf("rows after delete=%d", len(store.Rows))
+	}
+}
+
+func TestPlanBoundsOrderedByValue(t *testing.T) {
+	// Columns hold one signed byte, so 0xf6 (-10) sorts after 3 as bytes.
+	table := &TableContext{
+		Columns: []string{"v"},
+		Indexes: []IndexInfo{{Name: "idx_v", Columns: []int{0}}},
+		Decode: func(_ int, field data.Field) (eval.Value, error) {
+			return eval.Value{Kind: eval.KindInt, Int: int64(int8(field.Data[0]))}, nil
+		},
+	}
+	lit := func(b byte) pars.LiteralExpr {
+		return pars.LiteralExpr{Value: string([]byte{b}), Kind: pars.TokenString}
+	}
+	cond := func(op pars.TokenType, b byte) pars.Expr {
+		return pars.BinaryExpr{Op: op, Left: pars.IdentExpr{Name: "v"}, Right: lit(b)}
+	}
+	expr := pars.BinaryExpr{Op: pars.TokenAnd,
+		Left:  pars.BinaryExpr{Op: pars.TokenAnd, Left: cond(pars.TokenGt, 0xf6), Right: cond(pars.TokenGt, 3)},
+		Right: pars.BinaryExpr{Op: pars.TokenAnd, Left: cond(pars.TokenLt, 0xfb), Right: cond(pars.TokenLt, 20)},
+	}
+	path := table.planAccess(expr)
+	if path.Kind != AccessIndexRange || path.Lower == nil || path.Upper == nil {
+		t.Fatalf("path=%+v", path)
+	}
+	if got := path.Lower.Fields[0].Data[0]; got != 3 {
+		t.Fatalf("lower=%#x want 0x3", got)
+	}
+	if got := path.Upper.Fields[0].Data[0]; got != 0xfb {
+		t.Fatalf("upper=%#x want 0xfb", got)
+	}
+
+	table.Ordered = []bool{false}
+	if path := table.planAccess(expr); path.Kind != AccessFullScan {
+		t.Fatalf("unordered column path=%+v", path)
+	}
+}
@@ -0,0 +1,531 @@
+package que
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/wilhasse/innodb-go/eval"
+	"github.com/wilhasse/innodb-go/pars"
+)
+
+// ProcedureNode runs a stored procedure compiled onto the eval procedural
+// nodes. Each SQL statement of the body is built against the context when it
+// runs, with the procedure variables it reads replaced by their values; a
+// name that is also a column of the statement's tables means the column.
+// After Execute, Values holds the OUT parameters described by Output and
+// Affected the rows changed by the procedure's INSERT, UPDATE and DELETE
+// statements.
+type ProcedureNode struct {
+	BaseNode
+	Name     string
+	Proc     *eval.ProcNode
+	Output   []Projection
+	Values   []eval.Value
+	Affected int
+
+	ctx     *BuildContext
+	vars    map[string]*procVar
+	params  []*procVar
+	cursors map[string]*procCursor
+}
+
+// procVar is a procedure variable or parameter.
+type procVar struct {
+	sym  *eval.Symbol
+	kind eval.ValueKind
+	// init is the starting value of an IN parameter.
+	init pars.Expr
+}
+
+// procCursor holds the rows of an open cursor.
+type procCursor struct {
+	query    *pars.SelectStmt
+	open     bool
+	rows     [][]eval.Value
+	notFound bool
+}
+
+// Execute runs the procedure from its first statement.
+func (n *ProcedureNode) Execute(_ *Thr) error {
+	if n == nil || n.Proc == nil {
+		return ErrInvalidStatement
+	}
+	n.Values = nil
+	n.Affected = 0
+	for _, v := range n.vars {
+		v.sym.Val = eval.Value{Kind: eval.KindNull}
+		if v.init == nil {
+			continue
+		}
+		val, err := eval.EvalExprWith(v.init, n.lookup)
+		if err != nil {
+			return err
+		}
+		if v.sym.Val, err = coerceValue(val, v.kind); err != nil {
+			return err
+		}
+	}
+	for _, c := range n.cursors {
+		*c = procCursor{query: c.query}
+	}
+	if err := eval.ProcRun(n.Proc); err != nil {
+		return err
+	}
+	for _, v := range n.params {
+		n.Values = append(n.Values, v.sym.Val)
+	}
+	return nil
+}
+
+func buildProcedureNode(stmt *pars.ProcedureStmt, parent Node, ctx *BuildContext) (Node, error) {
+	n := &ProcedureNode{
+		BaseNode: NewBaseNode(NodeStatement, parent),
+		Name:     stmt.Name,
+		Proc:     &eval.ProcNode{Node: eval.Node{Type: eval.NodeProc}},
+		ctx:      ctx,
+		vars:     make(map[string]*procVar),
+		cursors:  make(map[string]*procCursor),
+	}
+	for _, param := range stmt.Params {
+		v, err := n.declare(param.Name, param.Type)
+		if err != nil {
+			return nil, err
+		}
+		if param.Out {
+			n.params = append(n.params, v)
+			n.Output = append(n.Output, Projection{Name: param.Name, Source: -1, Kind: v.kind})
+		} else {
+			v.init = param.Value
+		}
+	}
+	for _, decl := range stmt.Vars {
+		if _, err := n.declare(decl.Name, decl.Type); err != nil {
+			return nil, err
+		}
+	}
+	for _, decl := range stmt.Cursors {
+		name := strings.ToLower(decl.Name)
+		if n.cursors[name] != nil {
+			return nil, fmt.Errorf("que: cursor %s declared twice", decl.Name)
+		}
+		n.cursors[name] = &procCursor{query: decl.Query}
+	}
+	list, err := n.buildStatements(stmt.Body, n.Proc, false)
+	if err != nil {
+		return nil, err
+	}
+	n.Proc.StatList = list
+	return n, nil
+}
+
+func (n *ProcedureNode) declare(name, typ string) (*procVar, error) {
+	key := strings.ToLower(name)
+	if n.vars[key] != nil {
+		return nil, fmt.Errorf("que: variable %s declared twice", name)
+	}
+	v := &procVar{sym: &eval.Symbol{Node: eval.Node{Type: eval.NodeSymbol}}, kind: procVarKind(typ)}
+	n.vars[key] = v
+	return v, nil
+}
+
+// procVarKind maps a declared type to the kind of value the variable holds.
+func procVarKind(typ string) eval.ValueKind {
+	switch typ {
+	case "INT", "INTEGER", "BIGINT", "SMALLINT", "TINYINT":
+		return eval.KindInt
+	case "FLOAT", "DOUBLE", "REAL":
+		return eval.KindFloat
+	default:
+		return eval.KindBytes
+	}
+}
+
+// buildStatements compiles stmts into a list of nodes under parent and
+// returns its first node.
+func (n *ProcedureNode) buildStatements(stmts []pars.Statement, parent eval.NodeRef, inLoop bool) (eval.NodeRef, error) {
+	var first, last eval.NodeRef
+	for _, stmt := range stmts {
+		node, err := n.buildStatement(stmt, parent, inLoop)
+		if err != nil {
+			return nil, err
+		}
+		if first == nil {
+			first = node
+		} else {
+			eval.NodeSetNext(last, node)
+		}
+		last = node
+	}
+	return first, nil
+}
+
+func (n *ProcedureNode) buildStatement(stmt pars.Statement, parent eval.NodeRef, inLoop bool) (eval.NodeRef, error) {
+	var err error
+	switch st := stmt.(type) {
+	case pars.AssignStmt:
+		v, err := n.variable(st.Var)
+		if err != nil {
+			return nil, err
+		}
+		node := &eval.AssignNode{Node: eval.Node{Type: eval.NodeAssignment, Parent: parent}, Var: v.sym}
+		if node.Val, err = n.buildExpr(st.Value, node, v.kind); err != nil {
+			return nil, err
+		}
+		return node, nil
+	case pars.IfStmt:
+		node := &eval.IfNode{Node: eval.Node{Type: eval.NodeIf, Parent: parent}}
+		if node.Cond, err = n.buildExpr(st.Cond, node, eval.KindNull); err != nil {
+			return nil, err
+		}
+		if node.StatList, err = n.buildStatements(st.Then, node, inLoop); err != nil {
+			return nil, err
+		}
+		// ELSE is only taken when there are no ELSIF branches, so after
+		// them it becomes a final branch that always matches.
+		clauses := st.Elsifs
+		if len(st.Elsifs) > 0 && len(st.Else) > 0 {
+			clauses = append(clauses[:len(clauses):len(clauses)], pars.ElsifClause{
+				Cond: pars.LiteralExpr{Kind: pars.TokenInt, Value: "1"},
+				Then: st.Else,
+			})
+		} else if node.ElsePart, err = n.buildStatements(st.Else, node, inLoop); err != nil {
+			return nil, err
+		}
+		var prev *eval.ElsifNode
+		for _, clause := range clauses {
+			elsif := &eval.ElsifNode{Node: eval.Node{Type: eval.NodeElsif, Parent: node}}
+			if elsif.Cond, err = n.buildExpr(clause.Cond, elsif, eval.KindNull); err != nil {
+				return nil, err
+			}
+			if elsif.StatList, err = n.buildStatements(clause.Then, node, inLoop); err != nil {
+				return nil, err
+			}
+			if prev == nil {
+				node.ElsifList = elsif
+			} else {
+				eval.NodeSetNext(prev, elsif)
+			}
+			prev = elsif
+		}
+		return node, nil
+	case pars.WhileStmt:
+		node := &eval.WhileNode{Node: eval.Node{Type: eval.NodeWhile, Parent: parent}}
+		if node.Cond, err = n.buildExpr(st.Cond, node, eval.KindNull); err != nil {
+			return nil, err
+		}
+		if node.StatList, err = n.buildLoopBody(st.Body, node); err != nil {
+			return nil, err
+		}
+		return node, nil
+	case pars.ForStmt:
+		v, err := n.variable(st.Var)
+		if err != nil {
+			return nil, err
+		}
+		if v.kind != eval.KindInt {
+			return nil, fmt.Errorf("que: loop variable %s is not an integer", st.Var)
+		}
+		node := &eval.ForNode{Node: eval.Node{Type: eval.NodeFor, Parent: parent}, LoopVar: v.sym}
+		if node.LoopStartLimit, err = n.buildExpr(st.From, node, eval.KindInt); err != nil {
+			return nil, err
+		}
+		if node.LoopEndLimit, err = n.buildExpr(st.To, node, eval.KindInt); err != nil {
+			return nil, err
+		}
+		if node.StatList, err = n.buildLoopBody(st.Body, node); err != nil {
+			return nil, err
+		}
+		return node, nil
+	case pars.ExitStmt:
+		if !inLoop {
+			return nil, errors.New("que: EXIT outside a loop")
+		}
+		return &eval.ExitNode{Node: eval.Node{Type: eval.NodeExit, Parent: parent}}, nil
+	case pars.ReturnStmt:
+		return &eval.ReturnNode{Node: eval.Node{Type: eval.NodeReturn, Parent: parent}}, nil
+	case pars.OpenStmt:
+		c, err := n.cursor(st.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		return n.stmtNode(parent, func() error {
+			if c.open {
+				return fmt.Errorf("que: cursor %s is already open", st.Cursor)
+			}
+			rows, err := n.runSQL(c.query)
+			if err != nil {
+				return err
+			}
+			*c = procCursor{query: c.query, open: true, rows: rows}
+			return nil
+		}), nil
+	case pars.FetchStmt:
+		c, err := n.cursor(st.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		into, err := n.variables(st.Into)
+		if err != nil {
+			return nil, err
+		}
+		return n.stmtNode(parent, func() error {
+			if !c.open {
+				return fmt.Errorf("que: cursor %s is not open", st.Cursor)
+			}
+			c.notFound = len(c.rows) == 0
+			if c.notFound {
+				return nil
+			}
+			row := c.rows[0]
+			c.rows = c.rows[1:]
+			return assignRow(into, row)
+		}), nil
+	case pars.CloseStmt:
+		c, err := n.cursor(st.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		return n.stmtNode(parent, func() error {
+			if !c.open {
+				return fmt.Errorf("que: cursor %s is not open", st.Cursor)
+			}
+			*c = procCursor{query: c.query}
+			return nil
+		}), nil
+	case *pars.SelectStmt:
+		into, err := n.variables(st.Into)
+		if err != nil {
+			return nil, err
+		}
+		return n.stmtNode(parent, func() error {
+			rows, err := n.runSQL(st)
+			if err != nil || len(into) == 0 {
+				return err
+			}
+			if len(rows) == 0 {
+				for _, v := range into {
+					v.sym.Val = eval.Value{Kind: eval.KindNull}
+				}
+				return nil
+			}
+			return assignRow(into, rows[0])
+		}), nil
+	case *pars.InsertStmt, *pars.UpdateStmt, *pars.DeleteStmt:
+		return n.stmtNode(parent, func() error {
+			_, err := n.runSQL(st)
+			return err
+		}), nil
+	default:
+		return nil, ErrInvalidStatement
+	}
+}
+
+func (n *ProcedureNode) buildLoopBody(body []pars.Statement, loop eval.NodeRef) (eval.NodeRef, error) {
+	if len(body) == 0 {
+		return nil, errors.New("que: empty loop body")
+	}
+	return n.buildStatements(body, loop, true)
+}
+
+func (n *ProcedureNode) stmtNode(parent eval.NodeRef, run func() error) *eval.StmtNode {
+	return &eval.StmtNode{Node: eval.Node{Type: eval.NodeStatement, Parent: parent}, Run: run}
+}
+
+// buildExpr compiles expr into a node that evaluates it over the procedure
+// variables, converting the result to kind unless kind is KindNull.
+func (n *ProcedureNode) buildExpr(expr pars.Expr, parent eval.NodeRef, kind eval.ValueKind) (*eval.ExprNode, error) {
+	if _, err := mapIdents(expr, func(name string) (string, error) {
+		if _, ok := n.name(name); !ok {
+			return "", fmt.Errorf("que: unknown variable %s", name)
+		}
+		return name, nil
+	}); err != nil {
+		return nil, err
+	}
+	return &eval.ExprNode{
+		Node: eval.Node{Type: eval.NodeExpr, Parent: parent},
+		Eval: func() (eval.Value, error) {
+			val, err := eval.EvalExprWith(expr, n.lookup)
+			if err != nil || kind == eval.KindNull {
+				return val, err
+			}
+			return coerceValue(val, kind)
+		},
+	}, nil
+}
+
+// lookup reads a variable or a cursor attribute.
+func (n *ProcedureNode) lookup(name string) (eval.Value, error) {
+	val, ok := n.name(name)
+	if !ok {
+		return eval.Value{}, fmt.Errorf("que: unknown variable %s", name)
+	}
+	return val, nil
+}
+
+func (n *ProcedureNode) name(name string) (eval.Value, bool) {
+	key := strings.ToLower(name)
+	if v := n.vars[key]; v != nil {
+		return v.sym.Val, true
+	}
+	cursor, attr, ok := strings.Cut(key, "%")
+	if c := n.cursors[cursor]; ok && c != nil {
+		return eval.Value{Kind: eval.KindBool, Bool: c.notFound == (attr == "notfound")}, true
+	}
+	return eval.Value{}, false
+}
+
+func (n *ProcedureNode) variable(name string) (*procVar, error) {
+	v := n.vars[strings.ToLower(name)]
+	if v == nil {
+		return nil, fmt.Errorf("que: unknown variable %s", name)
+	}
+	return v, nil
+}
+
+func (n *ProcedureNode) variables(names []string) ([]*procVar, error) {
+	vars := make([]*procVar, len(names))
+	for i, name := range names {
+		var err error
+		if vars[i], err = n.variable(name); err != nil {
+			return nil, err
+		}
+	}
+	return vars, nil
+}
+
+func (n *ProcedureNode) cursor(name string) (*procCursor, error) {
+	c := n.cursors[strings.ToLower(name)]
+	if c == nil {
+		return nil, fmt.Errorf("que: unknown cursor %s", name)
+	}
+	return c, nil
+}
+
+func assignRow(into []*procVar, row []eval.Value) error {
+	if len(row) != len(into) {
+		return fmt.Errorf("que: %d values for %d variables", len(row), len(into))
+	}
+	for i, v := range into {
+		val, err := coerceValue(row[i], v.kind)
+		if err != nil {
+			return err
+		}
+		v.sym.Val = val
+	}
+	return nil
+}
+
+// runSQL builds and runs a statement of the procedure with the current
+// variable values and returns the rows of a select as values.
+func (n *ProcedureNode) runSQL(stmt pars.Statement) ([][]eval.Value, error) {
+	var columns []string
+	for _, name := range StatementTables(stmt) {
+		if table := n.ctx.Tables[name]; table != nil {
+			columns = append(columns, table.Columns...)
+		}
+	}
+	bound, err := pars.RewriteExprs(stmt, func(expr pars.Expr) (pars.Expr, error) {
+		ident, ok := expr.(pars.IdentExpr)
+		if !ok {
+			return expr, nil
+		}
+		if _, isColumn := columnIndex(columns, ident.Name); isColumn {
+			return expr, nil
+		}
+		if val, ok := n.name(ident.Name); ok {
+			return valueLiteral(val), nil
+		}
+		return expr, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if sel, ok := bound.(*pars.SelectStmt); ok {
+		sel.Into = nil
+	}
+	graph, err := BuildGraph(bound, n.ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer GraphFree(graph)
+	node := ForkGetFirstThr(graph).Child
+	if sel, ok := node.(*SelectNode); ok {
+		sel.KeepValues = true
+	}
+	if err := ForkRun(graph); err != nil {
+		return nil, err
+	}
+	switch st := node.(type) {
+	case *SelectNode:
+		return st.Values, nil
+	case *InsertNode:
+		n.Affected += st.Affected
+	case *UpdateNode:
+		n.Affected += st.Affected
+	case *DeleteNode:
+		n.Affected += st.Affected
+	}
+	return nil, nil
+}
+
+// StatementTables returns the tables a statement reads or changes, in the
+// order they appear: for a select the FROM tables, for an explain those of
+// the statement explained, and for a procedure the tables of its statements
+// and cursors, each listed once.
+func StatementTables(stmt pars.Statement) []string {
+	switch st := stmt.(type) {
+	case *pars.SelectStmt:
+		names := []string{st.Table}
+		for _, join := range st.Joins {
+			names = append(names, join.Table)
+		}
+		return names
+	case *pars.InsertStmt:
+		return []string{st.Table}
+	case *pars.UpdateStmt:
+		return []string{st.Table}
+	case *pars.DeleteStmt:
+		return []string{st.Table}
+	case *pars.ExplainStmt:
+		return StatementTables(st.Stmt)
+	case *pars.ProcedureStmt:
+		seen := make(map[string]bool)
+		var names []string
+		add := func(stmt pars.Statement) {
+			for _, name := range StatementTables(stmt) {
+				if !seen[name] {
+					seen[name] = true
+					names = append(names, name)
+				}
+			}
+		}
+		for _, cursor := range st.Cursors {
+			add(cursor.Query)
+		}
+		var walk func([]pars.Statement)
+		walk = func(stmts []pars.Statement) {
+			for _, stmt := range stmts {
+				switch s := stmt.(type) {
+				case pars.IfStmt:
+					walk(s.Then)
+					for _, elsif := range s.Elsifs {
+						walk(elsif.Then)
+					}
+					walk(s.Else)
+				case pars.WhileStmt:
+					walk(s.Body)
+				case pars.ForStmt:
+					walk(s.Body)
+				default:
+					add(stmt)
+				}
+			}
+		}
+		walk(st.Body)
+		return names
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,90 @@
+package que
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/wilhasse/innodb-go/pars"
+)
+
+func TestProcedure(t *testing.T) {
+	ctx := newIndexedTestContext(t)
+	info := pars.NewInfo()
+	info.AddStrLiteral("prefix", "z")
+	stmt, err := pars.ParseSQLWithInfo(info, `PROCEDURE p(prefix IN CHAR, total OUT INT, last OUT CHAR) IS
+		n INT;
+		i INT;
+		v CHAR;
+		CURSOR c IS SELECT name FROM t WHERE id < '4' ORDER BY id;
+	BEGIN
+		n := 0;
+		OPEN c;
+		WHILE 1 = 1 LOOP
+			FETCH c INTO v;
+			IF c%NOTFOUND THEN
+				EXIT;
+			END IF;
+			n := n + 1;
+			last := v;
+		END LOOP;
+		CLOSE c;
+		FOR i IN 6 .. 7 LOOP
+			INSERT INTO t VALUES (i, prefix);
+		END LOOP;
+		SELECT COUNT(*) INTO total FROM t WHERE name = prefix;
+		total := total + n;
+		IF total > 100 THEN
+			DELETE FROM t WHERE id = '1';
+		ELSE
+			RETURN;
+		END IF;
+		total := 0;
+	END;`)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	graph, err := BuildGraph(stmt, ctx)
+	if err != nil {
+		t.Fatalf("BuildGraph: %v", err)
+	}
+	node := ForkGetFirstThr(graph).Child.(*ProcedureNode)
+	if err := ForkRun(graph); err != nil {
+		t.Fatalf("ForkRun: %v", err)
+	}
+	if len(node.Values) != 2 || node.Values[0].Int != 5 || string(node.Values[1].Bytes) != "a" {
+		t.Fatalf("values=%+v", node.Values)
+	}
+	if node.Affected != 2 || len(node.Output) != 2 || node.Output[1].Name != "last" {
+		t.Fatalf("affected=%d output=%+v", node.Affected, node.Output)
+	}
+	if got := strings.Join(groupRows(t, ctx, "SELECT id FROM t WHERE name = 'z'"), "|"); got != "6|7" {
+		t.Fatalf("inserted rows=%q", got)
+	}
+
+	ForkStartCommand(graph)
+	if err := ForkRun(graph); err == nil {
+		t.Fatalf("expected duplicate key error on rerun")
+	}
+}
+
+func TestProcedureBuildErrors(t *testing.T) {
+	ctx := newIndexedTestContext(t)
+	for _, sql := range []string{
+		"PROCEDURE p() IS BEGIN x := 1; END",
+		"PROCEDURE p() IS n INT; BEGIN n := m; END",
+		"PROCEDURE p() IS n INT; BEGIN EXIT; END",
+		"PROCEDURE p() IS n INT; n CHAR; BEGIN n := 1; END",
+		"PROCEDURE p() IS v CHAR; BEGIN FOR v IN 1 .. 2 LOOP RETURN; END LOOP; END",
+		"PROCEDURE p() IS BEGIN OPEN c; END",
+		"PROCEDURE p() IS n INT; BEGIN SELECT id INTO m FROM t; END",
+		"SELECT id INTO n FROM t",
+	} {
+		stmt, err := pars.ParseSQL(sql)
+		if err != nil {
+			t.Fatalf("parse %q: %v", sql, err)
+		}
+		if _, err := BuildGraph(stmt, ctx); err == nil {
+			t.Fatalf("%s: expected build error", sql)
+		}
+	}
+}
@@ -4,19 +4,67 @@ import (
 	"errors"
 
 	"github.com/wilhasse/innodb-go/data"
+	"github.com/wilhasse/innodb-go/eval"
 	"github.com/wilhasse/innodb-go/row"
 )
 
 // ErrInvalidSelectNode reports missing select inputs.
 var ErrInvalidSelectNode = errors.New("que: invalid select node")
 
-// SelectNode executes a simple row scan.
+// SelectNode executes a row scan. Matching rows are sorted by Order, cut to
+// Offset and Limit, then projected through Output; with no Output the
+// columns listed in Columns, or every column, are returned.
 type SelectNode struct {
 	BaseNode
 	Store     *row.Store
 	Columns   []int
 	Predicate func(*data.Tuple) bool
 	Rows      []*data.Tuple
+	Access    RowAccess
+	Path      *AccessPath
+	Output    []Projection
+	// Joins, when set, join further tables to the rows of Store, which
+	// Predicate and Path then only filter. Output, Order and Filter are
+	// evaluated on combined rows of Width fields.
+	Joins  []*JoinNode
+	Width  int
+	Filter func(*data.Tuple) bool
+	// Group, when set, groups the scanned rows; Having then filters the
+	// group rows, which Order and Output are evaluated on.
+	Group  *GroupNode
+	Having func(*data.Tuple) (bool, error)
+	Order  []OrderKey
+	// Sorted is set when Path already returns rows in Order.
+	Sorted bool
+	// Limit caps the number of rows returned; negative means no limit.
+	Limit  int
+	Offset int
+	// Result stores computed output values; nil stores their text.
+	Result ValueEncoder
+	// KeepValues makes Execute also return each result row as values in
+	// Values, reading table columns through Decode.
+	KeepValues bool
+	Decode     FieldDecoder
+	Values     [][]eval.Value
+}
+
+// Projection is one output column of a select: a table column when Source is
+// a column position, or the value of Eval converted to Kind when Source is
+// negative.
+type Projection struct {
+	Name   string
+	Source int
+	Kind   eval.ValueKind
+	Eval   func(row *data.Tuple) (eval.Value, error)
+}
+
+// OrderKey is one ORDER BY key. Source names the table column a key reads,
+// or is -1 for a computed key. Keys compare the values returned by Eval, or
+// the stored fields of Source when Eval is nil.
+type OrderKey struct {
+	Source int
+	Eval   func(row *data.Tuple) (eval.Value, error)
+	Desc   bool
 }
 
 // NewSelectNode constructs a select node.
@@ -26,27 +74,211 @@ func NewSelectNode(parent Node, store *row.Store, columns []int, pred func(*data
 		Store:     store,
 		Columns:   columns,
 		Predicate: pred,
+		Limit:     -1,
 	}
 }
 
 // Execute runs the select node and stores results.
 func (n *SelectNode) Execute(_ *Thr) error {
-	if n == nil || n.Store == nil {
+	if n == nil || (n.Store == nil && n.Access == nil) {
 		return ErrInvalidSelectNode
 	}
 	n.Rows = nil
-	for _, row := range n.Store.Rows {
-		if row == nil {
-			continue
+	n.Values = nil
+	if n.Group != nil {
+		return n.executeGrouped()
+	}
+	sorting := len(n.Order) > 0 && !n.Sorted
+	if !sorting && n.Limit == 0 {
+		return nil
+	}
+	// Without a sort the scan stops as soon as the limit is reached.
+	var rows []*data.Tuple
+	skip := n.Offset
+	err := n.scan(func(row *data.Tuple) error {
+		if !sorting && skip > 0 {
+			skip--
+			return nil
 		}
-		if n.Predicate != nil && !n.Predicate(row) {
-			continue
+		rows = append(rows, row)
+		if !sorting && n.Limit >= 0 && len(rows) >= n.Limit {
+			return errStopScan
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errStopScan) {
+		return err
+	}
+	return n.finish(rows, sorting)
+}
+
+// scan passes the source rows of the select to fn: the matching table rows,
+// or for a join the combined rows that pass Filter. The rows of the first
+// table are read before any join so that no two scans of one table overlap.
+func (n *SelectNode) scan(fn func(*data.Tuple) error) error {
+	if len(n.Joins) == 0 {
+		return scanRows(n.Store, n.Access, n.Path, n.Predicate, fn)
+	}
+	var outer []*data.Tuple
+	if err := scanRows(n.Store, n.Access, n.Path, n.Predicate, func(r *data.Tuple) error {
+		outer = append(outer, r)
+		return nil
+	}); err != nil {
+		return err
+	}
+	for _, join := range n.Joins {
+		join.Reset()
+	}
+	for _, r := range outer {
+		if err := n.joinRows(0, widenRow(r, n.Width), fn); err != nil {
+			return err
 		}
-		n.Rows = append(n.Rows, projectRow(row, n.Columns))
 	}
 	return nil
 }
 
+func (n *SelectNode) joinRows(level int, row *data.Tuple, fn func(*data.Tuple) error) error {
+	if level == len(n.Joins) {
+		if n.Filter != nil && !n.Filter(row) {
+			return nil
+		}
+		return fn(row)
+	}
+	matches, err := n.Joins[level].Match(row)
+	if err != nil {
+		return err
+	}
+	for _, m := range matches {
+		if err := n.joinRows(level+1, m, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// executeGrouped feeds every matching row to the grouping node and finishes
+// with the group rows that pass Having.
+func (n *SelectNode) executeGrouped() error {
+	n.Group.Reset()
+	if err := n.scan(n.Group.Add); err != nil {
+		return err
+	}
+	groups, err := n.Group.Rows()
+	if err != nil {
+		return err
+	}
+	rows := groups[:0]
+	for _, r := range groups {
+		if n.Having != nil {
+			ok, err := n.Having(r)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				continue
+			}
+		}
+		rows = append(rows, r)
+	}
+	return n.finish(rows, true)
+}
+
+// finish sorts rows unless they arrive in order, applies Offset and Limit
+// when the scan has not already done so, and projects the result.
+func (n *SelectNode) finish(rows []*data.Tuple, limit bool) error {
+	var err error
+	if len(n.Order) > 0 && !n.Sorted {
+		if rows, err = sortRows(rows, n.Order); err != nil {
+			return err
+		}
+	}
+	if limit {
+		rows = limitRows(rows, n.Offset, n.Limit)
+	}
+	for _, r := range rows {
+		out, err := n.project(r)
+		if err != nil {
+			return err
+		}
+		n.Rows = append(n.Rows, out)
+	}
+	return nil
+}
+
+func (n *SelectNode) project(row *data.Tuple) (*data.Tuple, error) {
+	if len(n.Output) == 0 {
+		out := projectRow(row, n.Columns)
+		if n.KeepValues && out != nil {
+			vals := make([]eval.Value, len(out.Fields))
+			for i := range out.Fields {
+				col := i
+				if len(n.Columns) > 0 {
+					col = n.Columns[i]
+				}
+				var err error
+				if vals[i], err = n.decode(col, out.Fields[i]); err != nil {
+					return nil, err
+				}
+			}
+			n.Values = append(n.Values, vals)
+		}
+		return out, nil
+	}
+	if row == nil {
+		return nil, nil
+	}
+	out := data.NewTuple(len(n.Output))
+	var vals []eval.Value
+	if n.KeepValues {
+		vals = make([]eval.Value, len(n.Output))
+	}
+	for i, proj := range n.Output {
+		if proj.Source >= 0 {
+			if proj.Source < len(row.Fields) {
+				out.Fields[i] = row.Fields[proj.Source]
+			} else {
+				out.Fields[i].Len = data.UnivSQLNull
+			}
+			if vals != nil {
+				var err error
+				if vals[i], err = n.decode(proj.Source, out.Fields[i]); err != nil {
+					return nil, err
+				}
+			}
+			continue
+		}
+		val, err := proj.Eval(row)
+		if err != nil {
+			return nil, err
+		}
+		if val, err = coerceValue(val, proj.Kind); err != nil {
+			return nil, err
+		}
+		if vals != nil {
+			vals[i] = val
+		}
+		if n.Result == nil {
+			out.Fields[i] = eval.ValueToField(val)
+		} else if out.Fields[i], err = n.Result(val); err != nil {
+			return nil, err
+		}
+	}
+	if vals != nil {
+		n.Values = append(n.Values, vals)
+	}
+	return out, nil
+}
+
+func (n *SelectNode) decode(col int, field data.Field) (eval.Value, error) {
+	if n.Decode != nil {
+		return n.Decode(col, field)
+	}
+	if data.FieldIsNull(&field) {
+		return eval.Value{Kind: eval.KindNull}, nil
+	}
+	return eval.Value{Kind: eval.KindBytes, Bytes: field.Data}, nil
+}
+
 func projectRow(row *data.Tuple, columns []int) *data.Tuple {
 	if row == nil {
 		return nil
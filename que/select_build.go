@@ -0,0 +1,529 @@
+package que
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/wilhasse/innodb-go/data"
+	"github.com/wilhasse/innodb-go/eval"
+	"github.com/wilhasse/innodb-go/pars"
+)
+
+// selectScope resolves the expressions of a select. Plain selects evaluate
+// them on table rows; grouped selects evaluate them on group rows, with
+// aggregate calls replaced by references to the aggregate fields.
+type selectScope struct {
+	table *TableContext
+	view  *TableContext
+	group *groupBuilder
+}
+
+func newSelectScope(stmt *pars.SelectStmt, table *TableContext, node *SelectNode) (*selectScope, error) {
+	scope := &selectScope{table: table, view: table}
+	grouped := len(stmt.GroupBy) > 0 || stmt.Having != nil
+	for _, item := range stmt.Items {
+		grouped = grouped || pars.HasAggregate(item.Expr)
+	}
+	for _, item := range stmt.OrderBy {
+		grouped = grouped || pars.HasAggregate(item.Expr)
+	}
+	if !grouped {
+		return scope, nil
+	}
+	group, err := newGroupBuilder(stmt.GroupBy, table, node)
+	if err != nil {
+		return nil, err
+	}
+	scope.group, scope.view = group, group.view
+	return scope, nil
+}
+
+// column resolves a plain column reference.
+func (scope *selectScope) column(name string) (int, error) {
+	idx, ok := columnIndex(scope.table.Columns, name)
+	if !ok {
+		return -1, fmt.Errorf("que: unknown column %s", name)
+	}
+	if scope.group != nil && !scope.group.groupedColumn(idx) {
+		return -1, fmt.Errorf("que: column %s must appear in GROUP BY or an aggregate", name)
+	}
+	return idx, nil
+}
+
+// compile returns the evaluator and result kind of a computed expression.
+func (scope *selectScope) compile(expr pars.Expr) (func(*data.Tuple) (eval.Value, error), eval.ValueKind, error) {
+	if scope.group != nil {
+		var err error
+		if expr, err = scope.group.rewrite(expr); err != nil {
+			return nil, 0, err
+		}
+	}
+	fn, err := scope.view.compileExpr(expr)
+	if err != nil {
+		return nil, 0, err
+	}
+	return fn, scope.view.exprKind(expr), nil
+}
+
+// buildProjections resolves the select list into output columns. Columns
+// lists the table positions when every item is a plain column, and is nil
+// for "*" or when some item is computed.
+func (scope *selectScope) buildProjections(stmt *pars.SelectStmt) ([]Projection, []int, error) {
+	items := stmt.Items
+	if len(items) == 0 && !(len(stmt.Columns) == 1 && stmt.Columns[0] == "*") {
+		for _, name := range stmt.Columns {
+			items = append(items, pars.SelectItem{Expr: pars.IdentExpr{Name: name}})
+		}
+	}
+	if len(items) == 0 {
+		output := make([]Projection, len(scope.table.Columns))
+		for i, name := range scope.table.Columns {
+			if _, err := scope.column(name); err != nil {
+				return nil, nil, err
+			}
+			output[i] = Projection{Name: name, Source: i}
+		}
+		return output, nil, nil
+	}
+	output := make([]Projection, len(items))
+	columns := make([]int, 0, len(items))
+	for i, item := range items {
+		output[i] = Projection{Name: item.Name(), Source: -1}
+		if ident, ok := item.Expr.(pars.IdentExpr); ok {
+			idx, err := scope.column(ident.Name)
+			if err != nil {
+				return nil, nil, err
+			}
+			output[i].Source = idx
+			columns = append(columns, idx)
+			continue
+		}
+		fn, kind, err := scope.compile(item.Expr)
+		if err != nil {
+			return nil, nil, err
+		}
+		output[i].Eval, output[i].Kind = fn, kind
+	}
+	if len(columns) != len(output) {
+		columns = nil
+	}
+	return output, columns, nil
+}
+
+// buildOrder resolves ORDER BY keys. A key may name a select alias, give the
+// 1-based position of a select item, name a table column or be any
+// expression the select list allows.
+func (scope *selectScope) buildOrder(stmt *pars.SelectStmt, output []Projection) ([]OrderKey, error) {
+	keys := make([]OrderKey, 0, len(stmt.OrderBy))
+	for _, item := range stmt.OrderBy {
+		key := OrderKey{Source: -1, Desc: item.Desc}
+		switch e := item.Expr.(type) {
+		case pars.LiteralExpr:
+			pos, err := strconv.Atoi(e.Value)
+			if e.Kind != pars.TokenInt || err != nil || pos < 1 || pos > len(output) {
+				return nil, fmt.Errorf("que: invalid ORDER BY position %s", e.Value)
+			}
+			key.Source, key.Eval = output[pos-1].Source, output[pos-1].Eval
+		case pars.IdentExpr:
+			if proj, ok := aliasProjection(stmt.Items, output, e.Name); ok {
+				key.Source, key.Eval = proj.Source, proj.Eval
+				break
+			}
+			idx, err := scope.column(e.Name)
+			if err != nil {
+				return nil, err
+			}
+			key.Source = idx
+		default:
+			fn, _, err := scope.compile(item.Expr)
+			if err != nil {
+				return nil, err
+			}
+			key.Eval = fn
+		}
+		if key.Source >= 0 {
+			// Stored integers and floats do not sort by their bytes.
+			key.Eval = scope.view.columnReader(key.Source)
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// buildHaving compiles the HAVING condition over group rows.
+func (scope *selectScope) buildHaving(expr pars.Expr) (func(*data.Tuple) (bool, error), error) {
+	if expr == nil {
+		return nil, nil
+	}
+	fn, _, err := scope.compile(expr)
+	if err != nil {
+		return nil, err
+	}
+	return func(row *data.Tuple) (bool, error) {
+		val, err := fn(row)
+		if err != nil {
+			return false, err
+		}
+		return eval.ValueTrue(val)
+	}, nil
+}
+
+func aliasProjection(items []pars.SelectItem, output []Projection, name string) (Projection, bool) {
+	for i, item := range items {
+		if item.Alias != "" && strings.EqualFold(item.Alias, name) {
+			return output[i], true
+		}
+	}
+	return Projection{}, false
+}
+
+// limitValue reads a LIMIT or OFFSET operand, which must be a non-negative
+// integer literal or bound value.
+func limitValue(expr pars.Expr, def int) (int, error) {
+	if expr == nil {
+		return def, nil
+	}
+	lit, ok := expr.(pars.LiteralExpr)
+	if !ok || lit.Kind != pars.TokenInt {
+		return 0, fmt.Errorf("que: LIMIT and OFFSET take integers")
+	}
+	n, err := strconv.Atoi(lit.Value)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("que: invalid LIMIT or OFFSET %s", lit.Value)
+	}
+	return n, nil
+}
+
+// planSortedScan skips the sort when the rows can be read in ORDER BY order:
+// either the planned path already returns them that way, or a full scan can
+// be replaced by a walk of an index whose key starts with the sort columns.
+// Only ascending keys on table columns whose stored values sort in value
+// order qualify.
+func planSortedScan(node *SelectNode, table *TableContext) {
+	if len(node.Order) == 0 || node.Group != nil {
+		return
+	}
+	if node.Access != nil {
+		if _, ok := node.Access.(IndexAccess); !ok {
+			return
+		}
+	}
+	cols := make([]int, len(node.Order))
+	for i, key := range node.Order {
+		if key.Source < 0 || key.Desc || !table.ordered(key.Source) {
+			return
+		}
+		cols[i] = key.Source
+	}
+	if node.Path.orderedBy(cols) {
+		node.Sorted = true
+		return
+	}
+	if node.Path == nil || node.Path.Kind == AccessFullScan {
+		if path := table.planOrder(cols); path != nil {
+			node.Path, node.Sorted = path, true
+		}
+	}
+}
+
+// compileExpr checks a computed expression against the table and returns a
+// function evaluating it on a row.
+func (table *TableContext) compileExpr(expr pars.Expr) (func(*data.Tuple) (eval.Value, error), error) {
+	if err := validateComputed(expr, table); err != nil {
+		return nil, err
+	}
+	return func(row *data.Tuple) (eval.Value, error) {
+		return eval.EvalExprWith(expr, func(name string) (eval.Value, error) {
+			return table.columnValue(row, name)
+		})
+	}, nil
+}
+
+// columnReader returns a function reading the decoded value of column col.
+func (table *TableContext) columnReader(col int) func(*data.Tuple) (eval.Value, error) {
+	return func(row *data.Tuple) (eval.Value, error) {
+		field := data.Field{Len: data.UnivSQLNull}
+		if row != nil && col < len(row.Fields) {
+			field = row.Fields[col]
+		}
+		return table.decodeColumn(col, field)
+	}
+}
+
+// columnValue returns the decoded value of the column name of row.
+func (table *TableContext) columnValue(row *data.Tuple, name string) (eval.Value, error) {
+	idx, ok := columnIndex(table.Columns, name)
+	if !ok || row == nil || idx >= len(row.Fields) {
+		return eval.Value{}, fmt.Errorf("que: unknown column %s", name)
+	}
+	return table.decodeColumn(idx, row.Fields[idx])
+}
+
+func (table *TableContext) decodeColumn(col int, field data.Field) (eval.Value, error) {
+	if table.Decode != nil {
+		return table.Decode(col, field)
+	}
+	if data.FieldIsNull(&field) {
+		return eval.Value{Kind: eval.KindNull}, nil
+	}
+	return eval.Value{Kind: eval.KindBytes, Bytes: field.Data}, nil
+}
+
+// compareFields compares two stored values of column col by the values they
+// decode to, or by their bytes when they cannot be decoded.
+func (table *TableContext) compareFields(col int, a, b *data.Field) int {
+	av, errA := table.decodeColumn(col, *a)
+	bv, errB := table.decodeColumn(col, *b)
+	if errA == nil && errB == nil {
+		if cmp, err := compareValues(av, bv); err == nil {
+			return cmp
+		}
+	}
+	return data.CompareFields(a, b)
+}
+
+func (table *TableContext) ordered(col int) bool {
+	return table.Ordered == nil || col < 0 || col >= len(table.Ordered) || table.Ordered[col]
+}
+
+func (table *TableContext) columnKind(col int) eval.ValueKind {
+	if col >= 0 && col < len(table.Kinds) {
+		return table.Kinds[col]
+	}
+	return eval.KindBytes
+}
+
+func validateComputed(expr pars.Expr, table *TableContext) error {
+	switch e := expr.(type) {
+	case pars.IdentExpr:
+		if _, ok := columnIndex(table.Columns, e.Name); !ok {
+			return fmt.Errorf("que: unknown column %s", e.Name)
+		}
+		return nil
+	case pars.LiteralExpr:
+		return nil
+	case pars.UnaryExpr:
+		return validateComputed(e.Expr, table)
+	case pars.BinaryExpr:
+		if err := validateComputed(e.Left, table); err != nil {
+			return err
+		}
+		return validateComputed(e.Right, table)
+	case pars.FuncExpr:
+		minArgs, maxArgs := 0, 0
+		if pars.IsAggregate(e.Name) {
+			return fmt.Errorf("que: aggregate %s is not allowed here", e.Name)
+		}
+		switch e.Name {
+		case "SUBSTR", "SUBSTRING":
+			minArgs, maxArgs = 2, 3
+		case "CONCAT":
+			minArgs, maxArgs = 1, len(e.Args)
+		case "INSTR":
+			minArgs, maxArgs = 2, 2
+		default:
+			return fmt.Errorf("que: unknown function %s", e.Name)
+		}
+		if len(e.Args) < minArgs || len(e.Args) > maxArgs {
+			return fmt.Errorf("que: wrong number of arguments to %s", e.Name)
+		}
+		for _, arg := range e.Args {
+			if err := validateComputed(arg, table); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("que: unsupported expression")
+	}
+}
+
+// exprKind returns the kind of value a computed expression produces:
+// arithmetic yields a float when an operand is a float and an integer
+// otherwise, comparisons and INSTR yield integers and string functions bytes.
+func (table *TableContext) exprKind(expr pars.Expr) eval.ValueKind {
+	switch e := expr.(type) {
+	case pars.LiteralExpr:
+		if e.Kind == pars.TokenInt {
+			return eval.KindInt
+		}
+		return eval.KindBytes
+	case pars.IdentExpr:
+		idx, _ := columnIndex(table.Columns, e.Name)
+		return table.columnKind(idx)
+	case pars.UnaryExpr:
+		if table.exprKind(e.Expr) == eval.KindFloat {
+			return eval.KindFloat
+		}
+		return eval.KindInt
+	case pars.BinaryExpr:
+		if pars.IsArithmetic(e.Op) && (table.exprKind(e.Left) == eval.KindFloat || table.exprKind(e.Right) == eval.KindFloat) {
+			return eval.KindFloat
+		}
+		return eval.KindInt
+	case pars.FuncExpr:
+		if e.Name == "INSTR" {
+			return eval.KindInt
+		}
+		return eval.KindBytes
+	default:
+		return eval.KindBytes
+	}
+}
+
+// groupBuilder collects the group keys and aggregate calls of a grouped
+// select. view describes the group rows: the table columns followed by one
+// column per aggregate.
+type groupBuilder struct {
+	table *TableContext
+	keys  []pars.Expr
+	calls []pars.FuncExpr
+	node  *GroupNode
+	view  *TableContext
+}
+
+func newGroupBuilder(keys []pars.Expr, table *TableContext, node *SelectNode) (*groupBuilder, error) {
+	width := len(table.Columns)
+	b := &groupBuilder{table: table, keys: keys, node: NewGroupNode(node, width)}
+	for _, key := range keys {
+		if pars.HasAggregate(key) {
+			return nil, fmt.Errorf("que: aggregate in GROUP BY")
+		}
+		fn, err := table.compileExpr(key)
+		if err != nil {
+			return nil, err
+		}
+		b.node.Keys = append(b.node.Keys, fn)
+	}
+	view := &TableContext{
+		Store:   table.Store,
+		Columns: append([]string(nil), table.Columns...),
+		Kinds:   make([]eval.ValueKind, width),
+		Result:  table.Result,
+	}
+	for i := range view.Kinds {
+		view.Kinds[i] = table.columnKind(i)
+	}
+	view.Decode = func(col int, field data.Field) (eval.Value, error) {
+		if col < width {
+			return table.decodeColumn(col, field)
+		}
+		return slotValue(field, view.columnKind(col)), nil
+	}
+	b.view = view
+	node.Group = b.node
+	return b, nil
+}
+
+// groupedColumn reports whether column col is a GROUP BY key.
+func (b *groupBuilder) groupedColumn(col int) bool {
+	for _, key := range b.keys {
+		if ident, ok := key.(pars.IdentExpr); ok {
+			if idx, ok := columnIndex(b.table.Columns, ident.Name); ok && idx == col {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// rewrite replaces the aggregate calls of expr with references to their
+// group row columns and checks that every other column reference is grouped.
+// A subexpression equal to a GROUP BY key is kept as is, since it has the
+// same value on every row of the group.
+func (b *groupBuilder) rewrite(expr pars.Expr) (pars.Expr, error) {
+	for _, key := range b.keys {
+		if reflect.DeepEqual(key, expr) {
+			return expr, nil
+		}
+	}
+	switch e := expr.(type) {
+	case pars.IdentExpr:
+		idx, ok := columnIndex(b.table.Columns, e.Name)
+		if !ok {
+			return nil, fmt.Errorf("que: unknown column %s", e.Name)
+		}
+		if !b.groupedColumn(idx) {
+			return nil, fmt.Errorf("que: column %s must appear in GROUP BY or an aggregate", e.Name)
+		}
+		return expr, nil
+	case pars.UnaryExpr:
+		inner, err := b.rewrite(e.Expr)
+		if err != nil {
+			return nil, err
+		}
+		return pars.UnaryExpr{Op: e.Op, Expr: inner}, nil
+	case pars.BinaryExpr:
+		left, err := b.rewrite(e.Left)
+		if err != nil {
+			return nil, err
+		}
+		right, err := b.rewrite(e.Right)
+		if err != nil {
+			return nil, err
+		}
+		return pars.BinaryExpr{Op: e.Op, Left: left, Right: right}, nil
+	case pars.FuncExpr:
+		if pars.IsAggregate(e.Name) {
+			return b.aggregate(e)
+		}
+		out := pars.FuncExpr{Name: e.Name, Star: e.Star, Args: make([]pars.Expr, len(e.Args))}
+		for i, arg := range e.Args {
+			var err error
+			if out.Args[i], err = b.rewrite(arg); err != nil {
+				return nil, err
+			}
+		}
+		return out, nil
+	default:
+		return expr, nil
+	}
+}
+
+// aggregate registers an aggregate call, sharing the column of an identical
+// earlier call, and returns the reference to its group row column.
+func (b *groupBuilder) aggregate(fn pars.FuncExpr) (pars.Expr, error) {
+	width := len(b.table.Columns)
+	for i, call := range b.calls {
+		if reflect.DeepEqual(call, fn) {
+			return pars.IdentExpr{Name: b.view.Columns[width+i]}, nil
+		}
+	}
+	if !fn.Star && len(fn.Args) != 1 {
+		return nil, fmt.Errorf("que: %s takes one argument", fn.Name)
+	}
+	call := AggregateCall{Name: fn.Name, Star: fn.Star}
+	var argKind eval.ValueKind
+	if !fn.Star {
+		if pars.HasAggregate(fn.Args[0]) {
+			return nil, fmt.Errorf("que: nested aggregate in %s", fn.Name)
+		}
+		arg, err := b.table.compileExpr(fn.Args[0])
+		if err != nil {
+			return nil, err
+		}
+		call.Arg = arg
+		argKind = b.table.exprKind(fn.Args[0])
+	}
+	switch fn.Name {
+	case "COUNT":
+		call.Kind = eval.KindInt
+	case "AVG":
+		call.Kind = eval.KindFloat
+	case "SUM":
+		call.Kind = eval.KindInt
+		if argKind == eval.KindFloat {
+			call.Kind = eval.KindFloat
+		}
+	default:
+		call.Kind = argKind
+	}
+	name := "#agg" + strconv.Itoa(len(b.calls))
+	b.calls = append(b.calls, fn)
+	b.node.Aggregates = append(b.node.Aggregates, call)
+	b.view.Columns = append(b.view.Columns, name)
+	b.view.Kinds = append(b.view.Kinds, call.Kind)
+	return pars.IdentExpr{Name: name}, nil
+}
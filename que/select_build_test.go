@@ -0,0 +1,69 @@
+package que
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/wilhasse/innodb-go/pars"
+)
+
+func TestSelectOrderLimit(t *testing.T) {
+	ctx := newIndexedTestContext(t)
+	tests := []struct {
+		sql    string
+		ids    string
+		sorted bool
+	}{
+		{"SELECT id, name FROM t ORDER BY name DESC, id", "15243", false},
+		{"SELECT * FROM t ORDER BY name LIMIT 2 OFFSET 1", "24", true},
+		{"SELECT id FROM t WHERE id > 1 ORDER BY id LIMIT 3", "234", true},
+		{"SELECT id FROM t WHERE name = 'b' ORDER BY id DESC", "42", false},
+		{"SELECT id FROM t ORDER BY 1 DESC LIMIT 1, 2", "43", false},
+		{"SELECT id FROM t LIMIT 0", "", false},
+	}
+	for _, tt := range tests {
+		node, ids := runPlannedSelect(t, ctx, tt.sql)
+		if got := strings.Join(ids, ""); got != tt.ids {
+			t.Fatalf("%s: rows=%q want %q", tt.sql, got, tt.ids)
+		}
+		if node.Sorted != tt.sorted {
+			t.Fatalf("%s: sorted=%v path=%+v", tt.sql, node.Sorted, node.Path)
+		}
+	}
+	node, _ := runPlannedSelect(t, ctx, "SELECT * FROM t ORDER BY name")
+	if node.Path.Kind != AccessIndexScan || node.Path.Index.Name != "idx_name" {
+		t.Fatalf("path=%+v", node.Path)
+	}
+}
+
+func TestSelectComputedProjection(t *testing.T) {
+	ctx := newIndexedTestContext(t)
+	node, ids := runPlannedSelect(t, ctx, "SELECT id * 10 + 1 AS x, CONCAT(name, '-', id), SUBSTR('abc', id) FROM t WHERE id >= 4 ORDER BY x DESC")
+	if strings.Join(ids, ",") != "51,41" {
+		t.Fatalf("ids=%v", ids)
+	}
+	if node.Output[0].Name != "x" || node.Output[1].Name != "CONCAT(name, '-', id)" {
+		t.Fatalf("output=%+v", node.Output)
+	}
+	if got := string(node.Rows[0].Fields[1].Data); got != "c-5" {
+		t.Fatalf("concat=%q", got)
+	}
+	if got := node.Rows[1].Fields[2]; got.Len != 0 {
+		t.Fatalf("substr past end=%q", got.Data)
+	}
+
+	for _, sql := range []string{
+		"SELECT id FROM t ORDER BY 2",
+		"SELECT id FROM t LIMIT 'a'",
+		"SELECT UPPER(name) FROM t",
+		"SELECT missing + 1 FROM t",
+	} {
+		stmt, err := pars.ParseSQL(sql)
+		if err != nil {
+			t.Fatalf("parse %q: %v", sql, err)
+		}
+		if _, err := BuildGraph(stmt, ctx); err == nil {
+			t.Fatalf("%s: expected build error", sql)
+		}
+	}
+}
@@ -15,11 +15,38 @@ import (
 type TableContext struct {
 	Store   *row.Store
 	Columns []string
+	// Access, when set, performs the row operations of nodes built for
+	// this table.
+	Access RowAccess
+	// Encode, when set, converts literals into column values; otherwise
+	// literals are stored as their text.
+	Encode FieldEncoder
+	// Indexes lists the indexes the planner may use. When nil they are
+	// taken from Store.
+	Indexes []IndexInfo
+	// Decode, when set, converts column values read by computed select
+	// expressions; otherwise columns are read as their bytes.
+	Decode FieldDecoder
+	// Kinds gives the kind of value Decode returns for each column; when
+	// nil every column is read as bytes.
+	Kinds []eval.ValueKind
+	// Ordered tells, per column, whether stored values sort in value
+	// order, which index ranges and index order rely on; when nil every
+	// column does.
+	Ordered []bool
+	// Result, when set, stores the values of computed select expressions;
+	// otherwise they are stored as their text.
+	Result ValueEncoder
 }
 
 // BuildContext maps table names to stores and columns.
 type BuildContext struct {
 	Tables map[string]*TableContext
+	// Params, when set, holds the literals of a statement parsed by
+	// pars.ParsePrepared. The graph keeps it as its Info and reads the
+	// literal values from it each time it runs, so they can be rebound
+	// without building the graph again.
+	Params *pars.Info
 }
 
 var (
@@ -41,6 +68,13 @@ func BuildGraph(stmt pars.Statement, ctx *BuildContext) (*Fork, error) {
 	}
 	graph := ForkCreate(nil, nil, ForkExecute)
 	thr := ThrCreate(graph)
+	if ctx.Params != nil && pars.HasParams(stmt) {
+		if err := buildParamNode(stmt, thr, ctx); err != nil {
+			return nil, err
+		}
+		graph.Info = ctx.Params
+		return graph, nil
+	}
 	node, err := buildNode(stmt, thr, ctx)
 	if err != nil {
 		return nil, err
@@ -59,6 +93,10 @@ func buildNode(stmt pars.Statement, parent Node, ctx *BuildContext) (Node, error
 		return buildDeleteNode(st, parent, ctx)
 	case *pars.SelectStmt:
 		return buildSelectNode(st, parent, ctx)
+	case *pars.ProcedureStmt:
+		return buildProcedureNode(st, parent, ctx)
+	case *pars.ExplainStmt:
+		return buildExplainNode(st, parent, ctx)
 	default:
 		return nil, ErrInvalidStatement
 	}
@@ -76,7 +114,9 @@ func buildInsertNode(stmt *pars.InsertStmt, parent Node, ctx *BuildContext) (Nod
 	if err != nil {
 		return nil, err
 	}
-	return NewInsertNode(parent, table.Store, tuple), nil
+	node := NewInsertNode(parent, table.Store, tuple)
+	node.Access = table.Access
+	return node, nil
 }
 
 func buildUpdateNode(stmt *pars.UpdateStmt, parent Node, ctx *BuildContext) (Node, error) {
@@ -84,14 +124,25 @@ func buildUpdateNode(stmt *pars.UpdateStmt, parent Node, ctx *BuildContext) (Nod
 	if err != nil {
 		return nil, err
 	}
-	if stmt.Where == nil {
+	if stmt.Where == nil && table.Access == nil {
 		return nil, ErrMissingWhere
 	}
-	pred, err := buildPredicate(stmt.Where, table)
+	pred, path, err := buildFilter(stmt.Where, table)
 	if err != nil {
 		return nil, err
 	}
-	target := findRow(table.Store, pred)
+	if table.Access != nil {
+		if err := validateAssignments(stmt.Assignments, table); err != nil {
+			return nil, err
+		}
+		node := NewUpdateNode(parent, table.Store, nil, nil)
+		node.Access = table.Access
+		node.Predicate = pred
+		node.Path = path
+		node.Assign = assignFunc(stmt.Assignments, table)
+		return node, nil
+	}
+	target := findRow(table.Store, path, pred)
 	if target == nil {
 		return nil, ErrRowNotFound
 	}
@@ -107,14 +158,21 @@ func buildDeleteNode(stmt *pars.DeleteStmt, parent Node, ctx *BuildContext) (Nod
 	if err != nil {
 		return nil, err
 	}
-	if stmt.Where == nil {
+	if stmt.Where == nil && table.Access == nil {
 		return nil, ErrMissingWhere
 	}
-	pred, err := buildPredicate(stmt.Where, table)
+	pred, path, err := buildFilter(stmt.Where, table)
 	if err != nil {
 		return nil, err
 	}
-	target := findRow(table.Store, pred)
+	if table.Access != nil {
+		node := NewDeleteNode(parent, table.Store, nil)
+		node.Access = table.Access
+		node.Predicate = pred
+		node.Path = path
+		return node, nil
+	}
+	target := findRow(table.Store, path, pred)
 	if target == nil {
 		return nil, ErrRowNotFound
 	}
@@ -122,22 +180,53 @@ func buildDeleteNode(stmt *pars.DeleteStmt, parent Node, ctx *BuildContext) (Nod
 }
 
 func buildSelectNode(stmt *pars.SelectStmt, parent Node, ctx *BuildContext) (Node, error) {
-	table, err := tableContext(ctx, stmt.Table)
-	if err != nil {
-		return nil, err
+	if len(stmt.Into) > 0 {
+		return nil, fmt.Errorf("que: SELECT INTO outside a procedure")
 	}
-	columns, err := mapColumns(stmt.Columns, table)
+	table, err := tableContext(ctx, stmt.Table)
 	if err != nil {
 		return nil, err
 	}
-	var pred func(*data.Tuple) bool
-	if stmt.Where != nil {
-		pred, err = buildPredicate(stmt.Where, table)
+	var node *SelectNode
+	if len(stmt.Joins) > 0 {
+		if node, table, stmt, err = buildJoins(stmt, parent, ctx, table); err != nil {
+			return nil, err
+		}
+	} else {
+		if stmt, err = resolveSelectNames(stmt, singleTableResolver(stmt, table)); err != nil {
+			return nil, err
+		}
+		pred, path, err := buildFilter(stmt.Where, table)
 		if err != nil {
 			return nil, err
 		}
+		node = NewSelectNode(parent, table.Store, nil, pred)
+		node.Access = table.Access
+		node.Path = path
+	}
+	node.Result = table.Result
+	node.Decode = table.decodeColumn
+	scope, err := newSelectScope(stmt, table, node)
+	if err != nil {
+		return nil, err
+	}
+	if node.Output, node.Columns, err = scope.buildProjections(stmt); err != nil {
+		return nil, err
+	}
+	if node.Having, err = scope.buildHaving(stmt.Having); err != nil {
+		return nil, err
+	}
+	if node.Order, err = scope.buildOrder(stmt, node.Output); err != nil {
+		return nil, err
+	}
+	if node.Limit, err = limitValue(stmt.Limit, -1); err != nil {
+		return nil, err
+	}
+	if node.Offset, err = limitValue(stmt.Offset, 0); err != nil {
+		return nil, err
 	}
-	return NewSelectNode(parent, table.Store, columns, pred), nil
+	planSortedScan(node, table)
+	return node, nil
 }
 
 func tableContext(ctx *BuildContext, name string) (*TableContext, error) {
@@ -168,7 +257,7 @@ func buildInsertTuple(stmt *pars.InsertStmt, table *TableContext) (*data.Tuple,
 		if !ok {
 			return nil, fmt.Errorf("que: unknown column %s", col)
 		}
-		field, err := fieldFromExpr(stmt.Values[i])
+		field, err := table.fieldFromExpr(idx, stmt.Values[i])
 		if err != nil {
 			return nil, err
 		}
@@ -177,6 +266,15 @@ func buildInsertTuple(stmt *pars.InsertStmt, table *TableContext) (*data.Tuple,
 	return tuple, nil
 }
 
+// assignFunc returns the Assign function of an update node that applies
+// assigns to a copy of each row.
+func assignFunc(assigns []pars.Assignment, table *TableContext) func(*data.Tuple) (*data.Tuple, error) {
+	return func(old *data.Tuple) (*data.Tuple, error) {
+		next := row.CopyRow(old, row.CopyData)
+		return next, applyAssignments(next, assigns, table)
+	}
+}
+
 func applyAssignments(tuple *data.Tuple, assigns []pars.Assignment, table *TableContext) error {
 	if tuple == nil {
 		return ErrRowNotFound
@@ -186,7 +284,7 @@ func applyAssignments(tuple *data.Tuple, assigns []pars.Assignment, table *Table
 		if !ok {
 			return fmt.Errorf("que: unknown column %s", assign.Column)
 		}
-		field, err := fieldFromExpr(assign.Value)
+		field, err := table.fieldFromExpr(idx, assign.Value)
 		if err != nil {
 			return err
 		}
@@ -197,17 +295,151 @@ func applyAssignments(tuple *data.Tuple, assigns []pars.Assignment, table *Table
 	return nil
 }
 
-func buildPredicate(expr pars.Expr, table *TableContext) (func(*data.Tuple) bool, error) {
+// buildFilter compiles a WHERE clause into a row predicate and plans the
+// access path that reads the candidate rows.
+func buildFilter(expr pars.Expr, table *TableContext) (func(*data.Tuple) bool, *AccessPath, error) {
 	if expr == nil {
-		return nil, nil
+		return nil, &AccessPath{Kind: AccessFullScan}, nil
 	}
+	pred, expr, err := compileFilter(expr, table)
+	if err != nil {
+		return nil, nil, err
+	}
+	return pred, table.planAccess(expr), nil
+}
+
+// compileFilter checks a condition against the table and returns it as a
+// row predicate, along with the condition with its literals encoded. The
+// predicate compares decoded column values, since stored integers and
+// floats do not sort by their bytes.
+func compileFilter(expr pars.Expr, table *TableContext) (func(*data.Tuple) bool, pars.Expr, error) {
 	if err := validateExpr(expr, table); err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+	encoded, err := encodePredicate(expr, table)
+	if err != nil {
+		return nil, nil, err
 	}
-	return func(row *data.Tuple) bool {
-		ok, err := eval.EvalBool(expr, row, table.Columns)
+	values, err := mapComparedLiterals(expr, table, table.literalValue)
+	if err != nil {
+		return nil, nil, err
+	}
+	pred := func(row *data.Tuple) bool {
+		val, err := eval.EvalExprWith(values, func(name string) (eval.Value, error) {
+			return table.columnValue(row, name)
+		})
+		if err != nil {
+			return false
+		}
+		ok, err := eval.ValueTrue(val)
 		return err == nil && ok
-	}, nil
+	}
+	return pred, encoded, nil
+}
+
+func validateAssignments(assigns []pars.Assignment, table *TableContext) error {
+	for _, assign := range assigns {
+		if _, ok := columnIndex(table.Columns, assign.Column); !ok {
+			return fmt.Errorf("que: unknown column %s", assign.Column)
+		}
+	}
+	return nil
+}
+
+func (table *TableContext) fieldFromExpr(col int, expr pars.Expr) (data.Field, error) {
+	if table == nil || table.Encode == nil {
+		return fieldFromExpr(expr)
+	}
+	lit, ok := expr.(pars.LiteralExpr)
+	if !ok {
+		return data.Field{}, fmt.Errorf("que: expected literal")
+	}
+	return table.Encode(col, lit)
+}
+
+// encodePredicate rewrites literals compared with a column into the column's
+// stored form, the form index bounds are built from.
+func encodePredicate(expr pars.Expr, table *TableContext) (pars.Expr, error) {
+	if table == nil || table.Encode == nil {
+		return expr, nil
+	}
+	return mapComparedLiterals(expr, table, func(col int, lit pars.LiteralExpr) (pars.Expr, error) {
+		field, err := table.Encode(col, lit)
+		if err != nil {
+			return nil, err
+		}
+		if data.FieldIsNull(&field) {
+			return pars.LiteralExpr{Kind: pars.TokenNull}, nil
+		}
+		return pars.LiteralExpr{Value: string(field.Data), Kind: pars.TokenString}, nil
+	})
+}
+
+// literalValue rewrites a literal compared with column col into the text of
+// the value the column decodes to, so that it compares with the decoded
+// column as that value.
+func (table *TableContext) literalValue(col int, lit pars.LiteralExpr) (pars.Expr, error) {
+	if table.Encode == nil {
+		return lit, nil
+	}
+	field, err := table.Encode(col, lit)
+	if err != nil {
+		return nil, err
+	}
+	val, err := table.decodeColumn(col, field)
+	if err != nil {
+		return nil, err
+	}
+	field = eval.ValueToField(val)
+	switch {
+	case data.FieldIsNull(&field):
+		return pars.LiteralExpr{Kind: pars.TokenNull}, nil
+	case val.Kind == eval.KindInt:
+		return pars.LiteralExpr{Value: string(field.Data), Kind: pars.TokenInt}, nil
+	default:
+		return pars.LiteralExpr{Value: string(field.Data), Kind: pars.TokenString}, nil
+	}
+}
+
+// mapComparedLiterals rewrites, through fn, every literal compared with a
+// column in a condition.
+func mapComparedLiterals(expr pars.Expr, table *TableContext, fn func(col int, lit pars.LiteralExpr) (pars.Expr, error)) (pars.Expr, error) {
+	bin, ok := expr.(pars.BinaryExpr)
+	if !ok {
+		return expr, nil
+	}
+	if !pars.IsComparison(bin.Op) {
+		left, err := mapComparedLiterals(bin.Left, table, fn)
+		if err != nil {
+			return nil, err
+		}
+		right, err := mapComparedLiterals(bin.Right, table, fn)
+		if err != nil {
+			return nil, err
+		}
+		return pars.BinaryExpr{Op: bin.Op, Left: left, Right: right}, nil
+	}
+	mapLiteral := func(ident, other pars.Expr) (pars.Expr, error) {
+		id, ok := ident.(pars.IdentExpr)
+		if !ok {
+			return other, nil
+		}
+		lit, ok := other.(pars.LiteralExpr)
+		if !ok {
+			return other, nil
+		}
+		idx, _ := columnIndex(table.Columns, id.Name)
+		return fn(idx, lit)
+	}
+	right, err := mapLiteral(bin.Left, bin.Right)
+	if err != nil {
+		return nil, err
+	}
+	left, err := mapLiteral(bin.Right, bin.Left)
+	if err != nil {
+		return nil, err
+	}
+	return pars.BinaryExpr{Op: bin.Op, Left: left, Right: right}, nil
 }
 
 func fieldFromExpr(expr pars.Expr) (data.Field, error) {
@@ -226,21 +458,6 @@ func fieldFromExpr(expr pars.Expr) (data.Field, error) {
 	}
 }
 
-func mapColumns(cols []string, table *TableContext) ([]int, error) {
-	if len(cols) == 0 || (len(cols) == 1 && cols[0] == "*") {
-		return nil, nil
-	}
-	indices := make([]int, len(cols))
-	for i, col := range cols {
-		idx, ok := columnIndex(table.Columns, col)
-		if !ok {
-			return nil, fmt.Errorf("que: unknown column %s", col)
-		}
-		indices[i] = idx
-	}
-	return indices, nil
-}
-
 func columnIndex(columns []string, name string) (int, bool) {
 	for i, col := range columns {
 		if strings.EqualFold(col, name) {
@@ -253,15 +470,13 @@ func columnIndex(columns []string, name string) (int, bool) {
 func validateExpr(expr pars.Expr, table *TableContext) error {
 	switch e := expr.(type) {
 	case pars.BinaryExpr:
-		switch e.Op {
-		case pars.TokenAnd, pars.TokenOr, pars.TokenEq:
-			if err := validateExpr(e.Left, table); err != nil {
-				return err
-			}
-			return validateExpr(e.Right, table)
-		default:
+		if e.Op != pars.TokenAnd && e.Op != pars.TokenOr && !pars.IsComparison(e.Op) {
 			return fmt.Errorf("que: unsupported predicate op %v", e.Op)
 		}
+		if err := validateExpr(e.Left, table); err != nil {
+			return err
+		}
+		return validateExpr(e.Right, table)
 	case pars.IdentExpr:
 		if _, ok := columnIndex(table.Columns, e.Name); !ok {
 			return fmt.Errorf("que: unknown column %s", e.Name)
@@ -274,17 +489,14 @@ func validateExpr(expr pars.Expr, table *TableContext) error {
 	}
 }
 
-func findRow(store *row.Store, pred func(*data.Tuple) bool) *data.Tuple {
+func findRow(store *row.Store, path *AccessPath, pred func(*data.Tuple) bool) *data.Tuple {
 	if store == nil || pred == nil {
 		return nil
 	}
-	for _, row := range store.Rows {
-		if row == nil {
-			continue
-		}
-		if pred(row) {
-			return row
-		}
-	}
-	return nil
+	var found *data.Tuple
+	_ = scanRows(store, nil, path, pred, func(r *data.Tuple) error {
+		found = r
+		return errStopScan
+	})
+	return found
 }
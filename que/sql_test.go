@@ -109,6 +109,37 @@ func TestBuildGraphSelect(t *testing.T) {
 	}
 }
 
+func TestBuildGraphParams(t *testing.T) {
+	ctx, store := newTestContext()
+	info := pars.NewInfo()
+	stmt, err := pars.ParsePrepared(info, "INSERT INTO t (id,name) VALUES (:id,:name)")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	info.AddLiteral("id", []byte{1}, pars.LiteralInt, true)
+	info.AddLiteral("name", []byte("a"), pars.LiteralString, false)
+	ctx.Params = info
+	graph, err := BuildGraph(stmt, ctx)
+	if err != nil {
+		t.Fatalf("BuildGraph: %v", err)
+	}
+	if err := ForkRun(graph); err != nil {
+		t.Fatalf("ForkRun: %v", err)
+	}
+	info.AddLiteral("id", []byte{2}, pars.LiteralInt, true)
+	info.AddLiteral("name", []byte("b"), pars.LiteralString, false)
+	ForkStartCommand(graph)
+	if err := ForkRun(graph); err != nil {
+		t.Fatalf("ForkRun rebound: %v", err)
+	}
+	if len(store.Rows) != 2 {
+		t.Fatalf("rows=%d", len(store.Rows))
+	}
+	if got := string(store.Rows[1].Fields[1].Data); got != "b" {
+		t.Fatalf("rebound name=%s", got)
+	}
+}
+
 func newTestContext() (*BuildContext, *row.Store) {
 	store := row.NewStore(0)
 	ctx := &BuildContext{
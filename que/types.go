@@ -145,6 +145,9 @@ type Thr struct {
 	PrevNode  Node
 	Resource  uint64
 	LockState LockState
+	// bind, when set, refreshes Child from the graph's Info before each
+	// run; see BuildContext.Params.
+	bind func(info *pars.Info) error
 }
 
 // Session stores graphs published for a session.
@@ -62,7 +62,7 @@ func DecodeVar(rec []byte, nFields int, extra int) (*data.Tuple, error) {
 			fields[i].Len = data.UnivSQLNull
 			continue
 		}
-		if nullFlag != 0 {
+		if nullFlag != 0 && nullFlag != VarFlagExtern {
 			return nil, errors.New("rec: invalid null flag")
 		}
 		if pos+length > len(rec) {
@@ -71,7 +71,7 @@ func DecodeVar(rec []byte, nFields int, extra int) (*data.Tuple, error) {
 		dataBytes := make([]byte, length)
 		copy(dataBytes, rec[pos:pos+length])
 		pos += length
-		fields[i] = data.Field{Data: dataBytes, Len: uint32(length)}
+		fields[i] = data.Field{Data: dataBytes, Len: uint32(length), Ext: nullFlag == VarFlagExtern}
 	}
 	return &data.Tuple{
 		NFields:    len(fields),
@@ -83,3 +83,28 @@ func TestDecodeVarPrefix(t *testing.T) {
 		t.Fatalf("field 0 data=%q", dec.Fields[0].Data)
 	}
 }
+
+func TestDecodeVarExtern(t *testing.T) {
+	ref := []byte("reference")
+	tpl := &data.Tuple{Fields: []data.Field{
+		{Data: ref, Len: uint32(len(ref)), Ext: true},
+		{Data: []byte("inline"), Len: 6},
+	}}
+	enc, err := EncodeVar(tpl, []int{4, 4}, 0)
+	if err != nil {
+		t.Fatalf("EncodeVar: %v", err)
+	}
+	if enc[0] != VarFlagExtern {
+		t.Fatalf("flag=%d, want %d", enc[0], VarFlagExtern)
+	}
+	dec, err := DecodeVar(enc, len(tpl.Fields), 0)
+	if err != nil {
+		t.Fatalf("DecodeVar: %v", err)
+	}
+	if !dec.Fields[0].Ext || !bytes.Equal(dec.Fields[0].Data, ref) {
+		t.Fatalf("field 0 ext=%v data=%q", dec.Fields[0].Ext, dec.Fields[0].Data)
+	}
+	if dec.Fields[1].Ext || string(dec.Fields[1].Data) != "inli" {
+		t.Fatalf("field 1 ext=%v data=%q", dec.Fields[1].Ext, dec.Fields[1].Data)
+	}
+}
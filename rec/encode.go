@@ -53,8 +53,14 @@ func EncodeFixed(tuple *data.Tuple, lengths []int, extra int) ([]byte, error) {
 	return buf, nil
 }
 
+// VarFlagExtern is the EncodeVar flag of an externally stored field, whose
+// data is the reference to its off-page storage.
+const VarFlagExtern byte = 2
+
 // EncodeVar encodes a tuple for variable-length fields with NULL flags.
 // Each field is encoded as: nullFlag (1 byte), length (2 bytes), data.
+// Externally stored fields are flagged with VarFlagExtern and keep their
+// whole reference regardless of prefixes.
 func EncodeVar(tuple *data.Tuple, prefixes []int, extra int) ([]byte, error) {
 	if tuple == nil {
 		return nil, errors.New("rec: nil tuple")
@@ -77,10 +83,13 @@ func EncodeVar(tuple *data.Tuple, prefixes []int, extra int) ([]byte, error) {
 		if int(field.Len) < len(dataBytes) {
 			dataBytes = dataBytes[:field.Len]
 		}
-		if prefix > 0 && len(dataBytes) > prefix {
+		flag := byte(0)
+		if field.Ext {
+			flag = VarFlagExtern
+		} else if prefix > 0 && len(dataBytes) > prefix {
 			dataBytes = dataBytes[:prefix]
 		}
-		buf = append(buf, 0)
+		buf = append(buf, flag)
 		buf = appendUint16(buf, len(dataBytes))
 		buf = append(buf, dataBytes...)
 	}
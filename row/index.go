@@ -109,6 +109,7 @@ func (store *Store) rebuildIndex() {
 	if store == nil {
 		return
 	}
+	store.abortOnlineBuilds()
 	store.Tree = btr.NewTree(storeTreeOrder, CompareKeys)
 	if store.SecondaryIndexes != nil {
 		for _, idx := range store.SecondaryIndexes {
@@ -177,6 +178,9 @@ func (store *Store) Reset() {
 	defer store.mu.Unlock()
 	store.Rows = nil
 	_ = store.TruncateFile()
+	if store.PageTree != nil {
+		_ = store.PageTree.Truncate(externFields)
+	}
 	store.rebuildIndex()
 }
 
@@ -244,6 +248,26 @@ func (store *Store) RowByKey(key []byte) *data.Tuple {
 	return store.rowsByID[id]
 }
 
+// NextKey returns the smallest stored key greater than key.
+func (store *Store) NextKey(key []byte) ([]byte, bool) {
+	if store == nil {
+		return nil, false
+	}
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	store.ensureIndex()
+	cur := store.Tree.Seek(key)
+	for cur != nil && cur.Valid() {
+		if CompareKeys(cur.Key(), key) > 0 {
+			return append([]byte(nil), cur.Key()...), true
+		}
+		if !cur.Next() {
+			break
+		}
+	}
+	return nil, false
+}
+
 func (store *Store) removeTuple(row *data.Tuple) bool {
 	if store == nil || row == nil {
 		return false
@@ -307,7 +331,7 @@ func (store *Store) replaceTuple(oldRow, newRow *data.Tuple) error {
 	newVal := encodeRowValue(id, newRow)
 	pageOldKey := store.pageTreeKey(oldRow, id)
 	pageNewKey := store.pageTreeKey(newRow, id)
-	if err := store.updatePageTree(pageOldKey, pageNewKey, newVal); err != nil {
+	if err := store.updatePageTree(pageOldKey, pageNewKey, id, newRow); err != nil {
 		return err
 	}
 	for i, existing := range store.Rows {
@@ -31,6 +31,7 @@ type Store struct {
 	nextRowID          uint64
 	rowsByID           map[uint64]*data.Tuple
 	idByRow            map[*data.Tuple]uint64
+	onlineBuilds       []*OnlineIndexBuild
 	mu                 sync.RWMutex
 }
 
@@ -59,7 +60,7 @@ func (store *Store) Insert(tuple *data.Tuple) error {
 	key := store.keyForInsert(tuple, id)
 	val := encodeRowValue(id, tuple)
 	pageKey := store.pageTreeKey(tuple, id)
-	if err := store.insertPageTree(pageKey, val); err != nil {
+	if err := store.insertPageTree(pageKey, id, tuple); err != nil {
 		return err
 	}
 	store.nextRowID++
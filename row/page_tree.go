@@ -2,33 +2,67 @@ package row
 
 import (
 	"bytes"
+	"encoding/binary"
 
+	"github.com/wilhasse/innodb-go/btr"
 	"github.com/wilhasse/innodb-go/data"
+	"github.com/wilhasse/innodb-go/fil"
+	"github.com/wilhasse/innodb-go/rec"
+	"github.com/wilhasse/innodb-go/ut"
 )
 
-func (store *Store) insertPageTree(key, value []byte) error {
+// pageTreeRecordMax bounds the key and value of a page tree record. Longer
+// rows move their longest fields off-page, so that any two records fit a
+// page and a leaf split always succeeds. In a compressed tablespace the
+// bound is a quarter of the zip size instead.
+const pageTreeRecordMax = ut.UNIV_PAGE_SIZE / 4
+
+func (store *Store) recordMax() int {
+	if zipSize := fil.SpaceZipSize(store.PageTree.SpaceID); zipSize > 0 && zipSize/4 < pageTreeRecordMax {
+		return zipSize / 4
+	}
+	return pageTreeRecordMax
+}
+
+func (store *Store) insertPageTree(key []byte, id uint64, tuple *data.Tuple) error {
 	if store == nil || store.PageTree == nil {
 		return nil
 	}
-	_, err := store.PageTree.Insert(key, value)
-	return err
+	value, err := store.pageTreeValue(key, id, tuple)
+	if err != nil {
+		return err
+	}
+	if _, err := store.PageTree.Insert(key, value); err != nil {
+		freeExternFields(value)
+		return err
+	}
+	return nil
 }
 
-func (store *Store) updatePageTree(oldKey, newKey, value []byte) error {
+func (store *Store) updatePageTree(oldKey, newKey []byte, id uint64, tuple *data.Tuple) error {
 	if store == nil || store.PageTree == nil {
 		return nil
 	}
-	if bytes.Equal(oldKey, newKey) {
-		_, err := store.PageTree.Insert(newKey, value)
+	oldValue, _, err := store.PageTree.Search(oldKey)
+	if err != nil {
 		return err
 	}
-	if _, err := store.PageTree.Insert(newKey, value); err != nil {
+	value, err := store.pageTreeValue(newKey, id, tuple)
+	if err != nil {
 		return err
 	}
-	if _, err := store.PageTree.Delete(oldKey); err != nil {
-		_, _ = store.PageTree.Delete(newKey)
+	if _, err := store.PageTree.Insert(newKey, value); err != nil {
+		freeExternFields(value)
 		return err
 	}
+	if !bytes.Equal(oldKey, newKey) {
+		if _, err := store.PageTree.Delete(oldKey); err != nil {
+			_, _ = store.PageTree.Delete(newKey)
+			freeExternFields(value)
+			return err
+		}
+	}
+	freeExternFields(oldValue)
 	return nil
 }
 
@@ -36,8 +70,89 @@ func (store *Store) deletePageTree(key []byte) error {
 	if store == nil || store.PageTree == nil || len(key) == 0 {
 		return nil
 	}
-	_, err := store.PageTree.Delete(key)
-	return err
+	value, found, err := store.PageTree.Search(key)
+	if err != nil || !found {
+		return err
+	}
+	if _, err := store.PageTree.Delete(key); err != nil {
+		return err
+	}
+	freeExternFields(value)
+	return nil
+}
+
+// pageTreeValue encodes the page tree value of a row. While the record is
+// longer than the record bound its longest field is written to BLOB pages
+// in the tablespace of the tree and replaced by the reference.
+func (store *Store) pageTreeValue(key []byte, id uint64, tuple *data.Tuple) ([]byte, error) {
+	value := encodeRowValue(id, tuple)
+	recordMax := store.recordMax()
+	size := len(key) + len(value)
+	if size <= recordMax {
+		return value, nil
+	}
+	stored := data.NewTuple(len(tuple.Fields))
+	copy(stored.Fields, tuple.Fields)
+	for size > recordMax {
+		pick := -1
+		for i := range stored.Fields {
+			field := &stored.Fields[i]
+			if field.Ext || data.FieldIsNull(field) || len(fieldBytes(field)) <= btr.ExternFieldThreshold {
+				continue
+			}
+			if pick < 0 || len(fieldBytes(field)) > len(fieldBytes(&stored.Fields[pick])) {
+				pick = i
+			}
+		}
+		if pick < 0 {
+			break
+		}
+		inline := fieldBytes(&stored.Fields[pick])
+		ref, err := btr.StoreBigRecExternFields(store.PageTree.SpaceID, inline, 0)
+		if err != nil {
+			freeExternFields(encodeRowValue(id, stored))
+			return nil, err
+		}
+		stored.Fields[pick] = data.Field{Data: ref, Len: uint32(len(ref)), Ext: true, Type: stored.Fields[pick].Type}
+		size += len(ref) - len(inline)
+	}
+	return encodeRowValue(id, stored), nil
+}
+
+// fieldBytes returns the data of a field cut to its length.
+func fieldBytes(field *data.Field) []byte {
+	if int(field.Len) < len(field.Data) {
+		return field.Data[:field.Len]
+	}
+	return field.Data
+}
+
+// externFields returns the references of the off-page fields of a page
+// tree value.
+func externFields(value []byte) [][]byte {
+	var refs [][]byte
+	for pos := 8; pos+3 <= len(value); {
+		flag := value[pos]
+		length := int(binary.BigEndian.Uint16(value[pos+1 : pos+3]))
+		pos += 3
+		if flag == 1 {
+			continue
+		}
+		if pos+length > len(value) {
+			break
+		}
+		if flag == rec.VarFlagExtern {
+			refs = append(refs, value[pos:pos+length])
+		}
+		pos += length
+	}
+	return refs
+}
+
+// freeExternFields frees the BLOB pages of the off-page fields of a page
+// tree value.
+func freeExternFields(value []byte) {
+	btr.RecFreeExternallyStoredFields(externFields(value)...)
 }
 
 func (store *Store) pageTreeKey(row *data.Tuple, rowID uint64) []byte {
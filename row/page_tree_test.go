@@ -0,0 +1,103 @@
+package row
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+
+	"github.com/wilhasse/innodb-go/btr"
+	"github.com/wilhasse/innodb-go/buf"
+	"github.com/wilhasse/innodb-go/data"
+	"github.com/wilhasse/innodb-go/fil"
+	"github.com/wilhasse/innodb-go/fsp"
+	ibos "github.com/wilhasse/innodb-go/os"
+	"github.com/wilhasse/innodb-go/ut"
+)
+
+func TestPageTreeExternFields(t *testing.T) {
+	oldPools := append([]*buf.Pool(nil), buf.DefaultPools()...)
+	buf.SetDefaultPools(nil)
+	defer buf.SetDefaultPools(oldPools)
+	fil.VarInit()
+	fsp.Init()
+	if !fil.SpaceCreate("blobs", 1, 0, fil.SpaceTablespace) {
+		t.Fatalf("SpaceCreate failed")
+	}
+	defer fil.SpaceDrop(1)
+	file, err := ibos.FileCreateSimple(filepath.Join(t.TempDir(), "blobs.ibd"), ibos.FileOverwrite, ibos.FileReadWrite)
+	if err != nil {
+		t.Fatalf("FileCreateSimple: %v", err)
+	}
+	defer ibos.FileClose(file)
+	if err := fil.SpaceSetFile(1, file); err != nil {
+		t.Fatalf("SpaceSetFile: %v", err)
+	}
+
+	store := NewStore(0)
+	store.SpaceID = 1
+	store.PageTree = btr.NewPageTree(1, CompareKeys)
+	blob := func(id byte, n int) *data.Tuple {
+		return &data.Tuple{Fields: []data.Field{
+			{Data: []byte{id}, Len: 1},
+			{Data: bytes.Repeat([]byte{'a' + id}, n), Len: uint32(n)},
+		}}
+	}
+	pageRefs := func(tuple *data.Tuple) [][]byte {
+		t.Helper()
+		value, found, err := store.PageTree.Search(store.pageTreeKey(tuple, 0))
+		if err != nil || !found {
+			t.Fatalf("Search: found=%v err=%v", found, err)
+		}
+		return externFields(value)
+	}
+
+	small := blob(1, 100)
+	big := blob(2, 3*ut.UNIV_PAGE_SIZE)
+	for _, tuple := range []*data.Tuple{small, big} {
+		if err := store.Insert(tuple); err != nil {
+			t.Fatalf("Insert: %v", err)
+		}
+	}
+	if refs := pageRefs(small); len(refs) != 0 {
+		t.Fatalf("short row stored %d fields off-page", len(refs))
+	}
+	refs := pageRefs(big)
+	if len(refs) != 1 || btr.RecGetExternallyStoredLen(refs[0]) != 3*ut.UNIV_PAGE_SIZE {
+		t.Fatalf("long row refs=%d", len(refs))
+	}
+	oldRef := refs[0]
+
+	bigger := blob(2, 4*ut.UNIV_PAGE_SIZE)
+	if err := store.ReplaceTuple(big, bigger); err != nil {
+		t.Fatalf("ReplaceTuple: %v", err)
+	}
+	if btr.GetExternallyStoredField(oldRef) != nil {
+		t.Fatalf("update kept the old BLOB chain")
+	}
+	newRef := pageRefs(bigger)[0]
+
+	if err := store.LoadFromPages(); err != nil {
+		t.Fatalf("LoadFromPages: %v", err)
+	}
+	if len(store.Rows) != 2 {
+		t.Fatalf("rows=%d, want 2", len(store.Rows))
+	}
+	for _, row := range store.Rows {
+		want := small
+		if row.Fields[0].Data[0] == 2 {
+			want = bigger
+		}
+		if !bytes.Equal(row.Fields[1].Data, want.Fields[1].Data) || row.Fields[1].Ext {
+			t.Fatalf("row %d reloaded %d bytes", row.Fields[0].Data[0], len(row.Fields[1].Data))
+		}
+	}
+
+	for _, row := range append([]*data.Tuple(nil), store.Rows...) {
+		if !store.RemoveTuple(row) {
+			t.Fatalf("RemoveTuple failed")
+		}
+	}
+	if btr.GetExternallyStoredField(newRef) != nil {
+		t.Fatalf("delete kept the BLOB chain")
+	}
+}
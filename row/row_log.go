@@ -0,0 +1,255 @@
+package row
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/wilhasse/innodb-go/btr"
+	"github.com/wilhasse/innodb-go/data"
+	"github.com/wilhasse/innodb-go/read"
+)
+
+// ErrOnlineBuildAborted reports that an online index build lost track of the
+// table, for example because the store was truncated during the build.
+var ErrOnlineBuildAborted = errors.New("row: online index build aborted")
+
+// ErrSecondaryIndexExists reports that an index of the same name is already
+// registered or being built.
+var ErrSecondaryIndexExists = errors.New("row: secondary index exists")
+
+const (
+	rowLogInsert byte = iota + 1
+	rowLogDelete
+)
+
+// rowLogEntry records one change to the index being built.
+type rowLogEntry struct {
+	op    byte
+	key   []byte
+	rowID uint64
+}
+
+// OnlineIndexBuild builds a secondary index while DML on the store continues.
+// The build reads the clustered rows as a read view sees them when it starts;
+// changes made after that point are captured in a row log and replayed into
+// the new index before it is published.
+type OnlineIndexBuild struct {
+	store    *Store
+	index    *SecondaryIndex
+	snapshot map[uint64]*data.Tuple
+	// log and err are guarded by store.mu.
+	log []rowLogEntry
+	err error
+}
+
+// BeginOnlineIndex starts an online build of a secondary index. Row log
+// capture begins atomically with the clustered index snapshot, which holds
+// the row versions visible to view; a nil view takes the latest versions.
+func (store *Store) BeginOnlineIndex(name string, fields []int, prefixes []int, unique bool, view *read.ReadView) (*OnlineIndexBuild, error) {
+	if store == nil {
+		return nil, errors.New("row: nil store")
+	}
+	if len(fields) == 0 {
+		return nil, errors.New("row: empty secondary index")
+	}
+	if prefixes != nil && len(prefixes) != len(fields) {
+		return nil, errors.New("row: secondary prefix mismatch")
+	}
+	if name == "" {
+		return nil, errors.New("row: empty secondary index name")
+	}
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	store.ensureIndex()
+	key := strings.ToLower(name)
+	if _, ok := store.SecondaryIndexes[key]; ok {
+		return nil, ErrSecondaryIndexExists
+	}
+	for _, build := range store.onlineBuilds {
+		if strings.ToLower(build.index.Name) == key {
+			return nil, ErrSecondaryIndexExists
+		}
+	}
+	idx := &SecondaryIndex{
+		Name:     name,
+		Fields:   append([]int(nil), fields...),
+		Prefixes: append([]int(nil), prefixes...),
+		Unique:   unique,
+		Tree:     btr.NewTree(storeTreeOrder, CompareKeys),
+	}
+	idx.IbufSpaceID = store.SpaceID
+	idx.IbufPageNo = ibufPageNoForIndex(idx.Name)
+	build := &OnlineIndexBuild{
+		store:    store,
+		index:    idx,
+		snapshot: make(map[uint64]*data.Tuple, len(store.rowsByID)),
+	}
+	for id, row := range store.rowsByID {
+		if row == nil {
+			continue
+		}
+		if vr := store.versions[string(store.keyForInsert(row, id))]; vr != nil {
+			row = vr.VersionForView(view)
+		}
+		if row != nil {
+			build.snapshot[id] = row
+		}
+	}
+	store.onlineBuilds = append(store.onlineBuilds, build)
+	return build, nil
+}
+
+// Index returns the secondary index being built.
+func (build *OnlineIndexBuild) Index() *SecondaryIndex {
+	if build == nil {
+		return nil
+	}
+	return build.index
+}
+
+// Build populates the new index from the snapshot. The store latch is taken
+// shared for one row at a time, so concurrent DML proceeds between rows and
+// is captured in the row log.
+func (build *OnlineIndexBuild) Build() error {
+	if build == nil || build.index == nil || build.store == nil {
+		return errors.New("row: nil online build")
+	}
+	store := build.store
+	for id, row := range build.snapshot {
+		store.mu.RLock()
+		err := build.insertEntry(store.secondaryKeyForInsert(build.index, row, id), id)
+		store.mu.RUnlock()
+		if err != nil {
+			return err
+		}
+	}
+	build.snapshot = nil
+	return nil
+}
+
+// ApplyLog replays the changes captured so far into the new index and returns
+// how many were applied. DML is blocked only while the log is taken; the
+// replay holds the store latch shared.
+func (build *OnlineIndexBuild) ApplyLog() (int, error) {
+	if build == nil || build.store == nil {
+		return 0, errors.New("row: nil online build")
+	}
+	build.store.mu.Lock()
+	entries := build.log
+	build.log = nil
+	err := build.err
+	build.store.mu.Unlock()
+	if err != nil {
+		return 0, err
+	}
+	build.store.mu.RLock()
+	defer build.store.mu.RUnlock()
+	return len(entries), build.applyEntries(entries)
+}
+
+// Finish applies the remaining row log and publishes the index. DML on the
+// store is blocked only for the duration of the final apply.
+func (build *OnlineIndexBuild) Finish() error {
+	if build == nil || build.store == nil {
+		return errors.New("row: nil online build")
+	}
+	store := build.store
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	store.removeOnlineBuild(build)
+	if build.err != nil {
+		return build.err
+	}
+	entries := build.log
+	build.log = nil
+	if err := build.applyEntries(entries); err != nil {
+		return err
+	}
+	if store.SecondaryIndexes == nil {
+		store.SecondaryIndexes = make(map[string]*SecondaryIndex)
+	}
+	store.SecondaryIndexes[strings.ToLower(build.index.Name)] = build.index
+	return nil
+}
+
+// Abort stops row log capture and discards the build.
+func (build *OnlineIndexBuild) Abort() {
+	if build == nil || build.store == nil {
+		return
+	}
+	build.store.mu.Lock()
+	defer build.store.mu.Unlock()
+	build.store.removeOnlineBuild(build)
+	build.log = nil
+	build.snapshot = nil
+}
+
+// applyEntries assumes store.mu is held.
+func (build *OnlineIndexBuild) applyEntries(entries []rowLogEntry) error {
+	for _, entry := range entries {
+		switch entry.op {
+		case rowLogInsert:
+			if err := build.insertEntry(entry.key, entry.rowID); err != nil {
+				return err
+			}
+		case rowLogDelete:
+			build.index.Tree.Delete(entry.key)
+		}
+	}
+	return nil
+}
+
+// insertEntry assumes store.mu is held.
+func (build *OnlineIndexBuild) insertEntry(key []byte, rowID uint64) error {
+	if len(key) == 0 {
+		return nil
+	}
+	if build.index.Unique && build.store.secondaryDuplicate(build.index, key, rowID) {
+		return ErrDuplicateKey
+	}
+	build.index.Tree.Insert(key, encodeRowID(rowID))
+	return nil
+}
+
+// removeOnlineBuild assumes store.mu is held.
+func (store *Store) removeOnlineBuild(build *OnlineIndexBuild) {
+	for i, existing := range store.onlineBuilds {
+		if existing == build {
+			store.onlineBuilds = append(store.onlineBuilds[:i], store.onlineBuilds[i+1:]...)
+			return
+		}
+	}
+}
+
+// abortOnlineBuilds assumes store.mu is held.
+func (store *Store) abortOnlineBuilds() {
+	for _, build := range store.onlineBuilds {
+		build.err = ErrOnlineBuildAborted
+		build.log = nil
+	}
+	store.onlineBuilds = nil
+}
+
+// logOnlineInsert assumes store.mu is held.
+func (store *Store) logOnlineInsert(row *data.Tuple, rowID uint64) {
+	for _, build := range store.onlineBuilds {
+		if key := store.secondaryKeyForInsert(build.index, row, rowID); len(key) > 0 {
+			build.log = append(build.log, rowLogEntry{op: rowLogInsert, key: key, rowID: rowID})
+		}
+	}
+}
+
+// logOnlineDelete assumes store.mu is held.
+func (store *Store) logOnlineDelete(row *data.Tuple, rowID uint64) {
+	for _, build := range store.onlineBuilds {
+		if key := store.secondaryKeyForInsert(build.index, row, rowID); len(key) > 0 {
+			build.log = append(build.log, rowLogEntry{op: rowLogDelete, key: key, rowID: rowID})
+		}
+	}
+}
+
+// logOnlineUpdate assumes store.mu is held.
+func (store *Store) logOnlineUpdate(oldRow, newRow *data.Tuple, rowID uint64) {
+	store.logOnlineDelete(oldRow, rowID)
+	store.logOnlineInsert(newRow, rowID)
+}
@@ -0,0 +1,163 @@
+package row
+
+import (
+	"testing"
+
+	"github.com/wilhasse/innodb-go/data"
+	"github.com/wilhasse/innodb-go/read"
+)
+
+func pairTuple(key, value byte) *data.Tuple {
+	return &data.Tuple{Fields: []data.Field{
+		{Data: []byte{key}, Len: 1},
+		{Data: []byte{value}, Len: 1},
+	}}
+}
+
+func assertIndexMatchesRows(t *testing.T, store *Store, idx *SecondaryIndex) {
+	t.Helper()
+	if idx.Tree.Size() != len(store.rowsByID) {
+		t.Fatalf("index size=%d rows=%d", idx.Tree.Size(), len(store.rowsByID))
+	}
+	for id, row := range store.rowsByID {
+		key := store.secondaryKeyForInsert(idx, row, id)
+		val, ok := idx.Tree.Search(key)
+		if !ok {
+			t.Fatalf("missing index entry for row %d", id)
+		}
+		if got, _ := DecodeRowID(val); got != id {
+			t.Fatalf("row id=%d want %d", got, id)
+		}
+	}
+}
+
+func TestOnlineIndexBuildAppliesRowLog(t *testing.T) {
+	store := NewStore(0)
+	rows := make([]*data.Tuple, 0, 4)
+	for i := byte(1); i <= 4; i++ {
+		row := pairTuple(i, i*10)
+		if err := store.Insert(row); err != nil {
+			t.Fatalf("insert %d: %v", i, err)
+		}
+		rows = append(rows, row)
+	}
+
+	build, err := store.BeginOnlineIndex("idx_v", []int{1}, nil, false, nil)
+	if err != nil {
+		t.Fatalf("BeginOnlineIndex: %v", err)
+	}
+	if err := store.Insert(pairTuple(5, 50)); err != nil {
+		t.Fatalf("insert during build: %v", err)
+	}
+	if err := store.ReplaceTuple(rows[0], pairTuple(1, 99)); err != nil {
+		t.Fatalf("update during build: %v", err)
+	}
+	if err := build.Build(); err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	if applied, err := build.ApplyLog(); err != nil || applied != 3 {
+		t.Fatalf("ApplyLog applied=%d err=%v want 3", applied, err)
+	}
+	if !store.RemoveTuple(rows[1]) {
+		t.Fatalf("delete during build failed")
+	}
+	if store.SecondaryIndex("idx_v") != nil {
+		t.Fatalf("index visible before finish")
+	}
+	if err := build.Finish(); err != nil {
+		t.Fatalf("Finish: %v", err)
+	}
+	idx := store.SecondaryIndex("idx_v")
+	if idx == nil {
+		t.Fatalf("index not published")
+	}
+	assertIndexMatchesRows(t, store, idx)
+
+	if err := store.Insert(pairTuple(6, 60)); err != nil {
+		t.Fatalf("insert after build: %v", err)
+	}
+	if len(build.log) != 0 {
+		t.Fatalf("row log still captured after finish")
+	}
+}
+
+func TestOnlineIndexBuildUniqueConflict(t *testing.T) {
+	store := NewStore(0)
+	if err := store.Insert(pairTuple(1, 10)); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+	build, err := store.BeginOnlineIndex("uniq_v", []int{1}, nil, true, nil)
+	if err != nil {
+		t.Fatalf("BeginOnlineIndex: %v", err)
+	}
+	if err := store.Insert(pairTuple(2, 10)); err != nil {
+		t.Fatalf("insert during build: %v", err)
+	}
+	if err := build.Build(); err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	if err := build.Finish(); err != ErrDuplicateKey {
+		t.Fatalf("Finish err=%v want %v", err, ErrDuplicateKey)
+	}
+	if store.SecondaryIndex("uniq_v") != nil {
+		t.Fatalf("conflicting index published")
+	}
+}
+
+func TestOnlineIndexBuildAbortedByReset(t *testing.T) {
+	store := NewStore(0)
+	if err := store.Insert(pairTuple(1, 10)); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+	build, err := store.BeginOnlineIndex("idx_v", []int{1}, nil, false, nil)
+	if err != nil {
+		t.Fatalf("BeginOnlineIndex: %v", err)
+	}
+	if _, err := store.BeginOnlineIndex("IDX_V", []int{1}, nil, false, nil); err != ErrSecondaryIndexExists {
+		t.Fatalf("second build err=%v want %v", err, ErrSecondaryIndexExists)
+	}
+	store.Reset()
+	if err := build.Build(); err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	if err := build.Finish(); err != ErrOnlineBuildAborted {
+		t.Fatalf("Finish err=%v want %v", err, ErrOnlineBuildAborted)
+	}
+}
+
+func TestOnlineIndexBuildReadsViewVersions(t *testing.T) {
+	store := NewStore(0)
+	oldRow := pairTuple(1, 10)
+	if err := store.Insert(oldRow); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+	if err := store.Insert(pairTuple(2, 20)); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+	store.RecordVersion(store.KeyForRow(oldRow), 2, oldRow)
+	newRow := pairTuple(1, 99)
+	if err := store.ReplaceTuple(oldRow, newRow); err != nil {
+		t.Fatalf("replace: %v", err)
+	}
+	store.RecordVersion(store.KeyForRow(newRow), 7, newRow)
+
+	build, err := store.BeginOnlineIndex("idx_v", []int{1}, nil, false, read.NewReadView(3, nil))
+	if err != nil {
+		t.Fatalf("BeginOnlineIndex: %v", err)
+	}
+	defer build.Abort()
+	if err := build.Build(); err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	id := store.idByRow[newRow]
+	idx := build.Index()
+	if _, ok := idx.Tree.Search(store.secondaryKeyForInsert(idx, oldRow, id)); !ok {
+		t.Fatalf("missing entry for the version visible to the view")
+	}
+	if _, ok := idx.Tree.Search(store.secondaryKeyForInsert(idx, newRow, id)); ok {
+		t.Fatalf("entry built from a version the view does not see")
+	}
+	if idx.Tree.Size() != 2 {
+		t.Fatalf("index size=%d want 2", idx.Tree.Size())
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/binary"
 	"errors"
 	"hash/fnv"
+	"sort"
 	"strings"
 
 	"github.com/wilhasse/innodb-go/btr"
@@ -37,6 +38,25 @@ func (store *Store) SecondaryIndex(name string) *SecondaryIndex {
 	return store.SecondaryIndexes[strings.ToLower(name)]
 }
 
+// SecondaryIndexList returns the secondary indexes ordered by name.
+func (store *Store) SecondaryIndexList() []*SecondaryIndex {
+	if store == nil {
+		return nil
+	}
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+	out := make([]*SecondaryIndex, 0, len(store.SecondaryIndexes))
+	for _, idx := range store.SecondaryIndexes {
+		if idx != nil {
+			out = append(out, idx)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return strings.ToLower(out[i].Name) < strings.ToLower(out[j].Name)
+	})
+	return out
+}
+
 // RemoveSecondaryIndex removes a secondary index by name.
 func (store *Store) RemoveSecondaryIndex(name string) {
 	if store == nil || name == "" {
@@ -47,7 +67,11 @@ func (store *Store) RemoveSecondaryIndex(name string) {
 	if store.SecondaryIndexes == nil {
 		return
 	}
-	delete(store.SecondaryIndexes, strings.ToLower(name))
+	key := strings.ToLower(name)
+	if idx := store.SecondaryIndexes[key]; idx != nil {
+		ibuf.Delete(idx.IbufSpaceID, idx.IbufPageNo)
+	}
+	delete(store.SecondaryIndexes, key)
 }
 
 // AddSecondaryIndex registers a new secondary index and builds it from rows.
@@ -161,9 +185,10 @@ func (store *Store) hasSecondaryDuplicateExcept(row *data.Tuple, _ *data.Tuple,
 }
 
 func (store *Store) insertSecondaryIndexes(row *data.Tuple, rowID uint64) {
-	if store == nil || store.SecondaryIndexes == nil {
+	if store == nil {
 		return
 	}
+	store.logOnlineInsert(row, rowID)
 	for _, idx := range store.SecondaryIndexes {
 		if idx == nil || idx.Tree == nil {
 			continue
@@ -181,9 +206,10 @@ func (store *Store) insertSecondaryIndexes(row *data.Tuple, rowID uint64) {
 }
 
 func (store *Store) deleteSecondaryIndexes(row *data.Tuple, rowID uint64) {
-	if store == nil || store.SecondaryIndexes == nil {
+	if store == nil {
 		return
 	}
+	store.logOnlineDelete(row, rowID)
 	for _, idx := range store.SecondaryIndexes {
 		if idx == nil || idx.Tree == nil {
 			continue
@@ -197,7 +223,7 @@ func (store *Store) deleteSecondaryIndexes(row *data.Tuple, rowID uint64) {
 }
 
 func (store *Store) updateSecondaryIndexes(oldRow, newRow *data.Tuple, rowID uint64) error {
-	if store == nil || store.SecondaryIndexes == nil {
+	if store == nil {
 		return nil
 	}
 	type update struct {
@@ -228,6 +254,7 @@ func (store *Store) updateSecondaryIndexes(oldRow, newRow *data.Tuple, rowID uin
 			upd.idx.Tree.Insert(upd.newKey, encodeRowID(rowID))
 		}
 	}
+	store.logOnlineUpdate(oldRow, newRow, rowID)
 	return nil
 }
 
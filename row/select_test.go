@@ -42,3 +42,17 @@ func TestSelectAll(t *testing.T) {
 		t.Fatalf("rows=%d", len(rows))
 	}
 }
+
+func TestNextKey(t *testing.T) {
+	store := NewStore(0)
+	for _, k := range []byte{1, 3, 5} {
+		_ = store.Insert(tupleKey(k))
+	}
+	next, ok := store.NextKey(store.KeyForRow(store.Rows[0]))
+	if !ok || CompareKeys(next, store.KeyForRow(store.Rows[1])) != 0 {
+		t.Fatalf("next after 1=%v ok=%v", next, ok)
+	}
+	if _, ok := store.NextKey(store.KeyForRow(store.Rows[2])); ok {
+		t.Fatalf("expected no key after last row")
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/wilhasse/innodb-go/data"
 	"github.com/wilhasse/innodb-go/fil"
 	ibos "github.com/wilhasse/innodb-go/os"
+	"github.com/wilhasse/innodb-go/rec"
 )
 
 const (
@@ -173,6 +174,7 @@ func (store *Store) loadFromPages() error {
 	store.mu.Lock()
 	defer store.mu.Unlock()
 
+	store.abortOnlineBuilds()
 	store.Rows = nil
 	store.Tree = btr.NewTree(storeTreeOrder, CompareKeys)
 	store.rowsByID = make(map[uint64]*data.Tuple)
@@ -180,8 +182,14 @@ func (store *Store) loadFromPages() error {
 	store.versions = make(map[string]*VersionedRow)
 	store.nextRowID = 1
 
+	if err := store.PageTree.ReservePages(); err != nil {
+		return err
+	}
 	var maxID uint64
 	err := store.PageTree.ForEach(func(_ []byte, value []byte) bool {
+		for _, ref := range externFields(value) {
+			_ = btr.ReserveExternallyStoredField(ref)
+		}
 		id, tuple, err := decodeRowValue(value)
 		if err != nil || tuple == nil {
 			return true
@@ -191,7 +199,7 @@ func (store *Store) loadFromPages() error {
 		store.Rows = append(store.Rows, tuple)
 		memKey := store.keyForInsert(tuple, id)
 		if len(memKey) > 0 {
-			store.Tree.Insert(memKey, value)
+			store.Tree.Insert(memKey, encodeRowValue(id, tuple))
 			store.versions[string(memKey)] = NewVersionedRow(0, tuple)
 		}
 		if id > maxID {
@@ -326,7 +334,7 @@ func decodeVarTuple(buf []byte) (*data.Tuple, error) {
 		length := int(binary.BigEndian.Uint16(buf[pos : pos+2]))
 		pos += 2
 		field := data.Field{}
-		if nullFlag != 0 {
+		if nullFlag != 0 && nullFlag != rec.VarFlagExtern {
 			data.FieldSetNull(&field)
 			fields = append(fields, field)
 			continue
@@ -335,7 +343,12 @@ func decodeVarTuple(buf []byte) (*data.Tuple, error) {
 			return nil, errors.New("row: truncated field data")
 		}
 		dataBytes := append([]byte(nil), buf[pos:pos+length]...)
-		data.FieldSetData(&field, dataBytes, uint32(length))
+		if nullFlag == rec.VarFlagExtern {
+			if dataBytes = btr.GetExternallyStoredField(dataBytes); dataBytes == nil {
+				return nil, errors.New("row: missing externally stored field")
+			}
+		}
+		data.FieldSetData(&field, dataBytes, uint32(len(dataBytes)))
 		fields = append(fields, field)
 		pos += length
 	}
@@ -0,0 +1,323 @@
+package sqldriver
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/wilhasse/innodb-go/api"
+	"github.com/wilhasse/innodb-go/trx"
+)
+
+// ErrTxActive reports a Begin on a connection that already has a transaction.
+var ErrTxActive = errors.New("sqldriver: transaction already active")
+
+// Conn is a connection to the running engine. Statements outside a
+// transaction run in their own REPEATABLE READ transaction.
+type Conn struct {
+	trx    *trx.Trx
+	closed bool
+}
+
+// Prepare returns a statement bound to the connection.
+func (c *Conn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+// PrepareContext returns a statement bound to the connection. SELECT,
+// INSERT, UPDATE and DELETE statements are parsed once with api.Prepare and
+// keep their query graph between executions; other statements are parsed
+// each time they run.
+func (c *Conn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	if c.closed {
+		return nil, driver.ErrBadConn
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	prepared, code := api.Prepare(query)
+	switch code {
+	case api.DB_SUCCESS:
+	case api.DB_UNSUPPORTED:
+		prepared = nil
+	default:
+		return nil, code
+	}
+	return &Stmt{conn: c, query: query, prepared: prepared}, nil
+}
+
+// Close rolls back an open transaction.
+func (c *Conn) Close() error {
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+	if c.trx == nil {
+		return nil
+	}
+	ibTrx := c.trx
+	c.trx = nil
+	return errCode(api.TrxRollback(ibTrx))
+}
+
+// Begin starts a REPEATABLE READ transaction.
+func (c *Conn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+// BeginTx starts a transaction at the requested isolation level.
+// sql.LevelDefault maps to REPEATABLE READ.
+func (c *Conn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if c.closed {
+		return nil, driver.ErrBadConn
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if c.trx != nil {
+		return nil, ErrTxActive
+	}
+	if opts.ReadOnly {
+		return nil, errors.New("sqldriver: read-only transactions are not supported")
+	}
+	level, err := isolationLevel(sql.IsolationLevel(opts.Isolation))
+	if err != nil {
+		return nil, err
+	}
+	ibTrx := api.TrxBegin(level)
+	if ibTrx == nil {
+		return nil, api.DB_ERROR
+	}
+	c.trx = ibTrx
+	return &Tx{conn: c}, nil
+}
+
+func isolationLevel(level sql.IsolationLevel) (api.TrxIsolation, error) {
+	switch level {
+	case sql.LevelDefault, sql.LevelRepeatableRead:
+		return api.IB_TRX_REPEATABLE_READ, nil
+	case sql.LevelReadUncommitted:
+		return api.IB_TRX_READ_UNCOMMITTED, nil
+	case sql.LevelReadCommitted:
+		return api.IB_TRX_READ_COMMITTED, nil
+	case sql.LevelSerializable:
+		return api.IB_TRX_SERIALIZABLE, nil
+	default:
+		return 0, fmt.Errorf("sqldriver: unsupported isolation level %s", level)
+	}
+}
+
+// ExecContext runs a statement that returns no rows.
+func (c *Conn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	res, err := c.run(ctx, query, args)
+	if err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(res.RowsAffected), nil
+}
+
+// QueryContext runs a statement and returns its rows.
+func (c *Conn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	res, err := c.run(ctx, query, args)
+	if err != nil {
+		return nil, err
+	}
+	return newRows(res), nil
+}
+
+// CheckNamedValue accepts the default driver value types and nil.
+func (c *Conn) CheckNamedValue(nv *driver.NamedValue) error {
+	value, err := driver.DefaultParameterConverter.ConvertValue(nv.Value)
+	if err != nil {
+		return err
+	}
+	nv.Value = value
+	return nil
+}
+
+func (c *Conn) run(ctx context.Context, query string, args []driver.NamedValue) (*api.SQLResult, error) {
+	if c.closed {
+		return nil, driver.ErrBadConn
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	sqlArgs, err := bindArgs(args)
+	if err != nil {
+		return nil, err
+	}
+	if c.trx != nil {
+		res, code := api.ExecDDLSQLTrx(c.trx, query, sqlArgs...)
+		return res, errCode(code)
+	}
+	ibTrx := api.TrxBegin(api.IB_TRX_REPEATABLE_READ)
+	if ibTrx == nil {
+		return nil, api.DB_ERROR
+	}
+	res, code := api.ExecDDLSQLTrx(ibTrx, query, sqlArgs...)
+	if code != api.DB_SUCCESS {
+		_ = api.TrxRollback(ibTrx)
+		return nil, code
+	}
+	if code := api.TrxCommit(ibTrx); code != api.DB_SUCCESS {
+		return nil, code
+	}
+	return res, nil
+}
+
+// bindArgs converts driver values into SQL arguments. Positional arguments
+// bind to ? markers and to :1, :2, ... by ordinal.
+func bindArgs(args []driver.NamedValue) ([]api.SQLArg, error) {
+	out := make([]api.SQLArg, 0, len(args))
+	for _, arg := range args {
+		name := arg.Name
+		if name == "" {
+			name = strconv.Itoa(arg.Ordinal)
+		}
+		switch v := arg.Value.(type) {
+		case nil:
+			out = append(out, api.SQLArgNull(name))
+		case int64:
+			out = append(out, api.SQLArgIntSigned(name, 8, v))
+		case bool:
+			var n int64
+			if v {
+				n = 1
+			}
+			out = append(out, api.SQLArgIntSigned(name, 8, n))
+		case float64:
+			out = append(out, api.SQLArgString(name, strconv.FormatFloat(v, 'g', -1, 64)))
+		case string:
+			out = append(out, api.SQLArgString(name, v))
+		case []byte:
+			out = append(out, api.SQLArgString(name, string(v)))
+		case time.Time:
+			out = append(out, api.SQLArgString(name, v.Format(time.RFC3339Nano)))
+		default:
+			return nil, fmt.Errorf("sqldriver: unsupported argument type %T", arg.Value)
+		}
+	}
+	return out, nil
+}
+
+// Tx is a transaction started by Conn.BeginTx.
+type Tx struct {
+	conn *Conn
+}
+
+// Commit commits the transaction.
+func (tx *Tx) Commit() error {
+	ibTrx, err := tx.take()
+	if err != nil {
+		return err
+	}
+	return errCode(api.TrxCommit(ibTrx))
+}
+
+// Rollback rolls the transaction back.
+func (tx *Tx) Rollback() error {
+	ibTrx, err := tx.take()
+	if err != nil {
+		return err
+	}
+	return errCode(api.TrxRollback(ibTrx))
+}
+
+func (tx *Tx) take() (*trx.Trx, error) {
+	if tx.conn == nil || tx.conn.trx == nil {
+		return nil, driver.ErrBadConn
+	}
+	ibTrx := tx.conn.trx
+	tx.conn.trx = nil
+	tx.conn = nil
+	return ibTrx, nil
+}
+
+// Stmt is a prepared statement. prepared is nil for statements api.Prepare
+// does not take, which run as text on the connection.
+type Stmt struct {
+	conn     *Conn
+	query    string
+	prepared *api.PreparedStmt
+}
+
+// Close releases the statement.
+func (s *Stmt) Close() error {
+	if s.prepared == nil {
+		return nil
+	}
+	prepared := s.prepared
+	s.prepared = nil
+	return errCode(prepared.Close())
+}
+
+// NumInput returns -1; argument names are resolved when the statement runs.
+func (s *Stmt) NumInput() int {
+	return -1
+}
+
+// Exec runs the statement with positional arguments.
+func (s *Stmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.ExecContext(context.Background(), namedValues(args))
+}
+
+// Query runs the statement with positional arguments.
+func (s *Stmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.QueryContext(context.Background(), namedValues(args))
+}
+
+// ExecContext runs the statement on its connection.
+func (s *Stmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	if s.prepared == nil {
+		return s.conn.ExecContext(ctx, s.query, args)
+	}
+	res, err := s.execute(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(res.RowsAffected), nil
+}
+
+// QueryContext runs the statement on its connection.
+func (s *Stmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	if s.prepared == nil {
+		return s.conn.QueryContext(ctx, s.query, args)
+	}
+	res, err := s.execute(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+	return newRows(res), nil
+}
+
+// execute binds args to the prepared statement and runs it in the
+// connection's transaction, or in its own one outside a transaction.
+func (s *Stmt) execute(ctx context.Context, args []driver.NamedValue) (*api.SQLResult, error) {
+	if s.conn.closed {
+		return nil, driver.ErrBadConn
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	sqlArgs, err := bindArgs(args)
+	if err != nil {
+		return nil, err
+	}
+	if code := s.prepared.Bind(sqlArgs...); code != api.DB_SUCCESS {
+		return nil, code
+	}
+	res, code := s.prepared.Execute(s.conn.trx)
+	return res, errCode(code)
+}
+
+func namedValues(args []driver.Value) []driver.NamedValue {
+	named := make([]driver.NamedValue, len(args))
+	for i, value := range args {
+		named[i] = driver.NamedValue{Ordinal: i + 1, Value: value}
+	}
+	return named
+}
@@ -0,0 +1,44 @@
+package sqldriver
+
+import (
+	"database/sql/driver"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/wilhasse/innodb-go/api"
+)
+
+func TestBindArgs(t *testing.T) {
+	at := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+	args, err := bindArgs([]driver.NamedValue{
+		{Ordinal: 1, Value: nil},
+		{Ordinal: 2, Value: int64(-3)},
+		{Ordinal: 3, Value: true},
+		{Ordinal: 4, Value: 1.5},
+		{Ordinal: 5, Value: []byte("raw")},
+		{Name: "at", Ordinal: 6, Value: at},
+	})
+	if err != nil {
+		t.Fatalf("bindArgs: %v", err)
+	}
+	want := []api.SQLArg{
+		api.SQLArgNull("1"),
+		api.SQLArgIntSigned("2", 8, -3),
+		api.SQLArgIntSigned("3", 8, 1),
+		api.SQLArgString("4", "1.5"),
+		api.SQLArgString("5", "raw"),
+		api.SQLArgString("at", "2024-05-06T07:08:09Z"),
+	}
+	if len(args) != len(want) {
+		t.Fatalf("args=%d want %d", len(args), len(want))
+	}
+	for i := range want {
+		if !reflect.DeepEqual(args[i], want[i]) {
+			t.Fatalf("arg %d=%+v want %+v", i, args[i], want[i])
+		}
+	}
+	if _, err := bindArgs([]driver.NamedValue{{Ordinal: 1, Value: struct{}{}}}); err == nil {
+		t.Fatalf("expected error for unsupported argument type")
+	}
+}
@@ -0,0 +1,162 @@
+// Package sqldriver registers the embedded engine with database/sql under the
+// driver name "innodb".
+//
+// The data source name is a list of configuration settings separated by
+// semicolons, for example "format=barracuda;data_home_dir=/var/lib/ib/".
+// The format key selects the file format passed to api.Startup; every other
+// key is applied with api.CfgSet before startup. When the engine is already
+// running the settings are ignored and connections share the running engine.
+package sqldriver
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/wilhasse/innodb-go/api"
+)
+
+// DriverName is the name the driver is registered under.
+const DriverName = "innodb"
+
+func init() {
+	sql.Register(DriverName, &Driver{})
+}
+
+// engineMu serializes engine startup and shutdown across connectors.
+var engineMu sync.Mutex
+
+// Driver implements driver.Driver and driver.DriverContext.
+type Driver struct{}
+
+// Open returns a new connection for the data source name.
+func (d *Driver) Open(dsn string) (driver.Conn, error) {
+	connector, err := d.OpenConnector(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return connector.Connect(context.Background())
+}
+
+// OpenConnector parses the data source name once for a sql.DB.
+func (d *Driver) OpenConnector(dsn string) (driver.Connector, error) {
+	connector := &Connector{Config: make(map[string]string)}
+	for _, part := range strings.Split(dsn, ";") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(part, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("sqldriver: malformed setting %q", part)
+		}
+		value = strings.TrimSpace(value)
+		if key == "format" {
+			connector.Format = value
+			continue
+		}
+		connector.Config[key] = value
+	}
+	return connector, nil
+}
+
+// Connector starts the engine on first use and hands out connections to it.
+type Connector struct {
+	// Format is the file format passed to api.Startup.
+	Format string
+	// Config holds settings applied with api.CfgSet before startup.
+	Config map[string]string
+
+	// owned records that this connector started the engine.
+	owned bool
+}
+
+// Connect returns a connection, starting the engine if it is not running.
+func (c *Connector) Connect(ctx context.Context) (driver.Conn, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	engineMu.Lock()
+	defer engineMu.Unlock()
+	if !api.Started() {
+		if err := c.startup(); err != nil {
+			return nil, err
+		}
+		c.owned = true
+	}
+	return &Conn{}, nil
+}
+
+// Driver returns the driver the connector belongs to.
+func (c *Connector) Driver() driver.Driver {
+	return &Driver{}
+}
+
+// Close shuts the engine down if this connector started it. sql.DB.Close
+// calls it once all connections are closed.
+func (c *Connector) Close() error {
+	engineMu.Lock()
+	defer engineMu.Unlock()
+	if !c.owned || !api.Started() {
+		return nil
+	}
+	c.owned = false
+	return errCode(api.Shutdown(api.ShutdownNormal))
+}
+
+func (c *Connector) startup() error {
+	if err := api.Init(); err != api.DB_SUCCESS {
+		return err
+	}
+	for name, text := range c.Config {
+		value, err := configValue(name, text)
+		if err != nil {
+			return err
+		}
+		if code := api.CfgSet(name, value); code != api.DB_SUCCESS {
+			return fmt.Errorf("sqldriver: setting %s: %w", name, code)
+		}
+	}
+	return errCode(api.Startup(c.Format))
+}
+
+// configValue converts a setting from the data source name into the type
+// api.CfgSet expects for it.
+func configValue(name, text string) (any, error) {
+	typ, code := api.CfgVarGetType(name)
+	if code != api.DB_SUCCESS {
+		return nil, fmt.Errorf("sqldriver: unknown setting %s: %w", name, code)
+	}
+	switch typ {
+	case api.CfgTypeBool:
+		b, err := strconv.ParseBool(text)
+		if err != nil {
+			return nil, fmt.Errorf("sqldriver: setting %s: %w", name, err)
+		}
+		return b, nil
+	case api.CfgTypeUlint, api.CfgTypeUlong:
+		u, err := strconv.ParseUint(text, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("sqldriver: setting %s: %w", name, err)
+		}
+		return u, nil
+	case api.CfgTypeText:
+		return text, nil
+	default:
+		return nil, fmt.Errorf("sqldriver: setting %s cannot be set from a data source name", name)
+	}
+}
+
+// errCode turns api status codes into Go errors. The returned error is the
+// api.ErrCode itself so callers can match it with errors.Is or errors.As.
+func errCode(code api.ErrCode) error {
+	if code == api.DB_SUCCESS {
+		return nil
+	}
+	return code
+}
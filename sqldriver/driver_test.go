@@ -0,0 +1,333 @@
+package sqldriver
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+	"testing"
+
+	"github.com/wilhasse/innodb-go/api"
+)
+
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open(DriverName, "format=barracuda;data_home_dir="+t.TempDir()+"/")
+	if err != nil {
+		t.Fatalf("sql.Open: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := db.Close(); err != nil {
+			t.Errorf("db.Close: %v", err)
+		}
+		if api.Started() {
+			t.Errorf("engine still running after db.Close")
+		}
+	})
+	db.SetMaxOpenConns(1)
+	if err := db.Ping(); err != nil {
+		t.Fatalf("Ping: %v", err)
+	}
+	createPeopleTable(t)
+	return db
+}
+
+func createPeopleTable(t *testing.T) {
+	t.Helper()
+	if err := api.DatabaseCreate("drv"); err != api.DB_SUCCESS {
+		t.Fatalf("DatabaseCreate: %v", err)
+	}
+	var schema *api.TableSchema
+	if err := api.TableSchemaCreate("drv/people", &schema, api.IB_TBL_COMPACT, 0); err != api.DB_SUCCESS {
+		t.Fatalf("TableSchemaCreate: %v", err)
+	}
+	cols := []struct {
+		name string
+		typ  api.ColType
+		attr api.ColAttr
+		size uint32
+	}{
+		{"id", api.IB_INT, api.IB_COL_NOT_NULL, 8},
+		{"name", api.IB_VARCHAR, api.IB_COL_NONE, 32},
+		{"score", api.IB_DOUBLE, api.IB_COL_NONE, 8},
+	}
+	for _, col := range cols {
+		if err := api.TableSchemaAddCol(schema, col.name, col.typ, col.attr, 0, col.size); err != api.DB_SUCCESS {
+			t.Fatalf("TableSchemaAddCol %s: %v", col.name, err)
+		}
+	}
+	var idx *api.IndexSchema
+	if err := api.TableSchemaAddIndex(schema, "PRIMARY", &idx); err != api.DB_SUCCESS {
+		t.Fatalf("TableSchemaAddIndex: %v", err)
+	}
+	if err := api.IndexSchemaAddCol(idx, "id", 0); err != api.DB_SUCCESS {
+		t.Fatalf("IndexSchemaAddCol: %v", err)
+	}
+	if err := api.IndexSchemaSetClustered(idx); err != api.DB_SUCCESS {
+		t.Fatalf("IndexSchemaSetClustered: %v", err)
+	}
+	if err := api.TableCreate(nil, schema, nil); err != api.DB_SUCCESS {
+		t.Fatalf("TableCreate: %v", err)
+	}
+}
+
+type person struct {
+	id    int64
+	name  sql.NullString
+	score sql.NullFloat64
+}
+
+func queryPeople(t *testing.T, q interface {
+	Query(string, ...any) (*sql.Rows, error)
+}) []person {
+	t.Helper()
+	rows, err := q.Query(`SELECT id, name, score FROM "drv/people"`)
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	defer rows.Close()
+	var out []person
+	for rows.Next() {
+		var p person
+		if err := rows.Scan(&p.id, &p.name, &p.score); err != nil {
+			t.Fatalf("Scan: %v", err)
+		}
+		out = append(out, p)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatalf("rows: %v", err)
+	}
+	return out
+}
+
+func TestDriverCRUD(t *testing.T) {
+	db := openTestDB(t)
+	insert := `INSERT INTO "drv/people" VALUES (?, ?, ?)`
+	for _, args := range [][]any{{1, "ann", 1.5}, {2, "bob", nil}, {3, nil, 2.25}} {
+		res, err := db.Exec(insert, args...)
+		if err != nil {
+			t.Fatalf("Exec insert %v: %v", args, err)
+		}
+		if n, _ := res.RowsAffected(); n != 1 {
+			t.Fatalf("insert affected=%d", n)
+		}
+	}
+	got := queryPeople(t, db)
+	if len(got) != 3 || got[0].id != 1 || got[0].name.String != "ann" || got[0].score.Float64 != 1.5 {
+		t.Fatalf("rows=%+v", got)
+	}
+	if got[1].score.Valid || got[2].name.Valid {
+		t.Fatalf("NULL values not preserved: %+v", got)
+	}
+
+	res, err := db.Exec(`UPDATE "drv/people" SET name = :name WHERE id = :id`,
+		sql.Named("name", "carl"), sql.Named("id", 3))
+	if err != nil {
+		t.Fatalf("Exec update: %v", err)
+	}
+	if n, _ := res.RowsAffected(); n != 1 {
+		t.Fatalf("update affected=%d", n)
+	}
+	var name string
+	if err := db.QueryRow(`SELECT name FROM "drv/people" WHERE id = ?`, 3).Scan(&name); err != nil || name != "carl" {
+		t.Fatalf("name=%q err=%v", name, err)
+	}
+
+	res, err = db.Exec(`DELETE FROM "drv/people" WHERE id = ?`, 2)
+	if err != nil {
+		t.Fatalf("Exec delete: %v", err)
+	}
+	if n, _ := res.RowsAffected(); n != 1 {
+		t.Fatalf("delete affected=%d", n)
+	}
+	if got := queryPeople(t, db); len(got) != 2 {
+		t.Fatalf("rows after delete=%+v", got)
+	}
+
+	if _, err := db.Exec(insert, -7, "neg", 0.5); err != nil {
+		t.Fatalf("Exec insert negative id: %v", err)
+	}
+	var id int64
+	if err := db.QueryRow(`SELECT id FROM "drv/people" WHERE name = ?`, "neg").Scan(&id); err != nil || id != -7 {
+		t.Fatalf("id=%d err=%v", id, err)
+	}
+	if _, err := db.Exec(insert, "x", "bad", 0); err == nil {
+		t.Fatalf("expected error for non-numeric id")
+	}
+}
+
+func TestDriverScanTypes(t *testing.T) {
+	db := openTestDB(t)
+	if err := api.ExecDDLSQL(`CREATE TABLE "drv/ints" (a BIGINT PRIMARY KEY, b BIGINT UNSIGNED, c INT UNSIGNED, d TINYINT)`); err != api.DB_SUCCESS {
+		t.Fatalf("ExecDDLSQL: %v", err)
+	}
+	if _, err := db.Exec(`INSERT INTO "drv/ints" VALUES (?, ?, ?, ?)`, -5, "9223372036854775808", 7, -1); err != nil {
+		t.Fatalf("Exec insert: %v", err)
+	}
+	rows, err := db.Query(`SELECT a, b, c, d FROM "drv/ints"`)
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	defer rows.Close()
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		t.Fatalf("ColumnTypes: %v", err)
+	}
+	if !rows.Next() {
+		t.Fatalf("no row: %v", rows.Err())
+	}
+	vals := make([]any, len(types))
+	ptrs := make([]any, len(types))
+	for i := range vals {
+		ptrs[i] = &vals[i]
+	}
+	if err := rows.Scan(ptrs...); err != nil {
+		t.Fatalf("Scan: %v", err)
+	}
+	want := []any{int64(-5), uint64(1) << 63, int64(7), int64(-1)}
+	for i, ct := range types {
+		if vals[i] != want[i] {
+			t.Fatalf("column %s=%#v want %#v", ct.Name(), vals[i], want[i])
+		}
+		if got := reflect.TypeOf(vals[i]); got != ct.ScanType() {
+			t.Fatalf("column %s value type=%v scan type=%v", ct.Name(), got, ct.ScanType())
+		}
+	}
+}
+
+func TestDriverTransactions(t *testing.T) {
+	db := openTestDB(t)
+	if _, err := db.Exec(`INSERT INTO "drv/people" VALUES (?, ?, ?)`, 1, "ann", 1.0); err != nil {
+		t.Fatalf("Exec insert: %v", err)
+	}
+
+	tx, err := db.BeginTx(context.Background(), &sql.TxOptions{Isolation: sql.LevelSerializable})
+	if err != nil {
+		t.Fatalf("BeginTx: %v", err)
+	}
+	stmt, err := tx.Prepare(`INSERT INTO "drv/people" VALUES (?, ?, ?)`)
+	if err != nil {
+		t.Fatalf("Prepare: %v", err)
+	}
+	for id := int64(2); id <= 3; id++ {
+		if _, err := stmt.Exec(id, "tmp", nil); err != nil {
+			t.Fatalf("stmt.Exec: %v", err)
+		}
+	}
+	if got := queryPeople(t, tx); len(got) != 3 {
+		t.Fatalf("rows inside tx=%+v", got)
+	}
+	if err := tx.Rollback(); err != nil {
+		t.Fatalf("Rollback: %v", err)
+	}
+	if got := queryPeople(t, db); len(got) != 1 {
+		t.Fatalf("rows after rollback=%+v", got)
+	}
+
+	tx, err = db.Begin()
+	if err != nil {
+		t.Fatalf("Begin: %v", err)
+	}
+	if _, err := tx.Exec(`UPDATE "drv/people" SET score = ? WHERE id = ?`, 9.5, 1); err != nil {
+		t.Fatalf("tx update: %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+	if got := queryPeople(t, db); len(got) != 1 || got[0].score.Float64 != 9.5 {
+		t.Fatalf("rows after commit=%+v", got)
+	}
+
+	if _, err := db.BeginTx(context.Background(), &sql.TxOptions{Isolation: sql.LevelSnapshot}); err == nil {
+		t.Fatalf("expected unsupported isolation level error")
+	}
+}
+
+func TestDriverDDL(t *testing.T) {
+	db := openTestDB(t)
+	if _, err := db.Exec(`CREATE TABLE "drv/pets" (id INT PRIMARY KEY, name VARCHAR(16))`); err != nil {
+		t.Fatalf("Exec create: %v", err)
+	}
+	if _, err := db.Exec(`INSERT INTO "drv/pets" VALUES (?, ?)`, 1, "rex"); err != nil {
+		t.Fatalf("Exec insert: %v", err)
+	}
+	var name string
+	if err := db.QueryRow(`SELECT name FROM "drv/pets" WHERE id = ?`, 1).Scan(&name); err != nil || name != "rex" {
+		t.Fatalf("name=%q err=%v", name, err)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatalf("Begin: %v", err)
+	}
+	if _, err := tx.Exec(`CREATE INDEX pets_name ON "drv/pets" (name)`); err != nil {
+		t.Fatalf("Exec create index: %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+
+	if _, err := db.Exec(`DROP TABLE "drv/pets"`); err != nil {
+		t.Fatalf("Exec drop: %v", err)
+	}
+	if _, err := db.Exec(`SELECT name FROM "drv/pets"`); err != api.DB_TABLE_NOT_FOUND {
+		t.Fatalf("select after drop err=%v", err)
+	}
+}
+
+func TestDriverPreparedStmt(t *testing.T) {
+	db := openTestDB(t)
+	ins, err := db.Prepare(`INSERT INTO "drv/people" VALUES (?, ?, ?)`)
+	if err != nil {
+		t.Fatalf("Prepare insert: %v", err)
+	}
+	defer ins.Close()
+	sel, err := db.Prepare(`SELECT name FROM "drv/people" WHERE id = ?`)
+	if err != nil {
+		t.Fatalf("Prepare select: %v", err)
+	}
+	defer sel.Close()
+	names := []string{"ann", "bob", "cid", "dee"}
+	for i, name := range names {
+		if _, err := ins.Exec(i+1, name, float64(i)); err != nil {
+			t.Fatalf("Exec insert %s: %v", name, err)
+		}
+	}
+	for i, want := range names {
+		var name string
+		if err := sel.QueryRow(i + 1).Scan(&name); err != nil || name != want {
+			t.Fatalf("id %d: name=%q err=%v", i+1, name, err)
+		}
+	}
+	if _, err := db.Prepare(`SELECT FROM`); err == nil {
+		t.Fatalf("expected error preparing invalid SQL")
+	}
+
+	conn, err := db.Conn(context.Background())
+	if err != nil {
+		t.Fatalf("Conn: %v", err)
+	}
+	defer conn.Close()
+	err = conn.Raw(func(dc any) error {
+		c := dc.(*Conn)
+		stmt, err := c.Prepare(`DELETE FROM "drv/people" WHERE id = ?`)
+		if err != nil {
+			return err
+		}
+		defer stmt.Close()
+		if stmt.(*Stmt).prepared == nil {
+			t.Errorf("DELETE not prepared")
+		}
+		ddl, err := c.Prepare(`DROP TABLE "drv/people"`)
+		if err != nil {
+			return err
+		}
+		defer ddl.Close()
+		if ddl.(*Stmt).prepared != nil {
+			t.Errorf("DDL prepared")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Raw: %v", err)
+	}
+}
@@ -0,0 +1,156 @@
+package sqldriver
+
+import (
+	"database/sql/driver"
+	"encoding/binary"
+	"io"
+	"math"
+	"reflect"
+
+	"github.com/wilhasse/innodb-go/api"
+	"github.com/wilhasse/innodb-go/data"
+)
+
+// Rows iterates over a materialized SELECT result.
+type Rows struct {
+	columns []api.SQLColumn
+	rows    []*data.Tuple
+	pos     int
+}
+
+func newRows(res *api.SQLResult) *Rows {
+	if res == nil {
+		return &Rows{}
+	}
+	return &Rows{columns: res.Columns, rows: res.Rows}
+}
+
+// Columns returns the result column names.
+func (r *Rows) Columns() []string {
+	names := make([]string, len(r.columns))
+	for i, col := range r.columns {
+		names[i] = col.Name
+	}
+	return names
+}
+
+// Close releases the result.
+func (r *Rows) Close() error {
+	r.rows = nil
+	r.pos = 0
+	return nil
+}
+
+// Next decodes the next row into dest.
+func (r *Rows) Next(dest []driver.Value) error {
+	if r.pos >= len(r.rows) {
+		return io.EOF
+	}
+	row := r.rows[r.pos]
+	r.pos++
+	for i := range dest {
+		if i >= len(r.columns) || row == nil || i >= len(row.Fields) {
+			dest[i] = nil
+			continue
+		}
+		value, err := decodeValue(r.columns[i], row.Fields[i])
+		if err != nil {
+			return err
+		}
+		dest[i] = value
+	}
+	return nil
+}
+
+// ColumnTypeDatabaseTypeName returns the engine type of a column.
+func (r *Rows) ColumnTypeDatabaseTypeName(index int) string {
+	switch r.columns[index].Type {
+	case api.IB_VARCHAR, api.IB_VARCHAR_ANYCHARSET:
+		return "VARCHAR"
+	case api.IB_CHAR, api.IB_CHAR_ANYCHARSET:
+		return "CHAR"
+	case api.IB_BINARY:
+		return "BINARY"
+	case api.IB_VARBINARY:
+		return "VARBINARY"
+	case api.IB_BLOB:
+		return "BLOB"
+	case api.IB_INT:
+		if r.columns[index].Attr&api.IB_COL_UNSIGNED != 0 {
+			return "UNSIGNED INT"
+		}
+		return "INT"
+	case api.IB_FLOAT:
+		return "FLOAT"
+	case api.IB_DOUBLE:
+		return "DOUBLE"
+	case api.IB_DECIMAL:
+		return "DECIMAL"
+	default:
+		return ""
+	}
+}
+
+// ColumnTypeNullable reports whether a column allows NULL.
+func (r *Rows) ColumnTypeNullable(index int) (nullable, ok bool) {
+	return r.columns[index].Attr&api.IB_COL_NOT_NULL == 0, true
+}
+
+// ColumnTypeScanType returns the Go type Next produces for a column.
+func (r *Rows) ColumnTypeScanType(index int) reflect.Type {
+	col := r.columns[index]
+	switch col.Type {
+	case api.IB_INT:
+		if intScanUnsigned(col) {
+			return reflect.TypeOf(uint64(0))
+		}
+		return reflect.TypeOf(int64(0))
+	case api.IB_FLOAT, api.IB_DOUBLE:
+		return reflect.TypeOf(float64(0))
+	case api.IB_BINARY, api.IB_VARBINARY, api.IB_BLOB:
+		return reflect.TypeOf([]byte(nil))
+	default:
+		return reflect.TypeOf("")
+	}
+}
+
+// intScanUnsigned reports whether an integer column may hold values beyond
+// the int64 range and is read as uint64.
+func intScanUnsigned(col api.SQLColumn) bool {
+	return col.Attr&api.IB_COL_UNSIGNED != 0 && col.Size == 8
+}
+
+// decodeValue converts a stored field into a driver value of the type
+// ColumnTypeScanType reports: unsigned 8-byte integers as uint64 and other
+// integers as int64.
+func decodeValue(col api.SQLColumn, field data.Field) (driver.Value, error) {
+	if field.Len == data.UnivSQLNull {
+		return nil, nil
+	}
+	buf := field.Data
+	switch col.Type {
+	case api.IB_INT:
+		i, u, err := api.DecodeInt(buf, col.Attr&api.IB_COL_UNSIGNED != 0)
+		if err != api.DB_SUCCESS {
+			return nil, err
+		}
+		if intScanUnsigned(col) {
+			return u, nil
+		}
+		return i, nil
+	case api.IB_FLOAT:
+		if len(buf) != 4 {
+			return nil, api.DB_DATA_MISMATCH
+		}
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(buf))), nil
+	case api.IB_DOUBLE:
+		if len(buf) != 8 {
+			return nil, api.DB_DATA_MISMATCH
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(buf)), nil
+	case api.IB_BINARY, api.IB_VARBINARY, api.IB_BLOB:
+		return append([]byte(nil), buf...), nil
+	default:
+		return string(buf), nil
+	}
+}
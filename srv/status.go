@@ -35,6 +35,14 @@ type ExportStatusVars struct {
 	InnodbPagesWritten            ut.Ulint
 	InnodbDblwrPagesWritten       ut.Ulint
 	InnodbDblwrWrites             ut.Ulint
+	InnodbZipCompressOps          ut.Ulint
+	InnodbZipCompressFailures     ut.Ulint
+	InnodbZipDecompressOps        ut.Ulint
+	InnodbPageCompressionSaved    ut.Ulint
+	InnodbPagesPageCompressed     ut.Ulint
+	InnodbPagesPageDecompressed   ut.Ulint
+	InnodbPagesEncrypted          ut.Ulint
+	InnodbPagesDecrypted          ut.Ulint
 	InnodbLogWaits                ut.Ulint
 	InnodbLogWriteRequests        ut.Ulint
 	InnodbLogWrites               ut.Ulint
@@ -97,6 +105,9 @@ func ExportInnoDBStatus() {
 	pendingLogFlushes := atomic.LoadUint64(&iblog.NPendingLogFlushes)
 	pendingSpaceFlushes := atomic.LoadUint64(&fil.NPendingTablespaceFlushes)
 	dblwrPages, dblwrWrites := fil.DoublewriteStats()
+	zipCompress, zipFailures, zipDecompress := fil.ZipStats()
+	pageCompSaved, pageCompPages, pageDecompPages := fil.PageCompressionStats()
+	encPages, decPages := fil.EncryptionStats()
 
 	ExportVars.InnodbDataPendingWrites = ut.Ulint(pendingSpaceFlushes)
 	ExportVars.InnodbDataPendingFsyncs = ut.Ulint(pendingSpaceFlushes)
@@ -120,6 +131,14 @@ func ExportInnoDBStatus() {
 	ExportVars.InnodbPagesWritten = ut.Ulint(writes)
 	ExportVars.InnodbDblwrPagesWritten = ut.Ulint(dblwrPages)
 	ExportVars.InnodbDblwrWrites = ut.Ulint(dblwrWrites)
+	ExportVars.InnodbZipCompressOps = ut.Ulint(zipCompress)
+	ExportVars.InnodbZipCompressFailures = ut.Ulint(zipFailures)
+	ExportVars.InnodbZipDecompressOps = ut.Ulint(zipDecompress)
+	ExportVars.InnodbPageCompressionSaved = ut.Ulint(pageCompSaved)
+	ExportVars.InnodbPagesPageCompressed = ut.Ulint(pageCompPages)
+	ExportVars.InnodbPagesPageDecompressed = ut.Ulint(pageDecompPages)
+	ExportVars.InnodbPagesEncrypted = ut.Ulint(encPages)
+	ExportVars.InnodbPagesDecrypted = ut.Ulint(decPages)
 
 	ExportVars.InnodbLogWriteRequests = ut.Ulint(logFlushes)
 	ExportVars.InnodbLogWrites = ut.Ulint(logFlushes)
@@ -0,0 +1,226 @@
+package tests
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/wilhasse/innodb-go/api"
+	"github.com/wilhasse/innodb-go/buf"
+)
+
+const encryptedTable = "enc_db/t"
+
+// TestEncryptedTable writes an encrypted table with an encrypted redo log,
+// checks that neither keeps the rows in the clear and reads the rows back
+// after a master key rotation and restarts.
+func TestEncryptedTable(t *testing.T) {
+	resetAPI(t)
+	dir := t.TempDir() + "/"
+	start := func(keyring string) api.ErrCode {
+		t.Helper()
+		if err := api.Init(); err != api.DB_SUCCESS {
+			t.Fatalf("Init: %v", err)
+		}
+		if err := api.CfgSet("data_home_dir", dir); err != api.DB_SUCCESS {
+			t.Fatalf("CfgSet data_home_dir: %v", err)
+		}
+		if err := api.CfgSet("keyring_file", keyring); err != api.DB_SUCCESS {
+			t.Fatalf("CfgSet keyring_file: %v", err)
+		}
+		if err := api.CfgSet("encrypt_redo", api.IBTrue); err != api.DB_SUCCESS {
+			t.Fatalf("CfgSet encrypt_redo: %v", err)
+		}
+		return api.Startup("barracuda")
+	}
+	shutdown := func() {
+		t.Helper()
+		if err := api.Shutdown(api.ShutdownNormal); err != api.DB_SUCCESS {
+			t.Fatalf("Shutdown: %v", err)
+		}
+	}
+
+	if err := start("keyring"); err != api.DB_SUCCESS {
+		t.Fatalf("Startup: %v", err)
+	}
+	if err := api.DatabaseCreate("enc_db"); err != api.DB_SUCCESS {
+		t.Fatalf("DatabaseCreate: %v", err)
+	}
+	var schema *api.TableSchema
+	if err := api.TableSchemaCreate(encryptedTable, &schema, api.IB_TBL_COMPACT, 0); err != api.DB_SUCCESS {
+		t.Fatalf("TableSchemaCreate: %v", err)
+	}
+	if err := api.TableSchemaSetPageCompressed(schema, true); err != api.DB_SUCCESS {
+		t.Fatalf("TableSchemaSetPageCompressed: %v", err)
+	}
+	if err := api.TableSchemaSetEncrypted(schema, true); err != api.DB_INVALID_INPUT {
+		t.Fatalf("encryption of a page compressed table: %v", err)
+	}
+	api.TableSchemaDelete(schema)
+	if err := createEncryptedTable(); err != api.DB_SUCCESS {
+		t.Fatalf("create table: %v", err)
+	}
+	const rows = 50
+	if err := insertEncryptedRows(rows); err != api.DB_SUCCESS {
+		t.Fatalf("insert rows: %v", err)
+	}
+	_ = buf.FlushAll()
+	var encrypted int64
+	if err := api.StatusGetI64("pages_encrypted", &encrypted); err != api.DB_SUCCESS || encrypted == 0 {
+		t.Fatalf("pages_encrypted=%d err=%v", encrypted, err)
+	}
+	shutdown()
+
+	for _, pattern := range []string{"enc_db/t*.ibd", "ib_logfile*"} {
+		matches, _ := filepath.Glob(filepath.Join(dir, pattern))
+		if len(matches) == 0 {
+			t.Fatalf("no files match %s", pattern)
+		}
+		for _, path := range matches {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("ReadFile: %v", err)
+			}
+			if bytes.Contains(data, []byte("encrypted row")) {
+				t.Fatalf("%s holds rows in the clear", path)
+			}
+		}
+	}
+
+	if err := start("keyring"); err != api.DB_SUCCESS {
+		t.Fatalf("Startup: %v", err)
+	}
+	if err := verifyEncryptedRows(rows); err != api.DB_SUCCESS {
+		t.Fatalf("verify rows: %v", err)
+	}
+	if err := api.MasterKeyRotate(); err != api.DB_SUCCESS {
+		t.Fatalf("MasterKeyRotate: %v", err)
+	}
+	shutdown()
+
+	if err := start("keyring"); err != api.DB_SUCCESS {
+		t.Fatalf("Startup after rotation: %v", err)
+	}
+	if err := verifyEncryptedRows(rows); err != api.DB_SUCCESS {
+		t.Fatalf("verify rows after rotation: %v", err)
+	}
+	if err := api.TableDrop(nil, encryptedTable); err != api.DB_SUCCESS {
+		t.Fatalf("TableDrop: %v", err)
+	}
+	shutdown()
+
+	// The redo log cannot be opened without the keyring.
+	if err := start(""); err == api.DB_SUCCESS {
+		t.Fatalf("Startup without keyring succeeded")
+	}
+	_ = api.Shutdown(api.ShutdownNormal)
+}
+
+func encryptedValue(id uint32) []byte {
+	return []byte(fmt.Sprintf("encrypted row %08d", id))
+}
+
+func createEncryptedTable() api.ErrCode {
+	var schema *api.TableSchema
+	if err := api.TableSchemaCreate(encryptedTable, &schema, api.IB_TBL_COMPACT, 0); err != api.DB_SUCCESS {
+		return err
+	}
+	defer api.TableSchemaDelete(schema)
+	if err := api.TableSchemaSetEncrypted(schema, true); err != api.DB_SUCCESS {
+		return err
+	}
+	if err := api.TableSchemaAddCol(schema, "c1", api.IB_INT, api.IB_COL_UNSIGNED, 0, 4); err != api.DB_SUCCESS {
+		return err
+	}
+	if err := api.TableSchemaAddCol(schema, "c2", api.IB_VARCHAR, api.IB_COL_NONE, 0, 100); err != api.DB_SUCCESS {
+		return err
+	}
+	var idx *api.IndexSchema
+	if err := api.TableSchemaAddIndex(schema, "PRIMARY", &idx); err != api.DB_SUCCESS {
+		return err
+	}
+	if err := api.IndexSchemaAddCol(idx, "c1", 0); err != api.DB_SUCCESS {
+		return err
+	}
+	if err := api.IndexSchemaSetClustered(idx); err != api.DB_SUCCESS {
+		return err
+	}
+	trx := api.TrxBegin(api.IB_TRX_REPEATABLE_READ)
+	if err := api.SchemaLockExclusive(trx); err != api.DB_SUCCESS {
+		_ = api.TrxRollback(trx)
+		return err
+	}
+	if err := api.TableCreate(trx, schema, nil); err != api.DB_SUCCESS {
+		_ = api.TrxRollback(trx)
+		return err
+	}
+	return api.TrxCommit(trx)
+}
+
+func insertEncryptedRows(rows uint32) api.ErrCode {
+	trx := api.TrxBegin(api.IB_TRX_REPEATABLE_READ)
+	var crsr *api.Cursor
+	if err := api.CursorOpenTable(encryptedTable, trx, &crsr); err != api.DB_SUCCESS {
+		_ = api.TrxRollback(trx)
+		return err
+	}
+	tpl := api.ClustReadTupleCreate(crsr)
+	for id := uint32(1); id <= rows; id++ {
+		value := encryptedValue(id)
+		if err := api.TupleWriteU32(tpl, 0, id); err != api.DB_SUCCESS {
+			return err
+		}
+		if err := api.ColSetValue(tpl, 1, value, len(value)); err != api.DB_SUCCESS {
+			return err
+		}
+		if err := api.CursorInsertRow(crsr, tpl); err != api.DB_SUCCESS {
+			api.TupleDelete(tpl)
+			_ = api.CursorClose(crsr)
+			_ = api.TrxRollback(trx)
+			return err
+		}
+		tpl = api.TupleClear(tpl)
+	}
+	api.TupleDelete(tpl)
+	if err := api.CursorClose(crsr); err != api.DB_SUCCESS {
+		_ = api.TrxRollback(trx)
+		return err
+	}
+	return api.TrxCommit(trx)
+}
+
+func verifyEncryptedRows(rows uint32) api.ErrCode {
+	var crsr *api.Cursor
+	if err := api.CursorOpenTable(encryptedTable, nil, &crsr); err != api.DB_SUCCESS {
+		return err
+	}
+	defer api.CursorClose(crsr)
+	tpl := api.ClustReadTupleCreate(crsr)
+	defer api.TupleDelete(tpl)
+	if err := api.CursorFirst(crsr); err != api.DB_SUCCESS {
+		return err
+	}
+	for id := uint32(1); id <= rows; id++ {
+		if id > 1 {
+			if err := api.CursorNext(crsr); err != api.DB_SUCCESS {
+				return err
+			}
+		}
+		if err := api.CursorReadRow(crsr, tpl); err != api.DB_SUCCESS {
+			return err
+		}
+		var got uint32
+		if err := api.TupleReadU32(tpl, 0, &got); err != api.DB_SUCCESS {
+			return err
+		}
+		if got != id || !bytes.Equal(api.ColGetValue(tpl, 1), encryptedValue(id)) {
+			return api.DB_CORRUPTION
+		}
+	}
+	if err := api.CursorNext(crsr); err != api.DB_END_OF_INDEX {
+		return api.DB_CORRUPTION
+	}
+	return api.DB_SUCCESS
+}
@@ -0,0 +1,181 @@
+package tests
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/wilhasse/innodb-go/api"
+	"github.com/wilhasse/innodb-go/buf"
+)
+
+const pageCompTable = "page_comp/t"
+
+// TestPageCompressedTable writes a page compressed table and reads it back
+// from the compressed pages after a restart.
+func TestPageCompressedTable(t *testing.T) {
+	resetAPI(t)
+	dir := t.TempDir() + "/"
+	start := func() {
+		t.Helper()
+		if err := api.Init(); err != api.DB_SUCCESS {
+			t.Fatalf("Init: %v", err)
+		}
+		if err := api.CfgSet("data_home_dir", dir); err != api.DB_SUCCESS {
+			t.Fatalf("CfgSet data_home_dir: %v", err)
+		}
+		if err := api.Startup("barracuda"); err != api.DB_SUCCESS {
+			t.Fatalf("Startup: %v", err)
+		}
+	}
+
+	start()
+	if err := api.DatabaseCreate("page_comp"); err != api.DB_SUCCESS {
+		t.Fatalf("DatabaseCreate: %v", err)
+	}
+	var schema *api.TableSchema
+	if err := api.TableSchemaCreate(pageCompTable, &schema, api.IB_TBL_COMPRESSED, 4); err != api.DB_SUCCESS {
+		t.Fatalf("TableSchemaCreate: %v", err)
+	}
+	if err := api.TableSchemaSetPageCompressed(schema, true); err != api.DB_INVALID_INPUT {
+		t.Fatalf("page compression of a compressed table: %v", err)
+	}
+	api.TableSchemaDelete(schema)
+	if err := createPageCompTable(); err != api.DB_SUCCESS {
+		t.Fatalf("create table: %v", err)
+	}
+	const rows = 100
+	if err := insertPageCompRows(rows); err != api.DB_SUCCESS {
+		t.Fatalf("insert rows: %v", err)
+	}
+	_ = buf.FlushAll()
+	var compressed int64
+	if err := api.StatusGetI64("pages_page_compressed", &compressed); err != api.DB_SUCCESS || compressed == 0 {
+		t.Fatalf("pages_page_compressed=%d err=%v", compressed, err)
+	}
+	if err := api.Shutdown(api.ShutdownNormal); err != api.DB_SUCCESS {
+		t.Fatalf("Shutdown: %v", err)
+	}
+
+	start()
+	if err := verifyPageCompRows(rows); err != api.DB_SUCCESS {
+		t.Fatalf("verify rows: %v", err)
+	}
+	var decompressed int64
+	if err := api.StatusGetI64("pages_page_decompressed", &decompressed); err != api.DB_SUCCESS || decompressed == 0 {
+		t.Fatalf("pages_page_decompressed=%d err=%v", decompressed, err)
+	}
+	if err := api.TableDrop(nil, pageCompTable); err != api.DB_SUCCESS {
+		t.Fatalf("TableDrop: %v", err)
+	}
+	if err := api.Shutdown(api.ShutdownNormal); err != api.DB_SUCCESS {
+		t.Fatalf("Shutdown final: %v", err)
+	}
+}
+
+func pageCompValue(id uint32) []byte {
+	return []byte(fmt.Sprintf("row %08d %s", id, bytes.Repeat([]byte("page compression "), 4)))
+}
+
+func createPageCompTable() api.ErrCode {
+	var schema *api.TableSchema
+	if err := api.TableSchemaCreate(pageCompTable, &schema, api.IB_TBL_COMPACT, 0); err != api.DB_SUCCESS {
+		return err
+	}
+	defer api.TableSchemaDelete(schema)
+	if err := api.TableSchemaSetPageCompressed(schema, true); err != api.DB_SUCCESS {
+		return err
+	}
+	if err := api.TableSchemaAddCol(schema, "c1", api.IB_INT, api.IB_COL_UNSIGNED, 0, 4); err != api.DB_SUCCESS {
+		return err
+	}
+	if err := api.TableSchemaAddCol(schema, "c2", api.IB_VARCHAR, api.IB_COL_NONE, 0, 200); err != api.DB_SUCCESS {
+		return err
+	}
+	var idx *api.IndexSchema
+	if err := api.TableSchemaAddIndex(schema, "PRIMARY", &idx); err != api.DB_SUCCESS {
+		return err
+	}
+	if err := api.IndexSchemaAddCol(idx, "c1", 0); err != api.DB_SUCCESS {
+		return err
+	}
+	if err := api.IndexSchemaSetClustered(idx); err != api.DB_SUCCESS {
+		return err
+	}
+	trx := api.TrxBegin(api.IB_TRX_REPEATABLE_READ)
+	if err := api.SchemaLockExclusive(trx); err != api.DB_SUCCESS {
+		_ = api.TrxRollback(trx)
+		return err
+	}
+	if err := api.TableCreate(trx, schema, nil); err != api.DB_SUCCESS {
+		_ = api.TrxRollback(trx)
+		return err
+	}
+	return api.TrxCommit(trx)
+}
+
+func insertPageCompRows(rows uint32) api.ErrCode {
+	trx := api.TrxBegin(api.IB_TRX_REPEATABLE_READ)
+	var crsr *api.Cursor
+	if err := api.CursorOpenTable(pageCompTable, trx, &crsr); err != api.DB_SUCCESS {
+		_ = api.TrxRollback(trx)
+		return err
+	}
+	tpl := api.ClustReadTupleCreate(crsr)
+	for id := uint32(1); id <= rows; id++ {
+		value := pageCompValue(id)
+		if err := api.TupleWriteU32(tpl, 0, id); err != api.DB_SUCCESS {
+			return err
+		}
+		if err := api.ColSetValue(tpl, 1, value, len(value)); err != api.DB_SUCCESS {
+			return err
+		}
+		if err := api.CursorInsertRow(crsr, tpl); err != api.DB_SUCCESS {
+			api.TupleDelete(tpl)
+			_ = api.CursorClose(crsr)
+			_ = api.TrxRollback(trx)
+			return err
+		}
+		tpl = api.TupleClear(tpl)
+	}
+	api.TupleDelete(tpl)
+	if err := api.CursorClose(crsr); err != api.DB_SUCCESS {
+		_ = api.TrxRollback(trx)
+		return err
+	}
+	return api.TrxCommit(trx)
+}
+
+func verifyPageCompRows(rows uint32) api.ErrCode {
+	var crsr *api.Cursor
+	if err := api.CursorOpenTable(pageCompTable, nil, &crsr); err != api.DB_SUCCESS {
+		return err
+	}
+	defer api.CursorClose(crsr)
+	tpl := api.ClustReadTupleCreate(crsr)
+	defer api.TupleDelete(tpl)
+	if err := api.CursorFirst(crsr); err != api.DB_SUCCESS {
+		return err
+	}
+	for id := uint32(1); id <= rows; id++ {
+		if id > 1 {
+			if err := api.CursorNext(crsr); err != api.DB_SUCCESS {
+				return err
+			}
+		}
+		if err := api.CursorReadRow(crsr, tpl); err != api.DB_SUCCESS {
+			return err
+		}
+		var got uint32
+		if err := api.TupleReadU32(tpl, 0, &got); err != api.DB_SUCCESS {
+			return err
+		}
+		if got != id || !bytes.Equal(api.ColGetValue(tpl, 1), pageCompValue(id)) {
+			return api.DB_CORRUPTION
+		}
+	}
+	if err := api.CursorNext(crsr); err != api.DB_END_OF_INDEX {
+		return api.DB_CORRUPTION
+	}
+	return api.DB_SUCCESS
+}
@@ -0,0 +1,171 @@
+package tests
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/wilhasse/innodb-go/api"
+)
+
+const restartBlobTable = "restart_blob/t"
+
+// TestRestartBlobPersistence stores column values far larger than a page,
+// which go to BLOB pages of the table's tablespace, and reads them back
+// after restarts; rows inserted after a restart must not reuse those pages.
+func TestRestartBlobPersistence(t *testing.T) {
+	resetAPI(t)
+	dir := t.TempDir() + "/"
+	start := func() {
+		t.Helper()
+		if err := api.Init(); err != api.DB_SUCCESS {
+			t.Fatalf("Init: %v", err)
+		}
+		if err := api.CfgSet("data_home_dir", dir); err != api.DB_SUCCESS {
+			t.Fatalf("CfgSet data_home_dir: %v", err)
+		}
+		if err := api.Startup("barracuda"); err != api.DB_SUCCESS {
+			t.Fatalf("Startup: %v", err)
+		}
+	}
+	restart := func() {
+		t.Helper()
+		if err := api.Shutdown(api.ShutdownNormal); err != api.DB_SUCCESS {
+			t.Fatalf("Shutdown: %v", err)
+		}
+		start()
+	}
+
+	start()
+	if err := api.DatabaseCreate("restart_blob"); err != api.DB_SUCCESS {
+		t.Fatalf("DatabaseCreate: %v", err)
+	}
+	if err := createRestartBlobTable(); err != api.DB_SUCCESS {
+		t.Fatalf("create table: %v", err)
+	}
+	if err := insertRestartBlobRows(1, 2, 3); err != api.DB_SUCCESS {
+		t.Fatalf("insert rows: %v", err)
+	}
+	restart()
+	if err := insertRestartBlobRows(4, 5); err != api.DB_SUCCESS {
+		t.Fatalf("insert rows after restart: %v", err)
+	}
+	restart()
+	if err := verifyRestartBlobRows(1, 2, 3, 4, 5); err != api.DB_SUCCESS {
+		t.Fatalf("verify rows: %v", err)
+	}
+	if err := api.TableDrop(nil, restartBlobTable); err != api.DB_SUCCESS {
+		t.Fatalf("TableDrop: %v", err)
+	}
+	if err := api.Shutdown(api.ShutdownNormal); err != api.DB_SUCCESS {
+		t.Fatalf("Shutdown final: %v", err)
+	}
+}
+
+// restartBlobValue spans several pages and differs for every row.
+func restartBlobValue(id uint32) []byte {
+	value := make([]byte, 40000+int(id)*1000)
+	for i := range value {
+		value[i] = byte(i*int(id) + int(id))
+	}
+	return value
+}
+
+func createRestartBlobTable() api.ErrCode {
+	var schema *api.TableSchema
+	if err := api.TableSchemaCreate(restartBlobTable, &schema, api.IB_TBL_COMPACT, 0); err != api.DB_SUCCESS {
+		return err
+	}
+	defer api.TableSchemaDelete(schema)
+	if err := api.TableSchemaAddCol(schema, "c1", api.IB_INT, api.IB_COL_UNSIGNED, 0, 4); err != api.DB_SUCCESS {
+		return err
+	}
+	if err := api.TableSchemaAddCol(schema, "c2", api.IB_BLOB, api.IB_COL_NONE, 0, 0); err != api.DB_SUCCESS {
+		return err
+	}
+	var idx *api.IndexSchema
+	if err := api.TableSchemaAddIndex(schema, "PRIMARY", &idx); err != api.DB_SUCCESS {
+		return err
+	}
+	if err := api.IndexSchemaAddCol(idx, "c1", 0); err != api.DB_SUCCESS {
+		return err
+	}
+	if err := api.IndexSchemaSetClustered(idx); err != api.DB_SUCCESS {
+		return err
+	}
+	trx := api.TrxBegin(api.IB_TRX_REPEATABLE_READ)
+	if err := api.SchemaLockExclusive(trx); err != api.DB_SUCCESS {
+		_ = api.TrxRollback(trx)
+		return err
+	}
+	if err := api.TableCreate(trx, schema, nil); err != api.DB_SUCCESS {
+		_ = api.TrxRollback(trx)
+		return err
+	}
+	return api.TrxCommit(trx)
+}
+
+func insertRestartBlobRows(ids ...uint32) api.ErrCode {
+	trx := api.TrxBegin(api.IB_TRX_REPEATABLE_READ)
+	var crsr *api.Cursor
+	if err := api.CursorOpenTable(restartBlobTable, trx, &crsr); err != api.DB_SUCCESS {
+		_ = api.TrxRollback(trx)
+		return err
+	}
+	tpl := api.ClustReadTupleCreate(crsr)
+	for _, id := range ids {
+		value := restartBlobValue(id)
+		if err := api.TupleWriteU32(tpl, 0, id); err != api.DB_SUCCESS {
+			return err
+		}
+		if err := api.ColSetValue(tpl, 1, value, len(value)); err != api.DB_SUCCESS {
+			return err
+		}
+		if err := api.CursorInsertRow(crsr, tpl); err != api.DB_SUCCESS {
+			api.TupleDelete(tpl)
+			_ = api.CursorClose(crsr)
+			_ = api.TrxRollback(trx)
+			return err
+		}
+		tpl = api.TupleClear(tpl)
+	}
+	api.TupleDelete(tpl)
+	if err := api.CursorClose(crsr); err != api.DB_SUCCESS {
+		_ = api.TrxRollback(trx)
+		return err
+	}
+	return api.TrxCommit(trx)
+}
+
+func verifyRestartBlobRows(ids ...uint32) api.ErrCode {
+	var crsr *api.Cursor
+	if err := api.CursorOpenTable(restartBlobTable, nil, &crsr); err != api.DB_SUCCESS {
+		return err
+	}
+	defer api.CursorClose(crsr)
+	tpl := api.ClustReadTupleCreate(crsr)
+	defer api.TupleDelete(tpl)
+	if err := api.CursorFirst(crsr); err != api.DB_SUCCESS {
+		return err
+	}
+	for i, id := range ids {
+		if i > 0 {
+			if err := api.CursorNext(crsr); err != api.DB_SUCCESS {
+				return err
+			}
+		}
+		if err := api.CursorReadRow(crsr, tpl); err != api.DB_SUCCESS {
+			return err
+		}
+		var got uint32
+		if err := api.TupleReadU32(tpl, 0, &got); err != api.DB_SUCCESS {
+			return err
+		}
+		if got != id || !bytes.Equal(api.ColGetValue(tpl, 1), restartBlobValue(id)) {
+			return api.DB_CORRUPTION
+		}
+	}
+	if err := api.CursorNext(crsr); err != api.DB_END_OF_INDEX {
+		return api.DB_CORRUPTION
+	}
+	return api.DB_SUCCESS
+}
@@ -0,0 +1,124 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/wilhasse/innodb-go/api"
+)
+
+// TestRestartAfterRootSplit splits the root of a table, then deletes and
+// reinserts rows across restarts. The dictionary keeps the root page number
+// of the table, so a split must not move the root.
+func TestRestartAfterRootSplit(t *testing.T) {
+	resetAPI(t)
+	dir := t.TempDir() + "/"
+	tableName := restartDB + "/split"
+
+	restartSplitTest(t, dir, true)
+	if err := createRestartTable(tableName); err != api.DB_SUCCESS {
+		t.Fatalf("create table: %v", err)
+	}
+	if err := insertRestartRows(tableName, idRange(1, 40)); err != api.DB_SUCCESS {
+		t.Fatalf("insert rows: %v", err)
+	}
+
+	restartSplitTest(t, dir, false)
+	for id := uint32(31); id <= 40; id++ {
+		if err := deleteCRUDRow(tableName, id); err != api.DB_SUCCESS {
+			t.Fatalf("delete %d: %v", id, err)
+		}
+	}
+	if err := verifyRestartRows(tableName, idRange(1, 30)); err != api.DB_SUCCESS {
+		t.Fatalf("verify after delete: %v", err)
+	}
+
+	restartSplitTest(t, dir, false)
+	if err := verifyRestartRows(tableName, idRange(1, 30)); err != api.DB_SUCCESS {
+		t.Fatalf("verify after delete and restart: %v", err)
+	}
+	if err := insertRestartRows(tableName, idRange(100, 140)); err != api.DB_SUCCESS {
+		t.Fatalf("reinsert rows: %v", err)
+	}
+
+	restartSplitTest(t, dir, false)
+	want := append(idRange(1, 30), idRange(100, 140)...)
+	if err := verifyRestartRows(tableName, want); err != api.DB_SUCCESS {
+		t.Fatalf("verify after reinsert: %v", err)
+	}
+	if err := api.TableDrop(nil, tableName); err != api.DB_SUCCESS {
+		t.Fatalf("TableDrop: %v", err)
+	}
+	if err := api.Shutdown(api.ShutdownNormal); err != api.DB_SUCCESS {
+		t.Fatalf("Shutdown: %v", err)
+	}
+}
+
+// TestRestartAfterRootLowered deletes enough rows for the merges to lower
+// the root, then reinserts rows across restarts. Lowering the tree must not
+// move the root either.
+func TestRestartAfterRootLowered(t *testing.T) {
+	resetAPI(t)
+	dir := t.TempDir() + "/"
+	tableName := restartDB + "/lowered"
+
+	restartSplitTest(t, dir, true)
+	if err := createRestartTable(tableName); err != api.DB_SUCCESS {
+		t.Fatalf("create table: %v", err)
+	}
+	if err := insertRestartRows(tableName, idRange(1, 9)); err != api.DB_SUCCESS {
+		t.Fatalf("insert rows: %v", err)
+	}
+
+	restartSplitTest(t, dir, false)
+	for id := uint32(4); id <= 9; id++ {
+		if err := deleteCRUDRow(tableName, id); err != api.DB_SUCCESS {
+			t.Fatalf("delete %d: %v", id, err)
+		}
+	}
+
+	restartSplitTest(t, dir, false)
+	if err := verifyRestartRows(tableName, idRange(1, 3)); err != api.DB_SUCCESS {
+		t.Fatalf("verify after delete and restart: %v", err)
+	}
+	if err := insertRestartRows(tableName, idRange(100, 140)); err != api.DB_SUCCESS {
+		t.Fatalf("reinsert rows: %v", err)
+	}
+
+	restartSplitTest(t, dir, false)
+	want := append(idRange(1, 3), idRange(100, 140)...)
+	if err := verifyRestartRows(tableName, want); err != api.DB_SUCCESS {
+		t.Fatalf("verify after reinsert: %v", err)
+	}
+	if err := api.Shutdown(api.ShutdownNormal); err != api.DB_SUCCESS {
+		t.Fatalf("Shutdown: %v", err)
+	}
+}
+
+func restartSplitTest(t *testing.T, dir string, first bool) {
+	t.Helper()
+	if !first {
+		if err := api.Shutdown(api.ShutdownNormal); err != api.DB_SUCCESS {
+			t.Fatalf("Shutdown: %v", err)
+		}
+	}
+	if err := api.Init(); err != api.DB_SUCCESS {
+		t.Fatalf("Init: %v", err)
+	}
+	if err := api.CfgSet("data_home_dir", dir); err != api.DB_SUCCESS {
+		t.Fatalf("CfgSet data_home_dir: %v", err)
+	}
+	if err := api.Startup("barracuda"); err != api.DB_SUCCESS {
+		t.Fatalf("Startup: %v", err)
+	}
+	if err := api.DatabaseCreate(restartDB); err != api.DB_SUCCESS {
+		t.Fatalf("DatabaseCreate: %v", err)
+	}
+}
+
+func idRange(from, to uint32) []uint32 {
+	var out []uint32
+	for id := from; id <= to; id++ {
+		out = append(out, id)
+	}
+	return out
+}
@@ -0,0 +1,190 @@
+package tests
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/wilhasse/innodb-go/api"
+)
+
+const restartZipTable = "restart_zip/t"
+
+// TestRestartCompressedTable fills a table with 1K compressed pages, whose
+// leaves split when a page stops compressing into its zip size, and reads
+// the rows back from the compressed tablespace after a restart.
+func TestRestartCompressedTable(t *testing.T) {
+	resetAPI(t)
+	dir := t.TempDir() + "/"
+	start := func() {
+		t.Helper()
+		if err := api.Init(); err != api.DB_SUCCESS {
+			t.Fatalf("Init: %v", err)
+		}
+		if err := api.CfgSet("data_home_dir", dir); err != api.DB_SUCCESS {
+			t.Fatalf("CfgSet data_home_dir: %v", err)
+		}
+		if err := api.Startup("barracuda"); err != api.DB_SUCCESS {
+			t.Fatalf("Startup: %v", err)
+		}
+	}
+
+	start()
+	if err := api.DatabaseCreate("restart_zip"); err != api.DB_SUCCESS {
+		t.Fatalf("DatabaseCreate: %v", err)
+	}
+	if err := createRestartZipTable(); err != api.DB_SUCCESS {
+		t.Fatalf("create table: %v", err)
+	}
+	const rows = 200
+	if err := insertRestartZipRows(rows); err != api.DB_SUCCESS {
+		t.Fatalf("insert rows: %v", err)
+	}
+	var ops, failures int64
+	if err := api.StatusGetI64("zip_compress_ops", &ops); err != api.DB_SUCCESS || ops == 0 {
+		t.Fatalf("zip_compress_ops=%d err=%v", ops, err)
+	}
+	if err := api.StatusGetI64("zip_compress_failures", &failures); err != api.DB_SUCCESS || failures == 0 {
+		t.Fatalf("zip_compress_failures=%d err=%v", failures, err)
+	}
+	if err := api.Shutdown(api.ShutdownNormal); err != api.DB_SUCCESS {
+		t.Fatalf("Shutdown: %v", err)
+	}
+
+	matches, _ := filepath.Glob(filepath.Join(dir, "restart_zip", "t*.ibd"))
+	if len(matches) != 1 {
+		t.Fatalf("table files=%v", matches)
+	}
+	info, err := os.Stat(matches[0])
+	if err != nil || info.Size()%1024 != 0 || info.Size() >= rows*4096 {
+		t.Fatalf("table file size=%d err=%v", info.Size(), err)
+	}
+
+	start()
+	if err := verifyRestartZipRows(rows); err != api.DB_SUCCESS {
+		t.Fatalf("verify rows: %v", err)
+	}
+	var decompress int64
+	if err := api.StatusGetI64("zip_decompress_ops", &decompress); err != api.DB_SUCCESS || decompress == 0 {
+		t.Fatalf("zip_decompress_ops=%d err=%v", decompress, err)
+	}
+	if err := api.TableDrop(nil, restartZipTable); err != api.DB_SUCCESS {
+		t.Fatalf("TableDrop: %v", err)
+	}
+	if err := api.Shutdown(api.ShutdownNormal); err != api.DB_SUCCESS {
+		t.Fatalf("Shutdown final: %v", err)
+	}
+}
+
+// restartZipValue is a poorly compressible value that differs per row.
+func restartZipValue(id uint32) []byte {
+	value := []byte(fmt.Sprintf("%08d/", id))
+	seed := id * 2654435761
+	for i := 0; i < 150+int(id%7)*10; i++ {
+		seed = seed*1103515245 + 12345
+		value = append(value, 'a'+byte(seed>>16)%26)
+	}
+	return value
+}
+
+func createRestartZipTable() api.ErrCode {
+	var schema *api.TableSchema
+	if err := api.TableSchemaCreate(restartZipTable, &schema, api.IB_TBL_COMPRESSED, 1); err != api.DB_SUCCESS {
+		return err
+	}
+	defer api.TableSchemaDelete(schema)
+	if err := api.TableSchemaAddCol(schema, "c1", api.IB_INT, api.IB_COL_UNSIGNED, 0, 4); err != api.DB_SUCCESS {
+		return err
+	}
+	if err := api.TableSchemaAddCol(schema, "c2", api.IB_VARCHAR, api.IB_COL_NONE, 0, 250); err != api.DB_SUCCESS {
+		return err
+	}
+	var idx *api.IndexSchema
+	if err := api.TableSchemaAddIndex(schema, "PRIMARY", &idx); err != api.DB_SUCCESS {
+		return err
+	}
+	if err := api.IndexSchemaAddCol(idx, "c1", 0); err != api.DB_SUCCESS {
+		return err
+	}
+	if err := api.IndexSchemaSetClustered(idx); err != api.DB_SUCCESS {
+		return err
+	}
+	trx := api.TrxBegin(api.IB_TRX_REPEATABLE_READ)
+	if err := api.SchemaLockExclusive(trx); err != api.DB_SUCCESS {
+		_ = api.TrxRollback(trx)
+		return err
+	}
+	if err := api.TableCreate(trx, schema, nil); err != api.DB_SUCCESS {
+		_ = api.TrxRollback(trx)
+		return err
+	}
+	return api.TrxCommit(trx)
+}
+
+func insertRestartZipRows(rows uint32) api.ErrCode {
+	trx := api.TrxBegin(api.IB_TRX_REPEATABLE_READ)
+	var crsr *api.Cursor
+	if err := api.CursorOpenTable(restartZipTable, trx, &crsr); err != api.DB_SUCCESS {
+		_ = api.TrxRollback(trx)
+		return err
+	}
+	tpl := api.ClustReadTupleCreate(crsr)
+	for id := uint32(1); id <= rows; id++ {
+		value := restartZipValue(id)
+		if err := api.TupleWriteU32(tpl, 0, id); err != api.DB_SUCCESS {
+			return err
+		}
+		if err := api.ColSetValue(tpl, 1, value, len(value)); err != api.DB_SUCCESS {
+			return err
+		}
+		if err := api.CursorInsertRow(crsr, tpl); err != api.DB_SUCCESS {
+			api.TupleDelete(tpl)
+			_ = api.CursorClose(crsr)
+			_ = api.TrxRollback(trx)
+			return err
+		}
+		tpl = api.TupleClear(tpl)
+	}
+	api.TupleDelete(tpl)
+	if err := api.CursorClose(crsr); err != api.DB_SUCCESS {
+		_ = api.TrxRollback(trx)
+		return err
+	}
+	return api.TrxCommit(trx)
+}
+
+func verifyRestartZipRows(rows uint32) api.ErrCode {
+	var crsr *api.Cursor
+	if err := api.CursorOpenTable(restartZipTable, nil, &crsr); err != api.DB_SUCCESS {
+		return err
+	}
+	defer api.CursorClose(crsr)
+	tpl := api.ClustReadTupleCreate(crsr)
+	defer api.TupleDelete(tpl)
+	if err := api.CursorFirst(crsr); err != api.DB_SUCCESS {
+		return err
+	}
+	for id := uint32(1); id <= rows; id++ {
+		if id > 1 {
+			if err := api.CursorNext(crsr); err != api.DB_SUCCESS {
+				return err
+			}
+		}
+		if err := api.CursorReadRow(crsr, tpl); err != api.DB_SUCCESS {
+			return err
+		}
+		var got uint32
+		if err := api.TupleReadU32(tpl, 0, &got); err != api.DB_SUCCESS {
+			return err
+		}
+		if got != id || !bytes.Equal(api.ColGetValue(tpl, 1), restartZipValue(id)) {
+			return api.DB_CORRUPTION
+		}
+	}
+	if err := api.CursorNext(crsr); err != api.DB_END_OF_INDEX {
+		return api.DB_CORRUPTION
+	}
+	return api.DB_SUCCESS
+}
@@ -3,18 +3,62 @@ package trx
 import "github.com/wilhasse/innodb-go/read"
 
 // TrxAssignReadView returns the transaction read view, creating it if needed.
+// READ COMMITTED transactions get a fresh view on every call and READ
+// UNCOMMITTED transactions never get one.
 func TrxAssignReadView(trx *Trx) *read.ReadView {
 	if trx == nil || trx.State != TrxActive || trx.ID == 0 {
 		return nil
 	}
+	if trx.Isolation == IsolationReadUncommitted {
+		return nil
+	}
 	if TrxSys == nil {
 		TrxSysInit()
 	}
 	TrxSys.Mu.Lock()
 	defer TrxSys.Mu.Unlock()
 	if trx.ReadView != nil {
-		return trx.ReadView
+		if trx.Isolation != IsolationReadCommitted {
+			return trx.ReadView
+		}
+		if TrxSys.ReadViews != nil {
+			TrxSys.ReadViews.Close(trx.ReadView)
+		}
+		trx.ReadView = nil
+	}
+	trx.ReadView = openReadViewLocked(trx)
+	return trx.ReadView
+}
+
+// TrxOpenReadView opens a read view of the current state for trx without
+// attaching it to the transaction, so an existing snapshot of trx is left
+// alone. The view must be closed with TrxCloseView.
+func TrxOpenReadView(trx *Trx) *read.ReadView {
+	if trx == nil || trx.State != TrxActive || trx.ID == 0 {
+		return nil
+	}
+	if TrxSys == nil {
+		TrxSysInit()
 	}
+	TrxSys.Mu.Lock()
+	defer TrxSys.Mu.Unlock()
+	return openReadViewLocked(trx)
+}
+
+// TrxCloseView closes a view opened by TrxOpenReadView.
+func TrxCloseView(view *read.ReadView) {
+	if view == nil || TrxSys == nil {
+		return
+	}
+	TrxSys.Mu.Lock()
+	if TrxSys.ReadViews != nil {
+		TrxSys.ReadViews.Close(view)
+	}
+	TrxSys.Mu.Unlock()
+}
+
+// openReadViewLocked assumes TrxSys.Mu is held.
+func openReadViewLocked(trx *Trx) *read.ReadView {
 	active := make([]uint64, 0, len(TrxSys.Active))
 	for _, activeTrx := range TrxSys.Active {
 		if activeTrx == nil || activeTrx.ID == 0 || activeTrx == trx {
@@ -26,7 +70,14 @@ func TrxAssignReadView(trx *Trx) *read.ReadView {
 		TrxSys.ReadViews = &read.ViewList{}
 	}
 	view := TrxSys.ReadViews.Open(trx.ID, active)
-	trx.ReadView = view
+	// Ids allocated after the creator but already committed are visible;
+	// only ids not handed out yet lie beyond the view.
+	if TrxSys.NextID > view.LowLimitID {
+		if len(view.TrxIDs) == 0 {
+			view.UpLimitID = TrxSys.NextID
+		}
+		view.LowLimitID = TrxSys.NextID
+	}
 	return view
 }
 
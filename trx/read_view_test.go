@@ -42,3 +42,64 @@ func TestTrxAssignReadViewLifecycle(t *testing.T) {
 	}
 	TrxCommit(trx1)
 }
+
+func TestTrxAssignReadViewSeesLaterCommits(t *testing.T) {
+	TrxSysVarInit()
+	TrxSysInit()
+
+	reader := TrxCreate()
+	TrxBegin(reader)
+	writer := TrxCreate()
+	TrxBegin(writer)
+	TrxCommit(writer)
+
+	view := TrxAssignReadView(reader)
+	if view == nil {
+		t.Fatalf("expected read view")
+	}
+	if !view.Sees(writer.ID) {
+		t.Fatalf("expected writer committed before the view visible")
+	}
+	later := TrxCreate()
+	TrxBegin(later)
+	if view.Sees(later.ID) {
+		t.Fatalf("expected trx started after the view not visible")
+	}
+	TrxCommit(later)
+	TrxCommit(reader)
+}
+
+func TestTrxAssignReadViewIsolation(t *testing.T) {
+	TrxSysVarInit()
+	TrxSysInit()
+
+	ru := TrxCreate()
+	ru.Isolation = IsolationReadUncommitted
+	TrxBegin(ru)
+	if view := TrxAssignReadView(ru); view != nil || ru.ReadView != nil {
+		t.Fatalf("read uncommitted got view %v", view)
+	}
+
+	rc := TrxCreate()
+	rc.Isolation = IsolationReadCommitted
+	TrxBegin(rc)
+	first := TrxAssignReadView(rc)
+	if first == nil {
+		t.Fatalf("expected read view")
+	}
+	writer := TrxCreate()
+	TrxBegin(writer)
+	TrxCommit(writer)
+	second := TrxAssignReadView(rc)
+	if second == nil || second == first {
+		t.Fatalf("expected fresh read view")
+	}
+	if !second.Sees(writer.ID) {
+		t.Fatalf("expected committed writer visible")
+	}
+	if len(TrxSys.ReadViews.Views) != 1 {
+		t.Fatalf("read views=%d want 1", len(TrxSys.ReadViews.Views))
+	}
+	TrxCommit(rc)
+	TrxCommit(ru)
+}
@@ -19,6 +19,21 @@ const (
 	TrxRolledBack
 )
 
+// IsolationLevel controls which row versions a transaction reads.
+type IsolationLevel int
+
+const (
+	// IsolationRepeatableRead reads from one view opened on first use.
+	IsolationRepeatableRead IsolationLevel = iota
+	// IsolationReadUncommitted reads the latest version, committed or not.
+	IsolationReadUncommitted
+	// IsolationReadCommitted opens a fresh view for every statement.
+	IsolationReadCommitted
+	// IsolationSerializable turns every cursor read into a shared
+	// next-key locking read of the latest committed version.
+	IsolationSerializable
+)
+
 // Trx holds transaction state for rollback.
 type Trx struct {
 	ID          uint64
@@ -26,6 +41,7 @@ type Trx struct {
 	XAState     XAState
 	XID         *XID
 	StartTime   time.Time
+	Isolation   IsolationLevel
 	ReadView    *read.ReadView
 	UndoLog     []UndoAction
 	UndoRecords []UndoRecord